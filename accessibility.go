@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+const settingScreenReader = "screen_reader" // Озвучивать элемент, получивший фокус
+
+// Announcer озвучивает названия и состояния элементов интерфейса при переходе по ним
+// с клавиатуры. Fyne не передает сведения об элементах системному экранному диктору,
+// поэтому текст произносится синтезатором речи системы (spd-say/espeak, say, SAPI)
+type Announcer struct {
+	mu      sync.Mutex
+	enabled bool
+	current *exec.Cmd
+}
+
+// NewAnnouncer создает выключенного диктора
+func NewAnnouncer() *Announcer {
+	return &Announcer{}
+}
+
+// SetEnabled включает или выключает озвучивание
+func (a *Announcer) SetEnabled(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled = enabled
+	if !enabled {
+		a.stopLocked()
+	}
+}
+
+// Announce произносит текст, прерывая предыдущую фразу
+func (a *Announcer) Announce(text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.enabled || text == "" {
+		return
+	}
+
+	a.stopLocked()
+	cmd := speechSynthCommand(text)
+	if cmd == nil {
+		log.Println("Синтезатор речи не найден, озвучивание недоступно")
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Ошибка озвучивания: %v", err)
+		return
+	}
+	a.current = cmd
+	go cmd.Wait()
+}
+
+// stopLocked прерывает текущую фразу
+func (a *Announcer) stopLocked() {
+	if a.current != nil && a.current.Process != nil {
+		a.current.Process.Kill()
+	}
+	a.current = nil
+}
+
+// speechSynthCommand возвращает команду синтезатора речи системы или nil
+func speechSynthCommand(text string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("say", text)
+	case "windows":
+		script := "Add-Type -AssemblyName System.Speech; " +
+			"(New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak('" + strings.ReplaceAll(text, "'", "''") + "')"
+		return exec.Command("powershell", "-NoProfile", "-Command", script)
+	}
+
+	if path, err := exec.LookPath("spd-say"); err == nil {
+		return exec.Command(path, "-l", "ru", "-w", text)
+	}
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return exec.Command(path, "-v", "ru", text)
+		}
+	}
+	return nil
+}
+
+// announce озвучивает текст, если диктор включен в настройках
+func (gui *MainGUI) announce(text string) {
+	gui.announcer.Announce(text)
+}
+
+// blockAccessibleName описание блока программы для озвучивания
+func blockAccessibleName(block *ProgramBlock, selected bool) string {
+	parts := []string{"Блок " + block.Title}
+	if block.Description != "" {
+		parts = append(parts, block.Description)
+	}
+	if selected {
+		parts = append(parts, "выбран")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// deviceAccessibleName описание устройства для озвучивания
+func deviceAccessibleName(device *Device, units *UnitFormatter) string {
+	parts := []string{fmt.Sprintf("Порт %d, %s", device.PortID, device.Name)}
+	if value, ok := device.LastValue.(float64); ok {
+		parts = append(parts, "значение "+units.Format(device.DeviceType, value))
+	}
+	if device.Errors > 0 {
+		parts = append(parts, fmt.Sprintf("ошибок %d", device.Errors))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AccessibleButton кнопка, которая озвучивает свое название при получении фокуса
+type AccessibleButton struct {
+	widget.Button
+	announce func(text string)
+}
+
+// NewAccessibleButton создает кнопку с иконкой; announce вызывается при получении фокуса
+func NewAccessibleButton(label string, icon fyne.Resource, tapped func(), announce func(text string)) *AccessibleButton {
+	b := &AccessibleButton{announce: announce}
+	b.Text = label
+	b.Icon = icon
+	b.OnTapped = tapped
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// FocusGained выделяет кнопку и озвучивает ее название
+func (b *AccessibleButton) FocusGained() {
+	b.Button.FocusGained()
+	b.announce("Кнопка " + b.Text)
+}
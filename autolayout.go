@@ -0,0 +1,169 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// Параметры автоматического выравнивания программы
+const (
+	layoutMargin      = 50.0
+	layoutRowGap      = 40.0
+	layoutIndent      = 40.0
+	layoutColumnGap   = 60.0
+	layoutAnimateTime = 400 * time.Millisecond
+)
+
+// layoutProgram раскладывает блоки по слоям: каждая цепочка занимает свою колонку,
+// блоки идут сверху вниз в порядке выполнения, а тела циклов и условий
+// сдвигаются вправо на уровень вложенности.
+func layoutProgram(program *Program) map[int]fyne.Position {
+	blocks := make(map[int]*ProgramBlock, len(program.Blocks))
+	hasPredecessor := make(map[int]bool)
+	for _, block := range program.Blocks {
+		blocks[block.ID] = block
+		if block.NextBlockID > 0 {
+			hasPredecessor[block.NextBlockID] = true
+		}
+	}
+
+	// Корни цепочек: сначала шапки, затем остальные блоки без предшественника
+	var roots []*ProgramBlock
+	for _, block := range program.Blocks {
+		if !hasPredecessor[block.ID] {
+			roots = append(roots, block)
+		}
+	}
+	sort.SliceStable(roots, func(i, j int) bool {
+		hatI, hatJ := isHatBlock(roots[i].Type), isHatBlock(roots[j].Type)
+		if hatI != hatJ {
+			return hatI
+		}
+		return roots[i].ID < roots[j].ID
+	})
+
+	positions := make(map[int]fyne.Position, len(program.Blocks))
+	columnX := layoutMargin
+
+	placeChain := func(chain []*ProgramBlock) {
+		depths := chainDepths(chain)
+		y := layoutMargin
+		columnWidth := 0.0
+		for i, block := range chain {
+			x := columnX + float64(depths[i])*layoutIndent
+			positions[block.ID] = fyne.NewPos(float32(x), float32(y))
+			y += block.Height + layoutRowGap
+			if width := x - columnX + block.Width; width > columnWidth {
+				columnWidth = width
+			}
+		}
+		columnX += columnWidth + layoutColumnGap
+	}
+
+	for _, root := range roots {
+		var chain []*ProgramBlock
+		for block := root; block != nil; block = blocks[block.NextBlockID] {
+			if _, placed := positions[block.ID]; placed || containsBlock(chain, block) {
+				break
+			}
+			chain = append(chain, block)
+		}
+		placeChain(chain)
+	}
+
+	// Блоки, замкнутые в кольцо без начала, выкладываем последней колонкой
+	var rest []*ProgramBlock
+	for _, block := range program.Blocks {
+		if _, placed := positions[block.ID]; !placed {
+			rest = append(rest, block)
+		}
+	}
+	if len(rest) > 0 {
+		placeChain(rest)
+	}
+
+	return positions
+}
+
+// chainDepths возвращает уровень вложенности каждого блока цепочки
+// с учетом тел циклов и условий
+func chainDepths(chain []*ProgramBlock) []int {
+	depths := make([]int, len(chain))
+	var open []int // Сколько блоков осталось в каждом открытом теле
+
+	for i, block := range chain {
+		for len(open) > 0 && open[len(open)-1] == 0 {
+			open = open[:len(open)-1]
+		}
+		depths[i] = len(open)
+		for j := range open {
+			open[j]--
+		}
+
+		if block.Type == BlockTypeLoop || block.Type == BlockTypeCondition {
+			open = append(open, len(blockBody(chain, i)))
+		}
+	}
+	return depths
+}
+
+// containsBlock проверяет, есть ли блок в цепочке
+func containsBlock(chain []*ProgramBlock, block *ProgramBlock) bool {
+	for _, b := range chain {
+		if b == block {
+			return true
+		}
+	}
+	return false
+}
+
+// tidyUp выравнивает блоки программы и плавно перемещает их на новые места
+func (gui *MainGUI) tidyUp() {
+	if gui.readOnly() || gui.programPanel == nil {
+		return
+	}
+	gui.checkpoint()
+	gui.programPanel.animateLayout(layoutProgram(gui.programMgr.GetProgram()))
+}
+
+// animateLayout плавно перемещает блоки холста в заданные позиции
+func (p *ProgramPanel) animateLayout(targets map[int]fyne.Position) {
+	starts := make(map[int]fyne.Position, len(targets))
+	bottom := layoutMargin
+	for id, target := range targets {
+		blockWidget, exists := p.blockWidgets[id]
+		if !exists {
+			continue
+		}
+		starts[id] = blockWidget.Position()
+
+		// Данные блока сразу получают итоговые координаты
+		blockWidget.block.X = float64(target.X)
+		blockWidget.block.Y = float64(target.Y)
+		blockWidget.block.DragStartPos = target
+		p.programMgr.UpdateBlockPosition(id, blockWidget.block.X, blockWidget.block.Y)
+
+		if y := blockWidget.block.Y + blockWidget.block.Height + layoutRowGap; y > bottom {
+			bottom = y
+		}
+	}
+	p.lastBlockY = bottom
+
+	anim := fyne.NewAnimation(layoutAnimateTime, func(progress float32) {
+		for id, start := range starts {
+			target := targets[id]
+			p.blockWidgets[id].Move(fyne.NewPos(
+				start.X+(target.X-start.X)*progress,
+				start.Y+(target.Y-start.Y)*progress,
+			))
+		}
+		p.updateConnections()
+	})
+	anim.Curve = fyne.AnimationEaseInOut
+	anim.Start()
+
+	log.Printf("Программа выровнена: %d блок(ов)", len(starts))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	settingIdleMinutes  = "idle_disconnect_minutes" // Минут бездействия до отключения, 0 — не отключать
+	settingIdlePowerOff = "idle_power_off"          // Выключать хаб, а не только отключаться
+
+	idleCheckInterval = time.Second      // Как часто проверять бездействие
+	idleCountdown     = 30 * time.Second // За сколько до отключения показывать предупреждение
+)
+
+// idleMinuteChoices варианты времени бездействия в настройках
+var idleMinuteChoices = []int{0, 5, 10, 15, 30}
+
+// BatterySaver отключает хаб, когда программа не выполняется и с приложением
+// долго не работают. Перед отключением показывается обратный отсчет с кнопкой отмены
+type BatterySaver struct {
+	gui *MainGUI
+
+	mu           sync.Mutex
+	timeout      time.Duration
+	powerOff     bool
+	lastActivity time.Time
+
+	toast      *widget.PopUp
+	toastLabel *widget.Label
+	toastShown bool // Предупреждение на экране; меняется в потоке интерфейса под mu
+
+	crashHandler func()
+}
+
+// NewBatterySaver создает выключенную экономию батареи и запускает проверку бездействия
+func NewBatterySaver(gui *MainGUI) *BatterySaver {
+	s := &BatterySaver{gui: gui, lastActivity: time.Now()}
+	go s.loop()
+	return s
+}
+
+// Configure задает время бездействия (0 — не отключать) и выключение хаба
+func (s *BatterySaver) Configure(timeout time.Duration, powerOff bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeout = timeout
+	s.powerOff = powerOff
+	s.lastActivity = time.Now()
+}
+
+// Touch отмечает действие пользователя и откладывает отключение
+func (s *BatterySaver) Touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActivity = time.Now()
+}
+
+// SetCrashHandler устанавливает обработчик паники при проверке бездействия
+func (s *BatterySaver) SetCrashHandler(handler func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crashHandler = handler
+}
+
+// loop раз в секунду проверяет бездействие
+func (s *BatterySaver) loop() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.guardedCheck()
+	}
+}
+
+// guardedCheck выполняет проверку под обработчиком паники. Обработчик
+// устанавливается после запуска цикла, поэтому берется заново на каждой проверке
+func (s *BatterySaver) guardedCheck() {
+	s.mu.Lock()
+	crashHandler := s.crashHandler
+	s.mu.Unlock()
+	if crashHandler != nil {
+		defer crashHandler()
+	}
+	s.check()
+}
+
+// check отключает хаб по истечении времени или обновляет обратный отсчет
+func (s *BatterySaver) check() {
+	hubMgr, programMgr := s.gui.hubMgr, s.gui.programMgr
+
+	s.mu.Lock()
+	// Выполняющаяся программа и отсутствие хаба не считаются бездействием
+	if s.timeout == 0 || !hubMgr.IsConnected() || programMgr.GetProgramState() == ProgramStateRunning {
+		s.lastActivity = time.Now()
+	}
+	remaining := s.timeout - time.Since(s.lastActivity)
+	timeout, powerOff, shown := s.timeout, s.powerOff, s.toastShown
+	if remaining <= 0 {
+		s.lastActivity = time.Now()
+	}
+	s.mu.Unlock()
+
+	switch {
+	case timeout == 0 || remaining > idleCountdown:
+		// Прятать нечего: не нагружаем поток интерфейса каждую секунду
+		if shown {
+			fyne.Do(s.hideToast)
+		}
+
+	case remaining > 0:
+		seconds := int((remaining + time.Second - 1) / time.Second)
+		fyne.Do(func() { s.showToast(seconds) })
+
+	default:
+		if shown {
+			fyne.Do(s.hideToast)
+		}
+		action := HubPowerSleep
+		if powerOff {
+			action = HubPowerOff
+		}
+		log.Printf("Экономия батареи: %.0f мин без действий, хаб отключается", timeout.Minutes())
+		if err := hubMgr.PowerAction(action); err != nil {
+			log.Printf("Экономия батареи: %v", err)
+		}
+	}
+}
+
+// showToast показывает или обновляет предупреждение в правом нижнем углу окна
+func (s *BatterySaver) showToast(seconds int) {
+	text := fmt.Sprintf("Хаб отключится через %d с, чтобы сберечь батарею", seconds)
+	if s.toast != nil && !s.toast.Visible() {
+		// Щелчок мимо предупреждения закрывает его: это тоже действие пользователя
+		s.toast = nil
+		s.setToastShown(false)
+		s.Touch()
+		return
+	}
+	if s.toast != nil {
+		s.toastLabel.SetText(text)
+		return
+	}
+
+	s.toastLabel = widget.NewLabel(text)
+	cancel := widget.NewButton("Не отключать", func() {
+		s.Touch()
+		s.hideToast()
+	})
+	cancel.Importance = widget.HighImportance
+
+	bg := canvas.NewRectangle(color.NRGBA{R: 0, G: 0, B: 0, A: 200})
+	bg.CornerRadius = 8
+	content := container.NewStack(bg, container.NewPadded(container.NewHBox(s.toastLabel, cancel)))
+
+	c := s.gui.window.Canvas()
+	s.toast = widget.NewPopUp(content, c)
+	size := content.MinSize()
+	s.toast.ShowAtPosition(fyne.NewPos(c.Size().Width-size.Width-16, c.Size().Height-size.Height-48))
+	s.setToastShown(true)
+}
+
+// hideToast убирает предупреждение
+func (s *BatterySaver) hideToast() {
+	if s.toast == nil {
+		return
+	}
+	s.toast.Hide()
+	s.toast = nil
+	s.setToastShown(false)
+}
+
+// setToastShown отмечает, показано ли предупреждение
+func (s *BatterySaver) setToastShown(shown bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toastShown = shown
+}
+
+// idleMinutesName название варианта времени бездействия
+func idleMinutesName(minutes int) string {
+	if minutes == 0 {
+		return "Не отключать"
+	}
+	return fmt.Sprintf("Через %d мин", minutes)
+}
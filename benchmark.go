@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	benchmarkBlockSamples = 200 // Сколько последних замеров хранить для каждого блока
+	benchmarkMinSamples   = 5   // При меньшем числе замеров выбросы не ищутся
+	benchmarkOutlierFence = 1.5 // Множитель межквартильного размаха для границы выбросов
+	benchmarkOutlierLimit = 5   // Сколько самых долгих выбросов показывать
+)
+
+// durationSample замер длительности; Run — номер запуска программы, 0 — вне запуска
+type durationSample struct {
+	Run      int
+	Duration time.Duration
+}
+
+// blockSamples замеры одного блока
+type blockSamples struct {
+	title   string
+	samples []durationSample
+}
+
+// BenchmarkRecorder копит длительности выполнения блоков от запуска к запуску,
+// чтобы сравнивать их между запусками и находить аномально долгие выполнения
+type BenchmarkRecorder struct {
+	mu     sync.Mutex
+	run    int
+	blocks map[int]*blockSamples
+}
+
+// DurationStats сводка по набору замеров
+type DurationStats struct {
+	Count    int
+	Mean     time.Duration
+	Min      time.Duration
+	P50      time.Duration
+	P90      time.Duration
+	Max      time.Duration
+	Fence    time.Duration    // Граница выбросов, 0 — замеров слишком мало
+	Outliers []durationSample // Замеры выше границы, начиная с самого долгого
+}
+
+// BlockBenchmark сводка по одному блоку
+type BlockBenchmark struct {
+	BlockID int
+	Title   string
+	Runs    int
+	Stats   DurationStats
+}
+
+// BenchmarkReport отчет о задержке команд BLE и длительности блоков
+type BenchmarkReport struct {
+	Runs   int
+	Writes DurationStats
+	Blocks []BlockBenchmark
+}
+
+// NewBenchmarkRecorder создает пустой набор замеров
+func NewBenchmarkRecorder() *BenchmarkRecorder {
+	return &BenchmarkRecorder{blocks: make(map[int]*blockSamples)}
+}
+
+// StartRun начинает замеры нового запуска программы
+func (r *BenchmarkRecorder) StartRun() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run++
+}
+
+// RecordBlock учитывает успешное выполнение блока. Блоки расписания не
+// учитываются: их длительность рассчитана заранее, а не измерена
+func (r *BenchmarkRecorder) RecordBlock(execution BlockExecution) {
+	if execution.Err != nil || execution.Scheduled {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	samples, exists := r.blocks[execution.BlockID]
+	if !exists {
+		samples = &blockSamples{}
+		r.blocks[execution.BlockID] = samples
+	}
+	samples.title = execution.Title
+	samples.samples = append(samples.samples, durationSample{Run: r.run, Duration: execution.Duration})
+	if len(samples.samples) > benchmarkBlockSamples {
+		samples.samples = samples.samples[len(samples.samples)-benchmarkBlockSamples:]
+	}
+}
+
+// Reset удаляет все замеры
+func (r *BenchmarkRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run = 0
+	r.blocks = make(map[int]*blockSamples)
+}
+
+// Report сводит замеры блоков и переданные задержки записи BLE в отчет
+func (r *BenchmarkRecorder) Report(writeLatencies []time.Duration) BenchmarkReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	writes := make([]durationSample, len(writeLatencies))
+	for i, latency := range writeLatencies {
+		writes[i] = durationSample{Duration: latency}
+	}
+	report := BenchmarkReport{Runs: r.run, Writes: durationStats(writes)}
+
+	for id, samples := range r.blocks {
+		runs := make(map[int]bool)
+		for _, sample := range samples.samples {
+			runs[sample.Run] = true
+		}
+		report.Blocks = append(report.Blocks, BlockBenchmark{
+			BlockID: id,
+			Title:   samples.title,
+			Runs:    len(runs),
+			Stats:   durationStats(samples.samples),
+		})
+	}
+	sort.Slice(report.Blocks, func(i, j int) bool { return report.Blocks[i].BlockID < report.Blocks[j].BlockID })
+	return report
+}
+
+// durationStats считает сводку и ищет выбросы по правилу Тьюки:
+// выше третьего квартиля более чем на полтора межквартильных размаха
+func durationStats(samples []durationSample) DurationStats {
+	stats := DurationStats{Count: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	var total time.Duration
+	for i, sample := range samples {
+		sorted[i] = sample.Duration
+		total += sample.Duration
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats.Mean = total / time.Duration(len(samples))
+	stats.Min = sorted[0]
+	stats.P50 = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	stats.Max = sorted[len(sorted)-1]
+
+	if len(samples) < benchmarkMinSamples {
+		return stats
+	}
+	q1, q3 := percentile(sorted, 25), percentile(sorted, 75)
+	stats.Fence = q3 + time.Duration(benchmarkOutlierFence*float64(q3-q1))
+	for _, sample := range samples {
+		if sample.Duration > stats.Fence {
+			stats.Outliers = append(stats.Outliers, sample)
+		}
+	}
+	sort.Slice(stats.Outliers, func(i, j int) bool { return stats.Outliers[i].Duration > stats.Outliers[j].Duration })
+	return stats
+}
+
+// String записывает отчет текстом для просмотра и копирования
+func (r BenchmarkReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Запусков программы: %d\n\n", r.Runs)
+
+	b.WriteString("Запись команд BLE\n")
+	writeStatsText(&b, r.Writes)
+
+	if len(r.Blocks) == 0 {
+		b.WriteString("\nБлоки еще не выполнялись\n")
+	}
+	for _, block := range r.Blocks {
+		fmt.Fprintf(&b, "\nБлок «%s» (ID: %d), запусков: %d\n", block.Title, block.BlockID, block.Runs)
+		writeStatsText(&b, block.Stats)
+	}
+	return b.String()
+}
+
+// writeStatsText записывает сводку замеров и выбросы
+func writeStatsText(b *strings.Builder, stats DurationStats) {
+	if stats.Count == 0 {
+		b.WriteString("  замеров нет\n")
+		return
+	}
+	fmt.Fprintf(b, "  замеров: %d, среднее %s, мин. %s, p50 %s, p90 %s, макс. %s\n",
+		stats.Count, formatLatency(stats.Mean), formatLatency(stats.Min),
+		formatLatency(stats.P50), formatLatency(stats.P90), formatLatency(stats.Max))
+	if stats.Fence == 0 {
+		fmt.Fprintf(b, "  выбросы ищутся от %d замеров\n", benchmarkMinSamples)
+		return
+	}
+	if len(stats.Outliers) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "  ! выбросов: %d (дольше %s):", len(stats.Outliers), formatLatency(stats.Fence))
+	for i, outlier := range stats.Outliers {
+		if i == benchmarkOutlierLimit {
+			b.WriteString(" …")
+			break
+		}
+		if outlier.Run > 0 {
+			fmt.Fprintf(b, " %s (запуск %d)", formatLatency(outlier.Duration), outlier.Run)
+		} else {
+			fmt.Fprintf(b, " %s", formatLatency(outlier.Duration))
+		}
+	}
+	b.WriteString("\n")
+}
+
+// showBenchmarkReport показывает отчет о производительности
+func (gui *MainGUI) showBenchmarkReport() {
+	window := fyne.CurrentApp().NewWindow("Производительность")
+	report := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	update := func() {
+		report.SetText(gui.programMgr.Benchmarks().Report(gui.hubMgr.Metrics().Latencies()).String())
+	}
+	update()
+
+	buttons := container.NewHBox(
+		widget.NewButton("Обновить", update),
+		widget.NewButton("Копировать", func() { window.Clipboard().SetContent(report.Text) }),
+		widget.NewButton("Сбросить замеры блоков", func() {
+			gui.programMgr.Benchmarks().Reset()
+			update()
+		}),
+	)
+
+	window.SetContent(container.NewBorder(nil, buttons, nil, nil, container.NewScroll(report)))
+	window.Resize(fyne.NewSize(720, 520))
+	window.Show()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ParameterBinding привязка параметра блока к живому значению датчика расстояния:
+// показание из диапазона [InMin, InMax] переводится в [OutMin, OutMax]
+type ParameterBinding struct {
+	Port   byte
+	InMin  float64
+	InMax  float64
+	OutMin float64
+	OutMax float64
+}
+
+// bindingKey возвращает ключ параметра привязки, например "power_bind_port"
+func bindingKey(param, field string) string {
+	if field == "" {
+		return param + "_bind"
+	}
+	return param + "_bind_" + field
+}
+
+// bindingFromParameters читает привязку параметра; false, если привязка выключена
+func bindingFromParameters(params map[string]interface{}, param string) (ParameterBinding, bool) {
+	if enabled, _ := params[bindingKey(param, "")].(bool); !enabled {
+		return ParameterBinding{}, false
+	}
+
+	binding := ParameterBinding{Port: 1}
+	if port, ok := params[bindingKey(param, "port")].(byte); ok {
+		binding.Port = port
+	}
+	binding.InMin = parameterToFloat(params[bindingKey(param, "in_min")])
+	binding.InMax = parameterToFloat(params[bindingKey(param, "in_max")])
+	binding.OutMin = parameterToFloat(params[bindingKey(param, "out_min")])
+	binding.OutMax = parameterToFloat(params[bindingKey(param, "out_max")])
+	return binding, true
+}
+
+// Apply переводит показание датчика в значение параметра, не выходя за выходной диапазон
+func (b ParameterBinding) Apply(sensorValue float64) float64 {
+	if b.InMax == b.InMin {
+		return b.OutMin
+	}
+	value := mapRange(sensorValue, b.InMin, b.InMax, b.OutMin, b.OutMax)
+	if b.OutMin < b.OutMax {
+		return clamp(value, b.OutMin, b.OutMax)
+	}
+	return clamp(value, b.OutMax, b.OutMin)
+}
+
+// boundValue вычисляет привязанный параметр блока по последнему показанию датчика.
+// Возвращает false, если привязки нет или датчик еще не прислал значение.
+func (pm *ProgramManager) boundValue(block *ProgramBlock, param string) (float64, bool) {
+	binding, ok := bindingFromParameters(block.Parameters, param)
+	if !ok {
+		return 0, false
+	}
+
+	sensorValue, ok := pm.hubMgr.GetSensorValue(binding.Port)
+	if !ok {
+		log.Printf("Нет значения датчика на порту %d, параметр %q берется из блока", binding.Port, param)
+		return 0, false
+	}
+
+	value := binding.Apply(sensorValue)
+	log.Printf("Параметр %q блока %d привязан к датчику: %g -> %g", param, block.ID, sensorValue, value)
+	return value, true
+}
+
+// soundFrequency возвращает частоту звука с учетом привязки к датчику
+func (pm *ProgramManager) soundFrequency(block *ProgramBlock) uint16 {
+	if value, ok := pm.boundValue(block, "frequency"); ok {
+		return uint16(value)
+	}
+	return soundFromParameters(block.Parameters).Frequency
+}
+
+// addBindingControls создает флажок привязки параметра к датчику расстояния
+// и ползунки входного и выходного диапазонов. Привязка важнее случайного значения.
+func (e *BlockEditor) addBindingControls(param string, outLo, outHi, step float64,
+	format func(float64) string) *fyne.Container {
+
+	params := e.block.Parameters
+	distance := findConditionSensor(ConditionSensorDistance)
+
+	// Значения по умолчанию: близко — наибольшее значение, далеко — наименьшее
+	defaults := map[string]float64{
+		"in_min":  distance.Min,
+		"in_max":  distance.Max,
+		"out_min": outHi,
+		"out_max": outLo,
+	}
+	for field, value := range defaults {
+		if _, ok := params[bindingKey(param, field)]; !ok {
+			params[bindingKey(param, field)] = value
+		}
+	}
+	if _, ok := params[bindingKey(param, "port")].(byte); !ok {
+		params[bindingKey(param, "port")] = byte(1)
+	}
+
+	portSelect := widget.NewSelect([]string{"Порт 1", "Порт 2"}, func(selected string) {
+		if selected == "Порт 2" {
+			params[bindingKey(param, "port")] = byte(2)
+		} else {
+			params[bindingKey(param, "port")] = byte(1)
+		}
+		e.notifyChange()
+	})
+	if params[bindingKey(param, "port")].(byte) == 2 {
+		portSelect.SetSelected("Порт 2")
+	} else {
+		portSelect.SetSelected("Порт 1")
+	}
+
+	newSlider := func(field, caption string, lo, hi, step float64, format func(float64) string) fyne.CanvasObject {
+		key := bindingKey(param, field)
+		slider := widget.NewSlider(lo, hi)
+		slider.Step = step
+		slider.Value = parameterToFloat(params[key])
+		valueLabel := widget.NewLabel(format(slider.Value))
+		slider.OnChanged = func(value float64) {
+			params[key] = value
+			valueLabel.SetText(format(value))
+			e.notifyChange()
+		}
+		return container.NewBorder(nil, nil, widget.NewLabel(caption), valueLabel, slider)
+	}
+
+	distanceFormat := func(value float64) string {
+		return fmt.Sprintf("%.0f %s", value, distance.Unit)
+	}
+
+	settings := container.NewVBox(
+		container.NewBorder(nil, nil, widget.NewLabel("Датчик расстояния:"), nil, portSelect),
+		widget.NewLabel("Когда расстояние"),
+		newSlider("in_min", "от", distance.Min, distance.Max, distance.Step, distanceFormat),
+		newSlider("in_max", "до", distance.Min, distance.Max, distance.Step, distanceFormat),
+		widget.NewLabel("значение меняется"),
+		newSlider("out_min", "от", outLo, outHi, step, format),
+		newSlider("out_max", "до", outLo, outHi, step, format),
+	)
+
+	enabled, _ := params[bindingKey(param, "")].(bool)
+	params[bindingKey(param, "")] = enabled
+
+	check := widget.NewCheck("Брать значение с датчика", func(checked bool) {
+		params[bindingKey(param, "")] = checked
+		settings.Hidden = !checked
+		settings.Refresh()
+		e.notifyChange()
+	})
+	check.Checked = enabled
+	settings.Hidden = !enabled
+
+	return container.NewVBox(check, settings)
+}
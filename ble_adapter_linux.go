@@ -0,0 +1,101 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tinybluetooth "tinygo.org/x/bluetooth"
+)
+
+// availableAdapters перечисляет BLE-адаптеры системы (hci0, hci1, ...)
+func availableAdapters() []string {
+	entries, err := os.ReadDir("/sys/class/bluetooth")
+	if err != nil {
+		return nil
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		// Записи вида hci0:12 — это соединения, а не адаптеры
+		if name := entry.Name(); strings.HasPrefix(name, "hci") && !strings.Contains(name, ":") {
+			ids = append(ids, name)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// adapterByID возвращает адаптер по идентификатору, пустой — адаптер по умолчанию
+func adapterByID(id string) *tinybluetooth.Adapter {
+	if id == "" {
+		return tinybluetooth.DefaultAdapter
+	}
+	return tinybluetooth.NewAdapter(id)
+}
+
+// platformBLEIssue проверяет адаптеры и блокировку rfkill через sysfs
+func platformBLEIssue() *BLEIssue {
+	if len(availableAdapters()) == 0 {
+		return newBLEIssue(BLEIssueNoAdapter, nil)
+	}
+
+	switches, _ := filepath.Glob("/sys/class/rfkill/rfkill*")
+	for _, path := range switches {
+		if readSysfs(filepath.Join(path, "type")) != "bluetooth" {
+			continue
+		}
+		if readSysfs(filepath.Join(path, "hard")) == "1" || readSysfs(filepath.Join(path, "soft")) == "1" {
+			return newBLEIssue(BLEIssueOff, nil)
+		}
+	}
+	return nil
+}
+
+// readSysfs читает однострочный файл sysfs
+func readSysfs(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// bleFixSteps шаги устранения проблемы с Bluetooth в Linux
+func bleFixSteps(kind string) []string {
+	switch kind {
+	case BLEIssueOff:
+		return []string{
+			"Включите Bluetooth в настройках системы или выполните в терминале: rfkill unblock bluetooth",
+			"Если на ноутбуке есть переключатель или клавиша беспроводной связи, включите ее",
+			"Проверьте, что адаптер включен: bluetoothctl power on",
+		}
+	case BLEIssueNoAdapter:
+		return []string{
+			"Подключите USB-адаптер Bluetooth 4.0 или новее: хабу WeDo 2.0 нужен Bluetooth Low Energy",
+			"Проверьте, что система видит адаптер: bluetoothctl list",
+		}
+	case BLEIssuePermission:
+		return []string{
+			"Добавьте пользователя в группу bluetooth: sudo usermod -aG bluetooth $USER, затем выйдите из системы и войдите снова",
+			"Если приложение установлено через snap или flatpak, разрешите ему доступ к Bluetooth в настройках песочницы",
+		}
+	case BLEIssueService:
+		return []string{
+			"Установите пакет bluez, если его нет",
+			"Запустите службу Bluetooth: sudo systemctl enable --now bluetooth",
+		}
+	case BLEIssueBusy:
+		return []string{
+			"Закройте другие программы, которые работают с Bluetooth, например второй экземпляр WeDoProg",
+			"Перезапустите службу Bluetooth: sudo systemctl restart bluetooth",
+		}
+	}
+	return []string{
+		"Проверьте, что Bluetooth включен и служба bluetooth запущена: systemctl status bluetooth",
+		"Отключите и снова подключите USB-адаптер или перезагрузите компьютер",
+	}
+}
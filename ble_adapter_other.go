@@ -0,0 +1,71 @@
+//go:build !linux
+
+package main
+
+import (
+	"runtime"
+
+	tinybluetooth "tinygo.org/x/bluetooth"
+)
+
+// availableAdapters перечисляет BLE-адаптеры системы. В Windows и macOS
+// библиотека работает только с системным адаптером, поэтому выбирать не из чего
+func availableAdapters() []string {
+	return nil
+}
+
+// adapterByID возвращает адаптер по умолчанию: другие адаптеры недоступны
+func adapterByID(string) *tinybluetooth.Adapter {
+	return tinybluetooth.DefaultAdapter
+}
+
+// platformBLEIssue в Windows и macOS состояние Bluetooth известно только из
+// ошибок библиотеки, отдельной проверки нет
+func platformBLEIssue() *BLEIssue {
+	return nil
+}
+
+// bleFixSteps шаги устранения проблемы с Bluetooth в Windows и macOS
+func bleFixSteps(kind string) []string {
+	if runtime.GOOS == "darwin" {
+		switch kind {
+		case BLEIssueOff:
+			return []string{"Включите Bluetooth в Пункте управления или в Системных настройках → Bluetooth"}
+		case BLEIssuePermission:
+			return []string{
+				"Откройте Системные настройки → Конфиденциальность и безопасность → Bluetooth и разрешите доступ WeDoProg. " +
+					"Если приложение запущено из Терминала, разрешение нужно Терминалу",
+				"После изменения разрешения перезапустите приложение",
+			}
+		case BLEIssueNoAdapter:
+			return []string{"Этот Mac не поддерживает Bluetooth Low Energy: подключите адаптер Bluetooth 4.0 или новее"}
+		case BLEIssueBusy:
+			return []string{"Закройте другие программы, которые работают с Bluetooth, например второй экземпляр WeDoProg"}
+		}
+		return []string{
+			"Выключите и снова включите Bluetooth в Пункте управления",
+			"Перезагрузите компьютер, если ошибка повторяется",
+		}
+	}
+
+	switch kind {
+	case BLEIssueOff:
+		return []string{
+			"Включите Bluetooth: Параметры → Bluetooth и устройства",
+			"Выключите режим «в самолете»",
+		}
+	case BLEIssuePermission:
+		return []string{"Параметры → Конфиденциальность и защита → Радио: разрешите приложениям управлять радиомодулями"}
+	case BLEIssueNoAdapter:
+		return []string{
+			"Подключите адаптер Bluetooth 4.0 или новее: хабу WeDo 2.0 нужен Bluetooth Low Energy",
+			"Проверьте адаптер и обновите его драйвер в Диспетчере устройств",
+		}
+	case BLEIssueBusy:
+		return []string{"Закройте другие программы, которые работают с Bluetooth, например приложение LEGO Education WeDo 2.0"}
+	}
+	return []string{
+		"Выключите и снова включите Bluetooth в параметрах системы",
+		"Обновите драйвер адаптера в Диспетчере устройств и перезагрузите компьютер",
+	}
+}
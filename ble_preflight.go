@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Перед работой с хабом проверяется, что Bluetooth вообще доступен: адаптер
+// есть, включен, приложению разрешен доступ, и адаптер не занят другой
+// программой. Вместо общей ошибки пользователь видит причину, шаги для своей
+// системы и кнопку повторной проверки
+
+// Виды проблем с Bluetooth
+const (
+	BLEIssueOff        = "off"        // Bluetooth выключен программно или переключателем
+	BLEIssueNoAdapter  = "no_adapter" // В системе нет адаптера Bluetooth LE
+	BLEIssuePermission = "permission" // Приложению не разрешен доступ к Bluetooth
+	BLEIssueService    = "service"    // Системная служба Bluetooth не запущена
+	BLEIssueBusy       = "busy"       // Адаптер занят другой программой или операцией
+	BLEIssueUnknown    = "unknown"
+)
+
+// bleIssueTitles заголовки проблем для интерфейса
+var bleIssueTitles = map[string]string{
+	BLEIssueOff:        "Bluetooth выключен",
+	BLEIssueNoAdapter:  "Адаптер Bluetooth не найден",
+	BLEIssuePermission: "Нет разрешения на Bluetooth",
+	BLEIssueService:    "Служба Bluetooth не запущена",
+	BLEIssueBusy:       "Адаптер Bluetooth занят",
+	BLEIssueUnknown:    "Не удалось включить Bluetooth",
+}
+
+// bleErrorHints признаки проблем в тексте ошибок библиотеки и системы.
+// Проверяются по порядку: первое совпадение определяет вид проблемы
+var bleErrorHints = []struct {
+	kind    string
+	phrases []string
+}{
+	{BLEIssuePermission, []string{"accessdenied", "access denied", "permission denied", "not authorized", "unauthorized", "operation not permitted"}},
+	{BLEIssueService, []string{"serviceunknown", "has no owner", "not provided by any .service", "no such file or directory", "connection refused"}},
+	{BLEIssueBusy, []string{"busy", "inprogress", "in progress", "already"}},
+	{BLEIssueOff, []string{"powered off", "poweredoff", "notready", "not ready", "radio", "rfkill", "blocked"}},
+	{BLEIssueNoAdapter, []string{"адаптер не найден", "no bluetooth", "unsupported", "not supported", "no such adapter", "not found"}},
+}
+
+// BLEIssue проблема с Bluetooth и шаги для ее устранения
+type BLEIssue struct {
+	Kind  string
+	Err   error // Исходная ошибка; nil, если проблема найдена проверкой системы
+	Steps []string
+}
+
+// Title возвращает заголовок проблемы
+func (i *BLEIssue) Title() string {
+	return bleIssueTitles[i.Kind]
+}
+
+// newBLEIssue создает проблему с шагами для текущей системы
+func newBLEIssue(kind string, err error) *BLEIssue {
+	return &BLEIssue{Kind: kind, Err: err, Steps: bleFixSteps(kind)}
+}
+
+// classifyBLEError определяет вид проблемы по тексту ошибки
+func classifyBLEError(err error) string {
+	text := strings.ToLower(err.Error())
+	for _, hint := range bleErrorHints {
+		for _, phrase := range hint.phrases {
+			if strings.Contains(text, phrase) {
+				return hint.kind
+			}
+		}
+	}
+	return BLEIssueUnknown
+}
+
+// diagnoseBLEError объясняет ошибку Bluetooth. Проверка системы точнее текста
+// ошибки, поэтому найденная ею проблема важнее
+func diagnoseBLEError(err error) *BLEIssue {
+	if issue := platformBLEIssue(); issue != nil {
+		issue.Err = err
+		return issue
+	}
+	return newBLEIssue(classifyBLEError(err), err)
+}
+
+// newBLEIssueView показывает проблему: заголовок, шаги и подробности ошибки
+func newBLEIssueView(issue *BLEIssue) fyne.CanvasObject {
+	title := widget.NewLabelWithStyle(issue.Title(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	content := container.NewVBox(container.NewHBox(widget.NewIcon(theme.WarningIcon()), title))
+
+	for i, step := range issue.Steps {
+		label := widget.NewLabel(fmt.Sprintf("%d. %s", i+1, step))
+		label.Wrapping = fyne.TextWrapWord
+		content.Add(label)
+	}
+	if issue.Err != nil {
+		details := widget.NewLabelWithStyle(issue.Err.Error(), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		details.Wrapping = fyne.TextWrapWord
+		content.Add(widget.NewAccordion(widget.NewAccordionItem("Подробности", details)))
+	}
+	return content
+}
+
+// showBLEPreflight заполняет окно проблемой с Bluetooth, пока приложение не
+// может начать работу. "Повторить" вызывает retry в фоне; после успеха ready
+// строит главное окно, иначе окно показывает новую причину
+func showBLEPreflight(window fyne.Window, issue *BLEIssue, retry func() error, ready func()) {
+	view := container.NewVBox()
+	var retryButton *widget.Button
+	show := func(issue *BLEIssue) {
+		view.Objects = []fyne.CanvasObject{newBLEIssueView(issue)}
+		view.Refresh()
+	}
+	retryButton = widget.NewButtonWithIcon("Повторить", theme.ViewRefreshIcon(), func() {
+		retryButton.Disable()
+		go func() {
+			err := retry()
+			fyne.Do(func() {
+				retryButton.Enable()
+				if err != nil {
+					log.Printf("Bluetooth по-прежнему недоступен: %v", err)
+					show(diagnoseBLEError(err))
+					return
+				}
+				ready()
+			})
+		}()
+	})
+	retryButton.Importance = widget.HighImportance
+	quitButton := widget.NewButton("Выйти", func() { fyne.CurrentApp().Quit() })
+
+	show(issue)
+	intro := widget.NewLabel("Для работы с хабом WeDo 2.0 нужен Bluetooth. Исправьте проблему и нажмите «Повторить».")
+	intro.Wrapping = fyne.TextWrapWord
+	buttons := container.NewHBox(retryButton, quitButton)
+	window.SetContent(container.NewPadded(container.NewBorder(intro, buttons, nil, nil, container.NewVScroll(view))))
+}
+
+// showBLEIssue показывает проблему с Bluetooth в диалоге; retry повторяет
+// действие после исправления
+func (gui *MainGUI) showBLEIssue(issue *BLEIssue, retry func()) {
+	d := dialog.NewCustomConfirm("Bluetooth", "Повторить", "Закрыть", newBLEIssueView(issue), func(again bool) {
+		if again {
+			retry()
+		}
+	}, gui.window)
+	d.Resize(fyne.NewSize(520, 0))
+	d.Show()
+}
+
+// checkBluetoothReady проверяет систему после запуска: адаптер может включиться,
+// но оставаться заблокированным, и тогда поиск хабов ничего не найдет
+func (gui *MainGUI) checkBluetoothReady() {
+	issue := platformBLEIssue()
+	if issue == nil {
+		return
+	}
+	log.Printf("Проблема с Bluetooth: %s", issue.Title())
+	gui.showBLEIssue(issue, gui.checkBluetoothReady)
+}
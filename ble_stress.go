@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	stressLEDChanges   = 100                   // Смен цвета светодиода подряд
+	stressMotorToggles = 40                    // Включений и остановок каждого мотора
+	stressMotorPower   = 30                    // Мощность моторов при проверке, %
+	stressReaders      = 4                     // Одновременных читателей характеристики батареи
+	stressReadsEach    = 10                    // Чтений у каждого читателя
+	stressStepDelay    = 20 * time.Millisecond // Пауза между командами одной фазы
+	stressSlowWrite    = 50 * time.Millisecond // Задержка записи p90, которая считается большой
+)
+
+// StressPhase результат одной фазы нагрузочной проверки
+type StressPhase struct {
+	Name       string
+	Reads      bool // Фаза проверяет чтение, а не запись
+	Attempts   int
+	Errors     int
+	FirstError error
+	Latency    DurationStats
+}
+
+// StressReport результат нагрузочной проверки связи с хабом
+type StressReport struct {
+	Phases        []StressPhase
+	Duration      time.Duration
+	Disconnected  bool
+	HasSensors    bool // К хабу подключены датчики, которые должны присылать показания
+	Notifications int  // Показаний датчиков за время проверки
+}
+
+// stressCounter копит попытки, ошибки и задержки одной фазы из нескольких горутин
+type stressCounter struct {
+	mu      sync.Mutex
+	phase   StressPhase
+	samples []durationSample
+}
+
+// measure выполняет операцию и учитывает ее результат
+func (c *stressCounter) measure(operation func() error) {
+	started := time.Now()
+	err := operation()
+	elapsed := time.Since(started)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase.Attempts++
+	if err != nil {
+		c.phase.Errors++
+		if c.phase.FirstError == nil {
+			c.phase.FirstError = err
+		}
+		return
+	}
+	c.samples = append(c.samples, durationSample{Duration: elapsed})
+}
+
+// result возвращает итог фазы
+func (c *stressCounter) result() StressPhase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	phase := c.phase
+	phase.Latency = durationStats(c.samples)
+	return phase
+}
+
+// RunStressTest нагружает соединение сценарием: быстрая смена цвета светодиода,
+// включение и остановка моторов, одновременное чтение характеристики под потоком записи.
+// progress получает название фазы и долю выполнения от 0 до 1
+func (hm *HubManager) RunStressTest(ctx context.Context, motorPorts []byte, hasSensors bool,
+	progress func(phase string, fraction float64)) StressReport {
+
+	started := time.Now()
+	notificationsBefore := hm.metrics.Snapshot().Notifications[NotificationSensors]
+	report := StressReport{HasSensors: hasSensors}
+
+	colors := randomLEDColors
+	ledCommand := func(i int) Command {
+		c := colors[i%len(colors)]
+		return NewLEDCommand(hubLEDPort).RGB(c.r, c.g, c.b).From(SourceSystem)
+	}
+
+	phases := []struct {
+		name  string
+		reads bool
+		skip  bool
+		run   func(counter *stressCounter)
+	}{
+		{"Смена цвета светодиода", false, false, func(counter *stressCounter) {
+			counter.measure(func() error {
+				return hm.ConfigurePort(NewInputFormatCommand(hubLEDPort, DEVICE_TYPE_RGB_LIGHT).Mode(LED_DISCRETE_MODE))
+			})
+			for i := 0; i < stressLEDChanges && ctx.Err() == nil; i++ {
+				counter.measure(func() error { return hm.SendCommand(ledCommand(i)) })
+				progress("Смена цвета светодиода", float64(i+1)/stressLEDChanges)
+				time.Sleep(stressStepDelay)
+			}
+		}},
+		{"Включение и остановка моторов", false, len(motorPorts) == 0, func(counter *stressCounter) {
+			for i := 0; i < stressMotorToggles && ctx.Err() == nil; i++ {
+				power := stressMotorPower
+				if i%2 == 1 {
+					power = 0
+				}
+				for _, port := range motorPorts {
+					counter.measure(func() error { return hm.SendCommand(NewMotorCommand(port).Power(power).From(SourceSystem)) })
+				}
+				progress("Включение и остановка моторов", float64(i+1)/stressMotorToggles)
+				time.Sleep(stressStepDelay)
+			}
+			for _, port := range motorPorts {
+				hm.SendCommand(NewMotorCommand(port).Stop().From(SourceSystem))
+			}
+		}},
+		{"Чтение батареи под нагрузкой", true, false, func(counter *stressCounter) {
+			// Читатели работают одновременно друг с другом и с потоком команд светодиоду
+			var readers sync.WaitGroup
+			var readsMu sync.Mutex
+			reads := 0
+			for r := 0; r < stressReaders; r++ {
+				readers.Add(1)
+				go func() {
+					defer readers.Done()
+					for i := 0; i < stressReadsEach && ctx.Err() == nil; i++ {
+						counter.measure(func() error {
+							_, err := hm.ReadCharacteristic(BATTERY_LEVEL_UUID)
+							return err
+						})
+						readsMu.Lock()
+						reads++
+						fraction := float64(reads) / (stressReaders * stressReadsEach)
+						readsMu.Unlock()
+						progress("Чтение батареи под нагрузкой", fraction)
+					}
+				}()
+			}
+
+			finished := make(chan struct{})
+			go func() {
+				readers.Wait()
+				close(finished)
+			}()
+			for i := 0; ; i++ {
+				select {
+				case <-finished:
+					return
+				case <-time.After(stressStepDelay):
+					hm.SendCommand(ledCommand(i))
+				}
+			}
+		}},
+	}
+
+	for _, phase := range phases {
+		if ctx.Err() != nil {
+			break
+		}
+		if !hm.IsConnected() {
+			report.Disconnected = true
+			break
+		}
+		if phase.skip {
+			report.Phases = append(report.Phases, StressPhase{Name: phase.name, Reads: phase.reads})
+			continue
+		}
+
+		log.Printf("Нагрузочная проверка: %s", phase.name)
+		counter := &stressCounter{phase: StressPhase{Name: phase.name, Reads: phase.reads}}
+		phase.run(counter)
+		report.Phases = append(report.Phases, counter.result())
+	}
+	if !hm.IsConnected() {
+		report.Disconnected = true
+	} else {
+		// Зеленый цвет означает, что хаб подключен к приложению
+		hm.SendCommand(NewLEDCommand(hubLEDPort).RGB(0x00, 0xFF, 0x00).From(SourceSystem))
+	}
+
+	report.Duration = time.Since(started)
+	report.Notifications = hm.metrics.Snapshot().Notifications[NotificationSensors] - notificationsBefore
+	return report
+}
+
+// errorsByKind суммирует ошибки записи и чтения
+func (r StressReport) errorsByKind() (writeErrors, readErrors int, slowWrites bool) {
+	for _, phase := range r.Phases {
+		if phase.Reads {
+			readErrors += phase.Errors
+			continue
+		}
+		writeErrors += phase.Errors
+		if phase.Latency.P90 > stressSlowWrite {
+			slowWrites = true
+		}
+	}
+	return writeErrors, readErrors, slowWrites
+}
+
+// Findings выводы проверки: где, скорее всего, источник проблем — в адаптере или в хабе
+func (r StressReport) Findings() []string {
+	writeErrors, readErrors, slowWrites := r.errorsByKind()
+	var findings []string
+
+	if r.Disconnected {
+		findings = append(findings, "Связь оборвалась во время проверки. Если так происходит с разными хабами, "+
+			"причина в адаптере или помехах рядом с компьютером; если только с этим хабом — проверьте его батареи.")
+	}
+	switch {
+	case writeErrors > 0 && readErrors > 0:
+		findings = append(findings, "Ошибки и при записи, и при чтении: вероятнее всего, не справляется адаптер Bluetooth. "+
+			"Попробуйте другой адаптер или подключите хаб ближе к компьютеру.")
+	case writeErrors > 0:
+		findings = append(findings, "Команды теряются, а чтение работает: переполняется очередь записи адаптера. "+
+			"Попробуйте другой адаптер или обновите драйвер Bluetooth.")
+	case readErrors > 0:
+		findings = append(findings, "Команды уходят, но хаб не отвечает на чтение: вероятнее всего, проблема в хабе "+
+			"(разряженные батареи или прошивка).")
+	}
+	if slowWrites {
+		findings = append(findings, fmt.Sprintf("Запись команд медленная (p90 больше %s): адаптер перегружен "+
+			"или рядом много других устройств Bluetooth.", formatLatency(stressSlowWrite)))
+	}
+	if r.HasSensors && r.Notifications == 0 && !r.Disconnected {
+		findings = append(findings, "Команды уходят, но хаб перестал присылать показания датчиков: проблема, скорее всего, в хабе.")
+	}
+	if len(findings) == 0 {
+		findings = append(findings, "Ошибок нет, задержки в норме: связь с хабом стабильна.")
+	}
+	return findings
+}
+
+// String записывает отчет текстом для просмотра и копирования
+func (r StressReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Нагрузочная проверка BLE, длительность %s\n", r.Duration.Round(100*time.Millisecond))
+	for _, phase := range r.Phases {
+		fmt.Fprintf(&b, "\n%s\n", phase.Name)
+		if phase.Attempts == 0 {
+			b.WriteString("  пропущено: нет подходящих устройств\n")
+			continue
+		}
+		rate := float64(phase.Errors) / float64(phase.Attempts) * 100
+		fmt.Fprintf(&b, "  операций: %d, ошибок: %d (%.1f%%)\n", phase.Attempts, phase.Errors, rate)
+		if phase.FirstError != nil {
+			fmt.Fprintf(&b, "  первая ошибка: %v\n", phase.FirstError)
+		}
+		writeStatsText(&b, phase.Latency)
+	}
+	fmt.Fprintf(&b, "\nПоказаний датчиков за время проверки: %d\n", r.Notifications)
+	if r.Disconnected {
+		b.WriteString("Соединение потеряно\n")
+	}
+
+	b.WriteString("\nВыводы\n")
+	for _, finding := range r.Findings() {
+		b.WriteString("  • " + finding + "\n")
+	}
+	return b.String()
+}
+
+// showStressTestDialog предупреждает о нагрузочной проверке и запускает ее
+func (gui *MainGUI) showStressTestDialog() {
+	if !gui.hubMgr.IsConnected() {
+		dialog.ShowInformation("Нагрузочная проверка", "Сначала подключитесь к хабу", gui.window)
+		return
+	}
+	if gui.programMgr.GetProgramState() == ProgramStateRunning {
+		dialog.ShowInformation("Нагрузочная проверка", "Остановите программу перед проверкой", gui.window)
+		return
+	}
+
+	dialog.ShowConfirm("Нагрузочная проверка",
+		"Хаб будет быстро менять цвет светодиода, включать и останавливать моторы\n"+
+			"и одновременно читать данные. Проверка занимает около минуты.\n"+
+			"Уберите модель со стола, чтобы моторы ничего не сдвинули. Начать?",
+		func(confirmed bool) {
+			if confirmed {
+				gui.runStressTest()
+			}
+		}, gui.window)
+}
+
+// runStressTest выполняет проверку в фоне с индикатором и показывает отчет
+func (gui *MainGUI) runStressTest() {
+	var motorPorts []byte
+	hasSensors := false
+	for _, device := range gui.deviceMgr.GetConnectedDevices() {
+		if device.DeviceType == DEVICE_TYPE_MOTOR {
+			motorPorts = append(motorPorts, device.PortID)
+		}
+		if reportsValues(device.DeviceType) {
+			hasSensors = true
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	phaseLabel := widget.NewLabel("Подготовка...")
+	bar := widget.NewProgressBar()
+	progressDialog := dialog.NewCustom("Нагрузочная проверка", "Прервать",
+		container.NewVBox(phaseLabel, bar), gui.window)
+	progressDialog.SetOnClosed(cancel)
+	progressDialog.Resize(fyne.NewSize(420, 0))
+	progressDialog.Show()
+
+	go func() {
+		report := gui.hubMgr.RunStressTest(ctx, motorPorts, hasSensors, func(phase string, fraction float64) {
+			fyne.Do(func() {
+				phaseLabel.SetText(phase)
+				bar.SetValue(fraction)
+			})
+		})
+		interrupted := ctx.Err() != nil
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if interrupted {
+				return
+			}
+			gui.showStressReport(report)
+		})
+	}()
+}
+
+// showStressReport показывает отчет нагрузочной проверки
+func (gui *MainGUI) showStressReport(report StressReport) {
+	window := fyne.CurrentApp().NewWindow("Отчет нагрузочной проверки")
+	text := report.String()
+	label := widget.NewLabelWithStyle(text, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	label.Wrapping = fyne.TextWrapWord
+
+	copyButton := widget.NewButton("Копировать", func() { window.Clipboard().SetContent(text) })
+	window.SetContent(container.NewBorder(nil, container.NewHBox(copyButton), nil, nil, container.NewVScroll(label)))
+	window.Resize(fyne.NewSize(640, 520))
+	window.Show()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bleTraceSize   = 200   // Сколько последних кадров BLE хранить для отчета о сбое
+	bleCaptureSize = 50000 // Сколько кадров BLE можно захватить для записи сеанса
+)
+
+// Направления кадров BLE
+const (
+	FrameOut = "->" // Команда хабу
+	FrameIn  = "<-" // Уведомление от хаба
+)
+
+// bleFrame кадр обмена с хабом
+type bleFrame struct {
+	time      time.Time
+	direction string
+	uuid      string
+	data      []byte
+}
+
+// BLETrace кольцевой буфер последних кадров обмена с хабом
+type BLETrace struct {
+	mu     sync.Mutex
+	frames []bleFrame
+	next   int
+	full   bool
+
+	capturing bool
+	captured  []bleFrame // Все кадры с начала захвата, независимо от кольцевого буфера
+}
+
+// NewBLETrace создает пустой буфер кадров
+func NewBLETrace() *BLETrace {
+	return &BLETrace{frames: make([]bleFrame, bleTraceSize)}
+}
+
+// Record запоминает кадр, вытесняя самый старый
+func (t *BLETrace) Record(direction, uuid string, data []byte) {
+	frame := bleFrame{
+		time:      time.Now(),
+		direction: direction,
+		uuid:      uuid,
+		data:      append([]byte(nil), data...),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.frames[t.next] = frame
+	t.next = (t.next + 1) % len(t.frames)
+	if t.next == 0 {
+		t.full = true
+	}
+	if t.capturing && len(t.captured) < bleCaptureSize {
+		t.captured = append(t.captured, frame)
+	}
+}
+
+// Capture добавляет в идущий захват кадр, которого не было в обмене с хабом,
+// например команду, не отправленную при воспроизведении записи без хаба.
+// В кольцевой буфер такой кадр не попадает: журнал BLE показывает только настоящий обмен
+func (t *BLETrace) Capture(direction, uuid string, data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.capturing && len(t.captured) < bleCaptureSize {
+		t.captured = append(t.captured, bleFrame{
+			time:      time.Now(),
+			direction: direction,
+			uuid:      uuid,
+			data:      append([]byte(nil), data...),
+		})
+	}
+}
+
+// StartCapture начинает захват всех кадров, например на время записи сеанса
+func (t *BLETrace) StartCapture() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.capturing = true
+	t.captured = nil
+}
+
+// StopCapture останавливает захват и возвращает захваченные кадры от старых к новым
+func (t *BLETrace) StopCapture() []bleFrame {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	frames := t.captured
+	t.capturing = false
+	t.captured = nil
+	return frames
+}
+
+// ordered возвращает кадры от старых к новым
+func (t *BLETrace) ordered() []bleFrame {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var frames []bleFrame
+	if t.full {
+		frames = append(frames, t.frames[t.next:]...)
+	}
+	return append(frames, t.frames[:t.next]...)
+}
+
+// String возвращает кадры от старых к новым, по одному в строке
+func (t *BLETrace) String() string {
+	var b strings.Builder
+	for _, frame := range t.ordered() {
+		b.WriteString(frame.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// PortFrames возвращает до limit последних кадров, относящихся к порту, от старых к новым
+func (t *BLETrace) PortFrames(port byte, limit int) []string {
+	var lines []string
+	for _, frame := range t.ordered() {
+		if frame.mentionsPort(port) {
+			lines = append(lines, frame.String())
+		}
+	}
+	if len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines
+}
+
+// String форматирует кадр для журнала
+func (f bleFrame) String() string {
+	return fmt.Sprintf("%s %s %s %x", f.time.Format("15:04:05.000"), f.direction, f.uuid[4:8], f.data)
+}
+
+// mentionsPort проверяет, относится ли кадр к порту хаба
+func (f bleFrame) mentionsPort(port byte) bool {
+	switch f.uuid {
+	case OUTPUT_COMMAND_UUID, PORT_INFO_UUID:
+		return len(f.data) > 0 && f.data[0] == port
+	case INPUT_COMMAND_UUID:
+		return len(f.data) > 2 && f.data[2] == port
+	case SENSOR_VALUES_UUID:
+		for _, reading := range ParseSensorValues(f.data) {
+			if reading.PortID == port {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+)
+
+// largeControlsScale во сколько раз крупнее элементы в окне редактора блока
+const largeControlsScale = 1.35
+
+// largeControlsTheme тема с увеличенными текстом, отступами и значками
+// для окна редактора блока: ползунками и списками удобно пользоваться на
+// маленьком экране и сенсорной панели
+type largeControlsTheme struct {
+	fyne.Theme
+}
+
+var _ fyne.Theme = (*largeControlsTheme)(nil)
+
+// Size возвращает увеличенный размер элемента
+func (t *largeControlsTheme) Size(name fyne.ThemeSizeName) float32 {
+	size := t.Theme.Size(name)
+	switch name {
+	case theme.SizeNameText, theme.SizeNameSubHeadingText, theme.SizeNameHeadingText,
+		theme.SizeNameCaptionText, theme.SizeNamePadding, theme.SizeNameInnerPadding,
+		theme.SizeNameInlineIcon, theme.SizeNameLineSpacing:
+		return size * largeControlsScale
+	default:
+		return size
+	}
+}
+
+// showBlockEditorDialog открывает редактор блока в отдельном окне с крупными
+// элементами. Изменения применяются сразу, как в панели свойств
+func (gui *MainGUI) showBlockEditorDialog(block *ProgramBlock) {
+	if gui.readOnly() {
+		return
+	}
+
+	editor := NewBlockEditor(block, gui.deviceMgr, gui.programMgr, gui.window, func(updatedBlock *ProgramBlock) {
+		gui.programMgr.UpdateBlock(updatedBlock.ID, updatedBlock.Parameters)
+		log.Printf("Параметры блока %d обновлены", updatedBlock.ID)
+		gui.refreshToolbarState()
+	})
+	content := container.NewThemeOverride(
+		container.NewVScroll(container.NewPadded(editor.GetContainer())),
+		&largeControlsTheme{Theme: fyne.CurrentApp().Settings().Theme()},
+	)
+
+	d := dialog.NewCustom(fmt.Sprintf("%s (ID: %d)", block.Title, block.ID), "Готово", content, gui.window)
+	d.SetOnClosed(func() {
+		editor.Close()
+		// Панель свойств показывает значения, выбранные в окне
+		if gui.selectedBlock == block {
+			gui.showBlockProperties(block)
+		}
+	})
+
+	windowSize := gui.window.Canvas().Size()
+	d.Resize(fyne.NewSize(fyne.Min(720, windowSize.Width*0.9), fyne.Min(640, windowSize.Height*0.9)))
+	d.Show()
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// BlockGroup именованная группа последовательных блоков. Группа только
+// визуальная: на выполнение программы она не влияет.
+type BlockGroup struct {
+	ID        int
+	Name      string
+	BlockIDs  []int
+	Collapsed bool
+}
+
+// Размеры группы на холсте
+const (
+	groupCollapsedWidth  = 150
+	groupCollapsedHeight = 50
+	groupPadding         = 10
+	groupHeaderHeight    = 22
+)
+
+// CreateGroup объединяет блоки в новую группу
+func (pm *ProgramManager) CreateGroup(name string, blockIDs []int) *BlockGroup {
+	nextID := 1
+	for _, group := range pm.program.Groups {
+		if group.ID >= nextID {
+			nextID = group.ID + 1
+		}
+	}
+
+	group := &BlockGroup{ID: nextID, Name: name, BlockIDs: blockIDs}
+	pm.program.Groups = append(pm.program.Groups, group)
+	pm.program.Modified = time.Now()
+
+	log.Printf("Создана группа %q из %d блок(ов)", name, len(blockIDs))
+	return group
+}
+
+// RemoveGroup расформировывает группу, не трогая ее блоки
+func (pm *ProgramManager) RemoveGroup(groupID int) bool {
+	for i, group := range pm.program.Groups {
+		if group.ID == groupID {
+			pm.program.Groups = append(pm.program.Groups[:i], pm.program.Groups[i+1:]...)
+			pm.program.Modified = time.Now()
+			return true
+		}
+	}
+	return false
+}
+
+// GroupOf возвращает группу, в которую входит блок
+func (pm *ProgramManager) GroupOf(blockID int) *BlockGroup {
+	for _, group := range pm.program.Groups {
+		for _, id := range group.BlockIDs {
+			if id == blockID {
+				return group
+			}
+		}
+	}
+	return nil
+}
+
+// removeBlockFromGroups исключает удаленный блок из групп и убирает опустевшие группы
+func (pm *ProgramManager) removeBlockFromGroups(blockID int) {
+	var groups []*BlockGroup
+	for _, group := range pm.program.Groups {
+		var ids []int
+		for _, id := range group.BlockIDs {
+			if id != blockID {
+				ids = append(ids, id)
+			}
+		}
+		group.BlockIDs = ids
+		if len(ids) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	pm.program.Groups = groups
+}
+
+// groupableChain возвращает блоки, идущие по цепочке начиная с blockID и еще не входящие в группы
+func (pm *ProgramManager) groupableChain(blockID int) []*ProgramBlock {
+	var chain []*ProgramBlock
+	visited := make(map[int]bool)
+	for block, ok := pm.GetBlock(blockID); ok && !visited[block.ID]; block, ok = pm.GetBlock(block.NextBlockID) {
+		if pm.GroupOf(block.ID) != nil {
+			break
+		}
+		visited[block.ID] = true
+		chain = append(chain, block)
+	}
+	return chain
+}
+
+// GroupWidget группа блоков на холсте. Свернутая группа выглядит как один
+// компактный блок, развернутая — как рамка с заголовком вокруг своих блоков.
+type GroupWidget struct {
+	widget.BaseWidget
+
+	group  *BlockGroup
+	panel  *ProgramPanel
+	frame  *canvas.Rectangle
+	title  *canvas.Text
+	count  *canvas.Text
+	layout *fyne.Container
+
+	dragging bool
+}
+
+// NewGroupWidget создает виджет группы
+func NewGroupWidget(group *BlockGroup, panel *ProgramPanel) *GroupWidget {
+	g := &GroupWidget{
+		group: group,
+		panel: panel,
+		frame: canvas.NewRectangle(color.Transparent),
+		title: canvas.NewText("", color.White),
+		count: canvas.NewText("", color.NRGBA{R: 200, G: 200, B: 200, A: 255}),
+	}
+	g.frame.CornerRadius = 8
+	g.title.TextStyle.Bold = true
+	g.title.TextSize = 13
+	g.count.TextSize = 10
+	g.layout = container.NewWithoutLayout(g.frame, g.title, g.count)
+
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// members возвращает виджеты блоков группы
+func (g *GroupWidget) members() []*DraggableBlock {
+	var members []*DraggableBlock
+	for _, id := range g.group.BlockIDs {
+		if blockWidget, exists := g.panel.blockWidgets[id]; exists {
+			members = append(members, blockWidget)
+		}
+	}
+	return members
+}
+
+// rect возвращает прямоугольник, занимаемый группой на холсте
+func (g *GroupWidget) rect() blockRect {
+	pos := g.Position()
+	size := g.Size()
+	return blockRect{Min: pos, Max: pos.AddXY(size.Width, size.Height)}
+}
+
+// update пересчитывает положение группы по ее блокам и показывает или скрывает блоки
+func (g *GroupWidget) update() {
+	members := g.members()
+	if len(members) == 0 {
+		g.Hide()
+		return
+	}
+
+	g.title.Text = g.group.Name
+	g.count.Text = fmt.Sprintf("%d блок(ов)", len(members))
+
+	if g.group.Collapsed {
+		for _, member := range members {
+			member.Hide()
+		}
+
+		g.Move(members[0].Position())
+		g.Resize(fyne.NewSize(groupCollapsedWidth, groupCollapsedHeight))
+		g.frame.FillColor = color.NRGBA{R: 69, G: 90, B: 100, A: 255}
+		g.frame.StrokeColor = color.NRGBA{R: 144, G: 164, B: 174, A: 255}
+		g.frame.StrokeWidth = 1
+		g.title.Text = "▸ " + g.group.Name
+		g.title.Color = color.White
+		g.count.Show()
+	} else {
+		bounds := newBlockRect(members[0])
+		for _, member := range members {
+			member.Show()
+			r := newBlockRect(member)
+			bounds.Min = fyne.NewPos(fyne.Min(bounds.Min.X, r.Min.X), fyne.Min(bounds.Min.Y, r.Min.Y))
+			bounds.Max = fyne.NewPos(fyne.Max(bounds.Max.X, r.Max.X), fyne.Max(bounds.Max.Y, r.Max.Y))
+		}
+
+		origin := bounds.Min.SubtractXY(groupPadding, groupPadding+groupHeaderHeight)
+		g.Move(origin)
+		g.Resize(fyne.NewSize(
+			bounds.Max.X-bounds.Min.X+2*groupPadding,
+			bounds.Max.Y-bounds.Min.Y+2*groupPadding+groupHeaderHeight,
+		))
+		g.frame.FillColor = color.NRGBA{R: 255, G: 255, B: 255, A: 12}
+		g.frame.StrokeColor = color.NRGBA{R: 144, G: 164, B: 174, A: 200}
+		g.frame.StrokeWidth = 1.5
+		g.title.Text = "▾ " + g.group.Name
+		g.title.Color = g.panel.style.text
+		g.count.Hide()
+	}
+
+	g.Show()
+	g.Refresh()
+}
+
+// toggle сворачивает или разворачивает группу
+func (g *GroupWidget) toggle() {
+	g.group.Collapsed = !g.group.Collapsed
+	g.panel.refreshGroups()
+}
+
+// Tapped сворачивает развернутую группу по клику на заголовок и разворачивает свернутую по клику
+func (g *GroupWidget) Tapped(e *fyne.PointEvent) {
+	if g.group.Collapsed || e.Position.Y <= groupHeaderHeight {
+		g.toggle()
+	}
+}
+
+// TappedSecondary показывает контекстное меню группы
+func (g *GroupWidget) TappedSecondary(e *fyne.PointEvent) {
+	toggleLabel := "Свернуть"
+	if g.group.Collapsed {
+		toggleLabel = "Развернуть"
+	}
+
+	renameItem := fyne.NewMenuItem("Переименовать", func() {
+		g.panel.gui.showGroupNameDialog("Переименовать группу", g.group.Name, func(name string) {
+			g.group.Name = name
+			g.update()
+		})
+	})
+	ungroupItem := fyne.NewMenuItem("Разгруппировать", func() {
+		g.panel.ungroup(g.group)
+	})
+	renameItem.Disabled = g.panel.gui.readOnly()
+	ungroupItem.Disabled = g.panel.gui.readOnly()
+
+	menu := fyne.NewMenu("",
+		fyne.NewMenuItem(toggleLabel, g.toggle),
+		renameItem,
+		ungroupItem,
+	)
+	widget.ShowPopUpMenuAtPosition(menu, g.panel.gui.window.Canvas(), e.AbsolutePosition)
+}
+
+// Dragged перемещает свернутую группу вместе со всеми ее блоками
+func (g *GroupWidget) Dragged(e *fyne.DragEvent) {
+	if !g.group.Collapsed || g.panel.gui.readOnly() {
+		return
+	}
+
+	g.dragging = true
+	delta := fyne.NewPos(e.Dragged.DX, e.Dragged.DY)
+	for _, member := range g.members() {
+		pos := member.Position().Add(delta)
+		member.Move(pos)
+		member.block.X, member.block.Y = float64(pos.X), float64(pos.Y)
+		member.block.DragStartPos = pos
+	}
+	g.Move(g.Position().Add(delta))
+	g.panel.scheduleConnectionUpdate(g.group.BlockIDs[0])
+}
+
+// DragEnd сохраняет новые позиции блоков группы
+func (g *GroupWidget) DragEnd() {
+	if !g.dragging {
+		return
+	}
+	for _, member := range g.members() {
+		g.panel.programMgr.UpdateBlockPosition(member.block.ID, member.block.X, member.block.Y)
+	}
+	g.dragging = false
+}
+
+// CreateRenderer создает рендерер группы
+func (g *GroupWidget) CreateRenderer() fyne.WidgetRenderer {
+	return &groupWidgetRenderer{group: g}
+}
+
+// groupWidgetRenderer рендерер группы блоков
+type groupWidgetRenderer struct {
+	group *GroupWidget
+}
+
+func (r *groupWidgetRenderer) Layout(size fyne.Size) {
+	g := r.group
+	g.layout.Resize(size)
+	g.frame.Resize(size)
+
+	titleSize := g.title.MinSize()
+	if g.group.Collapsed {
+		countSize := g.count.MinSize()
+		top := (size.Height - titleSize.Height - countSize.Height) / 2
+		g.title.Move(fyne.NewPos((size.Width-titleSize.Width)/2, top))
+		g.count.Move(fyne.NewPos((size.Width-countSize.Width)/2, top+titleSize.Height))
+	} else {
+		g.title.Move(fyne.NewPos(groupPadding, (groupHeaderHeight-titleSize.Height)/2+groupPadding/2))
+	}
+}
+
+func (r *groupWidgetRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(groupCollapsedWidth, groupCollapsedHeight)
+}
+
+func (r *groupWidgetRenderer) Refresh() {
+	r.Layout(r.group.Size())
+	r.group.frame.Refresh()
+	r.group.title.Refresh()
+	r.group.count.Refresh()
+}
+
+func (r *groupWidgetRenderer) Destroy() {}
+
+func (r *groupWidgetRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.group.layout}
+}
+
+// endpointRect возвращает прямоугольник, к которому подходит соединение блока,
+// и ключ видимого объекта: ID блока или минус ID свернутой группы
+func (p *ProgramPanel) endpointRect(blockID int) (blockRect, int, bool) {
+	for _, groupWidget := range p.groupWidgets {
+		if !groupWidget.group.Collapsed {
+			continue
+		}
+		for _, id := range groupWidget.group.BlockIDs {
+			if id == blockID {
+				return groupWidget.rect(), -groupWidget.group.ID, true
+			}
+		}
+	}
+
+	blockWidget, exists := p.blockWidgets[blockID]
+	if !exists {
+		return blockRect{}, 0, false
+	}
+	return newBlockRect(blockWidget), blockID, true
+}
+
+// refreshGroups приводит виджеты групп в соответствие с группами программы
+func (p *ProgramPanel) refreshGroups() {
+	groups := make(map[int]bool)
+	for _, group := range p.programMgr.GetProgram().Groups {
+		groups[group.ID] = true
+		if _, exists := p.groupWidgets[group.ID]; !exists {
+			groupWidget := NewGroupWidget(group, p)
+			p.groupWidgets[group.ID] = groupWidget
+			p.addAboveGrid(groupWidget)
+		}
+	}
+
+	for id, groupWidget := range p.groupWidgets {
+		if !groups[id] {
+			for _, member := range groupWidget.members() {
+				member.Show()
+			}
+			p.removeObject(groupWidget)
+			delete(p.groupWidgets, id)
+		}
+	}
+
+	p.updateGroups()
+	p.updateConnections()
+	p.content.Refresh()
+}
+
+// updateGroups пересчитывает рамки групп после перемещения блоков
+func (p *ProgramPanel) updateGroups() {
+	for _, groupWidget := range p.groupWidgets {
+		groupWidget.update()
+	}
+}
+
+// removeObject убирает объект с холста
+func (p *ProgramPanel) removeObject(obj fyne.CanvasObject) {
+	for i, o := range p.content.Objects {
+		if o == obj {
+			p.content.Objects = append(p.content.Objects[:i], p.content.Objects[i+1:]...)
+			return
+		}
+	}
+}
+
+// groupFrom объединяет count блоков цепочки, начиная с blockID, в группу с именем name
+func (p *ProgramPanel) groupFrom(blockID, count int, name string) {
+	chain := p.programMgr.groupableChain(blockID)
+	if count > len(chain) {
+		count = len(chain)
+	}
+	if count == 0 {
+		return
+	}
+
+	p.gui.checkpoint()
+	ids := make([]int, count)
+	for i, block := range chain[:count] {
+		ids[i] = block.ID
+	}
+	p.programMgr.CreateGroup(name, ids)
+	p.refreshGroups()
+}
+
+// ungroup расформировывает группу
+func (p *ProgramPanel) ungroup(group *BlockGroup) {
+	p.gui.checkpoint()
+	p.programMgr.RemoveGroup(group.ID)
+	p.refreshGroups()
+	log.Printf("Группа %q расформирована", group.Name)
+}
+
+// showGroupDialog предлагает сгруппировать блоки, начиная с выбранного
+func (gui *MainGUI) showGroupDialog(block *ProgramBlock) {
+	chain := gui.programMgr.groupableChain(block.ID)
+	if len(chain) == 0 {
+		return
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(fmt.Sprintf("Группа %d", len(gui.programMgr.GetProgram().Groups)+1))
+
+	counts := make([]string, len(chain))
+	for i := range chain {
+		counts[i] = strconv.Itoa(i + 1)
+	}
+	countSelect := widget.NewSelect(counts, nil)
+	countSelect.SetSelected(counts[len(counts)-1])
+
+	countItem := widget.NewFormItem("Блоков", countSelect)
+	countItem.HintText = "Блоки берутся по цепочке, начиная с " + block.Title
+
+	dialog.ShowForm("Сгруппировать блоки", "Сгруппировать", "Отмена",
+		[]*widget.FormItem{widget.NewFormItem("Название", nameEntry), countItem},
+		func(confirmed bool) {
+			if !confirmed || nameEntry.Text == "" {
+				return
+			}
+			count, _ := strconv.Atoi(countSelect.Selected)
+			gui.programPanel.groupFrom(block.ID, count, nameEntry.Text)
+		}, gui.window)
+}
+
+// showGroupNameDialog запрашивает новое название группы
+func (gui *MainGUI) showGroupNameDialog(title, current string, onName func(name string)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(current)
+
+	dialog.ShowForm(title, "OK", "Отмена",
+		[]*widget.FormItem{widget.NewFormItem("Название", nameEntry)},
+		func(confirmed bool) {
+			if confirmed && nameEntry.Text != "" {
+				onName(nameEntry.Text)
+			}
+		}, gui.window)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// BlockExecution сведения о выполнении блока для наблюдателей
+type BlockExecution struct {
+	BlockID    int
+	Type       BlockType
+	Title      string
+	Parameters map[string]interface{} // Копия параметров на момент запуска блока
+	Started    time.Time
+	Duration   time.Duration // Заполняется после выполнения
+	Err        error         // Заполняется после выполнения
+	Scheduled  bool          // Блок выполнен по расписанию: Duration — рассчитанная, а не измеренная длительность
+}
+
+// BlockHook наблюдатель выполнения блоков. Before вызывается перед действием
+// блока, After — после него с длительностью и результатом. Обработчики
+// вызываются в горутинах цепочек и не должны надолго задерживать выполнение
+type BlockHook struct {
+	Before func(execution BlockExecution)
+	After  func(execution BlockExecution)
+}
+
+// blockHooks подписанные наблюдатели выполнения блоков
+type blockHooks struct {
+	mu     sync.RWMutex
+	hooks  map[int]BlockHook
+	nextID int
+}
+
+// AddBlockHook подписывает наблюдателя на выполнение блоков и возвращает функцию отписки.
+// Шкала времени, замеры блоков и будущие расширения получают одни и те же сведения
+func (pm *ProgramManager) AddBlockHook(hook BlockHook) func() {
+	pm.hooks.mu.Lock()
+	defer pm.hooks.mu.Unlock()
+
+	if pm.hooks.hooks == nil {
+		pm.hooks.hooks = make(map[int]BlockHook)
+	}
+	pm.hooks.nextID++
+	id := pm.hooks.nextID
+	pm.hooks.hooks[id] = hook
+
+	return func() {
+		pm.hooks.mu.Lock()
+		defer pm.hooks.mu.Unlock()
+		delete(pm.hooks.hooks, id)
+	}
+}
+
+// snapshot возвращает подписанных наблюдателей
+func (h *blockHooks) snapshot() []BlockHook {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hooks := make([]BlockHook, 0, len(h.hooks))
+	for _, hook := range h.hooks {
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// beforeBlock сообщает наблюдателям о начале блока и возвращает сведения для afterBlock
+func (pm *ProgramManager) beforeBlock(block *ProgramBlock) BlockExecution {
+	execution := BlockExecution{
+		BlockID:    block.ID,
+		Type:       block.Type,
+		Title:      block.Title,
+		Parameters: make(map[string]interface{}, len(block.Parameters)),
+		Started:    time.Now(),
+	}
+	for key, value := range block.Parameters {
+		execution.Parameters[key] = value
+	}
+	for _, hook := range pm.hooks.snapshot() {
+		if hook.Before != nil {
+			hook.Before(execution)
+		}
+	}
+	return execution
+}
+
+// afterBlock сообщает наблюдателям о завершении блока
+func (pm *ProgramManager) afterBlock(execution BlockExecution) {
+	for _, hook := range pm.hooks.snapshot() {
+		if hook.After != nil {
+			hook.After(execution)
+		}
+	}
+}
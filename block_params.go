@@ -0,0 +1,257 @@
+package main
+
+import "math"
+
+// BlockParams типизированные параметры блока. Parameters остается хранилищем
+// (его сохраняет формат программы, правит редактор, к нему привязываются
+// датчики и задания), а выполнение и проверка читают параметры через эти
+// структуры. Значение неверного типа или вне диапазона, например float64 на
+// месте byte, заменяется значением по умолчанию вместо паники
+type BlockParams interface {
+	// Store записывает параметры в Parameters блока
+	Store(params map[string]interface{})
+}
+
+var (
+	_ BlockParams = MotorParams{}
+	_ BlockParams = LEDParams{}
+	_ BlockParams = SoundParams{}
+	_ BlockParams = WaitParams{}
+	_ BlockParams = SensorParams{}
+	_ BlockParams = LightMusicParams{}
+)
+
+// MotorParams параметры блока «Мотор»
+type MotorParams struct {
+	Port     byte
+	Power    int8
+	Duration uint16 // Миллисекунды, 0 — работать до остановки
+	Random   bool   // Мощность выбирается случайно из PowerMin..PowerMax
+	PowerMin int8
+	PowerMax int8
+}
+
+// defaultMotorParams параметры нового блока «Мотор»
+var defaultMotorParams = MotorParams{Port: 1, Power: 50, Duration: 1000, PowerMin: 20, PowerMax: 80}
+
+// motorFromParameters читает параметры блока «Мотор»
+func motorFromParameters(params map[string]interface{}) MotorParams {
+	p := defaultMotorParams
+	p.Port = paramByte(params, "port", p.Port)
+	p.Power = paramInt8(params, "power", p.Power)
+	p.Duration = paramUint16(params, "duration", p.Duration)
+	p.Random = paramBool(params, "random", p.Random)
+	p.PowerMin = paramInt8(params, "power_min", p.PowerMin)
+	p.PowerMax = paramInt8(params, "power_max", p.PowerMax)
+	return p
+}
+
+// Store записывает параметры мотора
+func (p MotorParams) Store(params map[string]interface{}) {
+	params["port"] = p.Port
+	params["power"] = p.Power
+	params["duration"] = p.Duration
+	params["random"] = p.Random
+	params["power_min"] = p.PowerMin
+	params["power_max"] = p.PowerMax
+}
+
+// LEDParams параметры блока «Светодиод»
+type LEDParams struct {
+	Port   byte
+	Red    byte
+	Green  byte
+	Blue   byte
+	Random bool // Цвет выбирается случайно из randomLEDColors
+}
+
+// defaultLEDParams параметры нового блока «Светодиод»
+var defaultLEDParams = LEDParams{Port: 6, Red: 255}
+
+// ledFromParameters читает параметры блока «Светодиод»
+func ledFromParameters(params map[string]interface{}) LEDParams {
+	p := defaultLEDParams
+	p.Port = paramByte(params, "port", p.Port)
+	p.Red = paramByte(params, "red", p.Red)
+	p.Green = paramByte(params, "green", p.Green)
+	p.Blue = paramByte(params, "blue", p.Blue)
+	p.Random = paramBool(params, "random", p.Random)
+	return p
+}
+
+// Store записывает параметры светодиода
+func (p LEDParams) Store(params map[string]interface{}) {
+	params["port"] = p.Port
+	params["red"] = p.Red
+	params["green"] = p.Green
+	params["blue"] = p.Blue
+	params["random"] = p.Random
+}
+
+// SoundParams параметры блока «Звук»
+type SoundParams struct {
+	Port      byte
+	Frequency uint16 // Гц
+	Duration  uint16 // Миллисекунды
+}
+
+// defaultSoundParams параметры нового блока «Звук»
+var defaultSoundParams = SoundParams{Port: 1, Frequency: 440, Duration: 1000}
+
+// soundFromParameters читает параметры блока «Звук»
+func soundFromParameters(params map[string]interface{}) SoundParams {
+	p := defaultSoundParams
+	p.Port = paramByte(params, "port", p.Port)
+	p.Frequency = paramUint16(params, "frequency", p.Frequency)
+	p.Duration = paramUint16(params, "duration", p.Duration)
+	return p
+}
+
+// Store записывает параметры звука
+func (p SoundParams) Store(params map[string]interface{}) {
+	params["port"] = p.Port
+	params["frequency"] = p.Frequency
+	params["duration"] = p.Duration
+}
+
+// WaitParams параметры блока «Ждать»
+type WaitParams struct {
+	Duration    float64 // Секунды
+	Random      bool    // Пауза выбирается случайно из DurationMin..DurationMax
+	DurationMin float64
+	DurationMax float64
+}
+
+// defaultWaitParams параметры нового блока «Ждать»
+var defaultWaitParams = WaitParams{Duration: 1.0, DurationMin: 0.5, DurationMax: 3.0}
+
+// waitFromParameters читает параметры блока «Ждать»
+func waitFromParameters(params map[string]interface{}) WaitParams {
+	p := defaultWaitParams
+	p.Duration = paramFloat(params, "duration", p.Duration)
+	p.Random = paramBool(params, "random", p.Random)
+	p.DurationMin = paramFloat(params, "duration_min", p.DurationMin)
+	p.DurationMax = paramFloat(params, "duration_max", p.DurationMax)
+	return p
+}
+
+// Store записывает параметры паузы
+func (p WaitParams) Store(params map[string]interface{}) {
+	params["duration"] = p.Duration
+	params["random"] = p.Random
+	params["duration_min"] = p.DurationMin
+	params["duration_max"] = p.DurationMax
+}
+
+// SensorParams параметры блоков датчиков наклона и расстояния
+type SensorParams struct {
+	Port byte
+	Mode byte // Режим датчика из NewInputFormatCommand
+}
+
+// Параметры новых блоков датчиков
+var (
+	defaultTiltParams     = SensorParams{Port: 1, Mode: 1}
+	defaultDistanceParams = SensorParams{Port: 1, Mode: 0}
+)
+
+// sensorFromParameters читает параметры блока датчика; defaults — параметры
+// нового блока этого датчика
+func sensorFromParameters(params map[string]interface{}, defaults SensorParams) SensorParams {
+	p := defaults
+	p.Port = paramByte(params, "port", p.Port)
+	p.Mode = paramByte(params, "mode", p.Mode)
+	return p
+}
+
+// Store записывает параметры датчика
+func (p SensorParams) Store(params map[string]interface{}) {
+	params["port"] = p.Port
+	params["mode"] = p.Mode
+}
+
+// paramNumber возвращает числовое значение параметра любого числового типа
+func paramNumber(params map[string]interface{}, key string) (float64, bool) {
+	switch value := params[key].(type) {
+	case byte:
+		return float64(value), true
+	case int8:
+		return float64(value), true
+	case uint16:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return 0, false
+		}
+		return value, true
+	default:
+		return 0, false
+	}
+}
+
+// paramByte читает параметр 0..255
+func paramByte(params map[string]interface{}, key string, fallback byte) byte {
+	number, ok := paramNumber(params, key)
+	if !ok || number < 0 || number > math.MaxUint8 {
+		return fallback
+	}
+	return byte(number)
+}
+
+// paramInt8 читает параметр -128..127
+func paramInt8(params map[string]interface{}, key string, fallback int8) int8 {
+	number, ok := paramNumber(params, key)
+	if !ok || number < math.MinInt8 || number > math.MaxInt8 {
+		return fallback
+	}
+	return int8(number)
+}
+
+// paramUint16 читает параметр 0..65535
+func paramUint16(params map[string]interface{}, key string, fallback uint16) uint16 {
+	number, ok := paramNumber(params, key)
+	if !ok || number < 0 || number > math.MaxUint16 {
+		return fallback
+	}
+	return uint16(number)
+}
+
+// paramInt читает целый параметр
+func paramInt(params map[string]interface{}, key string, fallback int) int {
+	number, ok := paramNumber(params, key)
+	if !ok || number < math.MinInt32 || number > math.MaxInt32 {
+		return fallback
+	}
+	return int(number)
+}
+
+// paramFloat читает дробный параметр
+func paramFloat(params map[string]interface{}, key string, fallback float64) float64 {
+	number, ok := paramNumber(params, key)
+	if !ok {
+		return fallback
+	}
+	return number
+}
+
+// paramBool читает флажок
+func paramBool(params map[string]interface{}, key string, fallback bool) bool {
+	flag, ok := params[key].(bool)
+	if !ok {
+		return fallback
+	}
+	return flag
+}
+
+// paramString читает строковый параметр
+func paramString(params map[string]interface{}, key string, fallback string) string {
+	text, ok := params[key].(string)
+	if !ok {
+		return fallback
+	}
+	return text
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+// Числа из вручную исправленного файла или старой миграции приходят как float64 или int
+func TestParameterReadersCoerceNumbers(t *testing.T) {
+	cond := conditionFromParameters(map[string]interface{}{
+		"sensor":  ConditionSensorTilt,
+		"port":    float64(2),
+		"value":   3,
+		"timeout": 1,
+	})
+	if cond.Port != 2 || cond.Value != 3 || cond.Timeout != 1 || cond.Sensor != ConditionSensorTilt {
+		t.Errorf("условие прочитано как %+v", cond)
+	}
+
+	if policy := errorPolicyFromParameters(map[string]interface{}{"retries": float64(4)}); policy.Retries != 4 {
+		t.Errorf("число повторов %d, ожидалось 4", policy.Retries)
+	}
+
+	follow := followFromParameters(map[string]interface{}{"motor_port": 2, "sensor_port": float64(1), "gain": 3})
+	if follow.MotorPort != 2 || follow.SensorPort != 1 || follow.Gain != 3 {
+		t.Errorf("регулятор прочитан как %+v", follow)
+	}
+}
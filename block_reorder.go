@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"fyne.io/fyne/v2"
+)
+
+// predecessor возвращает блок, после которого выполняется blockID
+func (pm *ProgramManager) predecessor(blockID int) *ProgramBlock {
+	for _, block := range pm.program.Blocks {
+		if block.NextBlockID == blockID {
+			return block
+		}
+	}
+	return nil
+}
+
+// chainOf возвращает цепочку, в которой стоит блок, начиная с ее первого блока
+func (pm *ProgramManager) chainOf(blockID int) []*ProgramBlock {
+	root := pm.findBlockByID(blockID)
+	if root == nil {
+		return nil
+	}
+	visited := map[int]bool{root.ID: true}
+	for prev := pm.predecessor(root.ID); prev != nil && !visited[prev.ID]; prev = pm.predecessor(prev.ID) {
+		visited[prev.ID] = true
+		root = prev
+	}
+	chain, _ := pm.buildSequence(root)
+	return chain
+}
+
+// MoveBlockAfter переставляет блок цепочки так, чтобы он выполнялся сразу после
+// блока afterBlockID: соседи блока соединяются напрямую, а сам блок
+// вставляется в соединение после afterBlockID. Первый блок цепочки не переставляется
+func (pm *ProgramManager) MoveBlockAfter(blockID, afterBlockID int) bool {
+	block, exists := pm.GetBlock(blockID)
+	after, afterExists := pm.GetBlock(afterBlockID)
+	if !exists || !afterExists || blockID == afterBlockID || after.NextBlockID == blockID {
+		return false
+	}
+	prev := pm.predecessor(blockID)
+	if prev == nil {
+		return false
+	}
+
+	next := block.NextBlockID
+	pm.RemoveConnection(prev.ID)
+	pm.RemoveConnection(blockID)
+	if next > 0 {
+		pm.AddConnection(prev.ID, next)
+	}
+
+	tail := after.NextBlockID
+	if tail > 0 {
+		pm.RemoveConnection(afterBlockID)
+	}
+	pm.AddConnection(afterBlockID, blockID)
+	if tail > 0 {
+		pm.AddConnection(blockID, tail)
+	}
+	return true
+}
+
+// reorderDropped переставляет брошенный блок в цепочке, если его перетащили
+// по вертикали мимо соседей: блок встает после последнего блока цепочки,
+// который оказался выше него, и цепочка выстраивается заново. Возвращает
+// false, если порядок не изменился и перетаскивание было просто перемещением
+func (p *ProgramPanel) reorderDropped(d *DraggableBlock, startPos fyne.Position) bool {
+	chain := p.programMgr.chainOf(d.block.ID)
+	if len(chain) < 3 || chain[0] == d.block || p.programMgr.GroupOf(d.block.ID) != nil {
+		return false
+	}
+
+	// Блок должен остаться над колонкой цепочки, иначе это перенос в сторону
+	head := chain[0]
+	left, right := head.X, head.X+head.Width
+	center := d.block.Y + d.block.Height/2
+	var after *ProgramBlock
+	for _, block := range chain {
+		if block == d.block {
+			continue
+		}
+		left, right = math.Min(left, block.X), math.Max(right, block.X+block.Width)
+		if after == nil || block.Y+block.Height/2 < center {
+			after = block
+		}
+	}
+	if d.block.X+d.block.Width < left || d.block.X > right {
+		return false
+	}
+	current := p.programMgr.predecessor(d.block.ID)
+	if after == nil || current == nil || after == current {
+		return false
+	}
+
+	// Шаг отмены возвращает блок на место, откуда его взяли
+	dropped := fyne.NewPos(float32(d.block.X), float32(d.block.Y))
+	d.block.X, d.block.Y = float64(startPos.X), float64(startPos.Y)
+	p.gui.checkpoint()
+	d.block.X, d.block.Y = float64(dropped.X), float64(dropped.Y)
+
+	if !p.programMgr.MoveBlockAfter(d.block.ID, after.ID) {
+		return false
+	}
+	p.rebuildConnections()
+	if p.selectedBlock == d.block {
+		p.HighlightConnections(d.block.ID)
+	}
+	p.animateLayout(chainLayout(p.programMgr.chainOf(head.ID)))
+
+	log.Printf("Блок %d переставлен после блока %d", d.block.ID, after.ID)
+	return true
+}
+
+// chainLayout позиции блоков цепочки сверху вниз от ее первого блока
+// с отступами тел циклов и условий, как при выравнивании программы
+func chainLayout(chain []*ProgramBlock) map[int]fyne.Position {
+	targets := make(map[int]fyne.Position, len(chain))
+	if len(chain) == 0 {
+		return targets
+	}
+	depths := chainDepths(chain)
+	x, y := chain[0].X, chain[0].Y
+	for i, block := range chain {
+		targets[block.ID] = fyne.NewPos(float32(x+float64(depths[i])*layoutIndent), float32(y))
+		y += block.Height + layoutRowGap
+	}
+	return targets
+}
+
+// rebuildConnections заново рисует соединения холста по соединениям программы
+func (p *ProgramPanel) rebuildConnections() {
+	for len(p.connections) > 0 {
+		p.removeConnection(p.connections[0])
+	}
+	for _, conn := range p.programMgr.GetProgram().Connections {
+		p.createVisualConnection(conn.FromBlockID, conn.ToBlockID)
+	}
+	p.content.Refresh()
+}
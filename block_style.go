@@ -0,0 +1,284 @@
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// BlockCategory категория блоков палитры
+type BlockCategory string
+
+const (
+	CategoryControl BlockCategory = "control"
+	CategoryAction  BlockCategory = "action"
+	CategorySensor  BlockCategory = "sensor"
+	CategoryLogic   BlockCategory = "logic"
+	CategoryEvents  BlockCategory = "events"
+)
+
+// blockCategories категории палитры и входящие в них блоки
+var blockCategories = []struct {
+	Key    BlockCategory
+	Name   string
+	Blocks []BlockType
+}{
+	{CategoryControl, "Управление", []BlockType{BlockTypeStart, BlockTypeWait, BlockTypeLoop, BlockTypeStop, BlockTypeResetTimer, BlockTypeHubPower}},
+	{CategoryAction, "Действия", []BlockType{BlockTypeMotor, BlockTypeLED, BlockTypeSound, BlockTypeLightMusic, BlockTypeScreen, BlockTypeFollow}},
+	{CategorySensor, "Датчики", []BlockType{BlockTypeTiltSensor, BlockTypeDistanceSensor, BlockTypeVoltageSensor, BlockTypeCurrentSensor}},
+	{CategoryLogic, "Логика", []BlockType{BlockTypeCondition, BlockTypeWaitUntil}},
+	{CategoryEvents, "События", []BlockType{BlockTypeBroadcast, BlockTypeReceive, BlockTypeWhenMotion, BlockTypeWhenColor, BlockTypeWhenHear, BlockTypeWhenBumped}},
+}
+
+// blockCategory возвращает категорию типа блока
+func blockCategory(blockType BlockType) BlockCategory {
+	for _, category := range blockCategories {
+		for _, bt := range category.Blocks {
+			if bt == blockType {
+				return category.Key
+			}
+		}
+	}
+	return CategoryControl
+}
+
+// Формы блоков на холсте
+const (
+	BlockShapeRect    = "rect"
+	BlockShapeRounded = "rounded"
+	BlockShapeHexagon = "hexagon"
+)
+
+// blockShapes формы блоков для выбора в настройках
+var blockShapes = []struct {
+	Key  string
+	Name string
+}{
+	{BlockShapeRect, "Прямоугольник"},
+	{BlockShapeRounded, "Скругленный"},
+	{BlockShapeHexagon, "Шестиугольник"},
+}
+
+// defaultCategoryShapes формы по умолчанию: условия — шестиугольники, действия — скругленные
+var defaultCategoryShapes = map[BlockCategory]string{
+	CategoryControl: BlockShapeRect,
+	CategoryAction:  BlockShapeRounded,
+	CategorySensor:  BlockShapeRect,
+	CategoryLogic:   BlockShapeHexagon,
+	CategoryEvents:  BlockShapeRounded,
+}
+
+// blockColorChoices цвета категорий; пустой цвет оставляет собственный цвет каждого блока
+var blockColorChoices = []struct {
+	Name string
+	Hex  string
+}{
+	{"Цвета блоков", ""},
+	{"Синий", "#1E88E5"},
+	{"Зеленый", "#43A047"},
+	{"Оранжевый", "#FB8C00"},
+	{"Фиолетовый", "#8E24AA"},
+	{"Красный", "#E53935"},
+	{"Бирюзовый", "#00897B"},
+	{"Серый", "#607D8B"},
+}
+
+// BlockStyle внешний вид блока на холсте
+type BlockStyle struct {
+	Color string
+	Shape string
+}
+
+// blockColorSetting и blockShapeSetting ключи настроек категории
+func blockColorSetting(category BlockCategory) string { return "block_color_" + string(category) }
+func blockShapeSetting(category BlockCategory) string { return "block_shape_" + string(category) }
+
+// blockStyle возвращает цвет и форму блока с учетом настроек категории
+func (gui *MainGUI) blockStyle(block *ProgramBlock) BlockStyle {
+	prefs := gui.preferences()
+	category := blockCategory(block.Type)
+
+	style := BlockStyle{
+		Color: block.Color,
+		Shape: prefs.StringWithFallback(blockShapeSetting(category), defaultCategoryShapes[category]),
+	}
+	if categoryColor := prefs.String(blockColorSetting(category)); categoryColor != "" {
+		style.Color = categoryColor
+	}
+	if block.CustomColor != "" {
+		style.Color = block.CustomColor
+	}
+	return style
+}
+
+// validBlockColor проверяет цвет блока в формате #RRGGBB
+func validBlockColor(hex string) bool {
+	if len(hex) != 7 || hex[0] != '#' {
+		return false
+	}
+	for i := 1; i < 7; i += 2 {
+		if _, err := hexToByte(hex[i : i+2]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SetBlockColor перекрашивает блок цветом пользователя; пустой цвет
+// возвращает цвет типа и категории
+func (pm *ProgramManager) SetBlockColor(blockID int, hex string) bool {
+	if hex != "" && !validBlockColor(hex) {
+		return false
+	}
+	block, exists := pm.GetBlock(blockID)
+	if !exists {
+		return false
+	}
+	block.CustomColor = hex
+	pm.program.Modified = time.Now()
+	return true
+}
+
+// blockColorMenu подменю выбора цвета блока: отмечает текущий цвет
+// и позволяет вернуть обычный
+func (d *DraggableBlock) blockColorMenu() *fyne.Menu {
+	setColor := func(hex string) {
+		if d.block.CustomColor == hex {
+			return
+		}
+		d.gui.checkpoint()
+		if d.programMgr.SetBlockColor(d.block.ID, hex) {
+			d.applyStyle()
+			d.Refresh()
+		}
+	}
+
+	reset := fyne.NewMenuItem("Обычный цвет", func() { setColor("") })
+	reset.Checked = d.block.CustomColor == ""
+	items := []*fyne.MenuItem{reset, fyne.NewMenuItemSeparator()}
+	for _, choice := range blockColorChoices {
+		if choice.Hex == "" {
+			continue
+		}
+		hex := choice.Hex
+		item := fyne.NewMenuItem(choice.Name, func() { setColor(hex) })
+		item.Checked = d.block.CustomColor == hex
+		items = append(items, item)
+	}
+	return fyne.NewMenu("", items...)
+}
+
+// newBlockBackground создает фон блока заданной формы
+func newBlockBackground(shape string, fill color.Color, size fyne.Size) fyne.CanvasObject {
+	switch shape {
+	case BlockShapeHexagon:
+		bg := canvas.NewRasterWithPixels(func(x, y, w, h int) color.Color {
+			if insideHexagon(x, y, w, h) {
+				return fill
+			}
+			return color.Transparent
+		})
+		bg.SetMinSize(size)
+		return bg
+	case BlockShapeRounded:
+		bg := canvas.NewRectangle(fill)
+		bg.SetMinSize(size)
+		bg.CornerRadius = size.Height / 2
+		return bg
+	default:
+		bg := canvas.NewRectangle(fill)
+		bg.SetMinSize(size)
+		bg.CornerRadius = 5
+		return bg
+	}
+}
+
+// insideHexagon проверяет, лежит ли пиксель внутри вытянутого шестиугольника
+// с острыми углами слева и справа
+func insideHexagon(x, y, w, h int) bool {
+	inset := h / 3
+	if inset == 0 {
+		return true
+	}
+
+	dy := y - h/2
+	if dy < 0 {
+		dy = -dy
+	}
+
+	switch {
+	case x < inset:
+		return dy*inset <= x*(h/2)
+	case x >= w-inset:
+		return dy*inset <= (w-1-x)*(h/2)
+	default:
+		return true
+	}
+}
+
+// blockStyleSettings настройки фона холста и цвета и формы блоков по категориям
+func (gui *MainGUI) blockStyleSettings(prefs fyne.Preferences) settingsSection {
+	colorNames := make([]string, len(blockColorChoices))
+	for i, choice := range blockColorChoices {
+		colorNames[i] = choice.Name
+	}
+	shapeNames := make([]string, len(blockShapes))
+	for i, shape := range blockShapes {
+		shapeNames[i] = shape.Name
+	}
+
+	backgroundItem, saveBackground := canvasBackgroundItem(prefs)
+	items := []*widget.FormItem{backgroundItem}
+	saves := []func(prefs fyne.Preferences){saveBackground}
+
+	for _, category := range blockCategories {
+		key := category.Key
+
+		colorSelect := widget.NewSelect(colorNames, nil)
+		colorSelect.SetSelected(colorNames[0])
+		for _, choice := range blockColorChoices {
+			if choice.Hex == prefs.String(blockColorSetting(key)) {
+				colorSelect.SetSelected(choice.Name)
+			}
+		}
+
+		shapeSelect := widget.NewSelect(shapeNames, nil)
+		currentShape := prefs.StringWithFallback(blockShapeSetting(key), defaultCategoryShapes[key])
+		for _, shape := range blockShapes {
+			if shape.Key == currentShape {
+				shapeSelect.SetSelected(shape.Name)
+			}
+		}
+
+		items = append(items, widget.NewFormItem(category.Name, widget.NewForm(
+			widget.NewFormItem("Цвет", colorSelect),
+			widget.NewFormItem("Форма", shapeSelect),
+		)))
+
+		saves = append(saves, func(prefs fyne.Preferences) {
+			for _, choice := range blockColorChoices {
+				if choice.Name == colorSelect.Selected {
+					prefs.SetString(blockColorSetting(key), choice.Hex)
+				}
+			}
+			for _, shape := range blockShapes {
+				if shape.Name == shapeSelect.Selected {
+					prefs.SetString(blockShapeSetting(key), shape.Key)
+				}
+			}
+		})
+	}
+
+	return settingsSection{
+		title: "Холст и блоки",
+		items: items,
+		save: func(prefs fyne.Preferences) {
+			for _, save := range saves {
+				save(prefs)
+			}
+		},
+	}
+}
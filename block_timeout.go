@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	settingBlockTimeout = "block_timeout"
+	defaultBlockTimeout = 5.0  // Время ожидания хаба по умолчанию, секунды
+	maxBlockTimeout     = 60.0 // Наибольшее время ожидания в настройках блока, секунды
+)
+
+// usesHub сообщает, обращается ли блок к устройствам хаба и может ли зависнуть на обмене по BLE
+func usesHub(blockType BlockType) bool {
+	switch blockType {
+	case BlockTypeMotor, BlockTypeLED, BlockTypeSound, BlockTypeTiltSensor, BlockTypeDistanceSensor,
+		BlockTypeVoltageSensor, BlockTypeCurrentSensor, BlockTypeFollow, BlockTypeHubPower, BlockTypeLightMusic:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetBlockTimeout задает время ожидания хаба для блоков без собственной настройки
+func (pm *ProgramManager) SetBlockTimeout(seconds float64) {
+	if seconds <= 0 {
+		seconds = defaultBlockTimeout
+	}
+	pm.blockTimeout.Store(int64(seconds * float64(time.Second)))
+}
+
+// blockOperationTimeout возвращает, сколько ждать завершения блока, 0 — без ограничения.
+// К времени ожидания хаба прибавляется собственная длительность работы мотора или звука
+func (pm *ProgramManager) blockOperationTimeout(block *ProgramBlock) time.Duration {
+	if !usesHub(block.Type) {
+		return 0
+	}
+
+	timeout := time.Duration(pm.blockTimeout.Load())
+	if seconds := paramFloat(block.Parameters, "op_timeout", 0); seconds > 0 {
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+	if timeout <= 0 {
+		timeout = time.Duration(defaultBlockTimeout * float64(time.Second))
+	}
+
+	switch block.Type {
+	case BlockTypeMotor, BlockTypeSound:
+		timeout += time.Duration(paramUint16(block.Parameters, "duration", 0)) * time.Millisecond
+	case BlockTypeLightMusic:
+		timeout += lightMusicLength(block.Parameters)
+	}
+	return timeout
+}
+
+// errBlockStillRunning предыдущая попытка блока еще ждет хаб
+var errBlockStillRunning = errors.New("предыдущая попытка блока еще не завершилась")
+
+// runWithTimeout выполняет действие блока, не дожидаясь его дольше timeout.
+// Зависшая запись BLE не прерывается, но программа получает ошибку блока,
+// мотор или звук блока останавливается, и программа поступает по политике
+// ошибок блока. Новая попытка начинается только после завершения зависшей,
+// иначе команды двух попыток уходят в хаб вперемешку
+func (pm *ProgramManager) runWithTimeout(block *ProgramBlock, timeout time.Duration) error {
+	if timeout <= 0 {
+		return block.OnExecute()
+	}
+
+	if pending, ok := pm.pendingBlocks.Load(block.ID); ok {
+		select {
+		case <-pending.(chan struct{}):
+		case <-time.After(timeout):
+			return fmt.Errorf("блок %d: %w", block.ID, errBlockStillRunning)
+		}
+	}
+
+	done := make(chan error, 1)
+	finished := make(chan struct{})
+	pm.pendingBlocks.Store(block.ID, finished)
+	go func() {
+		defer func() {
+			pm.pendingBlocks.CompareAndDelete(block.ID, finished)
+			close(finished)
+		}()
+		if pm.crashHandler != nil {
+			defer pm.crashHandler()
+		}
+		done <- block.OnExecute()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		log.Printf("Блок %d не завершился за %v", block.ID, timeout)
+		pm.stopBlockOutputs(block)
+		return fmt.Errorf("хаб не ответил за %.1f с", timeout.Seconds())
+	}
+}
+
+// stopBlockOutputs останавливает мотор или звук блока, который не дождался
+// хаба. Команды отправляются в фоне: запись может зависнуть так же, как блок
+func (pm *ProgramManager) stopBlockOutputs(block *ProgramBlock) {
+	var commands []Command
+	switch block.Type {
+	case BlockTypeMotor:
+		commands = append(commands, NewMotorCommand(motorFromParameters(block.Parameters).Port).Stop())
+	case BlockTypeFollow:
+		commands = append(commands, NewMotorCommand(followFromParameters(block.Parameters).MotorPort).Stop())
+	case BlockTypeSound:
+		commands = append(commands, NewStopToneCommand(soundFromParameters(block.Parameters).Port))
+	case BlockTypeLightMusic:
+		commands = append(commands, NewStopToneCommand(lightMusicFromParameters(block.Parameters).SoundPort))
+	}
+	if len(commands) == 0 {
+		return
+	}
+
+	go func() {
+		if pm.crashHandler != nil {
+			defer pm.crashHandler()
+		}
+		for _, cmd := range commands {
+			if err := pm.hubMgr.SendCommand(cmd); err != nil {
+				log.Printf("Не удалось остановить блок %d после ожидания: %v", block.ID, err)
+			}
+		}
+	}()
+}
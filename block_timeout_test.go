@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunWithTimeoutWaitsForStuckAttempt(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	actuator.delay = 300 * time.Millisecond
+	block := pm.CreateBlock(BlockTypeMotor, 0, 0)
+
+	started := time.Now()
+	if err := pm.runWithTimeout(block, 50*time.Millisecond); err == nil {
+		t.Fatal("зависший блок завершился без ошибки")
+	}
+
+	// Мотор зависшего блока останавливается, не дожидаясь записи
+	deadline := time.Now().Add(time.Second)
+	for len(hub.sentCommands()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(hub.sentCommands()) == 0 {
+		t.Error("после ожидания мотору не отправлена остановка")
+	}
+
+	// Повтор не начинается, пока первая попытка не завершилась
+	if err := pm.runWithTimeout(block, 50*time.Millisecond); !errors.Is(err, errBlockStillRunning) {
+		t.Errorf("повтор при зависшей попытке вернул %v", err)
+	}
+	if got := len(actuator.recorded()); got != 1 {
+		t.Errorf("мотору отправлено %d команд, ожидалась 1", got)
+	}
+
+	// После завершения первой попытки блок снова выполняется
+	if err := pm.runWithTimeout(block, time.Second); err != nil {
+		t.Fatalf("повтор после завершения попытки: %v", err)
+	}
+	if since := time.Since(started); since < actuator.delay {
+		t.Errorf("вторая попытка началась через %v, раньше завершения первой", since)
+	}
+	if got := len(actuator.recorded()); got != 2 {
+		t.Errorf("мотору отправлено %d команд, ожидалось 2", got)
+	}
+}
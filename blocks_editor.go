@@ -2,35 +2,49 @@ package main
 
 import (
 	"fmt"
+	"image/color"
 	"log"
-	"strconv"
+	"maps"
+	"math"
+	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
 // BlockEditor редактор свойств блока
 type BlockEditor struct {
-	block     *ProgramBlock
-	deviceMgr *DeviceManager
-	container *fyne.Container
-	onChange  func(block *ProgramBlock)
-	window    fyne.Window
+	block      *ProgramBlock
+	deviceMgr  *DeviceManager
+	programMgr *ProgramManager
+	container  *fyne.Container
+	onChange   func(block *ProgramBlock)
+	window     fyne.Window
+	done       chan struct{} // Закрывается в Close и останавливает обновление показаний
+
+	// Последние принятые параметры: к ним редактор возвращается, если
+	// новое значение не прошло проверку
+	committed map[string]interface{}
 }
 
 // NewBlockEditor создает редактор свойств блока
-func NewBlockEditor(block *ProgramBlock, deviceMgr *DeviceManager, window fyne.Window, onChange func(block *ProgramBlock)) *BlockEditor {
+func NewBlockEditor(block *ProgramBlock, deviceMgr *DeviceManager, programMgr *ProgramManager, window fyne.Window, onChange func(block *ProgramBlock)) *BlockEditor {
 	editor := &BlockEditor{
-		block:     block,
-		deviceMgr: deviceMgr,
-		window:    window,
-		onChange:  onChange,
+		block:      block,
+		deviceMgr:  deviceMgr,
+		programMgr: programMgr,
+		window:     window,
+		onChange:   onChange,
+		done:       make(chan struct{}),
 	}
 
 	editor.container = editor.buildUI()
+	editor.committed = maps.Clone(block.Parameters)
 	return editor
 }
 
@@ -39,6 +53,15 @@ func (e *BlockEditor) GetContainer() *fyne.Container {
 	return e.container
 }
 
+// Close останавливает обновление текущих значений датчиков в редакторе
+func (e *BlockEditor) Close() {
+	select {
+	case <-e.done:
+	default:
+		close(e.done)
+	}
+}
+
 // buildUI строит интерфейс редактора
 func (e *BlockEditor) buildUI() *fyne.Container {
 	mainContainer := container.NewVBox()
@@ -70,6 +93,26 @@ func (e *BlockEditor) buildUI() *fyne.Container {
 		e.addSoundControls(mainContainer)
 	case BlockTypeVoltageSensor, BlockTypeCurrentSensor:
 		e.addSimpleSensorControls(mainContainer, e.block.Type)
+	case BlockTypeCondition:
+		e.addConditionBlockControls(mainContainer)
+	case BlockTypeWaitUntil:
+		e.addWaitUntilControls(mainContainer)
+	case BlockTypeBroadcast, BlockTypeReceive:
+		e.addMessageControls(mainContainer)
+	case BlockTypeScreen:
+		e.addScreenControls(mainContainer)
+	case BlockTypeWhenMotion, BlockTypeWhenColor:
+		e.addVisionControls(mainContainer)
+	case BlockTypeWhenHear:
+		e.addSpeechControls(mainContainer)
+	case BlockTypeWhenBumped:
+		e.addWhenBumpedControls(mainContainer)
+	case BlockTypeFollow:
+		e.addFollowControls(mainContainer)
+	case BlockTypeHubPower:
+		e.addHubPowerControls(mainContainer)
+	case BlockTypeLightMusic:
+		e.addLightMusicControls(mainContainer)
 	default:
 		// Для остальных блоков показываем базовую информацию
 		mainContainer.Add(widget.NewLabel(fmt.Sprintf("Тип: %s", e.block.Title)))
@@ -77,6 +120,18 @@ func (e *BlockEditor) buildUI() *fyne.Container {
 		mainContainer.Add(widget.NewLabel(fmt.Sprintf("Позиция: (%.0f, %.0f)", e.block.X, e.block.Y)))
 	}
 
+	if !isHatBlock(e.block.Type) {
+		mainContainer.Add(widget.NewSeparator())
+		if usesHub(e.block.Type) {
+			e.addBlockTimeoutControls(mainContainer)
+		}
+		e.addErrorPolicyControls(mainContainer)
+	}
+	if len(e.block.Parameters) > 0 {
+		mainContainer.Add(widget.NewSeparator())
+		e.addTemplateControls(mainContainer)
+	}
+
 	return mainContainer
 }
 
@@ -128,49 +183,47 @@ func (e *BlockEditor) addMotorControls(cont *fyne.Container) {
 	}
 
 	// Контейнер для ползунка мощности
-	powerContainer := container.NewBorder(nil, nil, nil, powerValueLabel, powerSlider)
-	powerContainer.SetMinSize(fyne.NewSize(250, 40)) // Минимальная ширина для ползунка
+	powerMinWidth := canvas.NewRectangle(color.Transparent)
+	powerMinWidth.SetMinSize(fyne.NewSize(250, 40)) // Минимальная ширина для ползунка
+	powerContainer := container.NewStack(
+		powerMinWidth,
+		container.NewBorder(nil, nil, nil, powerValueLabel, powerSlider),
+	)
 
-	// Длительность
-	durationLabelWidget := widget.NewLabel("Длительность (мс, 0 = бесконечно):")
-	durationEntry := widget.NewEntry()
+	// Случайная мощность
+	randomPowerContainer := e.addRandomRangeControls(
+		"Случайная мощность", "power_min", "power_max", -100, 100, 5,
+		func(value float64) string { return fmt.Sprintf("%.0f%%", value) },
+		func(value float64) interface{} { return int8(value) },
+	)
 
-	// Устанавливаем текущее значение
-	if duration, ok := e.block.Parameters["duration"].(uint16); ok {
-		durationEntry.SetText(fmt.Sprintf("%d", duration))
-	} else {
-		durationEntry.SetText("1000")
-		e.block.Parameters["duration"] = uint16(1000)
-	}
+	// Мощность от датчика расстояния
+	bindPowerContainer := e.addBindingControls("power", -100, 100, 5,
+		func(value float64) string { return fmt.Sprintf("%.0f%%", value) })
 
-	durationEntry.OnChanged = func(text string) {
-		if text == "" {
-			e.block.Parameters["duration"] = uint16(0)
-		} else if dur, err := strconv.ParseUint(text, 10, 16); err == nil {
-			e.block.Parameters["duration"] = uint16(dur)
-		}
+	// Длительность
+	durationLabelWidget := widget.NewLabel("Длительность (мс, 0 = бесконечно):")
+	duration := motorFromParameters(e.block.Parameters).Duration
+	e.block.Parameters["duration"] = duration
+	durationEntry := NewNumberSpinner(0, math.MaxUint16, 10, 0, float64(duration), func(value float64) {
+		e.block.Parameters["duration"] = uint16(value)
 		e.notifyChange()
-	}
+	})
 
 	// Кнопка теста
 	testButton := widget.NewButton("Тест мотор", func() {
 		if e.deviceMgr != nil && e.deviceMgr.hubMgr != nil && e.deviceMgr.hubMgr.IsConnected() {
-			port := e.block.Parameters["port"].(byte)
-			power := e.block.Parameters["power"].(int8)
-			duration := e.block.Parameters["duration"].(uint16)
-
-			// Сначала синхронизируем устройства
-			e.deviceMgr.SyncDevices()
+			params := motorFromParameters(e.block.Parameters)
 
 			// Тестируем
-			err := e.deviceMgr.SetMotorPower(port, power, duration)
+			err := e.deviceMgr.Manual().SetMotorPower(params.Port, params.Power, params.Duration)
 			if err != nil {
 				log.Printf("Ошибка теста мотора: %v", err)
 				dialog.ShowError(fmt.Errorf("Ошибка теста мотора: %v\nПроверьте подключение устройства", err), e.window)
 			} else {
-				message := fmt.Sprintf("Мотор на порту %d запущен на мощности %d%%", port, power)
-				if duration > 0 {
-					message += fmt.Sprintf("\nАвтоматически остановится через %d мс", duration)
+				message := fmt.Sprintf("Мотор на порту %d запущен на мощности %d%%", params.Port, params.Power)
+				if params.Duration > 0 {
+					message += fmt.Sprintf("\nАвтоматически остановится через %d мс", params.Duration)
 				}
 				dialog.ShowInformation("Тест мотора", message, e.window)
 			}
@@ -185,6 +238,8 @@ func (e *BlockEditor) addMotorControls(cont *fyne.Container) {
 	cont.Add(portSelect)
 	cont.Add(powerLabelWidget)
 	cont.Add(powerContainer)
+	cont.Add(randomPowerContainer)
+	cont.Add(bindPowerContainer)
 	cont.Add(durationLabelWidget)
 	cont.Add(durationEntry)
 	cont.Add(layout.NewSpacer())
@@ -317,18 +372,15 @@ func (e *BlockEditor) addLEDControls(cont *fyne.Container) {
 	// Кнопка теста
 	testButton := widget.NewButton("Тест светодиод", func() {
 		if e.deviceMgr != nil && e.deviceMgr.hubMgr != nil && e.deviceMgr.hubMgr.IsConnected() {
-			port := e.block.Parameters["port"].(byte)
-			red := e.block.Parameters["red"].(byte)
-			green := e.block.Parameters["green"].(byte)
-			blue := e.block.Parameters["blue"].(byte)
+			params := ledFromParameters(e.block.Parameters)
 
-			err := e.deviceMgr.SetLEDColor(port, red, green, blue)
+			err := e.deviceMgr.Manual().SetLEDColor(params.Port, params.Red, params.Green, params.Blue)
 			if err != nil {
 				log.Printf("Ошибка теста светодиода: %v", err)
 				dialog.ShowError(fmt.Errorf("Ошибка теста светодиода: %v", err), e.window)
 			} else {
 				dialog.ShowInformation("Тест светодиода",
-					fmt.Sprintf("Светодиод на порту %d установлен в RGB(%d,%d,%d)", port, red, green, blue),
+					fmt.Sprintf("Светодиод на порту %d установлен в RGB(%d,%d,%d)", params.Port, params.Red, params.Green, params.Blue),
 					e.window)
 			}
 		} else {
@@ -348,6 +400,7 @@ func (e *BlockEditor) addLEDControls(cont *fyne.Container) {
 	cont.Add(blueContainer)
 	cont.Add(quickColorsLabelWidget)
 	cont.Add(quickColorsContainer)
+	cont.Add(e.newRandomCheck("Случайный цвет при каждом выполнении", nil))
 	cont.Add(layout.NewSpacer())
 	cont.Add(container.NewCenter(testButton))
 }
@@ -377,23 +430,27 @@ func (e *BlockEditor) addWaitControls(cont *fyne.Container) {
 	// Контейнер для ползунка
 	durationContainer := container.NewBorder(nil, nil, nil, durationValueLabel, durationSlider)
 
+	// Случайная пауза
+	randomContainer := e.addRandomRangeControls(
+		"Случайная пауза", "duration_min", "duration_max", 0.1, 10.0, 0.1,
+		func(value float64) string { return fmt.Sprintf("%.1f с", value) },
+		func(value float64) interface{} { return value },
+	)
+
 	cont.Add(durationLabel)
 	cont.Add(durationContainer)
+	cont.Add(randomContainer)
 }
 
 // addLoopControls добавляет элементы управления для цикла
 func (e *BlockEditor) addLoopControls(cont *fyne.Container) {
-	loopTypeLabel := widget.NewLabel("Тип цикла:")
-	loopTypeSelect := widget.NewSelect([]string{"Определенное число раз", "Бесконечно"}, func(selected string) {
-		e.block.Parameters["forever"] = (selected == "Бесконечно")
-		e.notifyChange()
-	})
-
-	if forever, ok := e.block.Parameters["forever"].(bool); ok && forever {
-		loopTypeSelect.SetSelected("Бесконечно")
-	} else {
-		loopTypeSelect.SetSelected("Определенное число раз")
-		e.block.Parameters["forever"] = false
+	loopModes := []struct {
+		mode string
+		name string
+	}{
+		{LoopModeCount, "Определенное число раз"},
+		{LoopModeForever, "Бесконечно"},
+		{LoopModeUntil, "Пока не выполнится условие"},
 	}
 
 	countLabel := widget.NewLabel("Количество повторений:")
@@ -417,12 +474,92 @@ func (e *BlockEditor) addLoopControls(cont *fyne.Container) {
 	}
 
 	// Контейнер для ползунка
-	countContainer := container.NewBorder(nil, nil, nil, countValueLabel, countSlider)
+	countContainer := container.NewVBox(
+		countLabel,
+		container.NewBorder(nil, nil, nil, countValueLabel, countSlider),
+	)
+
+	// Условие выхода из цикла (общий редактор условий)
+	conditionContainer := container.NewVBox(widget.NewLabel("Повторять, пока не выполнится:"))
+	e.addConditionControls(conditionContainer)
+
+	updateVisibility := func(mode string) {
+		countContainer.Hidden = mode != LoopModeCount
+		conditionContainer.Hidden = mode != LoopModeUntil
+		countContainer.Refresh()
+		conditionContainer.Refresh()
+	}
+
+	loopTypeLabel := widget.NewLabel("Тип цикла:")
+	modeNames := make([]string, len(loopModes))
+	for i, loopMode := range loopModes {
+		modeNames[i] = loopMode.name
+	}
+	loopTypeSelect := widget.NewSelect(modeNames, func(selected string) {
+		for _, loopMode := range loopModes {
+			if loopMode.name == selected {
+				e.block.Parameters["mode"] = loopMode.mode
+				delete(e.block.Parameters, "forever")
+				updateVisibility(loopMode.mode)
+				break
+			}
+		}
+		e.notifyChange()
+	})
+
+	currentMode := loopModeFromParameters(e.block.Parameters)
+	for _, loopMode := range loopModes {
+		if loopMode.mode == currentMode {
+			loopTypeSelect.SetSelected(loopMode.name)
+		}
+	}
 
 	cont.Add(loopTypeLabel)
 	cont.Add(loopTypeSelect)
-	cont.Add(countLabel)
 	cont.Add(countContainer)
+	cont.Add(conditionContainer)
+	e.addBodySizeControls(cont, "Блоков в теле цикла (0 = до конца программы):", 0)
+}
+
+// addConditionBlockControls добавляет элементы управления для блока условия
+func (e *BlockEditor) addConditionBlockControls(cont *fyne.Container) {
+	cont.Add(widget.NewLabel("Выполнить следующие блоки, если:"))
+	e.addConditionControls(cont)
+	e.addBodySizeControls(cont, "Блоков под условием (0 = до конца программы):", 1)
+}
+
+// addBodySizeControls добавляет выбор количества блоков в теле цикла или условия
+func (e *BlockEditor) addBodySizeControls(cont *fyne.Container, label string, defaultSize int) {
+	bodyLabel := widget.NewLabel(label)
+	bodyLabel.Wrapping = fyne.TextWrapWord
+	bodySlider := widget.NewSlider(0, 20)
+	bodySlider.Step = 1
+	bodyValueLabel := widget.NewLabel("")
+
+	formatBody := func(size int) string {
+		if size == 0 {
+			return "все"
+		}
+		return fmt.Sprintf("%d", size)
+	}
+
+	if size, ok := e.block.Parameters["body"].(int); ok {
+		bodySlider.Value = float64(size)
+		bodyValueLabel.SetText(formatBody(size))
+	} else {
+		bodySlider.Value = float64(defaultSize)
+		e.block.Parameters["body"] = defaultSize
+		bodyValueLabel.SetText(formatBody(defaultSize))
+	}
+
+	bodySlider.OnChanged = func(value float64) {
+		e.block.Parameters["body"] = int(value)
+		bodyValueLabel.SetText(formatBody(int(value)))
+		e.notifyChange()
+	}
+
+	cont.Add(bodyLabel)
+	cont.Add(container.NewBorder(nil, nil, nil, bodyValueLabel, bodySlider))
 }
 
 // addTiltSensorControls добавляет элементы управления для датчика наклона
@@ -577,6 +714,10 @@ func (e *BlockEditor) addSoundControls(cont *fyne.Container) {
 	// Контейнер для ползунка частоты
 	freqContainer := container.NewBorder(nil, nil, nil, freqValueLabel, freqSlider)
 
+	// Частота от датчика расстояния
+	bindFreqContainer := e.addBindingControls("frequency", 100, 2000, 10,
+		func(value float64) string { return fmt.Sprintf("%.0f Гц", value) })
+
 	// Длительность
 	durationLabel := widget.NewLabel("Длительность (мс, 100-5000):")
 	durationSlider := widget.NewSlider(100, 5000)
@@ -636,17 +777,15 @@ func (e *BlockEditor) addSoundControls(cont *fyne.Container) {
 	// Кнопка теста
 	testButton := widget.NewButton("Тест звук", func() {
 		if e.deviceMgr != nil && e.deviceMgr.hubMgr != nil && e.deviceMgr.hubMgr.IsConnected() {
-			port := e.block.Parameters["port"].(byte)
-			frequency := e.block.Parameters["frequency"].(uint16)
-			duration := e.block.Parameters["duration"].(uint16)
+			params := soundFromParameters(e.block.Parameters)
 
-			err := e.deviceMgr.PlayTone(port, frequency, duration)
+			err := e.deviceMgr.Manual().PlayTone(params.Port, params.Frequency, params.Duration)
 			if err != nil {
 				log.Printf("Ошибка теста звука: %v", err)
 				dialog.ShowError(fmt.Errorf("Ошибка теста звука: %v", err), e.window)
 			} else {
 				dialog.ShowInformation("Тест звука",
-					fmt.Sprintf("Звук на порту %d: частота %d Гц, длительность %d мс", port, frequency, duration),
+					fmt.Sprintf("Звук на порту %d: частота %d Гц, длительность %d мс", params.Port, params.Frequency, params.Duration),
 					e.window)
 			}
 		} else {
@@ -655,16 +794,27 @@ func (e *BlockEditor) addSoundControls(cont *fyne.Container) {
 	})
 	testButton.Importance = widget.HighImportance
 
+	// Предпрослушивание на компьютере: ноту можно подобрать без хаба,
+	// пищалка которого звучит тихо
+	previewButton := widget.NewButtonWithIcon("Предпрослушать", theme.MediaPlayIcon(), func() {
+		params := soundFromParameters(e.block.Parameters)
+		if err := previewTone(params.Frequency, params.Duration); err != nil {
+			log.Printf("Ошибка предпрослушивания: %v", err)
+			dialog.ShowError(fmt.Errorf("Не удалось проиграть звук на компьютере: %v", err), e.window)
+		}
+	})
+
 	cont.Add(portLabel)
 	cont.Add(portSelect)
 	cont.Add(freqLabel)
 	cont.Add(freqContainer)
+	cont.Add(bindFreqContainer)
 	cont.Add(durationLabel)
 	cont.Add(durationContainer)
 	cont.Add(notesLabel)
 	cont.Add(notesContainer)
 	cont.Add(layout.NewSpacer())
-	cont.Add(container.NewCenter(testButton))
+	cont.Add(container.NewCenter(container.NewHBox(previewButton, testButton)))
 }
 
 // addSimpleSensorControls добавляет элементы управления для простых датчиков
@@ -703,9 +853,502 @@ func (e *BlockEditor) addSimpleSensorControls(cont *fyne.Container, sensorType B
 	cont.Add(infoLabel)
 }
 
+// addConditionControls добавляет редактор условия датчика (датчик, порт, сравнение, значение)
+func (e *BlockEditor) addConditionControls(cont *fyne.Container) {
+	cond := conditionFromParameters(e.block.Parameters)
+	e.block.Parameters["sensor"] = cond.Sensor
+	e.block.Parameters["port"] = cond.Port
+	e.block.Parameters["comparator"] = cond.Comparator
+	e.block.Parameters["value"] = cond.Value
+
+	// Значение порога
+	valueLabel := widget.NewLabel("Значение:")
+	valueSlider := widget.NewSlider(0, 10)
+	valueValueLabel := widget.NewLabel("")
+
+	applySensorRange := func(sensor ConditionSensorInfo) {
+		valueSlider.Min = sensor.Min
+		valueSlider.Max = sensor.Max
+		valueSlider.Step = sensor.Step
+		value := clamp(paramFloat(e.block.Parameters, "value", sensor.Min), sensor.Min, sensor.Max)
+		e.block.Parameters["value"] = value
+		valueSlider.Value = value
+		valueSlider.Refresh()
+		valueValueLabel.SetText(strings.TrimSpace(fmt.Sprintf("%g %s", value, sensor.Unit)))
+	}
+
+	valueSlider.OnChanged = func(value float64) {
+		sensor := findConditionSensor(conditionFromParameters(e.block.Parameters).Sensor)
+		e.block.Parameters["value"] = value
+		valueValueLabel.SetText(strings.TrimSpace(fmt.Sprintf("%g %s", value, sensor.Unit)))
+		e.notifyChange()
+	}
+
+	valueContainer := container.NewBorder(nil, nil, nil, valueValueLabel, valueSlider)
+
+	// Текущее значение датчика: порог можно взять прямо с показаний
+	readout := e.newConditionReadout(func(value float64) {
+		valueSlider.SetValue(value)
+	})
+	readoutContainer := container.NewBorder(nil, nil, nil, readout.button, readout.label)
+
+	// Порт
+	portLabel := widget.NewLabel("Порт датчика:")
+	portSelect := widget.NewSelect([]string{"Порт 1", "Порт 2"}, func(selected string) {
+		if selected == "Порт 1" {
+			e.block.Parameters["port"] = byte(1)
+		} else {
+			e.block.Parameters["port"] = byte(2)
+		}
+		e.notifyChange()
+	})
+
+	if cond.Port == 2 {
+		portSelect.SetSelected("Порт 2")
+	} else {
+		portSelect.SetSelected("Порт 1")
+		e.block.Parameters["port"] = byte(1)
+	}
+
+	// Датчик
+	sensorLabel := widget.NewLabel("Датчик:")
+	sensorNames := make([]string, len(conditionSensors))
+	for i, sensor := range conditionSensors {
+		sensorNames[i] = sensor.Name
+	}
+	sensorSelect := widget.NewSelect(sensorNames, func(selected string) {
+		for _, sensor := range conditionSensors {
+			if sensor.Name == selected {
+				e.block.Parameters["sensor"] = sensor.Key
+				applySensorRange(sensor)
+				portLabel.Hidden = !sensor.UsesPort
+				portSelect.Hidden = !sensor.UsesPort
+				portLabel.Refresh()
+				portSelect.Refresh()
+				break
+			}
+		}
+		e.notifyChange()
+	})
+	sensorSelect.SetSelected(findConditionSensor(cond.Sensor).Name)
+
+	// Сравнение
+	comparatorLabel := widget.NewLabel("Сравнение:")
+	comparatorSelect := widget.NewRadioGroup(conditionComparators, func(selected string) {
+		if selected == "" {
+			return
+		}
+		e.block.Parameters["comparator"] = selected
+		e.notifyChange()
+	})
+	comparatorSelect.Horizontal = true
+	comparatorSelect.Required = true
+	comparatorSelect.SetSelected(cond.Comparator)
+
+	cont.Add(sensorLabel)
+	cont.Add(sensorSelect)
+	cont.Add(portLabel)
+	cont.Add(portSelect)
+	cont.Add(comparatorLabel)
+	cont.Add(comparatorSelect)
+	cont.Add(valueLabel)
+	cont.Add(valueContainer)
+	cont.Add(readoutContainer)
+}
+
+// addWaitUntilControls добавляет элементы управления для блока ожидания условия
+func (e *BlockEditor) addWaitUntilControls(cont *fyne.Container) {
+	e.addConditionControls(cont)
+
+	// Тайм-аут
+	timeoutLabel := widget.NewLabel("Не дольше (секунды, 0 = без ограничения):")
+	timeoutSlider := widget.NewSlider(0, 60)
+	timeoutSlider.Step = 0.5
+	timeoutValueLabel := widget.NewLabel("")
+
+	formatTimeout := func(value float64) string {
+		if value <= 0 {
+			return "∞"
+		}
+		return fmt.Sprintf("%.1f с", value)
+	}
+
+	if timeout, ok := e.block.Parameters["timeout"].(float64); ok {
+		timeoutSlider.Value = timeout
+		timeoutValueLabel.SetText(formatTimeout(timeout))
+	} else {
+		timeoutSlider.Value = 0
+		e.block.Parameters["timeout"] = 0.0
+		timeoutValueLabel.SetText(formatTimeout(0))
+	}
+
+	timeoutSlider.OnChanged = func(value float64) {
+		e.block.Parameters["timeout"] = value
+		timeoutValueLabel.SetText(formatTimeout(value))
+		e.notifyChange()
+	}
+
+	timeoutContainer := container.NewBorder(nil, nil, nil, timeoutValueLabel, timeoutSlider)
+
+	cont.Add(timeoutLabel)
+	cont.Add(timeoutContainer)
+}
+
+// addBlockTimeoutControls добавляет время ожидания ответа хаба для блока
+func (e *BlockEditor) addBlockTimeoutControls(cont *fyne.Container) {
+	formatTimeout := func(value float64) string {
+		if value <= 0 {
+			return "как в настройках"
+		}
+		return fmt.Sprintf("%.0f с", value)
+	}
+
+	timeout := paramFloat(e.block.Parameters, "op_timeout", 0)
+	timeoutSlider := widget.NewSlider(0, maxBlockTimeout)
+	timeoutSlider.Step = 1
+	timeoutSlider.Value = timeout
+	timeoutValueLabel := widget.NewLabel(formatTimeout(timeout))
+	timeoutSlider.OnChanged = func(value float64) {
+		e.block.Parameters["op_timeout"] = value
+		timeoutValueLabel.SetText(formatTimeout(value))
+		e.notifyChange()
+	}
+
+	cont.Add(widget.NewLabel("Ждать ответа хаба не дольше:"))
+	cont.Add(container.NewBorder(nil, nil, nil, timeoutValueLabel, timeoutSlider))
+}
+
+// addErrorPolicyControls добавляет выбор действия при ошибке выполнения блока
+func (e *BlockEditor) addErrorPolicyControls(cont *fyne.Container) {
+	policy := errorPolicyFromParameters(e.block.Parameters)
+
+	retriesSlider := widget.NewSlider(1, maxErrorRetries)
+	retriesSlider.Step = 1
+	retriesSlider.Value = float64(policy.Retries)
+	retriesValueLabel := widget.NewLabel(fmt.Sprintf("%d", policy.Retries))
+	retriesSlider.OnChanged = func(value float64) {
+		e.block.Parameters["retries"] = int(value)
+		retriesValueLabel.SetText(fmt.Sprintf("%d", int(value)))
+		e.notifyChange()
+	}
+	retriesContainer := container.NewBorder(nil, nil, widget.NewLabel("Попыток:"), retriesValueLabel, retriesSlider)
+
+	actions := []string{ErrorActionStop, ErrorActionSkip, ErrorActionRetry}
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = errorActionNames[action]
+	}
+	actionSelect := widget.NewSelect(names, func(selected string) {
+		for _, action := range actions {
+			if errorActionNames[action] == selected {
+				e.block.Parameters["on_error"] = action
+			}
+		}
+		if e.block.Parameters["on_error"] == ErrorActionRetry {
+			retriesContainer.Show()
+		} else {
+			retriesContainer.Hide()
+		}
+		e.notifyChange()
+	})
+	actionSelect.Selected = errorActionNames[policy.Action]
+	retriesContainer.Hidden = policy.Action != ErrorActionRetry
+
+	cont.Add(widget.NewLabel("При ошибке:"))
+	cont.Add(actionSelect)
+	cont.Add(retriesContainer)
+}
+
+// addMessageControls добавляет выбор имени сообщения
+func (e *BlockEditor) addMessageControls(cont *fyne.Container) {
+	var messages []string
+	if e.programMgr != nil {
+		messages = e.programMgr.programMessages()
+	}
+
+	messageLabel := widget.NewLabel("Сообщение:")
+	messageEntry := widget.NewSelectEntry(messages)
+	messageEntry.SetText(messageFromParameters(e.block.Parameters))
+	e.block.Parameters["message"] = messageFromParameters(e.block.Parameters)
+
+	messageEntry.OnChanged = func(text string) {
+		e.block.Parameters["message"] = text
+		e.notifyChange()
+	}
+
+	var hint string
+	if e.block.Type == BlockTypeBroadcast {
+		hint = "Запускает все цепочки \"Когда получено\" с этим сообщением"
+	} else {
+		hint = "Блоки после этого запускаются, когда другая цепочка отправит сообщение"
+	}
+	hintLabel := widget.NewLabel(hint)
+	hintLabel.Wrapping = fyne.TextWrapWord
+
+	cont.Add(messageLabel)
+	cont.Add(messageEntry)
+	cont.Add(hintLabel)
+}
+
+// addHubPowerControls добавляет выбор действия питания хаба
+func (e *BlockEditor) addHubPowerControls(cont *fyne.Container) {
+	names := make([]string, len(hubPowerActions))
+	for i, item := range hubPowerActions {
+		names[i] = item.name
+	}
+
+	actionSelect := widget.NewSelect(names, func(selected string) {
+		for _, item := range hubPowerActions {
+			if item.name == selected {
+				e.block.Parameters["action"] = item.action
+			}
+		}
+		e.notifyChange()
+	})
+	current := hubPowerFromParameters(e.block.Parameters)
+	for _, item := range hubPowerActions {
+		if item.action == current {
+			actionSelect.SetSelected(item.name)
+		}
+	}
+
+	hintLabel := widget.NewLabel("Программа останавливается, а хаб перестает тратить батарею. " +
+		"Выключенный хаб включается кнопкой на корпусе")
+	hintLabel.Wrapping = fyne.TextWrapWord
+
+	cont.Add(widget.NewLabel("Действие:"))
+	cont.Add(actionSelect)
+	cont.Add(hintLabel)
+}
+
+// addScreenControls добавляет элементы управления для вывода на экран
+func (e *BlockEditor) addScreenControls(cont *fyne.Container) {
+	textLabel := widget.NewLabel("Текст или эмодзи:")
+	textEntry := widget.NewEntry()
+	if text, ok := e.block.Parameters["text"].(string); ok {
+		textEntry.SetText(text)
+	}
+	textEntry.OnChanged = func(text string) {
+		e.block.Parameters["text"] = text
+		e.notifyChange()
+	}
+
+	// Быстрые варианты
+	quickLabel := widget.NewLabel("Быстрый выбор:")
+	quickContainer := container.NewGridWithColumns(3)
+	for _, quick := range []string{"ПРЕПЯТСТВИЕ!", "ВПЕРЕД!", "ФИНИШ", "😀", "⭐", "❤"} {
+		btn := widget.NewButton(quick, func(text string) func() {
+			return func() {
+				textEntry.SetText(text)
+			}
+		}(quick))
+		btn.Importance = widget.LowImportance
+		quickContainer.Add(btn)
+	}
+
+	// Длительность
+	durationLabel := widget.NewLabel("Показывать (секунды):")
+	durationSlider := widget.NewSlider(0.5, 10.0)
+	durationSlider.Step = 0.5
+	durationValueLabel := widget.NewLabel("")
+
+	if duration, ok := e.block.Parameters["duration"].(float64); ok {
+		durationSlider.Value = duration
+		durationValueLabel.SetText(fmt.Sprintf("%.1f с", duration))
+	} else {
+		durationSlider.Value = 2.0
+		e.block.Parameters["duration"] = 2.0
+		durationValueLabel.SetText("2.0 с")
+	}
+
+	durationSlider.OnChanged = func(value float64) {
+		e.block.Parameters["duration"] = value
+		durationValueLabel.SetText(fmt.Sprintf("%.1f с", value))
+		e.notifyChange()
+	}
+
+	wait := paramBool(e.block.Parameters, "wait", false)
+	e.block.Parameters["wait"] = wait
+	waitCheck := widget.NewCheck("Ждать, пока сообщение на экране", func(checked bool) {
+		e.block.Parameters["wait"] = checked
+		e.notifyChange()
+	})
+	waitCheck.Checked = wait
+
+	cont.Add(textLabel)
+	cont.Add(textEntry)
+	cont.Add(quickLabel)
+	cont.Add(quickContainer)
+	cont.Add(durationLabel)
+	cont.Add(container.NewBorder(nil, nil, nil, durationValueLabel, durationSlider))
+	cont.Add(waitCheck)
+}
+
+// newRandomCheck создает флажок включения случайных параметров блока
+func (e *BlockEditor) newRandomCheck(label string, onToggle func(bool)) *widget.Check {
+	random := paramBool(e.block.Parameters, "random", false)
+	e.block.Parameters["random"] = random
+
+	check := widget.NewCheck(label, func(checked bool) {
+		e.block.Parameters["random"] = checked
+		if onToggle != nil {
+			onToggle(checked)
+		}
+		e.notifyChange()
+	})
+	check.Checked = random
+	return check
+}
+
+// addRandomRangeControls создает флажок и ползунки диапазона "от"/"до" для случайного значения.
+// convert приводит значение ползунка к типу параметра блока.
+func (e *BlockEditor) addRandomRangeControls(label, minKey, maxKey string, lo, hi, step float64,
+	format func(float64) string, convert func(float64) interface{}) *fyne.Container {
+
+	rangeContainer := container.NewVBox()
+
+	for _, key := range []string{minKey, maxKey} {
+		paramKey := key
+		value := lo
+		if current, ok := e.block.Parameters[paramKey]; ok {
+			value = parameterToFloat(current)
+		} else if paramKey == maxKey {
+			value = hi
+		}
+
+		slider := widget.NewSlider(lo, hi)
+		slider.Step = step
+		slider.Value = value
+		valueLabel := widget.NewLabel(format(value))
+		e.block.Parameters[paramKey] = convert(value)
+
+		slider.OnChanged = func(value float64) {
+			e.block.Parameters[paramKey] = convert(value)
+			valueLabel.SetText(format(value))
+			e.notifyChange()
+		}
+
+		caption := "от"
+		if paramKey == maxKey {
+			caption = "до"
+		}
+		rangeContainer.Add(container.NewBorder(nil, nil, widget.NewLabel(caption), valueLabel, slider))
+	}
+
+	check := e.newRandomCheck(label, func(checked bool) {
+		rangeContainer.Hidden = !checked
+		rangeContainer.Refresh()
+	})
+	rangeContainer.Hidden = !check.Checked
+
+	return container.NewVBox(check, rangeContainer)
+}
+
+// parameterToFloat приводит числовой параметр блока к float64
+func parameterToFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case byte:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	default:
+		return 0
+	}
+}
+
 // notifyChange уведомляет об изменении блока
 func (e *BlockEditor) notifyChange() {
+	if err := validateBlockParameters(e.block); err != nil {
+		log.Printf("Недопустимый параметр: %v", err)
+		e.revert()
+		dialog.ShowError(err, e.window)
+		return
+	}
+	e.committed = maps.Clone(e.block.Parameters)
 	if e.onChange != nil {
 		e.onChange(e.block)
 	}
 }
+
+// revert возвращает блоку последние принятые параметры и перестраивает
+// редактор, чтобы элементы управления показывали их, а не отклоненное значение
+func (e *BlockEditor) revert() {
+	clear(e.block.Parameters)
+	maps.Copy(e.block.Parameters, e.committed)
+
+	e.Close()
+	e.done = make(chan struct{})
+	e.container.Objects = e.buildUI().Objects
+	e.container.Refresh()
+}
+
+// addVisionControls добавляет элементы управления для блоков камеры
+func (e *BlockEditor) addVisionControls(cont *fyne.Container) {
+	switch e.block.Type {
+	case BlockTypeWhenMotion:
+		thresholdLabel := widget.NewLabel("Чувствительность (доля кадра):")
+		thresholdSlider := widget.NewSlider(1, 50)
+		thresholdSlider.Step = 1
+		thresholdValueLabel := widget.NewLabel("")
+
+		threshold := motionThreshold(e.block.Parameters)
+		e.block.Parameters["threshold"] = threshold
+		thresholdSlider.Value = threshold
+		thresholdValueLabel.SetText(fmt.Sprintf("%.0f%%", threshold))
+
+		thresholdSlider.OnChanged = func(value float64) {
+			e.block.Parameters["threshold"] = value
+			thresholdValueLabel.SetText(fmt.Sprintf("%.0f%%", value))
+			e.notifyChange()
+		}
+
+		cont.Add(thresholdLabel)
+		cont.Add(container.NewBorder(nil, nil, nil, thresholdValueLabel, thresholdSlider))
+		cont.Add(widget.NewLabel("Чем меньше значение, тем слабее движение,\nна которое реагирует блок"))
+
+	case BlockTypeWhenColor:
+		names := make([]string, len(visionColors))
+		for i, c := range visionColors {
+			names[i] = c.Name
+		}
+
+		colorLabel := widget.NewLabel("Цвет:")
+		colorSelect := widget.NewSelect(names, func(selected string) {
+			for _, c := range visionColors {
+				if c.Name == selected {
+					e.block.Parameters["color"] = c.Key
+				}
+			}
+			e.notifyChange()
+		})
+		colorKey := paramString(e.block.Parameters, "color", "")
+		colorSelect.Selected = visionColorName(colorKey)
+
+		cont.Add(colorLabel)
+		cont.Add(colorSelect)
+		cont.Add(widget.NewLabel("Цвет должен занимать заметную часть кадра"))
+	}
+
+	cont.Add(widget.NewLabel("Нужна веб-камера и программа ffmpeg.\nКамеру можно выбрать в настройках."))
+}
+
+// addSpeechControls добавляет элементы управления для блока "Когда слышу"
+func (e *BlockEditor) addSpeechControls(cont *fyne.Container) {
+	keywordLabel := widget.NewLabel("Ключевое слово:")
+	keywordSelect := widget.NewSelectEntry(e.programMgr.SpeechMonitor().Keywords())
+	keywordSelect.SetText(keywordFromParameters(e.block.Parameters))
+	keywordSelect.OnChanged = func(text string) {
+		e.block.Parameters["keyword"] = normalizeKeyword(text)
+		e.notifyChange()
+	}
+
+	cont.Add(keywordLabel)
+	cont.Add(keywordSelect)
+	cont.Add(widget.NewLabel("Нужна программа PocketSphinx и микрофон.\nСписок слов и микрофон задаются в настройках."))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// settingCanvasBackground ключ настройки фона холста
+const settingCanvasBackground = "canvas_background"
+
+// CanvasBackground фон холста программы
+type CanvasBackground string
+
+// Фоны холста
+const (
+	CanvasBackgroundGrid  CanvasBackground = "grid"  // Темный фон с сеткой
+	CanvasBackgroundPlain CanvasBackground = "plain" // Темный фон без разметки
+	CanvasBackgroundDots  CanvasBackground = "dots"  // Темный фон с точками в узлах сетки
+	CanvasBackgroundLines CanvasBackground = "lines" // Темный фон с горизонтальными линиями
+	CanvasBackgroundPrint CanvasBackground = "print" // Светлый фон с бледной сеткой: для печати и проектора
+)
+
+// canvasBackgrounds фоны с названиями для интерфейса
+var canvasBackgrounds = []struct {
+	background CanvasBackground
+	name       string
+}{
+	{CanvasBackgroundGrid, "Сетка"},
+	{CanvasBackgroundPlain, "Без разметки"},
+	{CanvasBackgroundDots, "Точки"},
+	{CanvasBackgroundLines, "Линии"},
+	{CanvasBackgroundPrint, "Светлый для печати"},
+}
+
+// canvasPattern разметка поверх фона холста
+type canvasPattern int
+
+const (
+	patternNone canvasPattern = iota
+	patternGrid
+	patternDots
+	patternLines
+)
+
+// canvasBackgroundStyle цвет фона, цвет разметки, сама разметка и цвет
+// подписей, которые лежат прямо на холсте, например заголовков групп
+type canvasBackgroundStyle struct {
+	fill    color.NRGBA
+	mark    color.NRGBA
+	pattern canvasPattern
+	text    color.Color
+}
+
+var (
+	darkCanvasFill = color.NRGBA{R: 30, G: 30, B: 30, A: 255}
+	darkCanvasMark = color.NRGBA{R: gridLineGray, G: gridLineGray, B: gridLineGray, A: 255}
+)
+
+// canvasBackgroundStyles внешний вид фонов холста
+var canvasBackgroundStyles = map[CanvasBackground]canvasBackgroundStyle{
+	CanvasBackgroundGrid:  {darkCanvasFill, darkCanvasMark, patternGrid, color.White},
+	CanvasBackgroundPlain: {darkCanvasFill, darkCanvasMark, patternNone, color.White},
+	CanvasBackgroundDots:  {darkCanvasFill, color.NRGBA{R: 90, G: 90, B: 90, A: 255}, patternDots, color.White},
+	CanvasBackgroundLines: {darkCanvasFill, darkCanvasMark, patternLines, color.White},
+	CanvasBackgroundPrint: {color.NRGBA{R: 255, G: 255, B: 255, A: 255}, color.NRGBA{R: 225, G: 225, B: 225, A: 255}, patternGrid,
+		color.NRGBA{R: 33, G: 33, B: 33, A: 255}},
+}
+
+// canvasStyle возвращает внешний вид фона; неизвестный фон заменяется сеткой
+func canvasStyle(background CanvasBackground) canvasBackgroundStyle {
+	if style, ok := canvasBackgroundStyles[background]; ok {
+		return style
+	}
+	return canvasBackgroundStyles[CanvasBackgroundGrid]
+}
+
+// draw рисует разметку на прозрачном растре размером w×h пикселей
+func (s canvasBackgroundStyle) draw(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if w == 0 || h == 0 || s.pattern == patternNone {
+		return img
+	}
+
+	// Узлы сетки в пикселях растра
+	scaleX, scaleY := float64(w)/canvasSize, float64(h)/canvasSize
+	var xs, ys []int
+	for i := 0; i <= canvasSize; i += gridStep {
+		xs = append(xs, min(int(float64(i)*scaleX), w-1))
+		ys = append(ys, min(int(float64(i)*scaleY), h-1))
+	}
+
+	switch s.pattern {
+	case patternGrid:
+		for _, x := range xs {
+			for y := 0; y < h; y++ {
+				img.SetNRGBA(x, y, s.mark)
+			}
+		}
+		fallthrough
+	case patternLines:
+		for _, y := range ys {
+			for x := 0; x < w; x++ {
+				img.SetNRGBA(x, y, s.mark)
+			}
+		}
+	case patternDots:
+		for _, y := range ys {
+			for _, x := range xs {
+				img.SetNRGBA(x, y, s.mark)
+				if x+1 < w && y+1 < h {
+					img.SetNRGBA(x+1, y, s.mark)
+					img.SetNRGBA(x, y+1, s.mark)
+					img.SetNRGBA(x+1, y+1, s.mark)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// SetBackground меняет фон холста
+func (p *ProgramPanel) SetBackground(background CanvasBackground) {
+	style := canvasStyle(background)
+	p.style = style
+	p.background.FillColor = style.fill
+	p.background.Refresh()
+	p.grid.Generator = style.draw
+	p.grid.Refresh()
+	p.refreshGroups()
+}
+
+// canvasBackgroundItem поле выбора фона холста для настроек и функция сохранения выбора
+func canvasBackgroundItem(prefs fyne.Preferences) (*widget.FormItem, func(prefs fyne.Preferences)) {
+	names := make([]string, len(canvasBackgrounds))
+	for i, item := range canvasBackgrounds {
+		names[i] = item.name
+	}
+	backgroundSelect := widget.NewSelect(names, nil)
+	backgroundSelect.SetSelected(names[0])
+	for _, item := range canvasBackgrounds {
+		if string(item.background) == prefs.String(settingCanvasBackground) {
+			backgroundSelect.SetSelected(item.name)
+		}
+	}
+
+	formItem := widget.NewFormItem("Фон холста", backgroundSelect)
+	formItem.HintText = "Светлый фон удобен для печати и проектора"
+	return formItem, func(prefs fyne.Preferences) {
+		for _, item := range canvasBackgrounds {
+			if item.name == backgroundSelect.Selected {
+				prefs.SetString(settingCanvasBackground, string(item.background))
+			}
+		}
+	}
+}
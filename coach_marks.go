@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	coachCalloutWidth    = 340                    // Ширина выноски с подсказкой
+	coachHighlightMargin = 4                      // Отступ рамки подсветки от элемента
+	coachRefreshInterval = 300 * time.Millisecond // Период перестановки выноски и проверки упражнения
+)
+
+// CoachStep шаг обучающего тура: подсвеченный элемент и выноска с пояснением
+type CoachStep struct {
+	Target func() fyne.CanvasObject // Подсвечиваемый элемент; nil — выноска по центру окна
+	Title  string
+	Text   string
+
+	// Необязательная кнопка действия, например «Найти хаб»
+	ActionLabel string
+	Action      func()
+
+	// Done для шага-упражнения: «Далее» доступна, когда условие выполнено
+	Done func() bool
+}
+
+// CoachTour показывает шаги тура поверх окна. Слой тура лежит над интерфейсом
+// и не перехватывает щелчки мимо выноски, поэтому упражнения выполняются в самом приложении
+type CoachTour struct {
+	layer    *fyne.Container
+	steps    []CoachStep
+	index    int
+	onFinish func()
+
+	highlight *canvas.Rectangle
+	callout   *fyne.Container
+	title     *widget.Label
+	text      *widget.Label
+	progress  *widget.Label
+	action    *widget.Button
+	back      *widget.Button
+	next      *widget.Button
+
+	done chan struct{}
+}
+
+// NewCoachTour создает тур на слое без раскладки; onFinish вызывается после
+// последнего шага или пропуска тура
+func NewCoachTour(layer *fyne.Container, steps []CoachStep, onFinish func()) *CoachTour {
+	t := &CoachTour{layer: layer, steps: steps, onFinish: onFinish}
+
+	t.highlight = canvas.NewRectangle(color.Transparent)
+	t.highlight.StrokeColor = theme.Color(theme.ColorNameFocus)
+	t.highlight.StrokeWidth = 3
+	t.highlight.CornerRadius = theme.InputRadiusSize()
+
+	t.title = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	t.text = widget.NewLabel("")
+	t.text.Wrapping = fyne.TextWrapWord
+	t.progress = widget.NewLabel("")
+	t.progress.Importance = widget.LowImportance
+
+	t.action = widget.NewButton("", func() {
+		if action := t.steps[t.index].Action; action != nil {
+			action()
+		}
+	})
+	t.back = widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { t.show(t.index - 1) })
+	t.next = widget.NewButton("Далее", func() {
+		if t.index == len(t.steps)-1 {
+			t.finish()
+			return
+		}
+		t.show(t.index + 1)
+	})
+	t.next.Importance = widget.HighImportance
+	skip := widget.NewButton("Пропустить", t.finish)
+	skip.Importance = widget.LowImportance
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameOverlayBackground))
+	background.StrokeColor = theme.Color(theme.ColorNameFocus)
+	background.StrokeWidth = 1
+	background.CornerRadius = theme.InputRadiusSize() * 2
+
+	t.callout = container.NewStack(background, container.NewPadded(container.NewVBox(
+		container.NewBorder(nil, nil, nil, t.progress, t.title),
+		t.text,
+		t.action,
+		container.NewHBox(skip, layout.NewSpacer(), t.back, t.next),
+	)))
+	return t
+}
+
+// Start показывает первый шаг тура
+func (t *CoachTour) Start() {
+	t.done = make(chan struct{})
+	t.layer.Objects = []fyne.CanvasObject{t.highlight, t.callout}
+	t.show(0)
+	go t.refreshLoop()
+}
+
+// Stop убирает тур без вызова onFinish
+func (t *CoachTour) Stop() {
+	if t.done == nil {
+		return
+	}
+	close(t.done)
+	t.done = nil
+	t.layer.Objects = nil
+	t.layer.Refresh()
+}
+
+// finish завершает тур
+func (t *CoachTour) finish() {
+	t.Stop()
+	if t.onFinish != nil {
+		t.onFinish()
+	}
+}
+
+// show переходит к шагу тура
+func (t *CoachTour) show(index int) {
+	if index < 0 || index >= len(t.steps) {
+		return
+	}
+	t.index = index
+	step := t.steps[index]
+
+	t.title.SetText(step.Title)
+	t.text.SetText(step.Text)
+	t.progress.SetText(fmt.Sprintf("%d из %d", index+1, len(t.steps)))
+	if step.ActionLabel != "" {
+		t.action.SetText(step.ActionLabel)
+		t.action.Show()
+	} else {
+		t.action.Hide()
+	}
+	if index == 0 {
+		t.back.Disable()
+	} else {
+		t.back.Enable()
+	}
+	if index == len(t.steps)-1 {
+		t.next.SetText("Готово")
+	} else {
+		t.next.SetText("Далее")
+	}
+	t.update()
+}
+
+// refreshLoop следит за размерами окна и выполнением упражнения
+func (t *CoachTour) refreshLoop() {
+	done := t.done
+	ticker := time.NewTicker(coachRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			fyne.Do(func() {
+				if t.done == done {
+					t.update()
+				}
+			})
+		}
+	}
+}
+
+// update проверяет упражнение и переставляет подсветку и выноску
+func (t *CoachTour) update() {
+	step := t.steps[t.index]
+	if step.Done == nil || step.Done() {
+		t.next.Enable()
+	} else {
+		t.next.Disable()
+	}
+
+	// Высота выноски зависит от переноса текста, поэтому ширину задаем до замера
+	t.callout.Resize(fyne.NewSize(coachCalloutWidth, t.callout.MinSize().Height))
+	calloutSize := fyne.NewSize(coachCalloutWidth, t.callout.MinSize().Height)
+	t.callout.Resize(calloutSize)
+	layerSize := t.layer.Size()
+
+	var target fyne.CanvasObject
+	if step.Target != nil {
+		target = step.Target()
+	}
+	if target == nil || !target.Visible() || target.Size().IsZero() {
+		t.highlight.Hide()
+		t.callout.Move(fyne.NewPos((layerSize.Width-calloutSize.Width)/2, (layerSize.Height-calloutSize.Height)/2))
+		t.layer.Refresh()
+		return
+	}
+
+	driver := fyne.CurrentApp().Driver()
+	pos := driver.AbsolutePositionForObject(target).Subtract(driver.AbsolutePositionForObject(t.layer))
+	size := target.Size()
+	t.highlight.Move(pos.SubtractXY(coachHighlightMargin, coachHighlightMargin))
+	t.highlight.Resize(size.AddWidthHeight(2*coachHighlightMargin, 2*coachHighlightMargin))
+	t.highlight.Show()
+
+	// Выноска под элементом, если помещается, иначе над ним, иначе внутри у верхнего края
+	gap := theme.Padding() + coachHighlightMargin
+	y := pos.Y + size.Height + gap
+	if y+calloutSize.Height > layerSize.Height {
+		y = pos.Y - calloutSize.Height - gap
+		if y < 0 {
+			y = pos.Y + gap
+		}
+	}
+	x := pos.X
+	if x+calloutSize.Width > layerSize.Width {
+		x = layerSize.Width - calloutSize.Width
+	}
+	if x < 0 {
+		x = 0
+	}
+	t.callout.Move(fyne.NewPos(x, y))
+	t.layer.Refresh()
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Допустимые номера портов хаба WeDo 2.0
+const (
+	minPortID = 1
+	maxPortID = 6
+)
+
+// Коды команд характеристики управления
+const (
+	outputCommandMotor    = 0x01
+	outputCommandTone     = 0x02
+	outputCommandStopTone = 0x03
+	outputCommandLED      = 0x04
+)
+
+// outputPayloadLengths допустимые длины данных для каждой команды управления
+var outputPayloadLengths = map[byte][]int{
+	outputCommandMotor:    {1},
+	outputCommandTone:     {4},
+	outputCommandStopTone: {0},
+	outputCommandLED:      {1, 3},
+}
+
+// Command команда хабу, собранная построителем
+type Command interface {
+	Characteristic() string
+	Build() ([]byte, error)
+}
+
+// validatePort проверяет номер порта хаба
+func validatePort(port byte) error {
+	if port < minPortID || port > maxPortID {
+		return fmt.Errorf("недопустимый порт %d (ожидается %d..%d)", port, minPortID, maxPortID)
+	}
+	return nil
+}
+
+// OutputCommand построитель команды для характеристики управления
+type OutputCommand struct {
+	port    byte
+	command byte
+	payload []byte
+	power   int           // Мощность мотора, %; нужна для ограничения при слабых батареях
+	source  CommandSource // Кто отправляет команду
+	err     error
+}
+
+// NewMotorCommand начинает команду мотору на порту
+func NewMotorCommand(port byte) *OutputCommand {
+	return &OutputCommand{port: port, command: outputCommandMotor}
+}
+
+// NewToneCommand начинает команду пищалке на порту
+func NewToneCommand(port byte) *OutputCommand {
+	return &OutputCommand{port: port, command: outputCommandTone}
+}
+
+// NewStopToneCommand создает команду остановки пищалки на порту
+func NewStopToneCommand(port byte) *OutputCommand {
+	return &OutputCommand{port: port, command: outputCommandStopTone, payload: []byte{}}
+}
+
+// NewLEDCommand начинает команду светодиоду на порту
+func NewLEDCommand(port byte) *OutputCommand {
+	return &OutputCommand{port: port, command: outputCommandLED}
+}
+
+// Power задает мощность мотора в процентах (-100..100)
+func (c *OutputCommand) Power(power int) *OutputCommand {
+	if power < -100 || power > 100 {
+		c.err = fmt.Errorf("мощность %d%% вне диапазона -100..100", power)
+		return c
+	}
+	c.power = power
+	c.payload = []byte{protocol.EncodeMotorSpeed(int8(power))}
+	return c
+}
+
+// Stop задает остановку мотора
+func (c *OutputCommand) Stop() *OutputCommand {
+	return c.Power(0)
+}
+
+// Tone задает частоту (Гц) и длительность (мс) звука
+func (c *OutputCommand) Tone(frequency, duration uint16) *OutputCommand {
+	if frequency == 0 {
+		c.err = fmt.Errorf("частота звука должна быть больше нуля")
+		return c
+	}
+	c.payload = []byte{
+		byte(frequency & 0xFF),
+		byte(frequency >> 8),
+		byte(duration & 0xFF),
+		byte(duration >> 8),
+	}
+	return c
+}
+
+// RGB задает цвет светодиода
+func (c *OutputCommand) RGB(red, green, blue byte) *OutputCommand {
+	c.payload = []byte{red, green, blue}
+	return c
+}
+
+// Index задает цвет светодиода по номеру палитры хаба
+func (c *OutputCommand) Index(colorIndex byte) *OutputCommand {
+	c.payload = []byte{colorIndex}
+	return c
+}
+
+// Characteristic возвращает характеристику для отправки команды
+func (c *OutputCommand) Characteristic() string {
+	return OUTPUT_COMMAND_UUID
+}
+
+// Build проверяет команду и собирает ее байты: порт, код команды, длина, данные
+func (c *OutputCommand) Build() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if err := validatePort(c.port); err != nil {
+		return nil, err
+	}
+	if c.payload == nil {
+		return nil, fmt.Errorf("команда 0x%02x для порта %d не заполнена", c.command, c.port)
+	}
+
+	lengthOK := false
+	for _, length := range outputPayloadLengths[c.command] {
+		if len(c.payload) == length {
+			lengthOK = true
+			break
+		}
+	}
+	if !lengthOK {
+		return nil, fmt.Errorf("недопустимая длина данных %d для команды 0x%02x", len(c.payload), c.command)
+	}
+
+	cmd := make([]byte, 0, 3+len(c.payload))
+	cmd = append(cmd, c.port, c.command, byte(len(c.payload)))
+	return append(cmd, c.payload...), nil
+}
+
+// InputFormatCommand построитель команды настройки режима устройства
+type InputFormatCommand struct {
+	port       byte
+	deviceType byte
+	mode       byte
+	delta      uint32
+	notify     bool
+}
+
+// NewInputFormatCommand начинает настройку устройства на порту, уведомления включены
+func NewInputFormatCommand(port byte, deviceType byte) *InputFormatCommand {
+	return &InputFormatCommand{port: port, deviceType: deviceType, delta: 1, notify: true}
+}
+
+// Mode задает режим устройства
+func (c *InputFormatCommand) Mode(mode byte) *InputFormatCommand {
+	c.mode = mode
+	return c
+}
+
+// Delta задает минимальное изменение значения для уведомления
+func (c *InputFormatCommand) Delta(delta uint32) *InputFormatCommand {
+	c.delta = delta
+	return c
+}
+
+// Notify включает или выключает уведомления о значениях
+func (c *InputFormatCommand) Notify(enabled bool) *InputFormatCommand {
+	c.notify = enabled
+	return c
+}
+
+// Characteristic возвращает характеристику для отправки команды
+func (c *InputFormatCommand) Characteristic() string {
+	return INPUT_COMMAND_UUID
+}
+
+// Build проверяет команду и собирает ее байты
+func (c *InputFormatCommand) Build() ([]byte, error) {
+	if err := validatePort(c.port); err != nil {
+		return nil, err
+	}
+	switch c.deviceType {
+	case DEVICE_TYPE_MOTOR, DEVICE_TYPE_VOLTAGE, DEVICE_TYPE_CURRENT, DEVICE_TYPE_PIEZO_TONE,
+		DEVICE_TYPE_RGB_LIGHT, DEVICE_TYPE_TILT_SENSOR, DEVICE_TYPE_MOTION_SENSOR:
+	default:
+		return nil, fmt.Errorf("неизвестный тип устройства 0x%02x", c.deviceType)
+	}
+
+	var notify byte
+	if c.notify {
+		notify = 0x01
+	}
+	return []byte{
+		0x01, 0x02, // команда формата ввода
+		c.port,
+		c.deviceType,
+		c.mode,
+		byte(c.delta), byte(c.delta >> 8), byte(c.delta >> 16), byte(c.delta >> 24), // интервал изменения
+		0x02, // единицы: проценты
+		notify,
+	}, nil
+}
+
+// Коды действий хаба
+const (
+	hubActionSwitchOff = 0x00 // Выключить хаб
+)
+
+// HubActionCommand команда действия хаба в целом, а не устройства на порту
+type HubActionCommand struct {
+	action byte
+}
+
+// NewHubActionCommand создает команду действия хаба
+func NewHubActionCommand(action byte) *HubActionCommand {
+	return &HubActionCommand{action: action}
+}
+
+// Characteristic возвращает характеристику для отправки команды
+func (c *HubActionCommand) Characteristic() string {
+	return HUB_ACTION_UUID
+}
+
+// Build проверяет команду и собирает ее байты
+func (c *HubActionCommand) Build() ([]byte, error) {
+	if c.action != hubActionSwitchOff {
+		return nil, fmt.Errorf("неизвестное действие хаба 0x%02x", c.action)
+	}
+	return protocol.EncodeHubActionCommand(c.action), nil
+}
+
+// portCommand команда, адресованная устройству на порту хаба
+type portCommand interface {
+	Port() byte
+}
+
+// Port возвращает порт, которому адресована команда
+func (c *OutputCommand) Port() byte {
+	return c.port
+}
+
+// Port возвращает порт, который настраивает команда
+func (c *InputFormatCommand) Port() byte {
+	return c.port
+}
+
+// SendCommand проверяет команду, согласует ее с командами других источников
+// и отправляет хабу. Неудачная отправка учитывается в счетчике ошибок устройства на порту
+func (hm *HubManager) SendCommand(cmd Command) error {
+	if output, ok := cmd.(*OutputCommand); ok {
+		output.limitPower(hm.voltage.PowerCap())
+	}
+	data, err := cmd.Build()
+	if err != nil {
+		return fmt.Errorf("некорректная команда: %v", err)
+	}
+	if hm.replayingOffline() {
+		// Команда не отправляется, но записываемый сеанс сохраняет ее,
+		// чтобы при просмотре были видны моторы, светодиод и ноты пищалки
+		hm.trace.Capture(FrameOut, cmd.Characteristic(), data)
+		devicesLog.Debugf("Воспроизведение записи без хаба: команда % x не отправлена", data)
+		return nil
+	}
+	if output, ok := cmd.(*OutputCommand); ok {
+		if send, err := hm.arbiter.Admit(output, time.Now()); !send {
+			return err
+		}
+	}
+	err = hm.WriteCharacteristic(cmd.Characteristic(), data)
+	if ported, ok := cmd.(portCommand); ok && err != nil {
+		hm.devices.RecordError(ported.Port())
+	}
+	if output, ok := cmd.(*OutputCommand); ok && err == nil {
+		if running, isMotor := output.motorRunning(); isMotor {
+			hm.usage.RecordMotor(output.port, running)
+		}
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// readoutRefreshInterval период обновления текущего значения в редакторе условия
+const readoutRefreshInterval = 200 * time.Millisecond
+
+// conditionReadout текущее значение источника условия рядом с порогом и
+// кнопка, переносящая его в порог: порог подбирается по показаниям датчика,
+// а не угадывается
+type conditionReadout struct {
+	label  *widget.Label
+	button *widget.Button
+	value  float64
+	ok     bool
+}
+
+// newConditionReadout создает показ текущего значения для редактора условия.
+// Значение обновляется, пока редактор не закрыт; use получает его по кнопке
+func (e *BlockEditor) newConditionReadout(use func(value float64)) *conditionReadout {
+	r := &conditionReadout{label: widget.NewLabel("")}
+	r.button = widget.NewButtonWithIcon("Взять текущее", theme.DownloadIcon(), func() {
+		if r.ok {
+			use(r.value)
+		}
+	})
+	r.update(e)
+
+	done := e.done
+	go func() {
+		ticker := time.NewTicker(readoutRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// Параметры блока меняются в потоке интерфейса, там же их и читаем
+				fyne.Do(func() { r.update(e) })
+			}
+		}
+	}()
+	return r
+}
+
+// update читает текущее значение выбранного в условии датчика
+func (r *conditionReadout) update(e *BlockEditor) {
+	cond := conditionFromParameters(e.block.Parameters)
+	sensor := findConditionSensor(cond.Sensor)
+	r.value, r.ok = e.programMgr.conditionValue(cond)
+
+	if !r.ok {
+		r.label.SetText("Сейчас: нет данных")
+		r.button.Disable()
+		return
+	}
+	r.label.SetText(strings.TrimSpace(fmt.Sprintf("Сейчас: %g %s", roundTo(r.value, sensor.Step), sensor.Unit)))
+	r.button.Enable()
+}
+
+// roundTo округляет значение до шага
+func roundTo(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+	return math.Round(value/step) * step
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Источники значений для условий
+const (
+	ConditionSensorDistance = "distance"
+	ConditionSensorTilt     = "tilt"
+	ConditionSensorObject   = "object" // Признак «объект обнаружен»: 1 — да, 0 — нет
+	ConditionSensorTimer    = "timer"
+)
+
+// errConditionTimeout условие не выполнилось за время ожидания. Ошибка
+// обрабатывается политикой ошибок блока: можно пропустить блок или повторить ожидание
+var errConditionTimeout = errors.New("время ожидания условия истекло")
+
+// ConditionSensorInfo описание источника значения для редактора условий
+type ConditionSensorInfo struct {
+	Key  string
+	Name string
+	Unit string
+	Min  float64
+	Max  float64
+	Step float64
+
+	// UsesPort источник читает значение датчика на порту хаба
+	UsesPort bool
+	// DeviceType тип датчика, который должен быть подключен к порту
+	DeviceType byte
+}
+
+// conditionSensors доступные в условиях датчики
+var conditionSensors = []ConditionSensorInfo{
+	{Key: ConditionSensorDistance, Name: "Расстояние", Unit: "см", Min: 0, Max: distanceMaxCM, Step: 1, UsesPort: true, DeviceType: DEVICE_TYPE_MOTION_SENSOR},
+	{Key: ConditionSensorObject, Name: "Объект обнаружен", Unit: "", Min: 0, Max: 1, Step: 1, UsesPort: true, DeviceType: DEVICE_TYPE_MOTION_SENSOR},
+	{Key: ConditionSensorTilt, Name: "Наклон", Unit: "", Min: 0, Max: 10, Step: 1, UsesPort: true, DeviceType: DEVICE_TYPE_TILT_SENSOR},
+	{Key: ConditionSensorTimer, Name: "Таймер", Unit: "с", Min: 0, Max: 60, Step: 0.5},
+}
+
+// conditionComparators доступные операторы сравнения
+var conditionComparators = []string{"<", "≤", "=", "≠", "≥", ">"}
+
+// findConditionSensor возвращает описание источника по ключу
+func findConditionSensor(key string) ConditionSensorInfo {
+	for _, sensor := range conditionSensors {
+		if sensor.Key == key {
+			return sensor
+		}
+	}
+	return conditionSensors[0]
+}
+
+// SensorCondition условие сравнения значения датчика с порогом
+type SensorCondition struct {
+	Sensor     string
+	Port       byte
+	Comparator string
+	Value      float64
+	Timeout    float64 // секунды, 0 = без ограничения
+}
+
+// setDefaultConditionParameters заполняет параметры условия значениями по умолчанию
+func setDefaultConditionParameters(params map[string]interface{}) {
+	params["sensor"] = ConditionSensorDistance
+	params["port"] = byte(1)
+	params["comparator"] = "<"
+	params["value"] = 15.0
+}
+
+// conditionFromParameters читает условие из параметров блока
+func conditionFromParameters(params map[string]interface{}) SensorCondition {
+	return SensorCondition{
+		Sensor:     paramString(params, "sensor", ConditionSensorDistance),
+		Port:       paramByte(params, "port", 1),
+		Comparator: paramString(params, "comparator", "<"),
+		Value:      paramFloat(params, "value", 0),
+		Timeout:    paramFloat(params, "timeout", 0),
+	}
+}
+
+// Matches проверяет, выполняется ли условие для значения
+func (c SensorCondition) Matches(value float64) bool {
+	switch c.Comparator {
+	case "<":
+		return value < c.Value
+	case "≤":
+		return value <= c.Value
+	case "=":
+		return value == c.Value
+	case "≠":
+		return value != c.Value
+	case "≥":
+		return value >= c.Value
+	case ">":
+		return value > c.Value
+	default:
+		return false
+	}
+}
+
+// String возвращает текстовое описание условия
+func (c SensorCondition) String() string {
+	sensor := findConditionSensor(c.Sensor)
+	text := fmt.Sprintf("%s %s %g", sensor.Name, c.Comparator, c.Value)
+	if sensor.UsesPort {
+		text = fmt.Sprintf("%s (порт %d) %s %g", sensor.Name, c.Port, c.Comparator, c.Value)
+	}
+	if sensor.Unit != "" {
+		text += " " + sensor.Unit
+	}
+	return text
+}
+
+// waitUntil ждет выполнения условия по потоку уведомлений датчика
+func (pm *ProgramManager) waitUntil(ctx context.Context, cond SensorCondition) error {
+	if !findConditionSensor(cond.Sensor).UsesPort {
+		return pm.pollUntil(ctx, cond)
+	}
+
+	matched := make(chan struct{}, 1)
+
+	// Признак «объект обнаружен» приходит своим событием при смене состояния
+	eventType := EventSensorValue
+	if cond.Sensor == ConditionSensorObject {
+		eventType = EventObjectDetected
+	}
+	unsubscribe := pm.hubMgr.Events().Subscribe(eventType, func(event Event) {
+		if event.PortID == cond.Port && pm.conditionSensorConnected(cond) && cond.Matches(event.Value) {
+			select {
+			case matched <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	// Условие может уже выполняться
+	if value, ok := pm.conditionValue(cond); ok && cond.Matches(value) {
+		log.Printf("Условие уже выполнено: %s (значение %g)", cond, value)
+		return nil
+	}
+
+	var timeout <-chan time.Time
+	if cond.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(cond.Timeout * float64(time.Second)))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	log.Printf("Ожидание условия: %s", cond)
+
+	select {
+	case <-matched:
+		log.Printf("Условие выполнено: %s", cond)
+		return nil
+	case <-timeout:
+		log.Printf("Время ожидания условия истекло (%.1f с): %s", cond.Timeout, cond)
+		return fmt.Errorf("%w (%.1f с): %s", errConditionTimeout, cond.Timeout, cond)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Режимы цикла
+const (
+	LoopModeCount   = "count"   // Определенное число раз
+	LoopModeForever = "forever" // Бесконечно
+	LoopModeUntil   = "until"   // Пока не выполнится условие
+)
+
+// loopModeFromParameters возвращает режим цикла с учетом старого параметра forever
+func loopModeFromParameters(params map[string]interface{}) string {
+	if mode, ok := params["mode"].(string); ok {
+		return mode
+	}
+	if forever, ok := params["forever"].(bool); ok && forever {
+		return LoopModeForever
+	}
+	return LoopModeCount
+}
+
+// pollUntil периодически проверяет условие для источников без уведомлений (таймер)
+func (pm *ProgramManager) pollUntil(ctx context.Context, cond SensorCondition) error {
+	var deadline time.Time
+	if cond.Timeout > 0 {
+		deadline = time.Now().Add(time.Duration(cond.Timeout * float64(time.Second)))
+	}
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	log.Printf("Ожидание условия: %s", cond)
+
+	for {
+		if pm.evaluateCondition(cond) {
+			log.Printf("Условие выполнено: %s", cond)
+			return nil
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			log.Printf("Время ожидания условия истекло (%.1f с): %s", cond.Timeout, cond)
+			return fmt.Errorf("%w (%.1f с): %s", errConditionTimeout, cond.Timeout, cond)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// conditionSensorConnected проверяет, что на порту условия подключен датчик
+// нужного типа: значение другого датчика на том же порту условие не выполняет
+func (pm *ProgramManager) conditionSensorConnected(cond SensorCondition) bool {
+	info := findConditionSensor(cond.Sensor)
+	return !info.UsesPort || pm.deviceMgr.IsDeviceConnected(cond.Port, info.DeviceType)
+}
+
+// conditionValue возвращает текущее значение источника условия
+func (pm *ProgramManager) conditionValue(cond SensorCondition) (float64, bool) {
+	if !pm.conditionSensorConnected(cond) {
+		return 0, false
+	}
+	switch cond.Sensor {
+	case ConditionSensorTimer:
+		return pm.TimerSeconds(), true
+	case ConditionSensorObject:
+		detected, ok := pm.hubMgr.ObjectDetected(cond.Port)
+		return boolValue(detected), ok
+	default:
+		return pm.hubMgr.GetSensorValue(cond.Port)
+	}
+}
+
+// evaluateCondition проверяет условие по последнему значению источника
+func (pm *ProgramManager) evaluateCondition(cond SensorCondition) bool {
+	value, ok := pm.conditionValue(cond)
+	if !ok {
+		log.Printf("Нет значения датчика на порту %d, условие считается невыполненным", cond.Port)
+		return false
+	}
+	return cond.Matches(value)
+}
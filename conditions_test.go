@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// publishSensorValue публикует значение датчика, пока не закроется done
+func publishSensorValue(hub *fakeHub, port byte, value float64, done <-chan struct{}) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			hub.Events().Publish(Event{Type: EventSensorValue, PortID: port, Value: value})
+		}
+	}
+}
+
+func TestWaitUntilReportsTimeout(t *testing.T) {
+	pm, _, _ := newTestProgramManager()
+
+	for _, cond := range []SensorCondition{
+		{Sensor: ConditionSensorDistance, Port: 1, Comparator: "<", Value: 15, Timeout: 0.05},
+		{Sensor: ConditionSensorTimer, Comparator: ">", Value: 100, Timeout: 0.05},
+	} {
+		if err := pm.waitUntil(context.Background(), cond); !errors.Is(err, errConditionTimeout) {
+			t.Errorf("%s: ошибка %v, ожидалось истечение времени", cond, err)
+		}
+	}
+}
+
+func TestWaitUntilMatchesSensorType(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	cond := SensorCondition{Sensor: ConditionSensorDistance, Port: 1, Comparator: "<", Value: 15, Timeout: 0.3}
+
+	done := make(chan struct{})
+	defer close(done)
+	go publishSensorValue(hub, 1, 5, done)
+
+	// На порту датчик наклона: его значение не выполняет условие расстояния
+	actuator.setDevices(map[byte]byte{1: DEVICE_TYPE_TILT_SENSOR})
+	if err := pm.waitUntil(context.Background(), cond); !errors.Is(err, errConditionTimeout) {
+		t.Errorf("значение датчика наклона: ошибка %v, ожидалось истечение времени", err)
+	}
+
+	actuator.setDevices(map[byte]byte{1: DEVICE_TYPE_MOTION_SENSOR})
+	if err := pm.waitUntil(context.Background(), cond); err != nil {
+		t.Errorf("значение датчика расстояния не выполнило условие: %v", err)
+	}
+}
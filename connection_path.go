@@ -0,0 +1,324 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Параметры отрисовки и трассировки соединений
+const (
+	connectionStub          = 20 // Вертикальный отступ от коннектора перед поворотом
+	connectionLaneGap       = 30 // Зазор между обходным путем и блоками
+	connectionCurveSegments = 16 // Число отрезков, которыми рисуется кривая
+	connectionArrowSize     = 12
+	connectionHoverDistance = 6 // Расстояние до пути, при котором он подсвечивается
+	connectionPadding       = connectionArrowSize
+)
+
+// Цвета соединений
+var (
+	connectionColor          = color.NRGBA{R: 0, G: 150, B: 255, A: 255}
+	connectionHighlightColor = color.NRGBA{R: 255, G: 215, B: 0, A: 255}
+	connectionHoverColor     = color.NRGBA{R: 130, G: 210, B: 255, A: 255}
+	connectionSelectedColor  = color.NRGBA{R: 100, G: 220, B: 120, A: 255}
+)
+
+// blockRect прямоугольник блока на холсте
+type blockRect struct {
+	Min, Max fyne.Position
+}
+
+// newBlockRect возвращает прямоугольник, занимаемый виджетом блока
+func newBlockRect(d *DraggableBlock) blockRect {
+	pos := d.Position()
+	size := d.Size()
+	return blockRect{Min: pos, Max: pos.Add(fyne.NewPos(size.Width, size.Height))}
+}
+
+// contains проверяет, лежит ли точка внутри прямоугольника
+func (r blockRect) contains(p fyne.Position) bool {
+	return p.X > r.Min.X && p.X < r.Max.X && p.Y > r.Min.Y && p.Y < r.Max.Y
+}
+
+// routeConnection прокладывает путь от нижнего коннектора одного блока к верхнему
+// коннектору другого. Если следующий блок ниже и кривая не задевает другие блоки,
+// путь — плавная кривая Безье; иначе — ломаная, обходящая блоки справа.
+func routeConnection(from, to fyne.Position, fromRect, toRect blockRect, obstacles []blockRect) []fyne.Position {
+	if to.Y-from.Y >= 2*connectionStub {
+		curve := bezierPath(from, to)
+		if !pathCrosses(curve, obstacles) {
+			return curve
+		}
+	}
+
+	top := fyne.Min(from.Y, to.Y) - connectionStub
+	bottom := fyne.Max(from.Y, to.Y) + connectionStub
+
+	// Полоса обхода правее обоих блоков и всех блоков, мешающих по вертикали
+	lane := fyne.Max(fromRect.Max.X, toRect.Max.X) + connectionLaneGap
+	for moved := true; moved; {
+		moved = false
+		for _, r := range obstacles {
+			overlapsY := r.Max.Y > top && r.Min.Y < bottom
+			if overlapsY && lane > r.Min.X-connectionLaneGap && lane < r.Max.X+connectionLaneGap {
+				lane = r.Max.X + connectionLaneGap
+				moved = true
+			}
+		}
+	}
+
+	return []fyne.Position{
+		from,
+		fyne.NewPos(from.X, from.Y+connectionStub),
+		fyne.NewPos(lane, from.Y+connectionStub),
+		fyne.NewPos(lane, to.Y-connectionStub),
+		fyne.NewPos(to.X, to.Y-connectionStub),
+		to,
+	}
+}
+
+// bezierPath возвращает точки кубической кривой Безье с вертикальными касательными на концах
+func bezierPath(from, to fyne.Position) []fyne.Position {
+	d := (to.Y - from.Y) / 2
+	c1 := fyne.NewPos(from.X, from.Y+d)
+	c2 := fyne.NewPos(to.X, to.Y-d)
+
+	points := make([]fyne.Position, 0, connectionCurveSegments+1)
+	for i := 0; i <= connectionCurveSegments; i++ {
+		t := float32(i) / connectionCurveSegments
+		u := 1 - t
+		points = append(points, fyne.NewPos(
+			u*u*u*from.X+3*u*u*t*c1.X+3*u*t*t*c2.X+t*t*t*to.X,
+			u*u*u*from.Y+3*u*u*t*c1.Y+3*u*t*t*c2.Y+t*t*t*to.Y,
+		))
+	}
+	return points
+}
+
+// pathCrosses проверяет, проходит ли путь через какой-либо из прямоугольников
+func pathCrosses(points []fyne.Position, obstacles []blockRect) bool {
+	for _, p := range points {
+		for _, r := range obstacles {
+			if r.contains(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// distanceToSegment возвращает расстояние от точки до отрезка
+func distanceToSegment(p, a, b fyne.Position) float32 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSq := dx*dx + dy*dy
+	t := float32(0)
+	if lengthSq > 0 {
+		t = ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / lengthSq
+		t = fyne.Max(0, fyne.Min(1, t))
+	}
+	cx, cy := a.X+t*dx-p.X, a.Y+t*dy-p.Y
+	return float32(math.Sqrt(float64(cx*cx + cy*cy)))
+}
+
+// ConnectionPath путь соединения блоков: кривая или ломаная со стрелкой на конце.
+// Путь подсвечивается целиком при наведении мыши.
+type ConnectionPath struct {
+	widget.BaseWidget
+
+	points      []fyne.Position // Точки пути в координатах холста
+	segments    []*canvas.Line
+	arrow       *canvas.Polygon
+	objects     []fyne.CanvasObject
+	lineColor   color.Color
+	strokeWidth float32
+	hovered     bool
+
+	// Вызываются при клике по самому пути, а не по пустому месту рядом
+	OnTapped          func()
+	OnTappedSecondary func(absolute fyne.Position)
+}
+
+// NewConnectionPath создает путь соединения
+func NewConnectionPath() *ConnectionPath {
+	c := &ConnectionPath{
+		arrow:       canvas.NewPolygon(3, connectionColor),
+		lineColor:   connectionColor,
+		strokeWidth: 2,
+	}
+	c.arrow.Resize(fyne.NewSize(connectionArrowSize, connectionArrowSize))
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetPoints задает точки пути в координатах холста
+func (c *ConnectionPath) SetPoints(points []fyne.Position) {
+	c.points = points
+	if len(points) < 2 {
+		c.Hide()
+		return
+	}
+
+	// Виджет занимает ограничивающий прямоугольник пути с запасом под стрелку
+	minPos, maxPos := points[0], points[0]
+	for _, p := range points[1:] {
+		minPos = fyne.NewPos(fyne.Min(minPos.X, p.X), fyne.Min(minPos.Y, p.Y))
+		maxPos = fyne.NewPos(fyne.Max(maxPos.X, p.X), fyne.Max(maxPos.Y, p.Y))
+	}
+	origin := minPos.SubtractXY(connectionPadding, connectionPadding)
+	c.Move(origin)
+	c.Resize(fyne.NewSize(maxPos.X-minPos.X+2*connectionPadding, maxPos.Y-minPos.Y+2*connectionPadding))
+
+	// Отрезки переиспользуются, если число точек не изменилось
+	for len(c.segments) < len(points)-1 {
+		c.segments = append(c.segments, canvas.NewLine(c.lineColor))
+	}
+	c.segments = c.segments[:len(points)-1]
+	for i, line := range c.segments {
+		line.Position1 = points[i].Subtract(origin)
+		line.Position2 = points[i+1].Subtract(origin)
+	}
+
+	// Стрелка направлена вдоль последнего отрезка и касается коннектора вершиной
+	tip, prev := points[len(points)-1], points[len(points)-2]
+	dx, dy := tip.X-prev.X, tip.Y-prev.Y
+	length := float32(math.Sqrt(float64(dx*dx + dy*dy)))
+	if length > 0 {
+		dx, dy = dx/length, dy/length
+	} else {
+		dx, dy = 0, 1
+	}
+	half := float32(connectionArrowSize) / 2
+	center := tip.Subtract(origin).SubtractXY(dx*half, dy*half)
+	c.arrow.Angle = float32(math.Atan2(float64(dx), float64(-dy)) * 180 / math.Pi)
+	c.arrow.Move(center.SubtractXY(half, half))
+
+	c.objects = make([]fyne.CanvasObject, 0, len(c.segments)+1)
+	for _, line := range c.segments {
+		c.objects = append(c.objects, line)
+	}
+	c.objects = append(c.objects, c.arrow)
+
+	c.Show()
+	c.applyStyle()
+}
+
+// Points возвращает точки пути в координатах холста
+func (c *ConnectionPath) Points() []fyne.Position {
+	return c.points
+}
+
+// samePoints проверяет, совпадает ли путь с заданными точками
+func (c *ConnectionPath) samePoints(points []fyne.Position) bool {
+	if len(c.points) != len(points) {
+		return false
+	}
+	for i := range points {
+		if c.points[i] != points[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SetStyle задает цвет и толщину пути
+func (c *ConnectionPath) SetStyle(lineColor color.Color, strokeWidth float32) {
+	c.lineColor = lineColor
+	c.strokeWidth = strokeWidth
+	c.applyStyle()
+}
+
+// applyStyle перекрашивает отрезки и стрелку с учетом наведения мыши
+func (c *ConnectionPath) applyStyle() {
+	lineColor, strokeWidth := c.lineColor, c.strokeWidth
+	if c.hovered {
+		lineColor = connectionHoverColor
+		strokeWidth = c.strokeWidth + 1
+	}
+
+	for _, line := range c.segments {
+		line.StrokeColor = lineColor
+		line.StrokeWidth = strokeWidth
+	}
+	c.arrow.FillColor = lineColor
+	c.Refresh()
+}
+
+// distanceTo возвращает расстояние от точки в координатах виджета до пути
+func (c *ConnectionPath) distanceTo(pos fyne.Position) float32 {
+	best := float32(math.MaxFloat32)
+	for _, line := range c.segments {
+		best = fyne.Min(best, distanceToSegment(pos, line.Position1, line.Position2))
+	}
+	return best
+}
+
+// setHovered меняет подсветку пути при наведении
+func (c *ConnectionPath) setHovered(hovered bool) {
+	if c.hovered == hovered {
+		return
+	}
+	c.hovered = hovered
+	c.applyStyle()
+}
+
+// MouseIn начало наведения мыши (для интерфейса desktop.Hoverable)
+func (c *ConnectionPath) MouseIn(e *desktop.MouseEvent) {
+	c.setHovered(c.distanceTo(e.Position) <= connectionHoverDistance)
+}
+
+// MouseMoved движение мыши над путем
+func (c *ConnectionPath) MouseMoved(e *desktop.MouseEvent) {
+	c.setHovered(c.distanceTo(e.Position) <= connectionHoverDistance)
+}
+
+// MouseOut окончание наведения мыши
+func (c *ConnectionPath) MouseOut() {
+	c.setHovered(false)
+}
+
+// Tapped обработка клика по пути
+func (c *ConnectionPath) Tapped(e *fyne.PointEvent) {
+	if c.OnTapped != nil && c.distanceTo(e.Position) <= connectionHoverDistance {
+		c.OnTapped()
+	}
+}
+
+// TappedSecondary обработка правого клика по пути
+func (c *ConnectionPath) TappedSecondary(e *fyne.PointEvent) {
+	if c.OnTappedSecondary != nil && c.distanceTo(e.Position) <= connectionHoverDistance {
+		c.OnTappedSecondary(e.AbsolutePosition)
+	}
+}
+
+// CreateRenderer создает рендерер пути
+func (c *ConnectionPath) CreateRenderer() fyne.WidgetRenderer {
+	return &connectionPathRenderer{path: c}
+}
+
+// connectionPathRenderer рендерер пути соединения
+type connectionPathRenderer struct {
+	path *ConnectionPath
+}
+
+func (r *connectionPathRenderer) Layout(size fyne.Size) {}
+
+func (r *connectionPathRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+func (r *connectionPathRenderer) Refresh() {
+	for _, obj := range r.path.objects {
+		obj.Refresh()
+	}
+}
+
+func (r *connectionPathRenderer) Destroy() {}
+
+func (r *connectionPathRenderer) Objects() []fyne.CanvasObject {
+	return r.path.objects
+}
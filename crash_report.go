@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	crashLogLines   = 500            // Сколько последних строк журнала попадает в отчет
+	crashReportFlag = "crash-report" // Флаг перезапуска с папкой отчета о сбое
+
+	crashTimesEnv      = "WEDOPROG_CRASH_TIMES" // Время недавних сбоев, передается перезапущенному процессу
+	crashRestartLimit  = 3                      // Сколько сбоев подряд приводят к перезапуску
+	crashRestartWindow = 2 * time.Minute        // За какое время считаются сбои подряд
+	crashCollectDelay  = 200 * time.Millisecond // Ожидание одновременных паник в других горутинах
+)
+
+// macAddressPattern MAC-адреса хабов вырезаются из отчета
+var macAddressPattern = regexp.MustCompile(`(?i)\b([0-9a-f]{2}[:-]){5}[0-9a-f]{2}\b`)
+
+// LogBuffer хранит последние строки журнала для отчета о сбое
+type LogBuffer struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// Write принимает вывод пакета log
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines = append(b.lines, strings.TrimRight(string(p), "\n"))
+	if len(b.lines) > crashLogLines {
+		b.lines = b.lines[len(b.lines)-crashLogLines:]
+	}
+	return len(p), nil
+}
+
+// String возвращает сохраненные строки журнала
+func (b *LogBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Join(b.lines, "\n") + "\n"
+}
+
+// crashPanic паника одной горутины
+type crashPanic struct {
+	reason interface{}
+	stack  []byte
+}
+
+// CrashReporter перехватывает панику и сохраняет набор диагностики на диск
+type CrashReporter struct {
+	logs *LogBuffer
+	gui  *MainGUI
+
+	mu     sync.Mutex
+	panics []crashPanic
+}
+
+// NewCrashReporter начинает собирать журнал для отчетов о сбоях
+func NewCrashReporter() *CrashReporter {
+	reporter := &CrashReporter{logs: &LogBuffer{}}
+	log.SetOutput(io.MultiWriter(os.Stderr, reporter.logs))
+	return reporter
+}
+
+// Attach передает отчету доступ к программе и хабу и перехватывает панику при
+// выполнении программы, в связи с хабом и в экономии батареи
+func (r *CrashReporter) Attach(gui *MainGUI) {
+	r.gui = gui
+	gui.programMgr.SetCrashHandler(r.Recover)
+	gui.hubMgr.SetCrashHandler(r.Recover)
+	gui.batterySaver.SetCrashHandler(r.Recover)
+}
+
+// Recover вызывается через defer на верхнем уровне горутины: сохраняет отчет
+// и перезапускает приложение, чтобы урок мог продолжиться. Отчет пишет первая
+// паника; паники других горутин, случившиеся одновременно, попадают в тот же отчет
+func (r *CrashReporter) Recover() {
+	reason := recover()
+	if reason == nil {
+		return
+	}
+	stack := debug.Stack()
+	log.Printf("Аварийное завершение: %v", reason)
+
+	r.mu.Lock()
+	r.panics = append(r.panics, crashPanic{reason: reason, stack: stack})
+	first := len(r.panics) == 1
+	r.mu.Unlock()
+	if !first {
+		// Процесс завершит горутина первой паники
+		select {}
+	}
+
+	time.Sleep(crashCollectDelay)
+	r.mu.Lock()
+	panics := append([]crashPanic(nil), r.panics...)
+	r.mu.Unlock()
+
+	dir, err := r.writeBundle(panics)
+	if err != nil {
+		log.Printf("Не удалось сохранить отчет о сбое: %v", err)
+	}
+
+	now := time.Now()
+	crashes := recentCrashes(os.Getenv(crashTimesEnv), now)
+	if len(crashes) >= crashRestartLimit {
+		log.Printf("Приложение аварийно завершилось %d раз за %v, перезапуск отменен", len(crashes)+1, crashRestartWindow)
+		os.Exit(2)
+	}
+
+	restart := exec.Command(os.Args[0], restartArgs(os.Args[1:], dir)...)
+	restart.Env = append(os.Environ(), crashTimesEnv+"="+formatCrashTimes(append(crashes, now)))
+	if err := restart.Start(); err != nil {
+		log.Printf("Не удалось перезапустить приложение: %v", err)
+	}
+	os.Exit(2)
+}
+
+// restartArgs повторяет аргументы запуска и заменяет в них папку отчета о сбое.
+// Пустой dir означает, что отчет сохранить не удалось
+func restartArgs(args []string, dir string) []string {
+	var restart []string
+	for i := 0; i < len(args); i++ {
+		name := strings.TrimLeft(args[i], "-")
+		switch {
+		case !strings.HasPrefix(args[i], "-"):
+		case name == crashReportFlag:
+			i++ // Пропускаем и значение флага
+			continue
+		case strings.HasPrefix(name, crashReportFlag+"="):
+			continue
+		}
+		restart = append(restart, args[i])
+	}
+	if dir != "" {
+		restart = append(restart, "-"+crashReportFlag, dir)
+	}
+	return restart
+}
+
+// recentCrashes разбирает время прошлых сбоев и оставляет случившиеся за crashRestartWindow
+func recentCrashes(value string, now time.Time) []time.Time {
+	var crashes []time.Time
+	for _, field := range strings.Split(value, ",") {
+		seconds, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			continue
+		}
+		if at := time.Unix(seconds, 0); now.Sub(at) <= crashRestartWindow {
+			crashes = append(crashes, at)
+		}
+	}
+	return crashes
+}
+
+// formatCrashTimes записывает время сбоев для переменной окружения crashTimesEnv
+func formatCrashTimes(crashes []time.Time) string {
+	fields := make([]string, len(crashes))
+	for i, at := range crashes {
+		fields[i] = strconv.FormatInt(at.Unix(), 10)
+	}
+	return strings.Join(fields, ",")
+}
+
+// writeBundle записывает набор диагностики в новую папку и возвращает ее путь
+func (r *CrashReporter) writeBundle(panics []crashPanic) (string, error) {
+	root, err := os.UserConfigDir()
+	if err != nil {
+		root = os.TempDir()
+	}
+	dir := filepath.Join(root, "WeDoProg", "crash-reports", time.Now().Format("2006-01-02_15-04-05"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	var report strings.Builder
+	for i, p := range panics {
+		if i > 0 {
+			report.WriteString("\n")
+		}
+		fmt.Fprintf(&report, "%v\n\n%s", p.reason, p.stack)
+	}
+
+	files := map[string]string{
+		"panic.txt":  report.String(),
+		"log.txt":    r.logs.String(),
+		"system.txt": r.systemInfo(),
+	}
+	if r.gui != nil {
+		files["ble_trace.txt"] = r.gui.hubMgr.Trace().String()
+		if data, err := EncodeProgram(r.gui.programMgr.GetProgram()); err == nil {
+			files["program"+programFileExtension] = string(data)
+		}
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(anonymize(content)), 0o644); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// systemInfo описывает систему и подключенный хаб
+func (r *CrashReporter) systemInfo() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Время: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "ОС: %s/%s, процессоров: %d\n", runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	fmt.Fprintf(&b, "Go: %s\n", runtime.Version())
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&b, "Сборка: %s\n", info.Main.Version)
+	}
+
+	if r.gui != nil && r.gui.hubMgr != nil {
+		hub := r.gui.hubMgr.GetHubInfo()
+		fmt.Fprintf(&b, "Хаб подключен: %v\n", r.gui.hubMgr.IsConnected())
+		fmt.Fprintf(&b, "Прошивка хаба: %s, ПО: %s, батарея: %d%%\n", hub.FirmwareVersion, hub.SoftwareVersion, hub.Battery)
+	}
+	return b.String()
+}
+
+// anonymize убирает из отчета MAC-адреса и путь к домашней папке пользователя
+func anonymize(text string) string {
+	text = macAddressPattern.ReplaceAllString(text, "XX:XX:XX:XX:XX:XX")
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		text = strings.ReplaceAll(text, home, "~")
+	}
+	return text
+}
+
+// showCrashReportDialog сообщает о предыдущем сбое и предлагает открыть папку отчета
+func (gui *MainGUI) showCrashReportDialog(dir string) {
+	message := widget.NewLabel(fmt.Sprintf("WeDoProg аварийно завершился и был перезапущен.\n"+
+		"Отчет о сбое сохранен в папке:\n%s\n\n"+
+		"Отчет не содержит адресов хабов и личных данных. Передайте его разработчикам.", dir))
+	message.Wrapping = fyne.TextWrapWord
+
+	dialog.ShowCustomConfirm("Приложение было перезапущено", "Открыть папку", "Закрыть", message,
+		func(open bool) {
+			if !open {
+				return
+			}
+			if err := fyne.CurrentApp().OpenURL(&url.URL{Scheme: "file", Path: dir}); err != nil {
+				dialog.ShowError(err, gui.window)
+			}
+		}, gui.window)
+}
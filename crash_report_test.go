@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRestartArgsKeepsLaunchFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		dir  string
+		want []string
+	}{
+		{nil, "/r", []string{"-crash-report", "/r"}},
+		{[]string{"-kiosk", "/exam", "-profile=teacher"}, "/r", []string{"-kiosk", "/exam", "-profile=teacher", "-crash-report", "/r"}},
+		{[]string{"-crash-report", "/old", "-kiosk", "/exam"}, "/new", []string{"-kiosk", "/exam", "-crash-report", "/new"}},
+		{[]string{"--crash-report=/old", "-kiosk=/exam"}, "/new", []string{"-kiosk=/exam", "-crash-report", "/new"}},
+		{[]string{"-kiosk", "/exam", "-crash-report", "/old"}, "", []string{"-kiosk", "/exam"}},
+	}
+	for _, tt := range tests {
+		if got := restartArgs(tt.args, tt.dir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("restartArgs(%q, %q) = %q, ожидалось %q", tt.args, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestRecentCrashesStopsRestartLoop(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	var crashes []time.Time
+	for i := 0; i < crashRestartLimit; i++ {
+		crashes = recentCrashes(formatCrashTimes(crashes), now)
+		if len(crashes) >= crashRestartLimit {
+			t.Fatalf("перезапуск отменен после %d сбоев, ожидалось %d", i, crashRestartLimit)
+		}
+		crashes = append(crashes, now)
+	}
+	if got := recentCrashes(formatCrashTimes(crashes), now); len(got) < crashRestartLimit {
+		t.Errorf("после %d сбоев подряд учтено %d, перезапуск не остановлен", crashRestartLimit, len(got))
+	}
+
+	// Старые сбои не мешают перезапуску
+	later := now.Add(crashRestartWindow + time.Second)
+	if got := recentCrashes(formatCrashTimes(crashes), later); len(got) != 0 {
+		t.Errorf("сбои старше %v учтены: %v", crashRestartWindow, got)
+	}
+	if got := recentCrashes("мусор,,12x", now); len(got) != 0 {
+		t.Errorf("из неверного значения прочитаны сбои %v", got)
+	}
+}
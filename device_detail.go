@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	detailFrameLimit      = 50                     // Сколько последних кадров порта показывать
+	detailRefreshInterval = 250 * time.Millisecond // Период обновления списка кадров
+)
+
+// showDeviceDetail открывает окно устройства: режимы, текущее значение,
+// кадры обмена с портом и ручное управление исполнительными устройствами
+func (gui *MainGUI) showDeviceDetail(portID byte) {
+	device, exists := gui.deviceMgr.GetDevice(portID)
+	if !exists {
+		return
+	}
+
+	window := fyne.CurrentApp().NewWindow(fmt.Sprintf("Порт %d: %s", portID, DeviceTypeName(device.DeviceType)))
+
+	state := widget.NewLabel("Подключено")
+	value := widget.NewLabelWithStyle("—", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	frames := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	framesScroll := container.NewVScroll(frames)
+	framesScroll.SetMinSize(fyne.NewSize(0, 200))
+
+	form := widget.NewForm(
+		widget.NewFormItem("Устройство", widget.NewLabel(fmt.Sprintf("%s (тип 0x%02x)", DeviceTypeName(device.DeviceType), device.DeviceType))),
+		widget.NewFormItem("Состояние", state),
+		widget.NewFormItem("Значение", value),
+	)
+	if modes := gui.newDeviceModeSelect(device, window); modes != nil {
+		form.Append("Режим", modes)
+	}
+	unsubscribeObject := func() {}
+	if device.DeviceType == DEVICE_TYPE_MOTION_SENSOR {
+		var indicator fyne.CanvasObject
+		indicator, unsubscribeObject = gui.newObjectIndicator(portID)
+		form.Append("Объект", indicator)
+	}
+
+	content := container.NewVBox(form)
+	updateOrientation := func() {}
+	if device.DeviceType == DEVICE_TYPE_TILT_SENSOR {
+		var orientation fyne.CanvasObject
+		orientation, updateOrientation = gui.newTiltOrientationView(portID)
+		content.Add(widget.NewCard("Положение", "", orientation))
+	}
+	if controls := gui.newDeviceControls(device, window); controls != nil {
+		content.Add(widget.NewCard("Управление", "", controls))
+	}
+	if device.DeviceType == DEVICE_TYPE_MOTION_SENSOR {
+		content.Add(widget.NewButton("Калибровать расстояние...", func() {
+			gui.showDistanceCalibration(portID, window)
+		}))
+	}
+	content.Add(widget.NewLabel("Кадры обмена с портом"))
+
+	// Значение датчика приходит по шине событий, кадры читаются из журнала обмена
+	unsubscribeValue := gui.hubMgr.Events().Subscribe(EventSensorValue, func(event Event) {
+		if event.PortID != portID {
+			return
+		}
+		text := gui.units.Format(device.DeviceType, event.Value)
+		fyne.Do(func() { value.SetText(text) })
+	})
+	unsubscribeDevice := gui.deviceMgr.Subscribe(func(changed byte, snapshot *Device) {
+		if changed != portID {
+			return
+		}
+		text := "Подключено"
+		if !snapshot.IsConnected {
+			text = "Отключено"
+		}
+		fyne.Do(func() { state.SetText(text) })
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(detailRefreshInterval)
+		defer ticker.Stop()
+		last := ""
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// Пока открыто окно устройства, за показаниями следят
+				gui.batterySaver.Touch()
+				fyne.Do(updateOrientation)
+				text := strings.Join(gui.hubMgr.Trace().PortFrames(portID, detailFrameLimit), "\n")
+				if text == last {
+					continue
+				}
+				last = text
+				fyne.Do(func() {
+					frames.SetText(text)
+					framesScroll.ScrollToBottom()
+				})
+			}
+		}
+	}()
+
+	window.SetOnClosed(func() {
+		close(done)
+		unsubscribeValue()
+		unsubscribeDevice()
+		unsubscribeObject()
+	})
+	window.SetContent(container.NewBorder(content, nil, nil, nil, framesScroll))
+	height := float32(520)
+	if device.DeviceType == DEVICE_TYPE_TILT_SENSOR {
+		height += cubeMinSize + 80
+	}
+	window.Resize(fyne.NewSize(560, height))
+	window.Show()
+}
+
+// newObjectIndicator создает индикатор признака «объект обнаружен» датчика
+// расстояния и возвращает его вместе с функцией отписки от обновлений
+func (gui *MainGUI) newObjectIndicator(portID byte) (fyne.CanvasObject, func()) {
+	circle := canvas.NewCircle(color.Transparent)
+	label := widget.NewLabel("")
+	show := func(detected, ok bool) {
+		switch {
+		case !ok:
+			circle.FillColor = color.Transparent
+			label.SetText("нет данных")
+		case detected:
+			circle.FillColor = theme.Color(theme.ColorNameSuccess)
+			label.SetText("обнаружен")
+		default:
+			circle.FillColor = theme.Color(theme.ColorNameDisabled)
+			label.SetText("не обнаружен")
+		}
+		circle.Refresh()
+	}
+	show(gui.hubMgr.ObjectDetected(portID))
+
+	unsubscribe := gui.hubMgr.Events().Subscribe(EventObjectDetected, func(event Event) {
+		if event.PortID != portID {
+			return
+		}
+		fyne.Do(func() { show(event.Value != 0, true) })
+	})
+
+	indicatorSize := fyne.NewSquareSize(theme.IconInlineSize() / 2)
+	return container.NewHBox(container.NewCenter(container.NewGridWrap(indicatorSize, circle)), label), unsubscribe
+}
+
+// newDeviceModeSelect создает выбор режима порта или nil, если переключать нечего
+func (gui *MainGUI) newDeviceModeSelect(device *Device, window fyne.Window) fyne.CanvasObject {
+	modes := deviceModes[device.DeviceType]
+	if len(modes) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(modes))
+	for i, mode := range modes {
+		names[i] = mode.Name
+	}
+
+	selectMode := widget.NewSelect(names, nil)
+	if current, ok := gui.hubMgr.PortMode(device.PortID); ok {
+		for _, mode := range modes {
+			if mode.Mode == current {
+				selectMode.SetSelected(mode.Name)
+			}
+		}
+	}
+	selectMode.OnChanged = func(name string) {
+		for _, mode := range modes {
+			if mode.Name != name {
+				continue
+			}
+			cmd := NewInputFormatCommand(device.PortID, device.DeviceType).Mode(mode.Mode)
+			if err := gui.hubMgr.ConfigurePort(cmd); err != nil {
+				dialog.ShowError(fmt.Errorf("не удалось переключить режим: %v", err), window)
+			}
+		}
+	}
+	if len(modes) == 1 {
+		selectMode.Disable()
+	}
+	return selectMode
+}
+
+// newDeviceControls создает ручное управление для моторов, светодиодов и пищалки
+func (gui *MainGUI) newDeviceControls(device *Device, window fyne.Window) fyne.CanvasObject {
+	portID := device.PortID
+	report := func(err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+		}
+	}
+
+	switch device.DeviceType {
+	case DEVICE_TYPE_MOTOR:
+		power := widget.NewSlider(-100, 100)
+		power.Step = 5
+		power.SetValue(50)
+		powerLabel := widget.NewLabel("50%")
+		power.OnChanged = func(v float64) { powerLabel.SetText(fmt.Sprintf("%.0f%%", v)) }
+
+		return container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel("Мощность"), powerLabel, power),
+			container.NewHBox(
+				widget.NewButton("Пуск", func() { report(gui.deviceMgr.Manual().SetMotorPower(portID, int8(power.Value), 0)) }),
+				widget.NewButton("Стоп", func() { report(gui.deviceMgr.Manual().StopMotor(portID)) }),
+			),
+		)
+
+	case DEVICE_TYPE_RGB_LIGHT:
+		channels := make([]*widget.Slider, 3)
+		rows := container.NewVBox()
+		for i, name := range []string{"Красный", "Зеленый", "Синий"} {
+			channels[i] = widget.NewSlider(0, 255)
+			rows.Add(container.NewBorder(nil, nil, widget.NewLabel(name), nil, channels[i]))
+		}
+		rows.Add(container.NewHBox(
+			widget.NewButton("Применить", func() {
+				report(gui.deviceMgr.Manual().SetLEDColor(portID,
+					byte(channels[0].Value), byte(channels[1].Value), byte(channels[2].Value)))
+			}),
+			layout.NewSpacer(),
+		))
+		return rows
+
+	case DEVICE_TYPE_PIEZO_TONE:
+		frequency := widget.NewSlider(100, 2000)
+		frequency.Step = 10
+		frequency.SetValue(440)
+		frequencyLabel := widget.NewLabel("440 Гц")
+		frequency.OnChanged = func(v float64) { frequencyLabel.SetText(fmt.Sprintf("%.0f Гц", v)) }
+
+		return container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel("Частота"), frequencyLabel, frequency),
+			container.NewHBox(
+				widget.NewButton("Сыграть", func() { report(gui.deviceMgr.Manual().PlayTone(portID, uint16(frequency.Value), 500)) }),
+				widget.NewButton("Стоп", func() { report(gui.deviceMgr.Manual().StopTone(portID)) }),
+			),
+		)
+	}
+	return nil
+}
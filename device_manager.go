@@ -7,47 +7,116 @@ import (
 	"time"
 )
 
-// DeviceManager управляет устройствами хаба
+// DeviceManager управляет устройствами хаба и единственный хранит их состояние.
+// HubManager записывает сюда обнаруженные устройства и значения датчиков,
+// интерфейс узнает об изменениях через подписку
 type DeviceManager struct {
-	hubMgr    *HubManager
+	hubMgr    HubLink
 	devices   map[byte]*Device
 	devicesMu sync.RWMutex
 
-	// Callback для обновлений GUI
-	deviceChangedCallback func(portID byte, device *Device)
+	// Подписчики на изменения устройств
+	subscribers  map[int]func(portID byte, device *Device)
+	nextSubID    int
+	subscriberMu sync.RWMutex
+
+	// Вызывается при каждой команде ручного управления
+	manualActivityCallback func()
 }
 
 // NewDeviceManager создает менеджер устройств
-func NewDeviceManager(hubMgr *HubManager) *DeviceManager {
+func NewDeviceManager(hubMgr HubLink) *DeviceManager {
 	return &DeviceManager{
-		hubMgr:  hubMgr,
-		devices: make(map[byte]*Device),
+		hubMgr:      hubMgr,
+		devices:     make(map[byte]*Device),
+		subscribers: make(map[int]func(portID byte, device *Device)),
+	}
+}
+
+// Subscribe подписывает обработчик на изменения устройств и возвращает функцию отписки.
+// Обработчик получает копию устройства и вызывается в потоке хаба
+func (dm *DeviceManager) Subscribe(handler func(portID byte, device *Device)) func() {
+	dm.subscriberMu.Lock()
+	defer dm.subscriberMu.Unlock()
+
+	dm.nextSubID++
+	id := dm.nextSubID
+	dm.subscribers[id] = handler
+
+	return func() {
+		dm.subscriberMu.Lock()
+		defer dm.subscriberMu.Unlock()
+		delete(dm.subscribers, id)
+	}
+}
+
+// notify рассылает подписчикам снимок устройства
+func (dm *DeviceManager) notify(portID byte, snapshot Device) {
+	dm.subscriberMu.RLock()
+	handlers := make([]func(byte, *Device), 0, len(dm.subscribers))
+	for _, handler := range dm.subscribers {
+		handlers = append(handlers, handler)
+	}
+	dm.subscriberMu.RUnlock()
+
+	for _, handler := range handlers {
+		device := snapshot
+		handler(portID, &device)
 	}
 }
 
 // AddOrUpdateDevice добавляет или обновляет устройство
 func (dm *DeviceManager) AddOrUpdateDevice(device *Device) {
 	dm.devicesMu.Lock()
-	defer dm.devicesMu.Unlock()
-
+	// Счетчик ошибок относится к порту и переживает повторное обнаружение
+	if previous, exists := dm.devices[device.PortID]; exists {
+		device.Errors = previous.Errors
+	}
 	dm.devices[device.PortID] = device
+	snapshot := *device
+	dm.devicesMu.Unlock()
 
-	// Уведомляем об изменении
-	if dm.deviceChangedCallback != nil {
-		dm.deviceChangedCallback(device.PortID, device)
+	dm.notify(device.PortID, snapshot)
+}
+
+// MarkDisconnected отмечает устройство на порту отключенным
+func (dm *DeviceManager) MarkDisconnected(portID byte) {
+	dm.devicesMu.Lock()
+	device, exists := dm.devices[portID]
+	if !exists {
+		dm.devicesMu.Unlock()
+		return
 	}
+	device.IsConnected = false
+	device.LastUpdate = time.Now()
+	snapshot := *device
+	dm.devicesMu.Unlock()
+
+	log.Printf("Устройство отключено: %s (порт %d)", snapshot.Name, portID)
+	dm.notify(portID, snapshot)
 }
 
-// GetDevice возвращает устройство по порту
+// Reset забывает все устройства, например после отключения хаба
+func (dm *DeviceManager) Reset() {
+	dm.devicesMu.Lock()
+	dm.devices = make(map[byte]*Device)
+	dm.devicesMu.Unlock()
+}
+
+// GetDevice возвращает копию устройства по порту
 func (dm *DeviceManager) GetDevice(portID byte) (*Device, bool) {
 	dm.devicesMu.RLock()
 	defer dm.devicesMu.RUnlock()
 
 	device, exists := dm.devices[portID]
-	return device, exists
+	if !exists {
+		return nil, false
+	}
+	snapshot := *device
+	return &snapshot, true
 }
 
-// GetConnectedDevices возвращает список подключенных устройств
+// GetConnectedDevices возвращает копии подключенных устройств
 func (dm *DeviceManager) GetConnectedDevices() []*Device {
 	dm.devicesMu.RLock()
 	defer dm.devicesMu.RUnlock()
@@ -55,21 +124,19 @@ func (dm *DeviceManager) GetConnectedDevices() []*Device {
 	var connected []*Device
 	for _, device := range dm.devices {
 		if device.IsConnected {
-			connected = append(connected, device)
+			snapshot := *device
+			connected = append(connected, &snapshot)
 		}
 	}
 
 	return connected
 }
 
-// GetDevicesByType возвращает устройства определенного типа
+// GetDevicesByType возвращает копии подключенных устройств определенного типа
 func (dm *DeviceManager) GetDevicesByType(deviceType byte) []*Device {
-	dm.devicesMu.RLock()
-	defer dm.devicesMu.RUnlock()
-
 	var filtered []*Device
-	for _, device := range dm.devices {
-		if device.DeviceType == deviceType && device.IsConnected {
+	for _, device := range dm.GetConnectedDevices() {
+		if device.DeviceType == deviceType {
 			filtered = append(filtered, device)
 		}
 	}
@@ -77,27 +144,27 @@ func (dm *DeviceManager) GetDevicesByType(deviceType byte) []*Device {
 	return filtered
 }
 
+// IsDeviceConnected проверяет, что на порту подключено устройство заданного типа
+func (dm *DeviceManager) IsDeviceConnected(portID byte, deviceType byte) bool {
+	device, exists := dm.GetDevice(portID)
+	return exists && device.IsConnected && device.DeviceType == deviceType
+}
+
 // SetMotorPower устанавливает мощность мотора
 func (dm *DeviceManager) SetMotorPower(portID byte, power int8, duration uint16) error {
-	if !dm.hubMgr.IsConnected() {
+	return dm.setMotorPower(SourceProgram, portID, power, duration)
+}
+
+// setMotorPower устанавливает мощность мотора от имени источника команд
+func (dm *DeviceManager) setMotorPower(source CommandSource, portID byte, power int8, duration uint16) error {
+	if !dm.hubMgr.Ready() {
 		return fmt.Errorf("не подключено к хабу")
 	}
 
-	// Сначала проверяем в своем хранилище
 	device, exists := dm.GetDevice(portID)
 
-	// Если не нашли, проверяем в HubManager
-	if !exists && dm.hubMgr != nil {
-		if hubDevice, hubExists := dm.hubMgr.GetDeviceFromPort(portID); hubExists {
-			device = hubDevice
-			exists = true
-			// Сохраняем для будущего использования
-			dm.AddOrUpdateDevice(device)
-		}
-	}
-
 	if !exists {
-		log.Printf("Устройство на порту %d не найдено ни в DeviceManager, ни в HubManager", portID)
+		log.Printf("Устройство на порту %d не найдено", portID)
 		// Пытаемся выполнить команду даже если устройство не найдено
 		log.Printf("Пытаемся выполнить команду для порта %d без проверки устройства", portID)
 	}
@@ -107,23 +174,9 @@ func (dm *DeviceManager) SetMotorPower(portID byte, power int8, duration uint16)
 		// Все равно пытаемся выполнить команду
 	}
 
-	// Преобразуем мощность в байт
-	var speedByte byte
-	powerFloat := float64(power) / 100.0
-
-	if powerFloat < 0 {
-		speedByte = byte(int(0x54*powerFloat) + 0xF0)
-	} else if powerFloat > 0 {
-		speedByte = byte(int(0x54*powerFloat) + 0x10)
-	} else {
-		speedByte = 0x00
-	}
-
-	cmd := []byte{portID, 0x01, 0x01, speedByte}
-
-	log.Printf("Установка мощности мотора на порту %d: %d%% (байт: 0x%02x)", portID, power, speedByte)
+	devicesLog.Debugf("Установка мощности мотора на порту %d: %d%% (байт: 0x%02x)", portID, power, protocol.EncodeMotorSpeed(power))
 
-	err := dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", cmd)
+	err := dm.hubMgr.SendCommand(NewMotorCommand(portID).Power(int(power)).From(source))
 
 	if err != nil {
 		return err
@@ -131,16 +184,15 @@ func (dm *DeviceManager) SetMotorPower(portID byte, power int8, duration uint16)
 
 	// Если есть длительность, ждем ее завершения
 	if duration > 0 {
-		log.Printf("Мотор на порту %d будет работать %d мс", portID, duration)
+		devicesLog.Debugf("Мотор на порту %d будет работать %d мс", portID, duration)
 
 		// Создаем канал для синхронизации
 		done := make(chan bool)
 
 		go func() {
 			time.Sleep(time.Duration(duration) * time.Millisecond)
-			stopCmd := []byte{portID, 0x01, 0x01, 0x00}
-			dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", stopCmd)
-			log.Printf("Мотор на порту %d автоматически остановлен после %d мс", portID, duration)
+			dm.hubMgr.SendCommand(NewMotorCommand(portID).Stop().From(source))
+			devicesLog.Debugf("Мотор на порту %d автоматически остановлен после %d мс", portID, duration)
 			done <- true
 		}()
 
@@ -154,27 +206,13 @@ func (dm *DeviceManager) SetMotorPower(portID byte, power int8, duration uint16)
 
 // Новая функция: SetMotorPowerAndWait - с ожиданием завершения
 func (dm *DeviceManager) SetMotorPowerAndWait(portID byte, power int8, duration uint16) error {
-	if !dm.hubMgr.IsConnected() {
+	if !dm.hubMgr.Ready() {
 		return fmt.Errorf("не подключено к хабу")
 	}
 
-	// Преобразуем мощность в байт
-	var speedByte byte
-	powerFloat := float64(power) / 100.0
+	devicesLog.Debugf("Установка мощности мотора на порту %d: %d%% на %d мс", portID, power, duration)
 
-	if powerFloat < 0 {
-		speedByte = byte(int(0x54*powerFloat) + 0xF0)
-	} else if powerFloat > 0 {
-		speedByte = byte(int(0x54*powerFloat) + 0x10)
-	} else {
-		speedByte = 0x00
-	}
-
-	cmd := []byte{portID, 0x01, 0x01, speedByte}
-
-	log.Printf("Установка мощности мотора на порту %d: %d%% на %d мс", portID, power, duration)
-
-	err := dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", cmd)
+	err := dm.hubMgr.SendCommand(NewMotorCommand(portID).Power(int(power)))
 
 	if err != nil {
 		return err
@@ -182,16 +220,15 @@ func (dm *DeviceManager) SetMotorPowerAndWait(portID byte, power int8, duration
 
 	// Если есть длительность, ждем ее завершения СИНХРОННО
 	if duration > 0 {
-		log.Printf("Мотор на порту %d работает %d мс...", portID, duration)
+		devicesLog.Debugf("Мотор на порту %d работает %d мс...", portID, duration)
 		time.Sleep(time.Duration(duration) * time.Millisecond)
 
 		// Останавливаем мотор
-		stopCmd := []byte{portID, 0x01, 0x01, 0x00}
-		err = dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", stopCmd)
+		err = dm.hubMgr.SendCommand(NewMotorCommand(portID).Stop())
 		if err != nil {
 			log.Printf("Ошибка остановки мотора на порту %d: %v", portID, err)
 		}
-		log.Printf("Мотор на порту %d остановлен", portID)
+		devicesLog.Debugf("Мотор на порту %d остановлен", portID)
 	}
 
 	return nil
@@ -199,25 +236,19 @@ func (dm *DeviceManager) SetMotorPowerAndWait(portID byte, power int8, duration
 
 // SetLEDColor устанавливает цвет светодиода
 func (dm *DeviceManager) SetLEDColor(portID byte, red, green, blue byte) error {
-	if !dm.hubMgr.IsConnected() {
+	return dm.setLEDColor(SourceProgram, portID, red, green, blue)
+}
+
+// setLEDColor устанавливает цвет светодиода от имени источника команд
+func (dm *DeviceManager) setLEDColor(source CommandSource, portID byte, red, green, blue byte) error {
+	if !dm.hubMgr.Ready() {
 		return fmt.Errorf("не подключено к хабу")
 	}
 
-	// Сначала проверяем в своем хранилище
 	device, exists := dm.GetDevice(portID)
 
-	// Если не нашли, проверяем в HubManager
-	if !exists && dm.hubMgr != nil {
-		if hubDevice, hubExists := dm.hubMgr.GetDeviceFromPort(portID); hubExists {
-			device = hubDevice
-			exists = true
-			// Сохраняем для будущего использования
-			dm.AddOrUpdateDevice(device)
-		}
-	}
-
 	if !exists {
-		log.Printf("Устройство на порту %d не найдено ни в DeviceManager, ни в HubManager", portID)
+		log.Printf("Устройство на порту %d не найдено", portID)
 		// Для порта 6 (встроенного светодиода) продолжаем без проверки
 		if portID != 6 {
 			return fmt.Errorf("устройство на порту %d не найдено", portID)
@@ -239,24 +270,26 @@ func (dm *DeviceManager) SetLEDColor(portID byte, red, green, blue byte) error {
 	}
 
 	// Настраиваем режим RGB (если нужно)
-	modeCmd := []byte{0x01, 0x02, portID, 0x17, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-	if err := dm.hubMgr.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", modeCmd); err != nil {
+	modeCmd := NewInputFormatCommand(portID, DEVICE_TYPE_RGB_LIGHT).Mode(LED_DISCRETE_MODE)
+	if err := dm.hubMgr.ConfigurePort(modeCmd); err != nil {
 		log.Printf("Предупреждение при установке режима светодиода: %v", err)
 		// Пробуем альтернативный режим
-		modeCmd = []byte{0x01, 0x02, portID, 0x17, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-		dm.hubMgr.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", modeCmd)
+		dm.hubMgr.ConfigurePort(modeCmd.Mode(LED_ABSOLUTE_MODE))
 	}
 
 	// Устанавливаем цвет
-	colorCmd := []byte{0x06, 0x04, 0x03, red, green, blue}
-
-	log.Printf("Установка цвета светодиода на порту %d: RGB(%d,%d,%d)", portID, red, green, blue)
-	return dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", colorCmd)
+	devicesLog.Debugf("Установка цвета светодиода на порту %d: RGB(%d,%d,%d)", portID, red, green, blue)
+	return dm.hubMgr.SendCommand(NewLEDCommand(6).RGB(red, green, blue).From(source))
 }
 
 // PlayTone воспроизводит тон на пищалке
 func (dm *DeviceManager) PlayTone(portID byte, frequency uint16, duration uint16) error {
-	if !dm.hubMgr.IsConnected() {
+	return dm.playTone(SourceProgram, portID, frequency, duration)
+}
+
+// playTone воспроизводит тон от имени источника команд
+func (dm *DeviceManager) playTone(source CommandSource, portID byte, frequency uint16, duration uint16) error {
+	if !dm.hubMgr.Ready() {
 		return fmt.Errorf("не подключено к хабу")
 	}
 
@@ -266,78 +299,54 @@ func (dm *DeviceManager) PlayTone(portID byte, frequency uint16, duration uint16
 		return fmt.Errorf("пищалка не подключена к порту %d", portID)
 	}
 
-	// Формируем команду
-	freqLow := byte(frequency & 0xFF)
-	freqHigh := byte((frequency >> 8) & 0xFF)
-	durLow := byte(duration & 0xFF)
-	durHigh := byte((duration >> 8) & 0xFF)
-
-	cmd := []byte{
-		portID,   // connectId
-		0x02,     // commandId
-		0x04,     // dataLength
-		freqLow,  // frequency low byte
-		freqHigh, // frequency high byte
-		durLow,   // duration low byte
-		durHigh,  // duration high byte
-	}
-
-	log.Printf("Проигрывание тона на порту %d: частота=%d Гц, длительность=%d мс", portID, frequency, duration)
-	return dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", cmd)
+	devicesLog.Debugf("Проигрывание тона на порту %d: частота=%d Гц, длительность=%d мс", portID, frequency, duration)
+	return dm.hubMgr.SendCommand(NewToneCommand(portID).Tone(frequency, duration).From(source))
 }
 
 // StopTone останавливает пищалку
 func (dm *DeviceManager) StopTone(portID byte) error {
-	if !dm.hubMgr.IsConnected() {
-		return fmt.Errorf("не подключено к хабу")
-	}
+	return dm.stopTone(SourceProgram, portID)
+}
 
-	cmd := []byte{
-		portID, // connectId
-		0x03,   // commandId
-		0x00,   // dataLength
+// stopTone останавливает пищалку от имени источника команд
+func (dm *DeviceManager) stopTone(source CommandSource, portID byte) error {
+	if !dm.hubMgr.Ready() {
+		return fmt.Errorf("не подключено к хабу")
 	}
 
-	log.Printf("Остановка пищалки на порту %d", portID)
-	return dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", cmd)
-}
-
-// SetDeviceChangedCallback устанавливает callback для обновлений
-func (dm *DeviceManager) SetDeviceChangedCallback(callback func(portID byte, device *Device)) {
-	dm.deviceChangedCallback = callback
+	devicesLog.Debugf("Остановка пищалки на порту %d", portID)
+	return dm.hubMgr.SendCommand(NewStopToneCommand(portID).From(source))
 }
 
 // UpdateDeviceValue обновляет значение устройства
 func (dm *DeviceManager) UpdateDeviceValue(portID byte, value interface{}) {
 	dm.devicesMu.Lock()
-	defer dm.devicesMu.Unlock()
-
-	if device, exists := dm.devices[portID]; exists {
-		device.LastValue = value
-		device.LastUpdate = time.Now()
-
-		// Уведомляем об изменении
-		if dm.deviceChangedCallback != nil {
-			dm.deviceChangedCallback(portID, device)
-		}
+	device, exists := dm.devices[portID]
+	if !exists {
+		dm.devicesMu.Unlock()
+		return
 	}
+	device.LastValue = value
+	device.LastUpdate = time.Now()
+	snapshot := *device
+	dm.devicesMu.Unlock()
+
+	dm.notify(portID, snapshot)
 }
 
-// SyncDevices синхронизирует устройства с HubManager
-func (dm *DeviceManager) SyncDevices() {
-	if dm.hubMgr == nil {
+// RecordError учитывает неудачную команду устройству на порту
+func (dm *DeviceManager) RecordError(portID byte) {
+	dm.devicesMu.Lock()
+	device, exists := dm.devices[portID]
+	if !exists {
+		dm.devicesMu.Unlock()
 		return
 	}
+	device.Errors++
+	snapshot := *device
+	dm.devicesMu.Unlock()
 
-	log.Println("Синхронизация устройств между HubManager и DeviceManager...")
-
-	// Получаем все устройства из HubManager
-	for portID := byte(1); portID <= 6; portID++ {
-		if device, exists := dm.hubMgr.GetDeviceFromPort(portID); exists {
-			dm.AddOrUpdateDevice(device)
-			log.Printf("Синхронизировано устройство на порту %d: %s", portID, device.Name)
-		}
-	}
+	dm.notify(portID, snapshot)
 }
 
 // ForceDetectAllDevices принудительно обнаруживает все устройства
@@ -347,61 +356,30 @@ func (dm *DeviceManager) ForceDetectAllDevices() {
 	}
 
 	log.Println("Принудительное обнаружение всех устройств...")
-	dm.hubMgr.autoDetectDevicesV2()
-
-	// Ждем и синхронизируем
-	time.Sleep(3 * time.Second)
-	dm.SyncDevices()
+	dm.hubMgr.DetectDevices()
 }
 
 // device_manager.go - добавляем функцию PlayToneAndWait
 func (dm *DeviceManager) PlayToneAndWait(portID byte, frequency uint16, duration uint16) error {
-	if !dm.hubMgr.IsConnected() {
+	if !dm.hubMgr.Ready() {
 		return fmt.Errorf("не подключено к хабу")
 	}
 
-	// Проверяем, подключена ли пищалка
-	device, exists := dm.GetDevice(portID)
-	if !exists && dm.hubMgr != nil {
-		if hubDevice, hubExists := dm.hubMgr.GetDeviceFromPort(portID); hubExists {
-			device = hubDevice
-			exists = true
-			dm.AddOrUpdateDevice(device)
-		}
-	}
-
-	// Формируем команду
-	freqLow := byte(frequency & 0xFF)
-	freqHigh := byte((frequency >> 8) & 0xFF)
-	durLow := byte(duration & 0xFF)
-	durHigh := byte((duration >> 8) & 0xFF)
-
-	cmd := []byte{
-		portID,   // connectId
-		0x02,     // commandId
-		0x04,     // dataLength
-		freqLow,  // frequency low byte
-		freqHigh, // frequency high byte
-		durLow,   // duration low byte
-		durHigh,  // duration high byte
-	}
-
-	log.Printf("Проигрывание тона на порту %d: частота=%d Гц, длительность=%d мс", portID, frequency, duration)
+	devicesLog.Debugf("Проигрывание тона на порту %d: частота=%d Гц, длительность=%d мс", portID, frequency, duration)
 
-	err := dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", cmd)
+	err := dm.hubMgr.SendCommand(NewToneCommand(portID).Tone(frequency, duration))
 	if err != nil {
 		return err
 	}
 
 	// Ждем завершения звука СИНХРОННО
 	if duration > 0 {
-		log.Printf("Звук на порту %d воспроизводится %d мс...", portID, duration)
+		devicesLog.Debugf("Звук на порту %d воспроизводится %d мс...", portID, duration)
 		time.Sleep(time.Duration(duration) * time.Millisecond)
 
 		// Останавливаем звук (на всякий случай)
-		stopCmd := []byte{portID, 0x03, 0x00}
-		dm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", stopCmd)
-		log.Printf("Звук на порту %d завершен", portID)
+		dm.hubMgr.SendCommand(NewStopToneCommand(portID))
+		devicesLog.Debugf("Звук на порту %d завершен", portID)
 	}
 
 	return nil
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	freshValueAge            = time.Second     // Значение моложе считается свежим
+	staleValueAge            = 5 * time.Second // Значение старше считается устаревшим
+	freshnessRefreshInterval = time.Second     // Период пересчета свежести значений
+)
+
+// DevicePanel панель с батареей, сведениями о хабе и подключенными устройствами.
+// Панель подписана на изменения DeviceManager и держит у себя только снимки устройств для отображения
+type DevicePanel struct {
+	deviceMgr *DeviceManager
+	units     *UnitFormatter
+	container *fyne.Container
+
+	batteryProgress  *widget.ProgressBar
+	hubInfoContainer *fyne.Container
+	devicesContainer *fyne.Container
+	noDevicesLabel   *widget.Label
+
+	devices     map[byte]*Device
+	cards       map[byte]*deviceCard
+	cardPool    []*deviceCard
+	updates     *DeviceUpdateCoalescer
+	unsubscribe func()
+	done        chan struct{}
+
+	// Callback после применения изменений устройств, вызывается в потоке интерфейса
+	devicesChangedCallback func()
+	// Callback двойного щелчка по карточке устройства
+	deviceOpenCallback func(portID byte)
+	// Callback озвучивания карточки, получившей фокус
+	announceCallback func(text string)
+	// Callback опознания хаба миганием светодиода
+	identifyCallback func()
+	powerCallback    func()
+}
+
+// NewDevicePanel создает панель устройств и подписывает ее на изменения менеджера устройств
+func NewDevicePanel(deviceMgr *DeviceManager, units *UnitFormatter) *DevicePanel {
+	p := &DevicePanel{
+		deviceMgr: deviceMgr,
+		units:     units,
+		devices:   make(map[byte]*Device),
+		cards:     make(map[byte]*deviceCard),
+		done:      make(chan struct{}),
+	}
+	p.container = p.buildUI()
+
+	p.updates = NewDeviceUpdateCoalescer(p.applyUpdates)
+	p.unsubscribe = deviceMgr.Subscribe(p.updates.Push)
+	go p.freshnessLoop()
+	return p
+}
+
+// GetContainer возвращает контейнер панели
+func (p *DevicePanel) GetContainer() *fyne.Container {
+	return p.container
+}
+
+// SetDevicesChangedCallback задает callback, вызываемый после обновления списка устройств
+func (p *DevicePanel) SetDevicesChangedCallback(callback func()) {
+	p.devicesChangedCallback = callback
+}
+
+// SetDeviceOpenCallback задает callback, открывающий подробности устройства по двойному щелчку
+func (p *DevicePanel) SetDeviceOpenCallback(callback func(portID byte)) {
+	p.deviceOpenCallback = callback
+}
+
+// SetAnnounceCallback задает callback озвучивания карточки, получившей фокус клавиатуры
+func (p *DevicePanel) SetAnnounceCallback(callback func(text string)) {
+	p.announceCallback = callback
+}
+
+// SetIdentifyCallback задает callback кнопки опознания хаба
+func (p *DevicePanel) SetIdentifyCallback(callback func()) {
+	p.identifyCallback = callback
+}
+
+// SetPowerCallback задает callback кнопки питания хаба
+func (p *DevicePanel) SetPowerCallback(callback func()) {
+	p.powerCallback = callback
+}
+
+// Close отписывает панель от изменений устройств
+func (p *DevicePanel) Close() {
+	p.unsubscribe()
+	close(p.done)
+}
+
+// freshnessLoop пересчитывает свежесть значений: датчик, который перестал
+// отвечать, не присылает уведомлений, поэтому карточки обновляются по таймеру
+func (p *DevicePanel) freshnessLoop() {
+	ticker := time.NewTicker(freshnessRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			fyne.Do(func() {
+				for _, card := range p.cards {
+					card.updateFreshness(now)
+				}
+			})
+		}
+	}
+}
+
+// buildUI строит интерфейс панели
+func (p *DevicePanel) buildUI() *fyne.Container {
+	mainContainer := container.NewVBox()
+
+	// Заголовок
+	title := canvas.NewText("Информация о хабе", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
+	title.TextSize = 16
+	title.TextStyle.Bold = true
+	mainContainer.Add(container.NewCenter(title))
+	mainContainer.Add(widget.NewSeparator())
+
+	// Батарея
+	mainContainer.Add(p.createBatteryWidget())
+	mainContainer.Add(widget.NewSeparator())
+
+	// Информация о хабе
+	hubTitle := canvas.NewText("Хаб", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
+	hubTitle.TextSize = 14
+	hubTitle.TextStyle.Bold = true
+	mainContainer.Add(container.NewCenter(hubTitle))
+
+	p.hubInfoContainer = container.NewVBox()
+	mainContainer.Add(p.hubInfoContainer)
+	mainContainer.Add(widget.NewSeparator())
+
+	// Подключенные устройства
+	devicesTitle := canvas.NewText("Подключенные устройства", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
+	devicesTitle.TextSize = 14
+	devicesTitle.TextStyle.Bold = true
+	mainContainer.Add(container.NewCenter(devicesTitle))
+
+	p.devicesContainer = container.NewVBox()
+	mainContainer.Add(p.devicesContainer)
+
+	// Повторный поиск устройств, которые хаб не сообщил сам
+	detectButton := widget.NewButton("Найти устройства", func() {
+		log.Println("Ручной поиск устройств...")
+		go p.deviceMgr.ForceDetectAllDevices()
+	})
+	detectButton.Importance = widget.MediumImportance
+	mainContainer.Add(detectButton)
+
+	// Мигание светодиодом помогает понять, к какому из хабов в классе подключено приложение
+	identifyButton := widget.NewButton("Идентифицировать", func() {
+		if p.identifyCallback != nil {
+			p.identifyCallback()
+		}
+	})
+	mainContainer.Add(identifyButton)
+
+	// Выключение хаба после урока, чтобы он не разряжал батареи
+	powerButton := widget.NewButton("Питание хаба...", func() {
+		if p.powerCallback != nil {
+			p.powerCallback()
+		}
+	})
+	mainContainer.Add(powerButton)
+
+	return mainContainer
+}
+
+// createBatteryWidget создает виджет батареи
+func (p *DevicePanel) createBatteryWidget() *fyne.Container {
+	title := canvas.NewText("Батарея", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
+	title.TextSize = 14
+	title.TextStyle.Bold = true
+
+	p.batteryProgress = widget.NewProgressBar()
+	p.batteryProgress.Min = 0
+	p.batteryProgress.Max = 1
+	p.batteryProgress.SetValue(0)
+	p.batteryProgress.TextFormatter = func() string {
+		if p.batteryProgress.Value <= 0 {
+			return "--%"
+		}
+		return fmt.Sprintf("%.0f%%", p.batteryProgress.Value*100)
+	}
+
+	return container.NewVBox(
+		container.NewCenter(title),
+		p.batteryProgress,
+	)
+}
+
+// SetBattery показывает заряд батареи хаба
+func (p *DevicePanel) SetBattery(batteryLevel int) {
+	p.batteryProgress.SetValue(float64(batteryLevel) / 100)
+}
+
+// SetHubInfo показывает сведения о хабе
+func (p *DevicePanel) SetHubInfo(info *HubInfo) {
+	p.hubInfoContainer.Objects = nil
+
+	p.hubInfoContainer.Add(widget.NewLabel(fmt.Sprintf("Имя: %s", info.Name)))
+	p.hubInfoContainer.Add(widget.NewLabel(fmt.Sprintf("Адрес: %s", info.Address)))
+
+	if info.Manufacturer != "" {
+		p.hubInfoContainer.Add(widget.NewLabel(fmt.Sprintf("Производитель: %s", info.Manufacturer)))
+	}
+	if info.FirmwareVersion != "" {
+		p.hubInfoContainer.Add(widget.NewLabel(fmt.Sprintf("Прошивка: %s", info.FirmwareVersion)))
+	}
+	if info.SoftwareVersion != "" {
+		p.hubInfoContainer.Add(widget.NewLabel(fmt.Sprintf("Софт: %s", info.SoftwareVersion)))
+	}
+
+	p.hubInfoContainer.Refresh()
+}
+
+// applyUpdates применяет пачку изменений устройств за одну перерисовку
+func (p *DevicePanel) applyUpdates(updates map[byte]*Device) {
+	for portID, device := range updates {
+		p.devices[portID] = device
+	}
+	p.Refresh()
+
+	if p.devicesChangedCallback != nil {
+		p.devicesChangedCallback()
+	}
+}
+
+// Refresh перерисовывает список устройств.
+// Карточки переиспользуются: перерисовываются только те, у которых изменилось
+// устройство, а контейнер пересобирается только при изменении набора портов.
+func (p *DevicePanel) Refresh() {
+	var ports []byte
+	for portID, device := range p.devices {
+		if device.IsConnected {
+			ports = append(ports, portID)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+
+	// Освобождаем карточки отключенных устройств
+	for portID, card := range p.cards {
+		if device, ok := p.devices[portID]; !ok || !device.IsConnected {
+			delete(p.cards, portID)
+			p.cardPool = append(p.cardPool, card)
+		}
+	}
+
+	var objects []fyne.CanvasObject
+	for _, portID := range ports {
+		card, exists := p.cards[portID]
+		if !exists {
+			card = p.acquireCard()
+			p.cards[portID] = card
+		}
+		card.update(portID, p.devices[portID], p.units)
+		objects = append(objects, card.container)
+	}
+
+	if len(objects) == 0 {
+		text := "Нет подключенных устройств"
+		if len(p.devices) > 0 {
+			text = "Все устройства отключены"
+		}
+		if p.noDevicesLabel == nil {
+			p.noDevicesLabel = widget.NewLabel(text)
+			p.noDevicesLabel.Alignment = fyne.TextAlignCenter
+			p.noDevicesLabel.TextStyle.Italic = true
+		} else if p.noDevicesLabel.Text != text {
+			p.noDevicesLabel.SetText(text)
+		}
+		objects = append(objects, p.noDevicesLabel)
+	}
+
+	if sameObjects(p.devicesContainer.Objects, objects) {
+		return
+	}
+
+	log.Printf("Обновление списка устройств. Всего: %d", len(p.devices))
+	p.devicesContainer.Objects = objects
+	p.devicesContainer.Refresh()
+}
+
+// Clear очищает панель после отключения хаба
+func (p *DevicePanel) Clear() {
+	p.hubInfoContainer.Objects = nil
+	p.hubInfoContainer.Refresh()
+
+	for portID, card := range p.cards {
+		delete(p.cards, portID)
+		p.cardPool = append(p.cardPool, card)
+	}
+	p.devices = make(map[byte]*Device)
+	p.devicesContainer.Objects = nil
+	p.devicesContainer.Refresh()
+
+	p.batteryProgress.SetValue(0)
+}
+
+// acquireCard берет карточку из пула или создает новую
+func (p *DevicePanel) acquireCard() *deviceCard {
+	if n := len(p.cardPool); n > 0 {
+		card := p.cardPool[n-1]
+		p.cardPool = p.cardPool[:n-1]
+		card.shown = false
+		return card
+	}
+	return newDeviceCard(p.openDevice, p.announceDevice)
+}
+
+// openDevice передает двойной щелчок по карточке устройства
+func (p *DevicePanel) openDevice(portID byte) {
+	if p.deviceOpenCallback != nil {
+		p.deviceOpenCallback(portID)
+	}
+}
+
+// announceDevice озвучивает устройство, карточка которого получила фокус
+func (p *DevicePanel) announceDevice(portID byte) {
+	device, exists := p.devices[portID]
+	if !exists || p.announceCallback == nil {
+		return
+	}
+	p.announceCallback(deviceAccessibleName(device, p.units))
+}
+
+// sameObjects проверяет, что списки объектов совпадают поэлементно
+func sameObjects(a, b []fyne.CanvasObject) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// deviceCard карточка устройства, переиспользуемая между обновлениями
+type deviceCard struct {
+	container *cardTapTarget
+	icon      *widget.Icon
+	info      *widget.Label
+	value     *widget.Label
+	freshness *canvas.Circle
+	errors    *widget.Label
+
+	// Отображаемое состояние, чтобы не перерисовывать неизменившуюся карточку
+	portID     byte
+	deviceType byte
+	name       string
+	valueText  string
+	errorCount int
+	lastUpdate time.Time
+	shown      bool
+}
+
+// newDeviceCard создает карточку устройства; onOpen вызывается по двойному щелчку
+// или Enter, onFocus — при переходе на карточку клавишей Tab
+func newDeviceCard(onOpen, onFocus func(portID byte)) *deviceCard {
+	card := &deviceCard{
+		icon:  widget.NewIcon(theme.ComputerIcon()),
+		info:  widget.NewLabel(""),
+		value: widget.NewLabel(""),
+		// Цвет индикатора показывает возраст последнего значения датчика
+		freshness: canvas.NewCircle(theme.Color(theme.ColorNameSuccess)),
+		errors:    widget.NewLabel(""),
+	}
+	card.info.TextStyle.Bold = true
+	card.errors.Importance = widget.DangerImportance
+	card.errors.Hide()
+
+	status := widget.NewLabel("✓ Подключено")
+	status.TextStyle.Italic = true
+
+	indicatorSize := fyne.NewSquareSize(theme.IconInlineSize() / 2)
+	indicator := container.NewCenter(container.NewGridWrap(indicatorSize, card.freshness))
+
+	content := container.NewVBox(
+		container.NewHBox(
+			card.icon,
+			card.info,
+			layout.NewSpacer(),
+			card.value,
+			indicator,
+			status,
+			card.errors,
+		),
+		widget.NewSeparator(),
+	)
+	card.container = newCardTapTarget(content,
+		func() { onOpen(card.portID) },
+		func() { onFocus(card.portID) })
+	return card
+}
+
+// cardTapTarget обертка карточки устройства, принимающая двойной щелчок и фокус клавиатуры
+type cardTapTarget struct {
+	widget.BaseWidget
+	content     fyne.CanvasObject
+	focusMark   *canvas.Rectangle
+	onDoubleTap func()
+	onFocus     func()
+}
+
+// newCardTapTarget оборачивает содержимое карточки
+func newCardTapTarget(content fyne.CanvasObject, onDoubleTap, onFocus func()) *cardTapTarget {
+	t := &cardTapTarget{content: content, onDoubleTap: onDoubleTap, onFocus: onFocus}
+	t.focusMark = canvas.NewRectangle(color.Transparent)
+	t.focusMark.StrokeWidth = 2
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+// CreateRenderer отрисовывает содержимое карточки с рамкой фокуса
+func (t *cardTapTarget) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewStack(t.content, t.focusMark))
+}
+
+// DoubleTapped открывает подробности устройства (для интерфейса fyne.DoubleTappable)
+func (t *cardTapTarget) DoubleTapped(*fyne.PointEvent) {
+	t.onDoubleTap()
+}
+
+// FocusGained показывает рамку фокуса и озвучивает устройство
+func (t *cardTapTarget) FocusGained() {
+	t.focusMark.StrokeColor = theme.Color(theme.ColorNameFocus)
+	t.focusMark.Refresh()
+	t.onFocus()
+}
+
+// FocusLost скрывает рамку фокуса
+func (t *cardTapTarget) FocusLost() {
+	t.focusMark.StrokeColor = color.Transparent
+	t.focusMark.Refresh()
+}
+
+// TypedRune не используется, нужен для интерфейса fyne.Focusable
+func (t *cardTapTarget) TypedRune(rune) {}
+
+// TypedKey открывает подробности устройства по Enter или пробелу
+func (t *cardTapTarget) TypedKey(event *fyne.KeyEvent) {
+	switch event.Name {
+	case fyne.KeyReturn, fyne.KeyEnter, fyne.KeySpace:
+		t.onDoubleTap()
+	}
+}
+
+// update обновляет карточку, если устройство изменилось
+func (c *deviceCard) update(portID byte, device *Device, units *UnitFormatter) {
+	valueText := ""
+	if value, ok := device.LastValue.(float64); ok {
+		valueText = units.Format(device.DeviceType, value)
+	}
+	if valueText != c.valueText || !c.shown {
+		c.value.SetText(valueText)
+		c.valueText = valueText
+	}
+	if device.Errors != c.errorCount || !c.shown {
+		c.errorCount = device.Errors
+		if device.Errors > 0 {
+			c.errors.SetText(fmt.Sprintf("Ошибок: %d", device.Errors))
+			c.errors.Show()
+		} else {
+			c.errors.Hide()
+		}
+	}
+	c.lastUpdate = device.LastUpdate
+	if reportsValues(device.DeviceType) {
+		c.freshness.Show()
+	} else {
+		c.freshness.Hide()
+	}
+	c.updateFreshness(time.Now())
+
+	if c.shown && c.portID == portID && c.deviceType == device.DeviceType && c.name == device.Name {
+		return
+	}
+
+	if !c.shown || c.deviceType != device.DeviceType {
+		c.icon.SetResource(deviceIcon(device.DeviceType))
+	}
+	c.info.SetText(fmt.Sprintf("Порт %d: %s", portID, device.Name))
+
+	c.portID = portID
+	c.deviceType = device.DeviceType
+	c.name = device.Name
+	c.shown = true
+}
+
+// updateFreshness окрашивает индикатор по возрасту последнего значения:
+// зеленый — моложе секунды, желтый — моложе пяти секунд, красный — датчик молчит
+func (c *deviceCard) updateFreshness(now time.Time) {
+	if !c.freshness.Visible() {
+		return
+	}
+
+	age := now.Sub(c.lastUpdate)
+	name := theme.ColorNameError
+	switch {
+	case age < freshValueAge:
+		name = theme.ColorNameSuccess
+	case age < staleValueAge:
+		name = theme.ColorNameWarning
+	}
+
+	fill := theme.Color(name)
+	if c.freshness.FillColor != fill {
+		c.freshness.FillColor = fill
+		c.freshness.Refresh()
+	}
+}
+
+// deviceIcon возвращает иконку для типа устройства
+func deviceIcon(deviceType byte) fyne.Resource {
+	return iconResource(deviceIconName(deviceType))
+}
@@ -36,6 +36,33 @@ const (
 	LED_INDEX_WHITE  = 0x0A // Белый
 )
 
+// DeviceMode режим устройства для выбора в окне устройства
+type DeviceMode struct {
+	Mode byte
+	Name string
+}
+
+// deviceModes режимы устройств по типу. Хаб WeDo 2.0 не отвечает на запросы
+// о режимах порта, поэтому список задан для известных типов устройств
+var deviceModes = map[byte][]DeviceMode{
+	DEVICE_TYPE_MOTOR:         {{0, "Мощность"}},
+	DEVICE_TYPE_VOLTAGE:       {{0, "Напряжение"}},
+	DEVICE_TYPE_CURRENT:       {{0, "Ток"}},
+	DEVICE_TYPE_PIEZO_TONE:    {{0, "Тон"}},
+	DEVICE_TYPE_RGB_LIGHT:     {{LED_ABSOLUTE_MODE, "Индексные цвета"}, {LED_DISCRETE_MODE, "RGB цвета"}},
+	DEVICE_TYPE_TILT_SENSOR:   {{TILT_ANGLE_MODE, "Угол наклона"}, {TILT_TILT_MODE, "Направление наклона"}, {TILT_CRASH_MODE, "Удар"}},
+	DEVICE_TYPE_MOTION_SENSOR: {{DIST_DETECT_MODE, "Расстояние"}, {DIST_COUNT_MODE, "Счет"}},
+}
+
+// reportsValues сообщает, присылает ли устройство значения само (датчики)
+func reportsValues(deviceType byte) bool {
+	switch deviceType {
+	case DEVICE_TYPE_VOLTAGE, DEVICE_TYPE_CURRENT, DEVICE_TYPE_TILT_SENSOR, DEVICE_TYPE_MOTION_SENSOR:
+		return true
+	}
+	return false
+}
+
 // DeviceTypeName возвращает имя типа устройства
 func DeviceTypeName(deviceType byte) string {
 	switch deviceType {
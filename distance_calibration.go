@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Датчик расстояния присылает в режиме измерения условную шкалу 0..10, а не
+// сантиметры. Показание переводится в сантиметры при получении, поэтому
+// панели, условия, привязки и запись датчиков работают в сантиметрах.
+// Калибровка хранится в учете хабов для каждого хаба; без нее используется
+// примерная калибровка типичного датчика
+
+const (
+	calibrationNearCM = 10.0 // Первая точка калибровки, см
+	calibrationFarCM  = 30.0 // Вторая точка калибровки, см
+	distanceMaxCM     = 40.0 // Наибольшее расстояние в условиях и привязках, см
+
+	calibrationRefreshInterval = 200 * time.Millisecond // Период обновления показания в окне калибровки
+	calibrationMinSpan         = 1.0                    // Наименьшая разница показаний в точках калибровки
+)
+
+// DistanceCalibration показания датчика по условной шкале для предмета на 10
+// и 30 см. Между точками и за ними показание переводится в сантиметры по прямой
+type DistanceCalibration struct {
+	Raw10 float64 `json:"raw_10"`
+	Raw30 float64 `json:"raw_30"`
+}
+
+// defaultDistanceCalibration примерная калибровка типичного датчика: 0 — вплотную, 10 — около 33 см
+var defaultDistanceCalibration = DistanceCalibration{Raw10: 3, Raw30: 9}
+
+// Validate проверяет, что по точкам калибровки можно различить расстояния
+func (c DistanceCalibration) Validate() error {
+	if math.Abs(c.Raw30-c.Raw10) < calibrationMinSpan {
+		return fmt.Errorf("показания на %g и %g см почти не различаются (%g и %g): проверьте, что предмет стоит напротив датчика",
+			calibrationNearCM, calibrationFarCM, c.Raw10, c.Raw30)
+	}
+	return nil
+}
+
+// Centimeters переводит показание условной шкалы в сантиметры
+func (c DistanceCalibration) Centimeters(raw float64) float64 {
+	cm := calibrationNearCM + (raw-c.Raw10)*(calibrationFarCM-calibrationNearCM)/(c.Raw30-c.Raw10)
+	return math.Max(0, math.Round(cm*10)/10)
+}
+
+// cmPerRaw сантиметров на единицу условной шкалы
+func (c DistanceCalibration) cmPerRaw() float64 {
+	return (calibrationFarCM - calibrationNearCM) / (c.Raw30 - c.Raw10)
+}
+
+// SetDistanceCalibration задает калибровку датчика расстояния подключенного хаба
+func (hm *HubManager) SetDistanceCalibration(calibration DistanceCalibration) {
+	hm.sensorMu.Lock()
+	defer hm.sensorMu.Unlock()
+	hm.distanceCalibration = calibration
+}
+
+// distanceReading переводит показание порта в сантиметры, если на порту
+// датчик расстояния в режиме измерения; остальные показания не меняются
+func (hm *HubManager) distanceReading(portID byte, value float64) float64 {
+	if !hm.isDistancePort(portID) {
+		return value
+	}
+
+	hm.sensorMu.Lock()
+	defer hm.sensorMu.Unlock()
+	hm.rawDistances[portID] = value
+	return hm.distanceCalibration.Centimeters(value)
+}
+
+// isDistancePort проверяет, что на порту датчик расстояния в режиме измерения
+func (hm *HubManager) isDistancePort(portID byte) bool {
+	device, ok := hm.devices.GetDevice(portID)
+	if !ok || device.DeviceType != DEVICE_TYPE_MOTION_SENSOR {
+		return false
+	}
+	mode, configured := hm.portModes.Mode(portID)
+	return !configured || mode == DIST_DETECT_MODE
+}
+
+// RawDistance возвращает последнее показание датчика расстояния по условной шкале
+func (hm *HubManager) RawDistance(portID byte) (float64, bool) {
+	hm.sensorMu.RLock()
+	defer hm.sensorMu.RUnlock()
+	value, ok := hm.rawDistances[portID]
+	return value, ok
+}
+
+// applyDistanceCalibration применяет калибровку подключенного хаба из учета
+func (gui *MainGUI) applyDistanceCalibration() {
+	calibration, calibrated := DistanceCalibration{}, false
+	if gui.connectedHub != nil {
+		calibration, calibrated = gui.inventory.DistanceCalibration(*gui.connectedHub)
+	}
+	if !calibrated {
+		calibration = defaultDistanceCalibration
+	}
+	gui.hubMgr.SetDistanceCalibration(calibration)
+	gui.units.DistanceApprox = !calibrated
+}
+
+// saveDistanceCalibration сохраняет калибровку подключенного хаба; nil — вернуть примерную
+func (gui *MainGUI) saveDistanceCalibration(calibration *DistanceCalibration) {
+	if gui.connectedHub == nil {
+		return
+	}
+	gui.inventory.SetDistanceCalibration(*gui.connectedHub, calibration)
+	gui.preferences().SetString(settingHubInventory, gui.inventory.Export())
+	gui.applyDistanceCalibration()
+}
+
+// showDistanceCalibration проводит калибровку датчика расстояния на порту:
+// ученик ставит предмет на 10 см, затем на 30 см и запоминает показания
+func (gui *MainGUI) showDistanceCalibration(portID byte, parent fyne.Window) {
+	if gui.connectedHub == nil || !gui.hubMgr.IsConnected() {
+		dialog.ShowError(fmt.Errorf("Нет подключения к хабу"), parent)
+		return
+	}
+	if err := gui.hubMgr.ConfigurePort(NewInputFormatCommand(portID, DEVICE_TYPE_MOTION_SENSOR).Mode(DIST_DETECT_MODE)); err != nil {
+		log.Printf("Предупреждение при настройке датчика расстояния: %v", err)
+	}
+
+	points := []float64{calibrationNearCM, calibrationFarCM}
+	readings := make([]float64, 0, len(points))
+
+	instruction := widget.NewLabel("")
+	instruction.Wrapping = fyne.TextWrapWord
+	current := widget.NewLabelWithStyle("—", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	showStep := func() {
+		instruction.SetText(fmt.Sprintf("Шаг %d из %d. Поставьте предмет ровно в %g см напротив датчика на порту %d и нажмите «Запомнить».",
+			len(readings)+1, len(points), points[len(readings)], portID))
+	}
+	showStep()
+
+	var d dialog.Dialog
+	rememberButton := widget.NewButton("Запомнить", func() {
+		raw, ok := gui.hubMgr.RawDistance(portID)
+		if !ok {
+			dialog.ShowError(fmt.Errorf("датчик еще не прислал показание"), parent)
+			return
+		}
+		readings = append(readings, raw)
+		if len(readings) < len(points) {
+			showStep()
+			return
+		}
+
+		calibration := DistanceCalibration{Raw10: readings[0], Raw30: readings[1]}
+		if err := calibration.Validate(); err != nil {
+			readings = readings[:0]
+			showStep()
+			dialog.ShowError(err, parent)
+			return
+		}
+		gui.saveDistanceCalibration(&calibration)
+		log.Printf("Датчик расстояния откалиброван: %+v", calibration)
+		d.Hide()
+		dialog.ShowInformation("Калибровка", "Датчик расстояния откалиброван для этого хаба", parent)
+	})
+	rememberButton.Importance = widget.HighImportance
+
+	resetButton := widget.NewButton("Вернуть примерную калибровку", func() {
+		gui.saveDistanceCalibration(nil)
+		d.Hide()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(calibrationRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				text := "нет показаний"
+				if raw, ok := gui.hubMgr.RawDistance(portID); ok {
+					text = fmt.Sprintf("Показание датчика: %g", raw)
+				}
+				fyne.Do(func() { current.SetText(text) })
+			}
+		}
+	}()
+
+	content := container.NewVBox(instruction, current, container.NewCenter(container.NewHBox(rememberButton, resetButton)))
+	d = dialog.NewCustom("Калибровка датчика расстояния", "Закрыть", content, parent)
+	d.SetOnClosed(func() { close(done) })
+	d.Resize(fyne.NewSize(480, 240))
+	d.Show()
+}
@@ -9,9 +9,12 @@ import (
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
+const keyboardMoveStep = 10 // Сдвиг блока стрелками, пикселей
+
 // DraggableBlock перетаскиваемый блок программирования
 type DraggableBlock struct {
 	widget.BaseWidget
@@ -23,9 +26,12 @@ type DraggableBlock struct {
 	dragStart       fyne.Position
 	blockStartPos   fyne.Position // Новая переменная для хранения начальной позиции блока
 	isSelected      bool
+	isReplayed      bool // Блок подсвечен при воспроизведении сеанса
 	connectorTop    *canvas.Circle
 	connectorBottom *canvas.Circle
 	selectionBorder *canvas.Rectangle
+	progress        *canvas.Text // Подпись итерации цикла во время выполнения
+	progressBadge   *fyne.Container
 }
 
 // NewDraggableBlock создает перетаскиваемый блок
@@ -45,16 +51,15 @@ func NewDraggableBlock(block *ProgramBlock, programMgr *ProgramManager, gui *Mai
 
 // createContent создает содержимое блока
 func (d *DraggableBlock) createContent() {
-	// Цвет блока
-	blockColor := parseColor(d.block.Color)
+	// Цвет и форма блока с учетом настроек категории
+	style := d.gui.blockStyle(d.block)
+	blockColor := parseColor(style.Color)
 	if blockColor == nil {
 		blockColor = color.NRGBA{R: 100, G: 100, B: 100, A: 255}
 	}
 
 	// Фон блока
-	bg := canvas.NewRectangle(blockColor)
-	bg.SetMinSize(fyne.NewSize(float32(d.block.Width), float32(d.block.Height)))
-	bg.CornerRadius = 5
+	bg := newBlockBackground(style.Shape, blockColor, fyne.NewSize(float32(d.block.Width), float32(d.block.Height)))
 
 	// Добавляем выделение при выборе
 	d.selectionBorder = canvas.NewRectangle(color.Transparent)
@@ -63,9 +68,10 @@ func (d *DraggableBlock) createContent() {
 	d.selectionBorder.StrokeColor = color.Transparent
 	d.selectionBorder.StrokeWidth = 2
 
-	// Иконка (заглушка)
-	icon := canvas.NewText("◼", color.White)
-	icon.TextSize = 20
+	// Иконка типа блока
+	icon := canvas.NewImageFromResource(blockIconOnColor(d.block.Type))
+	icon.FillMode = canvas.ImageFillContain
+	icon.SetMinSize(fyne.NewSize(20, 20))
 
 	// Заголовок
 	title := canvas.NewText(d.block.Title, color.White)
@@ -100,15 +106,49 @@ func (d *DraggableBlock) createContent() {
 		d.connectorBottom,
 	)
 
+	// Подпись итерации цикла в правом верхнем углу, видна только во время выполнения
+	progressText := ""
+	if d.progress != nil {
+		progressText = d.progress.Text
+	}
+	d.progress = canvas.NewText(progressText, color.NRGBA{R: 255, G: 235, B: 59, A: 255})
+	d.progress.TextStyle.Bold = true
+	d.progress.TextSize = 11
+	badgeBackground := canvas.NewRectangle(color.NRGBA{A: 160})
+	badgeBackground.CornerRadius = 4
+	d.progressBadge = container.NewStack(badgeBackground, container.NewPadded(d.progress))
+	d.progressBadge.Hidden = progressText == ""
+
 	// Объединяем все элементы
 	d.content = container.NewStack(
 		d.selectionBorder,
 		bg,
 		container.NewPadded(content),
 		connectors,
+		container.NewVBox(container.NewHBox(layout.NewSpacer(), d.progressBadge)),
 	)
 }
 
+// SetProgress показывает подпись итерации цикла, пустая строка скрывает ее
+func (d *DraggableBlock) SetProgress(text string) {
+	if d.progress.Text == text {
+		return
+	}
+	d.progress.Text = text
+	d.progress.Refresh()
+	if text == "" {
+		d.progressBadge.Hide()
+	} else {
+		d.progressBadge.Show()
+	}
+}
+
+// applyStyle пересоздает содержимое блока после смены цвета или формы категории
+func (d *DraggableBlock) applyStyle() {
+	d.createContent()
+	d.updateSelection()
+}
+
 // CreateRenderer создает рендерер виджета
 func (d *DraggableBlock) CreateRenderer() fyne.WidgetRenderer {
 	return &draggableBlockRenderer{
@@ -123,43 +163,145 @@ func (d *DraggableBlock) Tapped(e *fyne.PointEvent) {
 
 	// Выделяем этот блок и показываем его свойства
 	d.selectBlock()
+	d.gui.window.Canvas().Focus(d)
 
 	// Если это не стартовый блок, предлагаем соединить с предыдущим
-	if d.block.Type != BlockTypeStart && d.block.NextBlockID == 0 {
+	if !d.gui.readOnly() && !isHatBlock(d.block.Type) && d.block.NextBlockID == 0 {
 		// Автоматически соединяем с предыдущим блоком, если он есть
 		d.autoConnectToPrevious()
 	}
 }
 
+// DoubleTapped открывает редактор блока в отдельном окне с крупными элементами
+func (d *DraggableBlock) DoubleTapped(e *fyne.PointEvent) {
+	d.selectBlock()
+	d.gui.showBlockEditorDialog(d.block)
+}
+
 // TappedSecondary обработка правого клика по блоку
 func (d *DraggableBlock) TappedSecondary(e *fyne.PointEvent) {
+	d.gui.batterySaver.Touch()
+
 	// Создаем контекстное меню
+	deleteItem := fyne.NewMenuItem("Удалить", func() {
+		d.gui.deleteSelectedBlock()
+	})
+	deleteItem.Disabled = d.gui.readOnly()
+
+	// Блок без продолжения можно вручную соединить с любым другим блоком
+	connectItem := fyne.NewMenuItem("Соединить с", nil)
+	connectItem.ChildMenu = d.gui.programPanel.connectionTargetsMenu(d.block.ID, 0, func(toBlockID int) {
+		d.gui.programPanel.connectBlocks(d.block.ID, toBlockID)
+	})
+	connectItem.Disabled = d.gui.readOnly() || d.block.NextBlockID != 0 || len(connectItem.ChildMenu.Items) == 0
+
+	groupItem := fyne.NewMenuItem("Сгруппировать...", func() {
+		d.gui.showGroupDialog(d.block)
+	})
+	groupItem.Disabled = d.gui.readOnly() || d.programMgr.GroupOf(d.block.ID) != nil
+
+	editItem := fyne.NewMenuItem("Редактировать в окне", func() {
+		d.selectBlock()
+		d.gui.showBlockEditorDialog(d.block)
+	})
+	editItem.Disabled = d.gui.readOnly()
+
+	colorItem := fyne.NewMenuItem("Цвет", nil)
+	colorItem.ChildMenu = d.blockColorMenu()
+	colorItem.Disabled = d.gui.readOnly()
+
+	copyItem := fyne.NewMenuItem("Копировать", func() {
+		d.selectBlock()
+		d.gui.copySelectedBlock()
+	})
+	copyItem.Disabled = d.gui.readOnly()
+
 	menu := fyne.NewMenu("",
-		fyne.NewMenuItem("Удалить", func() {
-			d.gui.deleteSelectedBlock()
-		}),
-		fyne.NewMenuItem("Копировать", func() {
-			// TODO: реализовать копирование
-		}),
+		deleteItem,
+		connectItem,
+		groupItem,
+		colorItem,
+		copyItem,
 		fyne.NewMenuItemSeparator(),
 		fyne.NewMenuItem("Свойства", func() {
 			d.selectBlock()
 		}),
+		editItem,
 	)
 
 	// Показываем контекстное меню
 	widget.ShowPopUpMenuAtPosition(menu, d.gui.window.Canvas(), e.AbsolutePosition)
 }
 
+// FocusGained выделяет блок, на который перешли клавишей Tab, и озвучивает его
+func (d *DraggableBlock) FocusGained() {
+	if !d.isSelected {
+		d.selectBlock()
+	}
+	d.gui.announce(blockAccessibleName(d.block, true))
+}
+
+// FocusLost не снимает выделение: свойства блока остаются доступны для правки
+func (d *DraggableBlock) FocusLost() {}
+
+// TypedRune не используется, нужен для интерфейса fyne.Focusable
+func (d *DraggableBlock) TypedRune(rune) {}
+
+// TypedKey управляет блоком с клавиатуры: стрелки перемещают блок,
+// Enter открывает контекстное меню, остальные клавиши обрабатывает окно
+func (d *DraggableBlock) TypedKey(event *fyne.KeyEvent) {
+	switch event.Name {
+	case fyne.KeyUp:
+		d.moveBy(0, -keyboardMoveStep)
+	case fyne.KeyDown:
+		d.moveBy(0, keyboardMoveStep)
+	case fyne.KeyLeft:
+		d.moveBy(-keyboardMoveStep, 0)
+	case fyne.KeyRight:
+		d.moveBy(keyboardMoveStep, 0)
+	case fyne.KeyReturn, fyne.KeyEnter, fyne.KeySpace:
+		position := fyne.CurrentApp().Driver().AbsolutePositionForObject(d)
+		d.TappedSecondary(&fyne.PointEvent{AbsolutePosition: position.AddXY(0, d.Size().Height)})
+	default:
+		d.gui.handleTypedKey(event)
+	}
+}
+
+// moveBy сдвигает блок с клавиатуры
+func (d *DraggableBlock) moveBy(dx, dy float32) {
+	if d.gui.readOnly() {
+		return
+	}
+
+	newPos := d.Position().AddXY(dx, dy)
+	if newPos.X < 0 {
+		newPos.X = 0
+	}
+	if newPos.Y < 0 {
+		newPos.Y = 0
+	}
+	d.Move(newPos)
+
+	d.block.X = float64(newPos.X)
+	d.block.Y = float64(newPos.Y)
+	d.programMgr.UpdateBlockPosition(d.block.ID, d.block.X, d.block.Y)
+	d.gui.programPanel.scheduleConnectionUpdate(d.block.ID)
+}
+
 // selectBlock выделяет этот блок и показывает его свойства
 func (d *DraggableBlock) selectBlock() {
-	// Снимаем выделение со всех блоков
+	// Снимаем выделение с ранее выбранных блоков
 	for _, obj := range d.gui.programPanel.content.Objects {
-		if block, ok := obj.(*DraggableBlock); ok {
+		if block, ok := obj.(*DraggableBlock); ok && block != d && block.isSelected {
 			block.deselect()
 		}
 	}
 
+	if d.isSelected {
+		d.gui.showBlockProperties(d.block)
+		return
+	}
+
 	// Выделяем этот блок
 	d.isSelected = true
 	d.updateSelection()
@@ -177,7 +319,9 @@ func (d *DraggableBlock) deselect() {
 // updateSelection обновляет внешний вид блока в зависимости от выделения
 func (d *DraggableBlock) updateSelection() {
 	if d.selectionBorder != nil {
-		if d.isSelected {
+		if d.isReplayed {
+			d.selectionBorder.StrokeColor = highlightColor
+		} else if d.isSelected {
 			d.selectionBorder.StrokeColor = color.NRGBA{R: 0, G: 150, B: 255, A: 255}
 		} else {
 			d.selectionBorder.StrokeColor = color.Transparent
@@ -187,6 +331,15 @@ func (d *DraggableBlock) updateSelection() {
 	d.Refresh()
 }
 
+// SetReplayHighlight подсвечивает блок, который выполнялся в момент воспроизводимого сеанса
+func (d *DraggableBlock) SetReplayHighlight(on bool) {
+	if d.isReplayed == on {
+		return
+	}
+	d.isReplayed = on
+	d.updateSelection()
+}
+
 // autoConnectToPrevious автоматически соединяет с предыдущим блоком
 func (d *DraggableBlock) autoConnectToPrevious() {
 	// Находим последний блок в программе (кроме текущего)
@@ -210,6 +363,13 @@ func (d *DraggableBlock) autoConnectToPrevious() {
 
 // Dragged обработка перетаскивания (для интерфейса fyne.Draggable)
 func (d *DraggableBlock) Dragged(e *fyne.DragEvent) {
+	d.gui.batterySaver.Touch()
+
+	// В режиме просмотра блоки не перемещаются
+	if d.gui.readOnly() {
+		return
+	}
+
 	if !d.isDragging {
 		d.isDragging = true
 		d.dragStart = e.Position
@@ -239,11 +399,8 @@ func (d *DraggableBlock) Dragged(e *fyne.DragEvent) {
 	d.block.Y = float64(newPos.Y)
 	d.block.DragStartPos = newPos
 
-	// Обновляем позиции коннекторов
-	d.updateConnectorPositions()
-
-	// Обновляем соединения
-	d.gui.programPanel.updateConnections()
+	// Перерисовываем только соединения этого блока, не чаще раза за кадр
+	d.gui.programPanel.scheduleConnectionUpdate(d.block.ID)
 }
 
 // updateConnectorPositions обновляет позиции коннекторов
@@ -269,6 +426,14 @@ func (d *DraggableBlock) updateConnectorPositions() {
 func (d *DraggableBlock) DragEnd() {
 	if d.isDragging {
 		d.isDragging = false
+		if d.Position() == d.blockStartPos {
+			return
+		}
+
+		// Перетаскивание мимо соседей по цепочке меняет порядок выполнения
+		if d.gui.programPanel.reorderDropped(d, d.blockStartPos) {
+			return
+		}
 
 		// Обновляем позицию в менеджере программ
 		d.programMgr.UpdateBlockPosition(d.block.ID, d.block.X, d.block.Y)
@@ -281,7 +446,7 @@ func (d *DraggableBlock) DragEnd() {
 // MouseDown обработка нажатия мыши
 func (d *DraggableBlock) MouseDown(e *desktop.MouseEvent) {
 	if e.Button == desktop.LeftMouseButton {
-		d.isDragging = true
+		d.isDragging = !d.gui.readOnly()
 		d.dragStart = e.AbsolutePosition
 		d.blockStartPos = d.Position() // Сохраняем текущую позицию блока
 		d.selectBlock()                // Выделяем блок при клике
@@ -291,7 +456,6 @@ func (d *DraggableBlock) MouseDown(e *desktop.MouseEvent) {
 // MouseUp обработка отпускания мыши
 func (d *DraggableBlock) MouseUp(e *desktop.MouseEvent) {
 	if e.Button == desktop.LeftMouseButton && d.isDragging {
-		d.isDragging = false
 		d.DragEnd()
 	}
 }
@@ -328,11 +492,8 @@ func (d *DraggableBlock) MouseMoved(e *desktop.MouseEvent) {
 	d.block.Y = float64(newPos.Y)
 	d.block.DragStartPos = newPos
 
-	// Обновляем позиции коннекторов
-	d.updateConnectorPositions()
-
-	// Обновляем соединения
-	d.gui.programPanel.updateConnections()
+	// Перерисовываем только соединения этого блока, не чаще раза за кадр
+	d.gui.programPanel.scheduleConnectionUpdate(d.block.ID)
 }
 
 // Cursor возвращает курсор для блока
@@ -416,6 +577,11 @@ func (r *draggableBlockRenderer) MinSize() fyne.Size {
 }
 
 func (r *draggableBlockRenderer) Refresh() {
+	// Содержимое могло быть пересоздано после смены стиля блоков
+	if r.objects[0] != r.widget.content {
+		r.objects[0] = r.widget.content
+		r.widget.content.Resize(r.widget.Size())
+	}
 	r.widget.updateConnectorPositions()
 	for _, obj := range r.objects {
 		obj.Refresh()
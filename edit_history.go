@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+
+	"fyne.io/fyne/v2/dialog"
+)
+
+const undoLimit = 50 // Сколько шагов отмены хранить
+
+// UndoHistory стек снимков программы для отмены действий.
+// Снимки хранятся в формате обмена, поэтому не зависят от виджетов холста
+type UndoHistory struct {
+	snapshots [][]byte
+}
+
+// Push сохраняет снимок, вытесняя самый старый при переполнении
+func (h *UndoHistory) Push(snapshot []byte) {
+	h.snapshots = append(h.snapshots, snapshot)
+	if len(h.snapshots) > undoLimit {
+		h.snapshots = h.snapshots[len(h.snapshots)-undoLimit:]
+	}
+}
+
+// Pop возвращает последний снимок
+func (h *UndoHistory) Pop() ([]byte, bool) {
+	if len(h.snapshots) == 0 {
+		return nil, false
+	}
+	snapshot := h.snapshots[len(h.snapshots)-1]
+	h.snapshots = h.snapshots[:len(h.snapshots)-1]
+	return snapshot, true
+}
+
+// CanUndo сообщает, есть ли что отменять
+func (h *UndoHistory) CanUndo() bool {
+	return len(h.snapshots) > 0
+}
+
+// checkpoint запоминает программу перед изменением, чтобы его можно было отменить
+func (gui *MainGUI) checkpoint() {
+	gui.batterySaver.Touch()
+	snapshot, err := EncodeProgram(gui.programMgr.GetProgram())
+	if err != nil {
+		log.Printf("Не удалось сохранить шаг отмены: %v", err)
+		return
+	}
+	gui.history.Push(snapshot)
+	gui.updateEditMenu()
+}
+
+// undo возвращает программу к последнему сохраненному шагу
+func (gui *MainGUI) undo() {
+	if gui.locked || gui.programMgr.GetProgramState() == ProgramStateRunning {
+		return
+	}
+
+	snapshot, ok := gui.history.Pop()
+	if !ok {
+		return
+	}
+	program, err := gui.programMgr.DecodeProgram(snapshot)
+	if err != nil {
+		dialog.ShowError(err, gui.window)
+		return
+	}
+
+	gui.openProgram(program)
+	gui.updateEditMenu()
+	log.Println("Действие отменено")
+}
+
+// copiedBlock блок в буфере обмена программы
+type copiedBlock struct {
+	blockType  BlockType
+	parameters map[string]interface{}
+}
+
+// copySelectedBlock копирует выбранный блок с его параметрами
+func (gui *MainGUI) copySelectedBlock() {
+	if gui.selectedBlock == nil || gui.programMgr.GetProgram().RunOnly {
+		return
+	}
+
+	params := make(map[string]interface{}, len(gui.selectedBlock.Parameters))
+	for key, value := range gui.selectedBlock.Parameters {
+		params[key] = value
+	}
+	gui.clipboardBlock = &copiedBlock{blockType: gui.selectedBlock.Type, parameters: params}
+	gui.updateEditMenu()
+	log.Printf("Блок %s скопирован", gui.selectedBlock.Title)
+}
+
+// pasteBlock добавляет копию блока из буфера обмена в программу
+func (gui *MainGUI) pasteBlock() {
+	if gui.clipboardBlock == nil || gui.readOnly() {
+		return
+	}
+
+	gui.checkpoint()
+	block := gui.programMgr.CreateBlock(gui.clipboardBlock.blockType, 100, 100)
+	for key, value := range gui.clipboardBlock.parameters {
+		block.Parameters[key] = value
+	}
+	gui.programPanel.AddBlock(block)
+	gui.sounds.Play(SoundBlockAdded)
+	gui.updateToolbarState(gui.hubMgr.IsConnected(), true)
+	log.Printf("Вставлен блок: %s (ID: %d)", block.Title, block.ID)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Действия блока при ошибке выполнения
+const (
+	ErrorActionStop  = "stop"  // Остановить программу
+	ErrorActionSkip  = "skip"  // Пропустить блок и продолжить
+	ErrorActionRetry = "retry" // Повторить блок несколько раз
+)
+
+const (
+	defaultErrorRetries = 2
+	maxErrorRetries     = 5
+	errorRetryDelay     = 200 * time.Millisecond // Пауза перед повтором блока
+)
+
+// errorActionNames названия действий при ошибке для редактора блока
+var errorActionNames = map[string]string{
+	ErrorActionStop:  "Остановить программу",
+	ErrorActionSkip:  "Пропустить блок",
+	ErrorActionRetry: "Повторить блок",
+}
+
+// ErrorPolicy что делать, если блок завершился ошибкой
+type ErrorPolicy struct {
+	Action  string
+	Retries int
+}
+
+// setDefaultErrorPolicy заполняет параметры политики ошибок значениями по умолчанию
+func setDefaultErrorPolicy(params map[string]interface{}) {
+	params["on_error"] = ErrorActionStop
+	params["retries"] = defaultErrorRetries
+}
+
+// errorPolicyFromParameters читает политику ошибок из параметров блока
+func errorPolicyFromParameters(params map[string]interface{}) ErrorPolicy {
+	policy := ErrorPolicy{Action: ErrorActionStop, Retries: defaultErrorRetries}
+	if action, ok := params["on_error"].(string); ok && errorActionNames[action] != "" {
+		policy.Action = action
+	}
+	policy.Retries = min(max(paramInt(params, "retries", defaultErrorRetries), 1), maxErrorRetries)
+	return policy
+}
+
+// executeWithPolicy выполняет блок с учетом его политики ошибок: повторяет его
+// или пропускает, а ошибку возвращает, только если программу нужно остановить
+func (pm *ProgramManager) executeWithPolicy(ctx context.Context, block *ProgramBlock) error {
+	policy := errorPolicyFromParameters(block.Parameters)
+
+	err := pm.executeBlock(block)
+	for attempt := 1; err != nil && policy.Action == ErrorActionRetry && attempt <= policy.Retries; attempt++ {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		// Зависшая попытка так и не завершилась: повторять бесполезно
+		if errors.Is(err, errBlockStillRunning) {
+			break
+		}
+		log.Printf("Блок %d завершился ошибкой (%v), повтор %d из %d", block.ID, err, attempt, policy.Retries)
+		if pauseErr := pm.pause(ctx, errorRetryDelay); pauseErr != nil {
+			return pauseErr
+		}
+		err = pm.executeBlock(block)
+	}
+
+	if err != nil && policy.Action == ErrorActionSkip && !errors.Is(err, context.Canceled) {
+		log.Printf("Блок %d пропущен после ошибки: %v", block.ID, err)
+		pm.setLastError(fmt.Errorf("блок %d пропущен: %v", block.ID, err))
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType тип события внутренней шины
+type EventType int
+
+const (
+	EventSensorValue    EventType = iota // Новое значение датчика
+	EventMessage                         // Сообщение между цепочками программы
+	EventVisionMotion                    // Уровень движения в кадре камеры, %
+	EventVisionColor                     // Доминирующий цвет в кадре камеры
+	EventSpeech                          // Распознанное ключевое слово
+	EventProgramState                    // Запуск или завершение программы
+	EventLoopProgress                    // Итерация цикла: номер в Value, текст в Name, 0 — цикл завершен
+	EventObjectDetected                  // Смена признака «объект обнаружен»: 1 — обнаружен, 0 — нет
+	EventTiltBump                        // Удар или тряска датчика наклона: вид в Name, сила в Value
+	EventVoltageSag                      // Повторяющиеся просадки напряжения хаба: напряжение в Value, мВ
+)
+
+// Event событие внутренней шины
+type Event struct {
+	Type    EventType
+	PortID  byte
+	BlockID int
+	Value   float64
+	Name    string
+	Time    time.Time
+}
+
+// EventBus внутренняя шина событий с несколькими подписчиками
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[EventType]map[int]func(Event)
+	nextID      int
+}
+
+// NewEventBus создает шину событий
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[EventType]map[int]func(Event)),
+	}
+}
+
+// Subscribe подписывает обработчик на события типа и возвращает функцию отписки
+func (b *EventBus) Subscribe(eventType EventType, handler func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+
+	if b.subscribers[eventType] == nil {
+		b.subscribers[eventType] = make(map[int]func(Event))
+	}
+	b.subscribers[eventType][id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[eventType], id)
+	}
+}
+
+// Publish рассылает событие всем подписчикам
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	// Копируем обработчики, чтобы не держать блокировку во время вызова
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.subscribers[event.Type]))
+	for _, handler := range b.subscribers[event.Type] {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
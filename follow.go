@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// followInterval период пересчета мощности регулятора
+const followInterval = 100 * time.Millisecond
+
+// FollowController пропорциональный регулятор: мощность мотора пропорциональна
+// отклонению показания датчика от заданного значения
+type FollowController struct {
+	MotorPort  byte
+	SensorPort byte
+	Target     float64 // Заданное значение датчика
+	Gain       float64 // Коэффициент: процентов мощности на сантиметр отклонения
+	MaxPower   float64 // Ограничение мощности по модулю, %
+	Invert     bool    // Мотор установлен наоборот
+}
+
+// setDefaultFollowParameters заполняет параметры регулятора значениями по умолчанию
+func setDefaultFollowParameters(params map[string]interface{}) {
+	params["motor_port"] = byte(1)
+	params["sensor_port"] = byte(2)
+	params["target"] = 15.0
+	params["gain"] = 6.0
+	params["max_power"] = 60.0
+	params["invert"] = false
+}
+
+// followFromParameters читает параметры регулятора из блока
+func followFromParameters(params map[string]interface{}) FollowController {
+	return FollowController{
+		MotorPort:  paramByte(params, "motor_port", 1),
+		SensorPort: paramByte(params, "sensor_port", 2),
+		Target:     paramFloat(params, "target", 15),
+		Gain:       paramFloat(params, "gain", 6),
+		MaxPower:   paramFloat(params, "max_power", 60),
+		Invert:     paramBool(params, "invert", false),
+	}
+}
+
+// Power возвращает мощность мотора для показания датчика. Если предмет дальше
+// заданного расстояния, мотор едет вперед, если ближе — назад.
+func (c FollowController) Power(sensorValue float64) int8 {
+	power := c.Gain * (sensorValue - c.Target)
+	if c.Invert {
+		power = -power
+	}
+	return int8(clamp(power, -c.MaxPower, c.MaxPower))
+}
+
+// String возвращает текстовое описание регулятора
+func (c FollowController) String() string {
+	return fmt.Sprintf("мотор %d держит %g на датчике %d (k=%g, макс. %g%%)",
+		c.MotorPort, c.Target, c.SensorPort, c.Gain, c.MaxPower)
+}
+
+// startFollow запускает регулятор в фоне. Регулятор работает, пока программу
+// не остановят, и удерживает ее запущенной после окончания цепочек.
+func (pm *ProgramManager) startFollow(block *ProgramBlock) error {
+	if !pm.hubMgr.Ready() {
+		return fmt.Errorf("не подключено к хабу")
+	}
+
+	ctx := pm.runContext()
+	initial := followFromParameters(block.Parameters)
+	pm.setFollowParams(block.ID, initial)
+	log.Printf("Запуск регулятора блока %d: %s", block.ID, initial)
+
+	pm.controllers.Add(1)
+	go func() {
+		if pm.crashHandler != nil {
+			defer pm.crashHandler()
+		}
+		defer pm.controllers.Done()
+		defer pm.clearFollowParams(block.ID)
+
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+
+		lastPower := int8(0)
+		motorPort := initial.MotorPort
+		for {
+			select {
+			case <-ctx.Done():
+				pm.deviceMgr.SetMotorPower(motorPort, 0, 0)
+				log.Printf("Регулятор блока %d остановлен", block.ID)
+				return
+			case <-ticker.C:
+			}
+
+			// Параметры берутся на каждом шаге, чтобы их можно было подстраивать на ходу
+			c, ok := pm.followParamsFor(block.ID)
+			if !ok {
+				continue
+			}
+			if c.MotorPort != motorPort {
+				pm.deviceMgr.SetMotorPower(motorPort, 0, 0)
+				motorPort = c.MotorPort
+				lastPower = 0
+			}
+
+			value, ok := pm.hubMgr.GetSensorValue(c.SensorPort)
+			if !ok {
+				continue
+			}
+
+			power := c.Power(value)
+			if power == lastPower {
+				continue
+			}
+			if err := pm.deviceMgr.SetMotorPower(c.MotorPort, power, 0); err != nil {
+				log.Printf("Регулятор блока %d: %v", block.ID, err)
+				continue
+			}
+			lastPower = power
+		}
+	}()
+
+	return nil
+}
+
+// setFollowParams задает параметры работающего регулятора блока
+func (pm *ProgramManager) setFollowParams(blockID int, c FollowController) {
+	pm.followMu.Lock()
+	defer pm.followMu.Unlock()
+
+	if pm.followParams == nil {
+		pm.followParams = make(map[int]FollowController)
+	}
+	pm.followParams[blockID] = c
+}
+
+// followParamsFor возвращает параметры работающего регулятора блока
+func (pm *ProgramManager) followParamsFor(blockID int) (FollowController, bool) {
+	pm.followMu.Lock()
+	defer pm.followMu.Unlock()
+
+	c, ok := pm.followParams[blockID]
+	return c, ok
+}
+
+// clearFollowParams забывает параметры остановленного регулятора
+func (pm *ProgramManager) clearFollowParams(blockID int) {
+	pm.followMu.Lock()
+	defer pm.followMu.Unlock()
+
+	delete(pm.followParams, blockID)
+}
+
+// UpdateFollow передает новые параметры блока регулятору, если он работает.
+// Вызывается из редактора, который владеет картой параметров блока
+func (pm *ProgramManager) UpdateFollow(block *ProgramBlock) {
+	pm.followMu.Lock()
+	defer pm.followMu.Unlock()
+
+	if _, ok := pm.followParams[block.ID]; ok {
+		pm.followParams[block.ID] = followFromParameters(block.Parameters)
+	}
+}
+
+// addFollowControls добавляет элементы управления для блока регулятора
+func (e *BlockEditor) addFollowControls(cont *fyne.Container) {
+	params := e.block.Parameters
+	if _, ok := params["target"]; !ok {
+		setDefaultFollowParameters(params)
+	}
+	// Работающий регулятор получает копию параметров, а не читает карту блока
+	changed := func() {
+		e.notifyChange()
+		if e.programMgr != nil {
+			e.programMgr.UpdateFollow(e.block)
+		}
+	}
+
+	newPortSelect := func(key string) *widget.Select {
+		portSelect := widget.NewSelect([]string{"Порт 1", "Порт 2"}, func(selected string) {
+			if selected == "Порт 2" {
+				params[key] = byte(2)
+			} else {
+				params[key] = byte(1)
+			}
+			changed()
+		})
+		if port, _ := params[key].(byte); port == 2 {
+			portSelect.SetSelected("Порт 2")
+		} else {
+			portSelect.SetSelected("Порт 1")
+		}
+		return portSelect
+	}
+
+	newSlider := func(key string, lo, hi, step float64, format func(float64) string) fyne.CanvasObject {
+		slider := widget.NewSlider(lo, hi)
+		slider.Step = step
+		slider.Value = parameterToFloat(params[key])
+		valueLabel := widget.NewLabel(format(slider.Value))
+		slider.OnChanged = func(value float64) {
+			params[key] = value
+			valueLabel.SetText(format(value))
+			changed()
+		}
+		return container.NewBorder(nil, nil, nil, valueLabel, slider)
+	}
+
+	distance := findConditionSensor(ConditionSensorDistance)
+
+	invertCheck := widget.NewCheck("Мотор установлен наоборот", func(checked bool) {
+		params["invert"] = checked
+		changed()
+	})
+	invertCheck.Checked = paramBool(params, "invert", false)
+
+	hint := widget.NewLabel("Мощность = коэффициент × (расстояние − цель). Регулятор работает в фоне, пока программу не остановят.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	cont.Add(widget.NewLabel("Порт мотора:"))
+	cont.Add(newPortSelect("motor_port"))
+	cont.Add(widget.NewLabel("Порт датчика расстояния:"))
+	cont.Add(newPortSelect("sensor_port"))
+	cont.Add(widget.NewLabel("Держать расстояние:"))
+	cont.Add(newSlider("target", distance.Min, distance.Max, distance.Step,
+		func(value float64) string { return fmt.Sprintf("%.0f %s", value, distance.Unit) }))
+	cont.Add(widget.NewLabel("Коэффициент (% на сантиметр отклонения):"))
+	cont.Add(newSlider("gain", 1, 50, 1,
+		func(value float64) string { return fmt.Sprintf("%.0f", value) }))
+	cont.Add(widget.NewLabel("Максимальная мощность:"))
+	cont.Add(newSlider("max_power", 10, 100, 5,
+		func(value float64) string { return fmt.Sprintf("%.0f%%", value) }))
+	cont.Add(invertCheck)
+	cont.Add(hint)
+}
@@ -4,6 +4,7 @@ go 1.24.9
 
 require (
 	fyne.io/fyne/v2 v2.7.2
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	tinygo.org/x/bluetooth v0.14.0
 )
 
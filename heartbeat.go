@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	heartbeatInterval = 5 * time.Second  // Как часто проверять связь с хабом
+	heartbeatTimeout  = 15 * time.Second // Сколько ждать признаков жизни хаба
+	reconnectAttempts = 3                // Попыток переподключения после потери связи
+	reconnectDelay    = 2 * time.Second  // Пауза перед первой попыткой, дальше удваивается
+)
+
+// markActivity отмечает, что хаб недавно отвечал
+func (hm *HubManager) markActivity() {
+	hm.lastActivity.Store(time.Now().UnixNano())
+}
+
+// sinceActivity возвращает время с последнего ответа хаба
+func (hm *HubManager) sinceActivity() time.Duration {
+	return time.Since(time.Unix(0, hm.lastActivity.Load()))
+}
+
+// startHeartbeat запускает периодическую проверку связи с хабом.
+// Вызывается под connectionMutex
+func (hm *HubManager) startHeartbeat() {
+	hm.stopHeartbeat()
+	hm.markActivity()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hm.heartbeatCancel = cancel
+	go hm.heartbeatLoop(ctx, hm.deviceAddress)
+}
+
+// stopHeartbeat останавливает проверку связи. Вызывается под connectionMutex
+func (hm *HubManager) stopHeartbeat() {
+	if hm.heartbeatCancel != nil {
+		hm.heartbeatCancel()
+		hm.heartbeatCancel = nil
+	}
+}
+
+// cancelReconnect прерывает переподключение после потери связи, когда
+// пользователь сам отключился или выбрал другой хаб. Вызывается под connectionMutex
+func (hm *HubManager) cancelReconnect() {
+	if hm.reconnectCancel != nil {
+		hm.reconnectCancel()
+		hm.reconnectCancel = nil
+	}
+}
+
+// heartbeatLoop читает уровень батареи и следит, чтобы хаб не молчал дольше heartbeatTimeout
+func (hm *HubManager) heartbeatLoop(ctx context.Context, address string) {
+	if hm.crashHandler != nil {
+		defer hm.crashHandler()
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := hm.heartbeatRead()
+		if err == nil {
+			hm.markActivity()
+			continue
+		}
+
+		silence := hm.sinceActivity()
+		if silence <= heartbeatTimeout {
+			log.Printf("Проверка связи с хабом не удалась: %v", err)
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		hm.handleLostConnection(address, fmt.Errorf("хаб не отвечает %v: %v", silence.Round(time.Second), err))
+		return
+	}
+}
+
+// heartbeatRead читает батарею, не дольше одного интервала проверки
+func (hm *HubManager) heartbeatRead() error {
+	result := make(chan error, 1)
+	go func() {
+		if hm.crashHandler != nil {
+			defer hm.crashHandler()
+		}
+		_, err := hm.ReadCharacteristic(BATTERY_LEVEL_UUID)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(heartbeatInterval):
+		return fmt.Errorf("нет ответа на чтение батареи")
+	}
+}
+
+// handleLostConnection сообщает о потере связи и пытается переподключиться к
+// тому же хабу. Connect и Disconnect, вызванные пользователем, отменяют попытки
+func (hm *HubManager) handleLostConnection(address string, reason error) {
+	log.Printf("Связь с хабом потеряна: %v", reason)
+
+	hm.connectionMutex.Lock()
+	hm.cancelReconnect()
+	hm.disconnectLocked()
+	ctx, cancel := context.WithCancel(context.Background())
+	hm.reconnectCancel = cancel
+	hm.connectionMutex.Unlock()
+	defer cancel()
+
+	if hm.connectionHealthCallback != nil {
+		hm.connectionHealthCallback(false)
+	}
+
+	delay := reconnectDelay
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		select {
+		case <-ctx.Done():
+			log.Printf("Переподключение к хабу %s отменено", address)
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		log.Printf("Переподключение к хабу %s, попытка %d из %d", address, attempt, reconnectAttempts)
+		if err := hm.reconnect(ctx, address); err != nil {
+			log.Printf("Переподключение не удалось: %v", err)
+			continue
+		}
+
+		if hm.connectionHealthCallback != nil {
+			hm.connectionHealthCallback(true)
+		}
+		return
+	}
+
+	log.Printf("Не удалось переподключиться к хабу %s", address)
+	if hm.connectionStateCallback != nil {
+		hm.connectionStateCallback(false)
+	}
+}
+
+// reconnect подключается к хабу, если переподключение еще не отменено. Отмена
+// проверяется под тем же замком, что и подключение, поэтому попытка не
+// перебьет хаб, выбранный пользователем
+func (hm *HubManager) reconnect(ctx context.Context, address string) error {
+	hm.connectionMutex.Lock()
+	defer hm.connectionMutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := hm.connectLocked(address)
+	if err == nil {
+		hm.reconnectCancel = nil
+	}
+	return err
+}
+
+// SetConnectionHealthCallback устанавливает callback потери и восстановления связи с хабом
+func (hm *HubManager) SetConnectionHealthCallback(callback func(healthy bool)) {
+	hm.connectionHealthCallback = callback
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	tinybluetooth "tinygo.org/x/bluetooth"
+)
+
+const (
+	hubLEDPort            = 6                      // Встроенный светодиод хаба
+	identifyBlinkCount    = 4                      // Сколько раз мигнуть при опознании
+	identifyBlinkInterval = 300 * time.Millisecond // Длительность вспышки и паузы
+)
+
+// identifyColor фиолетовый цвет опознания: его не используют программы по умолчанию
+var identifyColor = [3]byte{0x80, 0x00, 0xFF}
+
+// Identify мигает светодиодом хаба фиолетовым, чтобы найти его среди других.
+// К подключенному хабу команды идут по текущему соединению, к остальным
+// хаб подключается ненадолго, не меняя состояние приложения
+func (hm *HubManager) Identify(address string) error {
+	hm.connectionMutex.RLock()
+	connected, current := hm.isConnected, hm.deviceAddress
+	hm.connectionMutex.RUnlock()
+
+	if connected {
+		if current != address {
+			return fmt.Errorf("отключитесь от хаба %s, чтобы опознать другой хаб", current)
+		}
+		if err := blinkHubLED(hm.ConfigurePort, hm.SendCommand); err != nil {
+			return err
+		}
+		// Зеленый цвет означает, что хаб подключен к приложению
+		return hm.SendCommand(NewLEDCommand(hubLEDPort).RGB(0x00, 0xFF, 0x00).From(SourceSystem))
+	}
+
+	return hm.identifyDisconnected(address)
+}
+
+// identifyDisconnected подключается к хабу только на время мигания
+func (hm *HubManager) identifyDisconnected(address string) error {
+	hm.connectionMutex.Lock()
+	defer hm.connectionMutex.Unlock()
+
+	result, err := hm.findDevice(address)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Временное подключение к %s для опознания", address)
+	device, err := hm.adapter.Connect(result.Address, tinybluetooth.ConnectionParams{})
+	if err != nil {
+		return fmt.Errorf("ошибка подключения: %v", err)
+	}
+	defer device.Disconnect()
+
+	characteristics := make(map[string]tinybluetooth.DeviceCharacteristic)
+	services, err := device.DiscoverServices(nil)
+	if err != nil {
+		return fmt.Errorf("ошибка обнаружения служб: %v", err)
+	}
+	for _, service := range services {
+		chars, err := service.DiscoverCharacteristics(nil)
+		if err != nil {
+			continue
+		}
+		for _, char := range chars {
+			characteristics[char.UUID().String()] = char
+		}
+	}
+
+	send := func(cmd Command) error {
+		data, err := cmd.Build()
+		if err != nil {
+			return err
+		}
+		char, exists := characteristics[cmd.Characteristic()]
+		if !exists {
+			return fmt.Errorf("характеристика %s не найдена", cmd.Characteristic())
+		}
+		_, err = char.WriteWithoutResponse(data)
+		return err
+	}
+	configure := func(cmd *InputFormatCommand) error { return send(cmd) }
+
+	if err := blinkHubLED(configure, send); err != nil {
+		return err
+	}
+	return send(NewLEDCommand(hubLEDPort).RGB(0x00, 0x00, 0x00))
+}
+
+// blinkHubLED переводит светодиод хаба в режим RGB и мигает цветом опознания
+func blinkHubLED(configure func(*InputFormatCommand) error, send func(Command) error) error {
+	if err := configure(NewInputFormatCommand(hubLEDPort, DEVICE_TYPE_RGB_LIGHT).Mode(LED_DISCRETE_MODE)); err != nil {
+		return fmt.Errorf("не удалось настроить светодиод: %v", err)
+	}
+
+	off := NewLEDCommand(hubLEDPort).RGB(0x00, 0x00, 0x00).From(SourceSystem)
+	on := NewLEDCommand(hubLEDPort).RGB(identifyColor[0], identifyColor[1], identifyColor[2]).From(SourceSystem)
+	for i := 0; i < identifyBlinkCount; i++ {
+		if err := send(on); err != nil {
+			return fmt.Errorf("не удалось зажечь светодиод: %v", err)
+		}
+		time.Sleep(identifyBlinkInterval)
+		if err := send(off); err != nil {
+			return fmt.Errorf("не удалось погасить светодиод: %v", err)
+		}
+		time.Sleep(identifyBlinkInterval)
+	}
+	return nil
+}
+
+// identifyHub мигает светодиодом хаба в фоне и показывает ошибку, если не вышло
+func (gui *MainGUI) identifyHub(address string) {
+	go func() {
+		if err := gui.hubMgr.Identify(address); err != nil {
+			fyne.Do(func() {
+				dialog.ShowError(fmt.Errorf("не удалось опознать хаб: %v", err), gui.window)
+			})
+		}
+	}()
+}
+
+// identifyConnectedHub мигает светодиодом подключенного хаба
+func (gui *MainGUI) identifyConnectedHub() {
+	if !gui.hubMgr.IsConnected() {
+		dialog.ShowInformation("Хаб не подключен", "Подключитесь к хабу, чтобы опознать его", gui.window)
+		return
+	}
+	gui.identifyHub(gui.hubMgr.GetHubInfo().Address)
+}
@@ -13,6 +13,12 @@ type HubInfo struct {
 	SystemID        string
 	Battery         int
 	LastUpdated     time.Time
+
+	// Сведения из рекламных пакетов, собранные при поиске
+	RSSIHistory      []int
+	Services         []string
+	ManufacturerData []byte
+	Pairing          bool // Хаб объявлял нажатую кнопку, то есть ждет подключения
 }
 
 // Device представляет подключенное устройство
@@ -24,6 +30,7 @@ type Device struct {
 	LastValue   interface{}
 	LastUpdate  time.Time
 	Properties  map[string]interface{}
+	Errors      int // Число неудачных команд устройству
 }
 
 // PortInfo информация о порте хаба
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	settingHubInventory = "hub_inventory" // Учет хабов класса в JSON
+	csvFileExtension    = ".csv"
+)
+
+// InventoryHub запись учета хаба: сведения с последнего подключения и заметки учителя
+type InventoryHub struct {
+	Address       string    `json:"address"`
+	Name          string    `json:"name"`
+	SystemID      string    `json:"system_id,omitempty"`
+	Firmware      string    `json:"firmware,omitempty"`
+	Battery       int       `json:"battery"` // Последний известный заряд, -1 — неизвестен
+	LastConnected time.Time `json:"last_connected"`
+	Student       string    `json:"student,omitempty"`
+	Notes         string    `json:"notes,omitempty"`
+
+	DistanceCalibration *DistanceCalibration `json:"distance_calibration,omitempty"` // Калибровка датчика расстояния
+}
+
+// Key ключ хаба в учете: System ID, а если он еще не прочитан — адрес
+func (h InventoryHub) Key() string {
+	if h.SystemID != "" {
+		return h.SystemID
+	}
+	return h.Address
+}
+
+// HubInventory учет всех хабов, которые когда-либо подключались к этому компьютеру
+type HubInventory struct {
+	mu   sync.Mutex
+	hubs []*InventoryHub
+}
+
+// NewHubInventory создает пустой учет
+func NewHubInventory() *HubInventory {
+	return &HubInventory{}
+}
+
+// Load загружает сохраненный учет
+func (inv *HubInventory) Load(data string) error {
+	if data == "" {
+		return nil
+	}
+	var hubs []*InventoryHub
+	if err := json.Unmarshal([]byte(data), &hubs); err != nil {
+		return err
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.hubs = hubs
+	return nil
+}
+
+// Export записывает учет для сохранения
+func (inv *HubInventory) Export() string {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	data, err := json.Marshal(inv.hubs)
+	if err != nil {
+		log.Printf("Не удалось сохранить учет хабов: %v", err)
+		return ""
+	}
+	return string(data)
+}
+
+// Record обновляет запись хаба сведениями подключения. Хаб находится по System ID,
+// а запись, созданная до чтения System ID, находится по адресу
+func (inv *HubInventory) Record(info HubInfo, battery int) {
+	if info.Address == "" {
+		return
+	}
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	hub := inv.findLocked(info)
+	if hub == nil {
+		hub = &InventoryHub{Battery: -1}
+		inv.hubs = append(inv.hubs, hub)
+	}
+
+	hub.Address = info.Address
+	hub.LastConnected = time.Now()
+	if info.Name != "" {
+		hub.Name = info.Name
+	}
+	if info.SystemID != "" {
+		hub.SystemID = info.SystemID
+	}
+	if info.FirmwareVersion != "" {
+		hub.Firmware = info.FirmwareVersion
+	}
+	if battery >= 0 {
+		hub.Battery = battery
+	}
+}
+
+// findLocked находит запись хаба по System ID или, пока он не прочитан, по адресу
+func (inv *HubInventory) findLocked(info HubInfo) *InventoryHub {
+	var hub *InventoryHub
+	for _, existing := range inv.hubs {
+		if info.SystemID != "" && existing.SystemID == info.SystemID {
+			return existing
+		}
+		if existing.Address == info.Address && (existing.SystemID == "" || info.SystemID == "") {
+			hub = existing
+		}
+	}
+	return hub
+}
+
+// DistanceCalibration возвращает калибровку датчика расстояния хаба; false, если хаб не калибровали
+func (inv *HubInventory) DistanceCalibration(info HubInfo) (DistanceCalibration, bool) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	hub := inv.findLocked(info)
+	if hub == nil || hub.DistanceCalibration == nil {
+		return DistanceCalibration{}, false
+	}
+	return *hub.DistanceCalibration, true
+}
+
+// SetDistanceCalibration сохраняет калибровку датчика расстояния хаба; nil удаляет ее
+func (inv *HubInventory) SetDistanceCalibration(info HubInfo, calibration *DistanceCalibration) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if hub := inv.findLocked(info); hub != nil {
+		hub.DistanceCalibration = calibration
+	}
+}
+
+// Update меняет ученика и заметки хаба
+func (inv *HubInventory) Update(key, student, notes string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for _, hub := range inv.hubs {
+		if hub.Key() == key {
+			hub.Student, hub.Notes = student, notes
+		}
+	}
+}
+
+// Remove удаляет хаб из учета
+func (inv *HubInventory) Remove(key string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	for i, hub := range inv.hubs {
+		if hub.Key() == key {
+			inv.hubs = append(inv.hubs[:i], inv.hubs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Hubs возвращает копию учета, упорядоченную по имени хаба
+func (inv *HubInventory) Hubs() []InventoryHub {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	hubs := make([]InventoryHub, len(inv.hubs))
+	for i, hub := range inv.hubs {
+		hubs[i] = *hub
+	}
+	sort.SliceStable(hubs, func(i, j int) bool { return hubs[i].Name < hubs[j].Name })
+	return hubs
+}
+
+// motorRuntime возвращает наработку моторов хаба из статистики обслуживания
+func motorRuntime(usage []HubUsage, hub InventoryHub) (time.Duration, bool) {
+	for _, u := range usage {
+		if u.Key == hub.Key() || u.Key == hub.Address {
+			runtime, _ := u.Total()
+			return runtime, true
+		}
+	}
+	return 0, false
+}
+
+// writeInventoryCSV записывает учет хабов в CSV
+func writeInventoryCSV(writer *csv.Writer, hubs []InventoryHub, usage []HubUsage) error {
+	header := []string{"Название", "System ID", "Адрес", "Прошивка", "Заряд, %",
+		"Последнее подключение", "Наработка моторов, ч", "Ученик", "Заметки"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, hub := range hubs {
+		battery := ""
+		if hub.Battery >= 0 {
+			battery = strconv.Itoa(hub.Battery)
+		}
+		runtime := ""
+		if d, ok := motorRuntime(usage, hub); ok {
+			runtime = strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+		}
+		record := []string{hub.Name, hub.SystemID, hub.Address, hub.Firmware, battery,
+			hub.LastConnected.Format("2006-01-02 15:04"), runtime, hub.Student, hub.Notes}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// recordHubInventory заносит сведения подключенного хаба в учет
+func (gui *MainGUI) recordHubInventory(info HubInfo, battery int) {
+	gui.inventory.Record(info, battery)
+	gui.preferences().SetString(settingHubInventory, gui.inventory.Export())
+}
+
+// showHubInventory показывает учет хабов класса с заметками и выгрузкой в CSV
+func (gui *MainGUI) showHubInventory() {
+	window := fyne.CurrentApp().NewWindow("Учет хабов")
+	list := container.NewVBox()
+	save := func() {
+		gui.preferences().SetString(settingHubInventory, gui.inventory.Export())
+	}
+
+	var refresh func()
+	refresh = func() {
+		list.Objects = nil
+		hubs := gui.inventory.Hubs()
+		usage := gui.hubMgr.MotorUsage().Snapshot()
+		if len(hubs) == 0 {
+			list.Add(widget.NewLabel("Хабы еще не подключались"))
+		}
+		for _, hub := range hubs {
+			hub := hub
+			student := widget.NewEntry()
+			student.SetText(hub.Student)
+			student.SetPlaceHolder("Не закреплен")
+			notes := widget.NewMultiLineEntry()
+			notes.SetText(hub.Notes)
+			notes.SetPlaceHolder("Например: треснул корпус, заменен мотор")
+			notes.SetMinRowsVisible(2)
+			changed := func(string) {
+				gui.inventory.Update(hub.Key(), student.Text, notes.Text)
+				save()
+			}
+			student.OnChanged = changed
+			notes.OnChanged = changed
+
+			battery := "неизвестен"
+			if hub.Battery >= 0 {
+				battery = fmt.Sprintf("%d%%", hub.Battery)
+			}
+			form := widget.NewForm(
+				widget.NewFormItem("System ID", widget.NewLabel(valueOrDash(hub.SystemID))),
+				widget.NewFormItem("Адрес", widget.NewLabel(hub.Address)),
+				widget.NewFormItem("Прошивка", widget.NewLabel(valueOrDash(hub.Firmware))),
+				widget.NewFormItem("Заряд", widget.NewLabel(battery+" на "+hub.LastConnected.Format("02.01.2006 15:04"))),
+			)
+			if runtime, ok := motorRuntime(usage, hub); ok {
+				form.Append("Наработка моторов", widget.NewLabel(formatRuntime(runtime)))
+			}
+			form.Append("Ученик", student)
+			form.Append("Заметки", notes)
+
+			remove := widget.NewButtonWithIcon("Убрать из учета", theme.DeleteIcon(), func() {
+				dialog.ShowConfirm("Убрать из учета",
+					fmt.Sprintf("Убрать хаб «%s» из учета? При следующем подключении он появится снова.", hub.Name),
+					func(confirmed bool) {
+						if confirmed {
+							gui.inventory.Remove(hub.Key())
+							save()
+							refresh()
+						}
+					}, window)
+			})
+
+			title := hub.Name
+			if title == "" {
+				title = "Хаб"
+			}
+			list.Add(widget.NewCard(title, "", container.NewVBox(form, container.NewHBox(remove))))
+		}
+		list.Refresh()
+	}
+	refresh()
+
+	export := widget.NewButtonWithIcon("Выгрузить в CSV...", theme.DownloadIcon(), func() {
+		d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			if err := writeInventoryCSV(csv.NewWriter(writer), gui.inventory.Hubs(), gui.hubMgr.MotorUsage().Snapshot()); err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка выгрузки учета: %v", err), window)
+				return
+			}
+			log.Printf("Учет хабов выгружен: %s", writer.URI().Path())
+		}, window)
+		d.SetFileName("хабы" + csvFileExtension)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{csvFileExtension}))
+		d.Show()
+	})
+
+	window.SetContent(container.NewBorder(nil, container.NewHBox(export), nil, nil, container.NewVScroll(list)))
+	window.Resize(fyne.NewSize(560, 620))
+	window.Show()
+}
+
+// valueOrDash возвращает значение или прочерк, если оно пустое
+func valueOrDash(value string) string {
+	if value == "" {
+		return "—"
+	}
+	return value
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// HubLink связь с хабом, через которую выполняется программа и управляются
+// устройства: команды выходам, настройка портов, значения датчиков и события.
+// HubManager реализует ее поверх Bluetooth; логику выполнения можно проверять
+// без хаба, подставив в NewProgramManager и NewDeviceManager свою реализацию
+type HubLink interface {
+	IsConnected() bool
+	Ready() bool
+	SendCommand(cmd Command) error
+	ConfigurePort(cmd *InputFormatCommand) error
+	PortMode(port byte) (byte, bool)
+	ForgetPortMode(port byte)
+	DetectDevices()
+	GetSensorValue(portID byte) (float64, bool)
+	ObjectDetected(portID byte) (bool, bool)
+	Events() *EventBus
+	Arbiter() *OutputArbiter
+	PowerAction(action string) error
+
+	Replaying() bool
+	StartReplay(recording *SensorRecording) error
+	PlayReplay(ctx context.Context, scale func(time.Duration) time.Duration)
+	StopReplay()
+}
+
+// DeviceActuator управление устройствами на портах, которое нужно блокам программы
+type DeviceActuator interface {
+	IsDeviceConnected(portID byte, deviceType byte) bool
+	SetMotorPower(portID byte, power int8, duration uint16) error
+	SetMotorPowerAndWait(portID byte, power int8, duration uint16) error
+	SetLEDColor(portID byte, red, green, blue byte) error
+	PlayToneAndWait(portID byte, frequency uint16, duration uint16) error
+}
+
+var (
+	_ HubLink        = (*HubManager)(nil)
+	_ DeviceActuator = (*DeviceManager)(nil)
+)
+
+// DetectDevices заново опрашивает порты хаба и находит устройства
+func (hm *HubManager) DetectDevices() {
+	hm.autoDetectDevicesV2()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// fakeHub подменяет хаб в тестах: всегда подключен, команды записывает,
+// показания датчиков берет из sensors
+type fakeHub struct {
+	events  *EventBus
+	arbiter *OutputArbiter
+
+	mu       sync.Mutex
+	commands []Command
+	sensors  map[byte]float64
+}
+
+func newFakeHub() *fakeHub {
+	return &fakeHub{
+		events:  NewEventBus(),
+		arbiter: NewOutputArbiter(),
+		sensors: make(map[byte]float64),
+	}
+}
+
+func (h *fakeHub) IsConnected() bool { return true }
+func (h *fakeHub) Ready() bool       { return true }
+
+func (h *fakeHub) SendCommand(cmd Command) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = append(h.commands, cmd)
+	return nil
+}
+
+// sentCommands возвращает копию отправленных команд
+func (h *fakeHub) sentCommands() []Command {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Command(nil), h.commands...)
+}
+
+func (h *fakeHub) ConfigurePort(cmd *InputFormatCommand) error { return nil }
+func (h *fakeHub) PortMode(port byte) (byte, bool)             { return 0, false }
+func (h *fakeHub) ForgetPortMode(port byte)                    {}
+func (h *fakeHub) DetectDevices()                              {}
+
+func (h *fakeHub) GetSensorValue(portID byte) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	value, ok := h.sensors[portID]
+	return value, ok
+}
+
+func (h *fakeHub) ObjectDetected(portID byte) (bool, bool) { return false, false }
+func (h *fakeHub) Events() *EventBus                       { return h.events }
+func (h *fakeHub) Arbiter() *OutputArbiter                 { return h.arbiter }
+func (h *fakeHub) PowerAction(action string) error         { return nil }
+
+func (h *fakeHub) Replaying() bool                              { return false }
+func (h *fakeHub) StartReplay(recording *SensorRecording) error { return nil }
+func (h *fakeHub) StopReplay()                                  {}
+func (h *fakeHub) PlayReplay(ctx context.Context, scale func(time.Duration) time.Duration) {
+}
+
+// fakeActuator записывает действия с устройствами в порядке вызова.
+// Действия «AndWait» занимают delay, чтобы можно было остановить программу посреди блока.
+// setDevices задает типы устройств на портах; без него подключено любое устройство
+type fakeActuator struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	actions []string
+	devices map[byte]byte
+}
+
+func (a *fakeActuator) record(format string, args ...interface{}) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.actions = append(a.actions, fmt.Sprintf(format, args...))
+}
+
+// recorded возвращает копию записанных действий
+func (a *fakeActuator) recorded() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.actions...)
+}
+
+// setDevices задает типы устройств на портах
+func (a *fakeActuator) setDevices(devices map[byte]byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.devices = devices
+}
+
+func (a *fakeActuator) IsDeviceConnected(portID byte, deviceType byte) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.devices == nil {
+		return true
+	}
+	connected, ok := a.devices[portID]
+	return ok && connected == deviceType
+}
+
+func (a *fakeActuator) SetMotorPower(portID byte, power int8, duration uint16) error {
+	a.record("motor %d %d", portID, power)
+	return nil
+}
+
+func (a *fakeActuator) SetMotorPowerAndWait(portID byte, power int8, duration uint16) error {
+	a.record("motor %d %d", portID, power)
+	time.Sleep(a.delay)
+	return nil
+}
+
+func (a *fakeActuator) SetLEDColor(portID byte, red, green, blue byte) error {
+	a.record("led %d %d %d %d", portID, red, green, blue)
+	return nil
+}
+
+func (a *fakeActuator) PlayToneAndWait(portID byte, frequency uint16, duration uint16) error {
+	a.record("tone %d %d", portID, frequency)
+	time.Sleep(a.delay)
+	return nil
+}
+
+var (
+	_ HubLink        = (*fakeHub)(nil)
+	_ DeviceActuator = (*fakeActuator)(nil)
+)
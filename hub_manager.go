@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tinybluetooth "tinygo.org/x/bluetooth"
@@ -13,49 +15,151 @@ import (
 
 // HubManager управляет подключением к WeDo 2.0 хабу
 type HubManager struct {
-	adapter                   *tinybluetooth.Adapter
-	device                    tinybluetooth.Device
-	deviceAddress             string
-	isConnected               bool
-	connectionMutex           sync.RWMutex
-	hubInfo                   *HubInfo
-	stopScan                  context.CancelFunc
-	services                  map[string]tinybluetooth.DeviceService
-	characteristics           map[string]tinybluetooth.DeviceCharacteristic
-	subscribedCharacteristics map[string]bool
-	devices                   map[byte]*Device
+	adapter         *tinybluetooth.Adapter
+	adapterID       string
+	allowedHubs     []string
+	allowedMu       sync.RWMutex
+	device          tinybluetooth.Device
+	deviceAddress   string
+	isConnected     bool
+	connectionMutex sync.RWMutex
+	hubInfo         *HubInfo
+	stopScan        context.CancelFunc
+	services        map[string]tinybluetooth.DeviceService
+	characteristics map[string]tinybluetooth.DeviceCharacteristic
+	subscriptions   *SubscriptionManager
+	devices         *DeviceManager
+	sensorValues    map[byte]float64
+	sensorMu        sync.RWMutex
+
+	// Калибровка датчика расстояния и его последние показания по условной шкале
+	distanceCalibration DistanceCalibration
+	rawDistances        map[byte]float64
+	tiltAngles          map[byte]tiltAngles
+	objects             *ObjectDetector
+	bumps               *BumpDetector
+	voltage             *VoltageWatchdog
+
+	portModes     *PortModeCache
+	events        *EventBus
+	metrics       *SessionMetrics
+	trace         *BLETrace
+	protocolLog   *ProtocolLogger
+	usage         *MotorUsageTracker
+	arbiter       *OutputArbiter
+	recorder      *SensorRecorder
+	replay        atomic.Pointer[SensorReplay]
+	lastActivity  atomic.Int64
+	pendingWrites atomic.Int32
+
+	// Проверка связи и переподключение после потери связи; меняются под connectionMutex
+	heartbeatCancel context.CancelFunc
+	reconnectCancel context.CancelFunc
 
 	// Callback'и
-	batteryUpdateCallback   func(batteryLevel int)
-	hubInfoUpdateCallback   func(info *HubInfo)
-	deviceUpdateCallback    func(portID byte, device *Device)
-	connectionStateCallback func(isConnected bool)
+	batteryUpdateCallback    func(batteryLevel int)
+	hubInfoUpdateCallback    func(info *HubInfo)
+	connectionStateCallback  func(isConnected bool)
+	connectionHealthCallback func(healthy bool)
+
+	crashHandler func()
+}
+
+// NewHubManager создает новый менеджер хаба на выбранном BLE-адаптере,
+// пустой adapterID — адаптер по умолчанию
+func NewHubManager(adapterID string) (*HubManager, error) {
+	adapter, err := enableAdapter(adapterID)
+	if err != nil && adapterID != "" {
+		log.Printf("Адаптер %s недоступен (%v), используем адаптер по умолчанию", adapterID, err)
+		adapterID = ""
+		adapter, err = enableAdapter(adapterID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newHubManager(adapter, adapterID), nil
 }
 
-// NewHubManager создает новый менеджер хаба
-func NewHubManager() (*HubManager, error) {
-	adapter := tinybluetooth.DefaultAdapter
+// newHubManager собирает менеджер вокруг включенного адаптера. Без адаптера
+// менеджер остается отключенным от хаба, чего достаточно для проверки интерфейса
+func newHubManager(adapter *tinybluetooth.Adapter, adapterID string) *HubManager {
+	hm := &HubManager{
+		adapter:             adapter,
+		adapterID:           adapterID,
+		hubInfo:             &HubInfo{},
+		services:            make(map[string]tinybluetooth.DeviceService),
+		characteristics:     make(map[string]tinybluetooth.DeviceCharacteristic),
+		subscriptions:       NewSubscriptionManager(),
+		sensorValues:        make(map[byte]float64),
+		rawDistances:        make(map[byte]float64),
+		tiltAngles:          make(map[byte]tiltAngles),
+		distanceCalibration: defaultDistanceCalibration,
+		portModes:           NewPortModeCache(),
+		events:              NewEventBus(),
+		metrics:             NewSessionMetrics(),
+		trace:               NewBLETrace(),
+		protocolLog:         NewProtocolLogger(),
+		usage:               NewMotorUsageTracker(),
+		arbiter:             NewOutputArbiter(),
+		recorder:            &SensorRecorder{},
+	}
+	hm.devices = NewDeviceManager(hm)
+	hm.objects = NewObjectDetector(hm.publishObjectDetected)
+	hm.bumps = NewBumpDetector(hm.publishTiltBump)
+	hm.voltage = NewVoltageWatchdog(hm.usage.AnyRunning, hm.publishVoltageSag)
+	hm.registerSubscriptions()
+	return hm
+}
+
+// enableAdapter включает BLE-адаптер по идентификатору
+func enableAdapter(id string) (*tinybluetooth.Adapter, error) {
+	adapter := adapterByID(id)
 	if adapter == nil {
 		return nil, fmt.Errorf("BLE адаптер не найден")
 	}
-
 	if err := adapter.Enable(); err != nil {
 		return nil, fmt.Errorf("ошибка включения BLE адаптера: %v", err)
 	}
+	return adapter, nil
+}
+
+// AdapterID возвращает идентификатор используемого адаптера, пустой — адаптер по умолчанию
+func (hm *HubManager) AdapterID() string {
+	return hm.adapterID
+}
+
+// SelectAdapter переключает менеджер на другой BLE-адаптер. Пока хаб подключен,
+// адаптер сменить нельзя
+func (hm *HubManager) SelectAdapter(id string) error {
+	if id == hm.adapterID {
+		return nil
+	}
+	if hm.IsConnected() {
+		return fmt.Errorf("отключитесь от хаба, чтобы сменить адаптер")
+	}
+
+	adapter, err := enableAdapter(id)
+	if err != nil {
+		return err
+	}
+	hm.adapter = adapter
+	hm.adapterID = id
+	log.Printf("Выбран BLE адаптер: %s", adapterName(id))
+	return nil
+}
 
-	return &HubManager{
-		adapter:                   adapter,
-		hubInfo:                   &HubInfo{},
-		services:                  make(map[string]tinybluetooth.DeviceService),
-		characteristics:           make(map[string]tinybluetooth.DeviceCharacteristic),
-		subscribedCharacteristics: make(map[string]bool),
-		devices:                   make(map[byte]*Device),
-	}, nil
+// adapterName название адаптера для интерфейса
+func adapterName(id string) string {
+	if id == "" {
+		return "По умолчанию"
+	}
+	return id
 }
 
-// ScanForHubs сканирует WeDo 2.0 хабы
+// ScanForHubs сканирует WeDo 2.0 хабы. Поиск идет все отведенное время,
+// чтобы найти все хабы поблизости и накопить историю уровня сигнала
 func (hm *HubManager) ScanForHubs(timeout time.Duration) ([]HubInfo, error) {
-	var foundHubs []HubInfo
+	var foundHubs []*HubInfo
 	var scanMutex sync.Mutex
 
 	log.Println("=== Начало сканирования WeDo 2.0 хабов ===")
@@ -74,6 +178,17 @@ func (hm *HubManager) ScanForHubs(timeout time.Duration) ([]HubInfo, error) {
 		address := result.Address.String()
 		rssi := result.RSSI
 
+		scanMutex.Lock()
+		defer scanMutex.Unlock()
+
+		// Повторные объявления уже найденного хаба дополняют его сведения
+		for _, hub := range foundHubs {
+			if hub.Address == address {
+				recordAdvertisement(hub, result)
+				return
+			}
+		}
+
 		// Ищем WeDo 2.0 хаб
 		if (strings.Contains(strings.ToUpper(name), "WEDO") ||
 			strings.Contains(strings.ToUpper(name), "LEGO") ||
@@ -82,16 +197,9 @@ func (hm *HubManager) ScanForHubs(timeout time.Duration) ([]HubInfo, error) {
 
 			log.Printf("!!! Найден WeDo 2.0 хаб: %s [%s] RSSI: %d", name, address, rssi)
 
-			scanMutex.Lock()
-			foundHubs = append(foundHubs, HubInfo{
-				Name:    name,
-				Address: address,
-				RSSI:    int(rssi),
-			})
-			scanMutex.Unlock()
-
-			adapter.StopScan()
-			cancel()
+			hub := &HubInfo{Address: address}
+			recordAdvertisement(hub, result)
+			foundHubs = append(foundHubs, hub)
 		}
 	})
 
@@ -102,8 +210,16 @@ func (hm *HubManager) ScanForHubs(timeout time.Duration) ([]HubInfo, error) {
 	<-ctx.Done()
 	hm.adapter.StopScan()
 
-	log.Printf("Сканирование завершено. Найдено хабов: %d", len(foundHubs))
-	return foundHubs, nil
+	scanMutex.Lock()
+	defer scanMutex.Unlock()
+	hubs := make([]HubInfo, len(foundHubs))
+	for i, hub := range foundHubs {
+		hubs[i] = *hub
+	}
+	sort.Slice(hubs, func(i, j int) bool { return hubs[i].RSSI > hubs[j].RSSI })
+
+	log.Printf("Сканирование завершено. Найдено хабов: %d", len(hubs))
+	return hubs, nil
 }
 
 // Connect подключается к хабу
@@ -111,39 +227,30 @@ func (hm *HubManager) Connect(address string) error {
 	hm.connectionMutex.Lock()
 	defer hm.connectionMutex.Unlock()
 
-	if hm.isConnected {
-		hm.Disconnect()
-	}
-
-	log.Printf("Подключение к хабу: %s", address)
-
-	var targetDevice tinybluetooth.ScanResult
-	found := false
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Пользователь выбрал хаб сам: переподключение к прежнему больше не нужно
+	hm.cancelReconnect()
+	return hm.connectLocked(address)
+}
 
-	log.Println("Поиск устройства для подключения...")
+// connectLocked подключается к хабу; вызывается под connectionMutex
+func (hm *HubManager) connectLocked(address string) (err error) {
+	defer func() {
+		hm.metrics.RecordConnect(err)
+	}()
 
-	err := hm.adapter.Scan(func(adapter *tinybluetooth.Adapter, result tinybluetooth.ScanResult) {
-		if result.Address.String() == address {
-			log.Printf("Найдено устройство: %s", result.LocalName())
-			adapter.StopScan()
-			targetDevice = result
-			found = true
-			cancel()
-		}
-	})
+	if hm.isConnected {
+		hm.disconnectLocked()
+	}
 
-	if err != nil {
-		return fmt.Errorf("ошибка сканирования: %v", err)
+	if !hm.HubAllowed(address) {
+		return fmt.Errorf("подключение к хабу %s запрещено на этом компьютере", address)
 	}
 
-	<-ctx.Done()
-	hm.adapter.StopScan()
+	log.Printf("Подключение к хабу: %s", address)
 
-	if !found {
-		return fmt.Errorf("устройство с адресом %s не найдено", address)
+	targetDevice, err := hm.findDevice(address)
+	if err != nil {
+		return err
 	}
 
 	log.Printf("Устанавливаем соединение с %s...", address)
@@ -159,6 +266,7 @@ func (hm *HubManager) Connect(address string) error {
 	hm.hubInfo.Name = targetDevice.LocalName()
 	hm.hubInfo.Address = address
 	hm.hubInfo.LastUpdated = time.Now()
+	hm.usage.SetHub(address, hm.hubInfo.Name)
 
 	log.Println("Обнаружение служб и характеристик...")
 	err = hm.discoverAllServices()
@@ -169,7 +277,8 @@ func (hm *HubManager) Connect(address string) error {
 	log.Println("Чтение информации об устройстве...")
 	go hm.readAllDeviceInfo()
 
-	go hm.subscribeToImportantNotifications()
+	go hm.subscriptions.Attach(hm.characteristics)
+	hm.startHeartbeat()
 
 	if hm.connectionStateCallback != nil {
 		hm.connectionStateCallback(true)
@@ -178,6 +287,39 @@ func (hm *HubManager) Connect(address string) error {
 	return nil
 }
 
+// findDevice ищет устройство с адресом среди рекламных пакетов
+func (hm *HubManager) findDevice(address string) (tinybluetooth.ScanResult, error) {
+	var targetDevice tinybluetooth.ScanResult
+	found := false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.Println("Поиск устройства для подключения...")
+
+	err := hm.adapter.Scan(func(adapter *tinybluetooth.Adapter, result tinybluetooth.ScanResult) {
+		if result.Address.String() == address {
+			log.Printf("Найдено устройство: %s", result.LocalName())
+			adapter.StopScan()
+			targetDevice = result
+			found = true
+			cancel()
+		}
+	})
+
+	if err != nil {
+		return targetDevice, fmt.Errorf("ошибка сканирования: %v", err)
+	}
+
+	<-ctx.Done()
+	hm.adapter.StopScan()
+
+	if !found {
+		return targetDevice, fmt.Errorf("устройство с адресом %s не найдено", address)
+	}
+	return targetDevice, nil
+}
+
 // discoverAllServices обнаруживает все службы и характеристики
 func (hm *HubManager) discoverAllServices() error {
 	services, err := hm.device.DiscoverServices(nil)
@@ -279,6 +421,7 @@ func (hm *HubManager) updateHubInfo(uuid string, value string) {
 		hm.hubInfo.SoftwareVersion = value
 	case "00002a23-0000-1000-8000-00805f9b34fb":
 		hm.hubInfo.SystemID = value
+		hm.usage.SetHub(value, hm.hubInfo.Name)
 	}
 
 	if hm.hubInfoUpdateCallback != nil {
@@ -308,57 +451,126 @@ func (hm *HubManager) readBatteryLevel() {
 	}
 }
 
-// subscribeToImportantNotifications подписывается на важные уведомления
-func (hm *HubManager) subscribeToImportantNotifications() {
-	hm.subscribeToBatteryNotifications()
-	hm.subscribeToPortNotifications()
+// registerSubscriptions регистрирует обработчики уведомлений хаба.
+// Менеджер подписок включает их при каждом подключении
+func (hm *HubManager) registerSubscriptions() {
+	hm.subscriptions.Subscribe(BATTERY_LEVEL_UUID, "обновления батареи", hm.traced(BATTERY_LEVEL_UUID, hm.handleBatteryNotification))
+	hm.subscriptions.Subscribe(PORT_INFO_UUID, "информацию о портах", hm.traced(PORT_INFO_UUID, func(data []byte) {
+		hm.markActivity()
+		hm.metrics.RecordNotification(NotificationPorts)
+		hm.handlePortNotification(data)
+	}))
+	hm.subscriptions.Subscribe(SENSOR_VALUES_UUID, "значения датчиков", hm.traced(SENSOR_VALUES_UUID, func(data []byte) {
+		hm.markActivity()
+		hm.metrics.RecordNotification(NotificationSensors)
+		hm.handleSensorNotification(data)
+	}))
 }
 
-// subscribeToBatteryNotifications подписывается на уведомления батареи
-func (hm *HubManager) subscribeToBatteryNotifications() {
-	batteryUUID := "00002a19-0000-1000-8000-00805f9b34fb"
+// traced добавляет запись входящих кадров в журнал BLE перед обработчиком и
+// перехватывает панику обработчика
+func (hm *HubManager) traced(uuid string, handler func(data []byte)) func(data []byte) {
+	return func(data []byte) {
+		if hm.crashHandler != nil {
+			defer hm.crashHandler()
+		}
+		hm.trace.Record(FrameIn, uuid, data)
+		handler(data)
+	}
+}
 
-	if char, exists := hm.characteristics[batteryUUID]; exists {
-		err := char.EnableNotifications(func(data []byte) {
-			if len(data) > 0 {
-				batteryLevel := int(data[0])
-				hm.hubInfo.Battery = batteryLevel
+// handleBatteryNotification обновляет уровень заряда батареи
+func (hm *HubManager) handleBatteryNotification(data []byte) {
+	hm.markActivity()
+	hm.metrics.RecordNotification(NotificationBattery)
+	if len(data) > 0 {
+		batteryLevel := int(data[0])
+		hm.hubInfo.Battery = batteryLevel
 
-				if hm.batteryUpdateCallback != nil {
-					hm.batteryUpdateCallback(batteryLevel)
-				}
-			}
-		})
+		if hm.batteryUpdateCallback != nil {
+			hm.batteryUpdateCallback(batteryLevel)
+		}
+	}
+}
 
-		if err != nil {
-			log.Printf("Ошибка подписки на батарею: %v", err)
-		} else {
-			log.Println("Подписка на обновления батареи установлена")
-			hm.subscribedCharacteristics[batteryUUID] = true
+// handleSensorNotification сохраняет значения датчиков и публикует их в шину событий.
+// Пока воспроизводится запись датчиков, живые значения не учитываются
+func (hm *HubManager) handleSensorNotification(data []byte) {
+	if hm.Replaying() {
+		return
+	}
+	for _, reading := range ParseSensorValues(data) {
+		if reading.HasY {
+			hm.setTiltAngles(reading.PortID, tiltAngles{X: reading.Value, Y: reading.Y})
 		}
+		hm.applySensorReading(reading.PortID, hm.distanceReading(reading.PortID, reading.Value))
 	}
 }
 
-// subscribeToPortNotifications подписывается на уведомления о портах
-func (hm *HubManager) subscribeToPortNotifications() {
-	portInfoUUID := PORT_INFO_UUID
+// applySensorReading сохраняет значение датчика, публикует его и обновляет устройство
+func (hm *HubManager) applySensorReading(portID byte, value float64) {
+	hm.sensorMu.Lock()
+	hm.sensorValues[portID] = value
+	hm.sensorMu.Unlock()
 
-	if char, exists := hm.characteristics[portInfoUUID]; exists {
-		err := char.EnableNotifications(func(data []byte) {
-			hm.handlePortNotification(data)
-		})
+	hm.events.Publish(Event{
+		Type:   EventSensorValue,
+		PortID: portID,
+		Value:  value,
+	})
 
-		if err != nil {
-			log.Printf("Ошибка подписки на информацию о портах: %v", err)
-		} else {
-			log.Println("Подписка на информацию о портах установлена")
-			hm.subscribedCharacteristics[portInfoUUID] = true
-		}
-	} else {
-		log.Printf("Характеристика информации о портах не найдена")
+	hm.devices.UpdateDeviceValue(portID, value)
+
+	if hm.isDistancePort(portID) {
+		hm.objects.Update(portID, value)
+	} else if hm.isTiltPortInMode(portID, TILT_CRASH_MODE) {
+		hm.bumps.Update(portID, value, time.Now())
+	} else if hm.isTiltPortInMode(portID, TILT_TILT_MODE) {
+		hm.setTiltAngles(portID, tiltDirectionAngles(value))
+	} else if device, ok := hm.devices.GetDevice(portID); ok && device.DeviceType == DEVICE_TYPE_VOLTAGE {
+		hm.voltage.Update(value, time.Now())
 	}
 }
 
+// GetSensorValue возвращает последнее значение датчика на порту
+func (hm *HubManager) GetSensorValue(portID byte) (float64, bool) {
+	hm.sensorMu.RLock()
+	defer hm.sensorMu.RUnlock()
+
+	value, exists := hm.sensorValues[portID]
+	return value, exists
+}
+
+// Metrics возвращает статистику сеанса
+func (hm *HubManager) Metrics() *SessionMetrics {
+	return hm.metrics
+}
+
+// Devices возвращает менеджер устройств, единственное хранилище состояния устройств хаба
+func (hm *HubManager) Devices() *DeviceManager {
+	return hm.devices
+}
+
+// PendingWrites возвращает число команд, ожидающих отправки хабу
+func (hm *HubManager) PendingWrites() int {
+	return int(hm.pendingWrites.Load())
+}
+
+// Subscriptions возвращает менеджер подписок на уведомления хаба
+func (hm *HubManager) Subscriptions() *SubscriptionManager {
+	return hm.subscriptions
+}
+
+// Trace возвращает журнал последних кадров обмена с хабом
+func (hm *HubManager) Trace() *BLETrace {
+	return hm.trace
+}
+
+// Events возвращает шину событий хаба
+func (hm *HubManager) Events() *EventBus {
+	return hm.events
+}
+
 // handlePortNotification обрабатывает уведомления о портах
 func (hm *HubManager) handlePortNotification(data []byte) {
 	if len(data) < 2 {
@@ -429,7 +641,8 @@ func (hm *HubManager) handleDeviceConnection(portID byte, deviceType byte, _ []b
 		Properties:  make(map[string]interface{}),
 	}
 
-	hm.devices[portID] = device
+	hm.devices.AddOrUpdateDevice(device)
+	hm.portModes.Forget(portID)
 
 	go func() {
 		time.Sleep(1 * time.Second)
@@ -441,10 +654,6 @@ func (hm *HubManager) handleDeviceConnection(portID byte, deviceType byte, _ []b
 		} else {
 			log.Printf("Устройство на порту %d успешно настроено", portID)
 		}
-
-		if hm.deviceUpdateCallback != nil {
-			hm.deviceUpdateCallback(portID, device)
-		}
 	}()
 
 	log.Printf("Устройство обнаружено: %s (порт %d)", device.Name, portID)
@@ -453,56 +662,38 @@ func (hm *HubManager) handleDeviceConnection(portID byte, deviceType byte, _ []b
 // handleDeviceDisconnection обрабатывает отключение устройства
 func (hm *HubManager) handleDeviceDisconnection(portID byte) {
 	log.Printf("Устройство отключено от порта %d", portID)
+	hm.portModes.Forget(portID)
 
-	if device, exists := hm.devices[portID]; exists {
-		device.IsConnected = false
-		device.LastUpdate = time.Now()
-		log.Printf("Устройство отключено: %s (порт %d)", device.Name, portID)
+	hm.sensorMu.Lock()
+	delete(hm.sensorValues, portID)
+	delete(hm.rawDistances, portID)
+	delete(hm.tiltAngles, portID)
+	hm.sensorMu.Unlock()
+	hm.objects.Forget(portID)
+	hm.bumps.Forget(portID)
 
-		if hm.deviceUpdateCallback != nil {
-			hm.deviceUpdateCallback(portID, device)
-		}
-	} else {
-		device := &Device{
-			PortID:      portID,
-			IsConnected: false,
-			LastUpdate:  time.Now(),
-			Properties:  make(map[string]interface{}),
-		}
-
-		if hm.deviceUpdateCallback != nil {
-			hm.deviceUpdateCallback(portID, device)
-		}
-	}
+	hm.devices.MarkDisconnected(portID)
 }
 
 // configureDevice настраивает устройство
 func (hm *HubManager) configureDevice(portID byte, deviceType byte) error {
 	log.Printf("Настройка устройства на порту %d (тип: 0x%02x)", portID, deviceType)
 
-	var cmd []byte
+	cmd := NewInputFormatCommand(portID, deviceType)
 
 	switch deviceType {
-	case DEVICE_TYPE_MOTOR:
-		cmd = []byte{0x01, 0x02, portID, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
 	case DEVICE_TYPE_TILT_SENSOR:
-		cmd = []byte{0x01, 0x02, portID, 0x22, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-	case DEVICE_TYPE_MOTION_SENSOR:
-		cmd = []byte{0x01, 0x02, portID, 0x23, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
+		cmd.Mode(TILT_TILT_MODE)
 	case DEVICE_TYPE_RGB_LIGHT:
-		cmd = []byte{0x01, 0x02, portID, 0x17, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-	case DEVICE_TYPE_PIEZO_TONE:
-		cmd = []byte{0x01, 0x02, portID, 0x16, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-	case DEVICE_TYPE_VOLTAGE:
-		cmd = []byte{0x01, 0x02, portID, 0x14, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-	case DEVICE_TYPE_CURRENT:
-		cmd = []byte{0x01, 0x02, portID, 0x15, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
+		cmd.Mode(LED_DISCRETE_MODE)
+	case DEVICE_TYPE_MOTOR, DEVICE_TYPE_MOTION_SENSOR, DEVICE_TYPE_PIEZO_TONE,
+		DEVICE_TYPE_VOLTAGE, DEVICE_TYPE_CURRENT:
 	default:
 		log.Printf("Неизвестный тип устройства 0x%02x, пропускаем настройку", deviceType)
 		return nil
 	}
 
-	if err := hm.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", cmd); err != nil {
+	if err := hm.ConfigurePort(cmd); err != nil {
 		return fmt.Errorf("ошибка настройки устройства: %v", err)
 	}
 
@@ -534,33 +725,42 @@ func (hm *HubManager) getDeviceName(deviceType byte) string {
 
 // WriteCharacteristic записывает данные в характеристику
 func (hm *HubManager) WriteCharacteristic(uuid string, data []byte) error {
+	hm.pendingWrites.Add(1)
+	defer hm.pendingWrites.Add(-1)
+
 	hm.connectionMutex.RLock()
 
 	if !hm.isConnected {
 		hm.connectionMutex.RUnlock()
+		hm.metrics.RecordWrite(0, fmt.Errorf("не подключено"))
 		return fmt.Errorf("не подключено к хабу")
 	}
 
 	char, exists := hm.characteristics[uuid]
 	if !exists {
 		hm.connectionMutex.RUnlock()
+		hm.metrics.RecordWrite(0, fmt.Errorf("нет характеристики"))
 		return fmt.Errorf("характеристика %s не найдена", uuid)
 	}
 
 	if !hm.isConnected {
 		hm.connectionMutex.RUnlock()
+		hm.metrics.RecordWrite(0, fmt.Errorf("потеряно подключение"))
 		return fmt.Errorf("потеряно подключение к хабу")
 	}
 
+	hm.trace.Record(FrameOut, uuid, data)
+	started := time.Now()
 	_, err := char.WriteWithoutResponse(data)
 	hm.connectionMutex.RUnlock()
+	hm.metrics.RecordWrite(time.Since(started), err)
 
 	if err != nil {
 		log.Printf("Ошибка отправки данных: %v", err)
 		return fmt.Errorf("ошибка отправки данных: %v", err)
 	}
 
-	log.Printf("Данные отправлены: %v (HEX: %x)", data, data)
+	hm.protocolLog.Write(uuid, data)
 	return nil
 }
 
@@ -592,11 +792,33 @@ func (hm *HubManager) Disconnect() {
 	hm.connectionMutex.Lock()
 	defer hm.connectionMutex.Unlock()
 
+	hm.cancelReconnect()
+	hm.disconnectLocked()
+}
+
+// disconnectLocked отключается от хаба; вызывается под connectionMutex
+func (hm *HubManager) disconnectLocked() {
 	if hm.isConnected {
 		log.Println("Отключение от хаба...")
+		hm.stopHeartbeat()
+		hm.subscriptions.Teardown()
 		hm.device.Disconnect()
 		hm.isConnected = false
+		hm.metrics.RecordDisconnect()
+		hm.usage.StopAll()
+		hm.arbiter.Reset()
+		hm.portModes.Reset()
 		hm.hubInfo = &HubInfo{}
+		hm.devices.Reset()
+
+		hm.sensorMu.Lock()
+		hm.sensorValues = make(map[byte]float64)
+		hm.rawDistances = make(map[byte]float64)
+		hm.tiltAngles = make(map[byte]tiltAngles)
+		hm.sensorMu.Unlock()
+		hm.objects.Reset()
+		hm.bumps.Reset()
+		hm.voltage.Reset()
 
 		if hm.connectionStateCallback != nil {
 			hm.connectionStateCallback(false)
@@ -630,14 +852,15 @@ func (hm *HubManager) SetHubInfoUpdateCallback(callback func(info *HubInfo)) {
 	hm.hubInfoUpdateCallback = callback
 }
 
-func (hm *HubManager) SetDeviceUpdateCallback(callback func(portID byte, device *Device)) {
-	hm.deviceUpdateCallback = callback
-}
-
 func (hm *HubManager) SetConnectionStateCallback(callback func(isConnected bool)) {
 	hm.connectionStateCallback = callback
 }
 
+// SetCrashHandler устанавливает обработчик паники в проверке связи и обработчиках уведомлений BLE
+func (hm *HubManager) SetCrashHandler(handler func()) {
+	hm.crashHandler = handler
+}
+
 // autoDetectDevicesV2 - улучшенная функция обнаружения устройств
 func (hm *HubManager) autoDetectDevicesV2() {
 	log.Println("=== Автоматическое обнаружение устройств ===")
@@ -652,7 +875,7 @@ func (hm *HubManager) autoDetectDevicesV2() {
 
 	log.Println("Проверка обнаруженных устройств:")
 	for port := byte(1); port <= 6; port++ {
-		if device, exists := hm.devices[port]; exists && device.IsConnected {
+		if device, exists := hm.devices.GetDevice(port); exists && device.IsConnected {
 			log.Printf("  Порт %d: %s", port, device.Name)
 		}
 	}
@@ -660,7 +883,7 @@ func (hm *HubManager) autoDetectDevicesV2() {
 	portsToCheck := []byte{1, 2, 6}
 
 	for _, portID := range portsToCheck {
-		if _, exists := hm.devices[portID]; !exists {
+		if _, exists := hm.devices.GetDevice(portID); !exists {
 			log.Printf("Порт %d не обнаружен автоматически, запускаем ручное обнаружение...", portID)
 			hm.manualDeviceDetection(portID)
 			time.Sleep(3 * time.Second)
@@ -679,20 +902,23 @@ func (hm *HubManager) manualDeviceDetection(portID byte) {
 		return
 	}
 
+	// Опрос заново настраивает порт, прежний режим больше не действителен
+	hm.portModes.Forget(portID)
+
 	deviceTypes := []struct {
 		name       string
 		deviceType byte
-		setupCmd   []byte
+		mode       byte
 	}{
-		{"Мотор", DEVICE_TYPE_MOTOR, []byte{0x01, 0x02, portID, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}},
-		{"Датчик наклона", DEVICE_TYPE_TILT_SENSOR, []byte{0x01, 0x02, portID, 0x22, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}},
-		{"Датчик расстояния", DEVICE_TYPE_MOTION_SENSOR, []byte{0x01, 0x02, portID, 0x23, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}},
+		{"Мотор", DEVICE_TYPE_MOTOR, 0},
+		{"Датчик наклона", DEVICE_TYPE_TILT_SENSOR, TILT_TILT_MODE},
+		{"Датчик расстояния", DEVICE_TYPE_MOTION_SENSOR, DIST_DETECT_MODE},
 	}
 
 	for _, dev := range deviceTypes {
 		log.Printf("Порт %d: проверка %s...", portID, dev.name)
 
-		err := hm.WriteCharacteristic(INPUT_COMMAND_UUID, dev.setupCmd)
+		err := hm.ConfigurePort(NewInputFormatCommand(portID, dev.deviceType).Mode(dev.mode))
 		if err != nil {
 			log.Printf("Порт %d: ошибка настройки %s - %v", portID, dev.name, err)
 			continue
@@ -701,16 +927,14 @@ func (hm *HubManager) manualDeviceDetection(portID byte) {
 		time.Sleep(2 * time.Second)
 
 		if dev.deviceType == DEVICE_TYPE_MOTOR {
-			runCmd := []byte{portID, 0x01, 0x01, 0x05}
-			err = hm.WriteCharacteristic(OUTPUT_COMMAND_UUID, runCmd)
+			err = hm.SendCommand(NewMotorCommand(portID).Power(5).From(SourceSystem))
 			if err != nil {
 				log.Printf("Порт %d: не удалось запустить мотор - %v", portID, err)
 				continue
 			}
 
 			time.Sleep(300 * time.Millisecond)
-			stopCmd := []byte{portID, 0x01, 0x01, 0x00}
-			hm.WriteCharacteristic(OUTPUT_COMMAND_UUID, stopCmd)
+			hm.SendCommand(NewMotorCommand(portID).Stop().From(SourceSystem))
 		}
 
 		device := &Device{
@@ -722,11 +946,7 @@ func (hm *HubManager) manualDeviceDetection(portID byte) {
 			Properties:  make(map[string]interface{}),
 		}
 
-		hm.devices[portID] = device
-
-		if hm.deviceUpdateCallback != nil {
-			hm.deviceUpdateCallback(portID, device)
-		}
+		hm.devices.AddOrUpdateDevice(device)
 
 		log.Printf("Порт %d: обнаружен %s", portID, dev.name)
 		return
@@ -739,18 +959,16 @@ func (hm *HubManager) manualDeviceDetection(portID byte) {
 func (hm *HubManager) detectBuiltInLED() {
 	log.Println("Обнаружение встроенного RGB светодиода на порту 6...")
 
-	setupCmd := []byte{0x01, 0x02, 6, 0x17, 0x01, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-	err := hm.WriteCharacteristic(INPUT_COMMAND_UUID, setupCmd)
+	setupCmd := NewInputFormatCommand(6, DEVICE_TYPE_RGB_LIGHT).Mode(LED_DISCRETE_MODE)
+	err := hm.ConfigurePort(setupCmd)
 	if err != nil {
 		log.Printf("Порт 6: ошибка настройки RGB режима - %v", err)
-		setupCmd = []byte{0x01, 0x02, 6, 0x17, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-		hm.WriteCharacteristic(INPUT_COMMAND_UUID, setupCmd)
+		hm.ConfigurePort(setupCmd.Mode(LED_ABSOLUTE_MODE))
 	}
 
 	time.Sleep(1 * time.Second)
 
-	colorCmd := []byte{0x06, 0x04, 0x03, 0x00, 0xFF, 0x00}
-	err = hm.WriteCharacteristic(OUTPUT_COMMAND_UUID, colorCmd)
+	err = hm.SendCommand(NewLEDCommand(6).RGB(0x00, 0xFF, 0x00).From(SourceSystem))
 	if err != nil {
 		log.Printf("Порт 6: ошибка установки цвета - %v", err)
 		return
@@ -765,12 +983,8 @@ func (hm *HubManager) detectBuiltInLED() {
 		Properties:  make(map[string]interface{}),
 	}
 
-	hm.devices[6] = device
+	hm.devices.AddOrUpdateDevice(device)
 	log.Println("Порт 6: RGB светодиод обнаружен (зеленый)")
-
-	if hm.deviceUpdateCallback != nil {
-		hm.deviceUpdateCallback(6, device)
-	}
 }
 
 // mapDeviceType преобразует WeDo 2.0 тип устройства в наш формат
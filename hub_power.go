@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Действия питания хаба
+const (
+	HubPowerOff   = "off"   // Выключить хаб
+	HubPowerSleep = "sleep" // Погасить выходы и отпустить хаб
+)
+
+// hubPowerActions действия питания с названиями для интерфейса
+var hubPowerActions = []struct {
+	action string
+	name   string
+}{
+	{HubPowerOff, "Выключить хаб"},
+	{HubPowerSleep, "Спящий режим"},
+}
+
+// hubPowerFromParameters возвращает действие блока «Питание хаба»
+func hubPowerFromParameters(params map[string]interface{}) string {
+	if action, ok := params["action"].(string); ok && action == HubPowerSleep {
+		return HubPowerSleep
+	}
+	return HubPowerOff
+}
+
+// PowerAction выключает хаб или переводит его в спящий режим и отключается от него.
+// Выключение отправляет действие хаба, после которого хаб гаснет сам.
+// В спящем режиме моторы и звук останавливаются, светодиод гаснет, а соединение
+// разрывается: хаб ничего не питает и ждет нового подключения
+func (hm *HubManager) PowerAction(action string) error {
+	if !hm.IsConnected() {
+		return fmt.Errorf("не подключено к хабу")
+	}
+
+	switch action {
+	case HubPowerOff:
+		log.Println("Выключение хаба...")
+		if err := hm.SendCommand(NewHubActionCommand(hubActionSwitchOff)); err != nil {
+			return fmt.Errorf("не удалось выключить хаб: %v", err)
+		}
+
+	case HubPowerSleep:
+		log.Println("Перевод хаба в спящий режим...")
+		for _, device := range hm.devices.GetConnectedDevices() {
+			switch device.DeviceType {
+			case DEVICE_TYPE_MOTOR:
+				hm.SendCommand(NewMotorCommand(device.PortID).Stop().From(SourceSystem))
+			case DEVICE_TYPE_PIEZO_TONE:
+				hm.SendCommand(NewStopToneCommand(device.PortID).From(SourceSystem))
+			}
+		}
+		if err := hm.SendCommand(NewLEDCommand(hubLEDPort).RGB(0, 0, 0).From(SourceSystem)); err != nil {
+			return fmt.Errorf("не удалось погасить светодиод хаба: %v", err)
+		}
+
+	default:
+		return fmt.Errorf("неизвестное действие питания %q", action)
+	}
+
+	hm.Disconnect()
+	return nil
+}
+
+// showHubPowerDialog предлагает выключить хаб или перевести его в спящий режим,
+// чтобы после урока хабы не разряжали батареи
+func (gui *MainGUI) showHubPowerDialog() {
+	if !gui.hubMgr.IsConnected() {
+		dialog.ShowInformation("Хаб не подключен", "Подключитесь к хабу, чтобы управлять его питанием", gui.window)
+		return
+	}
+
+	hint := widget.NewLabel("Выключенный хаб включается кнопкой на корпусе. " +
+		"В спящем режиме моторы останавливаются, светодиод гаснет и приложение отключается от хаба.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	var d *dialog.CustomDialog
+	buttons := container.NewGridWithColumns(len(hubPowerActions))
+	for _, item := range hubPowerActions {
+		action := item.action
+		buttons.Add(widget.NewButton(item.name, func() {
+			d.Hide()
+			gui.runHubPowerAction(action)
+		}))
+	}
+
+	d = dialog.NewCustom("Питание хаба", "Отмена", container.NewVBox(hint, buttons), gui.window)
+	d.Resize(fyne.NewSize(420, 200))
+	d.Show()
+}
+
+// runHubPowerAction останавливает программу и выполняет действие питания
+func (gui *MainGUI) runHubPowerAction(action string) {
+	gui.programMgr.StopProgram()
+	go func() {
+		if err := gui.hubMgr.PowerAction(action); err != nil {
+			fyne.Do(func() { dialog.ShowError(err, gui.window) })
+		}
+	}()
+}
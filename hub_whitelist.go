@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// settingAllowedHubs адреса хабов, к которым разрешено подключаться с этого компьютера, по одному в строке
+const settingAllowedHubs = "allowed_hubs"
+
+// parseHubWhitelist разбирает список адресов хабов; пустой список разрешает любой хаб
+func parseHubWhitelist(text string) ([]string, error) {
+	var addresses []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		mac, err := net.ParseMAC(line)
+		if err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("«%s» не похож на адрес хаба (ожидается вида 24:71:89:AA:BB:CC)", line)
+		}
+		addresses = append(addresses, strings.ToUpper(mac.String()))
+	}
+	return addresses, nil
+}
+
+// SetAllowedHubs задает адреса хабов, к которым разрешено подключаться; пустой список снимает ограничение
+func (hm *HubManager) SetAllowedHubs(addresses []string) {
+	hm.allowedMu.Lock()
+	defer hm.allowedMu.Unlock()
+	hm.allowedHubs = addresses
+}
+
+// HubAllowed проверяет, разрешено ли подключение к хабу с адресом
+func (hm *HubManager) HubAllowed(address string) bool {
+	hm.allowedMu.RLock()
+	defer hm.allowedMu.RUnlock()
+	if len(hm.allowedHubs) == 0 {
+		return true
+	}
+	for _, allowed := range hm.allowedHubs {
+		if strings.EqualFold(allowed, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// teacherSettings защищенный PIN-кодом учителя раздел: список разрешенных хабов
+func (gui *MainGUI) teacherSettings(prefs fyne.Preferences) settingsSection {
+	whitelistEntry := widget.NewMultiLineEntry()
+	whitelistEntry.SetText(prefs.String(settingAllowedHubs))
+	whitelistEntry.SetPlaceHolder("24:71:89:AA:BB:CC")
+	whitelistEntry.SetMinRowsVisible(4)
+	whitelistEntry.Validator = func(text string) error {
+		_, err := parseHubWhitelist(text)
+		return err
+	}
+	whitelistItem := widget.NewFormItem("Разрешенные хабы", whitelistEntry)
+	whitelistItem.HintText = "По одному адресу в строке; пустой список разрешает любой хаб"
+
+	// Без PIN-кода учителя раздел открыт, с ним — доступен только после ввода PIN-кода
+	var protection fyne.CanvasObject = widget.NewLabel("Задайте PIN-код учителя (блокировка программы), чтобы защитить раздел")
+	if storedHash := prefs.String(settingTeacherPIN); storedHash != "" && gui.isTeacher() {
+		protection = widget.NewLabel("Раздел открыт в профиле учителя")
+	} else if storedHash != "" {
+		whitelistEntry.Disable()
+		pinEntry := widget.NewPasswordEntry()
+		var unlockButton *widget.Button
+		unlockButton = widget.NewButton("Разблокировать", func() {
+			if err := gui.checkTeacherPIN(pinEntry.Text); err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			pinEntry.Disable()
+			unlockButton.Disable()
+			whitelistEntry.Enable()
+		})
+		pinEntry.OnSubmitted = func(string) { unlockButton.OnTapped() }
+		protection = container.NewBorder(nil, nil, nil, unlockButton, pinEntry)
+	}
+
+	return settingsSection{
+		title: "Учитель",
+		items: []*widget.FormItem{
+			widget.NewFormItem("PIN-код", protection),
+			whitelistItem,
+		},
+		save: func(prefs fyne.Preferences) {
+			if whitelistEntry.Disabled() {
+				return
+			}
+			if addresses, err := parseHubWhitelist(whitelistEntry.Text); err == nil {
+				prefs.SetString(settingAllowedHubs, strings.Join(addresses, "\n"))
+			}
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"embed"
+	"log"
+	"path"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// iconFiles встроенный набор SVG-иконок блоков и устройств
+//
+//go:embed icons/*.svg
+var iconFiles embed.FS
+
+// iconRegistry загруженные иконки по имени файла без расширения
+var (
+	iconRegistry   = make(map[string]fyne.Resource)
+	iconRegistryMu sync.Mutex
+)
+
+// iconResource возвращает иконку по имени. Иконка перекрашивается
+// в основной цвет текущей темы, поэтому подходит и для темной, и для светлой темы.
+func iconResource(name string) fyne.Resource {
+	iconRegistryMu.Lock()
+	defer iconRegistryMu.Unlock()
+
+	if res, ok := iconRegistry[name]; ok {
+		return res
+	}
+
+	data, err := iconFiles.ReadFile(path.Join("icons", name+".svg"))
+	if err != nil {
+		log.Printf("Иконка %s не найдена: %v", name, err)
+		return theme.QuestionIcon()
+	}
+
+	res := theme.NewThemedResource(fyne.NewStaticResource(name+".svg", data))
+	iconRegistry[name] = res
+	return res
+}
+
+// blockIconOnColor возвращает иконку для изображения поверх цветного фона блока
+func blockIconOnColor(blockType BlockType) fyne.Resource {
+	return theme.NewColoredResource(iconResource(blockIconName(blockType)), theme.ColorNameForegroundOnPrimary)
+}
+
+// blockIconName возвращает имя иконки для типа блока
+func blockIconName(blockType BlockType) string {
+	switch blockType {
+	case BlockTypeStart:
+		return "start"
+	case BlockTypeMotor:
+		return "motor"
+	case BlockTypeLED:
+		return "led"
+	case BlockTypeWait:
+		return "wait"
+	case BlockTypeLoop:
+		return "loop"
+	case BlockTypeCondition:
+		return "condition"
+	case BlockTypeTiltSensor:
+		return "tilt"
+	case BlockTypeDistanceSensor:
+		return "distance"
+	case BlockTypeSound:
+		return "sound"
+	case BlockTypeVoltageSensor:
+		return "voltage"
+	case BlockTypeCurrentSensor:
+		return "current"
+	case BlockTypeStop:
+		return "stop"
+	case BlockTypeWaitUntil:
+		return "wait_until"
+	case BlockTypeResetTimer:
+		return "timer"
+	case BlockTypeBroadcast:
+		return "broadcast"
+	case BlockTypeReceive:
+		return "receive"
+	case BlockTypeScreen:
+		return "screen"
+	case BlockTypeWhenMotion, BlockTypeWhenColor:
+		return "camera"
+	case BlockTypeWhenHear:
+		return "speech"
+	case BlockTypeWhenBumped:
+		return "bump"
+	case BlockTypeFollow:
+		return "follow"
+	case BlockTypeHubPower:
+		return "power"
+	case BlockTypeLightMusic:
+		return "light_music"
+	default:
+		return "device"
+	}
+}
+
+// deviceIconName возвращает имя иконки для типа устройства
+func deviceIconName(deviceType byte) string {
+	switch deviceType {
+	case DEVICE_TYPE_MOTOR:
+		return "motor"
+	case DEVICE_TYPE_RGB_LIGHT:
+		return "led"
+	case DEVICE_TYPE_TILT_SENSOR:
+		return "tilt"
+	case DEVICE_TYPE_MOTION_SENSOR:
+		return "distance"
+	case DEVICE_TYPE_PIEZO_TONE:
+		return "sound"
+	case DEVICE_TYPE_VOLTAGE:
+		return "voltage"
+	case DEVICE_TYPE_CURRENT:
+		return "current"
+	default:
+		return "device"
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+// kioskFlag флаг режима экзамена с папкой программ или одним файлом программы
+const kioskFlag = "kiosk"
+
+// KioskMode режим экзамена: можно только открыть программу из заданного набора
+// и запустить ее. Диалоги файлов, настройки, диагностика и ручное управление
+// портами недоступны, программа заблокирована без возможности разблокировки
+type KioskMode struct {
+	Source   string     // Папка или файл из командной строки
+	Programs []fyne.URI // Программы, доступные для открытия
+}
+
+// loadKioskMode собирает набор программ из папки или одного файла
+func loadKioskMode(source string) (*KioskMode, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	kiosk := &KioskMode{Source: source}
+	if !info.IsDir() {
+		kiosk.Programs = append(kiosk.Programs, storage.NewFileURI(source))
+		return kiosk, nil
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), programFileExtension) {
+			continue
+		}
+		kiosk.Programs = append(kiosk.Programs, storage.NewFileURI(filepath.Join(source, entry.Name())))
+	}
+	if len(kiosk.Programs) == 0 {
+		return nil, fmt.Errorf("в папке %s нет программ %s", source, programFileExtension)
+	}
+	return kiosk, nil
+}
+
+// programName название программы набора без расширения
+func (k *KioskMode) programName(uri fyne.URI) string {
+	return strings.TrimSuffix(uri.Name(), uri.Extension())
+}
+
+// unlessKiosk возвращает действие или пустую функцию в режиме экзамена
+func (gui *MainGUI) unlessKiosk(action func()) func() {
+	if gui.kiosk != nil {
+		return func() {}
+	}
+	return action
+}
+
+// createKioskMenu создает главное меню режима экзамена: набор программ,
+// подключение к хабу, панели и справка
+func (gui *MainGUI) createKioskMenu() *fyne.MainMenu {
+	items := make([]*fyne.MenuItem, 0, len(gui.kiosk.Programs))
+	for _, uri := range gui.kiosk.Programs {
+		uri := uri
+		items = append(items, fyne.NewMenuItem(gui.kiosk.programName(uri), func() { gui.openKioskProgram(uri) }))
+	}
+
+	hubMenu := fyne.NewMenu("Хаб",
+		fyne.NewMenuItem("Найти хаб...", gui.showHubDiscoveryDialog),
+		gui.hubConnectItem,
+		gui.hubDisconnectItem,
+	)
+
+	helpItem := fyne.NewMenuItem("Справка", gui.toolbar.showHelp)
+	return fyne.NewMainMenu(
+		fyne.NewMenu("Программы", items...),
+		hubMenu,
+		fyne.NewMenu("Вид",
+			gui.devicePanelItem,
+			gui.propertiesPanelItem,
+			fyne.NewMenuItem("Наблюдение...", gui.showWatchPanel),
+		),
+		fyne.NewMenu("Справка", helpItem),
+	)
+}
+
+// openKioskProgram открывает программу из набора режима экзамена.
+// Блокировка не мешает: набор задан при запуске, а в недавние программы
+// файлы экзамена не попадают
+func (gui *MainGUI) openKioskProgram(uri fyne.URI) {
+	reader, err := storage.Reader(uri)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("не удалось открыть %s: %v", uri.Name(), err), gui.window)
+		return
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), gui.window)
+		return
+	}
+	program, err := gui.programMgr.DecodeProgram(data)
+	if err != nil {
+		dialog.ShowError(err, gui.window)
+		return
+	}
+
+	gui.programMgr.StopProgram()
+	gui.openProgram(program)
+	gui.currentFile = uri
+	gui.updateWindowTitle()
+	log.Printf("Режим экзамена: открыта программа %s", uri.Name())
+}
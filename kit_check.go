@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	settingOfferKitCheck  = "offer_kit_check"      // Предлагать проверку комплекта после подключения
+	kitCheckMotorPower    = 40                     // Мощность короткого включения мотора, %
+	kitCheckMotorTime     = 300 * time.Millisecond // Сколько мотор крутится при проверке
+	kitCheckLEDTime       = 400 * time.Millisecond // Длительность вспышки светодиода
+	kitCheckToneFrequency = 880                    // Частота проверочного сигнала, Гц
+	kitCheckToneTime      = 200                    // Длительность проверочного сигнала, мс
+	kitCheckSensorTimeout = 2 * time.Second        // Сколько ждать показания датчика
+	kitCheckSettleTime    = 150 * time.Millisecond // Пауза между устройствами
+)
+
+// KitCheckResult результат проверки устройства на порту
+type KitCheckResult struct {
+	PortID byte
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// CheckKit по очереди ненадолго включает каждое найденное устройство: мотор
+// дергается, светодиод вспыхивает, пищалка пищит, у датчиков запрашивается показание.
+// progress вызывается перед проверкой очередного устройства
+func (dm *DeviceManager) CheckKit(progress func(done, total int, device *Device)) []KitCheckResult {
+	devices := dm.GetConnectedDevices()
+	sort.Slice(devices, func(i, j int) bool { return devices[i].PortID < devices[j].PortID })
+
+	results := make([]KitCheckResult, 0, len(devices))
+	for i, device := range devices {
+		progress(i, len(devices), device)
+		result := dm.checkDevice(device)
+		log.Printf("Проверка комплекта, порт %d (%s): %v, %s", device.PortID, device.Name, result.Passed, result.Detail)
+		results = append(results, result)
+		time.Sleep(kitCheckSettleTime)
+	}
+	progress(len(devices), len(devices), nil)
+	return results
+}
+
+// checkDevice проверяет одно устройство и убеждается, что оно осталось на порту
+func (dm *DeviceManager) checkDevice(device *Device) KitCheckResult {
+	result := KitCheckResult{PortID: device.PortID, Name: DeviceTypeName(device.DeviceType)}
+	port := device.PortID
+
+	var err error
+	switch device.DeviceType {
+	case DEVICE_TYPE_MOTOR:
+		err = dm.hubMgr.SendCommand(NewMotorCommand(port).Power(kitCheckMotorPower).From(SourceSystem))
+		time.Sleep(kitCheckMotorTime)
+		if stopErr := dm.hubMgr.SendCommand(NewMotorCommand(port).Stop().From(SourceSystem)); err == nil {
+			err = stopErr
+		}
+		result.Detail = "команды приняты, мотор должен был дернуться"
+
+	case DEVICE_TYPE_RGB_LIGHT:
+		err = dm.hubMgr.ConfigurePort(NewInputFormatCommand(port, DEVICE_TYPE_RGB_LIGHT).Mode(LED_DISCRETE_MODE))
+		if err == nil {
+			err = dm.hubMgr.SendCommand(NewLEDCommand(port).RGB(0xFF, 0xFF, 0xFF).From(SourceSystem))
+		}
+		time.Sleep(kitCheckLEDTime)
+		// Встроенный светодиод возвращается к зеленому цвету подключенного хаба
+		restore := NewLEDCommand(port).RGB(0x00, 0x00, 0x00).From(SourceSystem)
+		if port == hubLEDPort {
+			restore = NewLEDCommand(port).RGB(0x00, 0xFF, 0x00).From(SourceSystem)
+		}
+		if restoreErr := dm.hubMgr.SendCommand(restore); err == nil {
+			err = restoreErr
+		}
+		result.Detail = "команды приняты, светодиод должен был вспыхнуть белым"
+
+	case DEVICE_TYPE_PIEZO_TONE:
+		err = dm.hubMgr.SendCommand(NewToneCommand(port).Tone(kitCheckToneFrequency, kitCheckToneTime).From(SourceSystem))
+		time.Sleep(time.Duration(kitCheckToneTime) * time.Millisecond)
+		result.Detail = "команда принята, пищалка должна была пискнуть"
+
+	default:
+		if !reportsValues(device.DeviceType) {
+			result.Detail = "проверка для этого устройства не предусмотрена"
+			result.Passed = true
+			return result
+		}
+		var value float64
+		value, err = dm.requestSensorValue(device)
+		result.Detail = "показание получено: " + fmt.Sprintf("%g", value)
+	}
+
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	if !dm.IsDeviceConnected(port, device.DeviceType) {
+		result.Detail = "устройство пропало с порта во время проверки"
+		return result
+	}
+	result.Passed = true
+	return result
+}
+
+// requestSensorValue заново включает уведомления датчика в текущем режиме
+// и ждет показания: хаб присылает значение сразу после настройки порта
+func (dm *DeviceManager) requestSensorValue(device *Device) (float64, error) {
+	values := make(chan float64, 1)
+	unsubscribe := dm.hubMgr.Events().Subscribe(EventSensorValue, func(event Event) {
+		if event.PortID != device.PortID {
+			return
+		}
+		select {
+		case values <- event.Value:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	cmd := NewInputFormatCommand(device.PortID, device.DeviceType)
+	if mode, ok := dm.hubMgr.PortMode(device.PortID); ok {
+		cmd.Mode(mode)
+	}
+	// Настройка того же режима иначе пропускается как уже примененная
+	dm.hubMgr.ForgetPortMode(device.PortID)
+	if err := dm.hubMgr.ConfigurePort(cmd); err != nil {
+		return 0, err
+	}
+
+	select {
+	case value := <-values:
+		return value, nil
+	case <-time.After(kitCheckSensorTimeout):
+		return 0, fmt.Errorf("датчик не прислал показание за %v", kitCheckSensorTimeout)
+	}
+}
+
+// offerKitCheck после подключения предлагает проверить комплект, если это включено в настройках
+func (gui *MainGUI) offerKitCheck() {
+	if !gui.preferences().BoolWithFallback(settingOfferKitCheck, true) || !gui.hubMgr.IsConnected() {
+		return
+	}
+	if len(gui.deviceMgr.GetConnectedDevices()) == 0 {
+		return
+	}
+
+	dialog.ShowConfirm("Проверка комплекта",
+		"Проверить найденные устройства? Моторы ненадолго включатся, светодиод вспыхнет,\n"+
+			"пищалка пискнет. Отключить это предложение можно в настройках Bluetooth.",
+		func(confirmed bool) {
+			if confirmed {
+				gui.runKitCheck()
+			}
+		}, gui.window)
+}
+
+// runKitCheck проверяет комплект в фоне и показывает результат по портам
+func (gui *MainGUI) runKitCheck() {
+	if !gui.hubMgr.IsConnected() {
+		dialog.ShowInformation("Проверка комплекта", "Сначала подключитесь к хабу", gui.window)
+		return
+	}
+	if gui.programMgr.GetProgramState() == ProgramStateRunning {
+		dialog.ShowInformation("Проверка комплекта", "Остановите программу перед проверкой", gui.window)
+		return
+	}
+
+	status := widget.NewLabel("Подготовка...")
+	bar := widget.NewProgressBar()
+	progress := dialog.NewCustomWithoutButtons("Проверка комплекта", container.NewVBox(status, bar), gui.window)
+	progress.Resize(fyne.NewSize(380, 0))
+	progress.Show()
+
+	go func() {
+		results := gui.deviceMgr.CheckKit(func(done, total int, device *Device) {
+			// Проверка комплекта идет долго и без участия пользователя
+			gui.batterySaver.Touch()
+			fyne.Do(func() {
+				if total > 0 {
+					bar.SetValue(float64(done) / float64(total))
+				}
+				if device != nil {
+					status.SetText(fmt.Sprintf("Порт %d: %s", device.PortID, DeviceTypeName(device.DeviceType)))
+				}
+			})
+		})
+		fyne.Do(func() {
+			progress.Hide()
+			gui.showKitCheckResults(results)
+		})
+	}()
+}
+
+// showKitCheckResults показывает результат проверки по портам
+func (gui *MainGUI) showKitCheckResults(results []KitCheckResult) {
+	rows := container.NewVBox()
+	failed := 0
+	for _, result := range results {
+		icon := theme.ConfirmIcon()
+		if !result.Passed {
+			icon = theme.ErrorIcon()
+			failed++
+		}
+		detail := widget.NewLabel(result.Detail)
+		detail.Wrapping = fyne.TextWrapWord
+		rows.Add(container.NewBorder(nil, nil, widget.NewIcon(icon), nil, container.NewVBox(
+			widget.NewLabelWithStyle(fmt.Sprintf("Порт %d: %s", result.PortID, result.Name),
+				fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			detail,
+		)))
+	}
+
+	summary := "Все устройства прошли проверку"
+	switch {
+	case len(results) == 0:
+		summary = "Устройства не найдены"
+	case failed > 0:
+		summary = fmt.Sprintf("Не прошли проверку: %d из %d", failed, len(results))
+	}
+
+	content := container.NewBorder(widget.NewLabel(summary), nil, nil, nil, container.NewVScroll(rows))
+	d := dialog.NewCustom("Проверка комплекта", "Закрыть", content, gui.window)
+	d.Resize(fyne.NewSize(460, 420))
+	d.Show()
+}
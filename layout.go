@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+)
+
+// Ключи настроек расположения окна и панелей
+const (
+	settingWindowWidth      = "layout_window_width"
+	settingWindowHeight     = "layout_window_height"
+	settingLeftSplit        = "layout_left_split"
+	settingRightSplit       = "layout_right_split"
+	settingDevicePanelOff   = "layout_device_panel_hidden"
+	settingPropertiesOff    = "layout_properties_panel_hidden"
+	defaultLeftSplitOffset  = 0.25
+	defaultRightSplitOffset = 0.75
+)
+
+// restoreLayout восстанавливает размер окна, разделители и видимость панелей
+func (gui *MainGUI) restoreLayout() {
+	prefs := gui.preferences()
+
+	width := prefs.Float(settingWindowWidth)
+	height := prefs.Float(settingWindowHeight)
+	if width > 0 && height > 0 {
+		gui.window.Resize(fyne.NewSize(float32(width), float32(height)))
+	}
+
+	gui.leftSplit.SetOffset(prefs.FloatWithFallback(settingLeftSplit, defaultLeftSplitOffset))
+	gui.rightSplit.SetOffset(prefs.FloatWithFallback(settingRightSplit, defaultRightSplitOffset))
+
+	gui.setDevicePanelVisible(!prefs.Bool(settingDevicePanelOff))
+	gui.setPropertiesPanelVisible(!prefs.Bool(settingPropertiesOff))
+}
+
+// saveLayout сохраняет размер окна и положение разделителей
+func (gui *MainGUI) saveLayout() {
+	prefs := gui.preferences()
+
+	size := gui.window.Canvas().Size()
+	prefs.SetFloat(settingWindowWidth, float64(size.Width))
+	prefs.SetFloat(settingWindowHeight, float64(size.Height))
+	prefs.SetFloat(settingLeftSplit, gui.leftSplit.Offset)
+	prefs.SetFloat(settingRightSplit, gui.rightSplit.Offset)
+}
+
+// setDevicePanelVisible показывает или скрывает панель устройств
+func (gui *MainGUI) setDevicePanelVisible(visible bool) {
+	if visible {
+		gui.devicePanel.GetContainer().Show()
+	} else {
+		gui.devicePanel.GetContainer().Hide()
+	}
+	gui.preferences().SetBool(settingDevicePanelOff, !visible)
+	gui.updateViewMenu()
+}
+
+// setPropertiesPanelVisible показывает или скрывает панель свойств
+func (gui *MainGUI) setPropertiesPanelVisible(visible bool) {
+	if visible {
+		gui.propertiesPanel.Show()
+	} else {
+		gui.propertiesPanel.Hide()
+	}
+	gui.rightSplit.Refresh()
+	gui.preferences().SetBool(settingPropertiesOff, !visible)
+	gui.updateViewMenu()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// IsEditable сообщает, отмечен ли параметр как заготовка, которую ученик может менять в режиме урока
+func (block *ProgramBlock) IsEditable(key string) bool {
+	for _, editable := range block.Editable {
+		if editable == key {
+			return true
+		}
+	}
+	return false
+}
+
+// setEditable отмечает параметр как заготовку для ученика или снимает отметку
+func (block *ProgramBlock) setEditable(key string, editable bool) {
+	var keys []string
+	for _, existing := range block.Editable {
+		if existing != key {
+			keys = append(keys, existing)
+		}
+	}
+	if editable {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	block.Editable = keys
+}
+
+// sortedParameterKeys ключи параметров блока по алфавиту
+func sortedParameterKeys(block *ProgramBlock) []string {
+	keys := make([]string, 0, len(block.Parameters))
+	for key := range block.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addTemplateControls добавляет отметки параметров, которые ученик сможет менять в режиме урока
+func (e *BlockEditor) addTemplateControls(cont *fyne.Container) {
+	checks := container.NewVBox()
+	for _, key := range sortedParameterKeys(e.block) {
+		key := key
+		check := widget.NewCheck(key, func(checked bool) {
+			e.block.setEditable(key, checked)
+			e.notifyChange()
+		})
+		check.Checked = e.block.IsEditable(key)
+		checks.Add(check)
+	}
+
+	hint := widget.NewLabel("Отмеченные параметры ученик сможет менять, когда программа открыта как урок")
+	hint.Wrapping = fyne.TextWrapWord
+	cont.Add(widget.NewAccordion(widget.NewAccordionItem("Заготовка для урока", container.NewVBox(hint, checks))))
+}
+
+// newLessonParameterEditor поле параметра-заготовки в режиме урока.
+// Введенный текст приводится к типу параметра так же, как при чтении файла
+func (gui *MainGUI) newLessonParameterEditor(block *ProgramBlock, key string) fyne.CanvasObject {
+	apply := func(value interface{}) error {
+		converted, err := decodeBlockParameter(block.Type, block.Parameters[key], key, value)
+		if err != nil {
+			return err
+		}
+		block.Parameters[key] = converted
+		gui.programMgr.UpdateBlock(block.ID, block.Parameters)
+		return nil
+	}
+
+	if checked, ok := block.Parameters[key].(bool); ok {
+		check := widget.NewCheck("", func(value bool) { apply(value) })
+		check.Checked = checked
+		return check
+	}
+
+	entry := widget.NewEntry()
+	entry.SetText(fmt.Sprintf("%v", block.Parameters[key]))
+	parse := func(text string) interface{} {
+		if _, isText := block.Parameters[key].(string); isText {
+			return text
+		}
+		if number, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(text), ",", ".", 1), 64); err == nil {
+			return number
+		}
+		return text
+	}
+	entry.Validator = func(text string) error {
+		_, err := decodeBlockParameter(block.Type, block.Parameters[key], key, parse(text))
+		return err
+	}
+	entry.OnChanged = func(text string) {
+		if entry.Validator(text) == nil {
+			apply(parse(text))
+		}
+	}
+	return entry
+}
+
+// hasEditableParameters сообщает, есть ли в программе параметры-заготовки
+func hasEditableParameters(program *Program) bool {
+	for _, block := range program.Blocks {
+		if len(block.Editable) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// showOpenLessonDialog открывает программу как урок: она блокируется,
+// и ученик может менять только отмеченные учителем параметры
+func (gui *MainGUI) showOpenLessonDialog() {
+	if gui.locked {
+		return
+	}
+
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, gui.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		gui.readProgram(reader.URI(), reader)
+
+		if !hasEditableParameters(gui.programMgr.GetProgram()) {
+			dialog.ShowInformation("Урок", "В программе нет параметров, отмеченных для ученика: она откроется только для просмотра", gui.window)
+		}
+		// Без PIN-кода учителя блокировку нельзя было бы снять, поэтому сначала его задают
+		if gui.preferences().String(settingTeacherPIN) == "" {
+			gui.showLockDialog()
+			return
+		}
+		gui.setLocked(true)
+	}, gui.window)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{programFileExtension}))
+	d.Show()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Блок «Светомузыка» играет мелодию на пищалке и на каждой ноте меняет цвет
+// светодиода. Ноты и цвета раскладываются в одно расписание команд
+// (scheduleCompiler), поэтому свет не отстает от звука ни при выполнении
+// блока, ни в рассчитанной заранее цепочке
+
+// Палитры светомузыки
+const (
+	LightPaletteRainbow = "rainbow" // Цвета радуги по очереди
+	LightPaletteWarm    = "warm"    // Красный, оранжевый, желтый
+	LightPaletteCold    = "cold"    // Синий, голубой, фиолетовый
+	LightPalettePitch   = "pitch"   // Цвет по названию ноты: до — красный, си — фиолетовый
+)
+
+// lightPalette палитра с названием для интерфейса
+type lightPalette struct {
+	key    string
+	name   string
+	colors [][3]byte
+}
+
+// rainbowColors цвета радуги от красного до фиолетового
+var rainbowColors = [][3]byte{
+	{255, 0, 0}, {255, 127, 0}, {255, 255, 0}, {0, 255, 0}, {0, 255, 255}, {0, 0, 255}, {139, 0, 255},
+}
+
+// lightPalettes доступные палитры
+var lightPalettes = []lightPalette{
+	{LightPaletteRainbow, "Радуга", rainbowColors},
+	{LightPaletteWarm, "Теплые цвета", [][3]byte{{255, 0, 0}, {255, 127, 0}, {255, 255, 0}}},
+	{LightPaletteCold, "Холодные цвета", [][3]byte{{0, 0, 255}, {0, 255, 255}, {139, 0, 255}}},
+	{LightPalettePitch, "По высоте ноты", rainbowColors},
+}
+
+// findLightPalette возвращает палитру по ключу
+func findLightPalette(key string) lightPalette {
+	for _, palette := range lightPalettes {
+		if palette.key == key {
+			return palette
+		}
+	}
+	return lightPalettes[0]
+}
+
+// noteColor цвет ноты: по очереди из палитры или по ее названию
+func (p lightPalette) noteColor(index int, frequency uint16) [3]byte {
+	if p.key != LightPalettePitch {
+		return p.colors[index%len(p.colors)]
+	}
+	// Номер ноты в октаве 0..11 переводится в семь цветов радуги
+	semitone := int(math.Round(12*math.Log2(float64(frequency)/440))) + 69
+	step := (semitone%12 + 12) % 12
+	return p.colors[step*len(p.colors)/12]
+}
+
+// LightMusicParams параметры блока «Светомузыка»
+type LightMusicParams struct {
+	Melody    string // Строка RTTTL
+	SoundPort byte
+	LEDPort   byte
+	Palette   string
+}
+
+// defaultLightMusicParams параметры нового блока «Светомузыка»
+var defaultLightMusicParams = LightMusicParams{
+	Melody:    "Гамма:d=8,o=5,b=140:c,d,e,f,g,a,b,4c6",
+	SoundPort: 1,
+	LEDPort:   6,
+	Palette:   LightPaletteRainbow,
+}
+
+// lightMusicFromParameters читает параметры блока «Светомузыка»
+func lightMusicFromParameters(params map[string]interface{}) LightMusicParams {
+	p := defaultLightMusicParams
+	p.Melody = paramString(params, "melody", p.Melody)
+	p.SoundPort = paramByte(params, "sound_port", p.SoundPort)
+	p.LEDPort = paramByte(params, "led_port", p.LEDPort)
+	p.Palette = paramString(params, "palette", p.Palette)
+	return p
+}
+
+// Store записывает параметры светомузыки
+func (p LightMusicParams) Store(params map[string]interface{}) {
+	params["melody"] = p.Melody
+	params["sound_port"] = p.SoundPort
+	params["led_port"] = p.LEDPort
+	params["palette"] = p.Palette
+}
+
+// melodyLength общая длительность мелодии
+func melodyLength(notes []MelodyNote) time.Duration {
+	var length time.Duration
+	for _, note := range notes {
+		length += time.Duration(note.Duration) * time.Millisecond
+	}
+	return length
+}
+
+// lightMusicLength длительность мелодии блока или 0, если мелодия записана с ошибкой
+func lightMusicLength(params map[string]interface{}) time.Duration {
+	notes, err := parseRTTTL(lightMusicFromParameters(params).Melody)
+	if err != nil {
+		return 0
+	}
+	return melodyLength(notes)
+}
+
+// addLightMusic добавляет в расписание ноты мелодии и цвета светодиода. Цвет
+// меняется в начале каждой ноты, на паузах и после мелодии светодиод гаснет.
+// Отметка ScheduleBlock сообщает наблюдателям о блоке целиком; без нее
+// блок выполняется внутри executeBlock, который сообщает о нем сам
+func (c *scheduleCompiler) addLightMusic(block *ProgramBlock, announce bool) error {
+	params := lightMusicFromParameters(block.Parameters)
+	notes, err := parseRTTTL(params.Melody)
+	if err != nil {
+		return fmt.Errorf("мелодия блока %d: %v", block.ID, err)
+	}
+	palette := findLightPalette(params.Palette)
+
+	if announce {
+		c.add(ScheduleEntry{Kind: ScheduleBlock, BlockID: block.ID, Duration: melodyLength(notes)})
+	}
+	for i, note := range notes {
+		duration := time.Duration(note.Duration) * time.Millisecond
+		if note.Frequency == 0 {
+			c.addLED(ScheduleEntry{Kind: ScheduleLED, BlockID: block.ID, Port: params.LEDPort, Continues: true})
+			c.at += duration
+			continue
+		}
+		rgb := palette.noteColor(i, note.Frequency)
+		c.addLED(ScheduleEntry{Kind: ScheduleLED, BlockID: block.ID, Port: params.LEDPort, Continues: true,
+			Red: rgb[0], Green: rgb[1], Blue: rgb[2]})
+		c.add(ScheduleEntry{Kind: ScheduleSound, BlockID: block.ID, Port: params.SoundPort, Continues: true,
+			Duration: duration, Frequency: note.Frequency})
+		c.at += duration
+	}
+	c.addLED(ScheduleEntry{Kind: ScheduleLED, BlockID: block.ID, Port: params.LEDPort, Continues: true})
+	return nil
+}
+
+// playLightMusic выполняет блок «Светомузыка» по его собственному расписанию
+func (pm *ProgramManager) playLightMusic(block *ProgramBlock) error {
+	if !pm.hubMgr.Ready() {
+		return fmt.Errorf("не подключено к хабу")
+	}
+
+	c := &scheduleCompiler{schedule: &Schedule{}}
+	if err := c.addLightMusic(block, false); err != nil {
+		return err
+	}
+	c.schedule.Length = c.at
+
+	log.Printf("Светомузыка блока %d: %d команд, %v", block.ID, len(c.schedule.Entries), c.schedule.Length)
+	return pm.runSchedule(pm.runContext(), c.schedule)
+}
+
+// String возвращает текстовое описание светомузыки
+func (p LightMusicParams) String() string {
+	description := "мелодия с ошибкой"
+	if notes, err := parseRTTTL(p.Melody); err == nil {
+		description = fmt.Sprintf("%d нот, %s", len(notes), formatSummarySeconds(melodyLength(notes).Seconds()))
+	}
+	return fmt.Sprintf("%s: звук на порту %d, светодиод на порту %d, цвета «%s»",
+		description, p.SoundPort, p.LEDPort, findLightPalette(p.Palette).name)
+}
+
+// addLightMusicControls добавляет элементы управления для блока «Светомузыка»
+func (e *BlockEditor) addLightMusicControls(cont *fyne.Container) {
+	params := lightMusicFromParameters(e.block.Parameters)
+	params.Store(e.block.Parameters)
+
+	// Мелодия сохраняется, только если ее удалось прочитать
+	melodyStatus := widget.NewLabel("")
+	melodyStatus.Wrapping = fyne.TextWrapWord
+	showMelody := func(text string) bool {
+		notes, err := parseRTTTL(text)
+		if err != nil {
+			melodyStatus.SetText("Ошибка: " + err.Error())
+			return false
+		}
+		melodyStatus.SetText(fmt.Sprintf("%d нот, %s", len(notes), formatSummarySeconds(melodyLength(notes).Seconds())))
+		return true
+	}
+
+	melodyEntry := widget.NewMultiLineEntry()
+	melodyEntry.Wrapping = fyne.TextWrapWord
+	melodyEntry.SetMinRowsVisible(3)
+	melodyEntry.SetText(params.Melody)
+	showMelody(params.Melody)
+	melodyEntry.OnChanged = func(text string) {
+		if showMelody(text) {
+			e.block.Parameters["melody"] = text
+			e.notifyChange()
+		}
+	}
+
+	newPortSelect := func(key string, ports []byte) *widget.Select {
+		names := make([]string, len(ports))
+		for i, port := range ports {
+			names[i] = portName(port)
+		}
+		portSelect := widget.NewSelect(names, func(selected string) {
+			for i, name := range names {
+				if name == selected {
+					e.block.Parameters[key] = ports[i]
+				}
+			}
+			e.notifyChange()
+		})
+		current, _ := e.block.Parameters[key].(byte)
+		portSelect.SetSelected(portName(current))
+		return portSelect
+	}
+
+	paletteNames := make([]string, len(lightPalettes))
+	for i, palette := range lightPalettes {
+		paletteNames[i] = palette.name
+	}
+	paletteSelect := widget.NewSelect(paletteNames, func(selected string) {
+		for _, palette := range lightPalettes {
+			if palette.name == selected {
+				e.block.Parameters["palette"] = palette.key
+			}
+		}
+		e.notifyChange()
+	})
+	paletteSelect.SetSelected(findLightPalette(params.Palette).name)
+
+	cont.Add(widget.NewLabel("Мелодия (RTTTL):"))
+	cont.Add(melodyEntry)
+	cont.Add(melodyStatus)
+	cont.Add(widget.NewLabel("Порт пищалки:"))
+	cont.Add(newPortSelect("sound_port", []byte{1, 2}))
+	cont.Add(widget.NewLabel("Светодиод:"))
+	cont.Add(newPortSelect("led_port", []byte{6, 1, 2}))
+	cont.Add(widget.NewLabel("Цвета:"))
+	cont.Add(paletteSelect)
+}
+
+// portName название порта хаба для списков выбора
+func portName(port byte) string {
+	if port == 6 {
+		return "Встроенный светодиод"
+	}
+	return fmt.Sprintf("Порт %d", port)
+}
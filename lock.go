@@ -0,0 +1,327 @@
+package main
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Ключи настроек блокировки программы
+const (
+	settingTeacherPIN     = "teacher_pin_hash"
+	settingProgramLocked  = "program_locked"
+	settingPINFailures    = "teacher_pin_failures"     // Неверных попыток подряд
+	settingPINLockedUntil = "teacher_pin_locked_until" // Время конца паузы, Unix-секунды
+)
+
+// PIN-код из 4–8 цифр перебирается быстро, поэтому хеш медленный и с солью,
+// а после нескольких неверных попыток ввод откладывается
+const (
+	pinHashScheme     = "pbkdf2-sha256"
+	pinHashIterations = 200_000
+	pinSaltSize       = 16
+	pinKeySize        = 32
+
+	pinMaxAttempts = 5                // Неверных попыток до первой паузы
+	pinLockoutBase = 30 * time.Second // Первая пауза; каждая следующая попытка удваивает ее
+	pinLockoutMax  = 15 * time.Minute
+)
+
+// hashPIN возвращает хеш PIN-кода учителя для хранения в настройках:
+// схема, число итераций, соль и ключ через "$"
+func hashPIN(pin string) (string, error) {
+	salt := make([]byte, pinSaltSize)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, pin, salt, pinHashIterations, pinKeySize)
+	if err != nil {
+		return "", fmt.Errorf("ошибка хеширования PIN-кода: %v", err)
+	}
+	return strings.Join([]string{pinHashScheme, strconv.Itoa(pinHashIterations), hex.EncodeToString(salt), hex.EncodeToString(key)}, "$"), nil
+}
+
+// verifyPIN сравнивает PIN-код с сохраненным хешем. legacy означает хеш
+// старого формата без соли, который нужно заменить
+func verifyPIN(pin, stored string) (ok, legacy bool) {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 4 || parts[0] != pinHashScheme {
+		sum := sha256.Sum256([]byte("wedoprog:" + pin))
+		return stored != "" && subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(stored)) == 1, true
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false, false
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return false, false
+	}
+	key, err := pbkdf2.Key(sha256.New, pin, salt, iterations, len(want))
+	if err != nil {
+		return false, false
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1, false
+}
+
+// pinAttempts неверные попытки ввода PIN-кода подряд и пауза после них
+type pinAttempts struct {
+	failures    int
+	lockedUntil time.Time
+}
+
+// loadPINAttempts читает попытки из настроек, чтобы перезапуск приложения не снимал паузу
+func loadPINAttempts(prefs fyne.Preferences) pinAttempts {
+	attempts := pinAttempts{failures: prefs.Int(settingPINFailures)}
+	if until := prefs.Int(settingPINLockedUntil); until > 0 {
+		attempts.lockedUntil = time.Unix(int64(until), 0)
+	}
+	return attempts
+}
+
+// save сохраняет попытки в настройках
+func (a pinAttempts) save(prefs fyne.Preferences) {
+	prefs.SetInt(settingPINFailures, a.failures)
+	var until int
+	if !a.lockedUntil.IsZero() {
+		until = int(a.lockedUntil.Unix())
+	}
+	prefs.SetInt(settingPINLockedUntil, until)
+}
+
+// wait возвращает, сколько еще нельзя вводить PIN-код
+func (a pinAttempts) wait(now time.Time) time.Duration {
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+// fail учитывает неверную попытку и начиная с pinMaxAttempts назначает паузу
+func (a *pinAttempts) fail(now time.Time) {
+	a.failures++
+	if a.failures < pinMaxAttempts {
+		return
+	}
+	pause := pinLockoutBase
+	for i := pinMaxAttempts; i < a.failures && pause < pinLockoutMax; i++ {
+		pause *= 2
+	}
+	if pause > pinLockoutMax {
+		pause = pinLockoutMax
+	}
+	a.lockedUntil = now.Add(pause)
+}
+
+// checkTeacherPIN проверяет PIN-код учителя с учетом паузы после неверных
+// попыток. Хеш старого формата после верного ввода заменяется новым
+func (gui *MainGUI) checkTeacherPIN(pin string) error {
+	prefs := gui.preferences()
+	attempts := loadPINAttempts(prefs)
+	now := time.Now()
+	if wait := attempts.wait(now); wait > 0 {
+		return fmt.Errorf("слишком много неверных попыток, повторите через %v", wait.Round(time.Second))
+	}
+
+	ok, legacy := verifyPIN(pin, prefs.String(settingTeacherPIN))
+	if !ok {
+		attempts.fail(now)
+		attempts.save(prefs)
+		log.Printf("Неверный PIN-код учителя, попытка %d", attempts.failures)
+		return fmt.Errorf("неверный PIN-код")
+	}
+
+	if legacy {
+		if hash, err := hashPIN(pin); err == nil {
+			prefs.SetString(settingTeacherPIN, hash)
+		}
+	}
+	pinAttempts{}.save(prefs)
+	return nil
+}
+
+// setTeacherPIN задает новый PIN-код учителя
+func (gui *MainGUI) setTeacherPIN(pin string) error {
+	hash, err := hashPIN(pin)
+	if err != nil {
+		return err
+	}
+	gui.preferences().SetString(settingTeacherPIN, hash)
+	pinAttempts{}.save(gui.preferences())
+	return nil
+}
+
+// validatePIN проверяет, что PIN-код состоит из 4–8 цифр
+func validatePIN(pin string) error {
+	if len(pin) < 4 || len(pin) > 8 {
+		return fmt.Errorf("PIN-код должен содержать от 4 до 8 цифр")
+	}
+	for _, r := range pin {
+		if !unicode.IsDigit(r) {
+			return fmt.Errorf("PIN-код может содержать только цифры")
+		}
+	}
+	return nil
+}
+
+// toggleLock блокирует или разблокирует программу после ввода PIN-кода учителя
+func (gui *MainGUI) toggleLock() {
+	if gui.locked {
+		gui.showUnlockDialog()
+	} else {
+		gui.showLockDialog()
+	}
+}
+
+// showLockDialog запрашивает PIN-код и переводит программу в режим просмотра.
+// Если PIN-код еще не задан, он создается при первой блокировке.
+func (gui *MainGUI) showLockDialog() {
+	prefs := gui.preferences()
+	storedHash := prefs.String(settingTeacherPIN)
+
+	pinEntry := widget.NewPasswordEntry()
+	pinEntry.Validator = validatePIN
+	items := []*widget.FormItem{widget.NewFormItem("PIN-код", pinEntry)}
+
+	confirmEntry := widget.NewPasswordEntry()
+	if storedHash == "" {
+		confirmEntry.Validator = func(text string) error {
+			if text != pinEntry.Text {
+				return fmt.Errorf("PIN-коды не совпадают")
+			}
+			return nil
+		}
+		items = append(items, widget.NewFormItem("Повторите", confirmEntry))
+	}
+
+	hint := widget.NewLabel("В режиме просмотра блоки нельзя добавлять, изменять и удалять, но программу можно запускать и останавливать.")
+	hint.Wrapping = fyne.TextWrapWord
+	items = append(items, widget.NewFormItem("", hint))
+
+	d := dialog.NewForm("Заблокировать программу", "Заблокировать", "Отмена", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+
+		if storedHash == "" {
+			if err := gui.setTeacherPIN(pinEntry.Text); err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+		} else if err := gui.checkTeacherPIN(pinEntry.Text); err != nil {
+			dialog.ShowError(err, gui.window)
+			return
+		}
+
+		gui.setLocked(true)
+	}, gui.window)
+	d.Resize(fyne.NewSize(420, 260))
+	d.Show()
+}
+
+// showUnlockDialog запрашивает PIN-код и снимает блокировку программы
+func (gui *MainGUI) showUnlockDialog() {
+	pinEntry := widget.NewPasswordEntry()
+
+	d := dialog.NewForm("Разблокировать программу", "Разблокировать", "Отмена",
+		[]*widget.FormItem{widget.NewFormItem("PIN-код", pinEntry)},
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+
+			if err := gui.checkTeacherPIN(pinEntry.Text); err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+
+			gui.setLocked(false)
+		}, gui.window)
+	d.Resize(fyne.NewSize(360, 160))
+	d.Show()
+}
+
+// setLocked включает или выключает режим просмотра и сохраняет его между запусками
+func (gui *MainGUI) setLocked(locked bool) {
+	gui.locked = locked
+	gui.preferences().SetBool(settingProgramLocked, locked)
+	gui.applyLockState()
+
+	if locked {
+		log.Println("Программа заблокирована для просмотра")
+	} else {
+		log.Println("Программа разблокирована")
+	}
+}
+
+// applyLockState обновляет палитру, панель инструментов и свойства под текущую блокировку
+func (gui *MainGUI) applyLockState() {
+	for _, button := range gui.paletteButtons {
+		if gui.readOnly() {
+			button.Disable()
+		} else {
+			button.Enable()
+		}
+	}
+
+	if gui.toolbar != nil {
+		gui.toolbar.updateLockButton(gui.locked)
+	}
+	gui.updateViewMenu()
+	hasProgram := len(gui.programMgr.program.Blocks) > 0
+	gui.updateToolbarState(gui.hubMgr.IsConnected(), hasProgram)
+
+	if gui.selectedBlock != nil {
+		gui.showBlockProperties(gui.selectedBlock)
+	} else {
+		gui.clearPropertiesPanel()
+	}
+}
+
+// createReadOnlyBlockView показывает параметры блока без возможности изменения,
+// кроме параметров-заготовок, которые учитель отметил для ученика
+func (gui *MainGUI) createReadOnlyBlockView(block *ProgramBlock) fyne.CanvasObject {
+	notice := "Программа заблокирована: доступен только просмотр"
+	if gui.programMgr.GetProgram().RunOnly {
+		notice = "Программа только для запуска: изменять ее нельзя"
+	} else if len(block.Editable) > 0 {
+		notice = "Урок: можно менять только отмеченные учителем параметры"
+	}
+	hint := widget.NewLabel(notice)
+	hint.Wrapping = fyne.TextWrapWord
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(block.Title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		hint,
+		widget.NewSeparator(),
+	)
+
+	form := widget.NewForm()
+	for _, key := range sortedParameterKeys(block) {
+		if block.IsEditable(key) {
+			form.Append(key, gui.newLessonParameterEditor(block, key))
+			continue
+		}
+		value := widget.NewLabel(fmt.Sprintf("%v", block.Parameters[key]))
+		value.Wrapping = fyne.TextWrapWord
+		form.Append(key, value)
+	}
+	content.Add(form)
+
+	return content
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashPINIsSalted(t *testing.T) {
+	first, err := hashPIN("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := hashPIN("1234")
+	if first == second {
+		t.Error("хеши одного PIN-кода совпадают: соль не используется")
+	}
+
+	if ok, legacy := verifyPIN("1234", first); !ok || legacy {
+		t.Errorf("верный PIN-код: ok=%v legacy=%v", ok, legacy)
+	}
+	if ok, _ := verifyPIN("4321", first); ok {
+		t.Error("неверный PIN-код принят")
+	}
+
+	sum := sha256.Sum256([]byte("wedoprog:1234"))
+	if ok, legacy := verifyPIN("1234", hex.EncodeToString(sum[:])); !ok || !legacy {
+		t.Errorf("хеш старого формата: ok=%v legacy=%v", ok, legacy)
+	}
+	if ok, _ := verifyPIN("", ""); ok {
+		t.Error("пустой PIN-код принят без сохраненного хеша")
+	}
+}
+
+func TestPINAttemptsBackoff(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+	var attempts pinAttempts
+
+	for i := 1; i < pinMaxAttempts; i++ {
+		attempts.fail(now)
+		if wait := attempts.wait(now); wait != 0 {
+			t.Fatalf("пауза %v после %d неверных попыток", wait, i)
+		}
+	}
+
+	attempts.fail(now)
+	if wait := attempts.wait(now); wait != pinLockoutBase {
+		t.Errorf("первая пауза %v, ожидалось %v", wait, pinLockoutBase)
+	}
+	attempts.fail(now)
+	if wait := attempts.wait(now); wait != 2*pinLockoutBase {
+		t.Errorf("вторая пауза %v, ожидалось %v", wait, 2*pinLockoutBase)
+	}
+	for i := 0; i < 20; i++ {
+		attempts.fail(now)
+	}
+	if wait := attempts.wait(now); wait != pinLockoutMax {
+		t.Errorf("пауза после многих попыток %v, ожидалось %v", wait, pinLockoutMax)
+	}
+	if wait := attempts.wait(now.Add(pinLockoutMax)); wait != 0 {
+		t.Errorf("пауза не закончилась: осталось %v", wait)
+	}
+}
+
+func TestCheckTeacherPINLocksOut(t *testing.T) {
+	gui := newTestGUI(t)
+	if err := gui.setTeacherPIN("1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < pinMaxAttempts; i++ {
+		if err := gui.checkTeacherPIN("0000"); err == nil {
+			t.Fatal("неверный PIN-код принят")
+		}
+	}
+	if err := gui.checkTeacherPIN("1234"); err == nil || !strings.Contains(err.Error(), "попыток") {
+		t.Errorf("во время паузы верный PIN-код: %v, ожидался отказ", err)
+	}
+
+	// Пауза сохраняется в настройках и переживает перезапуск
+	if wait := loadPINAttempts(gui.preferences()).wait(time.Now()); wait <= 0 {
+		t.Error("пауза не сохранена в настройках")
+	}
+}
+
+func TestCheckTeacherPINUpgradesLegacyHash(t *testing.T) {
+	gui := newTestGUI(t)
+	sum := sha256.Sum256([]byte("wedoprog:1234"))
+	gui.preferences().SetString(settingTeacherPIN, hex.EncodeToString(sum[:]))
+
+	if err := gui.checkTeacherPIN("1234"); err != nil {
+		t.Fatalf("PIN-код со старым хешем: %v", err)
+	}
+	stored := gui.preferences().String(settingTeacherPIN)
+	if ok, legacy := verifyPIN("1234", stored); !ok || legacy {
+		t.Errorf("хеш не заменен новым: %q", stored)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	settingLogLevelPrefix = "log_level_"   // Префикс ключей уровня журнала модулей
+	settingProtocolLog    = "protocol_log" // Подробность журнала команд хабу
+
+	protocolLogInterval = 500 * time.Millisecond // Не чаще одной строки на характеристику и порт
+)
+
+// LogLevel подробность журнала модуля
+type LogLevel int
+
+// Уровни журнала
+const (
+	LogLevelError LogLevel = iota // Только ошибки
+	LogLevelInfo                  // Основные события
+	LogLevelDebug                 // Каждая команда и каждый шаг
+)
+
+// logLevelNames названия уровней для интерфейса
+var logLevelNames = []string{"Только ошибки", "Основные события", "Подробно"}
+
+// Модули журнала
+const (
+	LogModuleDevices = "devices" // Команды устройствам
+	LogModuleProgram = "program" // Выполнение блоков
+)
+
+// logModules модули с названиями для интерфейса
+var logModules = []struct {
+	module string
+	name   string
+}{
+	{LogModuleDevices, "Устройства"},
+	{LogModuleProgram, "Программа"},
+}
+
+// logLevels текущие уровни модулей, по умолчанию LogLevelInfo
+var logLevels = struct {
+	sync.RWMutex
+	modules map[string]LogLevel
+}{modules: make(map[string]LogLevel)}
+
+// SetLogLevel задает уровень журнала модуля
+func SetLogLevel(module string, level LogLevel) {
+	logLevels.Lock()
+	defer logLevels.Unlock()
+	logLevels.modules[module] = level
+}
+
+// logEnabled сообщает, пишется ли в журнал сообщение уровня level
+func logEnabled(module string, level LogLevel) bool {
+	logLevels.RLock()
+	defer logLevels.RUnlock()
+	current, ok := logLevels.modules[module]
+	if !ok {
+		current = LogLevelInfo
+	}
+	return level <= current
+}
+
+// ModuleLogger журнал модуля: сообщения ниже уровня модуля не пишутся
+type ModuleLogger struct {
+	module string
+}
+
+// Журналы модулей
+var (
+	devicesLog = ModuleLogger{module: LogModuleDevices}
+	programLog = ModuleLogger{module: LogModuleProgram}
+)
+
+// Infof пишет основное событие
+func (l ModuleLogger) Infof(format string, args ...interface{}) {
+	if logEnabled(l.module, LogLevelInfo) {
+		log.Printf(format, args...)
+	}
+}
+
+// Debugf пишет подробность, например каждую отправленную команду
+func (l ModuleLogger) Debugf(format string, args ...interface{}) {
+	if logEnabled(l.module, LogLevelDebug) {
+		log.Printf(format, args...)
+	}
+}
+
+// ProtocolLogMode подробность журнала команд, отправленных хабу
+type ProtocolLogMode string
+
+// Режимы журнала команд
+const (
+	ProtocolLogOff     ProtocolLogMode = "off"     // Команды не пишутся
+	ProtocolLogSummary ProtocolLogMode = "summary" // Характеристика и длина, без данных
+	ProtocolLogHex     ProtocolLogMode = "hex"     // Данные полностью в HEX
+)
+
+// protocolLogModes режимы с названиями для интерфейса
+var protocolLogModes = []struct {
+	mode ProtocolLogMode
+	name string
+}{
+	{ProtocolLogOff, "Не писать"},
+	{ProtocolLogSummary, "Без данных"},
+	{ProtocolLogHex, "Полностью (HEX)"},
+}
+
+// protocolLogModeName название режима журнала команд
+func protocolLogModeName(mode ProtocolLogMode) string {
+	for _, item := range protocolLogModes {
+		if item.mode == mode {
+			return item.name
+		}
+	}
+	return protocolLogModes[0].name
+}
+
+// ProtocolLogger пишет в журнал команды хабу. Частые команды, например при
+// анимации светодиода, прореживаются: не чаще строки в protocolLogInterval
+// на характеристику и порт, с числом пропущенных команд
+type ProtocolLogger struct {
+	mu      sync.Mutex
+	mode    ProtocolLogMode
+	last    map[string]time.Time
+	skipped map[string]int
+}
+
+// NewProtocolLogger создает выключенный журнал команд
+func NewProtocolLogger() *ProtocolLogger {
+	return &ProtocolLogger{
+		mode:    ProtocolLogOff,
+		last:    make(map[string]time.Time),
+		skipped: make(map[string]int),
+	}
+}
+
+// SetMode задает подробность журнала команд
+func (p *ProtocolLogger) SetMode(mode ProtocolLogMode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch mode {
+	case ProtocolLogSummary, ProtocolLogHex:
+	default:
+		mode = ProtocolLogOff
+	}
+	p.mode = mode
+}
+
+// Write пишет отправленную команду, если режим и прореживание позволяют
+func (p *ProtocolLogger) Write(uuid string, data []byte) {
+	p.mu.Lock()
+	mode := p.mode
+	if mode == ProtocolLogOff {
+		p.mu.Unlock()
+		return
+	}
+
+	key := uuid
+	if len(data) > 0 {
+		key = fmt.Sprintf("%s/%d", uuid, data[0])
+	}
+	now := time.Now()
+	if now.Sub(p.last[key]) < protocolLogInterval {
+		p.skipped[key]++
+		p.mu.Unlock()
+		return
+	}
+	skipped := p.skipped[key]
+	p.last[key] = now
+	delete(p.skipped, key)
+	p.mu.Unlock()
+
+	line := fmt.Sprintf("Данные отправлены: %s, %d байт", getShortUUID(uuid), len(data))
+	if mode == ProtocolLogHex {
+		line += fmt.Sprintf(" (HEX: % x)", data)
+	}
+	if skipped > 0 {
+		line += fmt.Sprintf(", пропущено похожих: %d", skipped)
+	}
+	log.Println(line)
+}
+
+// ProtocolLog возвращает журнал команд хабу
+func (hm *HubManager) ProtocolLog() *ProtocolLogger {
+	return hm.protocolLog
+}
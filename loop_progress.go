@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+)
+
+// loopProgressText подпись итерации цикла; total 0 — число повторов заранее неизвестно
+func loopProgressText(iteration, total int) string {
+	if total > 0 {
+		return fmt.Sprintf("повтор %d/%d", iteration, total)
+	}
+	return fmt.Sprintf("повтор %d", iteration)
+}
+
+// publishLoopProgress сообщает подписчикам шины итерацию цикла, iteration 0 — цикл завершен
+func (pm *ProgramManager) publishLoopProgress(block *ProgramBlock, iteration, total int) {
+	event := Event{
+		Type:    EventLoopProgress,
+		BlockID: block.ID,
+		Value:   float64(iteration),
+	}
+	if iteration > 0 {
+		event.Name = loopProgressText(iteration, total)
+	}
+	pm.hubMgr.Events().Publish(event)
+}
+
+// onLoopProgress показывает итерацию цикла на блоке и в строке состояния
+func (gui *MainGUI) onLoopProgress(event Event) {
+	fyne.Do(func() {
+		if blockWidget := gui.programPanel.GetBlockWidget(event.BlockID); blockWidget != nil {
+			blockWidget.SetProgress(event.Name)
+		}
+		gui.statusBar.SetLoopProgress(event.Name)
+	})
+}
+
+// clearLoopProgress убирает подписи итераций после завершения программы
+func (gui *MainGUI) clearLoopProgress(event Event) {
+	if event.Name == "running" {
+		return
+	}
+	fyne.Do(func() {
+		for _, block := range gui.programMgr.GetProgram().Blocks {
+			if blockWidget := gui.programPanel.GetBlockWidget(block.ID); blockWidget != nil {
+				blockWidget.SetProgress("")
+			}
+		}
+		gui.statusBar.SetLoopProgress("")
+	})
+}
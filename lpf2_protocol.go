@@ -1,5 +1,7 @@
 package main
 
+import "math"
+
 // UUID характеристик WeDo 2.0/LPF2
 const (
 	// Основные службы
@@ -15,6 +17,7 @@ const (
 	INPUT_COMMAND_UUID  = "00001563-1212-efde-1523-785feabcd123" // Команды настройки
 	OUTPUT_COMMAND_UUID = "00001565-1212-efde-1523-785feabcd123" // Команды управления
 	NAME_UUID           = "00001524-1212-efde-1523-785feabcd123" // Уведомления портов
+	HUB_ACTION_UUID     = "0000152b-1212-efde-1523-785feabcd123" // Действия хаба: выключение
 
 	// Информация об устройстве
 	MANUFACTURER_NAME_UUID = "00002a29-0000-1000-8000-00805f9b34fb"
@@ -30,22 +33,42 @@ const (
 // LPF2Protocol реализует протокол LPF2
 type LPF2Protocol struct{}
 
-// EncodeMotorCommand кодирует команду для мотора
-func (p *LPF2Protocol) EncodeMotorCommand(portID byte, speed float64) []byte {
-	var speedByte byte
+// protocol общий кодировщик команд LPF2
+var protocol = &LPF2Protocol{}
 
-	if speed < 0 {
-		// Обратное направление
-		speedByte = byte((0x54 * maxFloat(speed, -1)) + 0xF0)
-	} else if speed > 0 {
+// Диапазон байта скорости мотора: 0x10..0x64 вперед, 0xF0..0x9C назад
+const (
+	motorSpeedForward = 0x10
+	motorSpeedReverse = 0xF0
+	motorSpeedSpan    = 0x54
+)
+
+// EncodeMotorSpeed переводит мощность в процентах (-100..100) в байт скорости.
+// Значения за пределами диапазона ограничиваются, 100 и -100 дают 0x64 и 0x9C
+func (p *LPF2Protocol) EncodeMotorSpeed(power int8) byte {
+	percent := int(power)
+	if percent > 100 {
+		percent = 100
+	} else if percent < -100 {
+		percent = -100
+	}
+
+	switch {
+	case percent > 0:
 		// Прямое направление
-		speedByte = byte((0x54 * minFloat(speed, 1)) + 0x10)
-	} else {
+		return byte(motorSpeedForward + int(math.Round(motorSpeedSpan*float64(percent)/100)))
+	case percent < 0:
+		// Обратное направление
+		return byte(motorSpeedReverse - int(math.Round(motorSpeedSpan*float64(-percent)/100)))
+	default:
 		// Стоп
-		speedByte = 0x00
+		return 0x00
 	}
+}
 
-	return []byte{portID, 0x01, 0x01, speedByte}
+// EncodeMotorCommand кодирует команду для мотора с мощностью в процентах
+func (p *LPF2Protocol) EncodeMotorCommand(portID byte, power int8) []byte {
+	return []byte{portID, 0x01, 0x01, p.EncodeMotorSpeed(power)}
 }
 
 // EncodeLEDCommand кодирует команду для RGB светодиода
@@ -91,6 +114,11 @@ func (p *LPF2Protocol) EncodePiezoToneCommand(portID byte, frequency uint16, dur
 	}
 }
 
+// EncodeHubActionCommand кодирует действие хаба, например выключение
+func (p *LPF2Protocol) EncodeHubActionCommand(action byte) []byte {
+	return []byte{action}
+}
+
 // EncodeStopPiezoToneCommand кодирует команду остановки пищалки
 func (p *LPF2Protocol) EncodeStopPiezoToneCommand(portID byte) []byte {
 	return []byte{
@@ -99,18 +127,3 @@ func (p *LPF2Protocol) EncodeStopPiezoToneCommand(portID byte) []byte {
 		0x00,   // dataLength
 	}
 }
-
-// Вспомогательные функции
-func maxFloat(a, b float64) float64 {
-	if a > b {
-		return a
-	}
-	return b
-}
-
-func minFloat(a, b float64) float64 {
-	if a < b {
-		return a
-	}
-	return b
-}
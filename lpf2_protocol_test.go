@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestEncodeMotorSpeedPinned(t *testing.T) {
+	tests := []struct {
+		power int8
+		want  byte
+	}{
+		{0, 0x00},
+		{1, 0x11},
+		{-1, 0xEF},
+		{50, 0x3A},
+		{-50, 0xC6},
+		{100, 0x64},
+		{-100, 0x9C},
+		{101, 0x64},
+		{-101, 0x9C},
+		{math.MaxInt8, 0x64},
+		{math.MinInt8, 0x9C},
+	}
+	for _, tt := range tests {
+		if got := protocol.EncodeMotorSpeed(tt.power); got != tt.want {
+			t.Errorf("EncodeMotorSpeed(%d) = %#02x, ожидалось %#02x", tt.power, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeMotorSpeedFullRange(t *testing.T) {
+	var previous byte
+	for power := math.MinInt8; power <= math.MaxInt8; power++ {
+		got := protocol.EncodeMotorSpeed(int8(power))
+
+		switch {
+		case power > 100:
+			if want := protocol.EncodeMotorSpeed(100); got != want {
+				t.Errorf("мощность %d не ограничена до 100: %#02x, ожидалось %#02x", power, got, want)
+			}
+		case power < -100:
+			if want := protocol.EncodeMotorSpeed(-100); got != want {
+				t.Errorf("мощность %d не ограничена до -100: %#02x, ожидалось %#02x", power, got, want)
+			}
+		case power > 0:
+			// Вперед: 0x11..0x64, скорость не убывает с мощностью
+			if got <= motorSpeedForward || got > motorSpeedForward+motorSpeedSpan {
+				t.Errorf("мощность %d: байт %#02x вне диапазона вперед", power, got)
+			}
+			if power > 1 && got < previous {
+				t.Errorf("мощность %d: байт %#02x меньше, чем для %d (%#02x)", power, got, power-1, previous)
+			}
+		case power < 0:
+			// Назад: 0xEF..0x9C, байт не растет с модулем мощности
+			if got >= motorSpeedReverse || got < motorSpeedReverse-motorSpeedSpan {
+				t.Errorf("мощность %d: байт %#02x вне диапазона назад", power, got)
+			}
+			if power > -100 && got < previous {
+				t.Errorf("мощность %d: байт %#02x меньше, чем для %d (%#02x)", power, got, power-1, previous)
+			}
+		default:
+			if got != 0x00 {
+				t.Errorf("мощность 0: байт %#02x, ожидался стоп 0x00", got)
+			}
+		}
+		previous = got
+	}
+}
+
+func TestEncodeMotorSpeedSymmetric(t *testing.T) {
+	for power := 1; power <= 100; power++ {
+		forward := protocol.EncodeMotorSpeed(int8(power)) - motorSpeedForward
+		reverse := motorSpeedReverse - protocol.EncodeMotorSpeed(int8(-power))
+		if forward != reverse {
+			t.Errorf("мощность ±%d: шаг вперед %d, назад %d", power, forward, reverse)
+		}
+	}
+}
+
+func TestEncodeMotorCommand(t *testing.T) {
+	for power := math.MinInt8; power <= math.MaxInt8; power++ {
+		for _, port := range []byte{1, 2} {
+			got := protocol.EncodeMotorCommand(port, int8(power))
+			want := []byte{port, 0x01, 0x01, protocol.EncodeMotorSpeed(int8(power))}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("EncodeMotorCommand(%d, %d) = % x, ожидалось % x", port, power, got, want)
+			}
+		}
+	}
+}
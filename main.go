@@ -1,37 +1,89 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 )
 
+// appWindowTitle заголовок главного окна
+const appWindowTitle = "WeDoProg - Визуальный программист WeDo 2.0"
+
 func main() {
+	crashReportDir := flag.String(crashReportFlag, "", "папка отчета о предыдущем сбое")
+	kioskSource := flag.String(kioskFlag, "", "режим экзамена: папка или файл программ, которые можно открыть и запустить")
+	flag.Parse()
+
+	// Паника в главном цикле сохраняет отчет о сбое и перезапускает приложение
+	reporter := NewCrashReporter()
+	defer reporter.Recover()
+
 	log.Println("=== Запуск WeDoProg - Программирование WeDo 2.0 ===")
 
-	// Создаем приложение
-	myApp := app.New()
+	// Создаем приложение (идентификатор нужен для сохранения настроек)
+	myApp := app.NewWithID("io.github.maxho82.wedoprog")
 	myApp.Settings().SetTheme(&CustomTheme{})
 
 	// Создаем главное окно
-	window := myApp.NewWindow("WeDoProg - Визуальный программист WeDo 2.0")
+	window := myApp.NewWindow(appWindowTitle)
 	window.SetMaster()
 	window.Resize(fyne.NewSize(1400, 900))
 
-	// Инициализируем менеджер хаба
-	hubMgr, err := NewHubManager()
-	if err != nil {
-		log.Fatalf("Ошибка инициализации хаба: %v", err)
+	// Инициализируем менеджер хаба на сохраненном адаптере. Если Bluetooth
+	// недоступен, окно объясняет причину и ждет повторной проверки
+	var hubMgr *HubManager
+	initHub := func() (err error) {
+		hubMgr, err = NewHubManager(myApp.Preferences().String(settingBLEAdapter))
+		return err
+	}
+	startGUI := func() {
+		startMainGUI(window, hubMgr, reporter, *kioskSource, *crashReportDir)
+	}
+	if err := initHub(); err != nil {
+		log.Printf("Ошибка инициализации хаба: %v", err)
+		showBLEPreflight(window, diagnoseBLEError(err), initHub, startGUI)
+	} else {
+		startGUI()
+	}
+	window.ShowAndRun()
+
+	// Отключаемся при выходе
+	if hubMgr != nil {
+		hubMgr.Disconnect()
+	}
+}
+
+// startMainGUI строит главное окно, когда менеджер хаба готов
+func startMainGUI(window fyne.Window, hubMgr *HubManager, reporter *CrashReporter, kioskSource, crashReportDir string) {
+	if err := hubMgr.MotorUsage().Load(fyne.CurrentApp().Preferences().String(settingMotorUsage)); err != nil {
+		log.Printf("Не удалось загрузить наработку моторов: %v", err)
 	}
 
 	// Создаем GUI
 	gui := NewMainGUI(window, hubMgr)
+	reporter.Attach(gui)
+	if kioskSource != "" {
+		kiosk, err := loadKioskMode(kioskSource)
+		if err != nil {
+			log.Fatalf("Ошибка режима экзамена: %v", err)
+		}
+		gui.kiosk = kiosk
+		log.Printf("Режим экзамена: программ в наборе %d", len(kiosk.Programs))
+	}
 
 	// Запускаем приложение
 	window.SetContent(gui.BuildUI())
-	window.ShowAndRun()
-
-	// Отключаемся при выходе
-	hubMgr.Disconnect()
+	if gui.kiosk != nil {
+		// Обучение и отчет о сбое с папкой на диске на экзамене не показываются
+		gui.openKioskProgram(gui.kiosk.Programs[0])
+	} else {
+		gui.scheduleFirstRunTutorial()
+		if crashReportDir != "" {
+			gui.showCrashReportDialog(crashReportDir)
+		}
+		gui.checkBluetoothReady()
+	}
+	gui.updateWindowTitle()
 }
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"image/color"
 	"log"
-	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -12,7 +11,6 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
-	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -22,49 +20,137 @@ type MainGUI struct {
 	hubMgr     *HubManager
 	deviceMgr  *DeviceManager
 	programMgr *ProgramManager
+	oscOutput  *OSCOutput
 
 	// Виджеты
-	statusLabel      *widget.Label
+	statusBar        *StatusBar
 	connectButton    *widget.Button
 	disconnectButton *widget.Button
 	toolbar          *Toolbar
 
+	// Сообщение поверх окна от блока "Показать на экране"
+	screenPopUp *widget.PopUp
+
 	// Панели
-	devicePanel     *fyne.Container
+	devicePanel     *DevicePanel
 	propertiesPanel *container.Scroll
+	blockEditor     *BlockEditor // Редактор в панели свойств, закрывается при смене содержимого
 	programPanel    *ProgramPanel
 	blocksPanel     *container.Scroll
+	leftPanel       *fyne.Container
+	leftSplit       *container.Split
+	rightSplit      *container.Split
+
+	// Режим презентации
+	toolbarContainer  *fyne.Container
+	presentationBar   *fyne.Container
+	presentationState *canvas.Text
+	presentationHub   *canvas.Text
+	presentationMode  bool
+	presentationStop  chan struct{}
+
+	// Главное меню
+	mainMenu            *fyne.MainMenu
+	devicePanelItem     *fyne.MenuItem
+	propertiesPanelItem *fyne.MenuItem
+	tidyUpItem          *fyne.MenuItem
+	wizardItem          *fyne.MenuItem
+	melodyItem          *fyne.MenuItem
+	openLinkItem        *fyne.MenuItem
+	newItem             *fyne.MenuItem
+	openItem            *fyne.MenuItem
+	openLessonItem      *fyne.MenuItem
+	recentItem          *fyne.MenuItem
+	undoItem            *fyne.MenuItem
+	copyItem            *fyne.MenuItem
+	pasteItem           *fyne.MenuItem
+	hubConnectItem      *fyne.MenuItem
+	hubDisconnectItem   *fyne.MenuItem
+	hubKitCheckItem     *fyne.MenuItem
 
 	// Динамические элементы
-	batteryProgress  *widget.ProgressBar
-	hubInfoContainer *fyne.Container
-	devicesContainer *fyne.Container
+	units     *UnitFormatter
+	announcer *Announcer
+	sounds    *SoundCues
+
+	// Открытое окно наблюдения
+	watchPanel *WatchPanel
+
+	// Отключение хаба после долгого бездействия
+	batterySaver *BatterySaver
+
+	// Последняя записанная или открытая запись датчиков
+	sensorRecording     *SensorRecording
+	sensorRecordingName string
+
+	// Запись сеансов запуска программы
+	sessions *SessionRecorder
+
+	// Учет хабов, которые подключались к этому компьютеру
+	inventory *HubInventory
+
+	// Выбранный профиль пользователя: от него зависят настройки и недавние программы
+	profile     Profile
+	profileItem *fyne.MenuItem
+
+	// Слой обучающего тура поверх интерфейса и текущий тур
+	coachLayer *fyne.Container
+	tour       *CoachTour
 
 	// Данные
-	connectedHub     *HubInfo
-	connectedDevices map[byte]*Device
-	availableBlocks  map[BlockType]bool
-	selectedBlock    *ProgramBlock
+	connectedHub    *HubInfo
+	availableBlocks map[BlockType]bool
+	selectedBlock   *ProgramBlock
+	currentFile     fyne.URI
+	history         UndoHistory
+	clipboardBlock  *copiedBlock
+
+	// Режим просмотра: программу можно только запускать
+	locked         bool
+	paletteButtons []*AccessibleButton
+
+	// Режим экзамена: nil, если приложение запущено без флага -kiosk
+	kiosk *KioskMode
 }
 
 // NewMainGUI создает новый GUI
 func NewMainGUI(window fyne.Window, hubMgr *HubManager) *MainGUI {
-	deviceMgr := NewDeviceManager(hubMgr)
+	deviceMgr := hubMgr.Devices()
 	programMgr := NewProgramManager(hubMgr, deviceMgr)
 
 	gui := &MainGUI{
-		window:           window,
-		hubMgr:           hubMgr,
-		deviceMgr:        deviceMgr,
-		programMgr:       programMgr,
-		connectedDevices: make(map[byte]*Device),
-		availableBlocks:  make(map[BlockType]bool),
+		window:          window,
+		hubMgr:          hubMgr,
+		deviceMgr:       deviceMgr,
+		programMgr:      programMgr,
+		oscOutput:       NewOSCOutput(hubMgr.Events()),
+		availableBlocks: make(map[BlockType]bool),
+		units:           NewUnitFormatter(DistanceUnitCM),
+		announcer:       NewAnnouncer(),
+		sounds:          NewSoundCues(),
+		inventory:       NewHubInventory(),
+		profile:         currentProfile(fyne.CurrentApp().Preferences()),
 	}
+	if err := gui.inventory.Load(gui.preferences().String(settingHubInventory)); err != nil {
+		log.Printf("Не удалось загрузить учет хабов: %v", err)
+	}
+
+	gui.batterySaver = NewBatterySaver(gui)
+	deviceMgr.SetManualActivityCallback(gui.batterySaver.Touch)
+	gui.sessions = NewSessionRecorder(hubMgr, programMgr)
 
 	hubMgr.SetBatteryUpdateCallback(gui.UpdateBatteryDisplay)
 	hubMgr.SetHubInfoUpdateCallback(gui.UpdateHubInfoDisplay)
-	hubMgr.SetDeviceUpdateCallback(gui.UpdateDeviceDisplay)
 	hubMgr.SetConnectionStateCallback(gui.updateConnectionStatus)
+	hubMgr.SetConnectionHealthCallback(gui.updateConnectionHealth)
+	programMgr.SetScreenMessageCallback(gui.showScreenMessage)
+	hubMgr.Events().Subscribe(EventProgramState, gui.onProgramState)
+	hubMgr.Events().Subscribe(EventProgramState, gui.clearLoopProgress)
+	hubMgr.Events().Subscribe(EventProgramState, gui.onSessionProgramState)
+	hubMgr.Events().Subscribe(EventLoopProgress, gui.onLoopProgress)
+	hubMgr.Events().Subscribe(EventVoltageSag, gui.onVoltageSag)
+
+	gui.applySettings()
 
 	return gui
 }
@@ -73,43 +159,67 @@ func NewMainGUI(window fyne.Window, hubMgr *HubManager) *MainGUI {
 func (gui *MainGUI) BuildUI() fyne.CanvasObject {
 	// Создаем панели
 	toolbar := gui.createToolbar()
-	gui.devicePanel = gui.createDevicePanel()
+	gui.devicePanel = NewDevicePanel(gui.deviceMgr, gui.units)
+	gui.devicePanel.SetDevicesChangedCallback(gui.onDevicesChanged)
+	if gui.kiosk == nil {
+		gui.devicePanel.SetDeviceOpenCallback(gui.showDeviceDetail)
+	}
+	gui.devicePanel.SetAnnounceCallback(gui.announce)
+	gui.devicePanel.SetIdentifyCallback(gui.identifyConnectedHub)
+	gui.devicePanel.SetPowerCallback(gui.showHubPowerDialog)
 	gui.propertiesPanel = gui.createPropertiesPanel()
 	gui.blocksPanel = gui.createBlocksPanel()
 	gui.programPanel = NewProgramPanel(gui, gui.programMgr)
 
 	// Левая панель: устройства + разделитель + блоки
-	leftPanel := container.NewVBox(
-		gui.devicePanel,
+	gui.leftPanel = container.NewVBox(
+		gui.devicePanel.GetContainer(),
 		canvas.NewLine(color.NRGBA{R: 60, G: 60, B: 60, A: 255}),
 		gui.blocksPanel,
 	)
 
 	// Используем Split для правильного ресайза
-	leftSplit := container.NewHSplit(leftPanel, gui.programPanel.GetContainer())
-	leftSplit.SetOffset(0.25)
+	gui.leftSplit = container.NewHSplit(gui.leftPanel, gui.programPanel.GetContainer())
+	gui.leftSplit.SetOffset(defaultLeftSplitOffset)
 
-	rightSplit := container.NewHSplit(leftSplit, gui.propertiesPanel)
-	rightSplit.SetOffset(0.75)
+	gui.rightSplit = container.NewHSplit(gui.leftSplit, gui.propertiesPanel)
+	gui.rightSplit.SetOffset(defaultRightSplitOffset)
 
 	// Основной макет
-	mainContainer := container.NewBorder(
-		toolbar,
-		nil,
-		nil,
-		nil,
-		rightSplit,
-	)
-
-	// Настраиваем горячие клавиши
+	gui.toolbarContainer = toolbar
+	gui.presentationBar = gui.createPresentationBar()
+	gui.statusBar = NewStatusBar(gui)
+	// Объекты перечислены в порядке обхода клавишей Tab: панель инструментов,
+	// устройства и палитра, холст, свойства, строка состояния
+	top := container.NewVBox(toolbar, gui.presentationBar)
+	bottom := container.NewVBox(widget.NewSeparator(), gui.statusBar.GetContainer())
+	mainContainer := container.New(layout.NewBorderLayout(top, bottom, nil, nil), top, gui.rightSplit, bottom)
+
+	// Настраиваем горячие клавиши и меню
 	gui.setupKeyboardShortcuts()
+	gui.setupMainMenu()
+
+	// Восстанавливаем расположение панелей и сохраняем его при закрытии окна
+	gui.restoreLayout()
+	gui.locked = gui.kiosk != nil || gui.preferences().Bool(settingProgramLocked)
+	gui.applyLockState()
+	gui.window.SetCloseIntercept(func() {
+		gui.saveLayout()
+		gui.statusBar.Stop()
+		gui.hubMgr.MotorUsage().StopAll()
+		gui.saveMotorUsage()
+		gui.sounds.Close()
+		gui.window.Close()
+	})
 
-	return mainContainer
+	// Слой тура пуст, пока обучение не запущено
+	gui.coachLayer = container.NewWithoutLayout()
+	return container.NewStack(mainContainer, gui.coachLayer)
 }
 
 // deleteSelectedBlock удаляет выбранный блок
 func (gui *MainGUI) deleteSelectedBlock() {
-	if gui.selectedBlock == nil {
+	if gui.selectedBlock == nil || gui.readOnly() {
 		return
 	}
 
@@ -121,6 +231,7 @@ func (gui *MainGUI) deleteSelectedBlock() {
 		func(confirmed bool) {
 			if confirmed {
 				log.Printf("Начинаем удаление блока %d", blockID)
+				gui.checkpoint()
 
 				// Удаляем блок из менеджера программ
 				success := gui.programMgr.RemoveBlock(blockID)
@@ -147,13 +258,69 @@ func (gui *MainGUI) deleteSelectedBlock() {
 		}, gui.window)
 }
 
+// confirmClearProgram спрашивает подтверждение и очищает программу.
+// Очистку можно отменить через "Правка → Отменить"
+func (gui *MainGUI) confirmClearProgram() {
+	if gui.readOnly() {
+		return
+	}
+	if gui.programMgr.GetProgramState() == ProgramStateRunning {
+		dialog.ShowInformation("Очистить программу", "Сначала остановите выполняющуюся программу.", gui.window)
+		return
+	}
+
+	blocks := len(gui.programMgr.GetProgram().Blocks)
+	if blocks == 0 {
+		return
+	}
+
+	dialog.ShowConfirm("Очистить программу",
+		fmt.Sprintf("Удалить все блоки программы (%d)?\nОчистку можно отменить: Правка → Отменить.", blocks),
+		func(confirmed bool) {
+			if confirmed {
+				gui.clearProgram()
+			}
+		}, gui.window)
+}
+
+// clearProgram удаляет все блоки из программы и с холста за один шаг
+func (gui *MainGUI) clearProgram() {
+	gui.checkpoint()
+
+	gui.programMgr.ClearProgram()
+	gui.programPanel.Clear()
+	gui.selectedBlock = nil
+	gui.clearPropertiesPanel()
+	gui.updateToolbarState(gui.hubMgr.IsConnected(), false)
+}
+
+// openProgram заменяет текущую программу открытой и перестраивает холст
+func (gui *MainGUI) openProgram(program *Program) {
+	gui.programMgr.LoadProgram(program)
+	gui.selectedBlock = nil
+	gui.programPanel.ShowProgram()
+
+	// Программа только для запуска отключает палитру и редактирование
+	gui.applyLockState()
+}
+
+// closeBlockEditor закрывает редактор блока, показанный в панели свойств
+func (gui *MainGUI) closeBlockEditor() {
+	if gui.blockEditor != nil {
+		gui.blockEditor.Close()
+		gui.blockEditor = nil
+	}
+}
+
 // clearPropertiesPanel очищает панель свойств
 func (gui *MainGUI) clearPropertiesPanel() {
+	gui.closeBlockEditor()
 	if gui.propertiesPanel != nil {
 		container, ok := gui.propertiesPanel.Content.(*fyne.Container)
 		if ok {
 			container.Objects = nil
 			container.Add(widget.NewLabel("Выберите элемент для просмотра свойств"))
+			container.Add(gui.createProgramPropertiesView())
 			container.Refresh()
 			gui.propertiesPanel.Refresh()
 		}
@@ -175,10 +342,40 @@ func (gui *MainGUI) createPropertiesPanel() *container.Scroll {
 		widget.NewLabelWithStyle("Свойства", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewSeparator(),
 		widget.NewLabel("Выберите элемент для просмотра свойств"),
+		gui.createProgramPropertiesView(),
 	)
 	return container.NewVScroll(content)
 }
 
+// createProgramPropertiesView создает редактор свойств всей программы
+func (gui *MainGUI) createProgramPropertiesView() fyne.CanvasObject {
+	program := gui.programMgr.GetProgram()
+
+	seedEntry := NewNumberSpinner(0, maxRandomSeed, 1, 0, float64(program.RandomSeed), func(value float64) {
+		program.RandomSeed = int64(value)
+		program.Modified = time.Now()
+	})
+
+	if gui.readOnly() {
+		seedEntry.Disable()
+	}
+
+	seedHint := widget.NewLabel("С одинаковым зерном случайные значения повторяются при каждом запуске, 0 — каждый запуск разный")
+	seedHint.Wrapping = fyne.TextWrapWord
+
+	view := container.NewVBox(
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Программа", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+	)
+	if program.RunOnly {
+		view.Add(gui.createRunOnlyNotice(program))
+	}
+	view.Add(widget.NewLabel("Зерно случайных чисел:"))
+	view.Add(seedEntry)
+	view.Add(seedHint)
+	return view
+}
+
 // createBlocksPanel создает панель блоков программирования
 func (gui *MainGUI) createBlocksPanel() *container.Scroll {
 	blocksContainer := container.NewVBox()
@@ -191,39 +388,31 @@ func (gui *MainGUI) createBlocksPanel() *container.Scroll {
 	blocksContainer.Add(container.NewCenter(title))
 	blocksContainer.Add(widget.NewSeparator())
 
-	// Категории блоков
-	categories := []struct {
-		name   string
-		blocks []BlockType
-	}{
-		{"Управление", []BlockType{BlockTypeStart, BlockTypeWait, BlockTypeLoop, BlockTypeStop}},
-		{"Действия", []BlockType{BlockTypeMotor, BlockTypeLED, BlockTypeSound}},
-		{"Датчики", []BlockType{BlockTypeTiltSensor, BlockTypeDistanceSensor, BlockTypeVoltageSensor, BlockTypeCurrentSensor}},
-		{"Логика", []BlockType{BlockTypeCondition}},
-	}
-
-	for _, category := range categories {
+	for _, category := range blockCategories {
 		// Заголовок категории
-		categoryLabel := canvas.NewText(category.name, color.NRGBA{R: 200, G: 200, B: 200, A: 255})
+		categoryLabel := canvas.NewText(category.Name, color.NRGBA{R: 200, G: 200, B: 200, A: 255})
 		categoryLabel.TextSize = 14
 		categoryLabel.TextStyle.Bold = true
 		blocksContainer.Add(categoryLabel)
 
 		// Блоки в категории
-		for _, blockType := range category.blocks {
-			blockName := gui.getBlockName(blockType)
-			blockButton := widget.NewButton(blockName, func(bt BlockType) func() {
+		for _, blockType := range category.Blocks {
+			blockButton := NewPaletteButton(gui, blockType, func(bt BlockType) func() {
 				return func() {
+					gui.checkpoint()
 					block := gui.programMgr.CreateBlock(bt, 100, 100)
 					gui.programPanel.AddBlock(block)
 					hasProgram := len(gui.programMgr.program.Blocks) > 0
 					gui.updateToolbarState(gui.hubMgr.IsConnected(), hasProgram)
 					log.Printf("Добавлен новый блок: %s (ID: %d)", block.Title, block.ID)
+					gui.announce("Добавлен " + blockAccessibleName(block, false))
+					gui.sounds.Play(SoundBlockAdded)
 				}
 			}(blockType))
 
 			blockButton.Importance = widget.LowImportance
 			blocksContainer.Add(blockButton)
+			gui.paletteButtons = append(gui.paletteButtons, &blockButton.AccessibleButton)
 		}
 
 		blocksContainer.Add(widget.NewSeparator())
@@ -261,6 +450,30 @@ func (gui *MainGUI) getBlockName(blockType BlockType) string {
 		return "Датчик тока"
 	case BlockTypeStop:
 		return "Стоп"
+	case BlockTypeWaitUntil:
+		return "Ждать пока"
+	case BlockTypeResetTimer:
+		return "Сбросить таймер"
+	case BlockTypeBroadcast:
+		return "Отправить сообщение"
+	case BlockTypeReceive:
+		return "Когда получено сообщение"
+	case BlockTypeScreen:
+		return "Показать на экране"
+	case BlockTypeWhenMotion:
+		return "Когда камера видит движение"
+	case BlockTypeWhenColor:
+		return "Когда камера видит цвет"
+	case BlockTypeWhenHear:
+		return "Когда слышу слово"
+	case BlockTypeFollow:
+		return "Держать расстояние"
+	case BlockTypeHubPower:
+		return "Питание хаба"
+	case BlockTypeLightMusic:
+		return "Светомузыка"
+	case BlockTypeWhenBumped:
+		return "Когда удар"
 	default:
 		return "Неизвестный блок"
 	}
@@ -268,18 +481,29 @@ func (gui *MainGUI) getBlockName(blockType BlockType) string {
 
 // showBlockProperties показывает свойства выбранного блока
 func (gui *MainGUI) showBlockProperties(block *ProgramBlock) {
+	gui.batterySaver.Touch()
 	gui.selectedBlock = block
 	gui.programPanel.SetSelectedBlock(block)
+	gui.closeBlockEditor()
 
 	if gui.propertiesPanel != nil {
 		container, ok := gui.propertiesPanel.Content.(*fyne.Container)
 		if ok {
 			container.Objects = nil
 
-			editor := NewBlockEditor(block, gui.deviceMgr, gui.window, func(updatedBlock *ProgramBlock) {
+			if gui.readOnly() {
+				container.Add(gui.createReadOnlyBlockView(block))
+				container.Refresh()
+				gui.propertiesPanel.Refresh()
+				return
+			}
+
+			editor := NewBlockEditor(block, gui.deviceMgr, gui.programMgr, gui.window, func(updatedBlock *ProgramBlock) {
 				gui.programMgr.UpdateBlock(updatedBlock.ID, updatedBlock.Parameters)
 				log.Printf("Параметры блока %d обновлены", updatedBlock.ID)
+				gui.refreshToolbarState()
 			})
+			gui.blockEditor = editor
 
 			container.Add(editor.GetContainer())
 			container.Refresh()
@@ -288,6 +512,43 @@ func (gui *MainGUI) showBlockProperties(block *ProgramBlock) {
 	}
 }
 
+// showConnectionProperties показывает выбранное соединение и подсказку о вставке блоков
+func (gui *MainGUI) showConnectionProperties(fromBlockID, toBlockID int) {
+	gui.selectedBlock = nil
+	gui.closeBlockEditor()
+	for _, obj := range gui.programPanel.content.Objects {
+		if block, ok := obj.(*DraggableBlock); ok && block.isSelected {
+			block.deselect()
+		}
+	}
+
+	if gui.propertiesPanel == nil {
+		return
+	}
+	container, ok := gui.propertiesPanel.Content.(*fyne.Container)
+	if !ok {
+		return
+	}
+
+	describe := func(blockID int) string {
+		if block, exists := gui.programMgr.GetBlock(blockID); exists {
+			return fmt.Sprintf("%s (ID: %d)", block.Title, block.ID)
+		}
+		return fmt.Sprintf("ID: %d", blockID)
+	}
+
+	hint := widget.NewLabel("Блоки, добавленные из палитры, будут вставлены в это соединение. Щелкните по соединению еще раз, чтобы снять выбор.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	container.Objects = nil
+	container.Add(widget.NewLabelWithStyle("Соединение", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	container.Add(widget.NewLabel("Из: " + describe(fromBlockID)))
+	container.Add(widget.NewLabel("В: " + describe(toBlockID)))
+	container.Add(hint)
+	container.Refresh()
+	gui.propertiesPanel.Refresh()
+}
+
 // showHubDiscoveryDialog показывает диалог поиска хаба
 func (gui *MainGUI) showHubDiscoveryDialog() {
 	progress := dialog.NewProgressInfinite("Поиск WeDo 2.0 хаба", "Сканирование...", gui.window)
@@ -300,7 +561,7 @@ func (gui *MainGUI) showHubDiscoveryDialog() {
 			progress.Hide()
 
 			if err != nil {
-				dialog.ShowError(err, gui.window)
+				gui.showBLEIssue(diagnoseBLEError(err), gui.showHubDiscoveryDialog)
 				return
 			}
 
@@ -311,27 +572,56 @@ func (gui *MainGUI) showHubDiscoveryDialog() {
 				return
 			}
 
-			items := make([]string, len(hubs))
-			for i, hub := range hubs {
-				items[i] = fmt.Sprintf("%s [%s]", hub.Name, hub.Address)
-			}
-
-			list := widget.NewSelect(items, func(selected string) {
-				if selected != "" {
-					parts := strings.Split(selected, " [")
-					if len(parts) > 1 {
-						address := strings.TrimSuffix(parts[1], "]")
-						gui.connectToHub(address)
+			// Каждый найденный хаб раскрывается в подробности: сигнал, службы, кнопка и тип
+			var selectDialog *dialog.CustomDialog
+			list := widget.NewAccordion()
+			empty := widget.NewLabel("Нет хабов в режиме подключения.\nНажмите кнопку на нужном хабе и повторите поиск.")
+			showHubs := func(pairingOnly bool) {
+				list.Items = nil
+				for _, hub := range hubs {
+					if pairingOnly && !hub.Pairing {
+						continue
+					}
+					address := hub.Address
+					title := fmt.Sprintf("%s [%s] %d дБм", hub.Name, hub.Address, hub.RSSI)
+					if hub.Pairing {
+						title += " — режим подключения"
 					}
+					if !gui.hubMgr.HubAllowed(address) {
+						title += " — не разрешен"
+					}
+					list.Append(widget.NewAccordionItem(title, gui.newScanResultDetails(hub, func() {
+						selectDialog.Hide()
+						gui.connectToHub(address)
+					})))
 				}
+				if len(list.Items) == 1 {
+					list.Open(0)
+				}
+				list.Refresh()
+				if len(list.Items) == 0 {
+					empty.Show()
+				} else {
+					empty.Hide()
+				}
+			}
+
+			// В классе с десятком хабов фильтр оставляет только тот, на котором нажали кнопку
+			pairingOnly := widget.NewCheck("Только хабы в режиме подключения", func(checked bool) {
+				gui.preferences().SetBool(settingPairingOnly, checked)
+				showHubs(checked)
 			})
+			pairingOnly.Checked = gui.preferences().Bool(settingPairingOnly)
+			showHubs(pairingOnly.Checked)
 
 			content := container.NewVBox(
 				widget.NewLabel("Выберите хаб для подключения:"),
+				pairingOnly,
 				list,
+				empty,
 			)
 
-			selectDialog := dialog.NewCustom("Выбор хаба", "Закрыть", content, gui.window)
+			selectDialog = dialog.NewCustom("Выбор хаба", "Закрыть", content, gui.window)
 			selectDialog.Show()
 		})
 	}()
@@ -349,8 +639,10 @@ func (gui *MainGUI) connectToHub(address string) {
 			progress.Hide()
 
 			if err != nil {
+				gui.statusBar.ReportError(err)
 				dialog.ShowError(err, gui.window)
 			} else {
+				gui.preferences().SetString(settingLastHubAddress, address)
 				gui.updateConnectionStatus(true)
 				dialog.ShowInformation("Успешно", "Подключение установлено!", gui.window)
 
@@ -363,11 +655,8 @@ func (gui *MainGUI) connectToHub(address string) {
 					}
 
 					time.Sleep(2 * time.Second)
-					fyne.Do(func() {
-						gui.updateDeviceList()
-						gui.updateAvailableBlocks()
-						gui.ForceUpdateUI()
-					})
+					gui.ForceUpdateUI()
+					fyne.Do(gui.offerKitCheck)
 				}()
 			}
 		})
@@ -378,30 +667,45 @@ func (gui *MainGUI) connectToHub(address string) {
 func (gui *MainGUI) updateConnectionStatus(isConnected bool) {
 	fyne.Do(func() {
 		if isConnected {
-			gui.statusLabel.SetText("Подключено ✓")
+			gui.statusBar.SetConnectionText("Подключено")
 			gui.connectButton.Disable()
 			gui.disconnectButton.Enable()
 		} else {
-			gui.statusLabel.SetText("Не подключено")
+			gui.statusBar.SetConnectionText("Не подключено")
 			gui.connectButton.Enable()
 			gui.disconnectButton.Disable()
 			gui.connectedHub = nil
-			gui.connectedDevices = make(map[byte]*Device)
-			gui.clearDeviceDisplay()
+			gui.devicePanel.Clear()
+			gui.saveMotorUsage()
+			gui.updateAvailableBlocks()
 		}
 
-		gui.statusLabel.Refresh()
 		gui.connectButton.Refresh()
 		gui.disconnectButton.Refresh()
+		gui.updateHubMenu(isConnected)
+	})
+}
+
+// updateConnectionHealth показывает потерю связи с хабом и ее восстановление
+func (gui *MainGUI) updateConnectionHealth(healthy bool) {
+	fyne.Do(func() {
+		if healthy {
+			gui.statusBar.SetConnectionText("Подключено (связь восстановлена)")
+		} else {
+			gui.statusBar.SetConnectionText("Связь потеряна, переподключение...")
+			gui.statusBar.ReportError(fmt.Errorf("связь с хабом потеряна"))
+		}
 	})
 }
 
 // UpdateBatteryDisplay обновляет отображение батареи
 func (gui *MainGUI) UpdateBatteryDisplay(batteryLevel int) {
 	fyne.Do(func() {
-		if gui.batteryProgress != nil {
-			gui.batteryProgress.SetValue(float64(batteryLevel) / 100)
-			gui.batteryProgress.Refresh()
+		if gui.devicePanel != nil {
+			gui.devicePanel.SetBattery(batteryLevel)
+		}
+		if gui.connectedHub != nil {
+			gui.recordHubInventory(*gui.connectedHub, batteryLevel)
 		}
 	})
 }
@@ -410,226 +714,24 @@ func (gui *MainGUI) UpdateBatteryDisplay(batteryLevel int) {
 func (gui *MainGUI) UpdateHubInfoDisplay(info *HubInfo) {
 	fyne.Do(func() {
 		gui.connectedHub = info
-		gui.updateHubInfoUI(info)
-	})
-}
-
-// UpdateDeviceDisplay обновляет отображение устройств
-func (gui *MainGUI) UpdateDeviceDisplay(portID byte, device *Device) {
-	log.Printf("UpdateDeviceDisplay: порт %d, устройство: %s, подключено: %v",
-		portID, device.Name, device.IsConnected)
-
-	fyne.Do(func() {
-		gui.connectedDevices[portID] = device
-		gui.updateAvailableBlocks()
-		gui.updateDeviceList()
-	})
-}
-
-// createDevicePanel создает панель устройств
-func (gui *MainGUI) createDevicePanel() *fyne.Container {
-	mainContainer := container.NewVBox()
-
-	// Заголовок
-	title := canvas.NewText("Информация о хабе", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
-	title.TextSize = 16
-	title.TextStyle.Bold = true
-	mainContainer.Add(container.NewCenter(title))
-	mainContainer.Add(widget.NewSeparator())
-
-	// Батарея
-	batteryContainer := gui.createBatteryWidget()
-	mainContainer.Add(batteryContainer)
-	mainContainer.Add(widget.NewSeparator())
-
-	// Информация о хабе
-	hubTitle := canvas.NewText("Хаб", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
-	hubTitle.TextSize = 14
-	hubTitle.TextStyle.Bold = true
-	mainContainer.Add(container.NewCenter(hubTitle))
-
-	gui.hubInfoContainer = container.NewVBox()
-	mainContainer.Add(gui.hubInfoContainer)
-	mainContainer.Add(widget.NewSeparator())
-
-	// Подключенные устройства
-	devicesTitle := canvas.NewText("Подключенные устройства", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
-	devicesTitle.TextSize = 14
-	devicesTitle.TextStyle.Bold = true
-	mainContainer.Add(container.NewCenter(devicesTitle))
-
-	gui.devicesContainer = container.NewVBox()
-	mainContainer.Add(gui.devicesContainer)
-
-	// Кнопка синхронизации
-	syncButton := widget.NewButton("Синхронизировать устройства", func() {
-		log.Println("Ручная синхронизация устройств...")
-		go func() {
-			if gui.deviceMgr != nil {
-				gui.deviceMgr.SyncDevices()
-			}
-			time.Sleep(500 * time.Millisecond)
-			fyne.Do(func() {
-				gui.updateDeviceList()
-				gui.updateAvailableBlocks()
-			})
-		}()
-	})
-	syncButton.Importance = widget.MediumImportance
-	mainContainer.Add(syncButton)
-
-	return mainContainer
-}
-
-// createBatteryWidget создает виджет батареи
-func (gui *MainGUI) createBatteryWidget() *fyne.Container {
-	title := canvas.NewText("Батарея", color.NRGBA{R: 240, G: 240, B: 240, A: 255})
-	title.TextSize = 14
-	title.TextStyle.Bold = true
-
-	gui.batteryProgress = widget.NewProgressBar()
-	gui.batteryProgress.Min = 0
-	gui.batteryProgress.Max = 1
-	gui.batteryProgress.SetValue(0)
-	gui.batteryProgress.TextFormatter = func() string {
-		if gui.batteryProgress.Value <= 0 {
-			return "--%"
+		if gui.devicePanel != nil {
+			gui.devicePanel.SetHubInfo(info)
 		}
-		return fmt.Sprintf("%.0f%%", gui.batteryProgress.Value*100)
-	}
-
-	return container.NewVBox(
-		container.NewCenter(title),
-		gui.batteryProgress,
-	)
-}
-
-// updateHubInfoUI обновляет информацию о хабе в UI
-func (gui *MainGUI) updateHubInfoUI(info *HubInfo) {
-	if gui.hubInfoContainer == nil {
-		return
-	}
-
-	gui.hubInfoContainer.Objects = nil
-
-	nameLabel := widget.NewLabel(fmt.Sprintf("Имя: %s", info.Name))
-	gui.hubInfoContainer.Add(nameLabel)
-
-	addressLabel := widget.NewLabel(fmt.Sprintf("Адрес: %s", info.Address))
-	gui.hubInfoContainer.Add(addressLabel)
-
-	if info.Manufacturer != "" {
-		manufacturerLabel := widget.NewLabel(fmt.Sprintf("Производитель: %s", info.Manufacturer))
-		gui.hubInfoContainer.Add(manufacturerLabel)
-	}
-
-	if info.FirmwareVersion != "" {
-		firmwareLabel := widget.NewLabel(fmt.Sprintf("Прошивка: %s", info.FirmwareVersion))
-		gui.hubInfoContainer.Add(firmwareLabel)
-	}
-
-	if info.SoftwareVersion != "" {
-		softwareLabel := widget.NewLabel(fmt.Sprintf("Софт: %s", info.SoftwareVersion))
-		gui.hubInfoContainer.Add(softwareLabel)
-	}
-
-	gui.hubInfoContainer.Refresh()
-}
-
-// updateDeviceList обновляет список устройств
-func (gui *MainGUI) updateDeviceList() {
-	if gui.devicesContainer == nil {
-		return
-	}
-
-	log.Printf("Обновление списка устройств. Всего: %d", len(gui.connectedDevices))
-
-	gui.devicesContainer.Objects = nil
-
-	if len(gui.connectedDevices) == 0 {
-		noDevicesLabel := widget.NewLabel("Нет подключенных устройств")
-		noDevicesLabel.Alignment = fyne.TextAlignCenter
-		noDevicesLabel.TextStyle.Italic = true
-		gui.devicesContainer.Add(noDevicesLabel)
-	} else {
-		connectedCount := 0
-		for portID, device := range gui.connectedDevices {
-			if device.IsConnected {
-				connectedCount++
-				deviceCard := gui.createDeviceCard(portID, device)
-				gui.devicesContainer.Add(deviceCard)
-			}
-		}
-
-		if connectedCount == 0 {
-			noDevicesLabel := widget.NewLabel("Все устройства отключены")
-			noDevicesLabel.Alignment = fyne.TextAlignCenter
-			noDevicesLabel.TextStyle.Italic = true
-			gui.devicesContainer.Add(noDevicesLabel)
-		}
-	}
-
-	gui.devicesContainer.Refresh()
-}
-
-// createDeviceCard создает карточку устройства
-func (gui *MainGUI) createDeviceCard(portID byte, device *Device) *fyne.Container {
-	var iconRes fyne.Resource
-	switch device.DeviceType {
-	case DEVICE_TYPE_MOTOR:
-		iconRes = theme.StorageIcon()
-	case DEVICE_TYPE_RGB_LIGHT:
-		iconRes = theme.VisibilityIcon()
-	case DEVICE_TYPE_TILT_SENSOR:
-		iconRes = theme.ViewRefreshIcon()
-	case DEVICE_TYPE_MOTION_SENSOR:
-		iconRes = theme.MoveDownIcon()
-	case DEVICE_TYPE_PIEZO_TONE:
-		iconRes = theme.MediaFastForwardIcon()
-	default:
-		iconRes = theme.ComputerIcon()
-	}
-
-	icon := widget.NewIcon(iconRes)
-	info := widget.NewLabel(fmt.Sprintf("Порт %d: %s", portID, device.Name))
-	info.TextStyle.Bold = true
-
-	status := widget.NewLabel("✓ Подключено")
-	status.TextStyle.Italic = true
-
-	return container.NewVBox(
-		container.NewHBox(
-			icon,
-			info,
-			layout.NewSpacer(),
-			status,
-		),
-		widget.NewSeparator(),
-	)
+		gui.recordHubInventory(*info, -1)
+		gui.applyDistanceCalibration()
+	})
 }
 
-// clearDeviceDisplay очищает отображение устройств
-func (gui *MainGUI) clearDeviceDisplay() {
-	if gui.hubInfoContainer != nil {
-		gui.hubInfoContainer.Objects = nil
-		gui.hubInfoContainer.Refresh()
-	}
-
-	if gui.devicesContainer != nil {
-		gui.devicesContainer.Objects = nil
-		gui.devicesContainer.Refresh()
-	}
-
-	if gui.batteryProgress != nil {
-		gui.batteryProgress.SetValue(0)
-		gui.batteryProgress.Refresh()
-	}
+// onDevicesChanged пересчитывает доступные блоки и кнопки после изменения устройств
+func (gui *MainGUI) onDevicesChanged() {
+	gui.updateAvailableBlocks()
+	gui.refreshToolbarState()
 }
 
 // updateAvailableBlocks обновляет доступные блоки программирования
 func (gui *MainGUI) updateAvailableBlocks() {
 	// Сбрасываем все блоки
-	for blockType := BlockTypeStart; blockType <= BlockTypeStop; blockType++ {
+	for blockType := BlockTypeStart; blockType <= BlockTypeWhenBumped; blockType++ {
 		gui.availableBlocks[blockType] = false
 	}
 
@@ -639,24 +741,32 @@ func (gui *MainGUI) updateAvailableBlocks() {
 	gui.availableBlocks[BlockTypeLoop] = true
 	gui.availableBlocks[BlockTypeStop] = true
 	gui.availableBlocks[BlockTypeCondition] = true
+	gui.availableBlocks[BlockTypeWaitUntil] = true
+	gui.availableBlocks[BlockTypeResetTimer] = true
+	gui.availableBlocks[BlockTypeHubPower] = true
+	gui.availableBlocks[BlockTypeBroadcast] = true
+	gui.availableBlocks[BlockTypeReceive] = true
+	gui.availableBlocks[BlockTypeScreen] = true
+	gui.availableBlocks[BlockTypeWhenMotion] = true
+	gui.availableBlocks[BlockTypeWhenColor] = true
+	gui.availableBlocks[BlockTypeWhenHear] = true
 
 	// Активируем блоки в зависимости от подключенных устройств
-	for _, device := range gui.connectedDevices {
-		if !device.IsConnected {
-			continue
-		}
-
+	for _, device := range gui.deviceMgr.GetConnectedDevices() {
 		switch device.DeviceType {
 		case DEVICE_TYPE_MOTOR:
 			gui.availableBlocks[BlockTypeMotor] = true
+			gui.availableBlocks[BlockTypeFollow] = true
 		case DEVICE_TYPE_RGB_LIGHT:
 			gui.availableBlocks[BlockTypeLED] = true
 		case DEVICE_TYPE_TILT_SENSOR:
 			gui.availableBlocks[BlockTypeTiltSensor] = true
+			gui.availableBlocks[BlockTypeWhenBumped] = true
 		case DEVICE_TYPE_MOTION_SENSOR:
 			gui.availableBlocks[BlockTypeDistanceSensor] = true
 		case DEVICE_TYPE_PIEZO_TONE:
 			gui.availableBlocks[BlockTypeSound] = true
+			gui.availableBlocks[BlockTypeLightMusic] = true
 		case DEVICE_TYPE_VOLTAGE:
 			gui.availableBlocks[BlockTypeVoltageSensor] = true
 		case DEVICE_TYPE_CURRENT:
@@ -681,13 +791,9 @@ func (gui *MainGUI) ForceUpdateUI() {
 				gui.UpdateBatteryDisplay(hubInfo.Battery)
 			}
 
-			gui.updateDeviceList()
-			gui.updateAvailableBlocks()
-		} else {
-			gui.clearDeviceDisplay()
-			gui.connectedDevices = make(map[byte]*Device)
-			gui.availableBlocks = make(map[BlockType]bool)
+			gui.devicePanel.Refresh()
 		}
+		gui.updateAvailableBlocks()
 
 		hasProgram := len(gui.programMgr.program.Blocks) > 0
 		if gui.toolbar != nil {
@@ -696,6 +802,11 @@ func (gui *MainGUI) ForceUpdateUI() {
 	})
 }
 
+// refreshToolbarState пересчитывает состояние кнопок по текущей программе и подключению
+func (gui *MainGUI) refreshToolbarState() {
+	gui.updateToolbarState(gui.hubMgr.IsConnected(), len(gui.programMgr.GetProgram().Blocks) > 0)
+}
+
 func (gui *MainGUI) updateToolbarState(isConnected bool, hasProgram bool) {
 	if gui.toolbar != nil {
 		gui.toolbar.UpdateState(isConnected, hasProgram)
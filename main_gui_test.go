@@ -0,0 +1,222 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+// newTestGUI строит главное окно на тестовом драйвере Fyne без адаптера Bluetooth
+func newTestGUI(t *testing.T) *MainGUI {
+	t.Helper()
+
+	test.NewTempApp(t)
+	window := test.NewTempWindow(t, nil)
+	gui := NewMainGUI(window, newHubManager(nil, ""))
+	window.SetContent(gui.BuildUI())
+	t.Cleanup(gui.statusBar.Stop)
+	window.Resize(fyne.NewSize(1280, 800))
+	return gui
+}
+
+// findAll возвращает все объекты типа T внутри root, включая содержимое виджетов
+func findAll[T fyne.CanvasObject](root fyne.CanvasObject) []T {
+	var found []T
+	for _, o := range test.LaidOutObjects(root) {
+		if typed, ok := o.(T); ok {
+			found = append(found, typed)
+		}
+	}
+	return found
+}
+
+// paletteButton находит кнопку палитры для типа блока
+func paletteButton(t *testing.T, gui *MainGUI, blockType BlockType) *PaletteButton {
+	t.Helper()
+
+	for _, button := range findAll[*PaletteButton](gui.blocksPanel) {
+		if button.blockType == blockType {
+			return button
+		}
+	}
+	t.Fatalf("в палитре нет кнопки блока %d", blockType)
+	return nil
+}
+
+// answerConfirm нажимает в верхнем диалоге окна кнопку подтверждения или отказа
+func answerConfirm(t *testing.T, gui *MainGUI, confirm bool) {
+	t.Helper()
+
+	top := gui.window.Canvas().Overlays().Top()
+	if top == nil {
+		t.Fatal("диалог подтверждения не показан")
+	}
+	for _, button := range findAll[*widget.Button](top) {
+		// Кнопка подтверждения выделена, кнопка отказа — обычная
+		if (button.Importance == widget.HighImportance) == confirm {
+			test.Tap(button)
+			return
+		}
+	}
+	t.Fatal("в диалоге нет нужной кнопки")
+}
+
+// waitSlider находит ползунок длительности в редакторе блока «Ждать»
+func waitSlider(t *testing.T, gui *MainGUI) *widget.Slider {
+	t.Helper()
+
+	sliders := findAll[*widget.Slider](gui.propertiesPanel)
+	if len(sliders) == 0 {
+		t.Fatal("в панели свойств нет ползунка")
+	}
+	return sliders[0]
+}
+
+func TestPaletteAddsBlock(t *testing.T) {
+	gui := newTestGUI(t)
+
+	test.Tap(paletteButton(t, gui, BlockTypeMotor))
+	test.Tap(paletteButton(t, gui, BlockTypeLED))
+
+	blocks := gui.programMgr.GetProgram().Blocks
+	if len(blocks) != 2 {
+		t.Fatalf("в программе %d блоков, ожидалось 2", len(blocks))
+	}
+	if blocks[0].Type != BlockTypeMotor || blocks[1].Type != BlockTypeLED {
+		t.Errorf("типы блоков %d, %d", blocks[0].Type, blocks[1].Type)
+	}
+	for _, block := range blocks {
+		if _, ok := gui.programPanel.blockWidgets[block.ID]; !ok {
+			t.Errorf("блок %d не появился на холсте", block.ID)
+		}
+	}
+	if !gui.history.CanUndo() {
+		t.Error("добавление блока нельзя отменить")
+	}
+}
+
+func TestDeleteBlockAsksForConfirmation(t *testing.T) {
+	gui := newTestGUI(t)
+	test.Tap(paletteButton(t, gui, BlockTypeMotor))
+	block := gui.programMgr.GetProgram().Blocks[0]
+	gui.showBlockProperties(block)
+
+	gui.deleteSelectedBlock()
+	answerConfirm(t, gui, false)
+	if _, ok := gui.programMgr.GetBlock(block.ID); !ok {
+		t.Fatal("блок удален без подтверждения")
+	}
+	if gui.selectedBlock != block {
+		t.Error("отказ от удаления сбросил выделение")
+	}
+
+	gui.deleteSelectedBlock()
+	answerConfirm(t, gui, true)
+	if _, ok := gui.programMgr.GetBlock(block.ID); ok {
+		t.Fatal("блок не удален после подтверждения")
+	}
+	if _, ok := gui.programPanel.blockWidgets[block.ID]; ok {
+		t.Error("удаленный блок остался на холсте")
+	}
+	if gui.selectedBlock != nil {
+		t.Error("выделение не сброшено после удаления")
+	}
+}
+
+func TestDeleteBlockIgnoredWhenReadOnly(t *testing.T) {
+	gui := newTestGUI(t)
+	test.Tap(paletteButton(t, gui, BlockTypeMotor))
+	block := gui.programMgr.GetProgram().Blocks[0]
+	gui.showBlockProperties(block)
+
+	gui.programMgr.GetProgram().RunOnly = true
+	gui.deleteSelectedBlock()
+	if gui.window.Canvas().Overlays().Top() != nil {
+		t.Error("программа только для запуска предлагает удалить блок")
+	}
+}
+
+func TestPropertiesRoundTrip(t *testing.T) {
+	gui := newTestGUI(t)
+	test.Tap(paletteButton(t, gui, BlockTypeWait))
+	test.Tap(paletteButton(t, gui, BlockTypeLED))
+	wait := gui.programMgr.GetProgram().Blocks[0]
+	led := gui.programMgr.GetProgram().Blocks[1]
+
+	gui.showBlockProperties(wait)
+	waitSlider(t, gui).SetValue(2.5)
+	if got := paramFloat(wait.Parameters, "duration", 0); got != 2.5 {
+		t.Fatalf("длительность после изменения %g, ожидалось 2.5", got)
+	}
+
+	// Редактор другого блока не меняет параметры первого
+	gui.showBlockProperties(led)
+	gui.showBlockProperties(wait)
+	if got := waitSlider(t, gui).Value; got != 2.5 {
+		t.Errorf("редактор показывает длительность %g, ожидалось 2.5", got)
+	}
+	if got := paramFloat(wait.Parameters, "duration", 0); got != 2.5 {
+		t.Errorf("длительность после повторного выбора %g, ожидалось 2.5", got)
+	}
+}
+
+func TestToolbarGating(t *testing.T) {
+	gui := newTestGUI(t)
+	toolbar := gui.toolbar
+
+	// Пустая программа без хаба: сохранять и запускать нечего
+	if !toolbar.saveButton.Disabled() || !toolbar.exportButton.Disabled() {
+		t.Error("сохранение пустой программы доступно")
+	}
+	if !toolbar.runButton.Disabled() || !toolbar.stopButton.Disabled() {
+		t.Error("запуск или остановка доступны без хаба")
+	}
+	if toolbar.clearButton.Disabled() || toolbar.loadButton.Disabled() {
+		t.Error("очистка или загрузка недоступны в обычном режиме")
+	}
+
+	test.Tap(paletteButton(t, gui, BlockTypeStart))
+	if toolbar.saveButton.Disabled() || toolbar.exportButton.Disabled() {
+		t.Error("сохранение программы с блоком недоступно")
+	}
+	if !toolbar.runButton.Disabled() {
+		t.Error("запуск доступен без хаба")
+	}
+
+	// Программа только для запуска: очищать нельзя, загружать можно
+	gui.programMgr.GetProgram().RunOnly = true
+	gui.refreshToolbarState()
+	if !toolbar.clearButton.Disabled() {
+		t.Error("программу только для запуска можно очистить")
+	}
+	if toolbar.loadButton.Disabled() {
+		t.Error("загрузка недоступна для программы только для запуска")
+	}
+
+	// Блокировка учителем запрещает и загрузку
+	gui.locked = true
+	gui.refreshToolbarState()
+	if !toolbar.loadButton.Disabled() || !toolbar.clearButton.Disabled() {
+		t.Error("загрузка или очистка доступны в заблокированной программе")
+	}
+}
+
+func TestPropertiesRevertInvalidValue(t *testing.T) {
+	gui := newTestGUI(t)
+	test.Tap(paletteButton(t, gui, BlockTypeSound))
+	sound := gui.programMgr.GetProgram().Blocks[0]
+	gui.showBlockProperties(sound)
+	valid := sound.Parameters["frequency"]
+
+	sound.Parameters["frequency"] = uint16(5000)
+	gui.blockEditor.notifyChange()
+
+	if got := sound.Parameters["frequency"]; got != valid {
+		t.Errorf("после отклоненного значения частота %v, ожидалось %v", got, valid)
+	}
+	if gui.window.Canvas().Overlays().Top() == nil {
+		t.Error("ошибка проверки не показана")
+	}
+}
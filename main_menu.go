@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// settingLastHubAddress адрес хаба, к которому подключались последним
+const settingLastHubAddress = "last_hub_address"
+
+// setupMainMenu создает главное меню окна
+func (gui *MainGUI) setupMainMenu() {
+	gui.devicePanelItem = fyne.NewMenuItem("Панель устройств", func() {
+		gui.setDevicePanelVisible(!gui.devicePanel.GetContainer().Visible())
+	})
+	gui.propertiesPanelItem = fyne.NewMenuItem("Панель свойств", func() {
+		gui.setPropertiesPanelVisible(!gui.propertiesPanel.Visible())
+	})
+
+	gui.tidyUpItem = fyne.NewMenuItem("Выровнять программу", gui.tidyUp)
+
+	viewMenu := fyne.NewMenu("Вид",
+		gui.devicePanelItem,
+		gui.propertiesPanelItem,
+		fyne.NewMenuItem("Наблюдение...", gui.showWatchPanel),
+		fyne.NewMenuItemSeparator(),
+		gui.tidyUpItem,
+	)
+
+	gui.wizardItem = fyne.NewMenuItem("Мастер программ...", gui.showProgramWizard)
+	gui.melodyItem = fyne.NewMenuItem("Импорт мелодии...", gui.showMelodyImportDialog)
+	gui.openLinkItem = fyne.NewMenuItem("Открыть по ссылке...", gui.showOpenLinkDialog)
+	programMenu := fyne.NewMenu("Программа",
+		gui.wizardItem,
+		gui.melodyItem,
+		fyne.NewMenuItem("Описание программы...", gui.showProgramSummary),
+		fyne.NewMenuItem("Временная шкала...", gui.showTimelinePreview),
+		fyne.NewMenuItem("Запись датчиков...", gui.showSensorRecordingDialog),
+		fyne.NewMenuItem("Воспроизведение сеанса...", gui.showSessionReplay),
+		fyne.NewMenuItem("Сравнить программы...", gui.showProgramDiffDialog),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Поделиться программой...", gui.showShareDialog),
+		gui.openLinkItem,
+	)
+
+	gui.mainMenu = fyne.NewMainMenu(
+		gui.createFileMenu(),
+		gui.createEditMenu(),
+		programMenu,
+		viewMenu,
+		gui.createHubMenu(),
+		gui.createHelpMenu(),
+	)
+	// В режиме экзамена пункты остальных меню создаются, но не показываются
+	if gui.kiosk != nil {
+		gui.mainMenu = gui.createKioskMenu()
+	}
+	gui.window.SetMainMenu(gui.mainMenu)
+	gui.updateRecentMenu()
+	gui.updateViewMenu()
+}
+
+// createFileMenu создает меню "Файл"
+func (gui *MainGUI) createFileMenu() *fyne.Menu {
+	gui.newItem = fyne.NewMenuItem("Новая программа", gui.newProgram)
+	gui.newItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyN, Modifier: fyne.KeyModifierShortcutDefault}
+
+	gui.openItem = fyne.NewMenuItem("Открыть...", gui.showOpenProgramDialog)
+	gui.openItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyO, Modifier: fyne.KeyModifierShortcutDefault}
+
+	gui.openLessonItem = fyne.NewMenuItem("Открыть урок...", gui.showOpenLessonDialog)
+
+	gui.recentItem = fyne.NewMenuItem("Недавние программы", nil)
+
+	gui.profileItem = fyne.NewMenuItem("Сменить профиль...", gui.showProfilesDialog)
+
+	saveItem := fyne.NewMenuItem("Сохранить", gui.saveProgram)
+	saveItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault}
+
+	saveAsItem := fyne.NewMenuItem("Сохранить как...", gui.showSaveProgramDialog)
+	saveAsItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+
+	exportRunOnlyItem := fyne.NewMenuItem("Экспорт для запуска...", gui.showRunOnlyExportDialog)
+
+	return fyne.NewMenu("Файл",
+		gui.newItem,
+		gui.openItem,
+		gui.openLessonItem,
+		gui.recentItem,
+		fyne.NewMenuItemSeparator(),
+		saveItem,
+		saveAsItem,
+		exportRunOnlyItem,
+		fyne.NewMenuItemSeparator(),
+		gui.profileItem,
+	)
+}
+
+// createEditMenu создает меню "Правка"
+func (gui *MainGUI) createEditMenu() *fyne.Menu {
+	gui.undoItem = fyne.NewMenuItem("Отменить", func() {
+		if !gui.forwardShortcut(&fyne.ShortcutUndo{}) {
+			gui.undo()
+		}
+	})
+	gui.undoItem.Shortcut = &fyne.ShortcutUndo{}
+
+	gui.copyItem = fyne.NewMenuItem("Копировать блок", func() {
+		if !gui.forwardShortcut(&fyne.ShortcutCopy{Clipboard: gui.window.Clipboard()}) {
+			gui.copySelectedBlock()
+		}
+	})
+	gui.copyItem.Shortcut = &fyne.ShortcutCopy{}
+
+	gui.pasteItem = fyne.NewMenuItem("Вставить блок", func() {
+		if !gui.forwardShortcut(&fyne.ShortcutPaste{Clipboard: gui.window.Clipboard()}) {
+			gui.pasteBlock()
+		}
+	})
+	gui.pasteItem.Shortcut = &fyne.ShortcutPaste{}
+
+	return fyne.NewMenu("Правка", gui.undoItem, fyne.NewMenuItemSeparator(), gui.copyItem, gui.pasteItem)
+}
+
+// forwardShortcut передает сочетание клавиш полю ввода в фокусе,
+// чтобы меню не перехватывало копирование и отмену при редактировании текста
+func (gui *MainGUI) forwardShortcut(shortcut fyne.Shortcut) bool {
+	focused, ok := gui.window.Canvas().Focused().(fyne.Shortcutable)
+	if !ok {
+		return false
+	}
+	focused.TypedShortcut(shortcut)
+	return true
+}
+
+// createHubMenu создает меню "Хаб"
+func (gui *MainGUI) createHubMenu() *fyne.Menu {
+	scanItem := fyne.NewMenuItem("Найти хаб...", gui.showHubDiscoveryDialog)
+	scanItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyF, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+
+	gui.hubConnectItem = fyne.NewMenuItem("Подключиться к последнему хабу", func() {
+		if address := gui.preferences().String(settingLastHubAddress); address != "" {
+			gui.connectToHub(address)
+		} else {
+			gui.showHubDiscoveryDialog()
+		}
+	})
+	gui.hubConnectItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyK, Modifier: fyne.KeyModifierShortcutDefault}
+
+	gui.hubDisconnectItem = fyne.NewMenuItem("Отключиться", gui.hubMgr.Disconnect)
+	gui.hubDisconnectItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeyK, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+	gui.hubDisconnectItem.Disabled = true
+
+	gui.hubKitCheckItem = fyne.NewMenuItem("Проверка комплекта...", gui.runKitCheck)
+	gui.hubKitCheckItem.Disabled = true
+
+	return fyne.NewMenu("Хаб", scanItem, gui.hubConnectItem, gui.hubDisconnectItem,
+		fyne.NewMenuItemSeparator(), gui.hubKitCheckItem,
+		fyne.NewMenuItem("Обслуживание комплектов...", gui.showMotorUsage),
+		fyne.NewMenuItem("Учет хабов...", gui.showHubInventory))
+}
+
+// createHelpMenu создает меню "Справка"
+func (gui *MainGUI) createHelpMenu() *fyne.Menu {
+	helpItem := fyne.NewMenuItem("Справка", gui.toolbar.showHelp)
+	helpItem.Shortcut = &desktop.CustomShortcut{KeyName: fyne.KeySlash, Modifier: fyne.KeyModifierShortcutDefault}
+
+	aboutItem := fyne.NewMenuItem("О программе", func() {
+		dialog.ShowInformation("О программе",
+			"WeDoProg - визуальное программирование LEGO WeDo 2.0",
+			gui.window)
+	})
+
+	tutorialItem := fyne.NewMenuItem("Обучение", gui.startTutorial)
+
+	return fyne.NewMenu("Справка", helpItem, tutorialItem, aboutItem)
+}
+
+// updateRecentMenu перестраивает подменю недавних программ
+func (gui *MainGUI) updateRecentMenu() {
+	if gui.recentItem == nil {
+		return
+	}
+
+	var items []*fyne.MenuItem
+	for _, uri := range gui.recentPrograms() {
+		uri := uri
+		items = append(items, fyne.NewMenuItem(uri.Name(), func() { gui.openProgramURI(uri) }))
+	}
+	if len(items) == 0 {
+		empty := fyne.NewMenuItem("Нет недавних программ", nil)
+		empty.Disabled = true
+		items = append(items, empty)
+	}
+	gui.recentItem.ChildMenu = fyne.NewMenu("", items...)
+	gui.recentItem.Disabled = gui.locked
+	gui.mainMenu.Refresh()
+}
+
+// updateEditMenu включает пункты меню "Правка", когда есть что отменить или вставить
+func (gui *MainGUI) updateEditMenu() {
+	if gui.mainMenu == nil {
+		return
+	}
+
+	gui.undoItem.Disabled = gui.locked || !gui.history.CanUndo()
+	gui.pasteItem.Disabled = gui.readOnly() || gui.clipboardBlock == nil
+	gui.mainMenu.Refresh()
+}
+
+// updateHubMenu включает пункты меню "Хаб" по состоянию подключения
+func (gui *MainGUI) updateHubMenu(isConnected bool) {
+	if gui.mainMenu == nil {
+		return
+	}
+
+	gui.hubConnectItem.Disabled = isConnected
+	gui.hubDisconnectItem.Disabled = !isConnected
+	gui.hubKitCheckItem.Disabled = !isConnected
+	gui.mainMenu.Refresh()
+}
+
+// updateViewMenu отмечает в меню "Вид" видимые панели и доступные действия
+func (gui *MainGUI) updateViewMenu() {
+	if gui.mainMenu == nil {
+		return
+	}
+
+	gui.devicePanelItem.Checked = gui.devicePanel.GetContainer().Visible()
+	gui.propertiesPanelItem.Checked = gui.propertiesPanel.Visible()
+	gui.tidyUpItem.Disabled = gui.readOnly()
+	gui.wizardItem.Disabled = gui.locked
+	gui.melodyItem.Disabled = gui.readOnly()
+	gui.openLinkItem.Disabled = gui.locked
+	gui.newItem.Disabled = gui.locked
+	gui.openItem.Disabled = gui.locked
+	gui.openLessonItem.Disabled = gui.locked
+	gui.recentItem.Disabled = gui.locked
+	gui.profileItem.Disabled = gui.locked
+	gui.updateEditMenu()
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Импорт одноголосной мелодии из строки RTTTL или однодорожечного MIDI.
+// Ноты становятся цепочкой блоков «Звук», паузы — блоками «Ждать».
+// Частоты переносятся на октаву в диапазон пищалки, длительности
+// ограничиваются диапазоном редактора блока
+
+const (
+	melodyMaxNotes   = 100  // Больше блоков на холсте не разобрать, остаток мелодии отбрасывается
+	melodyMinNote    = 100  // Минимальная длительность ноты, мс
+	melodyMaxNote    = 5000 // Максимальная длительность ноты, мс
+	melodyMinRest    = 20   // Паузы короче не превращаются в блок «Ждать», мс
+	midiDefaultTempo = 500000
+
+	rtttlMaxOctave   = 8   // Октавы RTTTL 0..8
+	rtttlMinBPM      = 25  // Самый медленный темп RTTTL, ударов в минуту
+	rtttlMaxBPM      = 900 // Самый быстрый темп RTTTL, ударов в минуту
+	rtttlMaxDuration = 64  // Самая короткая нота RTTTL — 1/64
+)
+
+// melodyFileExtensions расширения файлов мелодий
+var melodyFileExtensions = []string{".mid", ".midi", ".rtttl", ".rtx", ".txt"}
+
+// MelodyNote нота мелодии; частота 0 — пауза
+type MelodyNote struct {
+	Frequency uint16 // Гц
+	Duration  uint16 // Миллисекунды
+}
+
+// noteFrequency частота ноты по номеру MIDI (69 — ля первой октавы, 440 Гц)
+func noteFrequency(midiNote int) float64 {
+	return 440 * math.Pow(2, float64(midiNote-69)/12)
+}
+
+// clipMelodyNote приводит ноту к возможностям пищалки: частота переносится
+// на целые октавы в frequencyRange, длительность ограничивается
+func clipMelodyNote(frequency float64, ms float64) MelodyNote {
+	if math.IsNaN(ms) || math.IsInf(ms, 0) {
+		ms = melodyMaxNote
+	}
+	ms = math.Round(ms)
+	// Бесконечную частоту нельзя перенести в диапазон делением, она становится паузой
+	if frequency <= 0 || math.IsNaN(frequency) || math.IsInf(frequency, 0) {
+		return MelodyNote{Duration: uint16(clamp(ms, 0, math.MaxUint16))}
+	}
+	for frequency < frequencyRange.min {
+		frequency *= 2
+	}
+	for frequency > frequencyRange.max {
+		frequency /= 2
+	}
+	return MelodyNote{
+		Frequency: uint16(math.Round(frequency)),
+		Duration:  uint16(clamp(ms, melodyMinNote, melodyMaxNote)),
+	}
+}
+
+// rtttlSemitones смещения нот от «до» в полутонах
+var rtttlSemitones = map[byte]int{'c': 0, 'd': 2, 'e': 4, 'f': 5, 'g': 7, 'a': 9, 'b': 11, 'h': 11}
+
+// parseRTTTL читает мелодию в формате RTTTL: «имя:d=4,o=5,b=120:8e6,8d#6,p,c.»
+func parseRTTTL(text string) ([]MelodyNote, error) {
+	sections := strings.Split(strings.TrimSpace(text), ":")
+	if len(sections) != 3 {
+		return nil, fmt.Errorf("ожидается строка RTTTL вида «имя:d=4,o=5,b=120:ноты»")
+	}
+
+	duration, octave, bpm := 4, 6, 63
+	for _, field := range strings.Split(sections[1], ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("неверное значение по умолчанию %q", field)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "d":
+			if number < 1 || number > rtttlMaxDuration {
+				return nil, fmt.Errorf("длительность по умолчанию %d: допустимо от 1 до %d", number, rtttlMaxDuration)
+			}
+			duration = number
+		case "o":
+			if number > rtttlMaxOctave {
+				return nil, fmt.Errorf("октава по умолчанию %d: допустимо от 0 до %d", number, rtttlMaxOctave)
+			}
+			octave = number
+		case "b":
+			if number < rtttlMinBPM || number > rtttlMaxBPM {
+				return nil, fmt.Errorf("темп %d: допустимо от %d до %d", number, rtttlMinBPM, rtttlMaxBPM)
+			}
+			bpm = number
+		}
+	}
+
+	// Длительность целой ноты: четыре доли
+	whole := 4 * 60000 / float64(bpm)
+
+	var notes []MelodyNote
+	for index, token := range strings.Split(sections[2], ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		noteDuration, noteOctave, dotted := duration, octave, false
+
+		i := 0
+		for i < len(token) && token[i] >= '0' && token[i] <= '9' {
+			i++
+		}
+		if i > 0 {
+			noteDuration, _ = strconv.Atoi(token[:i])
+			if noteDuration <= 0 || noteDuration > rtttlMaxDuration {
+				return nil, fmt.Errorf("нота %d (%q): неверная длительность", index+1, token)
+			}
+		}
+		if i >= len(token) {
+			return nil, fmt.Errorf("нота %d (%q): нет названия ноты", index+1, token)
+		}
+
+		name := token[i]
+		i++
+		semitone, isNote := rtttlSemitones[name]
+		if !isNote && name != 'p' {
+			return nil, fmt.Errorf("нота %d (%q): неизвестная нота", index+1, token)
+		}
+		if i < len(token) && token[i] == '#' {
+			semitone++
+			i++
+		}
+		if i < len(token) && token[i] == '.' {
+			dotted = true
+			i++
+		}
+		if i < len(token) && token[i] >= '0' && token[i] <= '9' {
+			noteOctave = int(token[i] - '0')
+			if noteOctave > rtttlMaxOctave {
+				return nil, fmt.Errorf("нота %d (%q): октава вне диапазона 0..%d", index+1, token, rtttlMaxOctave)
+			}
+			i++
+		}
+		if i < len(token) && token[i] == '.' {
+			dotted = true
+			i++
+		}
+		if i != len(token) {
+			return nil, fmt.Errorf("нота %d (%q): лишние символы", index+1, token)
+		}
+
+		ms := whole / float64(noteDuration)
+		if dotted {
+			ms *= 1.5
+		}
+		frequency := 0.0
+		if isNote {
+			frequency = noteFrequency((noteOctave+1)*12 + semitone)
+		}
+		notes = append(notes, clipMelodyNote(frequency, ms))
+	}
+
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("в мелодии нет нот")
+	}
+	return notes, nil
+}
+
+// midiTempo смена темпа: микросекунд на четверть с тика
+type midiTempo struct {
+	tick  uint64
+	usPer uint64
+}
+
+// midiNoteSpan звучание ноты в тиках
+type midiNoteSpan struct {
+	start, end uint64
+	note       int
+}
+
+// parseMIDI читает одноголосную мелодию из стандартного MIDI-файла. Ноты
+// должны быть в одной дорожке; темп может быть в отдельной. Если ноты
+// звучат одновременно, новая нота обрывает предыдущую
+func parseMIDI(data []byte) ([]MelodyNote, error) {
+	r := bytes.NewReader(data)
+
+	chunk, header, err := readMIDIChunk(r)
+	if err != nil || chunk != "MThd" || len(header) < 6 {
+		return nil, fmt.Errorf("это не MIDI-файл")
+	}
+	format := binary.BigEndian.Uint16(header[0:2])
+	division := binary.BigEndian.Uint16(header[4:6])
+	if format > 1 {
+		return nil, fmt.Errorf("формат MIDI %d не поддерживается, нужен однодорожечный файл", format)
+	}
+	if division&0x8000 != 0 || division == 0 {
+		return nil, fmt.Errorf("время в кадрах SMPTE не поддерживается")
+	}
+
+	var tempos []midiTempo
+	var spans []midiNoteSpan
+	noteTracks := 0
+	for r.Len() > 0 {
+		chunk, track, err := readMIDIChunk(r)
+		if err != nil {
+			return nil, err
+		}
+		if chunk != "MTrk" {
+			continue
+		}
+		trackSpans, trackTempos, err := parseMIDITrack(track)
+		if err != nil {
+			return nil, err
+		}
+		tempos = append(tempos, trackTempos...)
+		if len(trackSpans) > 0 {
+			noteTracks++
+			spans = trackSpans
+		}
+	}
+	if noteTracks == 0 {
+		return nil, fmt.Errorf("в файле нет нот")
+	}
+	if noteTracks > 1 {
+		return nil, fmt.Errorf("ноты записаны в %d дорожках, нужна одна", noteTracks)
+	}
+
+	sort.SliceStable(tempos, func(i, j int) bool { return tempos[i].tick < tempos[j].tick })
+	toMs := func(tick uint64) float64 {
+		ms, last, usPer := 0.0, uint64(0), uint64(midiDefaultTempo)
+		for _, tempo := range tempos {
+			if tempo.tick >= tick {
+				break
+			}
+			ms += float64(tempo.tick-last) * float64(usPer) / float64(division) / 1000
+			last, usPer = tempo.tick, tempo.usPer
+		}
+		return ms + float64(tick-last)*float64(usPer)/float64(division)/1000
+	}
+
+	var notes []MelodyNote
+	position := 0.0
+	for _, span := range spans {
+		start, end := toMs(span.start), toMs(span.end)
+		if rest := start - position; rest >= melodyMinRest {
+			notes = append(notes, clipMelodyNote(0, rest))
+		}
+		notes = append(notes, clipMelodyNote(noteFrequency(span.note), end-start))
+		position = end
+	}
+	return notes, nil
+}
+
+// readMIDIChunk читает блок файла: тип и содержимое
+func readMIDIChunk(r *bytes.Reader) (string, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", nil, fmt.Errorf("файл MIDI поврежден: %v", err)
+	}
+	size := binary.BigEndian.Uint32(header[4:8])
+	if int64(size) > int64(r.Len()) {
+		return "", nil, fmt.Errorf("файл MIDI поврежден: блок длиннее файла")
+	}
+	data := make([]byte, size)
+	io.ReadFull(r, data)
+	return string(header[:4]), data, nil
+}
+
+// readVarLen читает число переменной длины MIDI
+func readVarLen(r *bytes.Reader) (uint64, error) {
+	var value uint64
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value = value<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("слишком длинное число")
+}
+
+// parseMIDITrack читает ноты и смены темпа дорожки
+func parseMIDITrack(track []byte) ([]midiNoteSpan, []midiTempo, error) {
+	r := bytes.NewReader(track)
+	var spans []midiNoteSpan
+	var tempos []midiTempo
+	var tick uint64
+	var status byte
+	sounding := -1 // Номер звучащей ноты в spans
+
+	broken := func(err error) error { return fmt.Errorf("дорожка MIDI повреждена: %v", err) }
+	endNote := func(note int) {
+		if sounding >= 0 && (note < 0 || spans[sounding].note == note) {
+			spans[sounding].end = tick
+			sounding = -1
+		}
+	}
+
+	for r.Len() > 0 {
+		delta, err := readVarLen(r)
+		if err != nil {
+			return nil, nil, broken(err)
+		}
+		tick += delta
+
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, broken(err)
+		}
+		if b&0x80 != 0 {
+			status = b
+		} else {
+			// Повтор статуса предыдущего сообщения канала
+			if status == 0 {
+				return nil, nil, broken(fmt.Errorf("нет статуса сообщения"))
+			}
+			r.UnreadByte()
+		}
+
+		switch {
+		case status == 0xff:
+			kind, err := r.ReadByte()
+			if err != nil {
+				return nil, nil, broken(err)
+			}
+			size, err := readVarLen(r)
+			if err != nil || size > uint64(r.Len()) {
+				return nil, nil, broken(fmt.Errorf("неверная длина метасобытия"))
+			}
+			meta := make([]byte, size)
+			io.ReadFull(r, meta)
+			if kind == 0x51 && size == 3 {
+				tempos = append(tempos, midiTempo{tick: tick, usPer: uint64(meta[0])<<16 | uint64(meta[1])<<8 | uint64(meta[2])})
+			}
+			status = 0
+		case status == 0xf0 || status == 0xf7:
+			size, err := readVarLen(r)
+			if err != nil || size > uint64(r.Len()) {
+				return nil, nil, broken(fmt.Errorf("неверная длина системного сообщения"))
+			}
+			r.Seek(int64(size), io.SeekCurrent)
+			status = 0
+		default:
+			var message [2]byte
+			count := 2
+			if kind := status & 0xf0; kind == 0xc0 || kind == 0xd0 {
+				count = 1
+			}
+			if _, err := io.ReadFull(r, message[:count]); err != nil {
+				return nil, nil, broken(err)
+			}
+			note, velocity := int(message[0]), message[1]
+			switch status & 0xf0 {
+			case 0x90:
+				if velocity == 0 {
+					endNote(note)
+					break
+				}
+				endNote(-1)
+				spans = append(spans, midiNoteSpan{start: tick, end: tick, note: note})
+				sounding = len(spans) - 1
+			case 0x80:
+				endNote(note)
+			}
+		}
+	}
+	endNote(-1)
+	return spans, tempos, nil
+}
+
+// parseMelodyFile читает мелодию из файла: MIDI по расширению, иначе строку RTTTL
+func parseMelodyFile(name string, data []byte) ([]MelodyNote, error) {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".mid") || strings.HasSuffix(lower, ".midi") {
+		return parseMIDI(data)
+	}
+	return parseRTTTL(string(data))
+}
+
+// insertMelody добавляет мелодию цепочкой блоков «Звук» и «Ждать». Как и
+// блоки из палитры, цепочка встает в выбранное соединение или после
+// последнего блока. Возвращает число добавленных нот
+func (gui *MainGUI) insertMelody(notes []MelodyNote, port byte) int {
+	if len(notes) > melodyMaxNotes {
+		log.Printf("Мелодия сокращена с %d до %d нот", len(notes), melodyMaxNotes)
+		notes = notes[:melodyMaxNotes]
+	}
+
+	gui.checkpoint()
+	for _, note := range notes {
+		var block *ProgramBlock
+		if note.Frequency == 0 {
+			block = gui.programMgr.CreateBlock(BlockTypeWait, 100, 100)
+			wait := waitFromParameters(block.Parameters)
+			wait.Duration = float64(note.Duration) / 1000
+			wait.Store(block.Parameters)
+		} else {
+			block = gui.programMgr.CreateBlock(BlockTypeSound, 100, 100)
+			SoundParams{Port: port, Frequency: note.Frequency, Duration: note.Duration}.Store(block.Parameters)
+		}
+		gui.programPanel.AddBlock(block)
+	}
+
+	gui.refreshToolbarState()
+	log.Printf("Импортирована мелодия: %d нот", len(notes))
+	return len(notes)
+}
+
+// showMelodyImportDialog показывает импорт мелодии из строки RTTTL или файла
+func (gui *MainGUI) showMelodyImportDialog() {
+	if gui.readOnly() {
+		return
+	}
+
+	port := byte(1)
+	if ports := gui.connectedPorts(DEVICE_TYPE_PIEZO_TONE); len(ports) > 0 {
+		port = ports[0]
+	}
+
+	text := widget.NewMultiLineEntry()
+	text.SetPlaceHolder("Например: Scale:d=4,o=5,b=120:c,d,e,f,g,a,b,c6")
+	text.Wrapping = fyne.TextWrapWord
+	text.SetMinRowsVisible(4)
+
+	hint := widget.NewLabel(fmt.Sprintf("Вставьте строку RTTTL или откройте файл RTTTL или MIDI с одной дорожкой. "+
+		"Ноты вне диапазона пищалки переносятся на октаву, длительности ограничиваются %d–%d мс.", melodyMinNote, melodyMaxNote))
+	hint.Wrapping = fyne.TextWrapWord
+
+	var d dialog.Dialog
+	imported := func(notes []MelodyNote) {
+		d.Hide()
+		count := gui.insertMelody(notes, port)
+		gui.announce(fmt.Sprintf("Добавлена мелодия: %d нот", count))
+		gui.sounds.Play(SoundBlockAdded)
+	}
+
+	openButton := widget.NewButton("Открыть файл...", func() {
+		open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			notes, err := parseMelodyFile(reader.URI().Name(), data)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("Не удалось прочитать мелодию: %v", err), gui.window)
+				return
+			}
+			imported(notes)
+		}, gui.window)
+		open.SetFilter(storage.NewExtensionFileFilter(melodyFileExtensions))
+		open.Show()
+	})
+
+	content := container.NewVBox(hint, text, openButton)
+	d = dialog.NewCustomConfirm("Импорт мелодии", "Добавить", "Отмена", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		notes, err := parseRTTTL(text.Text)
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Не удалось прочитать мелодию: %v", err), gui.window)
+			return
+		}
+		imported(notes)
+	}, gui.window)
+	d.Resize(fyne.NewSize(520, 320))
+	d.Show()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultMessageName имя сообщения для новых блоков
+const defaultMessageName = "сообщение1"
+
+// messageFromParameters возвращает имя сообщения блока
+func messageFromParameters(params map[string]interface{}) string {
+	if message, ok := params["message"].(string); ok && strings.TrimSpace(message) != "" {
+		return strings.TrimSpace(message)
+	}
+	return defaultMessageName
+}
+
+// isHatBlock проверяет, начинает ли блок собственную цепочку
+func isHatBlock(blockType BlockType) bool {
+	switch blockType {
+	case BlockTypeStart, BlockTypeReceive, BlockTypeWhenMotion, BlockTypeWhenColor, BlockTypeWhenHear, BlockTypeWhenBumped:
+		return true
+	default:
+		return false
+	}
+}
+
+// Broadcast отправляет сообщение всем цепочкам "Когда получено"
+func (pm *ProgramManager) Broadcast(message string) {
+	log.Printf("Отправка сообщения: %s", message)
+	pm.hubMgr.Events().Publish(Event{
+		Type: EventMessage,
+		Name: message,
+	})
+}
+
+// listenForMessages запускает цепочки "Когда получено" при получении сообщений
+func (pm *ProgramManager) listenForMessages(ctx context.Context, chains *sync.WaitGroup) func() {
+	return pm.listenForHats(ctx, chains, EventMessage, func(block *ProgramBlock, event Event) bool {
+		return block.Type == BlockTypeReceive && messageFromParameters(block.Parameters) == event.Name
+	})
+}
+
+// listenForHats запускает цепочки блоков-шапок, для которых match возвращает true.
+// Цепочка, которая уже выполняется, повторно не запускается.
+func (pm *ProgramManager) listenForHats(ctx context.Context, chains *sync.WaitGroup,
+	eventType EventType, match func(block *ProgramBlock, event Event) bool) func() {
+
+	var runningMu sync.Mutex
+	running := make(map[int]bool)
+
+	return pm.hubMgr.Events().Subscribe(eventType, func(event Event) {
+		if ctx.Err() != nil || pm.GetProgramState() != ProgramStateRunning {
+			return
+		}
+
+		for _, block := range pm.program.Blocks {
+			if !match(block, event) {
+				continue
+			}
+
+			runningMu.Lock()
+			if running[block.ID] {
+				runningMu.Unlock()
+				continue
+			}
+			running[block.ID] = true
+			runningMu.Unlock()
+
+			log.Printf("Событие запускает цепочку блока %s (ID: %d)", block.Title, block.ID)
+
+			chains.Add(1)
+			go func(hat *ProgramBlock) {
+				defer func() {
+					runningMu.Lock()
+					delete(running, hat.ID)
+					runningMu.Unlock()
+				}()
+				pm.runChain(ctx, hat, chains)
+			}(block)
+		}
+	})
+}
+
+// holdUntilStopped не дает программе завершиться, пока ее не остановят.
+// Нужен цепочкам, которые запускаются внешними событиями (камера, речь).
+func holdUntilStopped(ctx context.Context, chains *sync.WaitGroup) {
+	chains.Add(1)
+	go func() {
+		<-ctx.Done()
+		chains.Done()
+	}()
+}
+
+// programMessages возвращает имена всех сообщений, используемых в программе
+func (pm *ProgramManager) programMessages() []string {
+	seen := make(map[string]bool)
+	var messages []string
+
+	for _, block := range pm.program.Blocks {
+		if block.Type != BlockTypeBroadcast && block.Type != BlockTypeReceive {
+			continue
+		}
+		message := messageFromParameters(block.Parameters)
+		if !seen[message] {
+			seen[message] = true
+			messages = append(messages, message)
+		}
+	}
+
+	sort.Strings(messages)
+	return messages
+}
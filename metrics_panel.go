@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showMetricsDialog показывает панель диагностики сеанса
+func (gui *MainGUI) showMetricsDialog() {
+	metrics := gui.hubMgr.Metrics()
+
+	bleLabel := widget.NewLabel("")
+	commandsLabel := widget.NewLabel("")
+	notificationsLabel := widget.NewLabel("")
+
+	update := func() {
+		s := metrics.Snapshot()
+
+		bleLabel.SetText(fmt.Sprintf(
+			"Время сеанса: %s\nПодключений: %d\nНеудачных подключений: %d\nОтключений: %d",
+			s.Uptime.Round(time.Second), s.Connects, s.ConnectFailures, s.Disconnects))
+
+		commandsLabel.SetText(fmt.Sprintf(
+			"Отправлено команд: %d\nПотеряно команд: %d\nЗадержка p50: %s\nЗадержка p90: %s\nЗадержка p99: %s",
+			s.Writes, s.DroppedWrites,
+			formatLatency(s.LatencyP50), formatLatency(s.LatencyP90), formatLatency(s.LatencyP99)))
+
+		var lines []string
+		for _, source := range []string{NotificationSensors, NotificationPorts, NotificationBattery} {
+			lines = append(lines, fmt.Sprintf("%s: %d (%.1f в секунду)",
+				source, s.Notifications[source], s.NotificationRate[source]))
+		}
+		notificationsLabel.SetText(strings.Join(lines, "\n"))
+	}
+	update()
+
+	hint := widget.NewLabel("Потерянные команды и рост задержки указывают на проблемы BLE.\n" +
+		"Если команды уходят, а уведомлений нет, проблема, скорее всего, в хабе.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	resetButton := widget.NewButton("Сбросить", func() {
+		metrics.Reset()
+		update()
+	})
+
+	content := container.NewVBox(
+		widget.NewCard("Bluetooth", "", bleLabel),
+		widget.NewCard("Команды", "", commandsLabel),
+		widget.NewCard("Уведомления", "", notificationsLabel),
+		hint,
+		container.NewCenter(container.NewHBox(resetButton,
+			widget.NewButton("Производительность...", gui.showBenchmarkReport),
+			widget.NewButton("Нагрузочная проверка...", gui.showStressTestDialog))),
+	)
+
+	d := dialog.NewCustom("Диагностика", "Закрыть", content, gui.window)
+	d.Resize(fyne.NewSize(460, 560))
+
+	// Обновляем значения, пока панель открыта
+	done := make(chan struct{})
+	d.SetOnClosed(func() {
+		close(done)
+	})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fyne.Do(update)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	d.Show()
+}
+
+// formatLatency форматирует задержку команды
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.1f мс", float64(d.Microseconds())/1000)
+}
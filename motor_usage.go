@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const settingMotorUsage = "motor_usage" // Наработка моторов по хабам в JSON
+
+// MotorUsage наработка мотора на одном порту хаба
+type MotorUsage struct {
+	Runtime     time.Duration `json:"runtime"`
+	Activations int           `json:"activations"`
+}
+
+// HubUsage наработка моторов одного хаба
+type HubUsage struct {
+	Key      string               `json:"key"` // System ID, а если он не прочитан — адрес
+	Name     string               `json:"name"`
+	LastSeen time.Time            `json:"last_seen"`
+	Motors   map[byte]*MotorUsage `json:"motors"`
+}
+
+// Total суммарная наработка и число включений всех моторов хаба
+func (h HubUsage) Total() (time.Duration, int) {
+	var runtime time.Duration
+	activations := 0
+	for _, motor := range h.Motors {
+		runtime += motor.Runtime
+		activations += motor.Activations
+	}
+	return runtime, activations
+}
+
+// MotorUsageTracker считает время работы и число включений моторов
+// для каждого хаба между сеансами, чтобы чередовать сильно изношенные наборы
+type MotorUsageTracker struct {
+	mu      sync.Mutex
+	hubs    map[string]*HubUsage
+	hubKey  string
+	hubName string
+	running map[byte]time.Time // Включенные сейчас моторы и время включения
+	changed bool
+}
+
+// NewMotorUsageTracker создает пустую статистику
+func NewMotorUsageTracker() *MotorUsageTracker {
+	return &MotorUsageTracker{
+		hubs:    make(map[string]*HubUsage),
+		running: make(map[byte]time.Time),
+	}
+}
+
+// Load загружает сохраненную статистику
+func (t *MotorUsageTracker) Load(data string) error {
+	if data == "" {
+		return nil
+	}
+	var hubs []*HubUsage
+	if err := json.Unmarshal([]byte(data), &hubs); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, hub := range hubs {
+		if hub.Motors == nil {
+			hub.Motors = make(map[byte]*MotorUsage)
+		}
+		t.hubs[hub.Key] = hub
+	}
+	return nil
+}
+
+// Export записывает статистику для сохранения; включенные моторы учитываются до текущего момента.
+// Возвращает false, если с прошлого сохранения ничего не изменилось
+func (t *MotorUsageTracker) Export() (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.flushLocked(time.Now())
+	if !t.changed {
+		return "", false
+	}
+	data, err := json.Marshal(t.snapshotLocked())
+	if err != nil {
+		log.Printf("Не удалось сохранить наработку моторов: %v", err)
+		return "", false
+	}
+	t.changed = false
+	return string(data), true
+}
+
+// SetHub задает хаб, к которому относятся следующие команды моторам
+func (t *MotorUsageTracker) SetHub(key, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if key == t.hubKey && name == t.hubName {
+		return
+	}
+	t.stopAllLocked(time.Now())
+	t.hubKey, t.hubName = key, name
+}
+
+// RecordMotor учитывает команду мотору: включение начинает отсчет, остановка его завершает
+func (t *MotorUsageTracker) RecordMotor(port byte, running bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	started, wasRunning := t.running[port]
+	switch {
+	case running && !wasRunning:
+		if hub := t.hubLocked(); hub != nil {
+			t.motorLocked(hub, port).Activations++
+			t.running[port] = now
+			t.changed = true
+		}
+	case !running && wasRunning:
+		if hub := t.hubLocked(); hub != nil {
+			t.motorLocked(hub, port).Runtime += now.Sub(started)
+			hub.LastSeen = now
+			t.changed = true
+		}
+		delete(t.running, port)
+	}
+}
+
+// AnyRunning сообщает, работает ли сейчас хотя бы один мотор
+func (t *MotorUsageTracker) AnyRunning() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.running) > 0
+}
+
+// StopAll завершает отсчет всех моторов, например при отключении хаба
+func (t *MotorUsageTracker) StopAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopAllLocked(time.Now())
+}
+
+// ResetHub обнуляет статистику хаба, например после замены моторов
+func (t *MotorUsageTracker) ResetHub(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if hub, exists := t.hubs[key]; exists {
+		hub.Motors = make(map[byte]*MotorUsage)
+		if key == t.hubKey {
+			t.running = make(map[byte]time.Time)
+		}
+		t.changed = true
+	}
+}
+
+// Snapshot возвращает копию статистики, начиная с самого нагруженного хаба
+func (t *MotorUsageTracker) Snapshot() []HubUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushLocked(time.Now())
+
+	hubs := make([]HubUsage, 0, len(t.hubs))
+	for _, hub := range t.snapshotLocked() {
+		hubs = append(hubs, *hub)
+	}
+	sort.Slice(hubs, func(i, j int) bool {
+		a, _ := hubs[i].Total()
+		b, _ := hubs[j].Total()
+		return a > b
+	})
+	return hubs
+}
+
+// snapshotLocked копирует статистику всех хабов
+func (t *MotorUsageTracker) snapshotLocked() []*HubUsage {
+	hubs := make([]*HubUsage, 0, len(t.hubs))
+	for _, hub := range t.hubs {
+		copied := *hub
+		copied.Motors = make(map[byte]*MotorUsage, len(hub.Motors))
+		for port, motor := range hub.Motors {
+			motorCopy := *motor
+			copied.Motors[port] = &motorCopy
+		}
+		hubs = append(hubs, &copied)
+	}
+	sort.Slice(hubs, func(i, j int) bool { return hubs[i].Key < hubs[j].Key })
+	return hubs
+}
+
+// flushLocked переносит время работы включенных моторов в статистику, не останавливая отсчет
+func (t *MotorUsageTracker) flushLocked(now time.Time) {
+	if len(t.running) == 0 {
+		return
+	}
+	hub := t.hubLocked()
+	if hub == nil {
+		return
+	}
+	for port, started := range t.running {
+		t.motorLocked(hub, port).Runtime += now.Sub(started)
+		t.running[port] = now
+		hub.LastSeen = now
+		t.changed = true
+	}
+}
+
+// stopAllLocked завершает отсчет всех включенных моторов
+func (t *MotorUsageTracker) stopAllLocked(now time.Time) {
+	t.flushLocked(now)
+	t.running = make(map[byte]time.Time)
+}
+
+// hubLocked возвращает статистику текущего хаба, создавая ее при первом обращении
+func (t *MotorUsageTracker) hubLocked() *HubUsage {
+	if t.hubKey == "" {
+		return nil
+	}
+	hub, exists := t.hubs[t.hubKey]
+	if !exists {
+		hub = &HubUsage{Key: t.hubKey, Motors: make(map[byte]*MotorUsage)}
+		t.hubs[t.hubKey] = hub
+	}
+	if t.hubName != "" {
+		hub.Name = t.hubName
+	}
+	return hub
+}
+
+// motorLocked возвращает статистику мотора на порту хаба
+func (t *MotorUsageTracker) motorLocked(hub *HubUsage, port byte) *MotorUsage {
+	motor, exists := hub.Motors[port]
+	if !exists {
+		motor = &MotorUsage{}
+		hub.Motors[port] = motor
+	}
+	return motor
+}
+
+// motorRunning сообщает, включает ли команда мотор; ok — команда адресована мотору
+func (c *OutputCommand) motorRunning() (running, ok bool) {
+	if c.command != outputCommandMotor || c.err != nil || len(c.payload) == 0 {
+		return false, false
+	}
+	return c.payload[0] != protocol.EncodeMotorSpeed(0), true
+}
+
+// MotorUsage возвращает статистику наработки моторов
+func (hm *HubManager) MotorUsage() *MotorUsageTracker {
+	return hm.usage
+}
+
+// saveMotorUsage сохраняет наработку моторов в настройках, если она изменилась
+func (gui *MainGUI) saveMotorUsage() {
+	if data, changed := gui.hubMgr.MotorUsage().Export(); changed {
+		gui.preferences().SetString(settingMotorUsage, data)
+	}
+}
+
+// formatRuntime записывает наработку в часах и минутах
+func formatRuntime(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	return fmt.Sprintf("%d ч %02d мин", minutes/60, minutes%60)
+}
+
+// showMotorUsage показывает наработку моторов по хабам, начиная с самого нагруженного
+func (gui *MainGUI) showMotorUsage() {
+	window := fyne.CurrentApp().NewWindow("Обслуживание комплектов")
+	list := container.NewVBox()
+
+	var refresh func()
+	refresh = func() {
+		list.Objects = nil
+		hubs := gui.hubMgr.MotorUsage().Snapshot()
+		if len(hubs) == 0 {
+			list.Add(widget.NewLabel("Моторы еще не включались"))
+		}
+		for _, hub := range hubs {
+			hub := hub
+			runtime, activations := hub.Total()
+			title := hub.Name
+			if title == "" {
+				title = "Хаб"
+			}
+
+			ports := make([]int, 0, len(hub.Motors))
+			for port := range hub.Motors {
+				ports = append(ports, int(port))
+			}
+			sort.Ints(ports)
+			form := widget.NewForm()
+			for _, port := range ports {
+				motor := hub.Motors[byte(port)]
+				form.Append(fmt.Sprintf("Мотор, порт %d", port),
+					widget.NewLabel(fmt.Sprintf("%s, включений: %d", formatRuntime(motor.Runtime), motor.Activations)))
+			}
+			if !hub.LastSeen.IsZero() {
+				form.Append("Последняя работа", widget.NewLabel(hub.LastSeen.Format("02.01.2006 15:04")))
+			}
+
+			reset := widget.NewButton("Обнулить", func() {
+				dialog.ShowConfirm("Обнулить наработку",
+					fmt.Sprintf("Обнулить наработку моторов хаба «%s»? Например, после замены моторов.", title),
+					func(confirmed bool) {
+						if confirmed {
+							gui.hubMgr.MotorUsage().ResetHub(hub.Key)
+							gui.saveMotorUsage()
+							refresh()
+						}
+					}, window)
+			})
+
+			subtitle := fmt.Sprintf("%s — всего %s, включений: %d", hub.Key, formatRuntime(runtime), activations)
+			list.Add(widget.NewCard(title, subtitle, container.NewVBox(form, container.NewHBox(reset))))
+		}
+		list.Refresh()
+	}
+	refresh()
+
+	hint := widget.NewLabel("Хабы отсортированы по наработке: наборы сверху стоит чаще отдавать на отдых или проверку")
+	hint.Wrapping = fyne.TextWrapWord
+	window.SetContent(container.NewBorder(hint, nil, nil, nil, container.NewVScroll(list)))
+	window.Resize(fyne.NewSize(520, 520))
+	window.Show()
+}
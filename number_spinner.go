@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// NumberSpinner поле ввода числа с кнопками «−» и «+». Значение всегда лежит
+// в диапазоне Min..Max и кратно шагу от Min: неверный ввод подсвечивается,
+// OnChanged не вызывается, а при уходе из поля возвращается последнее верное значение
+type NumberSpinner struct {
+	widget.BaseWidget
+
+	Min, Max, Step float64
+	Decimals       int                 // Знаков после запятой
+	OnChanged      func(value float64) // Вызывается только для верных значений
+
+	value float64
+	entry *spinnerEntry
+	minus *widget.Button
+	plus  *widget.Button
+}
+
+// spinnerEntry поле ввода счетчика: стрелки меняют значение на шаг,
+// а уход из поля исправляет неверный ввод
+type spinnerEntry struct {
+	widget.Entry
+	spinner *NumberSpinner
+}
+
+// NewNumberSpinner создает поле ввода числа с диапазоном и шагом
+func NewNumberSpinner(min, max, step float64, decimals int, value float64, onChanged func(value float64)) *NumberSpinner {
+	s := &NumberSpinner{Min: min, Max: max, Step: step, Decimals: decimals, OnChanged: onChanged}
+	s.entry = &spinnerEntry{spinner: s}
+	s.entry.ExtendBaseWidget(s.entry)
+	s.entry.Validator = func(text string) error {
+		_, err := s.parse(text)
+		return err
+	}
+	s.minus = widget.NewButtonWithIcon("", theme.ContentRemoveIcon(), func() { s.stepBy(-1) })
+	s.plus = widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() { s.stepBy(1) })
+
+	s.value = s.normalize(value)
+	s.entry.SetText(s.format(s.value))
+	s.updateButtons()
+
+	// Начальное значение не считается изменением
+	s.entry.OnChanged = func(text string) {
+		if value, err := s.parse(text); err == nil && value != s.value {
+			s.value = value
+			s.updateButtons()
+			if s.OnChanged != nil {
+				s.OnChanged(value)
+			}
+		}
+	}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// CreateRenderer размещает кнопки по сторонам поля
+func (s *NumberSpinner) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewBorder(nil, nil, s.minus, s.plus, s.entry))
+}
+
+// Value возвращает текущее значение
+func (s *NumberSpinner) Value() float64 {
+	return s.value
+}
+
+// SetValue задает значение, приводя его к диапазону и шагу. OnChanged вызывается при изменении
+func (s *NumberSpinner) SetValue(value float64) {
+	s.entry.SetText(s.format(s.normalize(value)))
+}
+
+// SetPlaceHolder задает подсказку в пустом поле
+func (s *NumberSpinner) SetPlaceHolder(text string) {
+	s.entry.SetPlaceHolder(text)
+}
+
+// Disable запрещает ввод
+func (s *NumberSpinner) Disable() {
+	s.entry.Disable()
+	s.minus.Disable()
+	s.plus.Disable()
+}
+
+// stepBy меняет значение на steps шагов
+func (s *NumberSpinner) stepBy(steps int) {
+	s.SetValue(s.value + float64(steps)*s.Step)
+}
+
+// updateButtons запрещает кнопки на границах диапазона
+func (s *NumberSpinner) updateButtons() {
+	if s.entry.Disabled() {
+		return
+	}
+	setEnabled := func(button *widget.Button, enabled bool) {
+		if enabled {
+			button.Enable()
+		} else {
+			button.Disable()
+		}
+	}
+	setEnabled(s.minus, s.value > s.Min)
+	setEnabled(s.plus, s.value < s.Max)
+}
+
+// normalize приводит значение к диапазону и шагу
+func (s *NumberSpinner) normalize(value float64) float64 {
+	if s.Step > 0 {
+		value = s.Min + math.Round((value-s.Min)/s.Step)*s.Step
+	}
+	return math.Max(s.Min, math.Min(s.Max, value))
+}
+
+// format записывает значение с заданным числом знаков
+func (s *NumberSpinner) format(value float64) string {
+	return strconv.FormatFloat(value, 'f', s.Decimals, 64)
+}
+
+// parse читает число из поля; запятая принимается как десятичный разделитель
+func (s *NumberSpinner) parse(text string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(text), ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("введите число")
+	}
+	if value < s.Min || value > s.Max {
+		return 0, fmt.Errorf("от %s до %s", s.format(s.Min), s.format(s.Max))
+	}
+	if s.Step > 0 && math.Abs(s.normalize(value)-value) > 1e-9 {
+		return 0, fmt.Errorf("с шагом %s", strconv.FormatFloat(s.Step, 'f', -1, 64))
+	}
+	return value, nil
+}
+
+// TypedKey меняет значение стрелками вверх и вниз
+func (e *spinnerEntry) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyUp:
+		e.spinner.stepBy(1)
+	case fyne.KeyDown:
+		e.spinner.stepBy(-1)
+	default:
+		e.Entry.TypedKey(key)
+	}
+}
+
+// FocusLost возвращает последнее верное значение вместо неверного ввода
+func (e *spinnerEntry) FocusLost() {
+	e.Entry.FocusLost()
+	if _, err := e.spinner.parse(e.Text); err != nil {
+		e.SetText(e.spinner.format(e.spinner.value))
+	}
+}
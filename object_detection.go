@@ -0,0 +1,167 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// По расстоянию в сантиметрах выводится признак «объект обнаружен»: он
+// включается, когда предмет ближе порога, и выключается, только когда предмет
+// отошел дальше порога на величину гистерезиса. Новое состояние принимается,
+// если оно держится objectDebounce, поэтому дрожание показаний у порога не
+// переключает признак
+
+const (
+	defaultObjectThreshold  = 15.0                   // Порог обнаружения по умолчанию, см
+	defaultObjectHysteresis = 3.0                    // Гистерезис по умолчанию, см
+	objectDebounce          = 150 * time.Millisecond // Сколько должно держаться новое состояние
+)
+
+// objectPortState состояние признака на порту
+type objectPortState struct {
+	detected   bool
+	generation int // Номер ожидаемой смены; по нему отмененный таймер узнает, что устарел
+	pending    *time.Timer
+}
+
+// ObjectDetector выводит признак «объект обнаружен» из показаний датчиков расстояния
+type ObjectDetector struct {
+	mu         sync.Mutex
+	threshold  float64
+	hysteresis float64
+	ports      map[byte]*objectPortState
+	onChange   func(portID byte, detected bool)
+}
+
+// NewObjectDetector создает детектор; onChange вызывается при смене состояния порта
+func NewObjectDetector(onChange func(portID byte, detected bool)) *ObjectDetector {
+	return &ObjectDetector{
+		threshold:  defaultObjectThreshold,
+		hysteresis: defaultObjectHysteresis,
+		ports:      make(map[byte]*objectPortState),
+		onChange:   onChange,
+	}
+}
+
+// Configure задает порог и гистерезис в сантиметрах
+func (d *ObjectDetector) Configure(threshold, hysteresis float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.threshold = threshold
+	d.hysteresis = math.Max(0, hysteresis)
+}
+
+// Update учитывает новое расстояние на порту
+func (d *ObjectDetector) Update(portID byte, distance float64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state, ok := d.ports[portID]
+	if !ok {
+		// Первое показание задает состояние сразу, без ожидания
+		state = &objectPortState{detected: distance < d.threshold}
+		d.ports[portID] = state
+		d.notify(portID, state.detected)
+		return
+	}
+
+	want := state.detected
+	if !state.detected && distance < d.threshold {
+		want = true
+	} else if state.detected && distance > d.threshold+d.hysteresis {
+		want = false
+	}
+
+	if want == state.detected {
+		if state.pending != nil {
+			state.pending.Stop()
+			state.pending = nil
+			state.generation++
+		}
+		return
+	}
+	if state.pending != nil {
+		return
+	}
+
+	state.generation++
+	generation := state.generation
+	state.pending = time.AfterFunc(objectDebounce, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if current, ok := d.ports[portID]; !ok || current != state || state.generation != generation {
+			return
+		}
+		state.pending = nil
+		state.detected = want
+		d.notify(portID, want)
+	})
+}
+
+// notify сообщает о смене состояния без блокировки детектора
+func (d *ObjectDetector) notify(portID byte, detected bool) {
+	if d.onChange != nil {
+		go d.onChange(portID, detected)
+	}
+}
+
+// Detected возвращает признак «объект обнаружен» на порту
+func (d *ObjectDetector) Detected(portID byte) (bool, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.ports[portID]
+	if !ok {
+		return false, false
+	}
+	return state.detected, true
+}
+
+// Forget сбрасывает состояние порта
+func (d *ObjectDetector) Forget(portID byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if state, ok := d.ports[portID]; ok && state.pending != nil {
+		state.pending.Stop()
+	}
+	delete(d.ports, portID)
+}
+
+// Reset сбрасывает состояние всех портов
+func (d *ObjectDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, state := range d.ports {
+		if state.pending != nil {
+			state.pending.Stop()
+		}
+	}
+	d.ports = make(map[byte]*objectPortState)
+}
+
+// ObjectDetector возвращает детектор признака «объект обнаружен»
+func (hm *HubManager) ObjectDetector() *ObjectDetector {
+	return hm.objects
+}
+
+// ObjectDetected возвращает признак «объект обнаружен» для датчика расстояния на порту
+func (hm *HubManager) ObjectDetected(portID byte) (bool, bool) {
+	return hm.objects.Detected(portID)
+}
+
+// publishObjectDetected публикует смену признака «объект обнаружен»
+func (hm *HubManager) publishObjectDetected(portID byte, detected bool) {
+	hm.events.Publish(Event{
+		Type:   EventObjectDetected,
+		PortID: portID,
+		Value:  boolValue(detected),
+	})
+}
+
+// boolValue переводит признак в значение условия: 1 — да, 0 — нет
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// defaultOSCPort порт OSC по умолчанию (Pure Data, TouchDesigner)
+const defaultOSCPort = 9000
+
+// OSCOutput отправляет значения датчиков и события программы
+// по протоколу OSC (Open Sound Control) через UDP
+type OSCOutput struct {
+	events      *EventBus
+	conn        net.Conn
+	unsubscribe []func()
+	mu          sync.Mutex
+}
+
+// NewOSCOutput создает выход OSC
+func NewOSCOutput(events *EventBus) *OSCOutput {
+	return &OSCOutput{events: events}
+}
+
+// Configure включает отправку на host:port или выключает ее
+func (o *OSCOutput) Configure(enabled bool, host string, port int) error {
+	o.Stop()
+
+	if !enabled {
+		return nil
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("неверный порт OSC: %d", port)
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения OSC к %s: %v", address, err)
+	}
+
+	o.mu.Lock()
+	o.conn = conn
+	o.unsubscribe = []func(){
+		o.events.Subscribe(EventSensorValue, func(event Event) {
+			o.send(fmt.Sprintf("/wedo/sensor/%d", event.PortID), float32(event.Value))
+		}),
+		o.events.Subscribe(EventMessage, func(event Event) {
+			o.send("/wedo/message", event.Name)
+		}),
+		o.events.Subscribe(EventProgramState, func(event Event) {
+			o.send("/wedo/program", event.Name)
+		}),
+		o.events.Subscribe(EventVisionMotion, func(event Event) {
+			o.send("/wedo/vision/motion", float32(event.Value))
+		}),
+		o.events.Subscribe(EventVisionColor, func(event Event) {
+			o.send("/wedo/vision/color", event.Name)
+		}),
+		o.events.Subscribe(EventSpeech, func(event Event) {
+			o.send("/wedo/speech", event.Name)
+		}),
+	}
+	o.mu.Unlock()
+
+	log.Printf("Отправка OSC включена: %s", address)
+	return nil
+}
+
+// Stop выключает отправку OSC
+func (o *OSCOutput) Stop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, unsubscribe := range o.unsubscribe {
+		unsubscribe()
+	}
+	o.unsubscribe = nil
+
+	if o.conn != nil {
+		o.conn.Close()
+		o.conn = nil
+		log.Println("Отправка OSC выключена")
+	}
+}
+
+// send отправляет одно OSC-сообщение
+func (o *OSCOutput) send(address string, args ...interface{}) {
+	packet, err := encodeOSCMessage(address, args...)
+	if err != nil {
+		log.Printf("Ошибка кодирования OSC: %v", err)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.conn == nil {
+		return
+	}
+	// UDP: получатель может быть еще не запущен, ошибки не критичны
+	o.conn.Write(packet)
+}
+
+// encodeOSCMessage кодирует OSC-сообщение с аргументами float32, int32 и string
+func encodeOSCMessage(address string, args ...interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	writeOSCString(&buf, address)
+
+	tags := ","
+	var payload bytes.Buffer
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case float32:
+			tags += "f"
+			binary.Write(&payload, binary.BigEndian, math.Float32bits(v))
+		case int32:
+			tags += "i"
+			binary.Write(&payload, binary.BigEndian, v)
+		case string:
+			tags += "s"
+			writeOSCString(&payload, v)
+		default:
+			return nil, fmt.Errorf("неподдерживаемый тип аргумента OSC: %T", arg)
+		}
+	}
+
+	writeOSCString(&buf, tags)
+	buf.Write(payload.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeOSCString записывает строку с завершающим нулем, выровненную до 4 байт
+func writeOSCString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	padding := 4 - len(s)%4
+	buf.Write(make([]byte, padding))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// settingArbitration ключ настройки политики совместного управления выходами
+const settingArbitration = "output_arbitration"
+
+// arbitrationHold сколько порт остается за источником после его последней команды
+const arbitrationHold = 2 * time.Second
+
+// CommandSource источник команды выходу хаба
+type CommandSource int
+
+// Источники команд в порядке возрастания приоритета
+const (
+	SourceProgram CommandSource = iota // Выполняющаяся программа
+	SourceManual                       // Ручное управление: кнопки проверки и панель устройства
+	SourceSystem                       // Приложение: остановка, питание, проверка комплекта, диагностика
+)
+
+// String название источника для сообщений
+func (s CommandSource) String() string {
+	switch s {
+	case SourceManual:
+		return "ручное управление"
+	case SourceSystem:
+		return "приложение"
+	default:
+		return "программа"
+	}
+}
+
+// ArbitrationPolicy политика, по которой решается, чья команда попадет на занятый порт
+type ArbitrationPolicy string
+
+// Политики совместного управления выходами
+const (
+	// ArbitrationLastWriter побеждает последняя команда источника с тем же или
+	// более высоким приоритетом. Команды источника ниже приоритетом молча
+	// пропускаются, пока порт занят
+	ArbitrationLastWriter ArbitrationPolicy = "last_writer"
+	// ArbitrationExclusive порт принадлежит первому источнику, пока тот не
+	// остановит выход или не перестанет им управлять. Команды других источников
+	// отклоняются с ошибкой
+	ArbitrationExclusive ArbitrationPolicy = "exclusive"
+)
+
+// arbitrationPolicies политики с названиями для интерфейса
+var arbitrationPolicies = []struct {
+	policy ArbitrationPolicy
+	name   string
+}{
+	{ArbitrationLastWriter, "По приоритету, побеждает последняя команда"},
+	{ArbitrationExclusive, "Порт занимает первый источник"},
+}
+
+// arbitrationPolicyName название политики для интерфейса
+func arbitrationPolicyName(policy ArbitrationPolicy) string {
+	for _, item := range arbitrationPolicies {
+		if item.policy == policy {
+			return item.name
+		}
+	}
+	return arbitrationPolicies[0].name
+}
+
+// portClaim источник, который сейчас управляет портом
+type portClaim struct {
+	source    CommandSource
+	running   bool      // Мотор включен и работает до остановки
+	busyUntil time.Time // До какого момента выход работает по последней команде
+}
+
+// active сообщает, что порт еще занят источником
+func (c portClaim) active(now time.Time) bool {
+	return c.running || now.Before(c.busyUntil.Add(arbitrationHold))
+}
+
+// OutputArbiter решает, какие команды выходам отправлять, когда программой,
+// кнопками проверки и приложением одновременно управляется один порт.
+// Без него команды разных источников перемешиваются, и мотор дергается
+type OutputArbiter struct {
+	mu     sync.Mutex
+	policy ArbitrationPolicy
+	claims map[byte]portClaim
+}
+
+// NewOutputArbiter создает арбитра с политикой «побеждает последняя команда»
+func NewOutputArbiter() *OutputArbiter {
+	return &OutputArbiter{policy: ArbitrationLastWriter, claims: make(map[byte]portClaim)}
+}
+
+// SetPolicy задает политику совместного управления
+func (a *OutputArbiter) SetPolicy(policy ArbitrationPolicy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if policy != ArbitrationExclusive {
+		policy = ArbitrationLastWriter
+	}
+	a.policy = policy
+}
+
+// Admit решает, отправлять ли команду. send == false и err == nil означает,
+// что команду нужно молча пропустить; ошибка — что порт занят другим источником
+func (a *OutputArbiter) Admit(cmd *OutputCommand, now time.Time) (send bool, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	claim, claimed := a.claims[cmd.port]
+	if claimed && claim.active(now) && claim.source != cmd.source && cmd.source != SourceSystem {
+		switch {
+		case a.policy == ArbitrationExclusive:
+			return false, fmt.Errorf("порт %d занят: им управляет %s", cmd.port, claim.source)
+		case cmd.source < claim.source:
+			log.Printf("Порт %d: команда (%s) пропущена, портом управляет %s", cmd.port, cmd.source, claim.source)
+			return false, nil
+		}
+	}
+
+	switch {
+	case cmd.source == SourceSystem || cmd.releases():
+		delete(a.claims, cmd.port)
+	default:
+		running, _ := cmd.motorRunning()
+		a.claims[cmd.port] = portClaim{source: cmd.source, running: running, busyUntil: cmd.busyUntil(now)}
+	}
+	return true, nil
+}
+
+// Release освобождает все порты источника, например когда программа завершилась
+func (a *OutputArbiter) Release(source CommandSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for port, claim := range a.claims {
+		if claim.source == source {
+			delete(a.claims, port)
+		}
+	}
+}
+
+// Reset освобождает все порты после отключения хаба
+func (a *OutputArbiter) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.claims = make(map[byte]portClaim)
+}
+
+// From помечает источник команды; без пометки команда считается командой программы
+func (c *OutputCommand) From(source CommandSource) *OutputCommand {
+	c.source = source
+	return c
+}
+
+// releases сообщает, что команда останавливает выход и освобождает порт
+func (c *OutputCommand) releases() bool {
+	if c.command == outputCommandStopTone {
+		return true
+	}
+	running, isMotor := c.motorRunning()
+	return isMotor && !running
+}
+
+// busyUntil момент, до которого выход работает по команде: звук — свою
+// длительность, светодиод и мотор — мгновенно (включенный мотор учитывается отдельно)
+func (c *OutputCommand) busyUntil(now time.Time) time.Time {
+	if c.command == outputCommandTone && len(c.payload) == 4 {
+		duration := time.Duration(uint16(c.payload[2])|uint16(c.payload[3])<<8) * time.Millisecond
+		return now.Add(duration)
+	}
+	return now
+}
+
+// Arbiter возвращает арбитра команд выходам
+func (hm *HubManager) Arbiter() *OutputArbiter {
+	return hm.arbiter
+}
+
+// ManualControl ручное управление устройствами: кнопки проверки в свойствах
+// блоков и панель устройства. Команды идут от источника «ручное управление»
+type ManualControl struct {
+	dm *DeviceManager
+}
+
+// Manual возвращает ручное управление устройствами
+func (dm *DeviceManager) Manual() ManualControl {
+	return ManualControl{dm: dm}
+}
+
+// SetManualActivityCallback устанавливает callback команд ручного управления:
+// проверка устройства кнопкой — тоже работа с приложением
+func (dm *DeviceManager) SetManualActivityCallback(callback func()) {
+	dm.manualActivityCallback = callback
+}
+
+// touch сообщает о команде ручного управления
+func (m ManualControl) touch() {
+	if m.dm.manualActivityCallback != nil {
+		m.dm.manualActivityCallback()
+	}
+}
+
+// SetMotorPower включает мотор; при ненулевой длительности он остановится сам
+func (m ManualControl) SetMotorPower(portID byte, power int8, duration uint16) error {
+	m.touch()
+	return m.dm.setMotorPower(SourceManual, portID, power, duration)
+}
+
+// StopMotor останавливает мотор и освобождает порт
+func (m ManualControl) StopMotor(portID byte) error {
+	m.touch()
+	return m.dm.hubMgr.SendCommand(NewMotorCommand(portID).Stop().From(SourceManual))
+}
+
+// SetLEDColor устанавливает цвет светодиода
+func (m ManualControl) SetLEDColor(portID byte, red, green, blue byte) error {
+	m.touch()
+	return m.dm.setLEDColor(SourceManual, portID, red, green, blue)
+}
+
+// PlayTone воспроизводит тон на пищалке
+func (m ManualControl) PlayTone(portID byte, frequency uint16, duration uint16) error {
+	m.touch()
+	return m.dm.playTone(SourceManual, portID, frequency, duration)
+}
+
+// StopTone останавливает пищалку и освобождает порт
+func (m ManualControl) StopTone(portID byte) error {
+	m.touch()
+	return m.dm.stopTone(SourceManual, portID)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"image/color"
+	"log"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+)
+
+// previewGapTime длительность раздвигания цепочки под призраком блока
+const previewGapTime = 150 * time.Millisecond
+
+// PaletteButton кнопка палитры: нажатие добавляет блок как раньше, а
+// перетаскивание на холст показывает полупрозрачный призрак блока в месте,
+// куда он встанет, и добавляет блок туда
+type PaletteButton struct {
+	AccessibleButton
+	gui       *MainGUI
+	blockType BlockType
+}
+
+// NewPaletteButton создает кнопку палитры для типа блока
+func NewPaletteButton(gui *MainGUI, blockType BlockType, tapped func()) *PaletteButton {
+	b := &PaletteButton{gui: gui, blockType: blockType}
+	b.announce = gui.announce
+	b.Text = gui.getBlockName(blockType)
+	b.Icon = iconResource(blockIconName(blockType))
+	b.OnTapped = tapped
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// Dragged показывает призрак блока под указателем
+func (b *PaletteButton) Dragged(e *fyne.DragEvent) {
+	if b.Disabled() || b.gui.readOnly() {
+		return
+	}
+	b.gui.programPanel.previewInsertion(b.blockType, e.AbsolutePosition)
+}
+
+// DragEnd добавляет блок на место призрака
+func (b *PaletteButton) DragEnd() {
+	b.gui.programPanel.dropFromPalette(b.blockType)
+}
+
+// insertionPreview призрак нового блока и раздвинутая под него цепочка
+type insertionPreview struct {
+	ghost   *fyne.Container
+	conn    *ConnectionLine       // Соединение, в которое встанет блок; nil — свободное место
+	pos     fyne.Position         // Место блока на холсте
+	shifted map[int]fyne.Position // Исходные позиции сдвинутых блоков цепочки
+	anim    *fyne.Animation
+}
+
+// newBlockGhost создает полупрозрачный призрак блока заданного типа
+func (p *ProgramPanel) newBlockGhost(blockType BlockType) *fyne.Container {
+	template := &ProgramBlock{Type: blockType, Width: 150, Height: 80, Parameters: make(map[string]interface{})}
+	p.programMgr.configureBlock(template)
+
+	fill := color.NRGBA{R: 100, G: 100, B: 100, A: 255}
+	if c, ok := parseColor(p.gui.blockStyle(template).Color).(color.NRGBA); ok {
+		fill = c
+	}
+	fill.A = 110
+
+	size := fyne.NewSize(float32(template.Width), float32(template.Height))
+	bg := canvas.NewRectangle(fill)
+	bg.CornerRadius = 5
+	bg.StrokeColor = color.NRGBA{R: 255, G: 255, B: 255, A: 160}
+	bg.StrokeWidth = 1.5
+	bg.Resize(size)
+
+	title := canvas.NewText(template.Title, color.NRGBA{R: 255, G: 255, B: 255, A: 200})
+	title.TextStyle.Bold = true
+	title.Alignment = fyne.TextAlignCenter
+	title.Resize(fyne.NewSize(size.Width, title.MinSize().Height))
+	title.Move(fyne.NewPos(0, (size.Height-title.MinSize().Height)/2))
+
+	ghost := container.NewWithoutLayout(bg, title)
+	ghost.Resize(size)
+	return ghost
+}
+
+// canvasPosition переводит координаты окна в координаты холста с учетом прокрутки
+func (p *ProgramPanel) canvasPosition(absolute fyne.Position) (fyne.Position, bool) {
+	driver := fyne.CurrentApp().Driver()
+	scrollPos := driver.AbsolutePositionForObject(p.scroll)
+	inside := absolute.X >= scrollPos.X && absolute.Y >= scrollPos.Y &&
+		absolute.X <= scrollPos.X+p.scroll.Size().Width && absolute.Y <= scrollPos.Y+p.scroll.Size().Height
+	return absolute.Subtract(driver.AbsolutePositionForObject(p.content)), inside
+}
+
+// insertionConnection находит соединение, между блоками которого находится точка
+func (p *ProgramPanel) insertionConnection(pos fyne.Position) *ConnectionLine {
+	for _, conn := range p.connections {
+		from, fromExists := p.blockWidgets[conn.fromBlockID]
+		to, toExists := p.blockWidgets[conn.toBlockID]
+		if !fromExists || !toExists || !from.Visible() || !to.Visible() || isHatBlock(to.block.Type) {
+			continue
+		}
+		fromCenter := from.Position().Y + from.Size().Height/2
+		toCenter := to.Position().Y + to.Size().Height/2
+		left := fyne.Min(from.Position().X, to.Position().X) - layoutIndent
+		right := fyne.Max(from.Position().X+from.Size().Width, to.Position().X+to.Size().Width) + layoutIndent
+		if pos.Y > fromCenter && pos.Y < toCenter && pos.X > left && pos.X < right {
+			return conn
+		}
+	}
+	return nil
+}
+
+// previewInsertion показывает призрак блока: между блоками соединения цепочка
+// раздвигается под него, на свободном месте он следует за указателем
+func (p *ProgramPanel) previewInsertion(blockType BlockType, absolute fyne.Position) {
+	pos, inside := p.canvasPosition(absolute)
+	if !inside {
+		p.clearPreview()
+		return
+	}
+
+	if p.preview == nil {
+		p.preview = &insertionPreview{ghost: p.newBlockGhost(blockType), shifted: make(map[int]fyne.Position)}
+		p.content.Add(p.preview.ghost)
+	}
+	preview := p.preview
+	size := preview.ghost.Size()
+
+	var conn *ConnectionLine
+	if !isHatBlock(blockType) {
+		conn = p.insertionConnection(pos)
+	}
+	if conn != preview.conn {
+		p.closeGap()
+		preview.conn = conn
+		if conn != nil {
+			p.openGap(conn, size.Height+layoutRowGap)
+		}
+	}
+
+	if conn != nil {
+		from := p.blockWidgets[conn.fromBlockID]
+		preview.pos = fyne.NewPos(from.Position().X, from.Position().Y+from.Size().Height+layoutRowGap)
+	} else {
+		preview.pos = fyne.NewPos(fyne.Max(0, pos.X-size.Width/2), fyne.Max(0, pos.Y-size.Height/2))
+	}
+	preview.ghost.Move(preview.pos)
+	p.content.Refresh()
+}
+
+// openGap плавно сдвигает вниз цепочку, начиная с блока назначения соединения
+func (p *ProgramPanel) openGap(conn *ConnectionLine, shift float32) {
+	preview := p.preview
+	visited := map[int]bool{conn.fromBlockID: true}
+	for id := conn.toBlockID; id > 0 && !visited[id]; {
+		visited[id] = true
+		target, exists := p.blockWidgets[id]
+		if !exists {
+			break
+		}
+		preview.shifted[id] = target.Position()
+		id = target.block.NextBlockID
+	}
+
+	starts := make(map[int]fyne.Position, len(preview.shifted))
+	for id, start := range preview.shifted {
+		starts[id] = start
+	}
+	preview.anim = fyne.NewAnimation(previewGapTime, func(progress float32) {
+		for id, start := range starts {
+			p.blockWidgets[id].Move(start.AddXY(0, shift*progress))
+		}
+		p.updateConnections()
+	})
+	preview.anim.Curve = fyne.AnimationEaseOut
+	preview.anim.Start()
+}
+
+// closeGap возвращает сдвинутые блоки на место
+func (p *ProgramPanel) closeGap() {
+	preview := p.preview
+	if preview.anim != nil {
+		preview.anim.Stop()
+		preview.anim = nil
+	}
+	for id, start := range preview.shifted {
+		if blockWidget, exists := p.blockWidgets[id]; exists {
+			blockWidget.Move(start)
+		}
+	}
+	preview.shifted = make(map[int]fyne.Position)
+	p.updateConnections()
+}
+
+// clearPreview убирает призрак и возвращает раздвинутую цепочку
+func (p *ProgramPanel) clearPreview() {
+	if p.preview == nil {
+		return
+	}
+	p.closeGap()
+	p.removeObject(p.preview.ghost)
+	p.preview = nil
+	p.content.Refresh()
+}
+
+// dropFromPalette добавляет блок на место призрака: в соединение, над которым
+// раздвинута цепочка, или в свободное место холста
+func (p *ProgramPanel) dropFromPalette(blockType BlockType) {
+	preview := p.preview
+	if preview == nil {
+		return
+	}
+	conn, pos := preview.conn, preview.pos
+	p.clearPreview()
+
+	p.gui.checkpoint()
+	block := p.programMgr.CreateBlock(blockType, float64(pos.X), float64(pos.Y))
+	if conn != nil {
+		p.selectedConn = conn
+	} else {
+		p.clearConnectionSelection()
+	}
+	p.AddBlock(block)
+	p.gui.refreshToolbarState()
+	p.gui.announce("Добавлен " + blockAccessibleName(block, false))
+	p.gui.sounds.Play(SoundBlockAdded)
+
+	log.Printf("Блок %s (ID: %d) перетащен из палитры", block.Title, block.ID)
+}
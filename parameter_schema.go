@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Схема параметров блоков. Тип значения задает параметр по умолчанию
+// (decodeParameter), а схема — допустимые значения: порты хаба и диапазоны
+// чисел. Она проверяет программу при загрузке файла и параметры, измененные
+// в редакторе и в режиме урока, чтобы мотор не получил мощность 120%,
+// а пищалка — частоту, которую не воспроизводит
+
+// parameterPorts порты хаба, к которым обращаются блоки: внешние 1 и 2 и встроенный светодиод
+var parameterPorts = []byte{1, 2, 6}
+
+// parameterRange допустимый диапазон числового параметра
+type parameterRange struct {
+	min float64
+	max float64
+}
+
+// Диапазоны параметров
+var (
+	powerRange     = parameterRange{-100, 100}
+	frequencyRange = parameterRange{100, 2000}
+)
+
+// parameterSchema диапазоны числовых параметров по типам блоков. Выходной
+// диапазон привязки к датчику ограничен так же, как сам параметр
+var parameterSchema = map[BlockType]map[string]parameterRange{
+	BlockTypeMotor: {
+		"power":                        powerRange,
+		"power_min":                    powerRange,
+		"power_max":                    powerRange,
+		bindingKey("power", "out_min"): powerRange,
+		bindingKey("power", "out_max"): powerRange,
+	},
+	BlockTypeSound: {
+		"frequency":                        frequencyRange,
+		bindingKey("frequency", "out_min"): frequencyRange,
+		bindingKey("frequency", "out_max"): frequencyRange,
+	},
+	BlockTypeFollow: {
+		"max_power": {0, 100},
+	},
+}
+
+// ParameterError ошибка параметра с путем к нему в файле программы,
+// например blocks[3].params.power
+type ParameterError struct {
+	Path    string
+	BlockID int
+	Err     error
+}
+
+// Error описывает ошибку вместе с путем и ID блока
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("%s (блок %d): %v", e.Path, e.BlockID, e.Err)
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// parameterPath путь к параметру блока в файле программы
+func parameterPath(index int, key string) string {
+	return fmt.Sprintf("blocks[%d].params.%s", index, key)
+}
+
+// isPortParameter сообщает, что параметр хранит номер порта хаба
+func isPortParameter(key string) bool {
+	return key == "port" || strings.HasSuffix(key, "_port")
+}
+
+// checkParameter проверяет значение параметра блока по схеме
+func checkParameter(blockType BlockType, key string, value interface{}) error {
+	if isPortParameter(key) {
+		if port, ok := value.(byte); ok {
+			for _, allowed := range parameterPorts {
+				if port == allowed {
+					return nil
+				}
+			}
+			return fmt.Errorf("порт %d не существует, ожидается 1, 2 или 6", port)
+		}
+	}
+
+	limits, ok := parameterSchema[blockType][key]
+	if !ok {
+		return nil
+	}
+	number, ok := paramNumber(map[string]interface{}{key: value}, key)
+	if !ok {
+		return fmt.Errorf("ожидается число %g..%g", limits.min, limits.max)
+	}
+	if number < limits.min || number > limits.max {
+		return fmt.Errorf("значение %g вне диапазона %g..%g", number, limits.min, limits.max)
+	}
+	return nil
+}
+
+// decodeBlockParameter приводит значение из JSON или поля ввода к типу
+// параметра по умолчанию и проверяет его по схеме
+func decodeBlockParameter(blockType BlockType, defaultValue interface{}, key string, value interface{}) (interface{}, error) {
+	converted, err := decodeParameter(defaultValue, key, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkParameter(blockType, key, converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// validateBlockParameters проверяет все параметры блока, например после
+// изменения в редакторе. Путь в ошибке указывается от блока: params.power
+func validateBlockParameters(block *ProgramBlock) error {
+	keys := make([]string, 0, len(block.Parameters))
+	for key := range block.Parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := checkParameter(block.Type, key, block.Parameters[key]); err != nil {
+			return &ParameterError{Path: "params." + key, BlockID: block.ID, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PortConflict выход хаба, которым могут одновременно управлять несколько
+// параллельных ветвей программы. Запуск не запрещается, но команды ветвей
+// перемешиваются, и мотор дергается или светодиод мигает не тем цветом
+type PortConflict struct {
+	Port       byte
+	DeviceType byte
+	BlockIDs   []int  // Блоки разных ветвей, управляющие выходом
+	Message    string // Что конфликтует
+	Suggestion string // Как исправить
+}
+
+// portUse блок цепочки, который управляет выходом
+type portUse struct {
+	block *ProgramBlock
+	chain int
+	index int // Место блока в цепочке
+}
+
+// concurrentWith сообщает, что блоки могут управлять выходом одновременно:
+// они из разных цепочек или второй выполняется после запуска регулятора первого
+func (u portUse) concurrentWith(other portUse) bool {
+	if u.chain != other.chain {
+		return true
+	}
+	first, second := u, other
+	if second.index < first.index {
+		first, second = second, first
+	}
+	return first.block.Type == BlockTypeFollow && second.index > first.index
+}
+
+// isOutputDevice сообщает, что устройство принимает команды, а не только сообщает значения
+func isOutputDevice(deviceType byte) bool {
+	switch deviceType {
+	case DEVICE_TYPE_MOTOR, DEVICE_TYPE_RGB_LIGHT, DEVICE_TYPE_PIEZO_TONE:
+		return true
+	default:
+		return false
+	}
+}
+
+// chainStarts возвращает первые блоки всех цепочек, которые могут выполняться
+// одновременно: стартовые и блоки-шапки событий
+func (pm *ProgramManager) chainStarts() []*ProgramBlock {
+	starts := pm.startBlocks()
+	for _, block := range pm.program.Blocks {
+		if isHatBlock(block.Type) && !block.IsStart {
+			starts = append(starts, block)
+		}
+	}
+	return starts
+}
+
+// PortConflicts находит выходы, которыми управляют разные цепочки. Регулятор
+// «Держать расстояние» работает в фоне до конца программы, поэтому конфликтует
+// и с блоками своей цепочки, которые выполняются после него
+func (pm *ProgramManager) PortConflicts() []PortConflict {
+	uses := make(map[requiredDevice][]portUse)
+	for chain, start := range pm.chainStarts() {
+		sequence, _ := pm.buildSequence(start)
+		for index, block := range sequence {
+			for _, device := range blockRequiredDevices(block) {
+				if isOutputDevice(device.deviceType) {
+					uses[device] = append(uses[device], portUse{block: block, chain: chain, index: index})
+				}
+			}
+		}
+	}
+
+	var conflicts []PortConflict
+	for device, list := range uses {
+		var concurrent []portUse
+		for i, use := range list {
+			for j, other := range list {
+				if i != j && use.concurrentWith(other) {
+					concurrent = append(concurrent, use)
+					break
+				}
+			}
+		}
+		if len(concurrent) > 0 {
+			conflicts = append(conflicts, newPortConflict(device, concurrent))
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Port != conflicts[j].Port {
+			return conflicts[i].Port < conflicts[j].Port
+		}
+		return conflicts[i].DeviceType < conflicts[j].DeviceType
+	})
+	return conflicts
+}
+
+// newPortConflict описывает конфликт и предлагает способ его устранить
+func newPortConflict(device requiredDevice, uses []portUse) PortConflict {
+	conflict := PortConflict{Port: device.port, DeviceType: device.deviceType}
+
+	seen := make(map[int]bool)
+	var names []string
+	hasFollow := false
+	for _, use := range uses {
+		if seen[use.block.ID] {
+			continue
+		}
+		seen[use.block.ID] = true
+		conflict.BlockIDs = append(conflict.BlockIDs, use.block.ID)
+		names = append(names, fmt.Sprintf("«%s» (ID: %d)", use.block.Title, use.block.ID))
+		hasFollow = hasFollow || use.block.Type == BlockTypeFollow
+	}
+
+	if len(names) == 1 {
+		conflict.Message = fmt.Sprintf("Порт %d (%s): блок %s выполняется сразу в нескольких цепочках",
+			device.port, DeviceTypeName(device.deviceType), names[0])
+	} else {
+		conflict.Message = fmt.Sprintf("Порт %d (%s): одновременно управляют %s",
+			device.port, DeviceTypeName(device.deviceType), strings.Join(names, ", "))
+	}
+	if hasFollow {
+		conflict.Suggestion = "Регулятор держит мотор до конца программы: уберите другие блоки этого мотора или подключите их к другому порту"
+	} else {
+		conflict.Suggestion = "Объедините эти действия в одну цепочку или передавайте порт по очереди: " +
+			"ветвь заканчивает работу с портом и отправляет сообщение, другая начинает по «Когда получено»"
+	}
+	return conflict
+}
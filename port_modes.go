@@ -0,0 +1,103 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// portMode режим, в котором сейчас настроен порт хаба
+type portMode struct {
+	deviceType byte
+	mode       byte
+	delta      uint32
+	notify     bool
+}
+
+// PortModeCache запоминает настроенные режимы портов, чтобы не повторять настройку
+type PortModeCache struct {
+	mu    sync.Mutex
+	modes map[byte]portMode
+}
+
+// NewPortModeCache создает пустой кэш режимов портов
+func NewPortModeCache() *PortModeCache {
+	return &PortModeCache{modes: make(map[byte]portMode)}
+}
+
+// Matches сообщает, настроен ли порт уже так же, как в команде
+func (c *PortModeCache) Matches(cmd *InputFormatCommand) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, exists := c.modes[cmd.port]
+	return exists && current == cmd.portMode()
+}
+
+// Store запоминает режим, отправленный на порт
+func (c *PortModeCache) Store(cmd *InputFormatCommand) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modes[cmd.port] = cmd.portMode()
+}
+
+// Mode возвращает режим, в котором сейчас настроен порт
+func (c *PortModeCache) Mode(port byte) (byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current, exists := c.modes[port]
+	return current.mode, exists
+}
+
+// Forget сбрасывает режим порта, например после смены устройства
+func (c *PortModeCache) Forget(port byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.modes, port)
+}
+
+// Reset сбрасывает режимы всех портов
+func (c *PortModeCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modes = make(map[byte]portMode)
+}
+
+// ForgetPortMode сбрасывает запомненный режим порта хаба, чтобы следующая
+// настройка того же режима отправилась хабу
+func (hm *HubManager) ForgetPortMode(port byte) {
+	hm.portModes.Forget(port)
+}
+
+// PortMode возвращает режим, в котором сейчас настроен порт хаба
+func (hm *HubManager) PortMode(port byte) (byte, bool) {
+	return hm.portModes.Mode(port)
+}
+
+// portMode возвращает режим, который задает команда
+func (c *InputFormatCommand) portMode() portMode {
+	return portMode{
+		deviceType: c.deviceType,
+		mode:       c.mode,
+		delta:      c.delta,
+		notify:     c.notify,
+	}
+}
+
+// ConfigurePort отправляет настройку порта, только если его режим отличается от запрошенного
+func (hm *HubManager) ConfigurePort(cmd *InputFormatCommand) error {
+	// Без хаба настраивать нечего, а запомненный режим помешал бы настройке после подключения
+	if hm.replayingOffline() {
+		return nil
+	}
+	if hm.portModes.Matches(cmd) {
+		log.Printf("Порт %d уже настроен в режиме %d, настройка пропущена", cmd.port, cmd.mode)
+		return nil
+	}
+
+	if err := hm.SendCommand(cmd); err != nil {
+		hm.portModes.Forget(cmd.port)
+		return err
+	}
+	hm.portModes.Store(cmd)
+	return nil
+}
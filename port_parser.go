@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/binary"
 	"log"
+	"math"
 )
 
 // PortMessage парсит сообщения о портах
@@ -123,3 +124,57 @@ func DecodeSensorValues(data []byte, portID byte) interface{} {
 
 	return nil
 }
+
+// SensorReading значение датчика из уведомления
+type SensorReading struct {
+	PortID byte
+	Value  float64
+
+	// Y второй угол датчика наклона в режиме угла (наклон вперед-назад), если HasY
+	Y    float64
+	HasY bool
+}
+
+// ParseSensorValues разбирает уведомление характеристики значений сенсоров.
+// Формат: [ревизия, порт, значение(float32 LE), порт, значение, ...];
+// короткий вариант [ревизия, порт, байт] используется в режиме RAW.
+// Датчик наклона в режиме угла присылает отдельным уведомлением два значения:
+// [ревизия, порт, X(float32 LE), Y(float32 LE)].
+func ParseSensorValues(data []byte) []SensorReading {
+	if len(data) < 3 {
+		return nil
+	}
+
+	var readings []SensorReading
+	for i := 1; i < len(data); {
+		remaining := len(data) - i
+		switch {
+		case remaining == 9:
+			readings = append(readings, SensorReading{
+				PortID: data[i],
+				Value:  float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i+1 : i+5]))),
+				Y:      float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i+5 : i+9]))),
+				HasY:   true,
+			})
+			i += 9
+		case remaining >= 5:
+			bits := binary.LittleEndian.Uint32(data[i+1 : i+5])
+			readings = append(readings, SensorReading{
+				PortID: data[i],
+				Value:  float64(math.Float32frombits(bits)),
+			})
+			i += 5
+		case remaining == 2:
+			readings = append(readings, SensorReading{
+				PortID: data[i],
+				Value:  float64(data[i+1]),
+			})
+			i += 2
+		default:
+			log.Printf("Неполное значение сенсора: %x", data)
+			return readings
+		}
+	}
+
+	return readings
+}
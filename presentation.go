@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// presentationTextSize размер текста индикаторов в режиме презентации
+const presentationTextSize = 28
+
+// createPresentationBar создает крупную строку состояния для режима презентации
+func (gui *MainGUI) createPresentationBar() *fyne.Container {
+	textColor := color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+
+	gui.presentationState = canvas.NewText("", textColor)
+	gui.presentationState.TextSize = presentationTextSize
+	gui.presentationState.TextStyle.Bold = true
+
+	gui.presentationHub = canvas.NewText("", textColor)
+	gui.presentationHub.TextSize = presentationTextSize
+
+	runButton := widget.NewButtonWithIcon("Запуск", theme.MediaPlayIcon(), func() {
+		gui.toolbar.runButton.OnTapped()
+	})
+	runButton.Importance = widget.HighImportance
+
+	stopButton := widget.NewButtonWithIcon("Стоп", theme.MediaStopIcon(), func() {
+		gui.programMgr.StopProgram()
+	})
+	stopButton.Importance = widget.DangerImportance
+
+	exitButton := widget.NewButtonWithIcon("Выйти (F11)", theme.ViewRestoreIcon(), func() {
+		gui.setPresentationMode(false)
+	})
+	exitButton.Importance = widget.LowImportance
+
+	bar := container.NewHBox(
+		gui.presentationState,
+		layout.NewSpacer(),
+		gui.presentationHub,
+		layout.NewSpacer(),
+		runButton,
+		stopButton,
+		exitButton,
+	)
+	bar.Hide()
+	return bar
+}
+
+// togglePresentationMode переключает режим презентации
+func (gui *MainGUI) togglePresentationMode() {
+	gui.setPresentationMode(!gui.presentationMode)
+}
+
+// setPresentationMode включает режим презентации: скрывает палитру, панели и
+// панель инструментов, разворачивает окно на весь экран и показывает крупные индикаторы
+func (gui *MainGUI) setPresentationMode(enabled bool) {
+	if gui.presentationMode == enabled {
+		return
+	}
+	gui.presentationMode = enabled
+
+	if enabled {
+		gui.toolbarContainer.Hide()
+		gui.leftPanel.Hide()
+		gui.propertiesPanel.Hide()
+		gui.presentationBar.Show()
+		gui.updatePresentationBar()
+
+		gui.presentationStop = make(chan struct{})
+		go gui.refreshPresentationBar(gui.presentationStop)
+	} else {
+		close(gui.presentationStop)
+
+		gui.presentationBar.Hide()
+		gui.toolbarContainer.Show()
+		gui.leftPanel.Show()
+		if !gui.preferences().Bool(settingPropertiesOff) {
+			gui.propertiesPanel.Show()
+		}
+	}
+
+	gui.leftSplit.Refresh()
+	gui.rightSplit.Refresh()
+	gui.window.SetFullScreen(enabled)
+}
+
+// refreshPresentationBar обновляет индикаторы, пока включен режим презентации
+func (gui *MainGUI) refreshPresentationBar(stop chan struct{}) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fyne.Do(gui.updatePresentationBar)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// updatePresentationBar показывает состояние программы, хаба и батареи
+func (gui *MainGUI) updatePresentationBar() {
+	switch gui.programMgr.GetProgramState() {
+	case ProgramStateRunning:
+		gui.presentationState.Text = "▶ Программа выполняется"
+		gui.presentationState.Color = color.NRGBA{R: 76, G: 175, B: 80, A: 255}
+	case ProgramStateError:
+		gui.presentationState.Text = "✖ Ошибка программы"
+		gui.presentationState.Color = color.NRGBA{R: 244, G: 67, B: 54, A: 255}
+	default:
+		gui.presentationState.Text = "■ Программа остановлена"
+		gui.presentationState.Color = color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+	}
+
+	if gui.hubMgr.IsConnected() {
+		info := gui.hubMgr.GetHubInfo()
+		gui.presentationHub.Text = fmt.Sprintf("%s  🔋 %d%%", info.Name, info.Battery)
+	} else {
+		gui.presentationHub.Text = "Хаб не подключен"
+	}
+
+	gui.presentationState.Refresh()
+	gui.presentationHub.Refresh()
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	settingProfiles       = "profiles"        // Профили учеников и учителей в JSON
+	settingCurrentProfile = "current_profile" // Идентификатор выбранного профиля
+	profileKeyPrefix      = "profile."        // Префикс настроек профиля: profile.<id>.<ключ>
+	defaultProfileName    = "Общий"
+)
+
+// sharedSettings настройки компьютера, общие для всех профилей:
+// оборудование, защита учителя и учет хабов
+var sharedSettings = map[string]bool{
+	settingProfiles:       true,
+	settingCurrentProfile: true,
+	settingBLEAdapter:     true,
+	settingLastHubAddress: true,
+	settingAllowedHubs:    true,
+	settingTeacherPIN:     true,
+	settingPINFailures:    true,
+	settingPINLockedUntil: true,
+	settingProgramLocked:  true,
+	settingHubInventory:   true,
+	settingMotorUsage:     true,
+	settingCameraDevice:   true,
+	settingMicrophone:     true,
+	settingSpeechModelDir: true,
+}
+
+// Profile локальный профиль пользователя компьютера. Профиль с пустым ID — общий:
+// его настройки хранятся без префикса, как до появления профилей
+type Profile struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Teacher bool   `json:"teacher,omitempty"`
+}
+
+// DisplayName имя профиля для списков
+func (p Profile) DisplayName() string {
+	if p.ID == "" {
+		return defaultProfileName
+	}
+	if p.Teacher {
+		return p.Name + " (учитель)"
+	}
+	return p.Name
+}
+
+// profilePreferences хранилище настроек выбранного профиля: собственные настройки
+// профиля получают префикс, общие настройки компьютера читаются и пишутся как есть
+type profilePreferences struct {
+	fyne.Preferences
+	prefix string
+}
+
+// key возвращает ключ хранилища для настройки
+func (p profilePreferences) key(key string) string {
+	if p.prefix == "" || sharedSettings[key] {
+		return key
+	}
+	return p.prefix + key
+}
+
+// Методы хранилища переводят ключ настройки в ключ профиля и обращаются к общему хранилищу
+
+func (p profilePreferences) Bool(key string) bool { return p.Preferences.Bool(p.key(key)) }
+func (p profilePreferences) BoolWithFallback(key string, fallback bool) bool {
+	return p.Preferences.BoolWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetBool(key string, value bool) { p.Preferences.SetBool(p.key(key), value) }
+func (p profilePreferences) BoolList(key string) []bool     { return p.Preferences.BoolList(p.key(key)) }
+func (p profilePreferences) BoolListWithFallback(key string, fallback []bool) []bool {
+	return p.Preferences.BoolListWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetBoolList(key string, value []bool) {
+	p.Preferences.SetBoolList(p.key(key), value)
+}
+func (p profilePreferences) Float(key string) float64 { return p.Preferences.Float(p.key(key)) }
+func (p profilePreferences) FloatWithFallback(key string, fallback float64) float64 {
+	return p.Preferences.FloatWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetFloat(key string, value float64) {
+	p.Preferences.SetFloat(p.key(key), value)
+}
+func (p profilePreferences) FloatList(key string) []float64 {
+	return p.Preferences.FloatList(p.key(key))
+}
+func (p profilePreferences) FloatListWithFallback(key string, fallback []float64) []float64 {
+	return p.Preferences.FloatListWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetFloatList(key string, value []float64) {
+	p.Preferences.SetFloatList(p.key(key), value)
+}
+func (p profilePreferences) Int(key string) int { return p.Preferences.Int(p.key(key)) }
+func (p profilePreferences) IntWithFallback(key string, fallback int) int {
+	return p.Preferences.IntWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetInt(key string, value int) { p.Preferences.SetInt(p.key(key), value) }
+func (p profilePreferences) IntList(key string) []int     { return p.Preferences.IntList(p.key(key)) }
+func (p profilePreferences) IntListWithFallback(key string, fallback []int) []int {
+	return p.Preferences.IntListWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetIntList(key string, value []int) {
+	p.Preferences.SetIntList(p.key(key), value)
+}
+func (p profilePreferences) String(key string) string { return p.Preferences.String(p.key(key)) }
+func (p profilePreferences) StringWithFallback(key, fallback string) string {
+	return p.Preferences.StringWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetString(key string, value string) {
+	p.Preferences.SetString(p.key(key), value)
+}
+func (p profilePreferences) StringList(key string) []string {
+	return p.Preferences.StringList(p.key(key))
+}
+func (p profilePreferences) StringListWithFallback(key string, fallback []string) []string {
+	return p.Preferences.StringListWithFallback(p.key(key), fallback)
+}
+func (p profilePreferences) SetStringList(key string, value []string) {
+	p.Preferences.SetStringList(p.key(key), value)
+}
+func (p profilePreferences) RemoveValue(key string) { p.Preferences.RemoveValue(p.key(key)) }
+
+// loadProfiles читает список профилей; общий профиль всегда первый
+func loadProfiles(prefs fyne.Preferences) []Profile {
+	profiles := []Profile{{}}
+	var saved []Profile
+	if data := prefs.String(settingProfiles); data != "" {
+		if err := json.Unmarshal([]byte(data), &saved); err != nil {
+			log.Printf("Не удалось прочитать профили: %v", err)
+		}
+	}
+	return append(profiles, saved...)
+}
+
+// saveProfiles сохраняет список профилей без общего
+func saveProfiles(prefs fyne.Preferences, profiles []Profile) {
+	var saved []Profile
+	for _, profile := range profiles {
+		if profile.ID != "" {
+			saved = append(saved, profile)
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		log.Printf("Не удалось сохранить профили: %v", err)
+		return
+	}
+	prefs.SetString(settingProfiles, string(data))
+}
+
+// currentProfile возвращает выбранный профиль или общий, если выбранный удален
+func currentProfile(prefs fyne.Preferences) Profile {
+	id := prefs.String(settingCurrentProfile)
+	for _, profile := range loadProfiles(prefs) {
+		if profile.ID == id {
+			return profile
+		}
+	}
+	return Profile{}
+}
+
+// profilePrefix префикс настроек профиля
+func profilePrefix(profile Profile) string {
+	if profile.ID == "" {
+		return ""
+	}
+	return profileKeyPrefix + profile.ID + "."
+}
+
+// isTeacher сообщает, выбран ли профиль учителя
+func (gui *MainGUI) isTeacher() bool {
+	return gui.profile.Teacher
+}
+
+// restrictedForStudent сообщает, что настройки учителя закрыты: выбран профиль ученика
+// и задан PIN-код. В общем профиле настройки защищаются PIN-кодом, как раньше
+func (gui *MainGUI) restrictedForStudent() bool {
+	return gui.profile.ID != "" && !gui.profile.Teacher &&
+		gui.preferences().String(settingTeacherPIN) != ""
+}
+
+// updateWindowTitle показывает выбранный профиль в заголовке окна
+func (gui *MainGUI) updateWindowTitle() {
+	title := appWindowTitle
+	if gui.kiosk != nil {
+		title += " — режим экзамена"
+		if gui.currentFile != nil {
+			title += ": " + gui.kiosk.programName(gui.currentFile)
+		}
+	} else if gui.profile.ID != "" {
+		title += " — " + gui.profile.DisplayName()
+	}
+	gui.window.SetTitle(title)
+}
+
+// switchProfile переключает профиль: у каждого свои недавние программы,
+// расположение панелей, настройки и пройденное обучение
+func (gui *MainGUI) switchProfile(profile Profile) {
+	gui.saveLayout()
+
+	gui.profile = profile
+	gui.preferences().SetString(settingCurrentProfile, profile.ID)
+	log.Printf("Выбран профиль: %s", profile.DisplayName())
+
+	gui.checkpoint()
+	gui.openProgram(&Program{Name: "Новая программа", Created: time.Now(), Modified: time.Now()})
+	gui.currentFile = nil
+
+	gui.restoreLayout()
+	gui.applySettings()
+	gui.updateRecentMenu()
+	gui.updateWindowTitle()
+	gui.scheduleFirstRunTutorial()
+}
+
+// showProfilesDialog выбирает, создает и удаляет профили
+func (gui *MainGUI) showProfilesDialog() {
+	if gui.locked {
+		return
+	}
+	prefs := gui.preferences()
+	profiles := loadProfiles(prefs)
+
+	names := make([]string, len(profiles))
+	for i, profile := range profiles {
+		names[i] = profile.DisplayName()
+	}
+	choice := widget.NewRadioGroup(names, nil)
+	choice.SetSelected(gui.profile.DisplayName())
+
+	selected := func() (Profile, bool) {
+		for i, name := range names {
+			if name == choice.Selected {
+				return profiles[i], true
+			}
+		}
+		return Profile{}, false
+	}
+
+	var d dialog.Dialog
+	enter := func() {
+		profile, ok := selected()
+		if !ok || profile.ID == gui.profile.ID {
+			d.Hide()
+			return
+		}
+		proceed := func() {
+			d.Hide()
+			if len(gui.programMgr.GetProgram().Blocks) == 0 {
+				gui.switchProfile(profile)
+				return
+			}
+			dialog.ShowConfirm("Сменить профиль", "Текущая программа будет закрыта. Продолжить?",
+				func(confirmed bool) {
+					if confirmed {
+						gui.switchProfile(profile)
+					}
+				}, gui.window)
+		}
+		if profile.Teacher {
+			gui.askTeacherPIN(proceed)
+			return
+		}
+		proceed()
+	}
+
+	remove := widget.NewButton("Удалить", func() {
+		profile, ok := selected()
+		if !ok || profile.ID == "" {
+			dialog.ShowInformation("Профили", "Общий профиль удалить нельзя", gui.window)
+			return
+		}
+		if profile.ID == gui.profile.ID {
+			dialog.ShowInformation("Профили", "Сначала перейдите в другой профиль", gui.window)
+			return
+		}
+		confirmRemove := func() {
+			dialog.ShowConfirm("Удалить профиль", fmt.Sprintf("Удалить профиль «%s»?", profile.DisplayName()),
+				func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					var kept []Profile
+					for _, other := range profiles {
+						if other.ID != profile.ID {
+							kept = append(kept, other)
+						}
+					}
+					saveProfiles(prefs, kept)
+					d.Hide()
+				}, gui.window)
+		}
+		// Удалять профили может только учитель, если PIN-код задан
+		if gui.isTeacher() || prefs.String(settingTeacherPIN) == "" {
+			confirmRemove()
+			return
+		}
+		gui.askTeacherPIN(confirmRemove)
+	})
+	create := widget.NewButton("Новый профиль...", func() {
+		d.Hide()
+		gui.showNewProfileDialog()
+	})
+
+	content := container.NewBorder(nil, container.NewHBox(create, remove), nil, nil, container.NewVScroll(choice))
+	d = dialog.NewCustomConfirm("Профили", "Войти", "Отмена", content, func(confirmed bool) {
+		if confirmed {
+			enter()
+		}
+	}, gui.window)
+	d.Resize(fyne.NewSize(400, 380))
+	d.Show()
+}
+
+// showNewProfileDialog создает профиль ученика или учителя.
+// Профиль учителя защищается PIN-кодом, который задается при его создании
+func (gui *MainGUI) showNewProfileDialog() {
+	prefs := gui.preferences()
+	storedHash := prefs.String(settingTeacherPIN)
+
+	nameEntry := widget.NewEntry()
+	nameEntry.Validator = func(text string) error {
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("введите имя")
+		}
+		return nil
+	}
+	teacherCheck := widget.NewCheck("Профиль учителя", nil)
+	pinEntry := widget.NewPasswordEntry()
+	pinItem := widget.NewFormItem("PIN-код учителя", pinEntry)
+	pinItem.HintText = "Нужен для профиля учителя"
+	if storedHash == "" {
+		pinItem.HintText = "Будет задан PIN-код учителя: 4–8 цифр"
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Имя", nameEntry),
+		widget.NewFormItem("", teacherCheck),
+		pinItem,
+	}
+	dialog.ShowForm("Новый профиль", "Создать", "Отмена", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		profile := Profile{
+			ID:      strconv.FormatInt(time.Now().UnixNano(), 36),
+			Name:    strings.TrimSpace(nameEntry.Text),
+			Teacher: teacherCheck.Checked,
+		}
+		if profile.Teacher {
+			if storedHash == "" {
+				if err := validatePIN(pinEntry.Text); err != nil {
+					dialog.ShowError(err, gui.window)
+					return
+				}
+				if err := gui.setTeacherPIN(pinEntry.Text); err != nil {
+					dialog.ShowError(err, gui.window)
+					return
+				}
+			} else if err := gui.checkTeacherPIN(pinEntry.Text); err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+		}
+
+		saveProfiles(prefs, append(loadProfiles(prefs), profile))
+		gui.showProfilesDialog()
+	}, gui.window)
+}
+
+// askTeacherPIN запрашивает PIN-код учителя и вызывает next, если он верный.
+// Без заданного PIN-кода next вызывается сразу
+func (gui *MainGUI) askTeacherPIN(next func()) {
+	storedHash := gui.preferences().String(settingTeacherPIN)
+	if storedHash == "" {
+		next()
+		return
+	}
+
+	pinEntry := widget.NewPasswordEntry()
+	dialog.ShowForm("PIN-код учителя", "Продолжить", "Отмена",
+		[]*widget.FormItem{widget.NewFormItem("PIN-код", pinEntry)},
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := gui.checkTeacherPIN(pinEntry.Text); err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			next()
+		}, gui.window)
+}
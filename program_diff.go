@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// BlockChangeKind вид различия блока между двумя программами
+type BlockChangeKind int
+
+const (
+	BlockAdded   BlockChangeKind = iota // Блок есть только во второй программе
+	BlockRemoved                        // Блок есть только в первой программе
+	BlockChanged                        // Блок есть в обеих, но отличается
+)
+
+// ParameterDiff различие значения одного параметра; пустая строка — параметра нет
+type ParameterDiff struct {
+	Key   string
+	Left  string
+	Right string
+}
+
+// BlockChange различие одного блока. Блоки сопоставляются по ID,
+// который сохраняется в файле и не меняется при правке программы
+type BlockChange struct {
+	Kind   BlockChangeKind
+	Left   *ProgramBlock
+	Right  *ProgramBlock
+	Params []ParameterDiff
+}
+
+// diffPrograms сравнивает две программы поблочно: добавленные, удаленные и измененные блоки
+func diffPrograms(left, right *Program) []BlockChange {
+	leftBlocks := make(map[int]*ProgramBlock)
+	for _, block := range left.Blocks {
+		leftBlocks[block.ID] = block
+	}
+	rightBlocks := make(map[int]*ProgramBlock)
+	for _, block := range right.Blocks {
+		rightBlocks[block.ID] = block
+	}
+
+	var changes []BlockChange
+	for id, block := range leftBlocks {
+		if rightBlocks[id] == nil {
+			changes = append(changes, BlockChange{Kind: BlockRemoved, Left: block, Params: diffBlocks(block, nil)})
+		}
+	}
+	for id, block := range rightBlocks {
+		other := leftBlocks[id]
+		if other == nil {
+			changes = append(changes, BlockChange{Kind: BlockAdded, Right: block, Params: diffBlocks(nil, block)})
+			continue
+		}
+		if params := diffBlocks(other, block); len(params) > 0 {
+			changes = append(changes, BlockChange{Kind: BlockChanged, Left: other, Right: block, Params: params})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].block().ID < changes[j].block().ID })
+	return changes
+}
+
+// block возвращает блок, по которому различие показывается в списке
+func (c BlockChange) block() *ProgramBlock {
+	if c.Right != nil {
+		return c.Right
+	}
+	return c.Left
+}
+
+// diffBlocks сравнивает тип, соединение, положение и параметры блока.
+// nil с одной стороны означает, что блока нет: показываются все его значения
+func diffBlocks(left, right *ProgramBlock) []ParameterDiff {
+	leftFields, rightFields := blockDiffFields(left), blockDiffFields(right)
+
+	keys := make(map[string]bool)
+	for key := range leftFields {
+		keys[key] = true
+	}
+	for key := range rightFields {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var diffs []ParameterDiff
+	for _, key := range sorted {
+		if leftFields[key] != rightFields[key] {
+			diffs = append(diffs, ParameterDiff{Key: key, Left: leftFields[key], Right: rightFields[key]})
+		}
+	}
+	return diffs
+}
+
+// blockDiffFields значения блока для сравнения: заголовок, соединение, положение и параметры
+func blockDiffFields(block *ProgramBlock) map[string]string {
+	fields := make(map[string]string)
+	if block == nil {
+		return fields
+	}
+	fields["блок"] = block.Title
+	fields["следующий блок"] = describeNextBlock(block.NextBlockID)
+	fields["положение"] = fmt.Sprintf("%.0f, %.0f", block.X, block.Y)
+	for key, value := range block.Parameters {
+		fields[key] = fmt.Sprintf("%v", value)
+	}
+	return fields
+}
+
+// describeNextBlock описание соединения блока
+func describeNextBlock(id int) string {
+	if id <= 0 {
+		return "нет"
+	}
+	return fmt.Sprintf("ID %d", id)
+}
+
+// showProgramDiffDialog открывает окно сравнения двух файлов программ
+func (gui *MainGUI) showProgramDiffDialog() {
+	window := fyne.CurrentApp().NewWindow("Сравнение программ")
+
+	var programs [2]*Program
+	names := [2]*widget.Label{widget.NewLabel("не выбрана"), widget.NewLabel("не выбрана")}
+	result := container.NewVBox()
+
+	update := func() {
+		result.Objects = nil
+		if programs[0] != nil && programs[1] != nil {
+			result.Objects = newProgramDiffView(diffPrograms(programs[0], programs[1]))
+		}
+		result.Refresh()
+	}
+
+	pick := func(side int) func() {
+		return func() {
+			d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				if reader == nil {
+					return
+				}
+				defer reader.Close()
+
+				data, err := io.ReadAll(reader)
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), window)
+					return
+				}
+				program, err := gui.programMgr.DecodeProgram(data)
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				programs[side] = program
+				names[side].SetText(reader.URI().Name())
+				update()
+			}, window)
+			d.SetFilter(storage.NewExtensionFileFilter([]string{programFileExtension}))
+			d.Show()
+		}
+	}
+
+	files := widget.NewForm(
+		widget.NewFormItem("Было", container.NewBorder(nil, nil, nil, widget.NewButton("Открыть...", pick(0)), names[0])),
+		widget.NewFormItem("Стало", container.NewBorder(nil, nil, nil, widget.NewButton("Открыть...", pick(1)), names[1])),
+	)
+
+	window.SetContent(container.NewBorder(files, nil, nil, nil, container.NewVScroll(result)))
+	window.Resize(fyne.NewSize(720, 560))
+	window.Show()
+}
+
+// newProgramDiffView список различий: заголовок блока и таблица «параметр — было — стало»
+func newProgramDiffView(changes []BlockChange) []fyne.CanvasObject {
+	if len(changes) == 0 {
+		return []fyne.CanvasObject{widget.NewLabel("Программы совпадают")}
+	}
+
+	var objects []fyne.CanvasObject
+	for _, change := range changes {
+		block := change.block()
+		header := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		switch change.Kind {
+		case BlockAdded:
+			header.SetText(fmt.Sprintf("+ Добавлен блок «%s» (ID: %d)", block.Title, block.ID))
+			header.Importance = widget.SuccessImportance
+		case BlockRemoved:
+			header.SetText(fmt.Sprintf("− Удален блок «%s» (ID: %d)", block.Title, block.ID))
+			header.Importance = widget.DangerImportance
+		case BlockChanged:
+			header.SetText(fmt.Sprintf("~ Изменен блок «%s» (ID: %d)", block.Title, block.ID))
+			header.Importance = widget.WarningImportance
+		}
+		objects = append(objects, header)
+
+		if len(change.Params) > 0 {
+			grid := container.NewGridWithColumns(3,
+				widget.NewLabelWithStyle("Параметр", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+				widget.NewLabelWithStyle("Было", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+				widget.NewLabelWithStyle("Стало", fyne.TextAlignLeading, fyne.TextStyle{Italic: true}),
+			)
+			for _, param := range change.Params {
+				grid.Add(widget.NewLabel(param.Key))
+				grid.Add(diffValueLabel(param.Left))
+				grid.Add(diffValueLabel(param.Right))
+			}
+			objects = append(objects, grid)
+		}
+		objects = append(objects, widget.NewSeparator())
+	}
+	return objects
+}
+
+// diffValueLabel значение параметра; отсутствующий параметр показывается прочерком
+func diffValueLabel(value string) *widget.Label {
+	if value == "" {
+		value = "—"
+	}
+	label := widget.NewLabel(value)
+	label.Wrapping = fyne.TextWrapWord
+	return label
+}
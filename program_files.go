@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+const (
+	settingRecentPrograms = "recent_programs"
+	maxRecentPrograms     = 8
+)
+
+// newProgram начинает новую пустую программу
+func (gui *MainGUI) newProgram() {
+	if gui.locked {
+		return
+	}
+
+	start := func() {
+		gui.checkpoint()
+		gui.openProgram(&Program{Name: "Новая программа", Created: time.Now(), Modified: time.Now()})
+		gui.currentFile = nil
+	}
+
+	if len(gui.programMgr.GetProgram().Blocks) == 0 {
+		start()
+		return
+	}
+	dialog.ShowConfirm("Новая программа", "Текущая программа будет закрыта. Продолжить?",
+		func(confirmed bool) {
+			if confirmed {
+				start()
+			}
+		}, gui.window)
+}
+
+// showOpenProgramDialog выбирает и открывает файл программы
+func (gui *MainGUI) showOpenProgramDialog() {
+	if gui.locked {
+		return
+	}
+
+	d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, gui.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+		gui.readProgram(reader.URI(), reader)
+	}, gui.window)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{programFileExtension}))
+	d.Show()
+}
+
+// openProgramURI открывает программу по адресу файла, например из списка недавних
+func (gui *MainGUI) openProgramURI(uri fyne.URI) {
+	if gui.locked {
+		return
+	}
+
+	reader, err := storage.Reader(uri)
+	if err != nil {
+		gui.forgetRecentProgram(uri)
+		dialog.ShowError(fmt.Errorf("не удалось открыть %s: %v", uri.Name(), err), gui.window)
+		return
+	}
+	defer reader.Close()
+	gui.readProgram(uri, reader)
+}
+
+// readProgram читает программу и делает ее текущей
+func (gui *MainGUI) readProgram(uri fyne.URI, reader io.Reader) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), gui.window)
+		return
+	}
+	program, err := gui.programMgr.DecodeProgram(data)
+	if err != nil {
+		dialog.ShowError(err, gui.window)
+		return
+	}
+
+	gui.checkpoint()
+	gui.openProgram(program)
+	gui.currentFile = uri
+	gui.rememberRecentProgram(uri)
+	log.Printf("Программа загружена: %s", uri.Path())
+}
+
+// saveProgram сохраняет программу в текущий файл или спрашивает имя нового
+func (gui *MainGUI) saveProgram() {
+	if gui.currentFile == nil {
+		gui.showSaveProgramDialog()
+		return
+	}
+
+	writer, err := storage.Writer(gui.currentFile)
+	if err != nil {
+		dialog.ShowError(err, gui.window)
+		return
+	}
+	gui.writeProgram(writer)
+}
+
+// showSaveProgramDialog сохраняет программу в новый файл
+func (gui *MainGUI) showSaveProgramDialog() {
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, gui.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		gui.writeProgram(writer)
+	}, gui.window)
+	d.SetFileName(gui.programMgr.GetProgram().Name + programFileExtension)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{programFileExtension}))
+	d.Show()
+}
+
+// writeProgram записывает программу в файл формата обмена
+func (gui *MainGUI) writeProgram(writer fyne.URIWriteCloser) {
+	defer writer.Close()
+
+	data, err := EncodeProgram(gui.programMgr.GetProgram())
+	if err == nil {
+		_, err = writer.Write(data)
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("ошибка сохранения программы: %v", err), gui.window)
+		return
+	}
+
+	gui.currentFile = writer.URI()
+	gui.rememberRecentProgram(writer.URI())
+	log.Printf("Программа сохранена: %s", writer.URI().Path())
+}
+
+// recentPrograms возвращает недавно открытые файлы программ, начиная с последнего
+func (gui *MainGUI) recentPrograms() []fyne.URI {
+	var uris []fyne.URI
+	for _, line := range strings.Split(gui.preferences().String(settingRecentPrograms), "\n") {
+		if line == "" {
+			continue
+		}
+		if uri, err := storage.ParseURI(line); err == nil {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// rememberRecentProgram поднимает файл в начало списка недавних
+func (gui *MainGUI) rememberRecentProgram(uri fyne.URI) {
+	recent := []string{uri.String()}
+	for _, other := range gui.recentPrograms() {
+		if other.String() != uri.String() && len(recent) < maxRecentPrograms {
+			recent = append(recent, other.String())
+		}
+	}
+	gui.preferences().SetString(settingRecentPrograms, strings.Join(recent, "\n"))
+	gui.updateRecentMenu()
+}
+
+// forgetRecentProgram убирает недоступный файл из списка недавних
+func (gui *MainGUI) forgetRecentProgram(uri fyne.URI) {
+	var recent []string
+	for _, other := range gui.recentPrograms() {
+		if other.String() != uri.String() {
+			recent = append(recent, other.String())
+		}
+	}
+	gui.preferences().SetString(settingRecentPrograms, strings.Join(recent, "\n"))
+	gui.updateRecentMenu()
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Формат обмена программами WeDoProg (JSON, расширение .wedo).
+//
+// Файл не зависит от языка интерфейса: типы блоков и параметры записываются
+// нейтральными идентификаторами, а заголовки и описания блоков не сохраняются
+// и берутся из интерфейса при открытии.
+//
+//	{
+//	  "format": "wedoprog",
+//	  "version": 2,
+//	  "name": "Моя программа",
+//	  "created": "2024-01-01T10:00:00Z",
+//	  "modified": "2024-01-01T10:05:00Z",
+//	  "random_seed": 0,
+//	  "blocks": [
+//	    {"id": 1, "type": "start", "x": 100, "y": 50},
+//	    {"id": 2, "type": "motor", "x": 100, "y": 170,
+//	     "params": {"port": 1, "power": 50, "duration": 2}}
+//	  ],
+//	  "connections": [{"from": 1, "to": 2}],
+//	  "groups": [{"id": 1, "name": "Разгон", "blocks": [2], "collapsed": false}]
+//	}
+//
+// Идентификаторы типов блоков перечислены в blockTypeIDs. Параметры хранятся
+// под теми же ключами, что и в ProgramBlock.Parameters; числа приводятся
+// к типам параметров по умолчанию при загрузке и проверяются по схеме
+// parameterSchema: файл с портом 3 или мощностью 120 не открывается, а ошибка
+// указывает путь к параметру, например blocks[3].params.power. Необязательное
+// поле блока "color" хранит цвет, которым пользователь отметил блок (#RRGGBB),
+// необязательное поле "editable" перечисляет параметры, которые ученик может
+// менять, когда программа открыта как урок. Файлы старых версий
+// обновляются функциями из programMigrations перед разбором.
+//
+// Программа, экспортированная только для запуска, помечается полем
+// "run_only": true. Такую программу можно открыть и запустить, но не изменить;
+// поля "editable" в ней не записываются. Поле "requires" перечисляет
+// устройства, которые программа ожидает на портах хаба:
+// [{"port": 1, "device": 1}], где device — тип устройства LWP.
+//
+// Чтобы файлы было удобно хранить в системе контроля версий, запись
+// детерминирована: блоки, соединения и группы упорядочены по ID, ключи
+// параметров по алфавиту, координаты округлены до пикселя. ID блоков
+// сохраняются как есть и не перенумеровываются.
+//
+// История версий:
+//
+//	1 — первая версия; переход блока дублировался полем "next"
+//	2 — переходы хранятся только в "connections"
+//	3 — пороги расстояния хранятся в сантиметрах
+
+// Заголовок и текущая версия формата
+const (
+	programFormatName    = "wedoprog"
+	programFormatVersion = 3
+	programFileExtension = ".wedo"
+)
+
+// blockTypeIDs нейтральные идентификаторы типов блоков в файле
+var blockTypeIDs = map[BlockType]string{
+	BlockTypeStart:          "start",
+	BlockTypeMotor:          "motor",
+	BlockTypeLED:            "led",
+	BlockTypeWait:           "wait",
+	BlockTypeLoop:           "loop",
+	BlockTypeCondition:      "condition",
+	BlockTypeTiltSensor:     "tilt_sensor",
+	BlockTypeDistanceSensor: "distance_sensor",
+	BlockTypeSound:          "sound",
+	BlockTypeVoltageSensor:  "voltage_sensor",
+	BlockTypeCurrentSensor:  "current_sensor",
+	BlockTypeStop:           "stop",
+	BlockTypeWaitUntil:      "wait_until",
+	BlockTypeResetTimer:     "reset_timer",
+	BlockTypeBroadcast:      "broadcast",
+	BlockTypeReceive:        "receive",
+	BlockTypeScreen:         "screen",
+	BlockTypeWhenMotion:     "when_motion",
+	BlockTypeWhenColor:      "when_color",
+	BlockTypeWhenHear:       "when_hear",
+	BlockTypeFollow:         "follow",
+	BlockTypeHubPower:       "hub_power",
+	BlockTypeLightMusic:     "light_music",
+	BlockTypeWhenBumped:     "when_bumped",
+}
+
+// blockTypeFromID возвращает тип блока по идентификатору из файла
+func blockTypeFromID(id string) (BlockType, bool) {
+	for blockType, typeID := range blockTypeIDs {
+		if typeID == id {
+			return blockType, true
+		}
+	}
+	return 0, false
+}
+
+// programMigrations обновляют документ старой версии до следующей:
+// programMigrations[v] переводит версию v в v+1
+var programMigrations = map[int]func(doc map[string]interface{}) error{
+	1: migrateProgramV1,
+	2: migrateProgramV2,
+}
+
+// migrateProgramV1 переносит переходы из поля "next" блоков в список соединений
+func migrateProgramV1(doc map[string]interface{}) error {
+	blocks, _ := doc["blocks"].([]interface{})
+	connections, _ := doc["connections"].([]interface{})
+
+	connected := make(map[int]bool)
+	for _, item := range connections {
+		if conn, ok := item.(map[string]interface{}); ok {
+			connected[int(parameterToFloat(conn["from"]))] = true
+		}
+	}
+
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("некорректный блок: %v", item)
+		}
+		from := int(parameterToFloat(block["id"]))
+		if next := parameterToFloat(block["next"]); next > 0 && !connected[from] {
+			connections = append(connections, map[string]interface{}{"from": from, "to": next})
+			connected[from] = true
+		}
+		delete(block, "next")
+	}
+
+	doc["connections"] = connections
+	return nil
+}
+
+// migrateProgramV2 переводит пороги датчика расстояния из условной шкалы 0..10
+// в сантиметры по примерной калибровке: условия, регулятор и привязки
+func migrateProgramV2(doc map[string]interface{}) error {
+	calibration := defaultDistanceCalibration
+	toCM := func(value interface{}) float64 {
+		return math.Round(calibration.Centimeters(parameterToFloat(value)))
+	}
+
+	blocks, _ := doc["blocks"].([]interface{})
+	for _, item := range blocks {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("некорректный блок: %v", item)
+		}
+		params, ok := block["params"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch block["type"] {
+		case "condition", "wait_until", "loop":
+			sensor := paramString(params, "sensor", "")
+			if value, exists := params["value"]; exists && (sensor == "" || sensor == ConditionSensorDistance) {
+				params["value"] = toCM(value)
+			}
+		case "follow":
+			if target, exists := params["target"]; exists {
+				params["target"] = toCM(target)
+			}
+			if gain, exists := params["gain"]; exists {
+				params["gain"] = math.Round(parameterToFloat(gain)/calibration.cmPerRaw()*10) / 10
+			}
+		}
+
+		for key, value := range params {
+			if strings.HasSuffix(key, "_bind_in_min") || strings.HasSuffix(key, "_bind_in_max") {
+				params[key] = toCM(value)
+			}
+		}
+	}
+	return nil
+}
+
+// ProgramDocument программа в формате обмена
+type ProgramDocument struct {
+	Format      string               `json:"format"`
+	Version     int                  `json:"version"`
+	Name        string               `json:"name"`
+	Created     time.Time            `json:"created"`
+	Modified    time.Time            `json:"modified"`
+	RandomSeed  int64                `json:"random_seed"`
+	Blocks      []DocumentBlock      `json:"blocks"`
+	Connections []DocumentConnection `json:"connections"`
+	Groups      []DocumentGroup      `json:"groups,omitempty"`
+	RunOnly     bool                 `json:"run_only,omitempty"`
+	Requires    []DocumentDevice     `json:"requires,omitempty"`
+}
+
+// DocumentBlock блок в формате обмена
+type DocumentBlock struct {
+	ID       int                    `json:"id"`
+	Type     string                 `json:"type"`
+	X        float64                `json:"x"`
+	Y        float64                `json:"y"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Color    string                 `json:"color,omitempty"`
+	Editable []string               `json:"editable,omitempty"`
+}
+
+// DocumentConnection соединение в формате обмена
+type DocumentConnection struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// DocumentDevice устройство, которое программа ожидает на порту хаба
+type DocumentDevice struct {
+	Port   byte `json:"port"`
+	Device byte `json:"device"`
+}
+
+// DocumentGroup группа блоков в формате обмена
+type DocumentGroup struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Blocks    []int  `json:"blocks"`
+	Collapsed bool   `json:"collapsed"`
+}
+
+// EncodeProgram записывает программу в формате обмена в детерминированном порядке
+func EncodeProgram(program *Program) ([]byte, error) {
+	doc := ProgramDocument{
+		Format:     programFormatName,
+		Version:    programFormatVersion,
+		Name:       program.Name,
+		Created:    program.Created,
+		Modified:   program.Modified,
+		RandomSeed: program.RandomSeed,
+		RunOnly:    program.RunOnly,
+	}
+
+	for _, block := range program.Blocks {
+		typeID, ok := blockTypeIDs[block.Type]
+		if !ok {
+			return nil, fmt.Errorf("блок %d: тип %d не поддерживается форматом", block.ID, block.Type)
+		}
+		docBlock := DocumentBlock{
+			ID:     block.ID,
+			Type:   typeID,
+			X:      math.Round(block.X),
+			Y:      math.Round(block.Y),
+			Params: block.Parameters,
+			Color:  block.CustomColor,
+		}
+		if !program.RunOnly {
+			docBlock.Editable = block.Editable
+		}
+		doc.Blocks = append(doc.Blocks, docBlock)
+	}
+	sort.Slice(doc.Blocks, func(i, j int) bool { return doc.Blocks[i].ID < doc.Blocks[j].ID })
+
+	for _, conn := range program.Connections {
+		doc.Connections = append(doc.Connections, DocumentConnection{From: conn.FromBlockID, To: conn.ToBlockID})
+	}
+	sort.Slice(doc.Connections, func(i, j int) bool {
+		if doc.Connections[i].From != doc.Connections[j].From {
+			return doc.Connections[i].From < doc.Connections[j].From
+		}
+		return doc.Connections[i].To < doc.Connections[j].To
+	})
+
+	for _, group := range program.Groups {
+		doc.Groups = append(doc.Groups, DocumentGroup{
+			ID:        group.ID,
+			Name:      group.Name,
+			Blocks:    group.BlockIDs,
+			Collapsed: group.Collapsed,
+		})
+	}
+	sort.Slice(doc.Groups, func(i, j int) bool { return doc.Groups[i].ID < doc.Groups[j].ID })
+
+	if program.RunOnly {
+		for _, device := range programRequiredDevices(program) {
+			doc.Requires = append(doc.Requires, DocumentDevice{Port: device.port, Device: device.deviceType})
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// decodeProgramDocument разбирает файл, обновляя старые версии формата
+func decodeProgramDocument(data []byte) (*ProgramDocument, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("файл не является программой WeDoProg: %v", err)
+	}
+	if format, _ := raw["format"].(string); format != programFormatName {
+		return nil, fmt.Errorf("файл не является программой WeDoProg")
+	}
+
+	version := int(parameterToFloat(raw["version"]))
+	if version > programFormatVersion {
+		return nil, fmt.Errorf("программа сохранена в более новой версии формата (%d), обновите WeDoProg", version)
+	}
+	for ; version < programFormatVersion; version++ {
+		migrate, ok := programMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("неизвестная версия формата программы: %d", version)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("ошибка обновления программы с версии %d: %v", version, err)
+		}
+		raw["version"] = version + 1
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var doc ProgramDocument
+	if err := json.Unmarshal(migrated, &doc); err != nil {
+		return nil, fmt.Errorf("ошибка чтения программы: %v", err)
+	}
+	return &doc, nil
+}
+
+// DecodeProgram читает программу из формата обмена. Блоки настраиваются
+// заново, поэтому заголовки берутся из текущего интерфейса
+func (pm *ProgramManager) DecodeProgram(data []byte) (*Program, error) {
+	doc, err := decodeProgramDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	program := &Program{
+		Name:       doc.Name,
+		RandomSeed: doc.RandomSeed,
+		RunOnly:    doc.RunOnly,
+		Created:    doc.Created,
+		Modified:   doc.Modified,
+	}
+
+	for index, docBlock := range doc.Blocks {
+		blockType, ok := blockTypeFromID(docBlock.Type)
+		if !ok {
+			return nil, fmt.Errorf("блок %d: неизвестный тип %q", docBlock.ID, docBlock.Type)
+		}
+
+		block := &ProgramBlock{
+			ID:         docBlock.ID,
+			Type:       blockType,
+			X:          docBlock.X,
+			Y:          docBlock.Y,
+			Width:      150,
+			Height:     80,
+			Parameters: make(map[string]interface{}),
+			IsStart:    blockType == BlockTypeStart,
+		}
+		pm.configureBlock(block)
+
+		if docBlock.Color != "" {
+			if !validBlockColor(docBlock.Color) {
+				return nil, fmt.Errorf("блок %d: цвет %q не в формате #RRGGBB", docBlock.ID, docBlock.Color)
+			}
+			block.CustomColor = docBlock.Color
+		}
+
+		for key, value := range docBlock.Params {
+			converted, err := decodeBlockParameter(blockType, block.Parameters[key], key, value)
+			if err != nil {
+				return nil, &ParameterError{Path: parameterPath(index, key), BlockID: docBlock.ID, Err: err}
+			}
+			block.Parameters[key] = converted
+		}
+		for _, key := range docBlock.Editable {
+			if _, exists := block.Parameters[key]; exists && !doc.RunOnly {
+				block.setEditable(key, true)
+			}
+		}
+		program.Blocks = append(program.Blocks, block)
+	}
+
+	blocks := make(map[int]*ProgramBlock)
+	for _, block := range program.Blocks {
+		if blocks[block.ID] != nil {
+			return nil, fmt.Errorf("повторяющийся ID блока %d", block.ID)
+		}
+		blocks[block.ID] = block
+	}
+
+	for _, conn := range doc.Connections {
+		from, to := blocks[conn.From], blocks[conn.To]
+		if from == nil || to == nil {
+			return nil, fmt.Errorf("соединение %d -> %d ссылается на несуществующий блок", conn.From, conn.To)
+		}
+		from.NextBlockID = conn.To
+		program.Connections = append(program.Connections, &Connection{FromBlockID: conn.From, ToBlockID: conn.To})
+	}
+	for _, group := range doc.Groups {
+		program.Groups = append(program.Groups, &BlockGroup{
+			ID:        group.ID,
+			Name:      group.Name,
+			BlockIDs:  group.Blocks,
+			Collapsed: group.Collapsed,
+		})
+	}
+
+	return program, nil
+}
+
+// decodeParameter приводит значение из JSON к типу параметра по умолчанию.
+// Для параметров без значения по умолчанию (например, привязок) тип выбирается по ключу
+func decodeParameter(defaultValue interface{}, key string, value interface{}) (interface{}, error) {
+	if defaultValue == nil && (key == "port" || strings.HasSuffix(key, "_port")) {
+		defaultValue = byte(0)
+	}
+
+	switch defaultValue.(type) {
+	case byte:
+		number, ok := value.(float64)
+		if !ok || number < 0 || number > 255 {
+			return nil, fmt.Errorf("ожидается число 0..255")
+		}
+		return byte(number), nil
+	case int8:
+		number, ok := value.(float64)
+		if !ok || number < -128 || number > 127 {
+			return nil, fmt.Errorf("ожидается число -128..127")
+		}
+		return int8(number), nil
+	case uint16:
+		number, ok := value.(float64)
+		if !ok || number < 0 || number > 65535 {
+			return nil, fmt.Errorf("ожидается число 0..65535")
+		}
+		return uint16(number), nil
+	case int:
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("ожидается число")
+		}
+		return int(number), nil
+	case float64:
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("ожидается число")
+		}
+		return number, nil
+	case bool:
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("ожидается true или false")
+		}
+		return flag, nil
+	case string:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("ожидается строка")
+		}
+		return text, nil
+	default:
+		switch value.(type) {
+		case float64, bool, string:
+			return value, nil
+		}
+		return nil, fmt.Errorf("неподдерживаемое значение %v", value)
+	}
+}
+
+// LoadProgram заменяет текущую программу загруженной
+func (pm *ProgramManager) LoadProgram(program *Program) {
+	pm.StopProgram()
+	pm.program = program
+	pm.setState(ProgramStateStopped)
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -11,12 +15,69 @@ import (
 
 // ProgramManager управляет программами
 type ProgramManager struct {
-	hubMgr       *HubManager
-	deviceMgr    *DeviceManager
+	hubMgr       HubLink
+	deviceMgr    DeviceActuator
 	program      *Program
 	programs     map[string]*Program
 	programsMu   sync.RWMutex
 	currentState ProgramState
+	stateMu      sync.Mutex // Состояние меняют цепочки выполнения и интерфейс
+
+	// Контекст текущего запуска, отменяется при остановке
+	runCtx    context.Context
+	runCancel context.CancelFunc
+	runMu     sync.Mutex
+
+	// Генератор случайных чисел для случайных параметров блоков
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// Время ожидания хаба для блоков без собственной настройки, наносекунды
+	blockTimeout atomic.Int64
+	// Попытки блоков, которые не дождались хаба и еще выполняются: ID блока -> chan struct{}
+	pendingBlocks sync.Map
+
+	// Выполнять цепочки по заранее рассчитанному расписанию
+	compileSchedules atomic.Bool
+
+	// Скорость выполнения: меньше 1 — замедленный показ программы
+	runSpeed float64
+	speedMu  sync.Mutex
+
+	// Таймер программы, сбрасывается при запуске и блоком "Сбросить таймер"
+	timerStart time.Time
+	timerMu    sync.Mutex
+
+	// Последняя ошибка выполнения для строки состояния
+	lastError     error
+	lastErrorTime time.Time
+	errMu         sync.Mutex
+
+	// Callback для вывода сообщений на экран компьютера
+	screenMessageCallback func(text string, duration time.Duration)
+	// Обработчик паники в горутинах выполнения, вызывается через defer
+	crashHandler func()
+
+	// Захват веб-камеры для блоков "Когда движение" и "Когда цвет"
+	vision *VisionMonitor
+
+	// Распознавание речи для блоков "Когда слышу"
+	speech *SpeechMonitor
+
+	// Запись датчиков, на которой выполняется программа вместо живых датчиков
+	sensorReplay *SensorRecording
+
+	// Фоновые регуляторы блоков "Держать расстояние" и их текущие параметры
+	// по ID блока: редактор меняет их на ходу, регулятор читает под followMu
+	controllers  sync.WaitGroup
+	followParams map[int]FollowController
+	followMu     sync.Mutex
+
+	// Длительности выполнения блоков по запускам
+	benchmarks *BenchmarkRecorder
+
+	// Наблюдатели выполнения блоков
+	hooks blockHooks
 }
 
 // Program представляет программу
@@ -24,6 +85,9 @@ type Program struct {
 	Name        string
 	Blocks      []*ProgramBlock
 	Connections []*Connection
+	Groups      []*BlockGroup // Визуальные группы блоков на холсте
+	RandomSeed  int64         // Зерно генератора случайных чисел, 0 = каждый запуск разный
+	RunOnly     bool          // Программа только для запуска: изменять ее нельзя
 	Created     time.Time
 	Modified    time.Time
 }
@@ -42,7 +106,9 @@ type ProgramBlock struct {
 	NextBlockID  int
 	IsStart      bool
 	Color        string
+	CustomColor  string // Цвет, выбранный пользователем; важнее цвета типа и категории
 	OnExecute    func() error
+	Editable     []string // Параметры, которые ученик может менять в режиме урока
 }
 
 // Connection соединение между блоками
@@ -77,23 +143,41 @@ const (
 	BlockTypeVoltageSensor
 	BlockTypeCurrentSensor
 	BlockTypeStop
+	BlockTypeWaitUntil
+	BlockTypeResetTimer
+	BlockTypeBroadcast
+	BlockTypeReceive
+	BlockTypeScreen
+	BlockTypeWhenMotion
+	BlockTypeWhenColor
+	BlockTypeWhenHear
+	BlockTypeFollow
+	BlockTypeHubPower
+	BlockTypeLightMusic
+	BlockTypeWhenBumped
 )
 
 // NewProgramManager создает менеджер программ
-func NewProgramManager(hubMgr *HubManager, deviceMgr *DeviceManager) *ProgramManager {
-	return &ProgramManager{
+func NewProgramManager(hubMgr HubLink, deviceMgr DeviceActuator) *ProgramManager {
+	pm := &ProgramManager{
 		hubMgr:       hubMgr,
 		deviceMgr:    deviceMgr,
 		program:      &Program{Name: "Новая программа", Created: time.Now(), Modified: time.Now()},
 		programs:     make(map[string]*Program),
 		currentState: ProgramStateStopped,
+		runSpeed:     1,
+		vision:       NewVisionMonitor(hubMgr.Events()),
+		speech:       NewSpeechMonitor(hubMgr.Events()),
+		benchmarks:   NewBenchmarkRecorder(),
 	}
+	pm.AddBlockHook(BlockHook{After: pm.benchmarks.RecordBlock})
+	return pm
 }
 
 // CreateBlock создает новый блок
 func (pm *ProgramManager) CreateBlock(blockType BlockType, x, y float64) *ProgramBlock {
 	block := &ProgramBlock{
-		ID:           len(pm.program.Blocks) + 1,
+		ID:           pm.nextBlockID(),
 		Type:         blockType,
 		X:            x,
 		Y:            y,
@@ -114,6 +198,18 @@ func (pm *ProgramManager) CreateBlock(blockType BlockType, x, y float64) *Progra
 	return block
 }
 
+// nextBlockID возвращает новый ID блока. ID удаленных блоков не переиспользуются
+// среди оставшихся, поэтому номера блоков в сохраненном файле не меняются
+func (pm *ProgramManager) nextBlockID() int {
+	nextID := 1
+	for _, block := range pm.program.Blocks {
+		if block.ID >= nextID {
+			nextID = block.ID + 1
+		}
+	}
+	return nextID
+}
+
 // configureBlock настраивает блок
 func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 	switch block.Type {
@@ -123,7 +219,7 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Color = "#4CAF50"
 		block.IsStart = true
 		block.OnExecute = func() error {
-			log.Println("Начало программы")
+			programLog.Debugf("Начало программы")
 			return nil
 		}
 
@@ -131,48 +227,37 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Title = "Мотор"
 		block.Description = "Управление мотором"
 		block.Color = "#2196F3"
-		block.Parameters["port"] = byte(1)
-		block.Parameters["power"] = int8(50)
-		block.Parameters["duration"] = uint16(1000)
+		defaultMotorParams.Store(block.Parameters)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			power := block.Parameters["power"].(int8)
-			duration := block.Parameters["duration"].(uint16)
-			return pm.deviceMgr.SetMotorPowerAndWait(port, power, duration)
+			params := motorFromParameters(block.Parameters)
+			return pm.deviceMgr.SetMotorPowerAndWait(params.Port, pm.motorPower(block), params.Duration)
 		}
 
 	case BlockTypeLED:
 		block.Title = "Светодиод"
 		block.Description = "Управление светодиодом"
 		block.Color = "#FF9800"
-		block.Parameters["port"] = byte(6)
-		block.Parameters["red"] = byte(255)
-		block.Parameters["green"] = byte(0)
-		block.Parameters["blue"] = byte(0)
+		defaultLEDParams.Store(block.Parameters)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			red := block.Parameters["red"].(byte)
-			green := block.Parameters["green"].(byte)
-			blue := block.Parameters["blue"].(byte)
-			return pm.deviceMgr.SetLEDColor(port, red, green, blue)
+			red, green, blue := pm.ledColor(block)
+			return pm.deviceMgr.SetLEDColor(ledFromParameters(block.Parameters).Port, red, green, blue)
 		}
 
 	case BlockTypeWait:
 		block.Title = "Ждать"
 		block.Description = "Пауза в программе"
 		block.Color = "#9E9E9E"
-		block.Parameters["duration"] = 1.0
+		defaultWaitParams.Store(block.Parameters)
 		block.OnExecute = func() error {
-			duration := block.Parameters["duration"].(float64)
-			log.Printf("Пауза: %.1f секунд", duration)
-			time.Sleep(time.Duration(duration*1000) * time.Millisecond)
-			return nil
+			duration := pm.waitDuration(block)
+			programLog.Debugf("Пауза: %.1f секунд", duration)
+			return pm.pause(pm.runContext(), pm.scaleWait(time.Duration(duration*1000)*time.Millisecond))
 		}
 
 	case BlockTypeLoop:
@@ -180,9 +265,11 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Description = "Цикл повторений"
 		block.Color = "#9C27B0"
 		block.Parameters["count"] = 5
-		block.Parameters["forever"] = false
+		block.Parameters["mode"] = LoopModeCount
+		block.Parameters["body"] = 0
+		setDefaultConditionParameters(block.Parameters)
 		block.OnExecute = func() error {
-			log.Println("Цикл выполняется")
+			programLog.Debugf("Цикл: %s", loopModeFromParameters(block.Parameters))
 			return nil
 		}
 
@@ -190,8 +277,10 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Title = "Условие"
 		block.Description = "Условный оператор"
 		block.Color = "#3F51B5"
+		block.Parameters["body"] = 1
+		setDefaultConditionParameters(block.Parameters)
 		block.OnExecute = func() error {
-			log.Println("Проверка условия")
+			programLog.Debugf("Проверка условия: %s", conditionFromParameters(block.Parameters))
 			return nil
 		}
 
@@ -199,49 +288,39 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Title = "Датчик наклона"
 		block.Description = "Чтение датчика наклона"
 		block.Color = "#673AB7"
-		block.Parameters["port"] = byte(1)
-		block.Parameters["mode"] = byte(1)
+		defaultTiltParams.Store(block.Parameters)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			mode := block.Parameters["mode"].(byte)
-			cmd := []byte{0x01, 0x02, port, 0x22, mode, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-			return pm.hubMgr.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", cmd)
+			params := sensorFromParameters(block.Parameters, defaultTiltParams)
+			return pm.hubMgr.ConfigurePort(NewInputFormatCommand(params.Port, DEVICE_TYPE_TILT_SENSOR).Mode(params.Mode))
 		}
 
 	case BlockTypeDistanceSensor:
 		block.Title = "Датчик расстояния"
 		block.Description = "Измерение расстояния"
 		block.Color = "#00BCD4"
-		block.Parameters["port"] = byte(1)
-		block.Parameters["mode"] = byte(0)
+		defaultDistanceParams.Store(block.Parameters)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			mode := block.Parameters["mode"].(byte)
-			cmd := []byte{0x01, 0x02, port, 0x23, mode, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-			return pm.hubMgr.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", cmd)
+			params := sensorFromParameters(block.Parameters, defaultDistanceParams)
+			return pm.hubMgr.ConfigurePort(NewInputFormatCommand(params.Port, DEVICE_TYPE_MOTION_SENSOR).Mode(params.Mode))
 		}
 
 	case BlockTypeSound:
 		block.Title = "Звук"
 		block.Description = "Воспроизведение звука"
 		block.Color = "#FF5722"
-		block.Parameters["port"] = byte(1)
-		block.Parameters["frequency"] = uint16(440)
-		block.Parameters["duration"] = uint16(1000)
+		defaultSoundParams.Store(block.Parameters)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			frequency := block.Parameters["frequency"].(uint16)
-			duration := block.Parameters["duration"].(uint16)
-			return pm.deviceMgr.PlayToneAndWait(port, frequency, duration)
+			params := soundFromParameters(block.Parameters)
+			return pm.deviceMgr.PlayToneAndWait(params.Port, pm.soundFrequency(block), params.Duration)
 		}
 
 	case BlockTypeVoltageSensor:
@@ -250,12 +329,11 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Color = "#8BC34A"
 		block.Parameters["port"] = byte(1)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			cmd := []byte{0x01, 0x02, port, 0x14, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-			return pm.hubMgr.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", cmd)
+			port := paramByte(block.Parameters, "port", 1)
+			return pm.hubMgr.ConfigurePort(NewInputFormatCommand(port, DEVICE_TYPE_VOLTAGE))
 		}
 
 	case BlockTypeCurrentSensor:
@@ -264,12 +342,11 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 		block.Color = "#F44336"
 		block.Parameters["port"] = byte(1)
 		block.OnExecute = func() error {
-			if !pm.hubMgr.IsConnected() {
+			if !pm.hubMgr.Ready() {
 				return fmt.Errorf("не подключено к хабу")
 			}
-			port := block.Parameters["port"].(byte)
-			cmd := []byte{0x01, 0x02, port, 0x15, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x01}
-			return pm.hubMgr.WriteCharacteristic("00001563-1212-efde-1523-785feabcd123", cmd)
+			port := paramByte(block.Parameters, "port", 1)
+			return pm.hubMgr.ConfigurePort(NewInputFormatCommand(port, DEVICE_TYPE_CURRENT))
 		}
 
 	case BlockTypeStop:
@@ -280,115 +357,505 @@ func (pm *ProgramManager) configureBlock(block *ProgramBlock) {
 			pm.StopProgram()
 			return nil
 		}
+
+	case BlockTypeWaitUntil:
+		block.Title = "Ждать пока"
+		block.Description = "Ожидание условия датчика"
+		block.Color = "#607D8B"
+		setDefaultConditionParameters(block.Parameters)
+		block.Parameters["timeout"] = 0.0
+		block.OnExecute = func() error {
+			if !pm.hubMgr.Ready() {
+				return fmt.Errorf("не подключено к хабу")
+			}
+			return pm.waitUntil(pm.runContext(), conditionFromParameters(block.Parameters))
+		}
+
+	case BlockTypeBroadcast:
+		block.Title = "Отправить сообщение"
+		block.Description = "Сообщение другим цепочкам"
+		block.Color = "#E91E63"
+		block.Parameters["message"] = defaultMessageName
+		block.OnExecute = func() error {
+			pm.Broadcast(messageFromParameters(block.Parameters))
+			return nil
+		}
+
+	case BlockTypeReceive:
+		block.Title = "Когда получено"
+		block.Description = "Запуск цепочки по сообщению"
+		block.Color = "#AD1457"
+		block.Parameters["message"] = defaultMessageName
+		block.OnExecute = func() error {
+			programLog.Debugf("Получено сообщение: %s", messageFromParameters(block.Parameters))
+			return nil
+		}
+
+	case BlockTypeScreen:
+		block.Title = "Показать на экране"
+		block.Description = "Крупный текст на экране"
+		block.Color = "#00897B"
+		block.Parameters["text"] = "ПРЕПЯТСТВИЕ!"
+		block.Parameters["duration"] = 2.0
+		block.Parameters["wait"] = false
+		block.OnExecute = func() error {
+			text := paramString(block.Parameters, "text", "")
+			seconds := paramFloat(block.Parameters, "duration", 0)
+			duration := time.Duration(seconds * float64(time.Second))
+
+			programLog.Infof("Вывод на экран: %q на %.1f с", text, seconds)
+			if pm.screenMessageCallback != nil {
+				pm.screenMessageCallback(text, duration)
+			}
+
+			if paramBool(block.Parameters, "wait", false) {
+				select {
+				case <-time.After(pm.scaleWait(duration)):
+				case <-pm.runContext().Done():
+					return pm.runContext().Err()
+				}
+			}
+			return nil
+		}
+
+	case BlockTypeWhenMotion:
+		block.Title = "Когда движение"
+		block.Description = "Запуск цепочки при движении перед камерой"
+		block.Color = "#6A1B9A"
+		block.Parameters["threshold"] = 10.0
+		block.OnExecute = func() error {
+			programLog.Debugf("Камера заметила движение (порог %.0f%%)", motionThreshold(block.Parameters))
+			return nil
+		}
+
+	case BlockTypeWhenColor:
+		block.Title = "Когда цвет"
+		block.Description = "Запуск цепочки, когда камера видит цвет"
+		block.Color = "#6A1B9A"
+		block.Parameters["color"] = VisionColorRed
+		block.OnExecute = func() error {
+			colorKey := paramString(block.Parameters, "color", "")
+			programLog.Debugf("Камера видит цвет: %s", visionColorName(colorKey))
+			return nil
+		}
+
+	case BlockTypeWhenHear:
+		block.Title = "Когда слышу"
+		block.Description = "Запуск цепочки по голосовой команде"
+		block.Color = "#6A1B9A"
+		block.Parameters["keyword"] = defaultSpeechKeywords[0]
+		block.OnExecute = func() error {
+			programLog.Debugf("Услышана команда: %s", keywordFromParameters(block.Parameters))
+			return nil
+		}
+
+	case BlockTypeWhenBumped:
+		block.Title = "Когда удар"
+		block.Description = "Запуск цепочки при ударе или тряске датчика наклона"
+		block.Color = "#6A1B9A"
+		WhenBumpedParams{Port: 1, Event: TiltEventBump}.Store(block.Parameters)
+		block.OnExecute = func() error {
+			programLog.Debugf("Событие датчика: %s", whenBumpedFromParameters(block.Parameters))
+			return nil
+		}
+
+	case BlockTypeFollow:
+		block.Title = "Держать расстояние"
+		block.Description = "П-регулятор мотора по датчику"
+		block.Color = "#1565C0"
+		setDefaultFollowParameters(block.Parameters)
+		block.OnExecute = func() error {
+			return pm.startFollow(block)
+		}
+
+	case BlockTypeHubPower:
+		block.Title = "Питание хаба"
+		block.Description = "Выключение или спящий режим"
+		block.Color = "#455A64"
+		block.Parameters["action"] = HubPowerOff
+		block.OnExecute = func() error {
+			// После выключения хаба продолжать программу нечем
+			pm.StopProgram()
+			return pm.hubMgr.PowerAction(hubPowerFromParameters(block.Parameters))
+		}
+
+	case BlockTypeLightMusic:
+		block.Title = "Светомузыка"
+		block.Description = "Мелодия с цветами светодиода"
+		block.Color = "#AD1457"
+		defaultLightMusicParams.Store(block.Parameters)
+		block.OnExecute = func() error {
+			return pm.playLightMusic(block)
+		}
+
+	case BlockTypeResetTimer:
+		block.Title = "Сбросить таймер"
+		block.Description = "Таймер начинает отсчет с нуля"
+		block.Color = "#795548"
+		block.OnExecute = func() error {
+			pm.ResetTimer()
+			return nil
+		}
+	}
+
+	if !isHatBlock(block.Type) {
+		setDefaultErrorPolicy(block.Parameters)
+	}
+	if usesHub(block.Type) {
+		block.Parameters["op_timeout"] = 0.0
 	}
 }
 
 // RunProgram запускает выполнение программы
 func (pm *ProgramManager) RunProgram() error {
-	if pm.currentState == ProgramStateRunning {
+	if pm.GetProgramState() == ProgramStateRunning {
 		return fmt.Errorf("программа уже выполняется")
 	}
 
-	if !pm.hubMgr.IsConnected() {
+	if !pm.hubMgr.IsConnected() && pm.sensorReplay == nil {
 		return fmt.Errorf("не подключено к хабу")
 	}
 
-	if len(pm.program.Blocks) == 0 {
-		return fmt.Errorf("нет блоков в программе")
+	if issues := pm.ValidateProgram(); len(issues) > 0 {
+		return fmt.Errorf("%s", issues[0].Message)
 	}
 
-	// Находим стартовый блок
-	var startBlock *ProgramBlock
-	for _, block := range pm.program.Blocks {
-		if block.IsStart {
-			startBlock = block
-			break
+	for _, conflict := range pm.PortConflicts() {
+		log.Printf("Внимание: %s. %s", conflict.Message, conflict.Suggestion)
+	}
+
+	startBlocks := pm.startBlocks()
+	keywords := pm.programKeywords()
+	if len(startBlocks) == 0 && !pm.usesVision() && len(keywords) == 0 && len(pm.bumpPorts()) == 0 {
+		return fmt.Errorf("нет блоков для выполнения")
+	}
+
+	if pm.usesVision() {
+		if err := pm.vision.Start(); err != nil {
+			return err
+		}
+	}
+	if len(keywords) > 0 {
+		if err := pm.speech.Start(keywords); err != nil {
+			pm.vision.Stop()
+			return err
 		}
 	}
 
-	if startBlock == nil {
-		if len(pm.program.Blocks) > 0 {
-			startBlock = pm.program.Blocks[0]
-			log.Println("Стартовый блок не найден, используем первый блок в программе")
-		} else {
-			return fmt.Errorf("нет блоков для выполнения")
+	if pm.sensorReplay != nil {
+		if err := pm.hubMgr.StartReplay(pm.sensorReplay); err != nil {
+			pm.vision.Stop()
+			pm.speech.Stop()
+			return err
 		}
 	}
 
-	pm.currentState = ProgramStateRunning
+	pm.runMu.Lock()
+	pm.runCtx, pm.runCancel = context.WithCancel(context.Background())
+	pm.runMu.Unlock()
+
+	pm.resetRandom()
+	pm.ResetTimer()
+	pm.benchmarks.StartRun()
+
+	pm.setState(ProgramStateRunning)
+	pm.publishProgramState("running")
 	log.Println("Запуск программы...")
 
 	// Запускаем выполнение в отдельной горутине
-	go pm.executeProgram(startBlock)
+	go pm.executeProgram(startBlocks)
 
 	return nil
 }
 
-// executeProgram выполняет программу
-func (pm *ProgramManager) executeProgram(startBlock *ProgramBlock) {
-	currentBlock := startBlock
-	executedBlocks := make(map[int]bool)
+// startBlocks находит стартовые блоки: каждая цепочка "Начать" выполняется параллельно.
+// Без блока "Начать" программа начинается с первого обычного блока
+func (pm *ProgramManager) startBlocks() []*ProgramBlock {
+	var startBlocks []*ProgramBlock
+	for _, block := range pm.program.Blocks {
+		if block.IsStart {
+			startBlocks = append(startBlocks, block)
+		}
+	}
 
+	if len(startBlocks) == 0 {
+		for _, block := range pm.program.Blocks {
+			if !isHatBlock(block.Type) {
+				startBlocks = append(startBlocks, block)
+				log.Println("Стартовый блок не найден, используем первый блок в программе")
+				break
+			}
+		}
+	}
+	return startBlocks
+}
+
+// executeProgram выполняет программу: все стартовые цепочки запускаются параллельно,
+// цепочки "Когда получено сообщение" запускаются по сообщениям
+func (pm *ProgramManager) executeProgram(startBlocks []*ProgramBlock) {
+	if pm.crashHandler != nil {
+		defer pm.crashHandler()
+	}
 	log.Println("=== Начало выполнения программы ===")
 
-	for pm.currentState == ProgramStateRunning && currentBlock != nil {
-		if executedBlocks[currentBlock.ID] {
-			log.Printf("Предотвращение бесконечного цикла: блок %d уже выполнялся", currentBlock.ID)
+	ctx := pm.runContext()
+	var chains sync.WaitGroup
+
+	unsubscribe := pm.listenForMessages(ctx, &chains)
+	unsubscribeVision := pm.listenForVision(ctx, &chains)
+	unsubscribeSpeech := pm.listenForSpeech(ctx, &chains)
+	unsubscribeBumps := pm.listenForBumps(ctx, &chains)
+	if pm.hubMgr.Replaying() {
+		go pm.hubMgr.PlayReplay(ctx, pm.scaleWait)
+	}
+
+	for _, startBlock := range startBlocks {
+		chains.Add(1)
+		go pm.runChain(ctx, startBlock, &chains)
+	}
+
+	chains.Wait()
+	pm.controllers.Wait()
+	unsubscribe()
+	unsubscribeVision()
+	unsubscribeSpeech()
+	unsubscribeBumps()
+	pm.vision.Stop()
+	pm.speech.Stop()
+
+	failed := false
+	if pm.changeState(ProgramStateRunning, ProgramStateStopped) {
+		log.Println("=== Программа завершена успешно ===")
+	} else if failed = pm.GetProgramState() == ProgramStateError; failed {
+		log.Println("=== Программа завершена с ошибкой ===")
+	}
+
+	if failed {
+		pm.publishProgramState("error")
+	} else {
+		pm.publishProgramState("stopped")
+	}
+
+	pm.cancelRun()
+	pm.ensureAllMotorsStopped()
+	pm.hubMgr.Arbiter().Release(SourceProgram)
+	pm.hubMgr.StopReplay()
+	log.Println("Все моторы остановлены")
+}
+
+// publishProgramState сообщает подписчикам шины о запуске или завершении программы
+func (pm *ProgramManager) publishProgramState(state string) {
+	pm.hubMgr.Events().Publish(Event{
+		Type: EventProgramState,
+		Name: state,
+	})
+}
+
+// runChain выполняет одну цепочку блоков начиная с заданного
+func (pm *ProgramManager) runChain(ctx context.Context, startBlock *ProgramBlock, chains *sync.WaitGroup) {
+	defer chains.Done()
+	if pm.crashHandler != nil {
+		defer pm.crashHandler()
+	}
+
+	programLog.Infof("Запуск цепочки с блока %s (ID: %d)", startBlock.Title, startBlock.ID)
+
+	sequence, err := pm.buildSequence(startBlock)
+	if err == nil {
+		if schedule := pm.compileChain(sequence); schedule != nil {
+			err = pm.runSchedule(ctx, schedule)
+		} else {
+			err = pm.runSequence(ctx, sequence)
+		}
+	}
+
+	if err != nil {
+		log.Printf("ОШИБКА: %v", err)
+		pm.setLastError(err)
+		pm.setState(ProgramStateError)
+		// Ошибка в одной цепочке останавливает остальные
+		pm.cancelRun()
+	}
+}
+
+// buildSequence собирает цепочку блоков по NextBlockID
+func (pm *ProgramManager) buildSequence(startBlock *ProgramBlock) ([]*ProgramBlock, error) {
+	var sequence []*ProgramBlock
+	visited := make(map[int]bool)
+
+	for currentBlock := startBlock; currentBlock != nil; {
+		if visited[currentBlock.ID] {
+			log.Printf("Предотвращение бесконечного цикла: блок %d уже в цепочке", currentBlock.ID)
+			break
+		}
+		visited[currentBlock.ID] = true
+		sequence = append(sequence, currentBlock)
+
+		if currentBlock.NextBlockID <= 0 {
 			break
 		}
-		executedBlocks[currentBlock.ID] = true
 
-		log.Printf(">>> Выполнение блока: %s (ID: %d) <<<", currentBlock.Title, currentBlock.ID)
+		nextBlock := pm.findBlockByID(currentBlock.NextBlockID)
+		if nextBlock == nil {
+			return sequence, fmt.Errorf("следующий блок %d не найден", currentBlock.NextBlockID)
+		}
+		currentBlock = nextBlock
+	}
 
-		// Выполняем блок
-		if currentBlock.OnExecute != nil {
-			startTime := time.Now()
+	return sequence, nil
+}
 
-			if err := currentBlock.OnExecute(); err != nil {
-				log.Printf("ОШИБКА выполнения блока %d: %v", currentBlock.ID, err)
-				pm.currentState = ProgramStateError
-				break
+// runSequence выполняет последовательность блоков; циклы и условия
+// забирают следующие за ними блоки в качестве тела
+func (pm *ProgramManager) runSequence(ctx context.Context, sequence []*ProgramBlock) error {
+	for i := 0; i < len(sequence); i++ {
+		if pm.GetProgramState() != ProgramStateRunning {
+			return nil
+		}
+
+		block := sequence[i]
+		if err := pm.executeWithPolicy(ctx, block); err != nil {
+			if errors.Is(err, context.Canceled) {
+				programLog.Infof("Выполнение блока %d прервано остановкой программы", block.ID)
+				return nil
 			}
+			return fmt.Errorf("выполнение блока %d: %v", block.ID, err)
+		}
 
-			executionTime := time.Since(startTime)
-			log.Printf("Блок %d выполнен за %v", currentBlock.ID, executionTime)
-		} else {
-			log.Printf("Блок %d не имеет функции выполнения", currentBlock.ID)
+		switch block.Type {
+		case BlockTypeLoop:
+			body := blockBody(sequence, i)
+			if err := pm.runLoop(ctx, block, body); err != nil {
+				return err
+			}
+			i += len(body)
+
+		case BlockTypeCondition:
+			body := blockBody(sequence, i)
+			cond := conditionFromParameters(block.Parameters)
+			if pm.evaluateCondition(cond) {
+				programLog.Debugf("Условие блока %d выполнено: %s", block.ID, cond)
+				if err := pm.runSequence(ctx, body); err != nil {
+					return err
+				}
+			} else {
+				programLog.Debugf("Условие блока %d не выполнено, пропускаем %d блок(ов)", block.ID, len(body))
+			}
+			i += len(body)
 		}
 
-		// Ищем следующий блок
-		if currentBlock.NextBlockID > 0 {
-			nextBlock := pm.findBlockByID(currentBlock.NextBlockID)
-			if nextBlock == nil {
-				log.Printf("ОШИБКА: следующий блок %d не найден", currentBlock.NextBlockID)
-				pm.currentState = ProgramStateError
-				break
+		if i+1 < len(sequence) {
+			if err := pm.pause(ctx, pm.stepDelay(sequence[i+1])); err != nil {
+				return nil
 			}
-			currentBlock = nextBlock
-		} else {
-			log.Printf("Достигнут конец программы (блок %d не имеет следующего блока)", currentBlock.ID)
-			break
 		}
+	}
 
-		if pm.currentState != ProgramStateRunning {
-			break
+	programLog.Debugf("Достигнут конец последовательности блоков")
+	return nil
+}
+
+// executeBlock выполняет действие одного блока
+func (pm *ProgramManager) executeBlock(block *ProgramBlock) error {
+	programLog.Debugf(">>> Выполнение блока: %s (ID: %d) <<<", block.Title, block.ID)
+
+	if block.OnExecute == nil {
+		log.Printf("Блок %d не имеет функции выполнения", block.ID)
+		return nil
+	}
+
+	execution := pm.beforeBlock(block)
+	err := pm.runWithTimeout(block, pm.blockOperationTimeout(block))
+	execution.Duration = time.Since(execution.Started)
+	execution.Err = err
+	pm.afterBlock(execution)
+	if err != nil {
+		return err
+	}
+
+	programLog.Debugf("Блок %d выполнен за %v", block.ID, execution.Duration)
+	return nil
+}
+
+// Benchmarks возвращает замеры длительности блоков
+func (pm *ProgramManager) Benchmarks() *BenchmarkRecorder {
+	return pm.benchmarks
+}
+
+// runLoop выполняет тело цикла в выбранном режиме
+func (pm *ProgramManager) runLoop(ctx context.Context, block *ProgramBlock, body []*ProgramBlock) error {
+	if len(body) == 0 {
+		programLog.Infof("Цикл %d не содержит блоков, пропускаем", block.ID)
+		return nil
+	}
+
+	mode := loopModeFromParameters(block.Parameters)
+	count := paramInt(block.Parameters, "count", 0)
+	cond := conditionFromParameters(block.Parameters)
+	total := 0
+	if mode == LoopModeCount {
+		total = count
+	}
+	defer pm.publishLoopProgress(block, 0, total)
+
+	for iteration := 1; pm.GetProgramState() == ProgramStateRunning; iteration++ {
+		switch mode {
+		case LoopModeCount:
+			if iteration > count {
+				return nil
+			}
+		case LoopModeUntil:
+			// Условие проверяется на границе каждой итерации
+			if pm.evaluateCondition(cond) {
+				programLog.Debugf("Цикл %d завершен по условию: %s", block.ID, cond)
+				return nil
+			}
 		}
 
-		if currentBlock.Type != BlockTypeWait {
-			time.Sleep(10 * time.Millisecond)
+		programLog.Debugf("Цикл %d: итерация %d", block.ID, iteration)
+		pm.publishLoopProgress(block, iteration, total)
+		if err := pm.runSequence(ctx, body); err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
 		}
 	}
 
-	switch pm.currentState {
-	case ProgramStateRunning:
-		pm.currentState = ProgramStateStopped
-		log.Println("=== Программа завершена успешно ===")
-	case ProgramStateError:
-		log.Println("=== Программа завершена с ошибкой ===")
+	return nil
+}
+
+// blockBody возвращает блоки, входящие в тело цикла или условия
+func blockBody(sequence []*ProgramBlock, index int) []*ProgramBlock {
+	rest := sequence[index+1:]
+
+	size := paramInt(sequence[index].Parameters, "body", 0)
+	if size <= 0 || size > len(rest) {
+		return rest
+	}
+	return rest[:size]
+}
+
+// runContext возвращает контекст текущего запуска программы
+func (pm *ProgramManager) runContext() context.Context {
+	pm.runMu.Lock()
+	defer pm.runMu.Unlock()
+
+	if pm.runCtx == nil {
+		return context.Background()
 	}
+	return pm.runCtx
+}
 
-	pm.ensureAllMotorsStopped()
-	log.Println("Все моторы остановлены")
+// cancelRun отменяет контекст текущего запуска
+func (pm *ProgramManager) cancelRun() {
+	pm.runMu.Lock()
+	defer pm.runMu.Unlock()
+
+	if pm.runCancel != nil {
+		pm.runCancel()
+	}
 }
 
 // ensureAllMotorsStopped гарантирует остановку всех моторов
@@ -396,19 +863,19 @@ func (pm *ProgramManager) ensureAllMotorsStopped() {
 	log.Println("Гарантированная остановка всех моторов...")
 	for port := byte(1); port <= 6; port++ {
 		if pm.deviceMgr != nil && pm.hubMgr != nil && pm.hubMgr.IsConnected() {
-			stopCmd := []byte{port, 0x01, 0x01, 0x00}
-			pm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", stopCmd)
+			pm.hubMgr.SendCommand(NewMotorCommand(port).Stop())
 		}
 	}
 }
 
 // StopProgram останавливает программу
 func (pm *ProgramManager) StopProgram() {
-	if pm.currentState == ProgramStateRunning {
-		pm.currentState = ProgramStateStopped
+	if pm.changeState(ProgramStateRunning, ProgramStateStopped) {
+		pm.cancelRun()
 		log.Println("Программа остановлена")
 		pm.ensureAllMotorsStopped()
 		pm.stopAllSounds()
+		pm.hubMgr.Arbiter().Release(SourceProgram)
 	}
 }
 
@@ -417,8 +884,7 @@ func (pm *ProgramManager) stopAllSounds() {
 	log.Println("Остановка всех звуков...")
 	for port := byte(1); port <= 6; port++ {
 		if pm.deviceMgr != nil && pm.hubMgr != nil && pm.hubMgr.IsConnected() {
-			stopCmd := []byte{port, 0x03, 0x00}
-			pm.hubMgr.WriteCharacteristic("00001565-1212-efde-1523-785feabcd123", stopCmd)
+			pm.hubMgr.SendCommand(NewStopToneCommand(port))
 		}
 	}
 }
@@ -437,7 +903,8 @@ func (pm *ProgramManager) findBlockByID(blockID int) *ProgramBlock {
 func (pm *ProgramManager) ClearProgram() {
 	pm.program.Blocks = make([]*ProgramBlock, 0)
 	pm.program.Connections = make([]*Connection, 0)
-	pm.currentState = ProgramStateStopped
+	pm.program.Groups = nil
+	pm.setState(ProgramStateStopped)
 	pm.program.Modified = time.Now()
 	log.Println("Программа очищена")
 }
@@ -510,6 +977,34 @@ func (pm *ProgramManager) RemoveConnection(fromBlockID int) bool {
 	return false
 }
 
+// InsertBlock вставляет блок в соединение fromBlockID -> toBlockID
+func (pm *ProgramManager) InsertBlock(fromBlockID, toBlockID, blockID int) bool {
+	fromBlock, fromExists := pm.GetBlock(fromBlockID)
+	_, blockExists := pm.GetBlock(blockID)
+	if !fromExists || !blockExists || fromBlock.NextBlockID != toBlockID {
+		return false
+	}
+
+	pm.RemoveConnection(fromBlockID)
+	pm.AddConnection(fromBlockID, blockID)
+	pm.AddConnection(blockID, toBlockID)
+	return true
+}
+
+// RedirectConnection направляет выход блока fromBlockID на блок toBlockID,
+// заменяя прежнее соединение, если оно было
+func (pm *ProgramManager) RedirectConnection(fromBlockID, toBlockID int) bool {
+	if fromBlockID == toBlockID {
+		return false
+	}
+	if _, exists := pm.GetBlock(toBlockID); !exists {
+		return false
+	}
+
+	pm.RemoveConnection(fromBlockID)
+	return pm.AddConnection(fromBlockID, toBlockID)
+}
+
 // RemoveBlock полностью удаляет блок
 func (pm *ProgramManager) RemoveBlock(blockID int) bool {
 	var blockToRemove *ProgramBlock
@@ -546,6 +1041,7 @@ func (pm *ProgramManager) RemoveBlock(blockID int) bool {
 		}
 	}
 	pm.program.Connections = newConnections
+	pm.removeBlockFromGroups(blockID)
 
 	// Если удаляемый блок был начальным, делаем первый блок начальным
 	if blockToRemove.IsStart && len(newBlocks) > 0 {
@@ -557,11 +1053,70 @@ func (pm *ProgramManager) RemoveBlock(blockID int) bool {
 	return true
 }
 
+// VisionMonitor возвращает монитор веб-камеры
+func (pm *ProgramManager) VisionMonitor() *VisionMonitor {
+	return pm.vision
+}
+
+// SpeechMonitor возвращает монитор распознавания речи
+func (pm *ProgramManager) SpeechMonitor() *SpeechMonitor {
+	return pm.speech
+}
+
+// SetScreenMessageCallback устанавливает callback вывода сообщений на экран
+func (pm *ProgramManager) SetScreenMessageCallback(callback func(text string, duration time.Duration)) {
+	pm.screenMessageCallback = callback
+}
+
+// SetCrashHandler устанавливает обработчик паники в горутинах выполнения программы,
+// регуляторов и мониторов камеры и речи
+func (pm *ProgramManager) SetCrashHandler(handler func()) {
+	pm.crashHandler = handler
+	pm.vision.SetCrashHandler(handler)
+	pm.speech.SetCrashHandler(handler)
+}
+
+// setLastError запоминает последнюю ошибку выполнения
+func (pm *ProgramManager) setLastError(err error) {
+	pm.errMu.Lock()
+	defer pm.errMu.Unlock()
+	pm.lastError = err
+	pm.lastErrorTime = time.Now()
+}
+
+// LastError возвращает время и текст последней ошибки выполнения программы
+func (pm *ProgramManager) LastError() (time.Time, error) {
+	pm.errMu.Lock()
+	defer pm.errMu.Unlock()
+	return pm.lastErrorTime, pm.lastError
+}
+
 // GetProgramState возвращает состояние программы
 func (pm *ProgramManager) GetProgramState() ProgramState {
+	pm.stateMu.Lock()
+	defer pm.stateMu.Unlock()
 	return pm.currentState
 }
 
+// setState задает состояние программы
+func (pm *ProgramManager) setState(state ProgramState) {
+	pm.stateMu.Lock()
+	defer pm.stateMu.Unlock()
+	pm.currentState = state
+}
+
+// changeState переводит программу из состояния from в to и сообщает, было ли
+// состояние равно from. Остановка и завершение цепочек не перебивают друг друга
+func (pm *ProgramManager) changeState(from, to ProgramState) bool {
+	pm.stateMu.Lock()
+	defer pm.stateMu.Unlock()
+	if pm.currentState != from {
+		return false
+	}
+	pm.currentState = to
+	return true
+}
+
 // UpdateBlockPosition обновляет позицию блока
 func (pm *ProgramManager) UpdateBlockPosition(blockID int, x, y float64) bool {
 	for _, block := range pm.program.Blocks {
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestProgramManager создает менеджер программ поверх поддельного хаба
+func newTestProgramManager() (*ProgramManager, *fakeHub, *fakeActuator) {
+	hub := newFakeHub()
+	actuator := &fakeActuator{}
+	return NewProgramManager(hub, actuator), hub, actuator
+}
+
+// chain создает блоки заданных типов и соединяет их по порядку
+func chain(pm *ProgramManager, types ...BlockType) []*ProgramBlock {
+	blocks := make([]*ProgramBlock, len(types))
+	for i, blockType := range types {
+		blocks[i] = pm.CreateBlock(blockType, float64(i*200), 0)
+		if i > 0 {
+			pm.AddConnection(blocks[i-1].ID, blocks[i].ID)
+		}
+	}
+	return blocks
+}
+
+// runAndWait запускает программу и ждет ее завершения
+func runAndWait(t *testing.T, pm *ProgramManager, hub *fakeHub) {
+	t.Helper()
+
+	finished := make(chan string, 1)
+	unsubscribe := hub.Events().Subscribe(EventProgramState, func(e Event) {
+		if e.Name != "running" {
+			finished <- e.Name
+		}
+	})
+	defer unsubscribe()
+
+	if err := pm.RunProgram(); err != nil {
+		t.Fatalf("RunProgram: %v", err)
+	}
+	select {
+	case state := <-finished:
+		if state != "stopped" {
+			t.Fatalf("программа завершилась в состоянии %q", state)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("программа не завершилась")
+	}
+}
+
+func TestExecutorRunsChainInOrder(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeMotor, BlockTypeLED, BlockTypeSound, BlockTypeMotor)
+	pm.UpdateBlock(blocks[1].ID, map[string]interface{}{"port": byte(1), "power": int8(40)})
+	pm.UpdateBlock(blocks[2].ID, map[string]interface{}{"red": byte(0), "green": byte(255), "blue": byte(0)})
+	pm.UpdateBlock(blocks[3].ID, map[string]interface{}{"frequency": uint16(523)})
+	pm.UpdateBlock(blocks[4].ID, map[string]interface{}{"port": byte(2), "power": int8(-30)})
+
+	runAndWait(t, pm, hub)
+
+	want := []string{"motor 1 40", "led 6 0 255 0", "tone 1 523", "motor 2 -30"}
+	if got := actuator.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("порядок действий %q, ожидалось %q", got, want)
+	}
+	if pm.GetProgramState() != ProgramStateStopped {
+		t.Errorf("состояние после завершения %v, ожидалось остановлено", pm.GetProgramState())
+	}
+}
+
+func TestExecutorRepeatsLoopBody(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeLoop, BlockTypeMotor, BlockTypeLED)
+	pm.UpdateBlock(blocks[1].ID, map[string]interface{}{"count": 3, "body": 1})
+	pm.UpdateBlock(blocks[2].ID, map[string]interface{}{"power": int8(25)})
+
+	runAndWait(t, pm, hub)
+
+	want := []string{"motor 1 25", "motor 1 25", "motor 1 25", "led 6 255 0 0"}
+	if got := actuator.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("порядок действий %q, ожидалось %q", got, want)
+	}
+}
+
+func TestRunProgramRejectsSecondStart(t *testing.T) {
+	pm, hub, _ := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeWait)
+	pm.UpdateBlock(blocks[1].ID, map[string]interface{}{"duration": 0.3})
+
+	finished := make(chan struct{})
+	unsubscribe := hub.Events().Subscribe(EventProgramState, func(e Event) {
+		if e.Name == "stopped" {
+			close(finished)
+		}
+	})
+	defer unsubscribe()
+
+	if err := pm.RunProgram(); err != nil {
+		t.Fatalf("RunProgram: %v", err)
+	}
+	if err := pm.RunProgram(); err == nil {
+		t.Error("повторный запуск работающей программы не вернул ошибку")
+	}
+	<-finished
+}
+
+func TestStopProgramInterruptsChain(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeWait, BlockTypeMotor)
+	pm.UpdateBlock(blocks[1].ID, map[string]interface{}{"duration": 10.0})
+
+	finished := make(chan string, 1)
+	unsubscribe := hub.Events().Subscribe(EventProgramState, func(e Event) {
+		if e.Name != "running" {
+			finished <- e.Name
+		}
+	})
+	defer unsubscribe()
+
+	if err := pm.RunProgram(); err != nil {
+		t.Fatalf("RunProgram: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	stopped := time.Now()
+	pm.StopProgram()
+
+	select {
+	case state := <-finished:
+		if state != "stopped" {
+			t.Errorf("после остановки состояние %q, ожидалось stopped", state)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("остановка не прервала блок «Ждать»")
+	}
+	if elapsed := time.Since(stopped); elapsed > time.Second {
+		t.Errorf("программа остановилась через %v", elapsed)
+	}
+	if got := actuator.recorded(); len(got) != 0 {
+		t.Errorf("после остановки выполнены действия %q", got)
+	}
+	if len(hub.sentCommands()) == 0 {
+		t.Error("при остановке моторам не отправлены команды остановки")
+	}
+	if pm.GetProgramState() != ProgramStateStopped {
+		t.Errorf("состояние %v, ожидалось остановлено", pm.GetProgramState())
+	}
+}
+
+func TestStopProgramInterruptsRunningBlock(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	actuator.delay = 200 * time.Millisecond
+	chain(pm, BlockTypeStart, BlockTypeMotor, BlockTypeLED)
+
+	finished := make(chan struct{})
+	unsubscribe := hub.Events().Subscribe(EventProgramState, func(e Event) {
+		if e.Name == "stopped" {
+			close(finished)
+		}
+	})
+	defer unsubscribe()
+
+	if err := pm.RunProgram(); err != nil {
+		t.Fatalf("RunProgram: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	pm.StopProgram()
+	<-finished
+
+	// Блок мотора успевает начаться, следующий за ним светодиод — нет
+	want := []string{"motor 1 50"}
+	if got := actuator.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("выполнены действия %q, ожидалось %q", got, want)
+	}
+}
+
+func TestInsertBlockIntoConnection(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeMotor)
+	led := pm.CreateBlock(BlockTypeLED, 100, 100)
+
+	if !pm.InsertBlock(blocks[0].ID, blocks[1].ID, led.ID) {
+		t.Fatal("InsertBlock отказал для существующего соединения")
+	}
+	if blocks[0].NextBlockID != led.ID || led.NextBlockID != blocks[1].ID {
+		t.Fatalf("цепочка после вставки %d -> %d -> %d", blocks[0].ID, blocks[0].NextBlockID, led.NextBlockID)
+	}
+	if n := len(pm.GetProgram().Connections); n != 2 {
+		t.Errorf("соединений после вставки %d, ожидалось 2", n)
+	}
+	if pm.InsertBlock(blocks[0].ID, blocks[1].ID, led.ID) {
+		t.Error("InsertBlock вставил блок в несуществующее соединение")
+	}
+
+	runAndWait(t, pm, hub)
+
+	want := []string{"led 6 255 0 0", "motor 1 50"}
+	if got := actuator.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("порядок действий %q, ожидалось %q", got, want)
+	}
+}
+
+func TestRemoveBlockDropsConnections(t *testing.T) {
+	pm, hub, actuator := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeMotor, BlockTypeLED)
+
+	if !pm.RemoveBlock(blocks[1].ID) {
+		t.Fatal("RemoveBlock не нашел блок")
+	}
+	if pm.RemoveBlock(blocks[1].ID) {
+		t.Error("RemoveBlock удалил блок повторно")
+	}
+	if _, ok := pm.GetBlock(blocks[1].ID); ok {
+		t.Error("удаленный блок остался в программе")
+	}
+	if blocks[0].NextBlockID != 0 {
+		t.Errorf("блок перед удаленным ссылается на %d", blocks[0].NextBlockID)
+	}
+	for _, conn := range pm.GetProgram().Connections {
+		if conn.FromBlockID == blocks[1].ID || conn.ToBlockID == blocks[1].ID {
+			t.Errorf("осталось соединение %d -> %d", conn.FromBlockID, conn.ToBlockID)
+		}
+	}
+
+	// Отсоединенный светодиод не выполняется
+	runAndWait(t, pm, hub)
+	if got := actuator.recorded(); len(got) != 0 {
+		t.Errorf("выполнены действия %q, ожидалось ничего", got)
+	}
+}
+
+func TestRemoveStartBlockPromotesFirstBlock(t *testing.T) {
+	pm, _, _ := newTestProgramManager()
+	blocks := chain(pm, BlockTypeStart, BlockTypeMotor)
+
+	pm.RemoveBlock(blocks[0].ID)
+	if !blocks[1].IsStart {
+		t.Error("после удаления «Начать» первый блок не стал начальным")
+	}
+	if id := pm.CreateBlock(BlockTypeLED, 0, 0).ID; id != blocks[1].ID+1 {
+		t.Errorf("новый блок получил ID %d, ожидался %d", id, blocks[1].ID+1)
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
-	"image/color"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
 )
 
 // ProgramPanel панель визуального программирования
@@ -17,14 +20,29 @@ type ProgramPanel struct {
 	programMgr    *ProgramManager
 	connections   []*ConnectionLine
 	blockWidgets  map[int]*DraggableBlock
+	groupWidgets  map[int]*GroupWidget
 	lastBlockY    float64
-	selectedBlock *ProgramBlock   // Выбранный блок для выделения
-	gridContainer *fyne.Container // Контейнер для сетки
+	selectedBlock *ProgramBlock     // Выбранный блок для выделения
+	selectedConn  *ConnectionLine   // Выбранное соединение, в которое вставляются новые блоки
+	background    *canvas.Rectangle // Фон холста
+	grid          *canvas.Raster    // Разметка холста: сетка, точки или линии
+	gridContainer *fyne.Container   // Контейнер для сетки
+	style         canvasBackgroundStyle
+	preview       *insertionPreview // Призрак блока, перетаскиваемого из палитры
+	pathPool      []*ConnectionPath // Освободившиеся пути соединений для повторного использования
+
+	// Блоки, соединения которых нужно перерисовать в следующем кадре
+	dirtyBlocks    map[int]bool
+	frameScheduled bool
+	dirtyMu        sync.Mutex
 }
 
+// connectionFrameInterval минимальный интервал между перерисовками соединений
+const connectionFrameInterval = time.Second / 60
+
 // ConnectionLine линия соединения между блоками
 type ConnectionLine struct {
-	line          *canvas.Line
+	path          *ConnectionPath
 	fromBlockID   int
 	toBlockID     int
 	isHighlighted bool
@@ -37,7 +55,9 @@ func NewProgramPanel(gui *MainGUI, programMgr *ProgramManager) *ProgramPanel {
 		programMgr:   programMgr,
 		connections:  make([]*ConnectionLine, 0),
 		blockWidgets: make(map[int]*DraggableBlock),
+		groupWidgets: make(map[int]*GroupWidget),
 		lastBlockY:   50,
+		dirtyBlocks:  make(map[int]bool),
 	}
 
 	// Создаем основной контейнер с сеткой и блоками
@@ -55,35 +75,75 @@ func (p *ProgramPanel) GetContainer() fyne.CanvasObject {
 	return p.scroll
 }
 
-// addGrid добавляет сетку на холст
+// Размеры холста и шаг сетки
+const (
+	canvasSize   = 2000
+	gridStep     = 20
+	gridLineGray = 50
+)
+
+// addGrid добавляет фон и разметку холста, выбранные в настройках
 func (p *ProgramPanel) addGrid() {
-	// Фон сетки
-	bg := canvas.NewRectangle(color.NRGBA{R: 30, G: 30, B: 30, A: 255})
-	bg.SetMinSize(fyne.NewSize(2000, 2000))
-	p.content.Add(bg)
+	style := canvasStyle(CanvasBackground(p.gui.preferences().String(settingCanvasBackground)))
+	p.style = style
 
-	// Контейнер для линий сетки
-	p.gridContainer = container.NewWithoutLayout()
+	p.background = canvas.NewRectangle(style.fill)
+	p.background.SetMinSize(fyne.NewSize(canvasSize, canvasSize))
+	p.content.Add(p.background)
 
-	// Вертикальные линии
-	for x := 0; x <= 2000; x += 20 {
-		line := canvas.NewLine(color.NRGBA{R: 50, G: 50, B: 50, A: 255})
-		line.Position1 = fyne.NewPos(float32(x), 0)
-		line.Position2 = fyne.NewPos(float32(x), 2000)
-		line.StrokeWidth = 1
-		p.gridContainer.Add(line)
+	// Разметка рисуется одним растром вместо двух сотен объектов-линий
+	p.grid = canvas.NewRaster(style.draw)
+	p.grid.Resize(fyne.NewSize(canvasSize, canvasSize))
+	p.gridContainer = container.NewWithoutLayout(p.grid)
+
+	p.content.Add(p.gridContainer)
+}
+
+// acquirePath берет путь соединения из пула или создает новый
+func (p *ProgramPanel) acquirePath() *ConnectionPath {
+	if n := len(p.pathPool); n > 0 {
+		path := p.pathPool[n-1]
+		p.pathPool = p.pathPool[:n-1]
+		return path
 	}
+	return NewConnectionPath()
+}
 
-	// Горизонтальные линии
-	for y := 0; y <= 2000; y += 20 {
-		line := canvas.NewLine(color.NRGBA{R: 50, G: 50, B: 50, A: 255})
-		line.Position1 = fyne.NewPos(0, float32(y))
-		line.Position2 = fyne.NewPos(2000, float32(y))
-		line.StrokeWidth = 1
-		p.gridContainer.Add(line)
+// releasePath возвращает путь соединения в пул
+func (p *ProgramPanel) releasePath(path *ConnectionPath) {
+	path.setHovered(false)
+	path.OnTapped = nil
+	path.OnTappedSecondary = nil
+	p.pathPool = append(p.pathPool, path)
+}
+
+// gridLayers число объектов холста под соединениями: фон и сетка
+const gridLayers = 2
+
+// addBelowBlocks добавляет объект на холст над сеткой, группами и соединениями, но под блоками
+func (p *ProgramPanel) addBelowBlocks(obj fyne.CanvasObject) {
+	index := len(p.content.Objects)
+	for i, o := range p.content.Objects {
+		if _, ok := o.(*DraggableBlock); ok {
+			index = i
+			break
+		}
 	}
+	p.insertObject(index, obj)
+}
 
-	p.content.Add(p.gridContainer)
+// addAboveGrid добавляет объект на холст сразу над сеткой, под всеми остальными объектами
+func (p *ProgramPanel) addAboveGrid(obj fyne.CanvasObject) {
+	p.insertObject(gridLayers, obj)
+}
+
+// insertObject вставляет объект в холст на позицию index
+func (p *ProgramPanel) insertObject(index int, obj fyne.CanvasObject) {
+	objects := make([]fyne.CanvasObject, 0, len(p.content.Objects)+1)
+	objects = append(objects, p.content.Objects[:index]...)
+	objects = append(objects, obj)
+	objects = append(objects, p.content.Objects[index:]...)
+	p.content.Objects = objects
 }
 
 // AddBlock добавляет блок на холст
@@ -108,12 +168,19 @@ func (p *ProgramPanel) AddBlock(block *ProgramBlock) {
 	p.content.Add(blockWidget)
 	p.blockWidgets[block.ID] = blockWidget
 
+	// Если выбрано соединение, вставляем блок в него, иначе
+	// автоматически соединяем с предыдущим блоком
+	if p.selectedConn != nil && !isHatBlock(block.Type) {
+		p.insertIntoConnection(p.selectedConn, blockWidget)
+		p.content.Refresh()
+		return
+	}
+
 	// Обновляем lastBlockY для следующего блока
 	p.lastBlockY = block.Y + block.Height + 40
 
 	p.content.Refresh()
 
-	// Автоматически соединяем с предыдущим блоком
 	p.autoConnectBlock(block)
 
 	log.Printf("Блок добавлен на холст: %s (ID: %d) на позиции (%.0f, %.0f)",
@@ -122,6 +189,11 @@ func (p *ProgramPanel) AddBlock(block *ProgramBlock) {
 
 // autoConnectBlock автоматически соединяет блок с предыдущим
 func (p *ProgramPanel) autoConnectBlock(newBlock *ProgramBlock) {
+	// Блоки-шапки начинают новую цепочку
+	if isHatBlock(newBlock.Type) {
+		return
+	}
+
 	// Находим последний добавленный блок (кроме текущего)
 	var lastBlock *ProgramBlock
 	lastBlockID := 0
@@ -146,57 +218,121 @@ func (p *ProgramPanel) autoConnectBlock(newBlock *ProgramBlock) {
 
 // createVisualConnection создает визуальное соединение между блоками
 func (p *ProgramPanel) createVisualConnection(fromBlockID, toBlockID int) {
-	// Получаем виджеты блоков
-	fromWidget, fromExists := p.blockWidgets[fromBlockID]
-	toWidget, toExists := p.blockWidgets[toBlockID]
+	// Проверяем, что оба блока есть на холсте
+	_, fromExists := p.blockWidgets[fromBlockID]
+	_, toExists := p.blockWidgets[toBlockID]
 
 	if !fromExists || !toExists {
 		log.Printf("Не удалось найти виджеты для соединения %d -> %d", fromBlockID, toBlockID)
 		return
 	}
 
-	// Получаем позиции коннекторов
-	fromPos := fromWidget.GetBottomConnectorPosition()
-	toPos := toWidget.GetTopConnectorPosition()
-
-	// Создаем линию соединения (синяя по умолчанию)
-	line := canvas.NewLine(color.NRGBA{R: 0, G: 150, B: 255, A: 255})
-	line.Position1 = fromPos
-	line.Position2 = toPos
-	line.StrokeWidth = 2
+	// Создаем путь соединения (синий по умолчанию)
+	path := p.acquirePath()
+	path.SetStyle(connectionColor, 2)
+	path.SetPoints(p.routeBetween(fromBlockID, toBlockID))
 
-	// Добавляем линию на панель (после сетки, но до блоков)
-	p.content.Add(line)
+	// Добавляем путь на панель (после сетки, но до блоков)
+	p.addBelowBlocks(path)
 
 	// Сохраняем соединение
 	connection := &ConnectionLine{
-		line:          line,
+		path:          path,
 		fromBlockID:   fromBlockID,
 		toBlockID:     toBlockID,
 		isHighlighted: false,
 	}
+	path.OnTapped = func() {
+		p.selectConnection(connection)
+	}
+	path.OnTappedSecondary = func(absolute fyne.Position) {
+		p.showConnectionMenu(connection, absolute)
+	}
 
 	p.connections = append(p.connections, connection)
 	p.content.Refresh()
 }
 
-// updateConnections обновляет все соединения
+// updateConnections обновляет рамки групп и все соединения
 func (p *ProgramPanel) updateConnections() {
+	p.updateGroups()
 	for _, conn := range p.connections {
-		// Получаем виджеты блоков
-		fromWidget, fromExists := p.blockWidgets[conn.fromBlockID]
-		toWidget, toExists := p.blockWidgets[conn.toBlockID]
-
-		if fromExists && toExists {
-			// Обновляем позиции линии
-			fromPos := fromWidget.GetBottomConnectorPosition()
-			toPos := toWidget.GetTopConnectorPosition()
-
-			conn.line.Position1 = fromPos
-			conn.line.Position2 = toPos
-			conn.line.Refresh()
+		p.updateConnectionLine(conn)
+	}
+}
+
+// updateConnectionLine прокладывает путь соединения заново и перерисовывает только его
+func (p *ProgramPanel) updateConnectionLine(conn *ConnectionLine) {
+	points := p.routeBetween(conn.fromBlockID, conn.toBlockID)
+	if conn.path.samePoints(points) {
+		return
+	}
+	conn.path.SetPoints(points)
+}
+
+// routeBetween прокладывает путь между блоками в обход остальных блоков холста.
+// Блок свернутой группы представлен группой целиком; соединение внутри
+// свернутой группы не рисуется, и тогда возвращается nil.
+func (p *ProgramPanel) routeBetween(fromBlockID, toBlockID int) []fyne.Position {
+	fromRect, fromKey, fromOK := p.endpointRect(fromBlockID)
+	toRect, toKey, toOK := p.endpointRect(toBlockID)
+	if !fromOK || !toOK || fromKey == toKey {
+		return nil
+	}
+
+	obstacles := make([]blockRect, 0, len(p.blockWidgets))
+	for _, blockWidget := range p.blockWidgets {
+		id := blockWidget.block.ID
+		if blockWidget.Visible() && id != fromBlockID && id != toBlockID {
+			obstacles = append(obstacles, newBlockRect(blockWidget))
+		}
+	}
+	for _, groupWidget := range p.groupWidgets {
+		if groupWidget.group.Collapsed && groupWidget.group.ID != -fromKey && groupWidget.group.ID != -toKey {
+			obstacles = append(obstacles, groupWidget.rect())
 		}
 	}
+
+	return routeConnection(
+		fyne.NewPos((fromRect.Min.X+fromRect.Max.X)/2, fromRect.Max.Y),
+		fyne.NewPos((toRect.Min.X+toRect.Max.X)/2, toRect.Min.Y),
+		fromRect,
+		toRect,
+		obstacles,
+	)
+}
+
+// scheduleConnectionUpdate помечает соединения блока для перерисовки.
+// Во время перетаскивания события приходят чаще кадров, поэтому линии
+// обновляются не чаще одного раза за кадр и только у перемещенных блоков.
+func (p *ProgramPanel) scheduleConnectionUpdate(blockID int) {
+	p.dirtyMu.Lock()
+	defer p.dirtyMu.Unlock()
+
+	p.dirtyBlocks[blockID] = true
+	if p.frameScheduled {
+		return
+	}
+
+	p.frameScheduled = true
+	time.AfterFunc(connectionFrameInterval, func() {
+		fyne.Do(p.flushConnectionUpdates)
+	})
+}
+
+// flushConnectionUpdates перерисовывает соединения после перемещения блоков.
+// Перемещенный блок может оказаться на пути чужих соединений, поэтому
+// трассируются все пути, а перерисовываются только изменившиеся.
+func (p *ProgramPanel) flushConnectionUpdates() {
+	p.dirtyMu.Lock()
+	dirty := len(p.dirtyBlocks) > 0
+	p.dirtyBlocks = make(map[int]bool)
+	p.frameScheduled = false
+	p.dirtyMu.Unlock()
+
+	if dirty {
+		p.updateConnections()
+	}
 }
 
 // RemoveBlock удаляет блок с холста
@@ -212,8 +348,9 @@ func (p *ProgramPanel) RemoveBlock(blockID int) {
 		delete(p.blockWidgets, blockID)
 	}
 
-	// Удаляем связанные соединения
+	// Удаляем связанные соединения и обновляем группы
 	p.removeConnectionsForBlock(blockID)
+	p.refreshGroups()
 
 	// Пересчитываем позиции оставшихся блоков
 	p.repositionRemainingBlocks()
@@ -226,13 +363,17 @@ func (p *ProgramPanel) removeConnectionsForBlock(blockID int) {
 	var newConnections []*ConnectionLine
 	for _, conn := range p.connections {
 		if conn.fromBlockID == blockID || conn.toBlockID == blockID {
-			// Удаляем линию из контейнера
+			// Удаляем путь из контейнера
 			for i, obj := range p.content.Objects {
-				if obj == conn.line {
+				if obj == conn.path {
 					p.content.Objects = append(p.content.Objects[:i], p.content.Objects[i+1:]...)
 					break
 				}
 			}
+			p.releasePath(conn.path)
+			if conn == p.selectedConn {
+				p.selectedConn = nil
+			}
 		} else {
 			newConnections = append(newConnections, conn)
 		}
@@ -282,41 +423,267 @@ func (p *ProgramPanel) Clear() {
 	newObjects = append(newObjects, p.content.Objects[1]) // Сетка
 
 	p.content.Objects = newObjects
+	for _, conn := range p.connections {
+		p.releasePath(conn.path)
+	}
 	p.connections = make([]*ConnectionLine, 0)
+	p.selectedConn = nil
+	p.selectedBlock = nil
 	p.blockWidgets = make(map[int]*DraggableBlock)
+	p.groupWidgets = make(map[int]*GroupWidget)
 	p.lastBlockY = 50
+
+	// Отложенная перерисовка не должна обращаться к удаленным блокам
+	p.dirtyMu.Lock()
+	p.dirtyBlocks = make(map[int]bool)
+	p.dirtyMu.Unlock()
+
 	p.content.Refresh()
 }
 
+// ShowProgram перестраивает холст по текущей программе, сохраняя позиции блоков
+func (p *ProgramPanel) ShowProgram() {
+	p.Clear()
+
+	program := p.programMgr.GetProgram()
+	for _, block := range program.Blocks {
+		block.DragStartPos = fyne.NewPos(float32(block.X), float32(block.Y))
+
+		blockWidget := NewDraggableBlock(block, p.programMgr, p.gui)
+		blockWidget.Resize(fyne.NewSize(float32(block.Width), float32(block.Height)))
+		blockWidget.Move(fyne.NewPos(float32(block.X), float32(block.Y)))
+		p.content.Add(blockWidget)
+		p.blockWidgets[block.ID] = blockWidget
+
+		if bottom := block.Y + block.Height + 40; bottom > p.lastBlockY {
+			p.lastBlockY = bottom
+		}
+	}
+
+	for _, conn := range program.Connections {
+		p.createVisualConnection(conn.FromBlockID, conn.ToBlockID)
+	}
+
+	p.refreshGroups()
+}
+
 // HighlightConnections выделяет соединения блока
 func (p *ProgramPanel) HighlightConnections(blockID int) {
-	// Сбрасываем выделение всех линий
 	for _, conn := range p.connections {
-		conn.isHighlighted = false
-		conn.line.StrokeColor = color.NRGBA{R: 0, G: 150, B: 255, A: 255} // Синий
-		conn.line.StrokeWidth = 2
+		p.setConnectionHighlight(conn, conn.fromBlockID == blockID || conn.toBlockID == blockID)
 	}
+}
 
-	// Выделяем линии, связанные с блоком
+// ResetHighlight сбрасывает выделение всех соединений
+func (p *ProgramPanel) ResetHighlight() {
 	for _, conn := range p.connections {
-		if conn.fromBlockID == blockID || conn.toBlockID == blockID {
-			conn.isHighlighted = true
-			conn.line.StrokeColor = color.NRGBA{R: 255, G: 215, B: 0, A: 255} // Золотой
-			conn.line.StrokeWidth = 3
+		p.setConnectionHighlight(conn, false)
+	}
+}
+
+// setConnectionHighlight меняет выделение пути и перерисовывает его, только если оно изменилось
+func (p *ProgramPanel) setConnectionHighlight(conn *ConnectionLine, highlighted bool) {
+	if conn.isHighlighted == highlighted {
+		return
+	}
+
+	conn.isHighlighted = highlighted
+	p.applyConnectionStyle(conn)
+}
+
+// applyConnectionStyle раскрашивает путь: выбранное соединение зеленое,
+// соединения выделенного блока золотые, остальные синие
+func (p *ProgramPanel) applyConnectionStyle(conn *ConnectionLine) {
+	switch {
+	case conn == p.selectedConn:
+		conn.path.SetStyle(connectionSelectedColor, 4)
+	case conn.isHighlighted:
+		conn.path.SetStyle(connectionHighlightColor, 3)
+	default:
+		conn.path.SetStyle(connectionColor, 2)
+	}
+}
+
+// selectConnection выбирает соединение: следующие блоки из палитры будут вставлены в него.
+// Повторный клик снимает выбор.
+func (p *ProgramPanel) selectConnection(conn *ConnectionLine) {
+	previous := p.selectedConn
+	if previous == conn {
+		p.selectedConn = nil
+	} else {
+		p.selectedConn = conn
+	}
+
+	if previous != nil {
+		p.applyConnectionStyle(previous)
+	}
+	p.applyConnectionStyle(conn)
+
+	if p.selectedConn != nil {
+		p.gui.showConnectionProperties(p.selectedConn.fromBlockID, p.selectedConn.toBlockID)
+	} else {
+		p.gui.clearPropertiesPanel()
+	}
+}
+
+// clearConnectionSelection снимает выбор соединения
+func (p *ProgramPanel) clearConnectionSelection() {
+	if conn := p.selectedConn; conn != nil {
+		p.selectedConn = nil
+		p.applyConnectionStyle(conn)
+	}
+}
+
+// insertIntoConnection вставляет блок в соединение from -> to. Блоки цепочки,
+// начиная с to, сдвигаются вниз, а выбор переходит на соединение нового блока
+// с to, чтобы следующие блоки вставлялись по порядку.
+func (p *ProgramPanel) insertIntoConnection(conn *ConnectionLine, blockWidget *DraggableBlock) {
+	fromID, toID := conn.fromBlockID, conn.toBlockID
+	block := blockWidget.block
+
+	fromWidget, fromExists := p.blockWidgets[fromID]
+	if !fromExists || !p.programMgr.InsertBlock(fromID, toID, block.ID) {
+		log.Printf("Не удалось вставить блок %d в соединение %d -> %d", block.ID, fromID, toID)
+		return
+	}
+
+	// Новый блок встает под блоком-источником
+	pos := fyne.NewPos(fromWidget.Position().X, fromWidget.Position().Y+float32(fromWidget.block.Height+layoutRowGap))
+	block.X, block.Y = float64(pos.X), float64(pos.Y)
+	block.DragStartPos = pos
+	blockWidget.Move(pos)
+
+	// Освобождаем место: сдвигаем вниз цепочку, начиная с блока назначения
+	shift := float32(block.Height + layoutRowGap)
+	visited := map[int]bool{fromID: true, block.ID: true}
+	for id := toID; id > 0 && !visited[id]; {
+		visited[id] = true
+		target, exists := p.blockWidgets[id]
+		if !exists {
+			break
+		}
+		if target.Position().Y >= pos.Y-shift {
+			newPos := target.Position().AddXY(0, shift)
+			target.Move(newPos)
+			target.block.X, target.block.Y = float64(newPos.X), float64(newPos.Y)
+			target.block.DragStartPos = newPos
+			p.lastBlockY = float64(fyne.Max(float32(p.lastBlockY), newPos.Y+float32(target.block.Height+layoutRowGap)))
 		}
+		id = target.block.NextBlockID
 	}
 
-	p.content.Refresh()
+	// Заменяем визуальное соединение двумя новыми
+	p.removeConnection(conn)
+	p.createVisualConnection(fromID, block.ID)
+	p.createVisualConnection(block.ID, toID)
+	p.updateConnections()
+
+	for _, c := range p.connections {
+		if c.fromBlockID == block.ID && c.toBlockID == toID {
+			p.selectConnection(c)
+		}
+	}
+
+	log.Printf("Блок %d вставлен в соединение %d -> %d", block.ID, fromID, toID)
 }
 
-// ResetHighlight сбрасывает выделение всех соединений
-func (p *ProgramPanel) ResetHighlight() {
-	for _, conn := range p.connections {
-		conn.isHighlighted = false
-		conn.line.StrokeColor = color.NRGBA{R: 0, G: 150, B: 255, A: 255}
-		conn.line.StrokeWidth = 2
+// showConnectionMenu показывает контекстное меню соединения: удаление и перенаправление
+func (p *ProgramPanel) showConnectionMenu(conn *ConnectionLine, absolute fyne.Position) {
+	deleteItem := fyne.NewMenuItem("Удалить соединение", func() {
+		p.deleteConnection(conn)
+	})
+
+	redirectItem := fyne.NewMenuItem("Перенаправить на", nil)
+	redirectItem.ChildMenu = p.connectionTargetsMenu(conn.fromBlockID, conn.toBlockID, func(toBlockID int) {
+		p.redirectConnection(conn, toBlockID)
+	})
+
+	locked := p.gui.readOnly()
+	deleteItem.Disabled = locked
+	redirectItem.Disabled = locked || len(redirectItem.ChildMenu.Items) == 0
+
+	menu := fyne.NewMenu("", deleteItem, redirectItem)
+	widget.ShowPopUpMenuAtPosition(menu, p.gui.window.Canvas(), absolute)
+}
+
+// connectionTargetsMenu возвращает меню блоков, к которым можно провести соединение от fromBlockID
+func (p *ProgramPanel) connectionTargetsMenu(fromBlockID, currentToID int, onPick func(toBlockID int)) *fyne.Menu {
+	menu := fyne.NewMenu("")
+	for _, block := range p.programMgr.GetProgram().Blocks {
+		// Шапки начинают цепочку и не могут стоять после другого блока
+		if block.ID == fromBlockID || block.ID == currentToID || isHatBlock(block.Type) {
+			continue
+		}
+		toBlockID := block.ID
+		menu.Items = append(menu.Items, fyne.NewMenuItem(
+			fmt.Sprintf("%s (ID: %d)", block.Title, block.ID),
+			func() { onPick(toBlockID) },
+		))
 	}
+	return menu
+}
+
+// deleteConnection удаляет соединение из программы и с холста
+func (p *ProgramPanel) deleteConnection(conn *ConnectionLine) {
+	p.gui.checkpoint()
+	p.programMgr.RemoveConnection(conn.fromBlockID)
+	p.removeConnection(conn)
 	p.content.Refresh()
+	p.gui.clearPropertiesPanel()
+
+	log.Printf("Соединение %d -> %d удалено", conn.fromBlockID, conn.toBlockID)
+}
+
+// redirectConnection перенаправляет соединение на другой блок
+func (p *ProgramPanel) redirectConnection(conn *ConnectionLine, toBlockID int) {
+	p.gui.checkpoint()
+	if !p.programMgr.RedirectConnection(conn.fromBlockID, toBlockID) {
+		return
+	}
+
+	p.removeConnection(conn)
+	p.createVisualConnection(conn.fromBlockID, toBlockID)
+	p.gui.clearPropertiesPanel()
+
+	log.Printf("Соединение блока %d перенаправлено на блок %d", conn.fromBlockID, toBlockID)
+}
+
+// connectBlocks соединяет блок без продолжения с выбранным блоком
+func (p *ProgramPanel) connectBlocks(fromBlockID, toBlockID int) {
+	p.gui.checkpoint()
+	if !p.programMgr.RedirectConnection(fromBlockID, toBlockID) {
+		return
+	}
+	p.createVisualConnection(fromBlockID, toBlockID)
+
+	log.Printf("Блоки соединены вручную: %d -> %d", fromBlockID, toBlockID)
+}
+
+// removeConnection удаляет одно визуальное соединение с холста
+func (p *ProgramPanel) removeConnection(conn *ConnectionLine) {
+	for i, c := range p.connections {
+		if c == conn {
+			p.connections = append(p.connections[:i], p.connections[i+1:]...)
+			break
+		}
+	}
+	for i, obj := range p.content.Objects {
+		if obj == conn.path {
+			p.content.Objects = append(p.content.Objects[:i], p.content.Objects[i+1:]...)
+			break
+		}
+	}
+	if conn == p.selectedConn {
+		p.selectedConn = nil
+	}
+	p.releasePath(conn.path)
+}
+
+// refreshBlockStyles перерисовывает блоки после изменения стиля категорий
+func (p *ProgramPanel) refreshBlockStyles() {
+	for _, blockWidget := range p.blockWidgets {
+		blockWidget.applyStyle()
+	}
 }
 
 // GetBlockWidget возвращает виджет блока по ID
@@ -328,6 +695,7 @@ func (p *ProgramPanel) GetBlockWidget(blockID int) *DraggableBlock {
 func (p *ProgramPanel) SetSelectedBlock(block *ProgramBlock) {
 	p.selectedBlock = block
 	if block != nil {
+		p.clearConnectionSelection()
 		p.HighlightConnections(block.ID)
 	} else {
 		p.ResetHighlight()
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	settingCompileProgram = "compile_program" // Выполнять цепочки по заранее рассчитанному расписанию
+	maxScheduleEntries    = 10000             // Больше команд — цепочка выполняется блок за блоком
+)
+
+// ScheduleKind вид команды в расписании
+type ScheduleKind int
+
+const (
+	ScheduleMotor ScheduleKind = iota // Мотор: мощность на время Duration, 0 — до остановки
+	ScheduleLED                       // Цвет светодиода
+	ScheduleSound                     // Звук пищалки на время Duration
+	ScheduleLoop                      // Отметка итерации цикла для индикатора прогресса
+	ScheduleBlock                     // Начало блока из нескольких команд на время Duration
+)
+
+// ScheduleEntry команда расписания со временем от начала цепочки
+type ScheduleEntry struct {
+	At       time.Duration
+	Duration time.Duration
+	BlockID  int
+	Kind     ScheduleKind
+	Port     byte
+
+	Power            int8
+	Red, Green, Blue byte
+	Frequency        uint16
+
+	Iteration, Total int // Для отметок цикла; Iteration 0 — цикл завершен
+
+	Continues bool // Команда продолжает блок, о начале которого уже сообщено
+}
+
+// Schedule плоское расписание команд цепочки: постоянные паузы уже учтены во времени
+// команд, циклы со счетчиком развернуты, подряд идущие команды светодиоду слиты
+type Schedule struct {
+	Entries []ScheduleEntry
+	Length  time.Duration
+}
+
+// scheduleCompiler переводит последовательность блоков в расписание
+type scheduleCompiler struct {
+	schedule  *Schedule
+	at        time.Duration
+	scaleWait func(time.Duration) time.Duration
+}
+
+// compileSchedule рассчитывает расписание цепочки. Ошибка означает, что цепочку
+// нельзя рассчитать заранее: она зависит от датчиков, событий или случайных значений
+func compileSchedule(sequence []*ProgramBlock, scaleWait func(time.Duration) time.Duration) (*Schedule, error) {
+	c := &scheduleCompiler{schedule: &Schedule{}, scaleWait: scaleWait}
+	if err := c.compile(sequence); err != nil {
+		return nil, err
+	}
+	c.schedule.Length = c.at
+	for _, entry := range c.schedule.Entries {
+		if end := entry.At + entry.Duration; end > c.schedule.Length {
+			c.schedule.Length = end
+		}
+	}
+	return c.schedule, nil
+}
+
+// compile добавляет в расписание блоки последовательности
+func (c *scheduleCompiler) compile(sequence []*ProgramBlock) error {
+	for i := 0; i < len(sequence); i++ {
+		block := sequence[i]
+		if !isHatBlock(block.Type) && errorPolicyFromParameters(block.Parameters).Action != ErrorActionStop {
+			return fmt.Errorf("у блока «%s» (ID: %d) своя политика ошибок", block.Title, block.ID)
+		}
+		if isRandomized(block) {
+			return fmt.Errorf("блок «%s» (ID: %d) использует случайные значения", block.Title, block.ID)
+		}
+
+		switch block.Type {
+		case BlockTypeStart:
+
+		case BlockTypeMotor:
+			if _, bound := bindingFromParameters(block.Parameters, "power"); bound {
+				return fmt.Errorf("мощность блока %d привязана к датчику", block.ID)
+			}
+			params := motorFromParameters(block.Parameters)
+			duration := time.Duration(params.Duration) * time.Millisecond
+			c.add(ScheduleEntry{Kind: ScheduleMotor, BlockID: block.ID, Duration: duration,
+				Port: params.Port, Power: params.Power})
+			c.at += duration
+
+		case BlockTypeLED:
+			params := ledFromParameters(block.Parameters)
+			c.addLED(ScheduleEntry{Kind: ScheduleLED, BlockID: block.ID, Port: params.Port,
+				Red: params.Red, Green: params.Green, Blue: params.Blue})
+
+		case BlockTypeWait:
+			seconds := waitFromParameters(block.Parameters).Duration
+			c.at += c.scaleWait(time.Duration(seconds * float64(time.Second)))
+
+		case BlockTypeSound:
+			if _, bound := bindingFromParameters(block.Parameters, "frequency"); bound {
+				return fmt.Errorf("частота блока %d привязана к датчику", block.ID)
+			}
+			params := soundFromParameters(block.Parameters)
+			duration := time.Duration(params.Duration) * time.Millisecond
+			c.add(ScheduleEntry{Kind: ScheduleSound, BlockID: block.ID, Duration: duration,
+				Port: params.Port, Frequency: params.Frequency})
+			c.at += duration
+
+		case BlockTypeLoop:
+			if loopModeFromParameters(block.Parameters) != LoopModeCount {
+				return fmt.Errorf("цикл %d повторяется по условию", block.ID)
+			}
+			body := blockBody(sequence, i)
+			count := paramInt(block.Parameters, "count", 0)
+			for iteration := 1; iteration <= count && len(body) > 0; iteration++ {
+				c.add(ScheduleEntry{Kind: ScheduleLoop, BlockID: block.ID, Iteration: iteration, Total: count})
+				if err := c.compile(body); err != nil {
+					return err
+				}
+			}
+			c.add(ScheduleEntry{Kind: ScheduleLoop, BlockID: block.ID, Total: count})
+			i += len(body)
+
+		case BlockTypeLightMusic:
+			if err := c.addLightMusic(block, true); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("блок «%s» (ID: %d) зависит от датчиков или событий", block.Title, block.ID)
+		}
+
+		if len(c.schedule.Entries) > maxScheduleEntries {
+			return fmt.Errorf("расписание длиннее %d команд", maxScheduleEntries)
+		}
+	}
+	return nil
+}
+
+// add добавляет команду в текущий момент расписания
+func (c *scheduleCompiler) add(entry ScheduleEntry) {
+	entry.At = c.at
+	c.schedule.Entries = append(c.schedule.Entries, entry)
+}
+
+// addLED добавляет цвет светодиода; цвет, выставленный в тот же момент на тот же порт,
+// сразу перекрывается, поэтому предыдущая команда заменяется
+func (c *scheduleCompiler) addLED(entry ScheduleEntry) {
+	entries := c.schedule.Entries
+	for i := len(entries) - 1; i >= 0 && entries[i].At == c.at; i-- {
+		if entries[i].Kind == ScheduleLED && entries[i].Port == entry.Port {
+			c.schedule.Entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	c.add(entry)
+}
+
+// SetCompileSchedules включает выполнение цепочек по расписанию, если их можно рассчитать заранее
+func (pm *ProgramManager) SetCompileSchedules(enabled bool) {
+	pm.compileSchedules.Store(enabled)
+}
+
+// compileChain рассчитывает расписание цепочки для выполнения или возвращает nil,
+// если цепочка выполняется блок за блоком
+func (pm *ProgramManager) compileChain(sequence []*ProgramBlock) *Schedule {
+	// При замедлении программу показывают по шагам, расписание это скрыло бы
+	if !pm.compileSchedules.Load() || pm.RunSpeed() < 1 {
+		return nil
+	}
+	schedule, err := compileSchedule(sequence, pm.scaleWait)
+	if err != nil {
+		log.Printf("Цепочка выполняется по блокам: %v", err)
+		return nil
+	}
+	log.Printf("Цепочка рассчитана: %d команд, %v", len(schedule.Entries), schedule.Length)
+	return schedule
+}
+
+// scheduledAction действие расписания в момент от начала цепочки
+type scheduledAction struct {
+	at      time.Duration
+	blockID int
+	run     func() error
+	starts  bool          // Действие начинает блок: о нем сообщается наблюдателям
+	planned time.Duration // Рассчитанная длительность начатого блока
+}
+
+// scheduleActions раскладывает расписание на отправляемые команды, включая остановку моторов и звука
+func (pm *ProgramManager) scheduleActions(schedule *Schedule) []scheduledAction {
+	var actions []scheduledAction
+	for _, entry := range schedule.Entries {
+		entry := entry
+		switch entry.Kind {
+		case ScheduleMotor:
+			actions = append(actions, scheduledAction{at: entry.At, blockID: entry.BlockID, run: func() error {
+				return pm.hubMgr.SendCommand(NewMotorCommand(entry.Port).Power(int(entry.Power)))
+			}, starts: true, planned: entry.Duration})
+			if entry.Duration > 0 {
+				actions = append(actions, scheduledAction{at: entry.At + entry.Duration, blockID: entry.BlockID, run: func() error {
+					return pm.hubMgr.SendCommand(NewMotorCommand(entry.Port).Stop())
+				}})
+			}
+		case ScheduleLED:
+			actions = append(actions, scheduledAction{at: entry.At, blockID: entry.BlockID, run: func() error {
+				return pm.hubMgr.SendCommand(NewLEDCommand(entry.Port).RGB(entry.Red, entry.Green, entry.Blue))
+			}, starts: !entry.Continues})
+		case ScheduleSound:
+			actions = append(actions, scheduledAction{at: entry.At, blockID: entry.BlockID, run: func() error {
+				return pm.hubMgr.SendCommand(NewToneCommand(entry.Port).Tone(entry.Frequency, uint16(entry.Duration/time.Millisecond)))
+			}, starts: !entry.Continues, planned: entry.Duration})
+		case ScheduleBlock:
+			actions = append(actions, scheduledAction{at: entry.At, blockID: entry.BlockID, run: func() error {
+				return nil
+			}, starts: true, planned: entry.Duration})
+		case ScheduleLoop:
+			actions = append(actions, scheduledAction{at: entry.At, blockID: entry.BlockID, run: func() error {
+				if block := pm.findBlockByID(entry.BlockID); block != nil {
+					pm.publishLoopProgress(block, entry.Iteration, entry.Total)
+				}
+				return nil
+			}})
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].at < actions[j].at })
+	return actions
+}
+
+// runSchedule выполняет расписание по часам от начала цепочки: задержки отправки
+// не накапливаются, как при выполнении блок за блоком
+func (pm *ProgramManager) runSchedule(ctx context.Context, schedule *Schedule) error {
+	// Режим светодиодов настраивается заранее, чтобы не задерживать первую команду цвета
+	configured := make(map[byte]bool)
+	for _, entry := range schedule.Entries {
+		if entry.Kind == ScheduleLED && !configured[entry.Port] {
+			configured[entry.Port] = true
+			if err := pm.hubMgr.ConfigurePort(NewInputFormatCommand(entry.Port, DEVICE_TYPE_RGB_LIGHT).Mode(LED_DISCRETE_MODE)); err != nil {
+				log.Printf("Предупреждение при установке режима светодиода: %v", err)
+			}
+		}
+	}
+
+	start := time.Now()
+	for _, action := range pm.scheduleActions(schedule) {
+		if pm.GetProgramState() != ProgramStateRunning {
+			return nil
+		}
+		if err := pm.pause(ctx, time.Until(start.Add(action.at))); err != nil {
+			log.Println("Выполнение расписания прервано остановкой программы")
+			return nil
+		}
+		if err := pm.runScheduledAction(action); err != nil {
+			return fmt.Errorf("выполнение блока %d: %v", action.blockID, err)
+		}
+	}
+
+	// Последний мотор или звук может закончиться после последней команды
+	if err := pm.pause(ctx, time.Until(start.Add(schedule.Length))); err != nil {
+		return nil
+	}
+	log.Println("Расписание цепочки выполнено")
+	return nil
+}
+
+// runScheduledAction выполняет действие расписания; о начале блока сообщается
+// наблюдателям с рассчитанной длительностью
+func (pm *ProgramManager) runScheduledAction(action scheduledAction) error {
+	block := pm.findBlockByID(action.blockID)
+	if !action.starts || block == nil {
+		return action.run()
+	}
+
+	execution := pm.beforeBlock(block)
+	err := action.run()
+	execution.Duration = action.planned
+	execution.Err = err
+	execution.Scheduled = true
+	pm.afterBlock(execution)
+	return err
+}
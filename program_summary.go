@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+const summaryFileExtension = ".txt"
+
+// summarizeProgram описывает программу обычным текстом: для каждой цепочки —
+// по строке на блок, тела циклов и условий с отступом
+func summarizeProgram(program *Program) string {
+	blocks := make(map[int]*ProgramBlock, len(program.Blocks))
+	for _, block := range program.Blocks {
+		blocks[block.ID] = block
+	}
+
+	// Цепочки начинаются так же, как при запуске: с блоков-шапок,
+	// а без блока "Начать" — с первого обычного блока
+	var starts []*ProgramBlock
+	hasStart := false
+	for _, block := range program.Blocks {
+		if isHatBlock(block.Type) {
+			starts = append(starts, block)
+			hasStart = hasStart || block.IsStart
+		}
+	}
+	if !hasStart {
+		for _, block := range program.Blocks {
+			if !isHatBlock(block.Type) {
+				starts = append([]*ProgramBlock{block}, starts...)
+				break
+			}
+		}
+	}
+
+	if len(starts) == 0 {
+		return "Программа пуста."
+	}
+
+	reached := make(map[int]bool)
+	var paragraphs []string
+	for _, start := range starts {
+		var sequence []*ProgramBlock
+		for block := start; block != nil && !reached[block.ID]; block = blocks[block.NextBlockID] {
+			reached[block.ID] = true
+			sequence = append(sequence, block)
+		}
+
+		var lines []string
+		if isHatBlock(start.Type) {
+			lines = append(lines, chainHeading(start))
+			sequence = sequence[1:]
+		} else {
+			lines = append(lines, "При запуске программы:")
+		}
+		if len(sequence) == 0 {
+			lines = append(lines, "    ничего не делать.")
+		}
+		lines = append(lines, summarizeSequence(sequence, 1, true)...)
+		paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+	}
+
+	if unreached := len(program.Blocks) - len(reached); unreached > 0 {
+		paragraphs = append(paragraphs, fmt.Sprintf("Блоков вне цепочек: %d — они не выполняются.", unreached))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// summarizeSequence описывает блоки цепочки по строке на блок; циклы и условия
+// забирают следующие за ними блоки в тело так же, как при выполнении.
+// final — последовательность заканчивает цепочку, и последняя фраза завершается точкой
+func summarizeSequence(sequence []*ProgramBlock, depth int, final bool) []string {
+	indent := strings.Repeat("    ", depth)
+	var lines []string
+	for i := 0; i < len(sequence); i++ {
+		phrase := describeBlockAction(sequence[i])
+		if i > 0 {
+			phrase = "затем " + phrase
+		} else {
+			phrase = capitalize(phrase)
+		}
+
+		container := sequence[i].Type == BlockTypeLoop || sequence[i].Type == BlockTypeCondition
+		switch {
+		case container:
+			phrase += ":"
+		case i == len(sequence)-1 && final:
+			phrase += "."
+		default:
+			phrase += ","
+		}
+		lines = append(lines, indent+phrase)
+
+		if container {
+			body := blockBody(sequence, i)
+			if len(body) == 0 {
+				lines = append(lines, indent+"    ничего не делать.")
+			}
+			lines = append(lines, summarizeSequence(body, depth+1, final && i+len(body) == len(sequence)-1)...)
+			i += len(body)
+		}
+	}
+	return lines
+}
+
+// chainHeading описывает событие, с которого начинается цепочка
+func chainHeading(block *ProgramBlock) string {
+	switch block.Type {
+	case BlockTypeReceive:
+		return fmt.Sprintf("Когда получено сообщение «%s»:", messageFromParameters(block.Parameters))
+	case BlockTypeWhenMotion:
+		return fmt.Sprintf("Когда камера замечает движение (порог %g%%):", motionThreshold(block.Parameters))
+	case BlockTypeWhenColor:
+		colorKey := paramString(block.Parameters, "color", "")
+		return fmt.Sprintf("Когда камера видит цвет «%s»:", strings.ToLower(visionColorName(colorKey)))
+	case BlockTypeWhenHear:
+		return fmt.Sprintf("Когда слышу слово «%s»:", keywordFromParameters(block.Parameters))
+	case BlockTypeWhenBumped:
+		return fmt.Sprintf("Когда %s:", strings.ToLower(whenBumpedFromParameters(block.Parameters).String()))
+	default:
+		return "При запуске программы:"
+	}
+}
+
+// describeBlockAction описывает действие блока короткой фразой, например
+// "мотор 1 вперед 50% на 2 с"
+func describeBlockAction(block *ProgramBlock) string {
+	params := block.Parameters
+	port := paramByte(params, "port", 0)
+
+	switch block.Type {
+	case BlockTypeMotor:
+		duration := paramUint16(params, "duration", 0)
+		var text string
+		if binding, ok := bindingFromParameters(params, "power"); ok {
+			text = fmt.Sprintf("мотор %d с мощностью по датчику расстояния на порту %d", port, binding.Port)
+		} else if isRandomized(block) {
+			minPower := paramInt8(params, "power_min", 0)
+			maxPower := paramInt8(params, "power_max", 0)
+			text = fmt.Sprintf("мотор %d со случайной мощностью от %d до %d%%", port, minPower, maxPower)
+		} else {
+			power := paramInt8(params, "power", 0)
+			switch {
+			case power > 0:
+				text = fmt.Sprintf("мотор %d вперед %d%%", port, power)
+			case power < 0:
+				text = fmt.Sprintf("мотор %d назад %d%%", port, -int(power))
+			default:
+				return fmt.Sprintf("остановить мотор %d", port)
+			}
+		}
+		if duration > 0 {
+			text += " на " + formatSummarySeconds(float64(duration)/1000)
+		}
+		return text
+
+	case BlockTypeLED:
+		target := fmt.Sprintf("светодиод на порту %d", port)
+		if port == hubLEDPort {
+			target = "светодиод хаба"
+		}
+		if isRandomized(block) {
+			return target + " — случайный цвет"
+		}
+		red := paramByte(params, "red", 0)
+		green := paramByte(params, "green", 0)
+		blue := paramByte(params, "blue", 0)
+		if red == 0 && green == 0 && blue == 0 {
+			return "выключить " + target
+		}
+		for _, c := range randomLEDColors {
+			if c.r == red && c.g == green && c.b == blue {
+				return target + " — " + strings.ToLower(c.name)
+			}
+		}
+		return fmt.Sprintf("%s — цвет #%02X%02X%02X", target, red, green, blue)
+
+	case BlockTypeWait:
+		if isRandomized(block) {
+			minDuration := paramFloat(params, "duration_min", 0)
+			maxDuration := paramFloat(params, "duration_max", 0)
+			return fmt.Sprintf("ждать случайное время от %g до %s", minDuration, formatSummarySeconds(maxDuration))
+		}
+		duration := paramFloat(params, "duration", 0)
+		return "ждать " + formatSummarySeconds(duration)
+
+	case BlockTypeLoop:
+		switch loopModeFromParameters(params) {
+		case LoopModeForever:
+			return "повторять бесконечно"
+		case LoopModeUntil:
+			return "повторять, пока не " + conditionFromParameters(params).String()
+		default:
+			count := paramInt(params, "count", 0)
+			return fmt.Sprintf("повторять %d раз", count)
+		}
+
+	case BlockTypeCondition:
+		return "если " + conditionFromParameters(params).String() + ", то"
+
+	case BlockTypeTiltSensor:
+		return fmt.Sprintf("включить датчик наклона на порту %d", port)
+
+	case BlockTypeDistanceSensor:
+		return fmt.Sprintf("включить датчик расстояния на порту %d", port)
+
+	case BlockTypeSound:
+		duration := paramUint16(params, "duration", 0)
+		frequency := fmt.Sprintf("%d Гц", paramUint16(params, "frequency", 0))
+		if binding, ok := bindingFromParameters(params, "frequency"); ok {
+			frequency = fmt.Sprintf("с высотой по датчику расстояния на порту %d", binding.Port)
+		}
+		return fmt.Sprintf("звук %s на порту %d на %s", frequency, port, formatSummarySeconds(float64(duration)/1000))
+
+	case BlockTypeVoltageSensor:
+		return "измерить напряжение батареи"
+
+	case BlockTypeCurrentSensor:
+		return "измерить ток"
+
+	case BlockTypeStop:
+		return "остановить программу"
+
+	case BlockTypeWaitUntil:
+		cond := conditionFromParameters(params)
+		text := "ждать, пока " + cond.String()
+		if cond.Timeout > 0 {
+			text += ", но не дольше " + formatSummarySeconds(cond.Timeout)
+		}
+		return text
+
+	case BlockTypeResetTimer:
+		return "сбросить таймер"
+
+	case BlockTypeBroadcast:
+		return fmt.Sprintf("отправить сообщение «%s»", messageFromParameters(params))
+
+	case BlockTypeScreen:
+		text := paramString(params, "text", "")
+		duration := paramFloat(params, "duration", 0)
+		phrase := fmt.Sprintf("показать на экране «%s» на %s", text, formatSummarySeconds(duration))
+		if wait := paramBool(params, "wait", false); wait {
+			phrase += " и дождаться"
+		}
+		return phrase
+
+	case BlockTypeFollow:
+		return "держать расстояние: " + followFromParameters(params).String()
+
+	case BlockTypeLightMusic:
+		return "светомузыка: " + lightMusicFromParameters(params).String()
+
+	case BlockTypeHubPower:
+		if hubPowerFromParameters(params) == HubPowerSleep {
+			return "перевести хаб в спящий режим и закончить программу"
+		}
+		return "выключить хаб и закончить программу"
+
+	default:
+		return strings.ToLower(block.Title)
+	}
+}
+
+// formatSummarySeconds записывает время в секундах без лишних нулей
+func formatSummarySeconds(seconds float64) string {
+	return fmt.Sprintf("%g с", seconds)
+}
+
+// capitalize делает первую букву фразы заглавной
+func capitalize(text string) string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return text
+	}
+	return strings.ToUpper(string(runes[:1])) + string(runes[1:])
+}
+
+// showProgramSummary показывает описание текущей программы с возможностью
+// скопировать его или сохранить в текстовый файл
+func (gui *MainGUI) showProgramSummary() {
+	program := gui.programMgr.GetProgram()
+	text := fmt.Sprintf("Программа «%s»\n\n%s\n", program.Name, summarizeProgram(program))
+
+	window := fyne.CurrentApp().NewWindow("Описание программы")
+	summary := widget.NewLabel(text)
+	summary.Wrapping = fyne.TextWrapWord
+
+	copyButton := widget.NewButton("Копировать", func() {
+		window.Clipboard().SetContent(text)
+	})
+	saveButton := widget.NewButton("Сохранить в файл...", func() {
+		d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			if _, err := writer.Write([]byte(text)); err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка сохранения описания: %v", err), window)
+				return
+			}
+			log.Printf("Описание программы сохранено: %s", writer.URI().Path())
+		}, window)
+		d.SetFileName(program.Name + summaryFileExtension)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{summaryFileExtension}))
+		d.Show()
+	})
+
+	window.SetContent(container.NewBorder(nil, container.NewHBox(copyButton, saveButton), nil, nil,
+		container.NewVScroll(summary)))
+	window.Resize(fyne.NewSize(560, 480))
+	window.Show()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// ResetTimer сбрасывает таймер программы
+func (pm *ProgramManager) ResetTimer() {
+	pm.timerMu.Lock()
+	pm.timerStart = time.Now()
+	pm.timerMu.Unlock()
+
+	log.Println("Таймер программы сброшен")
+}
+
+// TimerSeconds возвращает время в секундах с последнего сброса таймера
+func (pm *ProgramManager) TimerSeconds() float64 {
+	pm.timerMu.Lock()
+	defer pm.timerMu.Unlock()
+
+	if pm.timerStart.IsZero() {
+		return 0
+	}
+	return time.Since(pm.timerStart).Seconds()
+}
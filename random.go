@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// randomLEDColors цвета, из которых выбирается случайный цвет светодиода
+var randomLEDColors = []struct {
+	name    string
+	r, g, b byte
+}{
+	{"Красный", 255, 0, 0},
+	{"Зеленый", 0, 255, 0},
+	{"Синий", 0, 0, 255},
+	{"Белый", 255, 255, 255},
+	{"Желтый", 255, 255, 0},
+	{"Фиолетовый", 255, 0, 255},
+	{"Голубой", 0, 255, 255},
+}
+
+// maxRandomSeed наибольшее зерно, которое можно ввести в свойствах программы
+const maxRandomSeed = 999999999
+
+// resetRandom заново инициализирует генератор случайных чисел перед запуском.
+// При ненулевом зерне программы каждый запуск дает одну и ту же последовательность.
+func (pm *ProgramManager) resetRandom() {
+	seed := pm.program.RandomSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	} else {
+		log.Printf("Генератор случайных чисел инициализирован зерном %d", seed)
+	}
+
+	pm.rngMu.Lock()
+	pm.rng = rand.New(rand.NewSource(seed))
+	pm.rngMu.Unlock()
+}
+
+// randomFloat возвращает случайное число в диапазоне [a, b]
+func (pm *ProgramManager) randomFloat(a, b float64) float64 {
+	if a > b {
+		a, b = b, a
+	}
+
+	pm.rngMu.Lock()
+	defer pm.rngMu.Unlock()
+
+	if pm.rng == nil {
+		pm.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return a + pm.rng.Float64()*(b-a)
+}
+
+// randomInt возвращает случайное целое число в диапазоне [a, b]
+func (pm *ProgramManager) randomInt(a, b int) int {
+	if a > b {
+		a, b = b, a
+	}
+
+	pm.rngMu.Lock()
+	defer pm.rngMu.Unlock()
+
+	if pm.rng == nil {
+		pm.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return a + pm.rng.Intn(b-a+1)
+}
+
+// isRandomized проверяет, включены ли случайные параметры блока
+func isRandomized(block *ProgramBlock) bool {
+	return paramBool(block.Parameters, "random", false)
+}
+
+// motorPower возвращает мощность мотора с учетом привязки к датчику и случайного диапазона
+func (pm *ProgramManager) motorPower(block *ProgramBlock) int8 {
+	if value, ok := pm.boundValue(block, "power"); ok {
+		return int8(value)
+	}
+
+	params := motorFromParameters(block.Parameters)
+	if !params.Random {
+		return params.Power
+	}
+
+	power := int8(pm.randomInt(int(params.PowerMin), int(params.PowerMax)))
+	log.Printf("Случайная мощность мотора: %d%% (диапазон %d..%d)", power, params.PowerMin, params.PowerMax)
+	return power
+}
+
+// ledColor возвращает цвет светодиода с учетом случайного выбора
+func (pm *ProgramManager) ledColor(block *ProgramBlock) (byte, byte, byte) {
+	if params := ledFromParameters(block.Parameters); !params.Random {
+		return params.Red, params.Green, params.Blue
+	}
+
+	c := randomLEDColors[pm.randomInt(0, len(randomLEDColors)-1)]
+	log.Printf("Случайный цвет светодиода: %s", c.name)
+	return c.r, c.g, c.b
+}
+
+// waitDuration возвращает длительность паузы с учетом случайного диапазона
+func (pm *ProgramManager) waitDuration(block *ProgramBlock) float64 {
+	params := waitFromParameters(block.Parameters)
+	if !params.Random {
+		return params.Duration
+	}
+
+	duration := pm.randomFloat(params.DurationMin, params.DurationMax)
+	log.Printf("Случайная пауза: %.1f с (диапазон %.1f..%.1f)", duration, params.DurationMin, params.DurationMax)
+	return duration
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// readOnly сообщает, что программу нельзя изменять: она заблокирована
+// PIN-кодом учителя или открыта из файла только для запуска
+func (gui *MainGUI) readOnly() bool {
+	return gui.locked || gui.programMgr.GetProgram().RunOnly
+}
+
+// runOnlyCopy возвращает копию программы с отметкой «только для запуска»
+func runOnlyCopy(program *Program) *Program {
+	bundle := *program
+	bundle.RunOnly = true
+	return &bundle
+}
+
+// showRunOnlyExportDialog сохраняет программу в файл, который другие копии
+// WeDoProg откроют только для запуска. Текущая программа остается редактируемой
+func (gui *MainGUI) showRunOnlyExportDialog() {
+	program := gui.programMgr.GetProgram()
+	if len(program.Blocks) == 0 {
+		dialog.ShowInformation("Экспорт для запуска", "В программе нет блоков", gui.window)
+		return
+	}
+
+	data, err := EncodeProgram(runOnlyCopy(program))
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("ошибка экспорта программы: %v", err), gui.window)
+		return
+	}
+
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, gui.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write(data); err != nil {
+			dialog.ShowError(fmt.Errorf("ошибка экспорта программы: %v", err), gui.window)
+			return
+		}
+		log.Printf("Программа экспортирована для запуска: %s", writer.URI().Path())
+	}, gui.window)
+	d.SetFileName(program.Name + " (запуск)" + programFileExtension)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{programFileExtension}))
+	d.Show()
+}
+
+// createRunOnlyNotice объясняет в свойствах программы, что ее можно только
+// запускать, и перечисляет устройства, которые нужно подключить к хабу
+func (gui *MainGUI) createRunOnlyNotice(program *Program) fyne.CanvasObject {
+	lines := []string{"Программа только для запуска: изменять ее нельзя."}
+	if devices := programRequiredDevices(program); len(devices) > 0 {
+		lines = append(lines, "Подключите к хабу:")
+		for _, device := range devices {
+			lines = append(lines, fmt.Sprintf("• порт %d — %s", device.port, DeviceTypeName(device.deviceType)))
+		}
+	}
+
+	notice := widget.NewLabel(strings.Join(lines, "\n"))
+	notice.Wrapping = fyne.TextWrapWord
+	return notice
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	settingRunSpeed     = "run_speed"
+	minRunSpeed         = 0.25
+	maxRunSpeed         = 2.0
+	blockStepDelay      = 10 * time.Millisecond  // Пауза между блоками при обычной скорости
+	slowMotionStepDelay = 500 * time.Millisecond // Добавочная пауза между блоками при скорости 0.5x
+)
+
+// SetRunSpeed задает скорость выполнения: паузы делятся на нее,
+// а при замедлении между блоками вставляются дополнительные паузы
+func (pm *ProgramManager) SetRunSpeed(speed float64) {
+	speed = clamp(speed, minRunSpeed, maxRunSpeed)
+
+	pm.speedMu.Lock()
+	pm.runSpeed = speed
+	pm.speedMu.Unlock()
+
+	log.Printf("Скорость выполнения: %gx", speed)
+}
+
+// RunSpeed возвращает скорость выполнения программы
+func (pm *ProgramManager) RunSpeed() float64 {
+	pm.speedMu.Lock()
+	defer pm.speedMu.Unlock()
+	return pm.runSpeed
+}
+
+// scaleWait пересчитывает паузу блока под скорость выполнения
+func (pm *ProgramManager) scaleWait(duration time.Duration) time.Duration {
+	return time.Duration(float64(duration) / pm.RunSpeed())
+}
+
+// stepDelay возвращает паузу перед следующим блоком цепочки
+func (pm *ProgramManager) stepDelay(next *ProgramBlock) time.Duration {
+	var delay time.Duration
+	if next.Type != BlockTypeWait {
+		delay = blockStepDelay
+	}
+	if speed := pm.RunSpeed(); speed < 1 {
+		// 0.5x — полсекунды между блоками, 0.25x — полторы
+		delay += time.Duration(float64(slowMotionStepDelay) * (1/speed - 1))
+	}
+	return delay
+}
+
+// pause ждет заданное время, прерываясь при остановке программы
+func (pm *ProgramManager) pause(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	tinybluetooth "tinygo.org/x/bluetooth"
+)
+
+const (
+	legoManufacturerID   = 0x0397 // Идентификатор LEGO в данных производителя
+	lwp3HubServiceUUID   = "00001623-1212-efde-1623-785feabcd123"
+	settingPairingOnly   = "pairing_only" // Показывать только хабы в режиме подключения
+	scanRSSIHistoryLimit = 30             // Сколько последних замеров RSSI хранить для каждого хаба
+	sparklineMinRSSI     = -100           // Нижняя граница шкалы графика сигнала, дБм
+	sparklineMaxRSSI     = -30            // Верхняя граница шкалы графика сигнала, дБм
+)
+
+// advertisedServiceNames названия служб, которые объявляют хабы LEGO
+var advertisedServiceNames = map[string]string{
+	LPF2_HUB_SERVICE_UUID:       "Хаб LPF2 (WeDo 2.0)",
+	LPF2_EXTENDED_SERVICE_UUID:  "Расширенная служба LPF2",
+	WEDO2_SPECIFIC_SERVICE_UUID: "Служба WeDo 2.0",
+	DEVICE_INFO_SERVICE_UUID:    "Сведения об устройстве",
+	BATTERY_SERVICE_UUID:        "Батарея",
+	lwp3HubServiceUUID:          "Хаб Powered Up (LWP3)",
+}
+
+// lwp3HubTypes типы хабов по байту системного типа в данных производителя
+var lwp3HubTypes = map[byte]string{
+	0x00: "WeDo 2.0",
+	0x20: "Duplo Train",
+	0x40: "Boost Move Hub",
+	0x41: "City Hub",
+	0x42: "Пульт Powered Up",
+	0x80: "Technic Hub",
+}
+
+// recordAdvertisement дополняет сведения о хабе данными очередного объявления
+func recordAdvertisement(hub *HubInfo, result tinybluetooth.ScanResult) {
+	if name := result.LocalName(); name != "" {
+		hub.Name = name
+	}
+	hub.RSSI = int(result.RSSI)
+	hub.RSSIHistory = append(hub.RSSIHistory, hub.RSSI)
+	if n := len(hub.RSSIHistory); n > scanRSSIHistoryLimit {
+		hub.RSSIHistory = hub.RSSIHistory[n-scanRSSIHistoryLimit:]
+	}
+
+	if uuids := result.ServiceUUIDs(); len(uuids) > 0 {
+		hub.Services = hub.Services[:0]
+		for _, uuid := range uuids {
+			hub.Services = append(hub.Services, uuid.String())
+		}
+	}
+	for _, element := range result.ManufacturerData() {
+		if element.CompanyID == legoManufacturerID {
+			hub.ManufacturerData = append([]byte(nil), element.Data...)
+		}
+	}
+	// Кнопку держат недолго, поэтому режим подключения запоминается до конца поиска
+	if pressed, _ := advertisedButtonState(hub.ManufacturerData); pressed {
+		hub.Pairing = true
+	}
+}
+
+// advertisedButtonState состояние кнопки хаба из данных производителя
+func advertisedButtonState(data []byte) (pressed, known bool) {
+	if len(data) == 0 {
+		return false, false
+	}
+	return data[0] != 0, true
+}
+
+// guessHubType угадывает тип хаба по службам, данным производителя и имени
+func guessHubType(hub HubInfo) string {
+	for _, uuid := range hub.Services {
+		switch uuid {
+		case LPF2_HUB_SERVICE_UUID, WEDO2_SPECIFIC_SERVICE_UUID:
+			return "WeDo 2.0"
+		case lwp3HubServiceUUID:
+			if len(hub.ManufacturerData) > 1 {
+				if name, ok := lwp3HubTypes[hub.ManufacturerData[1]]; ok {
+					return name
+				}
+			}
+			return "Хаб Powered Up"
+		}
+	}
+
+	name := strings.ToUpper(hub.Name)
+	switch {
+	case strings.Contains(name, "LPF2"), strings.Contains(name, "WEDO"):
+		return "WeDo 2.0 (по имени)"
+	case strings.HasPrefix(hub.Address, "24:71:89:"):
+		return "Хаб LEGO (по адресу)"
+	}
+	return "Неизвестно"
+}
+
+// serviceDisplayName название службы для списка или сам UUID, если служба незнакома
+func serviceDisplayName(uuid string) string {
+	if name, ok := advertisedServiceNames[uuid]; ok {
+		return name
+	}
+	return uuid
+}
+
+// newRSSISparkline рисует график уровня сигнала по истории замеров
+func newRSSISparkline(history []int) fyne.CanvasObject {
+	size := fyne.NewSize(160, 32)
+	plot := container.NewWithoutLayout()
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	background.Resize(size)
+	plot.Add(background)
+
+	point := func(i, rssi int) fyne.Position {
+		level := float32(clamp(float64(rssi), sparklineMinRSSI, sparklineMaxRSSI)-sparklineMinRSSI) /
+			(sparklineMaxRSSI - sparklineMinRSSI)
+		x := float32(0)
+		if len(history) > 1 {
+			x = size.Width * float32(i) / float32(len(history)-1)
+		}
+		return fyne.NewPos(x, size.Height*(1-level))
+	}
+	for i := 1; i < len(history); i++ {
+		line := canvas.NewLine(theme.Color(theme.ColorNamePrimary))
+		line.StrokeWidth = 1.5
+		line.Position1 = point(i-1, history[i-1])
+		line.Position2 = point(i, history[i])
+		plot.Add(line)
+	}
+	return container.NewGridWrap(size, plot)
+}
+
+// newScanResultDetails подробности найденного хаба для раскрывающегося списка поиска
+func (gui *MainGUI) newScanResultDetails(hub HubInfo, connect func()) fyne.CanvasObject {
+	address := hub.Address
+	services := "не объявлены"
+	if len(hub.Services) > 0 {
+		names := make([]string, len(hub.Services))
+		for i, uuid := range hub.Services {
+			names[i] = serviceDisplayName(uuid)
+		}
+		services = strings.Join(names, "\n")
+	}
+
+	button := "неизвестно"
+	if pressed, known := advertisedButtonState(hub.ManufacturerData); known {
+		button = "не нажата"
+		if pressed {
+			button = "нажата"
+		}
+	}
+
+	manufacturer := "нет"
+	if len(hub.ManufacturerData) > 0 {
+		manufacturer = fmt.Sprintf("% x", hub.ManufacturerData)
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("Тип", widget.NewLabel(guessHubType(hub))),
+		widget.NewFormItem("Сигнал", container.NewHBox(
+			newRSSISparkline(hub.RSSIHistory),
+			widget.NewLabel(fmt.Sprintf("%d дБм", hub.RSSI)),
+		)),
+		widget.NewFormItem("Службы", widget.NewLabel(services)),
+		widget.NewFormItem("Кнопка", widget.NewLabel(button)),
+		widget.NewFormItem("Данные LEGO", widget.NewLabelWithStyle(manufacturer, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})),
+	)
+
+	connectButton := widget.NewButtonWithIcon("Подключить", theme.LoginIcon(), connect)
+	connectButton.Importance = widget.HighImportance
+	// Хаб мигнет фиолетовым, чтобы было видно, какой из них в списке
+	identifyButton := widget.NewButtonWithIcon("Идентифицировать", theme.VisibilityIcon(), func() {
+		gui.identifyHub(address)
+	})
+	return container.NewVBox(form, container.NewHBox(connectButton, identifyButton))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showScreenMessage показывает крупное сообщение поверх окна на заданное время
+func (gui *MainGUI) showScreenMessage(text string, duration time.Duration) {
+	fyne.Do(func() {
+		// Новое сообщение заменяет предыдущее
+		if gui.screenPopUp != nil {
+			gui.screenPopUp.Hide()
+		}
+
+		canvasSize := gui.window.Canvas().Size()
+
+		message := canvas.NewText(text, color.White)
+		message.TextSize = 96
+		message.TextStyle.Bold = true
+		message.Alignment = fyne.TextAlignCenter
+
+		bg := canvas.NewRectangle(color.NRGBA{R: 0, G: 0, B: 0, A: 200})
+		bg.CornerRadius = 16
+
+		popUpSize := fyne.NewSize(canvasSize.Width*0.8, canvasSize.Height*0.4)
+		content := container.NewStack(bg, container.NewCenter(message))
+
+		popUp := widget.NewPopUp(content, gui.window.Canvas())
+		popUp.Resize(popUpSize)
+		popUp.ShowAtPosition(fyne.NewPos(
+			(canvasSize.Width-popUpSize.Width)/2,
+			(canvasSize.Height-popUpSize.Height)/2,
+		))
+		gui.screenPopUp = popUp
+
+		go func() {
+			time.Sleep(duration)
+			fyne.Do(func() {
+				// Скрываем, только если сообщение не было заменено
+				if gui.screenPopUp == popUp {
+					popUp.Hide()
+					gui.screenPopUp = nil
+				}
+			})
+		}()
+	})
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	sensorRecordingExtension = ".wedosensors" // Расширение файлов записи датчиков
+	sensorRecordingVersion   = 1              // Версия формата записи
+)
+
+// SensorSample значение датчика в записи
+type SensorSample struct {
+	At    int64   `json:"t"` // Миллисекунды от начала записи
+	Port  byte    `json:"port"`
+	Value float64 `json:"value"`
+}
+
+// SensorRecording запись значений датчиков хаба. Программу можно запустить на
+// записи вместо живых датчиков, чтобы проверять условия на одних и тех же данных
+type SensorRecording struct {
+	Version  int              `json:"version"`
+	Hub      string           `json:"hub,omitempty"`
+	Recorded time.Time        `json:"recorded"`
+	Devices  []DocumentDevice `json:"devices"` // Устройства, подключенные во время записи
+	Samples  []SensorSample   `json:"samples"`
+}
+
+// Duration возвращает длительность записи
+func (r *SensorRecording) Duration() time.Duration {
+	if len(r.Samples) == 0 {
+		return 0
+	}
+	return time.Duration(r.Samples[len(r.Samples)-1].At) * time.Millisecond
+}
+
+// hasDevice сообщает, что во время записи на порту было устройство этого типа
+func (r *SensorRecording) hasDevice(port, deviceType byte) bool {
+	for _, device := range r.Devices {
+		if device.Port == port && device.Device == deviceType {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeSensorRecording сериализует запись датчиков
+func EncodeSensorRecording(recording *SensorRecording) ([]byte, error) {
+	return json.MarshalIndent(recording, "", "  ")
+}
+
+// DecodeSensorRecording читает запись датчиков и проверяет ее
+func DecodeSensorRecording(data []byte) (*SensorRecording, error) {
+	var recording SensorRecording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("файл не является записью датчиков: %v", err)
+	}
+	if recording.Version < 1 || recording.Version > sensorRecordingVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия записи датчиков: %d", recording.Version)
+	}
+	for _, device := range recording.Devices {
+		if err := validatePort(device.Port); err != nil {
+			return nil, err
+		}
+	}
+	for i, sample := range recording.Samples {
+		if err := validatePort(sample.Port); err != nil {
+			return nil, err
+		}
+		if sample.At < 0 || (i > 0 && sample.At < recording.Samples[i-1].At) {
+			return nil, fmt.Errorf("значения записи датчиков идут не по порядку времени")
+		}
+	}
+	return &recording, nil
+}
+
+// SensorRecorder записывает значения датчиков, которые приходят от хаба
+type SensorRecorder struct {
+	mu          sync.Mutex
+	recording   *SensorRecording
+	started     time.Time
+	unsubscribe func()
+}
+
+// StartSensorRecording начинает запись значений датчиков подключенного хаба
+func (hm *HubManager) StartSensorRecording() error {
+	if !hm.IsConnected() {
+		return fmt.Errorf("не подключено к хабу")
+	}
+	if hm.Replaying() {
+		return fmt.Errorf("во время воспроизведения записи датчики не записываются")
+	}
+
+	r := hm.recorder
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recording != nil {
+		return fmt.Errorf("запись датчиков уже идет")
+	}
+
+	r.recording = &SensorRecording{
+		Version:  sensorRecordingVersion,
+		Hub:      hm.GetHubInfo().Name,
+		Recorded: time.Now(),
+	}
+	for _, device := range hm.devices.GetConnectedDevices() {
+		r.recording.Devices = append(r.recording.Devices, DocumentDevice{Port: device.PortID, Device: device.DeviceType})
+	}
+	sort.Slice(r.recording.Devices, func(i, j int) bool { return r.recording.Devices[i].Port < r.recording.Devices[j].Port })
+	r.started = time.Now()
+	r.unsubscribe = hm.events.Subscribe(EventSensorValue, func(event Event) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.recording != nil {
+			r.recording.Samples = append(r.recording.Samples, SensorSample{
+				At:    time.Since(r.started).Milliseconds(),
+				Port:  event.PortID,
+				Value: event.Value,
+			})
+		}
+	})
+	log.Println("Запись датчиков начата")
+	return nil
+}
+
+// StopSensorRecording останавливает запись и возвращает ее; nil, если запись не шла
+func (hm *HubManager) StopSensorRecording() *SensorRecording {
+	r := hm.recorder
+	r.mu.Lock()
+	recording, unsubscribe := r.recording, r.unsubscribe
+	r.recording, r.unsubscribe = nil, nil
+	r.mu.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+	if recording != nil {
+		log.Printf("Запись датчиков остановлена: %d значений за %.1f с", len(recording.Samples), recording.Duration().Seconds())
+	}
+	return recording
+}
+
+// SensorRecordingActive сообщает, что идет запись датчиков
+func (hm *HubManager) SensorRecordingActive() bool {
+	hm.recorder.mu.Lock()
+	defer hm.recorder.mu.Unlock()
+	return hm.recorder.recording != nil
+}
+
+// SensorReplay воспроизведение записи вместо живых датчиков хаба
+type SensorReplay struct {
+	recording *SensorRecording
+	added     []byte // Порты устройств, которых не было на хабе и которые добавлены из записи
+}
+
+// Replaying сообщает, что значения датчиков берутся из записи
+func (hm *HubManager) Replaying() bool {
+	return hm.replay.Load() != nil
+}
+
+// Ready сообщает, что программа может выполняться: хаб подключен
+// или датчики воспроизводятся из записи
+func (hm *HubManager) Ready() bool {
+	return hm.IsConnected() || hm.Replaying()
+}
+
+// replayingOffline сообщает, что запись воспроизводится без хаба:
+// команды выходам только записываются в журнал
+func (hm *HubManager) replayingOffline() bool {
+	return hm.Replaying() && !hm.IsConnected()
+}
+
+// StartReplay подменяет живые датчики записью. Устройства записи, которых нет
+// на хабе, добавляются на время воспроизведения, чтобы блоки нашли их на портах
+func (hm *HubManager) StartReplay(recording *SensorRecording) error {
+	if hm.SensorRecordingActive() {
+		return fmt.Errorf("остановите запись датчиков, чтобы запустить программу на записи")
+	}
+	if hm.Replaying() {
+		return fmt.Errorf("запись датчиков уже воспроизводится")
+	}
+
+	replay := &SensorReplay{recording: recording}
+	for _, device := range recording.Devices {
+		if hm.devices.IsDeviceConnected(device.Port, device.Device) {
+			continue
+		}
+		hm.devices.AddOrUpdateDevice(&Device{
+			PortID:      device.Port,
+			DeviceType:  device.Device,
+			Name:        DeviceTypeName(device.Device),
+			IsConnected: true,
+			LastUpdate:  time.Now(),
+			Properties:  make(map[string]interface{}),
+		})
+		replay.added = append(replay.added, device.Port)
+	}
+	hm.replay.Store(replay)
+	log.Printf("Воспроизведение записи датчиков: %d значений за %.1f с", len(recording.Samples), recording.Duration().Seconds())
+	return nil
+}
+
+// PlayReplay подает значения записи с исходными промежутками, пересчитанными
+// scale под скорость выполнения. После конца записи датчики сохраняют последние значения
+func (hm *HubManager) PlayReplay(ctx context.Context, scale func(time.Duration) time.Duration) {
+	replay := hm.replay.Load()
+	if replay == nil {
+		return
+	}
+
+	started := time.Now()
+	for _, sample := range replay.recording.Samples {
+		wait := time.Until(started.Add(scale(time.Duration(sample.At) * time.Millisecond)))
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+		if ctx.Err() != nil || hm.replay.Load() != replay {
+			return
+		}
+		hm.applySensorReading(sample.Port, sample.Value)
+	}
+	log.Println("Запись датчиков закончилась, датчики сохраняют последние значения")
+}
+
+// StopReplay возвращает живые датчики и убирает добавленные из записи устройства
+func (hm *HubManager) StopReplay() {
+	replay := hm.replay.Swap(nil)
+	if replay == nil {
+		return
+	}
+
+	hm.sensorMu.Lock()
+	for _, device := range replay.recording.Devices {
+		delete(hm.sensorValues, device.Port)
+		delete(hm.tiltAngles, device.Port)
+	}
+	hm.sensorMu.Unlock()
+	for _, device := range replay.recording.Devices {
+		hm.objects.Forget(device.Port)
+	}
+
+	for _, port := range replay.added {
+		hm.devices.MarkDisconnected(port)
+	}
+	if !hm.IsConnected() {
+		hm.devices.Reset()
+	}
+	log.Println("Воспроизведение записи датчиков остановлено")
+}
+
+// SetSensorReplay задает запись, на которой выполняется программа; nil — живые датчики
+func (pm *ProgramManager) SetSensorReplay(recording *SensorRecording) {
+	pm.sensorReplay = recording
+}
+
+// SensorReplay возвращает запись, на которой выполняется программа
+func (pm *ProgramManager) SensorReplay() *SensorRecording {
+	return pm.sensorReplay
+}
+
+// sensorRecordingSummary описывает запись для интерфейса
+func sensorRecordingSummary(name string, recording *SensorRecording) string {
+	ports := make([]string, 0, len(recording.Devices))
+	for _, device := range recording.Devices {
+		ports = append(ports, fmt.Sprintf("порт %d — %s", device.Port, DeviceTypeName(device.Device)))
+	}
+	summary := fmt.Sprintf("%s: %d значений за %.1f с", name, len(recording.Samples), recording.Duration().Seconds())
+	if len(ports) > 0 {
+		summary += "\n" + strings.Join(ports, ", ")
+	}
+	return summary
+}
+
+// writeSensorRecordingCSV выгружает запись датчиков в CSV. Значения в тех же
+// единицах, что и в интерфейсе, но всегда с точкой: файл одинаково читается в любой локали
+func writeSensorRecordingCSV(writer *csv.Writer, recording *SensorRecording, units *UnitFormatter) error {
+	devices := make(map[byte]byte, len(recording.Devices))
+	for _, device := range recording.Devices {
+		devices[device.Port] = device.Device
+	}
+
+	if err := writer.Write([]string{"Хаб", "Время, с", "Порт", "Датчик", "Значение", "Единица"}); err != nil {
+		return err
+	}
+	for _, sample := range recording.Samples {
+		deviceType := devices[sample.Port]
+		record := []string{
+			recording.Hub,
+			strconv.FormatFloat(float64(sample.At)/1000, 'f', 3, 64),
+			strconv.Itoa(int(sample.Port)),
+			DeviceTypeName(deviceType),
+			units.CSVValue(deviceType, sample.Value),
+			units.Unit(deviceType),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// showSensorRecordingDialog записывает датчики, сохраняет и открывает записи
+// и включает выполнение программы на записи вместо живых датчиков
+func (gui *MainGUI) showSensorRecordingDialog() {
+	info := widget.NewLabel("")
+	info.Wrapping = fyne.TextWrapWord
+
+	var recordButton, saveButton, exportButton *widget.Button
+	var replayCheck *widget.Check
+	refresh := func() {
+		switch {
+		case gui.hubMgr.SensorRecordingActive():
+			info.SetText("Идет запись датчиков. Двигайте модель и меняйте условия, затем остановите запись.")
+			recordButton.SetText("Остановить запись")
+		case gui.sensorRecording != nil:
+			info.SetText(sensorRecordingSummary(gui.sensorRecordingName, gui.sensorRecording))
+			recordButton.SetText("Записать заново")
+		default:
+			info.SetText("Запишите значения датчиков хаба или откройте сохраненную запись.")
+			recordButton.SetText("Начать запись")
+		}
+		if gui.sensorRecording != nil && !gui.hubMgr.SensorRecordingActive() {
+			saveButton.Enable()
+			exportButton.Enable()
+			replayCheck.Enable()
+		} else {
+			saveButton.Disable()
+			exportButton.Disable()
+			replayCheck.Disable()
+		}
+		replayCheck.SetChecked(gui.programMgr.SensorReplay() != nil)
+	}
+
+	useRecording := func(name string, recording *SensorRecording) {
+		gui.sensorRecording, gui.sensorRecordingName = recording, name
+		if gui.programMgr.SensorReplay() != nil {
+			gui.programMgr.SetSensorReplay(recording)
+		}
+	}
+
+	recordButton = widget.NewButton("", func() {
+		if gui.hubMgr.SensorRecordingActive() {
+			if recording := gui.hubMgr.StopSensorRecording(); recording != nil {
+				useRecording("Новая запись", recording)
+			}
+		} else if err := gui.hubMgr.StartSensorRecording(); err != nil {
+			dialog.ShowError(err, gui.window)
+		}
+		refresh()
+	})
+
+	saveButton = widget.NewButton("Сохранить...", func() {
+		data, err := EncodeSensorRecording(gui.sensorRecording)
+		if err != nil {
+			dialog.ShowError(err, gui.window)
+			return
+		}
+		d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			if _, err := writer.Write(data); err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка сохранения записи: %v", err), gui.window)
+				return
+			}
+			gui.sensorRecordingName = writer.URI().Name()
+			refresh()
+		}, gui.window)
+		d.SetFileName("Датчики" + sensorRecordingExtension)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{sensorRecordingExtension}))
+		d.Show()
+	})
+
+	exportButton = widget.NewButton("В CSV...", func() {
+		recording := gui.sensorRecording
+		d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			if err := writeSensorRecordingCSV(csv.NewWriter(writer), recording, gui.units); err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка выгрузки записи: %v", err), gui.window)
+				return
+			}
+			log.Printf("Запись датчиков выгружена: %s", writer.URI().Path())
+		}, gui.window)
+		d.SetFileName("Датчики" + csvFileExtension)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{csvFileExtension}))
+		d.Show()
+	})
+
+	openButton := widget.NewButton("Открыть...", func() {
+		d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), gui.window)
+				return
+			}
+			recording, err := DecodeSensorRecording(data)
+			if err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			useRecording(reader.URI().Name(), recording)
+			refresh()
+		}, gui.window)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{sensorRecordingExtension}))
+		d.Show()
+	})
+
+	replayCheck = widget.NewCheck("Запускать программу на записи вместо датчиков хаба", func(checked bool) {
+		if checked == (gui.programMgr.SensorReplay() != nil) {
+			return
+		}
+		if gui.programMgr.GetProgramState() == ProgramStateRunning {
+			dialog.ShowInformation("Запись датчиков", "Остановите программу, чтобы сменить источник датчиков", gui.window)
+			refresh()
+			return
+		}
+		if checked {
+			gui.programMgr.SetSensorReplay(gui.sensorRecording)
+		} else {
+			gui.programMgr.SetSensorReplay(nil)
+		}
+		gui.updateToolbarState(gui.hubMgr.IsConnected(), len(gui.programMgr.GetProgram().Blocks) > 0)
+	})
+
+	hint := widget.NewLabel("На записи программа получает те же значения датчиков при каждом запуске. " +
+		"Без подключенного хаба команды моторам, светодиоду и пищалке только пишутся в журнал.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	refresh()
+	content := container.NewVBox(info, container.NewGridWithColumns(4, recordButton, saveButton, exportButton, openButton), replayCheck, hint)
+	d := dialog.NewCustom("Запись датчиков", "Закрыть", content, gui.window)
+	d.Resize(fyne.NewSize(520, 280))
+	d.Show()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestSensorRecordingCSV(t *testing.T) {
+	recording := &SensorRecording{
+		Hub:     `Хаб "Ромашка", 3Б`,
+		Devices: []DocumentDevice{{Port: 1, Device: DEVICE_TYPE_MOTION_SENSOR}, {Port: 2, Device: DEVICE_TYPE_TILT_SENSOR}},
+		Samples: []SensorSample{{At: 0, Port: 1, Value: 25.4}, {At: 1500, Port: 2, Value: 3}},
+	}
+	units := &UnitFormatter{DistanceUnit: DistanceUnitInch, DecimalComma: true}
+
+	var out bytes.Buffer
+	if err := writeSensorRecordingCSV(csv.NewWriter(&out), recording, units); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("выгрузка не читается как CSV: %v\n%s", err, out.String())
+	}
+	want := [][]string{
+		{"Хаб", "Время, с", "Порт", "Датчик", "Значение", "Единица"},
+		{recording.Hub, "0.000", "1", DeviceTypeName(DEVICE_TYPE_MOTION_SENSOR), "10.0", "дюйм"},
+		{recording.Hub, "1.500", "2", DeviceTypeName(DEVICE_TYPE_TILT_SENSOR), "3", "°"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("выгружено %q, ожидалось %q", records, want)
+	}
+}
+
+func TestOfflineReplayKeepsCommandsInSession(t *testing.T) {
+	hm := newHubManager(nil, "")
+	recording := &SensorRecording{Devices: []DocumentDevice{{Port: 1, Device: DEVICE_TYPE_PIEZO_TONE}}}
+	if err := hm.StartReplay(recording); err != nil {
+		t.Fatal(err)
+	}
+	defer hm.StopReplay()
+
+	tone := NewToneCommand(1).Tone(523, 200)
+	data, err := tone.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hm.Trace().StartCapture()
+	if err := hm.SendCommand(tone); err != nil {
+		t.Fatalf("SendCommand без хаба: %v", err)
+	}
+	frames := hm.Trace().StopCapture()
+	if len(frames) != 1 || frames[0].direction != FrameOut || !bytes.Equal(frames[0].data, data) {
+		t.Fatalf("в записи сеанса кадры %v, ожидалась нота % x", frames, data)
+	}
+
+	// Вне записи сеанса неотправленная команда не попадает и в журнал BLE
+	if err := hm.SendCommand(tone); err != nil {
+		t.Fatal(err)
+	}
+	if frames := hm.Trace().ordered(); len(frames) != 0 {
+		t.Errorf("в журнале BLE неотправленные кадры %v", frames)
+	}
+}
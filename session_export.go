@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/software"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Сеанс выгружается в анимацию GIF или видео MP4: холст программы с
+// подсвеченными блоками, графики датчиков и подпись с временем и текущими
+// блоками рисуются вне экрана, кадр за кадром. GIF собирается самим
+// приложением, для MP4 кадры передаются программе ffmpeg
+
+const (
+	sessionVideoFPS          = 10  // Кадров в секунду
+	sessionVideoMaxFrames    = 900 // Больше кадров не делается: длинный сеанс ускоряется
+	sessionVideoWidth        = 800
+	sessionVideoCanvasHeight = 380
+	sessionVideoCaption      = 56 // Высота подписи над холстом
+	sessionVideoChartHeight  = 56
+	sessionVideoMaxCharts    = 3 // Сколько графиков датчиков помещается под холстом
+	sessionVideoPadding      = 12
+
+	gifExtension = ".gif"
+	mp4Extension = ".mp4"
+)
+
+// sessionSceneBlock блок программы на кадре видео
+type sessionSceneBlock struct {
+	rect  *canvas.Rectangle
+	title string
+}
+
+// sessionScene кадр видео сеанса: объекты холста, которые меняются от момента к моменту
+type sessionScene struct {
+	session *SessionRecording
+	size    fyne.Size
+	content *fyne.Container
+	blocks  map[int]sessionSceneBlock
+	plots   []*sessionPlot
+	values  []*canvas.Text
+	samples [][]SensorSample
+	formats []func(float64) string
+	time    *canvas.Text
+	steps   *canvas.Text
+}
+
+// newSessionScene собирает кадр из текущей программы на холсте и графиков
+// датчиков сеанса. Вызывается в потоке интерфейса: читает стили блоков
+func (gui *MainGUI) newSessionScene(session *SessionRecording) *sessionScene {
+	s := &sessionScene{session: session, content: container.NewWithoutLayout(), blocks: make(map[int]sessionSceneBlock)}
+	foreground := theme.Color(theme.ColorNameForeground)
+
+	var chartPorts []DocumentDevice
+	for _, device := range session.Devices {
+		if len(chartPorts) < sessionVideoMaxCharts && len(session.portSamples(device.Port)) > 0 {
+			chartPorts = append(chartPorts, device)
+		}
+	}
+	chartsTop := sessionVideoCaption + sessionVideoCanvasHeight + sessionVideoPadding
+	s.size = fyne.NewSize(sessionVideoWidth,
+		float32(chartsTop+len(chartPorts)*(sessionVideoChartHeight+sessionVideoPadding+18)+sessionVideoPadding))
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameBackground))
+	background.Resize(s.size)
+	s.content.Add(background)
+
+	title := canvas.NewText(fmt.Sprintf("«%s» — %s", session.Program, session.Recorded.Format("02.01.2006 15:04")), foreground)
+	title.TextStyle.Bold = true
+	title.TextSize = 16
+	title.Move(fyne.NewPos(sessionVideoPadding, 6))
+	s.content.Add(title)
+	s.time = canvas.NewText("", foreground)
+	s.time.TextStyle.Monospace = true
+	s.time.TextSize = 16
+	s.content.Add(s.time)
+	s.steps = canvas.NewText("", theme.Color(theme.ColorNamePrimary))
+	s.steps.TextSize = 14
+	s.steps.Move(fyne.NewPos(sessionVideoPadding, 30))
+	s.content.Add(s.steps)
+
+	s.addProgram(gui, fyne.NewPos(sessionVideoPadding, sessionVideoCaption),
+		fyne.NewSize(sessionVideoWidth-2*sessionVideoPadding, sessionVideoCanvasHeight))
+
+	y := float32(chartsTop)
+	plotSize := fyne.NewSize(sessionVideoWidth-2*sessionVideoPadding-120, sessionVideoChartHeight)
+	for _, device := range chartPorts {
+		label := canvas.NewText(fmt.Sprintf("Порт %d — %s", device.Port, DeviceTypeName(device.Device)), foreground)
+		label.TextSize = 12
+		label.Move(fyne.NewPos(sessionVideoPadding, y))
+		s.content.Add(label)
+		y += 18
+
+		samples := session.portSamples(device.Port)
+		plot := newSessionPlot(samples, session.Length, plotSize)
+		plot.object.Move(fyne.NewPos(sessionVideoPadding, y))
+		plot.object.Resize(plotSize)
+		s.content.Add(plot.object)
+
+		value := canvas.NewText("—", foreground)
+		value.TextStyle.Bold = true
+		value.TextSize = 16
+		value.Move(fyne.NewPos(sessionVideoPadding+plotSize.Width+12, y+sessionVideoChartHeight/2-10))
+		s.content.Add(value)
+
+		deviceType := device.Device
+		s.plots = append(s.plots, plot)
+		s.values = append(s.values, value)
+		s.samples = append(s.samples, samples)
+		s.formats = append(s.formats, func(value float64) string { return gui.units.Format(deviceType, value) })
+		y += sessionVideoChartHeight + sessionVideoPadding
+	}
+	return s
+}
+
+// addProgram рисует блоки и соединения программы, вписанные в область кадра
+func (s *sessionScene) addProgram(gui *MainGUI, origin fyne.Position, area fyne.Size) {
+	program := gui.programMgr.GetProgram()
+	if len(program.Blocks) == 0 {
+		return
+	}
+
+	left, top := program.Blocks[0].X, program.Blocks[0].Y
+	right, bottom := left, top
+	for _, block := range program.Blocks {
+		left, top = math.Min(left, block.X), math.Min(top, block.Y)
+		right, bottom = math.Max(right, block.X+block.Width), math.Max(bottom, block.Y+block.Height)
+	}
+	scale := math.Min(1, math.Min(float64(area.Width)/(right-left), float64(area.Height)/(bottom-top)))
+	offset := fyne.NewPos(origin.X+(area.Width-float32((right-left)*scale))/2, origin.Y)
+	place := func(x, y float64) fyne.Position {
+		return fyne.NewPos(offset.X+float32((x-left)*scale), offset.Y+float32((y-top)*scale))
+	}
+
+	for _, conn := range program.Connections {
+		from, to := gui.programMgr.findBlockByID(conn.FromBlockID), gui.programMgr.findBlockByID(conn.ToBlockID)
+		if from == nil || to == nil {
+			continue
+		}
+		line := canvas.NewLine(theme.Color(theme.ColorNameDisabled))
+		line.StrokeWidth = 2
+		line.Position1 = place(from.X+from.Width/2, from.Y+from.Height)
+		line.Position2 = place(to.X+to.Width/2, to.Y)
+		s.content.Add(line)
+	}
+
+	for _, block := range program.Blocks {
+		fill := parseColor(gui.blockStyle(block).Color)
+		if fill == nil {
+			fill = color.NRGBA{R: 100, G: 100, B: 100, A: 255}
+		}
+		rect := canvas.NewRectangle(fill)
+		rect.CornerRadius = float32(6 * scale)
+		rect.StrokeColor = highlightColor
+		rect.Move(place(block.X, block.Y))
+		rect.Resize(fyne.NewSize(float32(block.Width*scale), float32(block.Height*scale)))
+		s.content.Add(rect)
+
+		text := canvas.NewText(block.Title, color.White)
+		text.TextStyle.Bold = true
+		text.TextSize = float32(14 * scale)
+		textSize := fyne.MeasureText(block.Title, text.TextSize, text.TextStyle)
+		text.Move(rect.Position().Add(fyne.NewPos((rect.Size().Width-textSize.Width)/2, (rect.Size().Height-textSize.Height)/2)))
+		s.content.Add(text)
+
+		s.blocks[block.ID] = sessionSceneBlock{rect: rect, title: block.Title}
+	}
+}
+
+// show переводит кадр на момент at
+func (s *sessionScene) show(at int64) {
+	s.time.Text = fmt.Sprintf("%.1f / %.1f с", float64(at)/1000, s.session.Duration().Seconds())
+	width := fyne.MeasureText(s.time.Text, s.time.TextSize, s.time.TextStyle).Width
+	s.time.Move(fyne.NewPos(s.size.Width-sessionVideoPadding-width, 6))
+
+	active := make(map[int]bool)
+	var titles []string
+	for _, step := range s.session.activeSteps(at) {
+		if block, ok := s.blocks[step.BlockID]; ok && block.title == step.Title {
+			active[step.BlockID] = true
+		}
+		titles = append(titles, step.Title)
+	}
+	s.steps.Text = strings.Join(titles, ", ")
+	for id, block := range s.blocks {
+		block.rect.StrokeWidth = 0
+		if active[id] {
+			block.rect.StrokeWidth = 4
+		}
+	}
+
+	for i, plot := range s.plots {
+		plot.moveCursor(at)
+		s.values[i].Text = "—"
+		if value, ok := sampleAt(s.samples[i], at); ok {
+			s.values[i].Text = s.formats[i](value)
+		}
+	}
+}
+
+// frameTimes возвращает моменты кадров видео, мс
+func (s *sessionScene) frameTimes() []int64 {
+	step := int64(1000 / sessionVideoFPS)
+	if frames := s.session.Length/step + 1; frames > sessionVideoMaxFrames {
+		step = s.session.Length/sessionVideoMaxFrames + 1
+	}
+	var times []int64
+	for at := int64(0); at < s.session.Length; at += step {
+		times = append(times, at)
+	}
+	return append(times, s.session.Length)
+}
+
+// render рисует кадры вне экрана и передает их emit; progress получает долю
+// готовых кадров. Кадр рисуется в потоке интерфейса, кодируется — в вызывающем
+func (s *sessionScene) render(ctx context.Context, emit func(frame image.Image) error, progress func(float64)) error {
+	c := software.NewCanvas()
+	c.SetPadded(false)
+	c.SetContent(s.content)
+	c.Resize(s.size)
+
+	times := s.frameTimes()
+	for i, at := range times {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var frame image.Image
+		fyne.DoAndWait(func() {
+			s.show(at)
+			frame = c.Capture()
+		})
+		if err := emit(frame); err != nil {
+			return err
+		}
+		progress(float64(i+1) / float64(len(times)))
+	}
+	return nil
+}
+
+// palettedFrame переводит кадр в палитру GIF. Цветов на кадре немного,
+// поэтому найденные индексы запоминаются
+func palettedFrame(frame image.Image, cache map[color.RGBA]uint8) *image.Paletted {
+	bounds := frame.Bounds()
+	paletted := image.NewPaletted(bounds, palette.Plan9)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(frame.At(x, y)).(color.RGBA)
+			index, ok := cache[c]
+			if !ok {
+				index = uint8(paletted.Palette.Index(c))
+				cache[c] = index
+			}
+			paletted.SetColorIndex(x, y, index)
+		}
+	}
+	return paletted
+}
+
+// encodeSessionGIF записывает сеанс анимацией GIF
+func (s *sessionScene) encodeSessionGIF(ctx context.Context, writer io.Writer, progress func(float64)) error {
+	times := s.frameTimes()
+	delay := 100 / sessionVideoFPS
+	if len(times) > 1 {
+		delay = int(times[1]-times[0]) / 10
+	}
+
+	animation := &gif.GIF{}
+	cache := make(map[color.RGBA]uint8)
+	err := s.render(ctx, func(frame image.Image) error {
+		animation.Image = append(animation.Image, palettedFrame(frame, cache))
+		animation.Delay = append(animation.Delay, delay)
+		return nil
+	}, progress)
+	if err != nil {
+		return err
+	}
+	return gif.EncodeAll(writer, animation)
+}
+
+// encodeSessionMP4 записывает сеанс видео MP4 через ffmpeg
+func (s *sessionScene) encodeSessionMP4(ctx context.Context, writer io.Writer, progress func(float64)) error {
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("для видео MP4 нужна программа ffmpeg, без нее сохраните GIF: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "wedoprog-session-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "session"+mp4Extension)
+
+	times := s.frameTimes()
+	fps := sessionVideoFPS
+	if len(times) > 1 {
+		fps = max(1, int(1000/(times[1]-times[0])))
+	}
+	cmd := exec.CommandContext(ctx, ffmpegPath, "-y", "-loglevel", "error",
+		"-f", "image2pipe", "-framerate", fmt.Sprint(fps), "-c:v", "png", "-i", "-",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p", "-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2", output)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("не удалось запустить ffmpeg: %v", err)
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	renderErr := s.render(ctx, func(frame image.Image) error {
+		return encoder.Encode(stdin, frame)
+	}, progress)
+	stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		if renderErr != nil {
+			return renderErr
+		}
+		return fmt.Errorf("ffmpeg не смог записать видео: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if renderErr != nil {
+		return renderErr
+	}
+
+	video, err := os.Open(output)
+	if err != nil {
+		return err
+	}
+	defer video.Close()
+	_, err = io.Copy(writer, video)
+	return err
+}
+
+// exportSessionVideo сохраняет сеанс анимацией GIF или видео MP4 по расширению файла
+func (gui *MainGUI) exportSessionVideo(session *SessionRecording, parent fyne.Window) {
+	d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, parent)
+			return
+		}
+		if writer == nil {
+			return
+		}
+
+		encode := (*sessionScene).encodeSessionGIF
+		if strings.EqualFold(writer.URI().Extension(), mp4Extension) {
+			encode = (*sessionScene).encodeSessionMP4
+		}
+		scene := gui.newSessionScene(session)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		bar := widget.NewProgressBar()
+		progressDialog := dialog.NewCustom("Экспорт сеанса", "Прервать",
+			container.NewVBox(widget.NewLabel("Кадры рисуются вне экрана..."), bar), parent)
+		progressDialog.SetOnClosed(cancel)
+		progressDialog.Resize(fyne.NewSize(420, 0))
+		progressDialog.Show()
+
+		go func() {
+			err := encode(scene, ctx, writer, func(fraction float64) {
+				fyne.Do(func() { bar.SetValue(fraction) })
+			})
+			closeErr := writer.Close()
+			interrupted := ctx.Err() != nil
+			fyne.Do(func() {
+				progressDialog.Hide()
+				switch {
+				case interrupted:
+					if err := storage.Delete(writer.URI()); err != nil {
+						log.Printf("Не удалось удалить прерванный экспорт: %v", err)
+					}
+				case err != nil:
+					dialog.ShowError(fmt.Errorf("ошибка экспорта сеанса: %v", err), parent)
+				case closeErr != nil:
+					dialog.ShowError(fmt.Errorf("ошибка сохранения сеанса: %v", closeErr), parent)
+				default:
+					dialog.ShowInformation("Экспорт сеанса", "Сеанс сохранен в "+writer.URI().Name(), parent)
+				}
+			})
+		}()
+	}, parent)
+	d.SetFileName("Сеанс" + gifExtension)
+	d.SetFilter(storage.NewExtensionFileFilter([]string{gifExtension, mp4Extension}))
+	d.Show()
+}
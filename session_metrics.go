@@ -0,0 +1,200 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	metricsLatencySamples = 500              // Сколько последних задержек команд хранить
+	metricsRateWindow     = 10 * time.Second // Окно расчета частоты уведомлений
+)
+
+// Источники уведомлений хаба
+const (
+	NotificationBattery = "Батарея"
+	NotificationPorts   = "Порты"
+	NotificationSensors = "Датчики"
+)
+
+// SessionMetrics локальная статистика сеанса работы с хабом.
+// Никуда не отправляется, нужна только для панели диагностики.
+type SessionMetrics struct {
+	mu sync.Mutex
+
+	started         time.Time
+	connects        int
+	connectFailures int
+	disconnects     int
+	writes          int
+	droppedWrites   int
+	latencies       []time.Duration
+	notifications   map[string]int
+	recent          []notificationStamp
+}
+
+// notificationStamp время получения уведомления для расчета частоты
+type notificationStamp struct {
+	source string
+	time   time.Time
+}
+
+// MetricsSnapshot снимок статистики для отображения
+type MetricsSnapshot struct {
+	Uptime           time.Duration
+	Connects         int
+	ConnectFailures  int
+	Disconnects      int
+	Writes           int
+	DroppedWrites    int
+	LatencyP50       time.Duration
+	LatencyP90       time.Duration
+	LatencyP99       time.Duration
+	Notifications    map[string]int
+	NotificationRate map[string]float64 // уведомлений в секунду
+}
+
+// NewSessionMetrics создает пустую статистику
+func NewSessionMetrics() *SessionMetrics {
+	m := &SessionMetrics{}
+	m.Reset()
+	return m
+}
+
+// Reset обнуляет статистику
+func (m *SessionMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.started = time.Now()
+	m.connects = 0
+	m.connectFailures = 0
+	m.disconnects = 0
+	m.writes = 0
+	m.droppedWrites = 0
+	m.latencies = nil
+	m.notifications = make(map[string]int)
+	m.recent = nil
+}
+
+// RecordConnect учитывает попытку подключения
+func (m *SessionMetrics) RecordConnect(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.connectFailures++
+	} else {
+		m.connects++
+	}
+}
+
+// RecordDisconnect учитывает отключение
+func (m *SessionMetrics) RecordDisconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disconnects++
+}
+
+// RecordWrite учитывает отправку команды и ее задержку
+func (m *SessionMetrics) RecordWrite(latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.droppedWrites++
+		return
+	}
+
+	m.writes++
+	m.latencies = append(m.latencies, latency)
+	if len(m.latencies) > metricsLatencySamples {
+		m.latencies = m.latencies[len(m.latencies)-metricsLatencySamples:]
+	}
+}
+
+// Latencies возвращает последние задержки записи команд
+func (m *SessionMetrics) Latencies() []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.latencies...)
+}
+
+// RecordNotification учитывает уведомление от хаба
+func (m *SessionMetrics) RecordNotification(source string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.notifications[source]++
+	m.recent = append(m.recent, notificationStamp{source: source, time: now})
+	m.pruneRecent(now)
+}
+
+// pruneRecent удаляет уведомления старше окна расчета частоты
+func (m *SessionMetrics) pruneRecent(now time.Time) {
+	cutoff := now.Add(-metricsRateWindow)
+	i := 0
+	for i < len(m.recent) && m.recent[i].time.Before(cutoff) {
+		i++
+	}
+	m.recent = m.recent[i:]
+}
+
+// Snapshot возвращает текущую статистику
+func (m *SessionMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.pruneRecent(now)
+
+	snapshot := MetricsSnapshot{
+		Uptime:           now.Sub(m.started),
+		Connects:         m.connects,
+		ConnectFailures:  m.connectFailures,
+		Disconnects:      m.disconnects,
+		Writes:           m.writes,
+		DroppedWrites:    m.droppedWrites,
+		Notifications:    make(map[string]int),
+		NotificationRate: make(map[string]float64),
+	}
+
+	for source, count := range m.notifications {
+		snapshot.Notifications[source] = count
+	}
+
+	window := metricsRateWindow
+	if snapshot.Uptime < window {
+		window = snapshot.Uptime
+	}
+	for _, stamp := range m.recent {
+		snapshot.NotificationRate[stamp.source]++
+	}
+	for source, count := range snapshot.NotificationRate {
+		if window > 0 {
+			snapshot.NotificationRate[source] = count / window.Seconds()
+		}
+	}
+
+	sorted := append([]time.Duration(nil), m.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	snapshot.LatencyP50 = percentile(sorted, 50)
+	snapshot.LatencyP90 = percentile(sorted, 90)
+	snapshot.LatencyP99 = percentile(sorted, 99)
+
+	return snapshot
+}
+
+// percentile возвращает перцентиль отсортированного списка задержек
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := (len(sorted)*p + 99) / 100
+	if index < 1 {
+		index = 1
+	}
+	return sorted[index-1]
+}
@@ -0,0 +1,685 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Сеанс — полная запись одного запуска программы: кадры обмена с хабом,
+// значения датчиков и выполненные блоки на общей шкале времени. Каждый запуск
+// записывается сам; сеанс можно сохранить, открыть и прокрутить по времени,
+// видя подсветку блоков на холсте, графики датчиков и команды хабу
+
+const (
+	sessionExtension     = ".wedosession"        // Расширение файлов сеанса
+	sessionVersion       = 1                     // Версия формата сеанса
+	sessionMaxSamples    = 50000                 // Сколько значений датчиков хранить в сеансе
+	sessionFrameLimit    = 14                    // Сколько последних кадров показывать при воспроизведении
+	sessionPlayInterval  = 50 * time.Millisecond // Период продвижения воспроизведения
+	sessionChartWidth    = 640
+	sessionChartHeight   = 60
+	sessionChartMaxLines = 320 // Сколько отрезков рисовать на графике датчика
+)
+
+// SessionFrame кадр обмена с хабом в сеансе
+type SessionFrame struct {
+	At        int64  `json:"t"` // Миллисекунды от начала сеанса
+	Direction string `json:"dir"`
+	UUID      string `json:"uuid"`
+	Data      []byte `json:"data"`
+}
+
+// String форматирует кадр для окна воспроизведения
+func (f SessionFrame) String() string {
+	uuid := f.UUID
+	if len(uuid) >= 8 {
+		uuid = uuid[4:8]
+	}
+	return fmt.Sprintf("%7.2f с %s %s %x", float64(f.At)/1000, f.Direction, uuid, f.Data)
+}
+
+// SessionStep выполненный блок в сеансе
+type SessionStep struct {
+	At       int64  `json:"t"`        // Миллисекунды от начала сеанса
+	Duration int64  `json:"duration"` // Миллисекунды
+	BlockID  int    `json:"block"`
+	Title    string `json:"title"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SessionRecording запись запуска программы
+type SessionRecording struct {
+	Version  int              `json:"version"`
+	Hub      string           `json:"hub,omitempty"`
+	Program  string           `json:"program"`
+	Recorded time.Time        `json:"recorded"`
+	Length   int64            `json:"length"` // Длительность сеанса, мс
+	Devices  []DocumentDevice `json:"devices"`
+	Samples  []SensorSample   `json:"samples"`
+	Frames   []SessionFrame   `json:"frames"`
+	Steps    []SessionStep    `json:"steps"`
+}
+
+// Duration возвращает длительность сеанса
+func (s *SessionRecording) Duration() time.Duration {
+	return time.Duration(s.Length) * time.Millisecond
+}
+
+// SensorRecording возвращает значения датчиков сеанса как запись датчиков,
+// чтобы запустить программу на тех же данных
+func (s *SessionRecording) SensorRecording() *SensorRecording {
+	return &SensorRecording{
+		Version:  sensorRecordingVersion,
+		Hub:      s.Hub,
+		Recorded: s.Recorded,
+		Devices:  s.Devices,
+		Samples:  s.Samples,
+	}
+}
+
+// portSamples возвращает значения датчика на порту по времени
+func (s *SessionRecording) portSamples(port byte) []SensorSample {
+	var samples []SensorSample
+	for _, sample := range s.Samples {
+		if sample.Port == port {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// framesUntil возвращает до limit последних кадров к моменту at
+func (s *SessionRecording) framesUntil(at int64, limit int) []SessionFrame {
+	end := sort.Search(len(s.Frames), func(i int) bool { return s.Frames[i].At > at })
+	return s.Frames[max(0, end-limit):end]
+}
+
+// activeSteps возвращает блоки, которые выполнялись в момент at. Если ни один
+// не выполнялся, возвращается последний начатый блок
+func (s *SessionRecording) activeSteps(at int64) []SessionStep {
+	var active []SessionStep
+	var last *SessionStep
+	for i, step := range s.Steps {
+		if step.At > at {
+			continue
+		}
+		if at < step.At+step.Duration {
+			active = append(active, step)
+		}
+		if last == nil || step.At >= last.At {
+			last = &s.Steps[i]
+		}
+	}
+	if len(active) == 0 && last != nil {
+		active = append(active, *last)
+	}
+	return active
+}
+
+// EncodeSession сериализует сеанс
+func EncodeSession(session *SessionRecording) ([]byte, error) {
+	return json.Marshal(session)
+}
+
+// DecodeSession читает сеанс и проверяет его
+func DecodeSession(data []byte) (*SessionRecording, error) {
+	var session SessionRecording
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("файл не является записью сеанса: %v", err)
+	}
+	if session.Version < 1 || session.Version > sessionVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия сеанса: %d", session.Version)
+	}
+	if session.Length < 0 {
+		return nil, fmt.Errorf("неверная длительность сеанса: %d", session.Length)
+	}
+	for _, device := range session.Devices {
+		if err := validatePort(device.Port); err != nil {
+			return nil, err
+		}
+	}
+	for i, sample := range session.Samples {
+		if err := validatePort(sample.Port); err != nil {
+			return nil, err
+		}
+		if sample.At < 0 || (i > 0 && sample.At < session.Samples[i-1].At) {
+			return nil, fmt.Errorf("значения датчиков сеанса идут не по порядку времени")
+		}
+	}
+	for i, frame := range session.Frames {
+		if frame.At < 0 || (i > 0 && frame.At < session.Frames[i-1].At) {
+			return nil, fmt.Errorf("кадры сеанса идут не по порядку времени")
+		}
+	}
+	return &session, nil
+}
+
+// SessionRecorder записывает сеанс каждого запуска программы
+type SessionRecorder struct {
+	hub *HubManager
+
+	mu          sync.Mutex
+	session     *SessionRecording // Записываемый сеанс, nil — запись не идет
+	last        *SessionRecording // Последний записанный сеанс
+	started     time.Time
+	unsubscribe func()
+}
+
+// NewSessionRecorder создает запись сеансов и подписывает ее на выполнение блоков
+func NewSessionRecorder(hub *HubManager, programMgr *ProgramManager) *SessionRecorder {
+	r := &SessionRecorder{hub: hub}
+	programMgr.AddBlockHook(BlockHook{After: r.recordStep})
+	return r
+}
+
+// onSessionProgramState начинает запись сеанса при запуске программы и
+// заканчивает при завершении
+func (gui *MainGUI) onSessionProgramState(event Event) {
+	if event.Name == "running" {
+		gui.sessions.start(gui.programMgr.GetProgram().Name)
+	} else {
+		gui.sessions.finish()
+	}
+}
+
+// start начинает запись сеанса
+func (r *SessionRecorder) start(program string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session != nil {
+		return
+	}
+
+	r.session = &SessionRecording{
+		Version:  sessionVersion,
+		Hub:      r.hub.GetHubInfo().Name,
+		Program:  program,
+		Recorded: time.Now(),
+	}
+	for _, device := range r.hub.devices.GetConnectedDevices() {
+		r.session.Devices = append(r.session.Devices, DocumentDevice{Port: device.PortID, Device: device.DeviceType})
+	}
+	sort.Slice(r.session.Devices, func(i, j int) bool { return r.session.Devices[i].Port < r.session.Devices[j].Port })
+	r.started = time.Now()
+	r.hub.Trace().StartCapture()
+	r.unsubscribe = r.hub.Events().Subscribe(EventSensorValue, func(event Event) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.session != nil && len(r.session.Samples) < sessionMaxSamples {
+			r.session.Samples = append(r.session.Samples, SensorSample{
+				At:    int64(max(0, int(event.Time.Sub(r.started).Milliseconds()))),
+				Port:  event.PortID,
+				Value: event.Value,
+			})
+		}
+	})
+}
+
+// recordStep добавляет выполненный блок в сеанс
+func (r *SessionRecorder) recordStep(execution BlockExecution) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session == nil {
+		return
+	}
+	step := SessionStep{
+		At:       execution.Started.Sub(r.started).Milliseconds(),
+		Duration: execution.Duration.Milliseconds(),
+		BlockID:  execution.BlockID,
+		Title:    execution.Title,
+	}
+	if execution.Err != nil {
+		step.Error = execution.Err.Error()
+	}
+	r.session.Steps = append(r.session.Steps, step)
+}
+
+// finish заканчивает запись сеанса и запоминает его как последний
+func (r *SessionRecorder) finish() {
+	r.mu.Lock()
+	session, unsubscribe, started := r.session, r.unsubscribe, r.started
+	r.session, r.unsubscribe = nil, nil
+	r.mu.Unlock()
+	if session == nil {
+		return
+	}
+	unsubscribe()
+
+	for _, frame := range r.hub.Trace().StopCapture() {
+		if frame.time.Before(started) {
+			continue
+		}
+		session.Frames = append(session.Frames, SessionFrame{
+			At:        frame.time.Sub(started).Milliseconds(),
+			Direction: frame.direction,
+			UUID:      frame.uuid,
+			Data:      frame.data,
+		})
+	}
+	// Значения датчиков приходят из разных горутин и могут перемешаться на миллисекунду
+	sort.SliceStable(session.Samples, func(i, j int) bool { return session.Samples[i].At < session.Samples[j].At })
+	session.Length = time.Since(started).Milliseconds()
+
+	r.mu.Lock()
+	r.last = session
+	r.mu.Unlock()
+	log.Printf("Сеанс записан: %d кадров, %d значений датчиков, %d блоков за %.1f с",
+		len(session.Frames), len(session.Samples), len(session.Steps), session.Duration().Seconds())
+}
+
+// Last возвращает последний записанный сеанс; nil, если программа еще не запускалась
+func (r *SessionRecorder) Last() *SessionRecording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// sessionSummary описывает сеанс для интерфейса
+func sessionSummary(name string, session *SessionRecording) string {
+	summary := fmt.Sprintf("%s: «%s», %s, %.1f с", name, session.Program,
+		session.Recorded.Format("02.01.2006 15:04"), session.Duration().Seconds())
+	return summary + fmt.Sprintf("\n%d блоков, %d команд и уведомлений, %d значений датчиков",
+		len(session.Steps), len(session.Frames), len(session.Samples))
+}
+
+// sessionPlot график значений датчика за сеанс с отметкой текущего момента.
+// Состоит только из фигур холста, поэтому рисуется и в окне, и вне экрана
+type sessionPlot struct {
+	object *fyne.Container
+	cursor *canvas.Line
+	size   fyne.Size
+	length int64
+}
+
+// newSessionPlot рисует график значений датчика на всю длительность сеанса
+func newSessionPlot(samples []SensorSample, length int64, size fyne.Size) *sessionPlot {
+	p := &sessionPlot{object: container.NewWithoutLayout(), size: size, length: int64(max(1, int(length)))}
+
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	background.Resize(size)
+	p.object.Add(background)
+
+	low, high := samples[0].Value, samples[0].Value
+	for _, sample := range samples {
+		low = math.Min(low, sample.Value)
+		high = math.Max(high, sample.Value)
+	}
+	if high == low {
+		high = low + 1
+	}
+	point := func(sample SensorSample) fyne.Position {
+		return fyne.NewPos(p.x(sample.At), size.Height-4-float32((sample.Value-low)/(high-low))*(size.Height-8))
+	}
+
+	// Близкие точки пропускаются, чтобы не рисовать тысячи отрезков
+	step := max(1, len(samples)/sessionChartMaxLines)
+	previous := point(samples[0])
+	for i := step; i < len(samples)+step-1; i += step {
+		current := point(samples[min(i, len(samples)-1)])
+		line := canvas.NewLine(theme.Color(theme.ColorNamePrimary))
+		line.StrokeWidth = 1.5
+		line.Position1, line.Position2 = previous, current
+		p.object.Add(line)
+		previous = current
+	}
+
+	p.cursor = canvas.NewLine(theme.Color(theme.ColorNameError))
+	p.cursor.StrokeWidth = 1
+	p.object.Add(p.cursor)
+	return p
+}
+
+// x координата момента на графике
+func (p *sessionPlot) x(at int64) float32 {
+	return float32(at) / float32(p.length) * p.size.Width
+}
+
+// moveCursor переносит отметку на момент at
+func (p *sessionPlot) moveCursor(at int64) {
+	x := p.x(at)
+	p.cursor.Position1 = fyne.NewPos(x, 0)
+	p.cursor.Position2 = fyne.NewPos(x, p.size.Height)
+	p.cursor.Refresh()
+}
+
+// sampleAt возвращает значение датчика в момент at
+func sampleAt(samples []SensorSample, at int64) (float64, bool) {
+	index := sort.Search(len(samples), func(i int) bool { return samples[i].At > at }) - 1
+	if index < 0 {
+		return 0, false
+	}
+	return samples[index].Value, true
+}
+
+// sessionChart график датчика в окне воспроизведения с подписью значения
+type sessionChart struct {
+	object  fyne.CanvasObject
+	plot    *sessionPlot
+	value   *widget.Label
+	format  func(value float64) string
+	samples []SensorSample
+}
+
+// newSessionChart создает график датчика для окна воспроизведения
+func newSessionChart(samples []SensorSample, length int64, format func(float64) string) *sessionChart {
+	size := fyne.NewSize(sessionChartWidth, sessionChartHeight)
+	c := &sessionChart{plot: newSessionPlot(samples, length, size), format: format, samples: samples}
+	c.value = widget.NewLabelWithStyle("—", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	c.object = container.NewHBox(container.NewGridWrap(size, c.plot.object), c.value)
+	return c
+}
+
+// Show переносит отметку на момент at и показывает значение датчика в этот момент
+func (c *sessionChart) Show(at int64) {
+	c.plot.moveCursor(at)
+	if value, ok := sampleAt(c.samples, at); ok {
+		c.value.SetText(c.format(value))
+	} else {
+		c.value.SetText("—")
+	}
+}
+
+// showSessionReplay открывает окно воспроизведения сеанса: прокрутка по времени
+// подсвечивает выполнявшиеся блоки на холсте, двигает отметку на графиках
+// датчиков и показывает команды хабу к этому моменту
+func (gui *MainGUI) showSessionReplay() {
+	window := fyne.CurrentApp().NewWindow("Воспроизведение сеанса")
+
+	var session *SessionRecording
+	var sessionName string
+	var charts []*sessionChart
+	highlighted := make(map[int]bool)
+	var playMu sync.Mutex
+	var stopPlay chan struct{}
+
+	info := widget.NewLabel("")
+	info.Wrapping = fyne.TextWrapWord
+	position := widget.NewSlider(0, 1)
+	timeLabel := widget.NewLabel("")
+	blocks := widget.NewLabel("")
+	blocks.Wrapping = fyne.TextWrapWord
+	frames := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	chartsBox := container.NewVBox()
+
+	// highlight подсвечивает на холсте блоки, которые выполнялись в этот момент.
+	// Блок подсвечивается, только если на холсте он тот же, что в сеансе
+	highlight := func(steps []SessionStep) {
+		want := make(map[int]bool)
+		for _, step := range steps {
+			if block, ok := gui.programMgr.GetBlock(step.BlockID); ok && block.Title == step.Title {
+				want[step.BlockID] = true
+			}
+		}
+		for id := range highlighted {
+			if !want[id] {
+				if blockWidget := gui.programPanel.GetBlockWidget(id); blockWidget != nil {
+					blockWidget.SetReplayHighlight(false)
+				}
+				delete(highlighted, id)
+			}
+		}
+		for id := range want {
+			if blockWidget := gui.programPanel.GetBlockWidget(id); blockWidget != nil && !highlighted[id] {
+				blockWidget.SetReplayHighlight(true)
+				highlighted[id] = true
+			}
+		}
+	}
+
+	show := func(at int64) {
+		if session == nil {
+			return
+		}
+		timeLabel.SetText(fmt.Sprintf("%.2f / %.2f с", float64(at)/1000, session.Duration().Seconds()))
+
+		steps := session.activeSteps(at)
+		lines := make([]string, 0, len(steps))
+		for _, step := range steps {
+			line := fmt.Sprintf("%s (ID: %d)", step.Title, step.BlockID)
+			if step.Error != "" {
+				line += ": " + step.Error
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "—")
+		}
+		blocks.SetText(strings.Join(lines, "\n"))
+		highlight(steps)
+
+		frameLines := make([]string, 0, sessionFrameLimit)
+		for _, frame := range session.framesUntil(at, sessionFrameLimit) {
+			frameLines = append(frameLines, frame.String())
+		}
+		frames.SetText(strings.Join(frameLines, "\n"))
+
+		for _, chart := range charts {
+			chart.Show(at)
+		}
+	}
+	position.OnChanged = func(value float64) { show(int64(value)) }
+
+	pause := func() {
+		playMu.Lock()
+		defer playMu.Unlock()
+		if stopPlay != nil {
+			close(stopPlay)
+			stopPlay = nil
+		}
+	}
+
+	var playButton *widget.Button
+	playButton = widget.NewButtonWithIcon("Воспроизвести", theme.MediaPlayIcon(), func() {
+		playMu.Lock()
+		playing := stopPlay != nil
+		playMu.Unlock()
+		if playing || session == nil {
+			pause()
+			playButton.SetText("Воспроизвести")
+			playButton.SetIcon(theme.MediaPlayIcon())
+			return
+		}
+		if position.Value >= position.Max {
+			position.SetValue(0)
+		}
+		playButton.SetText("Пауза")
+		playButton.SetIcon(theme.MediaPauseIcon())
+
+		stop := make(chan struct{})
+		playMu.Lock()
+		stopPlay = stop
+		playMu.Unlock()
+		go func() {
+			ticker := time.NewTicker(sessionPlayInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					finished := false
+					fyne.DoAndWait(func() {
+						next := position.Value + float64(sessionPlayInterval.Milliseconds())
+						if next >= position.Max {
+							next, finished = position.Max, true
+						}
+						position.SetValue(next)
+					})
+					if finished {
+						pause()
+						fyne.Do(func() {
+							playButton.SetText("Воспроизвести")
+							playButton.SetIcon(theme.MediaPlayIcon())
+						})
+						return
+					}
+				}
+			}
+		}()
+	})
+
+	var saveButton, sensorsButton, exportButton *widget.Button
+	useSession := func(name string, s *SessionRecording) {
+		pause()
+		playButton.SetText("Воспроизвести")
+		playButton.SetIcon(theme.MediaPlayIcon())
+		session, sessionName = s, name
+		info.SetText(sessionSummary(sessionName, session))
+
+		charts = nil
+		chartsBox.RemoveAll()
+		for _, device := range session.Devices {
+			samples := session.portSamples(device.Port)
+			if len(samples) == 0 {
+				continue
+			}
+			deviceType := device.Device
+			chart := newSessionChart(samples, session.Length, func(value float64) string {
+				return gui.units.Format(deviceType, value)
+			})
+			charts = append(charts, chart)
+			chartsBox.Add(widget.NewLabel(fmt.Sprintf("Порт %d — %s", device.Port, DeviceTypeName(device.Device))))
+			chartsBox.Add(chart.object)
+		}
+		if len(charts) == 0 {
+			chartsBox.Add(widget.NewLabel("В сеансе нет значений датчиков"))
+		}
+
+		position.Max = float64(max(1, int(session.Length)))
+		position.Step = 10
+		position.SetValue(0)
+		show(0)
+		saveButton.Enable()
+		sensorsButton.Enable()
+		exportButton.Enable()
+		playButton.Enable()
+	}
+
+	lastButton := widget.NewButton("Последний запуск", func() {
+		if last := gui.sessions.Last(); last != nil {
+			useSession("Последний запуск", last)
+		} else {
+			dialog.ShowInformation("Сеанс", "Программа еще не запускалась", window)
+		}
+	})
+
+	saveButton = widget.NewButton("Сохранить...", func() {
+		data, err := EncodeSession(session)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		d := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			defer writer.Close()
+			if _, err := writer.Write(data); err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка сохранения сеанса: %v", err), window)
+				return
+			}
+			sessionName = writer.URI().Name()
+			info.SetText(sessionSummary(sessionName, session))
+		}, window)
+		d.SetFileName("Сеанс" + sessionExtension)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{sessionExtension}))
+		d.Show()
+	})
+
+	openButton := widget.NewButton("Открыть...", func() {
+		d := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			if reader == nil {
+				return
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), window)
+				return
+			}
+			loaded, err := DecodeSession(data)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			useSession(reader.URI().Name(), loaded)
+		}, window)
+		d.SetFilter(storage.NewExtensionFileFilter([]string{sessionExtension}))
+		d.Show()
+	})
+
+	sensorsButton = widget.NewButton("Датчики в запись", func() {
+		gui.sensorRecording, gui.sensorRecordingName = session.SensorRecording(), sessionName
+		if gui.programMgr.SensorReplay() != nil {
+			gui.programMgr.SetSensorReplay(gui.sensorRecording)
+		}
+		dialog.ShowInformation("Запись датчиков",
+			"Значения датчиков сеанса стали записью датчиков: программу можно запустить на них в окне «Запись датчиков»", window)
+	})
+
+	exportButton = widget.NewButton("Экспорт видео...", func() {
+		pause()
+		gui.exportSessionVideo(session, window)
+	})
+
+	saveButton.Disable()
+	sensorsButton.Disable()
+	exportButton.Disable()
+	playButton.Disable()
+	info.SetText("Каждый запуск программы записывается: кадры обмена с хабом, значения датчиков и выполненные блоки. " +
+		"Откройте последний запуск или сохраненный сеанс.")
+
+	framesScroll := container.NewVScroll(frames)
+	framesScroll.SetMinSize(fyne.NewSize(0, 220))
+	marker := canvas.NewRectangle(color.Transparent)
+	marker.StrokeColor = highlightColor
+	marker.StrokeWidth = 2
+	marker.SetMinSize(fyne.NewSize(16, 16))
+
+	top := container.NewVBox(
+		info,
+		container.NewGridWithColumns(5, lastButton, openButton, saveButton, sensorsButton, exportButton),
+		container.NewBorder(nil, nil, playButton, timeLabel, position),
+		widget.NewCard("", "", container.NewBorder(nil, nil, container.NewCenter(marker), nil, blocks)),
+	)
+	content := container.NewVBox(
+		widget.NewCard("Датчики", "", chartsBox),
+		widget.NewCard("Команды и уведомления хаба", "", framesScroll),
+	)
+
+	window.SetOnClosed(func() {
+		pause()
+		highlight(nil)
+	})
+	window.SetContent(container.NewBorder(top, nil, nil, nil, container.NewVScroll(content)))
+	window.Resize(fyne.NewSize(820, 720))
+	window.Show()
+
+	if last := gui.sessions.Last(); last != nil {
+		useSession("Последний запуск", last)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Ключи настроек приложения
+const (
+	settingCameraDevice   = "camera_device"
+	settingSpeechKeywords = "speech_keywords"
+	settingMicrophone     = "speech_microphone"
+	settingSpeechModelDir = "speech_model_dir"
+	settingOSCEnabled     = "osc_enabled"
+	settingOSCHost        = "osc_host"
+	settingOSCPort        = "osc_port"
+	settingDistanceUnit   = "distance_unit"
+	settingBLEAdapter     = "ble_adapter"
+
+	settingObjectThreshold  = "object_threshold"
+	settingObjectHysteresis = "object_hysteresis"
+)
+
+// preferences возвращает хранилище настроек выбранного профиля
+func (gui *MainGUI) preferences() fyne.Preferences {
+	return profilePreferences{fyne.CurrentApp().Preferences(), profilePrefix(gui.profile)}
+}
+
+// applySettings передает сохраненные настройки менеджерам
+func (gui *MainGUI) applySettings() {
+	prefs := gui.preferences()
+	gui.programMgr.VisionMonitor().SetDevice(prefs.String(settingCameraDevice))
+	gui.programMgr.SpeechMonitor().Configure(
+		parseKeywords(prefs.StringWithFallback(settingSpeechKeywords, strings.Join(defaultSpeechKeywords, ", "))),
+		prefs.String(settingMicrophone),
+		prefs.String(settingSpeechModelDir),
+	)
+
+	gui.units.DistanceUnit = prefs.StringWithFallback(settingDistanceUnit, DistanceUnitCM)
+	gui.hubMgr.ObjectDetector().Configure(
+		prefs.FloatWithFallback(settingObjectThreshold, defaultObjectThreshold),
+		prefs.FloatWithFallback(settingObjectHysteresis, defaultObjectHysteresis),
+	)
+	gui.hubMgr.BumpDetector().SetSensitivity(prefs.IntWithFallback(settingBumpSensitivity, defaultBumpSensitivity))
+	gui.announcer.SetEnabled(prefs.Bool(settingScreenReader))
+	gui.sounds.SetEnabled(prefs.Bool(settingSoundCues))
+	gui.programMgr.SetBlockTimeout(prefs.FloatWithFallback(settingBlockTimeout, defaultBlockTimeout))
+	gui.programMgr.SetCompileSchedules(prefs.Bool(settingCompileProgram))
+	gui.hubMgr.Arbiter().SetPolicy(ArbitrationPolicy(prefs.String(settingArbitration)))
+	gui.hubMgr.VoltageWatchdog().Configure(
+		prefs.FloatWithFallback(settingVoltageThreshold, defaultVoltageThreshold),
+		prefs.Bool(settingVoltageAutoLimit),
+	)
+	gui.batterySaver.Configure(time.Duration(prefs.Int(settingIdleMinutes))*time.Minute, prefs.Bool(settingIdlePowerOff))
+	for _, item := range logModules {
+		SetLogLevel(item.module, LogLevel(prefs.IntWithFallback(settingLogLevelPrefix+item.module, int(LogLevelInfo))))
+	}
+	gui.hubMgr.ProtocolLog().SetMode(ProtocolLogMode(prefs.String(settingProtocolLog)))
+	if allowed, err := parseHubWhitelist(prefs.String(settingAllowedHubs)); err == nil {
+		gui.hubMgr.SetAllowedHubs(allowed)
+	}
+	if gui.devicePanel != nil {
+		gui.devicePanel.Refresh()
+	}
+	if gui.programPanel != nil {
+		gui.programPanel.refreshBlockStyles()
+		gui.programPanel.SetBackground(CanvasBackground(prefs.String(settingCanvasBackground)))
+	}
+
+	err := gui.oscOutput.Configure(
+		prefs.Bool(settingOSCEnabled),
+		prefs.StringWithFallback(settingOSCHost, "127.0.0.1"),
+		prefs.IntWithFallback(settingOSCPort, defaultOSCPort),
+	)
+	if err != nil {
+		log.Printf("Ошибка настройки OSC: %v", err)
+	}
+}
+
+// settingsSection вкладка диалога настроек: поля формы и сохранение их значений
+type settingsSection struct {
+	title string
+	items []*widget.FormItem
+	save  func(prefs fyne.Preferences)
+}
+
+// showSettingsDialog показывает диалог настроек приложения
+func (gui *MainGUI) showSettingsDialog() {
+	prefs := gui.preferences()
+
+	sections := []settingsSection{
+		gui.generalSettings(prefs),
+		gui.blockStyleSettings(prefs),
+		gui.inputSettings(prefs),
+		gui.oscSettings(prefs),
+		gui.bluetoothSettings(prefs),
+		gui.logSettings(prefs),
+		gui.teacherSettings(prefs),
+	}
+
+	tabs := container.NewAppTabs()
+	for _, section := range sections {
+		tabs.Append(container.NewTabItem(section.title, widget.NewForm(section.items...)))
+	}
+
+	d := dialog.NewCustomConfirm("Настройки", "Сохранить", "Отмена", tabs, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+
+		for _, section := range sections {
+			section.save(prefs)
+		}
+		gui.applySettings()
+	}, gui.window)
+	d.Resize(fyne.NewSize(620, 460))
+	d.Show()
+}
+
+// generalSettings общие настройки: единицы измерения, обнаружение объекта и удара, ожидание хаба, расчет программы,
+// совместное управление портами, озвучивание и звуковые сигналы
+func (gui *MainGUI) generalSettings(prefs fyne.Preferences) settingsSection {
+	distanceUnits := map[string]string{
+		"Сантиметры": DistanceUnitCM,
+		"Дюймы":      DistanceUnitInch,
+	}
+	distanceSelect := widget.NewSelect([]string{"Сантиметры", "Дюймы"}, nil)
+	distanceSelect.SetSelected("Сантиметры")
+	if prefs.String(settingDistanceUnit) == DistanceUnitInch {
+		distanceSelect.SetSelected("Дюймы")
+	}
+
+	thresholdEntry := NewNumberSpinner(1, distanceMaxCM, 1, 0,
+		prefs.FloatWithFallback(settingObjectThreshold, defaultObjectThreshold), nil)
+	hysteresisEntry := NewNumberSpinner(0, 10, 0.5, 1,
+		prefs.FloatWithFallback(settingObjectHysteresis, defaultObjectHysteresis), nil)
+	objectItem := widget.NewFormItem("Объект обнаружен", container.NewGridWithColumns(2,
+		container.NewBorder(nil, nil, widget.NewLabel("ближе, см"), nil, thresholdEntry),
+		container.NewBorder(nil, nil, widget.NewLabel("гистерезис, см"), nil, hysteresisEntry),
+	))
+	objectItem.HintText = "Признак пропадает, когда предмет отходит дальше порога на величину гистерезиса"
+
+	bumpEntry := NewNumberSpinner(1, maxBumpSensitivity, 1, 0,
+		float64(prefs.IntWithFallback(settingBumpSensitivity, defaultBumpSensitivity)), nil)
+	bumpItem := widget.NewFormItem("Чувствительность к удару", bumpEntry)
+	bumpItem.HintText = "Для блока «Когда удар»: 1 — только сильные удары, 5 — легкие толчки"
+
+	timeoutEntry := NewNumberSpinner(0.1, maxBlockTimeout, 0.1, 1,
+		prefs.FloatWithFallback(settingBlockTimeout, defaultBlockTimeout), nil)
+	timeoutItem := widget.NewFormItem("Ожидание хаба, с", timeoutEntry)
+	timeoutItem.HintText = "Блок, который дольше ждет ответа хаба, завершается ошибкой"
+
+	readerCheck := widget.NewCheck("Озвучивать элементы при переходе клавишей Tab", nil)
+	readerCheck.SetChecked(prefs.Bool(settingScreenReader))
+	readerItem := widget.NewFormItem("Диктор", readerCheck)
+	readerItem.HintText = "Нужен синтезатор речи: spd-say или espeak в Linux, встроенный в macOS и Windows"
+
+	compileCheck := widget.NewCheck("Рассчитывать программу перед запуском", nil)
+	compileCheck.SetChecked(prefs.Bool(settingCompileProgram))
+	compileItem := widget.NewFormItem("Выполнение", compileCheck)
+	compileItem.HintText = "Цепочки из моторов, светодиодов, звуков, пауз и циклов со счетчиком идут точно по времени"
+
+	policyNames := make([]string, len(arbitrationPolicies))
+	for i, item := range arbitrationPolicies {
+		policyNames[i] = item.name
+	}
+	policySelect := widget.NewSelect(policyNames, nil)
+	policySelect.SetSelected(arbitrationPolicyName(ArbitrationPolicy(prefs.String(settingArbitration))))
+	policyItem := widget.NewFormItem("Общие порты", policySelect)
+	policyItem.HintText = "Кнопки проверки важнее программы. Если порт занимает первый источник, команды остальных отклоняются"
+
+	soundsCheck := widget.NewCheck("Звуковые сигналы", nil)
+	soundsCheck.SetChecked(prefs.Bool(settingSoundCues))
+	soundsItem := widget.NewFormItem("Звуки", soundsCheck)
+	soundsItem.HintText = "Сигналы при добавлении блока, завершении программы и ошибке"
+
+	return settingsSection{
+		title: "Общие",
+		items: []*widget.FormItem{
+			widget.NewFormItem("Расстояние", distanceSelect),
+			objectItem,
+			bumpItem,
+			timeoutItem,
+			compileItem,
+			policyItem,
+			readerItem,
+			soundsItem,
+		},
+		save: func(prefs fyne.Preferences) {
+			prefs.SetString(settingDistanceUnit, distanceUnits[distanceSelect.Selected])
+			prefs.SetFloat(settingObjectThreshold, thresholdEntry.Value())
+			prefs.SetFloat(settingObjectHysteresis, hysteresisEntry.Value())
+			prefs.SetInt(settingBumpSensitivity, int(bumpEntry.Value()))
+			prefs.SetFloat(settingBlockTimeout, timeoutEntry.Value())
+			prefs.SetBool(settingCompileProgram, compileCheck.Checked)
+			prefs.SetString(settingArbitration, string(arbitrationPolicies[policySelect.SelectedIndex()].policy))
+			prefs.SetBool(settingScreenReader, readerCheck.Checked)
+			prefs.SetBool(settingSoundCues, soundsCheck.Checked)
+		},
+	}
+}
+
+// inputSettings настройки камеры и распознавания речи
+func (gui *MainGUI) inputSettings(prefs fyne.Preferences) settingsSection {
+	cameraEntry := widget.NewEntry()
+	cameraEntry.SetText(prefs.String(settingCameraDevice))
+	cameraEntry.SetPlaceHolder("По умолчанию")
+	cameraItem := widget.NewFormItem("Камера", cameraEntry)
+	cameraItem.HintText = "Устройство камеры для блоков зрения (Linux: /dev/video0, Windows: имя камеры)"
+
+	keywordsEntry := widget.NewEntry()
+	keywordsEntry.SetText(strings.Join(gui.programMgr.SpeechMonitor().Keywords(), ", "))
+	keywordsItem := widget.NewFormItem("Ключевые слова", keywordsEntry)
+	keywordsItem.HintText = "Слова для блока \"Когда слышу\", через запятую"
+
+	microphoneEntry := widget.NewEntry()
+	microphoneEntry.SetText(prefs.String(settingMicrophone))
+	microphoneEntry.SetPlaceHolder("По умолчанию")
+	microphoneItem := widget.NewFormItem("Микрофон", microphoneEntry)
+	microphoneItem.HintText = "Устройство записи (например, plughw:1,0)"
+
+	modelEntry := widget.NewEntry()
+	modelEntry.SetText(prefs.String(settingSpeechModelDir))
+	modelEntry.SetPlaceHolder("Модель движка по умолчанию")
+	modelItem := widget.NewFormItem("Модель речи", modelEntry)
+	modelItem.HintText = "Папка русской модели PocketSphinx (acoustic, dictionary.dic)"
+
+	return settingsSection{
+		title: "Камера и речь",
+		items: []*widget.FormItem{cameraItem, keywordsItem, microphoneItem, modelItem},
+		save: func(prefs fyne.Preferences) {
+			prefs.SetString(settingCameraDevice, cameraEntry.Text)
+			prefs.SetString(settingSpeechKeywords, keywordsEntry.Text)
+			prefs.SetString(settingMicrophone, microphoneEntry.Text)
+			prefs.SetString(settingSpeechModelDir, modelEntry.Text)
+		},
+	}
+}
+
+// oscSettings настройки отправки данных по OSC
+func (gui *MainGUI) oscSettings(prefs fyne.Preferences) settingsSection {
+	oscCheck := widget.NewCheck("Отправлять данные по OSC", nil)
+	oscCheck.SetChecked(prefs.Bool(settingOSCEnabled))
+	oscItem := widget.NewFormItem("OSC", oscCheck)
+	oscItem.HintText = "Датчики: /wedo/sensor/<порт>, события: /wedo/message, /wedo/program"
+
+	oscHostEntry := widget.NewEntry()
+	oscHostEntry.SetText(prefs.StringWithFallback(settingOSCHost, "127.0.0.1"))
+	oscPortEntry := NewNumberSpinner(1, 65535, 1, 0, float64(prefs.IntWithFallback(settingOSCPort, defaultOSCPort)), nil)
+
+	return settingsSection{
+		title: "OSC",
+		items: []*widget.FormItem{
+			oscItem,
+			widget.NewFormItem("Адрес", oscHostEntry),
+			widget.NewFormItem("Порт", oscPortEntry),
+		},
+		save: func(prefs fyne.Preferences) {
+			prefs.SetBool(settingOSCEnabled, oscCheck.Checked)
+			prefs.SetString(settingOSCHost, oscHostEntry.Text)
+			prefs.SetInt(settingOSCPort, int(oscPortEntry.Value()))
+		},
+	}
+}
+
+// logSettings подробность журнала по модулям и журнал команд хабу
+func (gui *MainGUI) logSettings(prefs fyne.Preferences) settingsSection {
+	items := make([]*widget.FormItem, 0, len(logModules)+1)
+	levelSelects := make([]*widget.Select, len(logModules))
+	for i, item := range logModules {
+		levelSelects[i] = widget.NewSelect(logLevelNames, nil)
+		level := prefs.IntWithFallback(settingLogLevelPrefix+item.module, int(LogLevelInfo))
+		if level < 0 || level >= len(logLevelNames) {
+			level = int(LogLevelInfo)
+		}
+		levelSelects[i].SetSelectedIndex(level)
+		items = append(items, widget.NewFormItem(item.name, levelSelects[i]))
+	}
+	items[len(items)-1].HintText = "Подробно — каждая команда устройству и каждый шаг программы"
+
+	protocolNames := make([]string, len(protocolLogModes))
+	for i, item := range protocolLogModes {
+		protocolNames[i] = item.name
+	}
+	protocolSelect := widget.NewSelect(protocolNames, nil)
+	protocolSelect.SetSelected(protocolLogModeName(ProtocolLogMode(prefs.String(settingProtocolLog))))
+	protocolItem := widget.NewFormItem("Команды хабу", protocolSelect)
+	protocolItem.HintText = "Частые команды записываются не чаще двух раз в секунду на порт"
+	items = append(items, protocolItem)
+
+	return settingsSection{
+		title: "Журнал",
+		items: items,
+		save: func(prefs fyne.Preferences) {
+			for i, item := range logModules {
+				prefs.SetInt(settingLogLevelPrefix+item.module, levelSelects[i].SelectedIndex())
+			}
+			prefs.SetString(settingProtocolLog, string(protocolLogModes[protocolSelect.SelectedIndex()].mode))
+		},
+	}
+}
+
+// bluetoothSettings выбор BLE-адаптера, если в системе их несколько, проверка
+// комплекта, экономия батареи и сторож напряжения
+func (gui *MainGUI) bluetoothSettings(prefs fyne.Preferences) settingsSection {
+	ids := append([]string{""}, availableAdapters()...)
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = adapterName(id)
+	}
+
+	adapterSelect := widget.NewSelect(names, nil)
+	adapterSelect.SetSelected(adapterName(gui.hubMgr.AdapterID()))
+	adapterItem := widget.NewFormItem("Адаптер", adapterSelect)
+	adapterItem.HintText = "Встроенный адаптер или USB-адаптер; сменить можно, когда хаб отключен"
+	if len(ids) == 1 {
+		adapterSelect.Disable()
+		adapterItem.HintText = "В системе один адаптер или выбор адаптера не поддерживается"
+	} else if gui.restrictedForStudent() {
+		adapterSelect.Disable()
+		adapterItem.HintText = "Адаптер выбирает учитель в своем профиле"
+	}
+
+	kitCheck := widget.NewCheck("Предлагать проверку комплекта после подключения", nil)
+	kitCheck.SetChecked(prefs.BoolWithFallback(settingOfferKitCheck, true))
+	kitCheckItem := widget.NewFormItem("Проверка", kitCheck)
+	kitCheckItem.HintText = "Каждое найденное устройство ненадолго включается, результат показывается по портам"
+
+	idleNames := make([]string, len(idleMinuteChoices))
+	for i, minutes := range idleMinuteChoices {
+		idleNames[i] = idleMinutesName(minutes)
+	}
+	idleSelect := widget.NewSelect(idleNames, nil)
+	idleSelect.SetSelected(idleMinutesName(prefs.Int(settingIdleMinutes)))
+	if idleSelect.SelectedIndex() < 0 {
+		idleSelect.SetSelectedIndex(0)
+	}
+	idlePowerOff := widget.NewCheck("Выключать хаб, а не только отключаться", nil)
+	idlePowerOff.SetChecked(prefs.Bool(settingIdlePowerOff))
+	idleItem := widget.NewFormItem("Экономия батареи", container.NewVBox(idleSelect, idlePowerOff))
+	idleItem.HintText = "Хаб отключается, если программа не выполняется и с приложением не работают"
+
+	voltageEntry := NewNumberSpinner(minVoltageThreshold, maxVoltageThreshold, 50, 0,
+		prefs.FloatWithFallback(settingVoltageThreshold, defaultVoltageThreshold), nil)
+	autoLimitCheck := widget.NewCheck("Ограничивать мощность моторов при просадках", nil)
+	autoLimitCheck.SetChecked(prefs.Bool(settingVoltageAutoLimit))
+	voltageItem := widget.NewFormItem("Просадка напряжения, мВ", container.NewVBox(voltageEntry, autoLimitCheck))
+	voltageItem.HintText = fmt.Sprintf("Если под нагрузкой моторов напряжение %d раза за минуту падает ниже порога, батареи слабые", sagRepeats)
+
+	return settingsSection{
+		title: "Bluetooth",
+		items: []*widget.FormItem{adapterItem, kitCheckItem, idleItem, voltageItem},
+		save: func(prefs fyne.Preferences) {
+			prefs.SetBool(settingOfferKitCheck, kitCheck.Checked)
+			prefs.SetInt(settingIdleMinutes, idleMinuteChoices[idleSelect.SelectedIndex()])
+			prefs.SetBool(settingIdlePowerOff, idlePowerOff.Checked)
+			prefs.SetFloat(settingVoltageThreshold, voltageEntry.Value())
+			prefs.SetBool(settingVoltageAutoLimit, autoLimitCheck.Checked)
+			id := ids[adapterSelect.SelectedIndex()]
+			if err := gui.hubMgr.SelectAdapter(id); err != nil {
+				dialog.ShowError(err, gui.window)
+				return
+			}
+			prefs.SetString(settingBLEAdapter, id)
+		},
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	shareLifetime      = 10 * time.Minute // Сколько времени программа доступна по ссылке
+	shareFetchTimeout  = 10 * time.Second // Сколько ждать ответа при открытии по ссылке
+	shareMaxSize       = 4 << 20          // Максимальный размер загружаемой программы
+	shareQRCodePixels  = 256
+	shareRoutePrefix   = "/program/"
+	shareTokenByteSize = 8
+)
+
+// ProgramShare временный HTTP-сервер в локальной сети, отдающий одну программу
+type ProgramShare struct {
+	server *http.Server
+	url    string
+	timer  *time.Timer
+}
+
+// StartProgramShare начинает раздавать программу по случайной ссылке в локальной сети
+func StartProgramShare(data []byte) (*ProgramShare, error) {
+	ip, err := localNetworkIP()
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, shareTokenByteSize)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	path := shareRoutePrefix + hex.EncodeToString(token) + programFileExtension
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть порт для раздачи: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Программу забирает %s", r.RemoteAddr)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(data)
+	})
+
+	share := &ProgramShare{
+		server: &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second},
+		url:    "http://" + listener.Addr().String() + path,
+	}
+	go func() {
+		if err := share.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Ошибка раздачи программы: %v", err)
+		}
+	}()
+	share.timer = time.AfterFunc(shareLifetime, share.Stop)
+
+	log.Printf("Программа доступна по ссылке %s", share.url)
+	return share, nil
+}
+
+// URL возвращает ссылку на программу
+func (s *ProgramShare) URL() string {
+	return s.url
+}
+
+// Stop прекращает раздачу программы
+func (s *ProgramShare) Stop() {
+	s.timer.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err == nil {
+		log.Println("Раздача программы остановлена")
+	}
+}
+
+// localNetworkIP возвращает IPv4-адрес компьютера в локальной сети
+func localNetworkIP() (net.IP, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				if ip := ipNet.IP.To4(); ip != nil && ip.IsPrivate() {
+					return ip, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("компьютер не подключен к локальной сети")
+}
+
+// fetchSharedProgram скачивает программу по ссылке из локальной сети
+func fetchSharedProgram(link string) ([]byte, error) {
+	if !strings.HasPrefix(link, "http://") {
+		return nil, fmt.Errorf("ссылка должна начинаться с http://")
+	}
+
+	client := &http.Client{Timeout: shareFetchTimeout}
+	resp, err := client.Get(link)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить программу: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("программа по ссылке недоступна (%s)", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, shareMaxSize))
+}
+
+// showShareDialog раздает текущую программу и показывает QR-код со ссылкой
+func (gui *MainGUI) showShareDialog() {
+	data, err := EncodeProgram(gui.programMgr.GetProgram())
+	if err != nil {
+		dialog.ShowError(err, gui.window)
+		return
+	}
+
+	share, err := StartProgramShare(data)
+	if err != nil {
+		dialog.ShowError(err, gui.window)
+		return
+	}
+
+	png, err := qrcode.Encode(share.URL(), qrcode.Medium, shareQRCodePixels)
+	if err != nil {
+		share.Stop()
+		dialog.ShowError(err, gui.window)
+		return
+	}
+	qrImage := canvas.NewImageFromResource(fyne.NewStaticResource("program-qr.png", png))
+	qrImage.FillMode = canvas.ImageFillContain
+	qrImage.SetMinSize(fyne.NewSize(shareQRCodePixels, shareQRCodePixels))
+
+	linkEntry := widget.NewEntry()
+	linkEntry.SetText(share.URL())
+	copyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		gui.window.Clipboard().SetContent(share.URL())
+	})
+
+	content := container.NewVBox(
+		widget.NewLabel("Откройте ссылку на другом компьютере: Программа → Открыть по ссылке"),
+		container.NewCenter(qrImage),
+		container.NewBorder(nil, nil, nil, copyButton, linkEntry),
+		widget.NewLabel(fmt.Sprintf("Ссылка работает %d минут, пока открыто это окно", int(shareLifetime.Minutes()))),
+	)
+
+	d := dialog.NewCustom("Поделиться программой", "Закрыть", content, gui.window)
+	d.SetOnClosed(share.Stop)
+	d.Show()
+}
+
+// showOpenLinkDialog открывает программу, которой поделились по ссылке
+func (gui *MainGUI) showOpenLinkDialog() {
+	if gui.locked {
+		return
+	}
+
+	linkEntry := widget.NewEntry()
+	linkEntry.SetPlaceHolder("http://192.168.1.10:50000/program/...")
+	if text := strings.TrimSpace(gui.window.Clipboard().Content()); strings.HasPrefix(text, "http://") {
+		linkEntry.SetText(text)
+	}
+
+	dialog.ShowForm("Открыть по ссылке", "Открыть", "Отмена",
+		[]*widget.FormItem{widget.NewFormItem("Ссылка", linkEntry)},
+		func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+
+			link := strings.TrimSpace(linkEntry.Text)
+			go func() {
+				data, err := fetchSharedProgram(link)
+				var program *Program
+				if err == nil {
+					program, err = gui.programMgr.DecodeProgram(data)
+				}
+
+				fyne.Do(func() {
+					if err != nil {
+						dialog.ShowError(err, gui.window)
+						return
+					}
+					gui.checkpoint()
+					gui.openProgram(program)
+					gui.currentFile = nil
+					log.Printf("Программа открыта по ссылке %s", link)
+				})
+			}()
+		}, gui.window)
+}
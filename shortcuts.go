@@ -4,12 +4,27 @@ import "fyne.io/fyne/v2"
 
 // setupKeyboardShortcuts настраивает горячие клавиши
 func (gui *MainGUI) setupKeyboardShortcuts() {
-	// Обработка клавиши Delete для удаления выделенного блока
-	gui.window.Canvas().SetOnTypedKey(func(event *fyne.KeyEvent) {
-		if event.Name == fyne.KeyDelete || event.Name == fyne.KeyBackspace {
-			if gui.selectedBlock != nil {
-				gui.deleteSelectedBlock()
-			}
+	gui.window.Canvas().SetOnTypedKey(gui.handleTypedKey)
+}
+
+// handleTypedKey обрабатывает клавиши окна: F11/Escape для презентации,
+// Delete для удаления выделенного блока
+func (gui *MainGUI) handleTypedKey(event *fyne.KeyEvent) {
+	gui.batterySaver.Touch()
+
+	// F11 переключает режим презентации, Escape выходит из него
+	if event.Name == fyne.KeyF11 {
+		gui.togglePresentationMode()
+		return
+	}
+	if event.Name == fyne.KeyEscape && gui.presentationMode {
+		gui.setPresentationMode(false)
+		return
+	}
+
+	if event.Name == fyne.KeyDelete || event.Name == fyne.KeyBackspace {
+		if gui.selectedBlock != nil {
+			gui.deleteSelectedBlock()
 		}
-	})
+	}
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultSpeechKeywords ключевые слова распознавания по умолчанию
+var defaultSpeechKeywords = []string{"вперед", "назад", "стоп", "налево", "направо"}
+
+// normalizeKeyword приводит слово к виду, в котором оно сравнивается с распознанным
+func normalizeKeyword(word string) string {
+	word = strings.ToLower(strings.TrimSpace(word))
+	return strings.ReplaceAll(word, "ё", "е")
+}
+
+// parseKeywords разбирает список ключевых слов, разделенных запятыми
+func parseKeywords(text string) []string {
+	seen := make(map[string]bool)
+	var keywords []string
+
+	for _, part := range strings.Split(text, ",") {
+		keyword := normalizeKeyword(part)
+		if keyword != "" && !seen[keyword] {
+			seen[keyword] = true
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// keywordFromParameters возвращает ключевое слово блока "Когда слышу"
+func keywordFromParameters(params map[string]interface{}) string {
+	if keyword, ok := params["keyword"].(string); ok && normalizeKeyword(keyword) != "" {
+		return normalizeKeyword(keyword)
+	}
+	return defaultSpeechKeywords[0]
+}
+
+// SpeechMonitor распознает ключевые слова с микрофона с помощью офлайн-движка
+// PocketSphinx (pocketsphinx_continuous в режиме поиска ключевых слов)
+// и публикует их в шину событий
+type SpeechMonitor struct {
+	events     *EventBus
+	keywords   []string
+	microphone string
+	modelDir   string
+	cancel     context.CancelFunc
+	run        uint64 // Номер запуска распознавания; завершившийся запуск не трогает следующий
+	mu         sync.Mutex
+
+	crashHandler func()
+}
+
+// NewSpeechMonitor создает монитор распознавания речи
+func NewSpeechMonitor(events *EventBus) *SpeechMonitor {
+	return &SpeechMonitor{
+		events:   events,
+		keywords: defaultSpeechKeywords,
+	}
+}
+
+// SetCrashHandler устанавливает обработчик паники при чтении распознанных слов
+func (sm *SpeechMonitor) SetCrashHandler(handler func()) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.crashHandler = handler
+}
+
+// Configure задает ключевые слова, микрофон (пусто = по умолчанию)
+// и папку с русской акустической моделью (пусто = модель движка)
+func (sm *SpeechMonitor) Configure(keywords []string, microphone, modelDir string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if len(keywords) == 0 {
+		keywords = defaultSpeechKeywords
+	}
+	sm.keywords = keywords
+	sm.microphone = microphone
+	sm.modelDir = modelDir
+}
+
+// Keywords возвращает настроенные ключевые слова
+func (sm *SpeechMonitor) Keywords() []string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return append([]string(nil), sm.keywords...)
+}
+
+// Start запускает распознавание. Слова программы добавляются к настроенным.
+func (sm *SpeechMonitor) Start(extraKeywords []string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.cancel != nil {
+		return nil
+	}
+
+	enginePath, err := exec.LookPath("pocketsphinx_continuous")
+	if err != nil {
+		return fmt.Errorf("для распознавания речи нужна программа pocketsphinx_continuous: %v", err)
+	}
+
+	keywords := parseKeywords(strings.Join(append(sm.keywords, extraKeywords...), ","))
+	keywordFile, err := writeKeywordFile(keywords)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-inmic", "yes", "-kws", keywordFile, "-logfn", os.DevNull}
+	if sm.microphone != "" {
+		args = append(args, "-adcdev", sm.microphone)
+	}
+	if sm.modelDir != "" {
+		args = append(args,
+			"-hmm", filepath.Join(sm.modelDir, "acoustic"),
+			"-dict", filepath.Join(sm.modelDir, "dictionary.dic"),
+		)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, enginePath, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		os.Remove(keywordFile)
+		return fmt.Errorf("ошибка подключения к распознаванию речи: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		os.Remove(keywordFile)
+		return fmt.Errorf("ошибка запуска распознавания речи: %v", err)
+	}
+
+	sm.cancel = cancel
+	sm.run++
+	run := sm.run
+	log.Printf("Распознавание речи запущено, ключевые слова: %s", strings.Join(keywords, ", "))
+
+	crashHandler := sm.crashHandler
+	go func() {
+		if crashHandler != nil {
+			defer crashHandler()
+		}
+		defer os.Remove(keywordFile)
+
+		sm.readHypotheses(stdout, keywords)
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			log.Printf("Распознавание речи завершилось с ошибкой: %v", err)
+		}
+		sm.mu.Lock()
+		if sm.run == run {
+			sm.cancel = nil
+		}
+		sm.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// Stop останавливает распознавание
+func (sm *SpeechMonitor) Stop() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sm.cancel != nil {
+		sm.cancel()
+		sm.cancel = nil
+		log.Println("Распознавание речи остановлено")
+	}
+}
+
+// writeKeywordFile создает файл ключевых слов в формате PocketSphinx
+func writeKeywordFile(keywords []string) (string, error) {
+	file, err := os.CreateTemp("", "wedoprog-keywords-*.list")
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания списка ключевых слов: %v", err)
+	}
+	defer file.Close()
+
+	for _, keyword := range keywords {
+		// Порог обнаружения: меньше значение — меньше ложных срабатываний
+		fmt.Fprintf(file, "%s /1e-20/\n", keyword)
+	}
+	return file.Name(), nil
+}
+
+// readHypotheses читает распознанные фразы и публикует найденные ключевые слова
+func (sm *SpeechMonitor) readHypotheses(r io.Reader, keywords []string) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		heard := normalizeKeyword(scanner.Text())
+		if heard == "" {
+			continue
+		}
+
+		for _, keyword := range keywords {
+			if strings.Contains(heard, keyword) {
+				log.Printf("Услышано ключевое слово: %s", keyword)
+				sm.events.Publish(Event{
+					Type: EventSpeech,
+					Name: keyword,
+				})
+			}
+		}
+	}
+}
+
+// programKeywords возвращает ключевые слова блоков "Когда слышу"
+func (pm *ProgramManager) programKeywords() []string {
+	var keywords []string
+	for _, block := range pm.program.Blocks {
+		if block.Type == BlockTypeWhenHear {
+			keywords = append(keywords, keywordFromParameters(block.Parameters))
+		}
+	}
+	return keywords
+}
+
+// listenForSpeech запускает цепочки "Когда слышу" по распознанным словам.
+// Пока в программе есть такие блоки, она выполняется до нажатия "Стоп".
+func (pm *ProgramManager) listenForSpeech(ctx context.Context, chains *sync.WaitGroup) func() {
+	if len(pm.programKeywords()) == 0 {
+		return func() {}
+	}
+
+	unsubscribe := pm.listenForHats(ctx, chains, EventSpeech, func(block *ProgramBlock, event Event) bool {
+		return block.Type == BlockTypeWhenHear && keywordFromParameters(block.Parameters) == event.Name
+	})
+	holdUntilStopped(ctx, chains)
+
+	return unsubscribe
+}
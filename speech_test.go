@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeSpeechEngine подкладывает в PATH pocketsphinx_continuous, который
+// записывает свой PID в файл и ждет остановки. Возвращает путь к файлу PID
+func fakeSpeechEngine(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("поддельный движок распознавания — сценарий sh")
+	}
+
+	dir := t.TempDir()
+	pids := filepath.Join(dir, "pids")
+	script := "#!/bin/sh\necho $$ >> '" + pids + "'\nexec sleep 30\n"
+	if err := os.WriteFile(filepath.Join(dir, "pocketsphinx_continuous"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return pids
+}
+
+// enginePIDs ждет, пока запустятся count движков, и возвращает их PID
+func enginePIDs(t *testing.T, path string, count int) []int {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		data, _ := os.ReadFile(path)
+		fields := strings.Fields(string(data))
+		if len(fields) >= count {
+			pids := make([]int, len(fields))
+			for i, field := range fields {
+				pids[i], _ = strconv.Atoi(field)
+			}
+			return pids
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("запущено движков %d, ожидалось %d", len(fields), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// processAlive проверяет, что процесс еще работает
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+func TestSpeechMonitorRestartKeepsNewSession(t *testing.T) {
+	pids := fakeSpeechEngine(t)
+	sm := NewSpeechMonitor(NewEventBus())
+
+	if err := sm.Start(nil); err != nil {
+		t.Fatalf("первый Start: %v", err)
+	}
+	enginePIDs(t, pids, 1)
+	sm.Stop()
+	if err := sm.Start(nil); err != nil {
+		t.Fatalf("второй Start: %v", err)
+	}
+	started := enginePIDs(t, pids, 2)
+
+	// Горутина первого запуска завершается после второго Start и не должна
+	// сбросить его остановку
+	deadline := time.Now().Add(3 * time.Second)
+	for processAlive(started[0]) {
+		if time.Now().After(deadline) {
+			t.Fatal("первый движок не остановлен")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	sm.Stop()
+	deadline = time.Now().Add(3 * time.Second)
+	for processAlive(started[1]) {
+		if time.Now().After(deadline) {
+			t.Fatal("после Stop второй движок продолжает работать")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const statusBarRefreshInterval = time.Second
+
+// StatusBar строка состояния внизу окна: подключение, хаб, батарея, программа,
+// очередь команд BLE и последняя ошибка. Каждый индикатор открывает подробности по щелчку
+type StatusBar struct {
+	gui       *MainGUI
+	container *fyne.Container
+
+	connection *widget.Button
+	hub        *widget.Button
+	battery    *widget.Button
+	program    *widget.Button
+	queue      *widget.Button
+	lastError  *widget.Button
+
+	connectionText string
+	loopText       string
+	errorText      string
+	errorTime      time.Time
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewStatusBar создает строку состояния и запускает ее периодическое обновление
+func NewStatusBar(gui *MainGUI) *StatusBar {
+	s := &StatusBar{gui: gui, connectionText: "Не подключено", done: make(chan struct{})}
+
+	s.connection = s.newIndicator(theme.MediaRecordIcon(), gui.showConnectionDetails)
+	s.hub = s.newIndicator(theme.ComputerIcon(), gui.showHubDetails)
+	s.battery = s.newIndicator(iconResource("voltage"), gui.showBatteryDetails)
+	s.program = s.newIndicator(theme.MediaPlayIcon(), gui.showProgramDetails)
+	s.queue = s.newIndicator(theme.UploadIcon(), gui.unlessKiosk(gui.showMetricsDialog))
+	s.lastError = s.newIndicator(theme.ErrorIcon(), s.showErrorDetails)
+
+	s.container = container.NewHBox(
+		s.connection,
+		widget.NewSeparator(),
+		s.hub,
+		s.battery,
+		widget.NewSeparator(),
+		s.program,
+		s.queue,
+		layout.NewSpacer(),
+		s.lastError,
+	)
+
+	s.Refresh()
+	go s.refreshLoop()
+	return s
+}
+
+// newIndicator создает плоскую кнопку-индикатор
+func (s *StatusBar) newIndicator(icon fyne.Resource, onTap func()) *widget.Button {
+	button := widget.NewButtonWithIcon("", icon, onTap)
+	button.Importance = widget.LowImportance
+	return button
+}
+
+// GetContainer возвращает контейнер строки состояния
+func (s *StatusBar) GetContainer() fyne.CanvasObject {
+	return s.container
+}
+
+// SetConnectionText показывает состояние подключения к хабу
+func (s *StatusBar) SetConnectionText(text string) {
+	s.connectionText = text
+	s.Refresh()
+}
+
+// SetLoopProgress показывает итерацию выполняемого цикла, пустая строка скрывает ее
+func (s *StatusBar) SetLoopProgress(text string) {
+	s.loopText = text
+	s.Refresh()
+}
+
+// ReportError показывает ошибку в строке состояния
+func (s *StatusBar) ReportError(err error) {
+	if err == nil {
+		return
+	}
+	s.errorText = err.Error()
+	s.errorTime = time.Now()
+	s.gui.sounds.Play(SoundError)
+	s.Refresh()
+}
+
+// Stop останавливает периодическое обновление; повторный вызов ничего не делает
+func (s *StatusBar) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+// refreshLoop обновляет индикаторы, значения которых меняются без уведомлений, до вызова Stop
+func (s *StatusBar) refreshLoop() {
+	ticker := time.NewTicker(statusBarRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			fyne.Do(s.Refresh)
+		}
+	}
+}
+
+// Refresh перечитывает состояние хаба и программы
+func (s *StatusBar) Refresh() {
+	gui := s.gui
+	connected := gui.hubMgr.IsConnected()
+
+	if connected {
+		s.connection.SetIcon(theme.ConfirmIcon())
+	} else {
+		s.connection.SetIcon(theme.MediaRecordIcon())
+	}
+	s.connection.SetText(s.connectionText)
+
+	if connected {
+		info := gui.hubMgr.GetHubInfo()
+		s.hub.SetText(info.Name)
+		battery := fmt.Sprintf("%d%%", info.Battery)
+		if powerCap := gui.hubMgr.VoltageWatchdog().PowerCap(); powerCap < fullPowerCap {
+			battery += fmt.Sprintf(", моторы до %d%%", powerCap)
+		}
+		s.battery.SetText(battery)
+		s.hub.Show()
+		s.battery.Show()
+	} else {
+		s.hub.Hide()
+		s.battery.Hide()
+	}
+
+	switch gui.programMgr.GetProgramState() {
+	case ProgramStateRunning:
+		if s.loopText != "" {
+			s.program.SetText("Выполняется, " + s.loopText)
+		} else {
+			s.program.SetText("Выполняется")
+		}
+		s.program.SetIcon(theme.MediaPlayIcon())
+	case ProgramStateError:
+		s.program.SetText("Ошибка")
+		s.program.SetIcon(theme.ErrorIcon())
+	default:
+		s.program.SetText("Остановлена")
+		s.program.SetIcon(theme.MediaStopIcon())
+	}
+
+	s.queue.SetText(fmt.Sprintf("Очередь BLE: %d", gui.hubMgr.PendingWrites()))
+
+	// Ошибка выполнения программы новее показанной заменяет ее
+	if at, err := gui.programMgr.LastError(); err != nil && at.After(s.errorTime) {
+		s.errorText = err.Error()
+		s.errorTime = at
+	}
+	if s.errorText == "" {
+		s.lastError.Hide()
+	} else {
+		s.lastError.SetText(truncateText(s.errorText, 48))
+		s.lastError.Show()
+	}
+}
+
+// truncateText обрезает текст до limit символов, добавляя многоточие
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// showErrorDetails показывает полный текст последней ошибки
+func (s *StatusBar) showErrorDetails() {
+	if s.errorText == "" {
+		return
+	}
+	message := widget.NewLabel(fmt.Sprintf("%s\n\nВремя: %s", s.errorText, s.errorTime.Format("15:04:05")))
+	message.Wrapping = fyne.TextWrapWord
+
+	d := dialog.NewCustom("Последняя ошибка", "Закрыть", message, s.gui.window)
+	d.Resize(fyne.NewSize(420, 200))
+	d.Show()
+}
+
+// showConnectionDetails предлагает подключиться или показывает сведения о хабе
+func (gui *MainGUI) showConnectionDetails() {
+	if !gui.hubMgr.IsConnected() {
+		gui.showHubDiscoveryDialog()
+		return
+	}
+	gui.showHubDetails()
+}
+
+// showHubDetails показывает сведения о подключенном хабе
+func (gui *MainGUI) showHubDetails() {
+	info := gui.hubMgr.GetHubInfo()
+	dialog.ShowInformation("Хаб", fmt.Sprintf(
+		"Имя: %s\nАдрес: %s\nПрошивка: %s\nПО: %s\nУстройств: %d",
+		info.Name, info.Address, info.FirmwareVersion, info.SoftwareVersion, len(gui.deviceMgr.GetConnectedDevices())),
+		gui.window)
+}
+
+// showBatteryDetails показывает заряд батареи хаба
+func (gui *MainGUI) showBatteryDetails() {
+	battery := gui.hubMgr.GetHubInfo().Battery
+	text := fmt.Sprintf("Заряд батареи хаба: %d%%", battery)
+	if battery < 20 {
+		text += "\n\nЗамените батареи: при низком заряде моторы работают слабее, а связь может прерываться."
+	}
+	if powerCap := gui.hubMgr.VoltageWatchdog().PowerCap(); powerCap < fullPowerCap {
+		text += fmt.Sprintf("\n\nИз-за просадок напряжения мощность моторов ограничена до %d%%. "+
+			"Ограничение снимется после замены батарей и переподключения хаба.", powerCap)
+	}
+	dialog.ShowInformation("Батарея", text, gui.window)
+}
+
+// showProgramDetails показывает состояние и размер программы
+func (gui *MainGUI) showProgramDetails() {
+	program := gui.programMgr.GetProgram()
+	var state string
+	switch gui.programMgr.GetProgramState() {
+	case ProgramStateRunning:
+		state = "выполняется"
+	case ProgramStateError:
+		state = "остановлена с ошибкой"
+	default:
+		state = "остановлена"
+	}
+
+	text := fmt.Sprintf("Программа: %s\nСостояние: %s\nБлоков: %d\nСоединений: %d",
+		program.Name, state, len(program.Blocks), len(program.Connections))
+	if at, err := gui.programMgr.LastError(); err != nil {
+		text += fmt.Sprintf("\n\nПоследняя ошибка (%s):\n%v", at.Format("15:04:05"), err)
+	}
+	dialog.ShowInformation("Программа", text, gui.window)
+}
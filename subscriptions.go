@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	tinybluetooth "tinygo.org/x/bluetooth"
+)
+
+// subscription подписка на уведомления характеристики
+type subscription struct {
+	name    string
+	handler func(data []byte)
+	// char характеристика, на которой уведомления включены сейчас; nil, если подписка не активна
+	char *tinybluetooth.DeviceCharacteristic
+}
+
+// SubscriptionManager ведет все подписки на уведомления хаба.
+// Зарегистрированные подписки переживают отключение и включаются снова при следующем подключении
+type SubscriptionManager struct {
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+	// characteristics характеристики текущего подключения; nil, если хаб не подключен
+	characteristics map[string]tinybluetooth.DeviceCharacteristic
+}
+
+// NewSubscriptionManager создает пустой менеджер подписок
+func NewSubscriptionManager() *SubscriptionManager {
+	return &SubscriptionManager{
+		subscriptions: make(map[string]*subscription),
+	}
+}
+
+// Subscribe регистрирует обработчик уведомлений характеристики и включает его, если хаб подключен
+func (sm *SubscriptionManager) Subscribe(uuid, name string, handler func(data []byte)) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if sub, exists := sm.subscriptions[uuid]; exists {
+		sm.disable(uuid, sub)
+	}
+
+	sub := &subscription{name: name, handler: handler}
+	sm.subscriptions[uuid] = sub
+
+	if sm.characteristics == nil {
+		return nil
+	}
+	return sm.enable(uuid, sub)
+}
+
+// Unsubscribe отключает уведомления характеристики и забывает обработчик
+func (sm *SubscriptionManager) Unsubscribe(uuid string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sub, exists := sm.subscriptions[uuid]
+	if !exists {
+		return
+	}
+	sm.disable(uuid, sub)
+	delete(sm.subscriptions, uuid)
+}
+
+// Attach включает все зарегистрированные подписки на характеристиках нового подключения
+func (sm *SubscriptionManager) Attach(characteristics map[string]tinybluetooth.DeviceCharacteristic) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.characteristics = characteristics
+	for uuid, sub := range sm.subscriptions {
+		if err := sm.enable(uuid, sub); err != nil {
+			log.Printf("Ошибка подписки (%s): %v", sub.name, err)
+		}
+	}
+}
+
+// Teardown отключает все активные уведомления при отключении от хаба.
+// Обработчики остаются зарегистрированными для следующего подключения
+func (sm *SubscriptionManager) Teardown() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for uuid, sub := range sm.subscriptions {
+		sm.disable(uuid, sub)
+	}
+	sm.characteristics = nil
+}
+
+// IsActive сообщает, включены ли сейчас уведомления характеристики
+func (sm *SubscriptionManager) IsActive(uuid string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sub, exists := sm.subscriptions[uuid]
+	return exists && sub.char != nil
+}
+
+// enable включает уведомления для подписки на текущем подключении
+func (sm *SubscriptionManager) enable(uuid string, sub *subscription) error {
+	char, exists := sm.characteristics[uuid]
+	if !exists {
+		return fmt.Errorf("характеристика %s не найдена", uuid)
+	}
+
+	if err := char.EnableNotifications(sub.handler); err != nil {
+		return err
+	}
+	sub.char = &char
+	log.Printf("Подписка на %s установлена", sub.name)
+	return nil
+}
+
+// disable выключает уведомления подписки, если они были включены
+func (sm *SubscriptionManager) disable(uuid string, sub *subscription) {
+	if sub.char == nil {
+		return
+	}
+	if err := sub.char.EnableNotifications(nil); err != nil {
+		log.Printf("Ошибка отписки от %s (%s): %v", sub.name, uuid, err)
+	}
+	sub.char = nil
+}
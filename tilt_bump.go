@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// В режиме удара датчик наклона присылает счетчик толчков, который растет с
+// каждым сотрясением. Прирост счетчика за короткое окно превращается в
+// событие «удар», несколько ударов подряд — в событие «тряска». События
+// идут по шине, на них запускаются цепочки «Когда удар» и реагирует окно
+// наблюдения
+
+// События датчика наклона в режиме удара
+const (
+	TiltEventBump  = "bump"  // Одиночный удар
+	TiltEventShake = "shake" // Тряска: несколько ударов подряд
+)
+
+const (
+	settingBumpSensitivity = "bump_sensitivity"
+	defaultBumpSensitivity = 3                      // Чувствительность по умолчанию
+	maxBumpSensitivity     = 5                      // 1 — только сильные удары, 5 — легкие толчки
+	bumpWindow             = 300 * time.Millisecond // Окно, за которое складывается прирост счетчика
+	bumpCooldown           = 250 * time.Millisecond // Наименьший промежуток между ударами
+	shakeWindow            = time.Second            // Окно, в которое должны попасть удары тряски
+	shakeBumps             = 3                      // Сколько ударов в окне считается тряской
+)
+
+// tiltEventNames названия событий для интерфейса
+var tiltEventNames = map[string]string{
+	TiltEventBump:  "Удар",
+	TiltEventShake: "Тряска",
+}
+
+// bumpPortState состояние счетчика толчков на порту
+type bumpPortState struct {
+	counter     float64
+	accumulated float64
+	windowStart time.Time
+	lastBump    time.Time
+	recent      []time.Time // Удары последней секунды для определения тряски
+}
+
+// BumpDetector переводит счетчик толчков датчика наклона в события удара и тряски
+type BumpDetector struct {
+	mu          sync.Mutex
+	sensitivity int
+	ports       map[byte]*bumpPortState
+	onEvent     func(portID byte, kind string, strength float64)
+}
+
+// NewBumpDetector создает детектор; onEvent вызывается для каждого удара и тряски
+func NewBumpDetector(onEvent func(portID byte, kind string, strength float64)) *BumpDetector {
+	return &BumpDetector{
+		sensitivity: defaultBumpSensitivity,
+		ports:       make(map[byte]*bumpPortState),
+		onEvent:     onEvent,
+	}
+}
+
+// SetSensitivity задает чувствительность от 1 до maxBumpSensitivity
+func (d *BumpDetector) SetSensitivity(sensitivity int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sensitivity = int(clamp(float64(sensitivity), 1, maxBumpSensitivity))
+}
+
+// threshold прирост счетчика за окно, который считается ударом
+func (d *BumpDetector) threshold() float64 {
+	return float64(maxBumpSensitivity + 1 - d.sensitivity)
+}
+
+// Update учитывает новое значение счетчика толчков на порту
+func (d *BumpDetector) Update(portID byte, counter float64, now time.Time) {
+	type bumpEvent struct {
+		kind     string
+		strength float64
+	}
+	var events []bumpEvent
+
+	d.mu.Lock()
+	state, ok := d.ports[portID]
+	if !ok {
+		// Первое значение только запоминается: счетчик мог накопиться раньше
+		d.ports[portID] = &bumpPortState{counter: counter}
+		d.mu.Unlock()
+		return
+	}
+
+	delta := counter - state.counter
+	state.counter = counter
+	// Счетчик уменьшается после переполнения или сброса датчика
+	if delta > 0 {
+		if now.Sub(state.windowStart) > bumpWindow {
+			state.windowStart = now
+			state.accumulated = 0
+		}
+		state.accumulated += delta
+
+		if state.accumulated >= d.threshold() && now.Sub(state.lastBump) >= bumpCooldown {
+			events = append(events, bumpEvent{TiltEventBump, state.accumulated})
+			state.accumulated = 0
+			state.lastBump = now
+
+			recent := state.recent[:0]
+			for _, at := range state.recent {
+				if now.Sub(at) < shakeWindow {
+					recent = append(recent, at)
+				}
+			}
+			state.recent = append(recent, now)
+			if len(state.recent) >= shakeBumps {
+				events = append(events, bumpEvent{TiltEventShake, float64(len(state.recent))})
+				state.recent = nil
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	if d.onEvent != nil {
+		for _, event := range events {
+			d.onEvent(portID, event.kind, event.strength)
+		}
+	}
+}
+
+// Forget сбрасывает счетчик порта
+func (d *BumpDetector) Forget(portID byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.ports, portID)
+}
+
+// Reset сбрасывает счетчики всех портов
+func (d *BumpDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ports = make(map[byte]*bumpPortState)
+}
+
+// BumpDetector возвращает детектор ударов датчика наклона
+func (hm *HubManager) BumpDetector() *BumpDetector {
+	return hm.bumps
+}
+
+// publishTiltBump публикует удар или тряску датчика наклона
+func (hm *HubManager) publishTiltBump(portID byte, kind string, strength float64) {
+	log.Printf("Датчик наклона на порту %d: %s (сила %g)", portID, tiltEventNames[kind], strength)
+	hm.events.Publish(Event{
+		Type:   EventTiltBump,
+		PortID: portID,
+		Name:   kind,
+		Value:  strength,
+	})
+}
+
+// WhenBumpedParams параметры блока «Когда удар»
+type WhenBumpedParams struct {
+	Port  byte
+	Event string
+}
+
+// whenBumpedFromParameters читает параметры блока «Когда удар»
+func whenBumpedFromParameters(params map[string]interface{}) WhenBumpedParams {
+	return WhenBumpedParams{
+		Port:  paramByte(params, "port", 1),
+		Event: paramString(params, "event", TiltEventBump),
+	}
+}
+
+// Store записывает параметры блока «Когда удар»
+func (p WhenBumpedParams) Store(params map[string]interface{}) {
+	params["port"] = p.Port
+	params["event"] = p.Event
+}
+
+// String возвращает текстовое описание события
+func (p WhenBumpedParams) String() string {
+	return fmt.Sprintf("%s датчика наклона на порту %d", tiltEventNames[p.Event], p.Port)
+}
+
+// bumpPorts возвращает порты датчиков наклона, на удары которых реагирует программа
+func (pm *ProgramManager) bumpPorts() []byte {
+	seen := make(map[byte]bool)
+	var ports []byte
+	for _, block := range pm.program.Blocks {
+		if block.Type != BlockTypeWhenBumped {
+			continue
+		}
+		port := whenBumpedFromParameters(block.Parameters).Port
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// listenForBumps переводит датчики наклона в режим удара и запускает цепочки
+// «Когда удар». Пока в программе есть такие блоки, она выполняется до нажатия
+// "Стоп"; после остановки датчики возвращаются в режим наклона
+func (pm *ProgramManager) listenForBumps(ctx context.Context, chains *sync.WaitGroup) func() {
+	ports := pm.bumpPorts()
+	if len(ports) == 0 {
+		return func() {}
+	}
+
+	for _, port := range ports {
+		if err := pm.hubMgr.ConfigurePort(NewInputFormatCommand(port, DEVICE_TYPE_TILT_SENSOR).Mode(TILT_CRASH_MODE)); err != nil {
+			log.Printf("Не удалось включить режим удара на порту %d: %v", port, err)
+		}
+	}
+
+	unsubscribe := pm.listenForHats(ctx, chains, EventTiltBump, func(block *ProgramBlock, event Event) bool {
+		if block.Type != BlockTypeWhenBumped {
+			return false
+		}
+		params := whenBumpedFromParameters(block.Parameters)
+		return params.Port == event.PortID && params.Event == event.Name
+	})
+	holdUntilStopped(ctx, chains)
+
+	return func() {
+		unsubscribe()
+		for _, port := range ports {
+			if err := pm.hubMgr.ConfigurePort(NewInputFormatCommand(port, DEVICE_TYPE_TILT_SENSOR).Mode(TILT_TILT_MODE)); err != nil {
+				log.Printf("Не удалось вернуть режим наклона на порту %d: %v", port, err)
+			}
+		}
+	}
+}
+
+// bumpPortConflicts находит датчики наклона, которые программа читает и как
+// наклон, и как удары: в режиме удара датчик не сообщает наклон
+func (pm *ProgramManager) bumpPortConflicts() []ValidationIssue {
+	bumpPorts := make(map[byte]bool)
+	for _, port := range pm.bumpPorts() {
+		bumpPorts[port] = true
+	}
+
+	var issues []ValidationIssue
+	for _, block := range pm.program.Blocks {
+		if block.Type == BlockTypeWhenBumped {
+			continue
+		}
+		for _, required := range blockRequiredDevices(block) {
+			if required.deviceType == DEVICE_TYPE_TILT_SENSOR && bumpPorts[required.port] {
+				issues = append(issues, ValidationIssue{
+					BlockID: block.ID,
+					Message: fmt.Sprintf("%s: датчик наклона на порту %d следит за ударами и не сообщает наклон",
+						block.Title, required.port),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// addWhenBumpedControls добавляет элементы управления для блока «Когда удар»
+func (e *BlockEditor) addWhenBumpedControls(cont *fyne.Container) {
+	params := whenBumpedFromParameters(e.block.Parameters)
+	params.Store(e.block.Parameters)
+
+	portSelect := widget.NewSelect([]string{portName(1), portName(2)}, func(selected string) {
+		for _, port := range []byte{1, 2} {
+			if portName(port) == selected {
+				e.block.Parameters["port"] = port
+			}
+		}
+		e.notifyChange()
+	})
+	portSelect.SetSelected(portName(params.Port))
+
+	kinds := []string{TiltEventBump, TiltEventShake}
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = tiltEventNames[kind]
+	}
+	eventSelect := widget.NewRadioGroup(names, func(selected string) {
+		for _, kind := range kinds {
+			if tiltEventNames[kind] == selected {
+				e.block.Parameters["event"] = kind
+			}
+		}
+		e.notifyChange()
+	})
+	eventSelect.Horizontal = true
+	eventSelect.Required = true
+	eventSelect.SetSelected(tiltEventNames[params.Event])
+
+	cont.Add(widget.NewLabel("Датчик наклона:"))
+	cont.Add(portSelect)
+	cont.Add(widget.NewLabel("Событие:"))
+	cont.Add(eventSelect)
+	cont.Add(widget.NewLabel("Тряска — несколько ударов за секунду.\nЧувствительность задается в настройках."))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Направления датчика наклона в режиме определения наклона
+const (
+	TiltDirectionNeutral  = 0
+	TiltDirectionBackward = 3
+	TiltDirectionRight    = 5
+	TiltDirectionLeft     = 7
+	TiltDirectionForward  = 9
+)
+
+const (
+	tiltDirectionAngle = 45.0 // Угол, которым показывается направление наклона, градусы
+	cubeViewYaw        = -30.0
+	cubeViewElevation  = 20.0
+	cubeMinSize        = 160
+)
+
+// tiltAngles углы наклона датчика: X — влево-вправо, Y — вперед-назад, градусы
+type tiltAngles struct {
+	X, Y   float64
+	Approx bool // Углы выведены из направления, а не измерены
+}
+
+// tiltDirectionAngles переводит направление наклона в примерные углы
+func tiltDirectionAngles(direction float64) tiltAngles {
+	angles := tiltAngles{Approx: true}
+	switch int(direction) {
+	case TiltDirectionBackward:
+		angles.Y = -tiltDirectionAngle
+	case TiltDirectionRight:
+		angles.X = tiltDirectionAngle
+	case TiltDirectionLeft:
+		angles.X = -tiltDirectionAngle
+	case TiltDirectionForward:
+		angles.Y = tiltDirectionAngle
+	}
+	return angles
+}
+
+// isTiltPortInMode проверяет, что на порту датчик наклона в режиме mode.
+// Ненастроенный датчик считается в режиме определения наклона, как после подключения
+func (hm *HubManager) isTiltPortInMode(portID byte, mode byte) bool {
+	device, ok := hm.devices.GetDevice(portID)
+	if !ok || device.DeviceType != DEVICE_TYPE_TILT_SENSOR {
+		return false
+	}
+	current, configured := hm.portModes.Mode(portID)
+	if !configured {
+		return mode == TILT_TILT_MODE
+	}
+	return current == mode
+}
+
+// setTiltAngles запоминает положение датчика наклона
+func (hm *HubManager) setTiltAngles(portID byte, angles tiltAngles) {
+	hm.sensorMu.Lock()
+	defer hm.sensorMu.Unlock()
+	hm.tiltAngles[portID] = angles
+}
+
+// TiltAngles возвращает последнее положение датчика наклона на порту
+func (hm *HubManager) TiltAngles(portID byte) (tiltAngles, bool) {
+	hm.sensorMu.RLock()
+	defer hm.sensorMu.RUnlock()
+	angles, ok := hm.tiltAngles[portID]
+	return angles, ok
+}
+
+// OrientationCube куб, повернутый как датчик наклона: верхняя грань выделена
+// цветом, поэтому видно, куда наклонен датчик
+type OrientationCube struct {
+	widget.BaseWidget
+	angles tiltAngles
+}
+
+// NewOrientationCube создает куб в нейтральном положении
+func NewOrientationCube() *OrientationCube {
+	c := &OrientationCube{}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetAngles поворачивает куб
+func (c *OrientationCube) SetAngles(angles tiltAngles) {
+	if c.angles == angles {
+		return
+	}
+	c.angles = angles
+	c.Refresh()
+}
+
+// CreateRenderer создает рендерер куба
+func (c *OrientationCube) CreateRenderer() fyne.WidgetRenderer {
+	r := &orientationCubeRenderer{cube: c}
+	for i := range r.edges {
+		r.edges[i] = canvas.NewLine(color.Transparent)
+		r.objects = append(r.objects, r.edges[i])
+	}
+	r.applyTheme()
+	return r
+}
+
+// cubeEdges ребра куба: пары вершин, номера которых различаются одним битом
+var cubeEdges = func() [][2]int {
+	var edges [][2]int
+	for a := 0; a < 8; a++ {
+		for bit := 1; bit < 8; bit <<= 1 {
+			if b := a | bit; b != a {
+				edges = append(edges, [2]int{a, b})
+			}
+		}
+	}
+	return edges
+}()
+
+// cubeVertex вершина куба по номеру: биты задают знаки координат x, y, z
+func cubeVertex(i int) (x, y, z float64) {
+	sign := func(bit int) float64 {
+		if i&bit != 0 {
+			return 1
+		}
+		return -1
+	}
+	return sign(1), sign(2), sign(4)
+}
+
+// rotate поворачивает точку в плоскости на угол в градусах
+func rotate(a, b, degrees float64) (float64, float64) {
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	return a*cos - b*sin, a*sin + b*cos
+}
+
+// orientationCubeRenderer рисует куб двенадцатью ребрами в изометрии
+type orientationCubeRenderer struct {
+	cube    *OrientationCube
+	edges   [12]*canvas.Line
+	objects []fyne.CanvasObject
+}
+
+func (r *orientationCubeRenderer) Layout(size fyne.Size) {
+	angles := r.cube.angles
+	scale := float64(fyne.Min(size.Width, size.Height)) / 4
+	center := fyne.NewPos(size.Width/2, size.Height/2)
+
+	// Наклон вперед опускает ближнюю грань, наклон вправо — правую; затем куб
+	// поворачивается к зрителю и наклоняется, чтобы была видна верхняя грань
+	project := func(i int) fyne.Position {
+		x, y, z := cubeVertex(i)
+		y, z = rotate(y, z, angles.Y)
+		y, x = rotate(y, x, angles.X)
+		x, z = rotate(x, z, cubeViewYaw)
+		y, z = rotate(y, z, cubeViewElevation)
+		return fyne.NewPos(center.X+float32(x*scale), center.Y-float32(y*scale))
+	}
+
+	for i, edge := range cubeEdges {
+		r.edges[i].Position1 = project(edge[0])
+		r.edges[i].Position2 = project(edge[1])
+	}
+}
+
+func (r *orientationCubeRenderer) MinSize() fyne.Size {
+	return fyne.NewSquareSize(cubeMinSize)
+}
+
+// applyTheme окрашивает ребра цветами темы
+func (r *orientationCubeRenderer) applyTheme() {
+	for i, edge := range cubeEdges {
+		_, y1, _ := cubeVertex(edge[0])
+		_, y2, _ := cubeVertex(edge[1])
+		line := r.edges[i]
+		line.StrokeColor = theme.Color(theme.ColorNameForeground)
+		line.StrokeWidth = 2
+		if y1 > 0 && y2 > 0 {
+			line.StrokeColor = theme.Color(theme.ColorNamePrimary)
+			line.StrokeWidth = 4
+		}
+	}
+}
+
+func (r *orientationCubeRenderer) Refresh() {
+	r.applyTheme()
+	r.Layout(r.cube.Size())
+	for _, obj := range r.objects {
+		obj.Refresh()
+	}
+}
+
+func (r *orientationCubeRenderer) Destroy() {}
+
+func (r *orientationCubeRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+// newTiltOrientationView создает куб положения датчика наклона для окна
+// устройства и функцию, которая обновляет его по последним углам
+func (gui *MainGUI) newTiltOrientationView(portID byte) (fyne.CanvasObject, func()) {
+	cube := NewOrientationCube()
+	label := widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{})
+
+	update := func() {
+		angles, ok := gui.hubMgr.TiltAngles(portID)
+		switch {
+		case !ok:
+			label.SetText("нет данных")
+		case angles.Approx:
+			label.SetText("Примерно, по направлению наклона.\nТочные углы — в режиме «Угол наклона»")
+		default:
+			label.SetText(fmt.Sprintf("Влево-вправо: %.0f°, вперед-назад: %.0f°", angles.X, angles.Y))
+		}
+		cube.SetAngles(angles)
+	}
+	update()
+
+	return container.NewVBox(container.NewCenter(cube), label), update
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	timelinePixelsPerSecond = 120 // Масштаб шкалы
+	timelineTrackHeight     = 28  // Высота дорожки устройства
+	timelineMarkerWidth     = 6   // Ширина отметки мгновенной команды светодиода
+)
+
+// ChainSchedule расписание одной стартовой цепочки или причина, по которой его нет
+type ChainSchedule struct {
+	Start    *ProgramBlock
+	Schedule *Schedule
+	Err      error
+}
+
+// CompileProgram рассчитывает расписания всех стартовых цепочек с текущей скоростью выполнения
+func (pm *ProgramManager) CompileProgram() []ChainSchedule {
+	var chains []ChainSchedule
+	for _, start := range pm.startBlocks() {
+		chain := ChainSchedule{Start: start}
+		sequence, err := pm.buildSequence(start)
+		if err == nil {
+			chain.Schedule, err = compileSchedule(sequence, pm.scaleWait)
+		}
+		chain.Err = err
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// timelineTrack дорожка шкалы: одно устройство на одном порту
+type timelineTrack struct {
+	kind ScheduleKind
+	port byte
+}
+
+// name название дорожки
+func (t timelineTrack) name() string {
+	switch t.kind {
+	case ScheduleMotor:
+		return fmt.Sprintf("Мотор, порт %d", t.port)
+	case ScheduleLED:
+		return fmt.Sprintf("Светодиод, порт %d", t.port)
+	default:
+		return fmt.Sprintf("Звук, порт %d", t.port)
+	}
+}
+
+// showTimelinePreview показывает, когда и сколько будут работать моторы,
+// светодиоды и звуки, не запуская программу на хабе
+func (gui *MainGUI) showTimelinePreview() {
+	window := fyne.CurrentApp().NewWindow("Временная шкала программы")
+
+	content := container.NewVBox()
+	chains := gui.programMgr.CompileProgram()
+	if len(chains) == 0 {
+		content.Add(widget.NewLabel("В программе нет блоков для выполнения"))
+	}
+	for _, chain := range chains {
+		title := fmt.Sprintf("Цепочка с блока «%s» (ID: %d)", chain.Start.Title, chain.Start.ID)
+		if chain.Err != nil {
+			reason := widget.NewLabel("Время нельзя рассчитать заранее: " + chain.Err.Error())
+			reason.Wrapping = fyne.TextWrapWord
+			content.Add(widget.NewCard(title, "", reason))
+			continue
+		}
+		subtitle := fmt.Sprintf("Длительность %.1f с", chain.Schedule.Length.Seconds())
+		content.Add(widget.NewCard(title, subtitle, newScheduleTimeline(chain.Schedule)))
+	}
+
+	window.SetContent(container.NewScroll(content))
+	window.Resize(fyne.NewSize(900, 500))
+	window.Show()
+}
+
+// newScheduleTimeline рисует дорожки устройств с отрезками работы и шкалой времени
+func newScheduleTimeline(schedule *Schedule) fyne.CanvasObject {
+	entries := make(map[timelineTrack][]ScheduleEntry)
+	var tracks []timelineTrack
+	for _, entry := range schedule.Entries {
+		if entry.Kind == ScheduleLoop || entry.Kind == ScheduleBlock {
+			continue
+		}
+		track := timelineTrack{entry.Kind, entry.Port}
+		if _, exists := entries[track]; !exists {
+			tracks = append(tracks, track)
+		}
+		entries[track] = append(entries[track], entry)
+	}
+	if len(tracks) == 0 {
+		return widget.NewLabel("Цепочка не управляет устройствами")
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		if tracks[i].kind != tracks[j].kind {
+			return tracks[i].kind < tracks[j].kind
+		}
+		return tracks[i].port < tracks[j].port
+	})
+
+	// Хвост после последней команды, чтобы было видно ее конец
+	width := timelineX(schedule.Length) + timelinePixelsPerSecond/2
+
+	names := container.NewVBox(widget.NewLabel(""))
+	rows := container.NewVBox(newTimelineRuler(schedule.Length, width))
+	for _, track := range tracks {
+		names.Add(container.NewGridWrap(fyne.NewSize(140, timelineTrackHeight), widget.NewLabel(track.name())))
+		rows.Add(newTimelineTrackRow(entries[track], schedule.Length, width))
+	}
+	return container.NewBorder(nil, nil, names, nil, container.NewHScroll(rows))
+}
+
+// timelineX координата момента на шкале
+func timelineX(at time.Duration) float32 {
+	return float32(at.Seconds() * timelinePixelsPerSecond)
+}
+
+// newTimelineRuler шкала с отметками секунд
+func newTimelineRuler(length time.Duration, width float32) fyne.CanvasObject {
+	ruler := container.NewWithoutLayout()
+	for second := 0; time.Duration(second)*time.Second <= length+time.Second/2; second++ {
+		x := timelineX(time.Duration(second) * time.Second)
+		tick := canvas.NewLine(theme.Color(theme.ColorNameDisabled))
+		tick.Position1 = fyne.NewPos(x, timelineTrackHeight-6)
+		tick.Position2 = fyne.NewPos(x, timelineTrackHeight)
+		label := canvas.NewText(fmt.Sprintf("%d с", second), theme.Color(theme.ColorNameForeground))
+		label.TextSize = theme.CaptionTextSize()
+		label.Move(fyne.NewPos(x+2, 0))
+		ruler.Add(tick)
+		ruler.Add(label)
+	}
+	return container.NewGridWrap(fyne.NewSize(width, timelineTrackHeight), ruler)
+}
+
+// newTimelineTrackRow дорожка устройства: отрезки работы мотора и звука, отметки цвета светодиода
+func newTimelineTrackRow(entries []ScheduleEntry, length time.Duration, width float32) fyne.CanvasObject {
+	row := container.NewWithoutLayout()
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	background.Resize(fyne.NewSize(width, timelineTrackHeight-4))
+	row.Add(background)
+
+	for i, entry := range entries {
+		var fill color.Color = theme.Color(theme.ColorNamePrimary)
+		label := ""
+		barWidth := timelineX(entry.Duration)
+
+		switch entry.Kind {
+		case ScheduleMotor:
+			label = fmt.Sprintf("%d%%", entry.Power)
+			if entry.Duration == 0 {
+				// Мотор без длительности работает до следующей команды или до конца программы
+				end := length
+				if i+1 < len(entries) {
+					end = entries[i+1].At
+				}
+				barWidth = timelineX(end - entry.At)
+				label += ", до остановки"
+			}
+			if entry.Power == 0 {
+				fill = theme.Color(theme.ColorNameDisabled)
+			}
+		case ScheduleLED:
+			fill = color.NRGBA{R: entry.Red, G: entry.Green, B: entry.Blue, A: 255}
+			barWidth = timelineMarkerWidth
+		case ScheduleSound:
+			fill = theme.Color(theme.ColorNameWarning)
+			label = fmt.Sprintf("%d Гц", entry.Frequency)
+		}
+		if barWidth < timelineMarkerWidth {
+			barWidth = timelineMarkerWidth
+		}
+
+		bar := canvas.NewRectangle(fill)
+		bar.StrokeColor = theme.Color(theme.ColorNameForeground)
+		bar.StrokeWidth = 0.5
+		bar.CornerRadius = 3
+		bar.Move(fyne.NewPos(timelineX(entry.At), 2))
+		bar.Resize(fyne.NewSize(barWidth, timelineTrackHeight-8))
+		row.Add(bar)
+
+		if label != "" {
+			text := canvas.NewText(label, theme.Color(theme.ColorNameForeground))
+			text.TextSize = theme.CaptionTextSize()
+			if text.MinSize().Width+4 < barWidth {
+				text.Move(fyne.NewPos(timelineX(entry.At)+4, 4))
+				row.Add(text)
+			}
+		}
+	}
+	return container.NewGridWrap(fyne.NewSize(width, timelineTrackHeight), row)
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -15,11 +17,14 @@ import (
 type Toolbar struct {
 	gui          *MainGUI
 	container    *fyne.Container
-	runButton    *widget.Button
+	runButton    *TooltipButton
 	stopButton   *widget.Button
+	speedSlider  *widget.Slider
 	saveButton   *widget.Button
 	loadButton   *widget.Button
 	exportButton *widget.Button
+	clearButton  *widget.Button
+	lockButton   *widget.Button
 }
 
 // NewToolbar создает новую панель инструментов
@@ -40,13 +45,27 @@ func (t *Toolbar) GetContainer() fyne.CanvasObject {
 // UpdateState обновляет состояние кнопок панели инструментов
 func (t *Toolbar) UpdateState(isConnected bool, hasProgram bool) {
 	if t.runButton != nil && t.stopButton != nil {
-		if isConnected {
-			t.runButton.Enable()
+		if isConnected || t.gui.programMgr.SensorReplay() != nil {
 			t.stopButton.Enable()
 		} else {
-			t.runButton.Disable()
 			t.stopButton.Disable()
 		}
+		t.updateRunButton()
+	}
+
+	// В режиме просмотра нельзя загружать и очищать программу,
+	// программу только для запуска нельзя очистить
+	if t.loadButton != nil && t.clearButton != nil {
+		if t.gui.locked {
+			t.loadButton.Disable()
+		} else {
+			t.loadButton.Enable()
+		}
+		if t.gui.readOnly() {
+			t.clearButton.Disable()
+		} else {
+			t.clearButton.Enable()
+		}
 	}
 
 	if t.saveButton != nil && t.exportButton != nil {
@@ -80,12 +99,13 @@ func (t *Toolbar) buildUI() *fyne.Container {
 	disconnectButton.Disable()
 
 	// Кнопки управления программой
-	t.runButton = widget.NewButtonWithIcon("Запуск", theme.MediaPlayIcon(), func() {
+	t.runButton = NewTooltipButton("Запуск", theme.MediaPlayIcon(), func() {
 		if t.gui != nil && t.gui.programMgr != nil {
 			log.Println("Запуск программы...")
 			err := t.gui.programMgr.RunProgram()
 			if err != nil {
 				log.Printf("Ошибка запуска программы: %v", err)
+				t.gui.statusBar.ReportError(err)
 				dialog.ShowError(err, t.gui.window)
 			} else {
 				log.Println("Программа успешно запущена")
@@ -104,15 +124,30 @@ func (t *Toolbar) buildUI() *fyne.Container {
 	t.stopButton.Importance = widget.MediumImportance
 	t.stopButton.Disable()
 
+	// Скорость выполнения для замедленного показа программы
+	speedLabel := widget.NewLabel("")
+	t.speedSlider = widget.NewSlider(minRunSpeed, maxRunSpeed)
+	t.speedSlider.Step = 0.25
+	t.speedSlider.OnChanged = func(speed float64) {
+		speedLabel.SetText(fmt.Sprintf("Скорость %gx", speed))
+		t.gui.programMgr.SetRunSpeed(speed)
+	}
+	t.speedSlider.OnChangeEnded = func(speed float64) {
+		t.gui.preferences().SetFloat(settingRunSpeed, speed)
+	}
+	t.speedSlider.Value = clamp(t.gui.preferences().FloatWithFallback(settingRunSpeed, 1), minRunSpeed, maxRunSpeed)
+	t.speedSlider.OnChanged(t.speedSlider.Value)
+	speedControl := container.NewGridWrap(fyne.NewSize(120, t.speedSlider.MinSize().Height), t.speedSlider)
+
 	// Кнопки работы с файлами
 	t.saveButton = widget.NewButtonWithIcon("Сохранить", theme.DocumentSaveIcon(), func() {
-		t.saveProgram()
+		t.gui.saveProgram()
 	})
 	t.saveButton.Importance = widget.MediumImportance
 	t.saveButton.Disable()
 
 	t.loadButton = widget.NewButtonWithIcon("Загрузить", theme.FolderOpenIcon(), func() {
-		t.loadProgram()
+		t.gui.showOpenProgramDialog()
 	})
 	t.loadButton.Importance = widget.MediumImportance
 
@@ -123,20 +158,28 @@ func (t *Toolbar) buildUI() *fyne.Container {
 	t.exportButton.Disable()
 
 	// Кнопка очистки
-	clearButton := widget.NewButtonWithIcon("Очистить", theme.DeleteIcon(), func() {
-		if t.gui.programMgr != nil {
-			dialog.ShowConfirm("Очистить программу",
-				"Вы уверены, что хотите удалить все блоки программы?",
-				func(confirmed bool) {
-					if confirmed {
-						t.gui.programMgr.ClearProgram()
-						t.gui.programPanel.Clear()
-						log.Println("Программа очищена")
-					}
-				}, t.gui.window)
-		}
+	t.clearButton = widget.NewButtonWithIcon("Очистить", theme.DeleteIcon(), func() {
+		t.gui.confirmClearProgram()
+	})
+	t.clearButton.Importance = widget.MediumImportance
+
+	// Кнопка блокировки программы PIN-кодом учителя
+	t.lockButton = widget.NewButtonWithIcon("Заблокировать", iconResource("lock"), func() {
+		t.gui.toggleLock()
+	})
+	t.lockButton.Importance = widget.LowImportance
+
+	// Кнопка настроек
+	settingsButton := widget.NewButtonWithIcon("Настройки", theme.SettingsIcon(), func() {
+		t.gui.showSettingsDialog()
+	})
+	settingsButton.Importance = widget.LowImportance
+
+	// Кнопка диагностики
+	metricsButton := widget.NewButtonWithIcon("Диагностика", theme.InfoIcon(), func() {
+		t.gui.showMetricsDialog()
 	})
-	clearButton.Importance = widget.MediumImportance
+	metricsButton.Importance = widget.LowImportance
 
 	// Кнопка помощи
 	helpButton := widget.NewButtonWithIcon("Справка", theme.HelpIcon(), func() {
@@ -144,16 +187,27 @@ func (t *Toolbar) buildUI() *fyne.Container {
 	})
 	helpButton.Importance = widget.LowImportance
 
-	// Статус подключения
 	if t.gui != nil {
-		t.gui.statusLabel = widget.NewLabel("Не подключено")
-		t.gui.statusLabel.Alignment = fyne.TextAlignCenter
-		t.gui.statusLabel.TextStyle.Bold = true
-
 		t.gui.connectButton = connectButton
 		t.gui.disconnectButton = disconnectButton
 	}
 
+	// В режиме экзамена остаются только подключение, запуск и справка
+	if t.gui.kiosk != nil {
+		return container.NewHBox(
+			connectButton,
+			disconnectButton,
+			widget.NewSeparator(),
+			t.runButton,
+			t.stopButton,
+			speedControl,
+			speedLabel,
+			widget.NewSeparator(),
+			helpButton,
+			layout.NewSpacer(),
+		)
+	}
+
 	// Контейнер панели инструментов
 	toolbarContainer := container.NewHBox(
 		connectButton,
@@ -161,43 +215,72 @@ func (t *Toolbar) buildUI() *fyne.Container {
 		widget.NewSeparator(),
 		t.runButton,
 		t.stopButton,
+		speedControl,
+		speedLabel,
 		widget.NewSeparator(),
 		t.saveButton,
 		t.loadButton,
 		t.exportButton,
 		widget.NewSeparator(),
-		clearButton,
+		t.clearButton,
 		widget.NewSeparator(),
+		t.lockButton,
+		settingsButton,
+		metricsButton,
 		helpButton,
 		layout.NewSpacer(),
 	)
 
-	// Добавляем статус в отдельный контейнер
-	statusContainer := container.NewHBox(
-		layout.NewSpacer(),
-		t.gui.statusLabel,
-		layout.NewSpacer(),
-	)
+	return toolbarContainer
+}
 
-	// Основной контейнер с панелью инструментов и статусом
-	mainContainer := container.NewVBox(
-		toolbarContainer,
-		statusContainer,
-	)
+// updateRunButton разрешает запуск только корректной программы
+// и объясняет в подсказке, что мешает запуску
+func (t *Toolbar) updateRunButton() {
+	issues := t.gui.programMgr.ValidateProgram()
+	if len(issues) == 0 {
+		t.runButton.Enable()
+		tooltip := portConflictsTooltip(t.gui.programMgr.PortConflicts())
+		if t.gui.programMgr.SensorReplay() != nil {
+			tooltip = strings.TrimSpace("Датчики: запись «" + t.gui.sensorRecordingName + "» вместо датчиков хаба\n" + tooltip)
+		}
+		t.runButton.SetTooltip(tooltip)
+		return
+	}
 
-	return mainContainer
+	lines := make([]string, 0, len(issues)+1)
+	lines = append(lines, "Запуск недоступен:")
+	for _, issue := range issues {
+		lines = append(lines, "• "+issue.Message)
+	}
+	t.runButton.Disable()
+	t.runButton.SetTooltip(strings.Join(lines, "\n"))
 }
 
-// saveProgram сохраняет программу
-func (t *Toolbar) saveProgram() {
-	// TODO: Реализовать сохранение программы в файл
-	dialog.ShowInformation("Информация", "Функция сохранения программы в разработке", t.gui.window)
+// portConflictsTooltip предупреждает о конфликтах портов, которые не мешают запуску
+func portConflictsTooltip(conflicts []PortConflict) string {
+	if len(conflicts) == 0 {
+		return ""
+	}
+	lines := []string{"Запуск возможен, но ветви программы спорят за порты:"}
+	for _, conflict := range conflicts {
+		lines = append(lines, "• "+conflict.Message, "  "+conflict.Suggestion)
+	}
+	return strings.Join(lines, "\n")
 }
 
-// loadProgram загружает программу
-func (t *Toolbar) loadProgram() {
-	// TODO: Реализовать загрузку программы из файла
-	dialog.ShowInformation("Информация", "Функция загрузки программы в разработке", t.gui.window)
+// updateLockButton показывает на кнопке блокировки действие, доступное сейчас
+func (t *Toolbar) updateLockButton(locked bool) {
+	if t.lockButton == nil {
+		return
+	}
+	if locked {
+		t.lockButton.SetText("Разблокировать")
+		t.lockButton.SetIcon(iconResource("unlock"))
+	} else {
+		t.lockButton.SetText("Заблокировать")
+		t.lockButton.SetIcon(iconResource("lock"))
+	}
 }
 
 // exportProgram экспортирует программу
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// TooltipButton кнопка со всплывающей подсказкой при наведении мыши.
+// Подсказка показывается и у неактивной кнопки, чтобы объяснить, почему она недоступна
+type TooltipButton struct {
+	widget.Button
+	tooltip string
+	popUp   *widget.PopUp
+}
+
+// NewTooltipButton создает кнопку с иконкой и подсказкой
+func NewTooltipButton(label string, icon fyne.Resource, tapped func()) *TooltipButton {
+	b := &TooltipButton{}
+	b.Text = label
+	b.Icon = icon
+	b.OnTapped = tapped
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// SetTooltip задает текст подсказки, пустая строка отключает подсказку
+func (b *TooltipButton) SetTooltip(text string) {
+	b.tooltip = text
+	if text == "" {
+		b.hideTooltip()
+	}
+}
+
+// MouseIn показывает подсказку под кнопкой
+func (b *TooltipButton) MouseIn(e *desktop.MouseEvent) {
+	b.Button.MouseIn(e)
+	if b.tooltip == "" {
+		return
+	}
+
+	driver := fyne.CurrentApp().Driver()
+	c := driver.CanvasForObject(b)
+	if c == nil {
+		return
+	}
+	b.hideTooltip()
+	b.popUp = widget.NewPopUp(widget.NewLabel(b.tooltip), c)
+	b.popUp.ShowAtPosition(driver.AbsolutePositionForObject(b).Add(fyne.NewPos(0, b.Size().Height)))
+}
+
+// MouseOut скрывает подсказку
+func (b *TooltipButton) MouseOut() {
+	b.Button.MouseOut()
+	b.hideTooltip()
+}
+
+// hideTooltip убирает показанную подсказку
+func (b *TooltipButton) hideTooltip() {
+	if b.popUp != nil {
+		b.popUp.Hide()
+		b.popUp = nil
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// settingTutorialDone обучение при первом запуске уже пройдено или пропущено
+const settingTutorialDone = "tutorial_done"
+
+// scheduleFirstRunTutorial запускает обучение при первом запуске, когда окно уже разложено
+func (gui *MainGUI) scheduleFirstRunTutorial() {
+	if gui.preferences().Bool(settingTutorialDone) {
+		return
+	}
+	go func() {
+		time.Sleep(time.Second)
+		fyne.Do(gui.startTutorial)
+	}()
+}
+
+// startTutorial показывает обучающий тур: панели окна и упражнение
+// «подключи хаб и помигай светодиодом»
+func (gui *MainGUI) startTutorial() {
+	if gui.tour != nil {
+		gui.tour.Stop()
+	}
+
+	blinked := false
+	steps := []CoachStep{
+		{
+			Title: "Добро пожаловать в WeDoProg",
+			Text:  "Несколько шагов покажут, где что находится, а в конце вы подключите хаб и помигаете его светодиодом.",
+		},
+		{
+			Target: func() fyne.CanvasObject { return gui.toolbarContainer },
+			Title:  "Панель инструментов",
+			Text:   "Поиск и подключение хаба, запуск и остановка программы, открытие и сохранение файлов.",
+		},
+		{
+			Target: func() fyne.CanvasObject { return gui.blocksPanel },
+			Title:  "Палитра блоков",
+			Text:   "Нажмите на блок, чтобы добавить его в программу. Блоки для моторов и датчиков появляются, когда они подключены к хабу.",
+		},
+		{
+			Target: func() fyne.CanvasObject { return gui.programPanel.GetContainer() },
+			Title:  "Холст",
+			Text:   "Здесь собирается программа. Блоки выполняются от блока «Старт» по соединениям; перетаскивайте их мышью.",
+		},
+		{
+			Target: func() fyne.CanvasObject { return gui.devicePanel.GetContainer() },
+			Title:  "Панель устройств",
+			Text:   "Заряд батареи, сведения о хабе и устройства на его портах. Двойной щелчок по устройству открывает его подробности.",
+		},
+		{
+			Target:      func() fyne.CanvasObject { return gui.connectButton },
+			Title:       "Упражнение: подключите хаб",
+			Text:        "Включите хаб кнопкой, затем нажмите «Найти хаб» и выберите его в списке. «Далее» станет доступна после подключения.",
+			ActionLabel: "Найти хаб",
+			Action:      gui.showHubDiscoveryDialog,
+			Done:        gui.hubMgr.IsConnected,
+		},
+		{
+			Target:      func() fyne.CanvasObject { return gui.devicePanel.GetContainer() },
+			Title:       "Упражнение: помигайте светодиодом",
+			Text:        "Нажмите «Мигнуть» — светодиод хаба несколько раз загорится фиолетовым. Так же можно узнать свой хаб среди других.",
+			ActionLabel: "Мигнуть",
+			Action: func() {
+				blinked = true
+				gui.identifyConnectedHub()
+			},
+			Done: func() bool { return blinked },
+		},
+		{
+			Title: "Готово!",
+			Text:  "Теперь соберите первую программу. Повторить обучение можно в меню «Справка → Обучение».",
+		},
+	}
+
+	gui.tour = NewCoachTour(gui.coachLayer, steps, func() {
+		gui.preferences().SetBool(settingTutorialDone, true)
+		gui.tour = nil
+	})
+	gui.tour.Start()
+}
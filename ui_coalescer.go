@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// deviceUpdateInterval минимальный интервал между обновлениями интерфейса (~10 раз в секунду)
+const deviceUpdateInterval = 100 * time.Millisecond
+
+// DeviceUpdateCoalescer собирает обновления устройств, приходящие с частотой
+// уведомлений хаба, и передает их интерфейсу пачкой не чаще deviceUpdateInterval.
+// Исполнитель программ получает значения напрямую из шины событий на полной частоте.
+type DeviceUpdateCoalescer struct {
+	mu        sync.Mutex
+	pending   map[byte]*Device
+	scheduled bool
+	lastFlush time.Time
+	flush     func(updates map[byte]*Device)
+}
+
+// NewDeviceUpdateCoalescer создает накопитель обновлений; flush вызывается в потоке интерфейса
+func NewDeviceUpdateCoalescer(flush func(updates map[byte]*Device)) *DeviceUpdateCoalescer {
+	return &DeviceUpdateCoalescer{
+		pending: make(map[byte]*Device),
+		flush:   flush,
+	}
+}
+
+// Push добавляет обновление устройства; более новое обновление порта заменяет старое
+func (c *DeviceUpdateCoalescer) Push(portID byte, device *Device) {
+	// Копируем устройство, чтобы интерфейс не читал структуру, которую меняет хаб
+	snapshot := *device
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[portID] = &snapshot
+	if c.scheduled {
+		return
+	}
+	c.scheduled = true
+
+	delay := deviceUpdateInterval - time.Since(c.lastFlush)
+	if delay < 0 {
+		delay = 0
+	}
+	time.AfterFunc(delay, func() {
+		fyne.Do(c.deliver)
+	})
+}
+
+// deliver передает накопленные обновления интерфейсу
+func (c *DeviceUpdateCoalescer) deliver() {
+	c.mu.Lock()
+	updates := c.pending
+	c.pending = make(map[byte]*Device)
+	c.scheduled = false
+	c.lastFlush = time.Now()
+	c.mu.Unlock()
+
+	if len(updates) > 0 {
+		c.flush(updates)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	settingSoundCues = "sound_cues" // Звуковые сигналы интерфейса
+	cueSampleRate    = 22050        // Частота дискретизации сигналов, Гц
+	cueFadeSamples   = 200          // Плавное начало и конец тона без щелчков
+)
+
+// SoundCue звуковой сигнал интерфейса
+type SoundCue int
+
+const (
+	SoundBlockAdded      SoundCue = iota // Блок добавлен на холст
+	SoundProgramFinished                 // Программа завершилась
+	SoundError                           // Ошибка
+)
+
+// cueTone нота сигнала: частота и длительность
+type cueTone struct {
+	frequency float64
+	ms        int
+}
+
+// cueMelodies короткие мелодии сигналов: восходящая — успех, нисходящая — ошибка
+var cueMelodies = map[SoundCue][]cueTone{
+	SoundBlockAdded:      {{880, 60}},
+	SoundProgramFinished: {{523, 110}, {659, 110}, {784, 180}},
+	SoundError:           {{330, 160}, {220, 260}},
+}
+
+// SoundCues проигрывает звуковые сигналы интерфейса через системный проигрыватель.
+// Сигналы собираются в WAV-файлы при первом использовании и не требуют звуковых ресурсов
+type SoundCues struct {
+	mu      sync.Mutex
+	enabled bool
+	files   map[SoundCue]string
+}
+
+// NewSoundCues создает выключенные звуковые сигналы
+func NewSoundCues() *SoundCues {
+	return &SoundCues{files: make(map[SoundCue]string)}
+}
+
+// SetEnabled включает или выключает звуковые сигналы
+func (s *SoundCues) SetEnabled(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = enabled
+}
+
+// Play проигрывает сигнал, не дожидаясь его окончания
+func (s *SoundCues) Play(cue SoundCue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return
+	}
+
+	path, err := s.fileLocked(cue)
+	if err != nil {
+		log.Printf("Не удалось подготовить звуковой сигнал: %v", err)
+		return
+	}
+	cmd := soundPlayerCommand(path)
+	if cmd == nil {
+		log.Println("Проигрыватель звука не найден, звуковые сигналы недоступны")
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		log.Printf("Ошибка воспроизведения сигнала: %v", err)
+		return
+	}
+	go cmd.Wait()
+}
+
+// fileLocked возвращает WAV-файл сигнала, создавая его при первом обращении
+func (s *SoundCues) fileLocked(cue SoundCue) (string, error) {
+	if path, ok := s.files[cue]; ok {
+		return path, nil
+	}
+
+	file, err := os.CreateTemp("", "wedoprog-cue-*.wav")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.Write(encodeCueWAV(cueMelodies[cue])); err != nil {
+		return "", err
+	}
+	s.files[cue] = file.Name()
+	return file.Name(), nil
+}
+
+// Close удаляет временные файлы сигналов
+func (s *SoundCues) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for cue, path := range s.files {
+		os.Remove(path)
+		delete(s.files, cue)
+	}
+}
+
+// encodeCueWAV собирает мелодию в 16-битный моно WAV
+func encodeCueWAV(melody []cueTone) []byte {
+	var samples []int16
+	for _, tone := range melody {
+		count := cueSampleRate * tone.ms / 1000
+		for i := 0; i < count; i++ {
+			amplitude := 0.4
+			if edge := min(i, count-1-i); edge < cueFadeSamples {
+				amplitude *= float64(edge) / cueFadeSamples
+			}
+			value := amplitude * math.Sin(2*math.Pi*tone.frequency*float64(i)/cueSampleRate)
+			samples = append(samples, int16(value*math.MaxInt16))
+		}
+	}
+
+	dataSize := uint32(len(samples) * 2)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	for _, field := range []any{
+		uint32(16),                // размер блока формата
+		uint16(1),                 // PCM
+		uint16(1),                 // моно
+		uint32(cueSampleRate),     // частота дискретизации
+		uint32(cueSampleRate * 2), // байт в секунду
+		uint16(2),                 // байт на отсчет
+		uint16(16),                // бит на отсчет
+	} {
+		binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// soundPlayerCommand возвращает команду системного проигрывателя WAV или nil
+func soundPlayerCommand(path string) *exec.Cmd {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("afplay", path)
+	case "windows":
+		script := "(New-Object Media.SoundPlayer '" + strings.ReplaceAll(filepath.Clean(path), "'", "''") + "').PlaySync()"
+		return exec.Command("powershell", "-NoProfile", "-Command", script)
+	}
+
+	for _, name := range []string{"paplay", "pw-play", "aplay"} {
+		if player, err := exec.LookPath(name); err == nil {
+			return exec.Command(player, path)
+		}
+	}
+	return nil
+}
+
+// onProgramState озвучивает завершение программы
+func (gui *MainGUI) onProgramState(event Event) {
+	switch event.Name {
+	case "stopped":
+		gui.sounds.Play(SoundProgramFinished)
+	case "error":
+		gui.sounds.Play(SoundError)
+	}
+}
+
+// tonePreview предпрослушивание тона блока «Звук» на динамиках компьютера.
+// Новое прослушивание прерывает предыдущее
+var tonePreview struct {
+	mu  sync.Mutex
+	cmd *exec.Cmd
+}
+
+// previewTone проигрывает тон заданной частоты и длительности на компьютере,
+// не дожидаясь его окончания. Прослушивание не зависит от настройки звуковых
+// сигналов: его включают кнопкой в редакторе блока
+func previewTone(frequency uint16, durationMs uint16) error {
+	file, err := os.CreateTemp("", "wedoprog-tone-*.wav")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(encodeCueWAV([]cueTone{{float64(frequency), int(durationMs)}}))
+	file.Close()
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	cmd := soundPlayerCommand(file.Name())
+	if cmd == nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("проигрыватель звука не найден")
+	}
+
+	tonePreview.mu.Lock()
+	defer tonePreview.mu.Unlock()
+	if tonePreview.cmd != nil && tonePreview.cmd.Process != nil {
+		tonePreview.cmd.Process.Kill()
+	}
+	if err := cmd.Start(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	tonePreview.cmd = cmd
+
+	go func() {
+		cmd.Wait()
+		os.Remove(file.Name())
+		tonePreview.mu.Lock()
+		if tonePreview.cmd == cmd {
+			tonePreview.cmd = nil
+		}
+		tonePreview.mu.Unlock()
+	}()
+	return nil
+}
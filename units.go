@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2/lang"
+)
+
+// Единицы измерения расстояния
+const (
+	DistanceUnitCM   = "cm"
+	DistanceUnitInch = "inch"
+)
+
+// cmPerInch сантиметров в дюйме
+const cmPerInch = 2.54
+
+// UnitFormatter форматирует показания датчиков с единицами измерения
+// и десятичным разделителем текущей локали
+type UnitFormatter struct {
+	DistanceUnit   string
+	DecimalComma   bool
+	DistanceApprox bool // Датчик расстояния не откалиброван: сантиметры примерные
+}
+
+// NewUnitFormatter создает форматтер для системной локали
+func NewUnitFormatter(distanceUnit string) *UnitFormatter {
+	return &UnitFormatter{
+		DistanceUnit:   distanceUnit,
+		DecimalComma:   localeUsesDecimalComma(string(lang.SystemLocale())),
+		DistanceApprox: true,
+	}
+}
+
+// localeUsesDecimalComma проверяет, используется ли в локали запятая как десятичный разделитель
+func localeUsesDecimalComma(locale string) bool {
+	language := strings.ToLower(strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0])
+	switch language {
+	case "en", "ja", "zh", "ko", "he", "th", "":
+		return false
+	default:
+		return true
+	}
+}
+
+// convert переводит значение датчика в отображаемые единицы и возвращает
+// значение, обозначение единицы и число знаков после запятой
+func (f *UnitFormatter) convert(deviceType byte, value float64) (float64, string, int) {
+	switch deviceType {
+	case DEVICE_TYPE_MOTION_SENSOR:
+		if f.DistanceUnit == DistanceUnitInch {
+			return value / cmPerInch, "дюйм", 1
+		}
+		return value, "см", 0
+	case DEVICE_TYPE_TILT_SENSOR:
+		return value, "°", 0
+	case DEVICE_TYPE_VOLTAGE:
+		return value, "мВ", 0
+	case DEVICE_TYPE_CURRENT:
+		return value, "мА", 0
+	default:
+		return value, "", 1
+	}
+}
+
+// Format возвращает значение с единицей измерения для интерфейса, например "12,5 дюйм"
+func (f *UnitFormatter) Format(deviceType byte, value float64) string {
+	converted, unit, decimals := f.convert(deviceType, value)
+
+	text := strconv.FormatFloat(converted, 'f', decimals, 64)
+	if f.DecimalComma {
+		text = strings.Replace(text, ".", ",", 1)
+	}
+	if unit != "" {
+		text += " " + unit
+	}
+	if f.DistanceApprox && deviceType == DEVICE_TYPE_MOTION_SENSOR {
+		text = "≈" + text
+	}
+	return text
+}
+
+// CSVValue возвращает значение для экспорта: те же единицы и точность,
+// но всегда с точкой, чтобы файл одинаково читался в любой локали
+func (f *UnitFormatter) CSVValue(deviceType byte, value float64) string {
+	converted, _, decimals := f.convert(deviceType, value)
+	return strconv.FormatFloat(converted, 'f', decimals, 64)
+}
+
+// Unit возвращает обозначение единицы измерения датчика (для заголовков)
+func (f *UnitFormatter) Unit(deviceType byte) string {
+	_, unit, _ := f.convert(deviceType, 0)
+	return unit
+}
@@ -64,20 +64,3 @@ func FormatHubInfo(info *HubInfo) string {
 
 	return builder.String()
 }
-
-// GetDeviceFromPort получает устройство по порту
-func (hm *HubManager) GetDeviceFromPort(portID byte) (*Device, bool) {
-	device, exists := hm.devices[portID]
-	return device, exists
-}
-
-// GetConnectedDevices возвращает список подключенных устройств
-func (hm *HubManager) GetConnectedDevices() []*Device {
-	var devices []*Device
-	for _, device := range hm.devices {
-		if device.IsConnected {
-			devices = append(devices, device)
-		}
-	}
-	return devices
-}
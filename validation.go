@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ValidationIssue причина, по которой программу нельзя запустить
+type ValidationIssue struct {
+	BlockID int // 0, если причина относится ко всей программе
+	Message string
+}
+
+// requiredDevice устройство, которое блок ожидает на порту хаба
+type requiredDevice struct {
+	port       byte
+	deviceType byte
+}
+
+// blockRequiredDevices возвращает устройства, без которых блок не выполнится
+func blockRequiredDevices(block *ProgramBlock) []requiredDevice {
+	port := func(key string) byte {
+		value, _ := block.Parameters[key].(byte)
+		return value
+	}
+
+	switch block.Type {
+	case BlockTypeMotor:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_MOTOR}}
+	case BlockTypeLED:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_RGB_LIGHT}}
+	case BlockTypeTiltSensor:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_TILT_SENSOR}}
+	case BlockTypeDistanceSensor:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_MOTION_SENSOR}}
+	case BlockTypeSound:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_PIEZO_TONE}}
+	case BlockTypeVoltageSensor:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_VOLTAGE}}
+	case BlockTypeCurrentSensor:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_CURRENT}}
+	case BlockTypeLightMusic:
+		return []requiredDevice{
+			{port("sound_port"), DEVICE_TYPE_PIEZO_TONE},
+			{port("led_port"), DEVICE_TYPE_RGB_LIGHT},
+		}
+	case BlockTypeWhenBumped:
+		return []requiredDevice{{port("port"), DEVICE_TYPE_TILT_SENSOR}}
+	case BlockTypeFollow:
+		return []requiredDevice{
+			{port("motor_port"), DEVICE_TYPE_MOTOR},
+			{port("sensor_port"), DEVICE_TYPE_MOTION_SENSOR},
+		}
+	case BlockTypeCondition, BlockTypeWaitUntil:
+		switch conditionFromParameters(block.Parameters).Sensor {
+		case ConditionSensorDistance, ConditionSensorObject:
+			return []requiredDevice{{port("port"), DEVICE_TYPE_MOTION_SENSOR}}
+		case ConditionSensorTilt:
+			return []requiredDevice{{port("port"), DEVICE_TYPE_TILT_SENSOR}}
+		}
+	}
+	return nil
+}
+
+// programRequiredDevices возвращает устройства всех блоков программы без повторов,
+// упорядоченные по портам
+func programRequiredDevices(program *Program) []requiredDevice {
+	seen := make(map[requiredDevice]bool)
+	var devices []requiredDevice
+	for _, block := range program.Blocks {
+		for _, device := range blockRequiredDevices(block) {
+			if device.port == 0 || seen[device] {
+				continue
+			}
+			seen[device] = true
+			devices = append(devices, device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].port != devices[j].port {
+			return devices[i].port < devices[j].port
+		}
+		return devices[i].deviceType < devices[j].deviceType
+	})
+	return devices
+}
+
+// ValidateProgram проверяет, что программу можно запустить: есть блок, с которого
+// начинается выполнение, хаб подключен и нужные блокам устройства на своих портах
+func (pm *ProgramManager) ValidateProgram() []ValidationIssue {
+	var issues []ValidationIssue
+
+	if len(pm.program.Blocks) == 0 {
+		return []ValidationIssue{{Message: "В программе нет блоков"}}
+	}
+
+	hasEntry := false
+	for _, block := range pm.program.Blocks {
+		switch block.Type {
+		case BlockTypeStart, BlockTypeWhenMotion, BlockTypeWhenColor, BlockTypeWhenHear, BlockTypeWhenBumped:
+			hasEntry = true
+		}
+	}
+	if !hasEntry {
+		issues = append(issues, ValidationIssue{Message: "Добавьте блок «Начать», с которого начнется программа"})
+	}
+
+	replay := pm.sensorReplay
+	if !pm.hubMgr.IsConnected() && replay == nil {
+		return append(issues, ValidationIssue{Message: "Хаб не подключен"})
+	}
+
+	issues = append(issues, pm.bumpPortConflicts()...)
+
+	for _, block := range pm.program.Blocks {
+		for _, required := range blockRequiredDevices(block) {
+			if pm.deviceMgr.IsDeviceConnected(required.port, required.deviceType) {
+				continue
+			}
+			// Устройство из записи датчиков появится на порту при запуске
+			if replay != nil && replay.hasDevice(required.port, required.deviceType) {
+				continue
+			}
+			issues = append(issues, ValidationIssue{
+				BlockID: block.ID,
+				Message: fmt.Sprintf("%s: на порту %d нет устройства «%s»", block.Title, required.port,
+					DeviceTypeName(required.deviceType)),
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"os/exec"
+	"runtime"
+	"sync"
+)
+
+// Цвета, распознаваемые камерой
+const (
+	VisionColorRed    = "red"
+	VisionColorYellow = "yellow"
+	VisionColorGreen  = "green"
+	VisionColorBlue   = "blue"
+)
+
+// visionColors цвета для блока "Когда камера видит цвет"
+var visionColors = []struct {
+	Key  string
+	Name string
+}{
+	{VisionColorRed, "Красный"},
+	{VisionColorYellow, "Желтый"},
+	{VisionColorGreen, "Зеленый"},
+	{VisionColorBlue, "Синий"},
+}
+
+// visionColorName возвращает русское название цвета
+func visionColorName(key string) string {
+	for _, c := range visionColors {
+		if c.Key == key {
+			return c.Name
+		}
+	}
+	return key
+}
+
+const (
+	visionSampleWidth     = 64   // Ширина сетки выборки кадра
+	visionSampleHeight    = 48   // Высота сетки выборки кадра
+	visionMotionDelta     = 25   // Изменение яркости пикселя, считающееся движением
+	visionColorMinShare   = 0.15 // Доля пикселей, при которой цвет считается доминирующим
+	visionFramesPerSecond = 5
+)
+
+// VisionMonitor захватывает кадры веб-камеры через ffmpeg и публикует
+// в шину событий уровень движения и доминирующий цвет
+type VisionMonitor struct {
+	events   *EventBus
+	device   string
+	cancel   context.CancelFunc
+	run      uint64 // Номер запуска захвата; завершившийся запуск не трогает следующий
+	mu       sync.Mutex
+	previous []uint8
+
+	crashHandler func()
+}
+
+// NewVisionMonitor создает монитор камеры
+func NewVisionMonitor(events *EventBus) *VisionMonitor {
+	return &VisionMonitor{events: events}
+}
+
+// SetCrashHandler устанавливает обработчик паники при чтении кадров
+func (vm *VisionMonitor) SetCrashHandler(handler func()) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.crashHandler = handler
+}
+
+// SetDevice задает камеру (пусто = камера по умолчанию)
+func (vm *VisionMonitor) SetDevice(device string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.device = device
+}
+
+// Start запускает захват кадров
+func (vm *VisionMonitor) Start() error {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if vm.cancel != nil {
+		return nil
+	}
+
+	ffmpegPath, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("для работы с камерой нужна программа ffmpeg: %v", err)
+	}
+
+	inputArgs, err := cameraInputArgs(vm.device)
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"-loglevel", "error"}, inputArgs...)
+	args = append(args,
+		"-vf", fmt.Sprintf("fps=%d,scale=160:-1", visionFramesPerSecond),
+		"-f", "image2pipe", "-vcodec", "mjpeg", "-q:v", "5", "-",
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("ошибка подключения к камере: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("ошибка запуска захвата камеры: %v", err)
+	}
+
+	vm.cancel = cancel
+	vm.run++
+	run := vm.run
+	vm.previous = nil
+	log.Printf("Захват камеры запущен: ffmpeg %v", args)
+
+	crashHandler := vm.crashHandler
+	go func() {
+		if crashHandler != nil {
+			defer crashHandler()
+		}
+		vm.readFrames(stdout)
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			log.Printf("Захват камеры завершился с ошибкой: %v", err)
+		}
+		vm.mu.Lock()
+		if vm.run == run {
+			vm.cancel = nil
+		}
+		vm.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// Stop останавливает захват кадров
+func (vm *VisionMonitor) Stop() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if vm.cancel != nil {
+		vm.cancel()
+		vm.cancel = nil
+		log.Println("Захват камеры остановлен")
+	}
+}
+
+// cameraInputArgs возвращает параметры ввода ffmpeg для текущей ОС
+func cameraInputArgs(device string) ([]string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if device == "" {
+			device = "/dev/video0"
+		}
+		return []string{"-f", "v4l2", "-i", device}, nil
+	case "darwin":
+		if device == "" {
+			device = "0"
+		}
+		return []string{"-f", "avfoundation", "-framerate", "30", "-i", device}, nil
+	case "windows":
+		if device == "" {
+			return nil, fmt.Errorf("укажите имя камеры в настройках (ffmpeg -list_devices true -f dshow -i dummy)")
+		}
+		return []string{"-f", "dshow", "-i", "video=" + device}, nil
+	default:
+		return nil, fmt.Errorf("камера не поддерживается на %s", runtime.GOOS)
+	}
+}
+
+// visionMaxFrameSize наибольший размер одного JPEG-кадра в потоке, байты
+const visionMaxFrameSize = 8 << 20
+
+// Маркеры начала и конца JPEG-изображения
+var (
+	jpegSOI = []byte{0xFF, 0xD8}
+	jpegEOI = []byte{0xFF, 0xD9}
+)
+
+// splitJPEGFrames делит поток MJPEG на кадры от маркера SOI до маркера EOI.
+// В сжатых данных байт 0xFF всегда экранируется, поэтому EOI внутри кадра не
+// встречается. Байты между кадрами отбрасываются
+func splitJPEGFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := bytes.Index(data, jpegSOI)
+	if start < 0 {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		// Последний байт может оказаться первой половиной маркера
+		if len(data) > 1 {
+			return len(data) - 1, nil, nil
+		}
+		return 0, nil, nil
+	}
+	end := bytes.Index(data[start+len(jpegSOI):], jpegEOI)
+	if end < 0 {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return start, nil, nil
+	}
+	end += start + len(jpegSOI) + len(jpegEOI)
+	return end, data[start:end], nil
+}
+
+// readFrames делит поток на JPEG-кадры и декодирует каждый отдельно.
+// jpeg.Decode читает с упреждением, поэтому декодировать прямо из потока
+// нельзя: он съедает начало следующего кадра
+func (vm *VisionMonitor) readFrames(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 256<<10), visionMaxFrameSize)
+	scanner.Split(splitJPEGFrames)
+	for scanner.Scan() {
+		img, err := jpeg.Decode(bytes.NewReader(scanner.Bytes()))
+		if err != nil {
+			log.Printf("Ошибка декодирования кадра камеры: %v", err)
+			continue
+		}
+		vm.analyzeFrame(img)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Ошибка чтения потока камеры: %v", err)
+	}
+}
+
+// analyzeFrame вычисляет движение и доминирующий цвет кадра
+func (vm *VisionMonitor) analyzeFrame(img image.Image) {
+	bounds := img.Bounds()
+	luma := make([]uint8, 0, visionSampleWidth*visionSampleHeight)
+	colorCounts := make(map[string]int)
+	samples := 0
+
+	for sy := 0; sy < visionSampleHeight; sy++ {
+		y := bounds.Min.Y + sy*bounds.Dy()/visionSampleHeight
+		for sx := 0; sx < visionSampleWidth; sx++ {
+			x := bounds.Min.X + sx*bounds.Dx()/visionSampleWidth
+			r, g, b, _ := img.At(x, y).RGBA()
+			r8, g8, b8 := uint8(r>>8), uint8(g>>8), uint8(b>>8)
+
+			luma = append(luma, uint8((299*int(r8)+587*int(g8)+114*int(b8))/1000))
+			if key := classifyColor(r8, g8, b8); key != "" {
+				colorCounts[key]++
+			}
+			samples++
+		}
+	}
+
+	vm.mu.Lock()
+	previous := vm.previous
+	vm.previous = luma
+	vm.mu.Unlock()
+
+	if previous != nil {
+		vm.events.Publish(Event{
+			Type:  EventVisionMotion,
+			Value: motionLevel(previous, luma),
+		})
+	}
+
+	dominant, best := "", 0
+	for key, count := range colorCounts {
+		if count > best {
+			dominant, best = key, count
+		}
+	}
+	if dominant != "" && float64(best)/float64(samples) >= visionColorMinShare {
+		vm.events.Publish(Event{
+			Type:  EventVisionColor,
+			Name:  dominant,
+			Value: float64(best) * 100 / float64(samples),
+		})
+	}
+}
+
+// motionLevel возвращает процент пикселей, изменивших яркость
+func motionLevel(previous, current []uint8) float64 {
+	if len(previous) != len(current) || len(current) == 0 {
+		return 0
+	}
+
+	changed := 0
+	for i := range current {
+		diff := int(current[i]) - int(previous[i])
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > visionMotionDelta {
+			changed++
+		}
+	}
+	return float64(changed) * 100 / float64(len(current))
+}
+
+// classifyColor относит насыщенный пиксель к одному из распознаваемых цветов
+func classifyColor(r, g, b uint8) string {
+	maxC := max(int(r), max(int(g), int(b)))
+	minC := min(int(r), min(int(g), int(b)))
+	if maxC < 64 || maxC == 0 {
+		return ""
+	}
+
+	saturation := float64(maxC-minC) / float64(maxC)
+	if saturation < 0.4 {
+		return ""
+	}
+
+	// Оттенок в градусах
+	var hue float64
+	delta := float64(maxC - minC)
+	switch maxC {
+	case int(r):
+		hue = 60 * (float64(int(g)-int(b)) / delta)
+	case int(g):
+		hue = 60 * (2 + float64(int(b)-int(r))/delta)
+	default:
+		hue = 60 * (4 + float64(int(r)-int(g))/delta)
+	}
+	if hue < 0 {
+		hue += 360
+	}
+
+	switch {
+	case hue < 20 || hue >= 340:
+		return VisionColorRed
+	case hue >= 40 && hue < 70:
+		return VisionColorYellow
+	case hue >= 80 && hue < 160:
+		return VisionColorGreen
+	case hue >= 190 && hue < 260:
+		return VisionColorBlue
+	default:
+		return ""
+	}
+}
+
+// motionThreshold возвращает порог движения блока в процентах
+func motionThreshold(params map[string]interface{}) float64 {
+	return paramFloat(params, "threshold", 10)
+}
+
+// usesVision проверяет, есть ли в программе блоки, работающие с камерой
+func (pm *ProgramManager) usesVision() bool {
+	for _, block := range pm.program.Blocks {
+		if block.Type == BlockTypeWhenMotion || block.Type == BlockTypeWhenColor {
+			return true
+		}
+	}
+	return false
+}
+
+// listenForVision запускает цепочки блоков камеры по событиям движения и цвета.
+// Пока в программе есть такие блоки, она выполняется до нажатия "Стоп".
+func (pm *ProgramManager) listenForVision(ctx context.Context, chains *sync.WaitGroup) func() {
+	if !pm.usesVision() {
+		return func() {}
+	}
+
+	unsubscribeMotion := pm.listenForHats(ctx, chains, EventVisionMotion, func(block *ProgramBlock, event Event) bool {
+		return block.Type == BlockTypeWhenMotion && event.Value >= motionThreshold(block.Parameters)
+	})
+	unsubscribeColor := pm.listenForHats(ctx, chains, EventVisionColor, func(block *ProgramBlock, event Event) bool {
+		color := paramString(block.Parameters, "color", "")
+		return block.Type == BlockTypeWhenColor && color == event.Name
+	})
+
+	holdUntilStopped(ctx, chains)
+
+	return func() {
+		unsubscribeMotion()
+		unsubscribeColor()
+	}
+}
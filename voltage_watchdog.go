@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// Слабые батареи выдают себя просадками напряжения, когда работают моторы:
+// в покое хаб показывает почти полный заряд, а под нагрузкой напряжение
+// падает и хаб может перезагрузиться. Сторож напряжения считает такие
+// просадки и, если они повторяются, предупреждает о батареях и по желанию
+// ограничивает мощность всех моторов
+
+const (
+	settingVoltageThreshold = "voltage_sag_threshold" // Порог просадки, мВ
+	settingVoltageAutoLimit = "voltage_auto_limit"    // Ограничивать мощность моторов при просадках
+
+	defaultVoltageThreshold = 2500.0 // Порог просадки по умолчанию для двух батарей AA, мВ
+	minVoltageThreshold     = 1800.0
+	maxVoltageThreshold     = 3200.0
+	voltageRecovery         = 100.0 // Насколько напряжение должно подняться над порогом, чтобы просадка закончилась, мВ
+
+	sagWindow       = time.Minute     // Окно, в котором считаются просадки
+	sagRepeats      = 3               // Сколько просадок в окне означают слабые батареи
+	warningInterval = 2 * time.Minute // Наименьший промежуток между предупреждениями
+
+	fullPowerCap = 100 // Мощность без ограничения, %
+	minPowerCap  = 40  // Ниже ограничение не опускается, %
+	powerCapStep = 20  // Шаг снижения ограничения, %
+)
+
+// VoltageWatchdog следит за просадками напряжения хаба под нагрузкой моторов
+type VoltageWatchdog struct {
+	mu          sync.Mutex
+	threshold   float64
+	autoLimit   bool
+	sagging     bool
+	sags        []time.Time
+	lastWarning time.Time
+	powerCap    int
+
+	motorsRunning func() bool
+	onWarning     func(voltage float64)
+}
+
+// NewVoltageWatchdog создает сторож напряжения; motorsRunning сообщает, есть ли
+// нагрузка, onWarning вызывается при повторяющихся просадках
+func NewVoltageWatchdog(motorsRunning func() bool, onWarning func(voltage float64)) *VoltageWatchdog {
+	return &VoltageWatchdog{
+		threshold:     defaultVoltageThreshold,
+		powerCap:      fullPowerCap,
+		motorsRunning: motorsRunning,
+		onWarning:     onWarning,
+	}
+}
+
+// Configure задает порог просадки и автоматическое ограничение мощности.
+// Без автоматического ограничения прежнее ограничение снимается
+func (w *VoltageWatchdog) Configure(threshold float64, autoLimit bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.threshold = clamp(threshold, minVoltageThreshold, maxVoltageThreshold)
+	w.autoLimit = autoLimit
+	if !autoLimit {
+		w.powerCap = fullPowerCap
+	}
+}
+
+// Update учитывает новое напряжение хаба в милливольтах
+func (w *VoltageWatchdog) Update(voltage float64, now time.Time) {
+	w.mu.Lock()
+	if voltage >= w.threshold+voltageRecovery {
+		w.sagging = false
+	}
+	// Просадка считается один раз, пока напряжение не восстановится
+	if w.sagging || voltage >= w.threshold || !w.motorsRunning() {
+		w.mu.Unlock()
+		return
+	}
+	w.sagging = true
+
+	sags := w.sags[:0]
+	for _, at := range w.sags {
+		if now.Sub(at) < sagWindow {
+			sags = append(sags, at)
+		}
+	}
+	w.sags = append(sags, now)
+	log.Printf("Просадка напряжения хаба под нагрузкой: %.0f мВ (%d за минуту)", voltage, len(w.sags))
+	if len(w.sags) < sagRepeats {
+		w.mu.Unlock()
+		return
+	}
+	w.sags = nil
+
+	limited := false
+	if w.autoLimit && w.powerCap > minPowerCap {
+		w.powerCap = max(minPowerCap, w.powerCap-powerCapStep)
+		limited = true
+		log.Printf("Мощность моторов ограничена до %d%% из-за просадок напряжения", w.powerCap)
+	}
+	if !limited && now.Sub(w.lastWarning) < warningInterval {
+		w.mu.Unlock()
+		return
+	}
+	w.lastWarning = now
+	w.mu.Unlock()
+
+	if w.onWarning != nil {
+		w.onWarning(voltage)
+	}
+}
+
+// PowerCap возвращает ограничение мощности моторов в процентах
+func (w *VoltageWatchdog) PowerCap() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.powerCap
+}
+
+// Reset забывает просадки и снимает ограничение, например после замены батарей
+func (w *VoltageWatchdog) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sagging = false
+	w.sags = nil
+	w.lastWarning = time.Time{}
+	w.powerCap = fullPowerCap
+}
+
+// VoltageWatchdog возвращает сторож напряжения хаба
+func (hm *HubManager) VoltageWatchdog() *VoltageWatchdog {
+	return hm.voltage
+}
+
+// publishVoltageSag сообщает о повторяющихся просадках напряжения; ограничение
+// мощности подписчики читают через PowerCap
+func (hm *HubManager) publishVoltageSag(voltage float64) {
+	hm.events.Publish(Event{
+		Type:  EventVoltageSag,
+		Value: voltage,
+	})
+}
+
+// limitPower снижает мощность команды мотору пропорционально ограничению
+func (c *OutputCommand) limitPower(powerCap int) {
+	if c.command != outputCommandMotor || c.err != nil || c.payload == nil || powerCap >= fullPowerCap {
+		return
+	}
+	c.payload = []byte{protocol.EncodeMotorSpeed(int8(c.power * powerCap / fullPowerCap))}
+}
+
+// onVoltageSag предупреждает о слабых батареях
+func (gui *MainGUI) onVoltageSag(event Event) {
+	text := fmt.Sprintf("батареи хаба слабые: под нагрузкой моторов напряжение падает до %.0f мВ", event.Value)
+	if powerCap := gui.hubMgr.VoltageWatchdog().PowerCap(); powerCap < fullPowerCap {
+		text += fmt.Sprintf(", мощность моторов ограничена до %d%%", powerCap)
+	}
+	fyne.Do(func() { gui.statusBar.ReportError(fmt.Errorf("%s", text)) })
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	settingWatchExpressions = "watch_expressions"
+	watchRefreshInterval    = 200 * time.Millisecond
+	watchBumpShowTime       = 3 * time.Second // Сколько показывать последний удар датчика наклона
+)
+
+// watchExpressionPattern выражение наблюдения: источник, необязательный порт, оператор и порог,
+// например "расстояние < 10", "наклон порт 2 = 3" или "distance(2) <= 10"
+var watchExpressionPattern = regexp.MustCompile(
+	`^\s*(\S+?)(?:\s+порт\s+(\d+)|\s*\(\s*(\d+)\s*\))?\s*(<=|>=|!=|==|≤|≥|≠|<|>|=)\s*(-?\d+(?:[.,]\d+)?)\s*$`)
+
+// asciiComparators операторы, которые удобно набирать с клавиатуры
+var asciiComparators = map[string]string{"<=": "≤", ">=": "≥", "!=": "≠", "==": "="}
+
+// parseWatchExpression разбирает выражение наблюдения в условие датчика
+func parseWatchExpression(text string) (SensorCondition, error) {
+	match := watchExpressionPattern.FindStringSubmatch(strings.ToLower(text))
+	if match == nil {
+		return SensorCondition{}, fmt.Errorf("ожидается выражение вида «расстояние < 10» или «наклон порт 2 = 3»")
+	}
+
+	cond := SensorCondition{Port: 1, Comparator: match[4]}
+	for _, sensor := range conditionSensors {
+		if match[1] == sensor.Key || match[1] == strings.ToLower(sensor.Name) {
+			cond.Sensor = sensor.Key
+		}
+	}
+	if cond.Sensor == "" {
+		return SensorCondition{}, fmt.Errorf("неизвестный источник «%s»", match[1])
+	}
+
+	if port := match[2] + match[3]; port != "" {
+		value, err := strconv.Atoi(port)
+		if err != nil || value < 1 || value > 6 {
+			return SensorCondition{}, fmt.Errorf("порт должен быть от 1 до 6")
+		}
+		cond.Port = byte(value)
+	}
+	if comparator, ok := asciiComparators[cond.Comparator]; ok {
+		cond.Comparator = comparator
+	}
+	cond.Value, _ = strconv.ParseFloat(strings.Replace(match[5], ",", ".", 1), 64)
+	return cond, nil
+}
+
+// watchPin закрепленное выражение с индикатором истинности
+type watchPin struct {
+	text      string
+	cond      SensorCondition
+	indicator *canvas.Circle
+}
+
+// WatchPanel окно наблюдения: таймер, счетчики циклов, значения датчиков
+// и закрепленные выражения, которые подсвечиваются, когда истинны
+type WatchPanel struct {
+	gui    *MainGUI
+	window fyne.Window
+
+	values     *widget.Label
+	pinnedList *fyne.Container
+	pins       []*watchPin
+
+	loops   map[int]string
+	loopsMu sync.Mutex
+
+	bumps   map[byte]Event // Последний удар или тряска по портам
+	bumpsMu sync.Mutex
+
+	unsubscribe      func()
+	unsubscribeBumps func()
+	done             chan struct{}
+}
+
+// showWatchPanel открывает окно наблюдения или поднимает уже открытое
+func (gui *MainGUI) showWatchPanel() {
+	if gui.watchPanel != nil {
+		gui.watchPanel.window.RequestFocus()
+		return
+	}
+	gui.watchPanel = newWatchPanel(gui)
+	gui.watchPanel.window.Show()
+}
+
+// newWatchPanel создает окно наблюдения
+func newWatchPanel(gui *MainGUI) *WatchPanel {
+	w := &WatchPanel{
+		gui:    gui,
+		window: fyne.CurrentApp().NewWindow("Наблюдение"),
+		values: widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true}),
+		loops:  make(map[int]string),
+		bumps:  make(map[byte]Event),
+		done:   make(chan struct{}),
+	}
+	w.pinnedList = container.NewVBox()
+
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("расстояние < 10")
+	add := func() {
+		if err := w.pin(entry.Text); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		entry.SetText("")
+		w.savePins()
+	}
+	entry.OnSubmitted = func(string) { add() }
+
+	for _, text := range strings.Split(gui.preferences().String(settingWatchExpressions), "\n") {
+		if text != "" {
+			w.pin(text)
+		}
+	}
+
+	w.unsubscribe = gui.hubMgr.Events().Subscribe(EventLoopProgress, w.onLoopProgress)
+	w.unsubscribeBumps = gui.hubMgr.Events().Subscribe(EventTiltBump, w.onTiltBump)
+
+	content := container.NewVBox(
+		widget.NewCard("Значения", "", w.values),
+		widget.NewCard("Выражения", "Подсвечиваются, когда истинны",
+			container.NewVBox(
+				w.pinnedList,
+				container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon("", theme.ContentAddIcon(), add), entry),
+			)),
+	)
+	w.window.SetContent(container.NewVScroll(content))
+	w.window.Resize(fyne.NewSize(380, 460))
+	w.window.SetOnClosed(w.close)
+
+	w.update()
+	go w.refreshLoop()
+	return w
+}
+
+// pin закрепляет выражение
+func (w *WatchPanel) pin(text string) error {
+	cond, err := parseWatchExpression(text)
+	if err != nil {
+		return err
+	}
+
+	p := &watchPin{text: strings.TrimSpace(text), cond: cond, indicator: canvas.NewCircle(color.Transparent)}
+	w.pins = append(w.pins, p)
+
+	indicatorSize := fyne.NewSquareSize(theme.IconInlineSize() / 2)
+	var row *fyne.Container
+	row = container.NewBorder(nil, nil,
+		container.NewCenter(container.NewGridWrap(indicatorSize, p.indicator)),
+		widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+			w.unpin(p)
+			w.pinnedList.Remove(row)
+			w.savePins()
+		}),
+		widget.NewLabel(cond.String()),
+	)
+	w.pinnedList.Add(row)
+	return nil
+}
+
+// unpin убирает закрепленное выражение
+func (w *WatchPanel) unpin(p *watchPin) {
+	for i, other := range w.pins {
+		if other == p {
+			w.pins = append(w.pins[:i], w.pins[i+1:]...)
+			return
+		}
+	}
+}
+
+// savePins сохраняет закрепленные выражения в настройках
+func (w *WatchPanel) savePins() {
+	texts := make([]string, len(w.pins))
+	for i, p := range w.pins {
+		texts[i] = p.text
+	}
+	w.gui.preferences().SetString(settingWatchExpressions, strings.Join(texts, "\n"))
+}
+
+// onLoopProgress запоминает итерации выполняемых циклов
+func (w *WatchPanel) onLoopProgress(event Event) {
+	w.loopsMu.Lock()
+	defer w.loopsMu.Unlock()
+	if event.Name == "" {
+		delete(w.loops, event.BlockID)
+	} else {
+		w.loops[event.BlockID] = event.Name
+	}
+}
+
+// onTiltBump запоминает последний удар датчика наклона
+func (w *WatchPanel) onTiltBump(event Event) {
+	w.bumpsMu.Lock()
+	defer w.bumpsMu.Unlock()
+	w.bumps[event.PortID] = event
+}
+
+// refreshLoop обновляет значения, пока окно открыто
+func (w *WatchPanel) refreshLoop() {
+	ticker := time.NewTicker(watchRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			// Наблюдение за показаниями — тоже работа с приложением
+			w.gui.batterySaver.Touch()
+			fyne.Do(w.update)
+		}
+	}
+}
+
+// update перечитывает значения и пересчитывает выражения
+func (w *WatchPanel) update() {
+	gui := w.gui
+	lines := []string{fmt.Sprintf("Таймер: %.1f с", gui.programMgr.TimerSeconds())}
+
+	w.loopsMu.Lock()
+	loopIDs := make([]int, 0, len(w.loops))
+	for id := range w.loops {
+		loopIDs = append(loopIDs, id)
+	}
+	sort.Ints(loopIDs)
+	for _, id := range loopIDs {
+		lines = append(lines, fmt.Sprintf("Цикл (блок %d): %s", id, w.loops[id]))
+	}
+	w.loopsMu.Unlock()
+
+	for _, device := range gui.deviceMgr.GetConnectedDevices() {
+		if !reportsValues(device.DeviceType) {
+			continue
+		}
+		value := "нет данных"
+		if v, ok := gui.hubMgr.GetSensorValue(device.PortID); ok {
+			value = gui.units.Format(device.DeviceType, v)
+		}
+		w.bumpsMu.Lock()
+		if bump, ok := w.bumps[device.PortID]; ok && time.Since(bump.Time) < watchBumpShowTime {
+			value = fmt.Sprintf("%s! (%.1f с назад)", tiltEventNames[bump.Name], time.Since(bump.Time).Seconds())
+		}
+		w.bumpsMu.Unlock()
+		lines = append(lines, fmt.Sprintf("Порт %d, %s: %s", device.PortID, device.Name, value))
+	}
+	w.values.SetText(strings.Join(lines, "\n"))
+
+	for _, p := range w.pins {
+		fill := color.Color(theme.Color(theme.ColorNameDisabled))
+		if value, ok := gui.programMgr.conditionValue(p.cond); ok && p.cond.Matches(value) {
+			fill = theme.Color(theme.ColorNameSuccess)
+		}
+		if p.indicator.FillColor != fill {
+			p.indicator.FillColor = fill
+			p.indicator.Refresh()
+		}
+	}
+}
+
+// close останавливает обновление после закрытия окна
+func (w *WatchPanel) close() {
+	close(w.done)
+	w.unsubscribe()
+	w.unsubscribeBumps()
+	w.gui.watchPanel = nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Режимы мастера программ. В наборе WeDo 2.0 нет датчика цвета, поэтому
+// оба режима строятся на датчике расстояния
+const (
+	WizardModeFollow = "Следовать за предметом"
+	WizardModeAvoid  = "Объезжать препятствия"
+)
+
+// WizardOptions настройки программы, которую создает мастер
+type WizardOptions struct {
+	Mode       string
+	MotorPort  byte
+	SensorPort byte
+	Distance   float64 // Расстояние до предмета или порог препятствия
+	Power      float64 // Мощность движения, %
+	Gain       float64 // Коэффициент регулятора для режима следования
+	BackupTime uint16  // Время отъезда назад от препятствия, мс
+	Beep       bool    // Сигнал при обнаружении препятствия
+}
+
+// wizardBlock описание блока, который мастер добавит на холст
+type wizardBlock struct {
+	blockType BlockType
+	params    map[string]interface{}
+}
+
+// wizardBlocks возвращает блоки программы для заданных настроек по порядку выполнения
+func wizardBlocks(opts WizardOptions) []wizardBlock {
+	blocks := []wizardBlock{{blockType: BlockTypeStart}}
+
+	switch opts.Mode {
+	case WizardModeFollow:
+		blocks = append(blocks, wizardBlock{BlockTypeFollow, map[string]interface{}{
+			"motor_port":  opts.MotorPort,
+			"sensor_port": opts.SensorPort,
+			"target":      opts.Distance,
+			"gain":        opts.Gain,
+			"max_power":   opts.Power,
+		}})
+
+	case WizardModeAvoid:
+		body := []wizardBlock{
+			{BlockTypeMotor, map[string]interface{}{
+				"port":     opts.MotorPort,
+				"power":    int8(opts.Power),
+				"duration": uint16(0),
+			}},
+			{BlockTypeWaitUntil, map[string]interface{}{
+				"sensor":     ConditionSensorDistance,
+				"port":       opts.SensorPort,
+				"comparator": "<",
+				"value":      opts.Distance,
+			}},
+		}
+		if opts.Beep {
+			body = append(body, wizardBlock{BlockTypeSound, map[string]interface{}{
+				"frequency": uint16(880),
+				"duration":  uint16(200),
+			}})
+		}
+		body = append(body, wizardBlock{BlockTypeMotor, map[string]interface{}{
+			"port":     opts.MotorPort,
+			"power":    int8(-opts.Power),
+			"duration": opts.BackupTime,
+		}})
+
+		blocks = append(blocks, wizardBlock{BlockTypeLoop, map[string]interface{}{
+			"mode": LoopModeForever,
+			"body": len(body),
+		}})
+		blocks = append(blocks, body...)
+	}
+
+	return blocks
+}
+
+// insertWizardProgram добавляет созданные мастером блоки на холст отдельной цепочкой
+func (gui *MainGUI) insertWizardProgram(opts WizardOptions) {
+	gui.checkpoint()
+	gui.programPanel.clearConnectionSelection()
+
+	for _, wb := range wizardBlocks(opts) {
+		block := gui.programMgr.CreateBlock(wb.blockType, 100, 100)
+		for key, value := range wb.params {
+			block.Parameters[key] = value
+		}
+		gui.programPanel.AddBlock(block)
+	}
+
+	hasProgram := len(gui.programMgr.program.Blocks) > 0
+	gui.updateToolbarState(gui.hubMgr.IsConnected(), hasProgram)
+	log.Printf("Мастер добавил программу: %s", opts.Mode)
+}
+
+// connectedPorts возвращает порты подключенных устройств заданного типа
+func (gui *MainGUI) connectedPorts(deviceType byte) []byte {
+	var ports []byte
+	for _, device := range gui.deviceMgr.GetDevicesByType(deviceType) {
+		ports = append(ports, device.PortID)
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+	return ports
+}
+
+// showProgramWizard показывает мастер готовых программ следования и объезда
+func (gui *MainGUI) showProgramWizard() {
+	if gui.locked {
+		return
+	}
+
+	opts := WizardOptions{
+		Mode:       WizardModeFollow,
+		MotorPort:  1,
+		SensorPort: 2,
+		Distance:   15,
+		Power:      50,
+		Gain:       6,
+		BackupTime: 1000,
+		Beep:       true,
+	}
+
+	// Порты подставляются по подключенным устройствам
+	if ports := gui.connectedPorts(DEVICE_TYPE_MOTOR); len(ports) > 0 {
+		opts.MotorPort = ports[0]
+	}
+	sensorPorts := gui.connectedPorts(DEVICE_TYPE_MOTION_SENSOR)
+	if len(sensorPorts) > 0 {
+		opts.SensorPort = sensorPorts[0]
+	}
+
+	portNames := []string{"Порт 1", "Порт 2"}
+	portFromName := func(name string) byte {
+		if name == "Порт 2" {
+			return 2
+		}
+		return 1
+	}
+	// Устройство может сообщить о порте вне 1 и 2; тогда выбирается порт 1,
+	// а настройки обновляются вместе с выбором
+	portName := func(port byte) string {
+		if port == 2 {
+			return "Порт 2"
+		}
+		return "Порт 1"
+	}
+
+	distance := findConditionSensor(ConditionSensorDistance)
+	newSlider := func(value *float64, lo, hi, step float64, format string) fyne.CanvasObject {
+		slider := widget.NewSlider(lo, hi)
+		slider.Step = step
+		slider.Value = *value
+		valueLabel := widget.NewLabel(fmt.Sprintf(format, *value))
+		slider.OnChanged = func(v float64) {
+			*value = v
+			valueLabel.SetText(fmt.Sprintf(format, v))
+		}
+		return container.NewBorder(nil, nil, nil, valueLabel, slider)
+	}
+
+	motorSelect := widget.NewSelect(portNames, func(name string) { opts.MotorPort = portFromName(name) })
+	motorSelect.SetSelected(portName(opts.MotorPort))
+	sensorSelect := widget.NewSelect(portNames, func(name string) { opts.SensorPort = portFromName(name) })
+	sensorSelect.SetSelected(portName(opts.SensorPort))
+
+	distanceItem := widget.NewFormItem("Расстояние", newSlider(&opts.Distance, distance.Min, distance.Max, distance.Step, "%.0f "+distance.Unit))
+	form := widget.NewForm(
+		widget.NewFormItem("Мотор", motorSelect),
+		widget.NewFormItem("Датчик расстояния", sensorSelect),
+		distanceItem,
+		widget.NewFormItem("Мощность", newSlider(&opts.Power, 10, 100, 5, "%.0f%%")),
+	)
+
+	// Настройки режима следования
+	followForm := widget.NewForm(
+		widget.NewFormItem("Коэффициент", newSlider(&opts.Gain, 1, 50, 1, "%.0f")),
+	)
+
+	// Настройки режима объезда
+	backup := float64(opts.BackupTime)
+	beepCheck := widget.NewCheck("Сигнал при препятствии", func(checked bool) { opts.Beep = checked })
+	beepCheck.SetChecked(opts.Beep)
+	avoidForm := widget.NewForm(
+		widget.NewFormItem("Отъезд назад", newSlider(&backup, 200, 3000, 100, "%.0f мс")),
+		widget.NewFormItem("", beepCheck),
+	)
+
+	// Настройки показываются только для выбранного режима
+	modeRadio := widget.NewRadioGroup([]string{WizardModeFollow, WizardModeAvoid}, func(mode string) {
+		if mode == "" {
+			return
+		}
+		opts.Mode = mode
+		if mode == WizardModeFollow {
+			distanceItem.HintText = "Робот держит это расстояние до предмета"
+			followForm.Show()
+			avoidForm.Hide()
+		} else {
+			distanceItem.HintText = "Порог, ближе которого робот отъезжает назад"
+			followForm.Hide()
+			avoidForm.Show()
+		}
+		form.Refresh()
+	})
+	modeRadio.SetSelected(opts.Mode)
+
+	content := container.NewVBox(modeRadio, form, followForm, avoidForm)
+	if len(sensorPorts) == 0 {
+		warning := widget.NewLabel("Датчик расстояния не подключен: программа будет создана, но заработает только с датчиком.")
+		warning.Wrapping = fyne.TextWrapWord
+		content.Add(warning)
+	}
+
+	d := dialog.NewCustomConfirm("Мастер программ", "Создать", "Отмена", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		opts.BackupTime = uint16(backup)
+		gui.insertWizardProgram(opts)
+	}, gui.window)
+	d.Resize(fyne.NewSize(480, 480))
+	d.Show()
+}